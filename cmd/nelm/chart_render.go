@@ -21,16 +21,14 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 
 	cmd := cli.NewSubCommand(
 		ctx,
-		"render [options...] [chart-dir]",
+		"render [options...] [chart-dir|chart-archive|chart-url|oci://chart-ref]",
 		"Render a chart.",
 		"Render a chart.",
 		60,
 		chartCmdGroup,
 		cli.SubCommandOptions{
-			Args: cobra.MaximumNArgs(1),
-			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-				return nil, cobra.ShellCompDirectiveFilterDirs
-			},
+			Args:              cobra.MaximumNArgs(1),
+			ValidArgsFunction: completeChartDirs,
 		},
 		func(cmd *cobra.Command, args []string) error {
 			ctx = action.SetupLogging(ctx, cfg.LogLevel, action.DefaultChartRenderLogLevel)
@@ -39,6 +37,8 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 				cfg.ChartDirPath = args[0]
 			}
 
+			cfg.ValuesEnv = environ()
+
 			if err := action.ChartRender(ctx, cfg.ChartRenderOptions); err != nil {
 				return fmt.Errorf("chart render: %w", err)
 			}
@@ -76,6 +76,35 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.ChartDependencyUpdate, "dependency-update", false, "Update Chart.lock and download chart dependencies before loading the chart, same as \"nelm chart dependency update\". Respects --no-update-chart-repos", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                chartRepoFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.ChartRepositoryPlainHTTP, "plain-http", false, "Use plain HTTP instead of HTTPS to pull charts from OCI registries", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                chartRepoFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.ChartProvenanceVerify, "verify", false, "Verify the provenance of the chart archive or the chart pulled from a chart repository before using it", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                chartRepoFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.ChartProvenanceKeyringPath, "keyring", action.DefaultChartProvenanceKeyringPath, "Keyring with public keys used to verify the chart provenance", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                chartRepoFlagGroup,
+			Type:                 cli.FlagTypeFile,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.DefaultSecretValuesDisable, "no-default-secret-values", false, "Ignore secret-values.yaml of the top-level chart", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                secretFlagGroup,
@@ -111,6 +140,35 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.PostRenderer, "post-renderer", "", "Path to an executable which receives rendered manifests of non-hook resources on stdin and prints modified manifests to stdout, or path to a self-contained kustomization directory to apply to these manifests with the built-in kustomize", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                patchFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.PostRendererArgs, "post-renderer-args", []string{}, "Arguments for the --post-renderer executable", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                patchFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.KubeDiscoveryCacheDir, "kube-discovery-cache-dir", "", "Directory for Kubernetes discovery cache. By default, $KUBECACHEDIR or ~/.kube/cache is used", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+			Group:                kubeConnectionFlagGroup,
+			Type:                 cli.FlagTypeDir,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.KubeRefreshDiscovery, "refresh-discovery", false, "Invalidate Kubernetes discovery cache before doing anything", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                kubeConnectionFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.KubeAPIServerName, "kube-api-server", "", "Kubernetes API server address", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                kubeConnectionFlagGroup,
@@ -165,6 +223,20 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.KubeImpersonateUser, "kube-impersonate-user", "", "Username to impersonate for requests to Kubernetes API", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                kubeConnectionFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.KubeImpersonateGroups, "kube-impersonate-group", []string{}, "Group to impersonate for requests to Kubernetes API. Can be specified multiple times", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalMultiEnvVarRegexes,
+			Group:                kubeConnectionFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.KubeQPSLimit, "kube-qps-limit", action.DefaultQPSLimit, "Queries Per Second limit for requests to Kubernetes", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                performanceFlagGroup,
@@ -205,6 +277,14 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.KubeTokenPath, "kube-token-path", "", "Path to the file with the bearer token for authentication in Kubernetes API", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                kubeConnectionFlagGroup,
+			Type:                 cli.FlagTypeFile,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.LogColorMode, "color-mode", action.DefaultLogColorMode, "Color mode for logs. "+allowedLogColorModesHelp(), cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                miscFlagGroup,
@@ -264,6 +344,10 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := addAgeIdentityFlag(cmd, &cfg.SecretAgeIdentityPath, secretFlagGroup); err != nil {
+			return err
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                secretFlagGroup,
@@ -286,18 +370,46 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.CRDsPolicy, "crds-policy", action.DefaultCRDsPolicy, `How to deploy CRDs from "crds/" directories: "skip" to not deploy them, "create" to only create missing ones, "update" to also update existing ones and wait for them to be established. Can be overridden per CRD with the "werf.io/crd-policy" annotation. `+allowedCRDsPoliciesHelp(), cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.StrictTemplates, "strict-templates", false, "Fail if templates access missing keys of maps instead of rendering \"<no value>\". With cluster access, also fail on unknown fields in rendered resources, which are checked by a server-side dry-run. All failures are reported at once", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.ShowCRDs, "show-crds", false, `Show CRDs from "crds/" directories in the output`, cli.AddFlagOptions{
 			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.ShowOnlyFiles, "show-only", []string{}, "Show manifests only from specified template files. The render result has corresponding template paths specified before each resource manifest", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.ShowOnlyFiles, "show-only", []string{}, "Show manifests only from specified template files, e.g. templates/app.yaml or charts/redis/templates/master.yaml. Glob patterns are supported. The render result has corresponding template paths specified before each resource manifest", cli.AddFlagOptions{
 			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.ShowValues, "show-values", false, "Show the release values, merged from values files, --set* flags and environment variables, instead of the manifests", cli.AddFlagOptions{
+			Group: mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.RenderCacheDir, "render-cache-dir", "", "Cache rendered manifests in this directory and reuse them when the chart, values and release options are the same. Charts using secrets or non-deterministic functions like now or randAlphaNum are not cached, as well as charts using the lookup function if there is cluster access", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+			Group:                performanceFlagGroup,
+			Type:                 cli.FlagTypeDir,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.TempDirPath, "temp-dir", "", "The directory for temporary files. By default, create a new directory in the default system directory for temporary files", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
 			Group:                miscFlagGroup,
@@ -306,6 +418,13 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.ValuesEnvPrefix, "set-from-env", "", "Set new values from environment variables with this prefix, where the rest of the variable name is the value path with \"__\" as the key separator and the value is the string value. Values files and other --set* flags override them", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                valuesFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.ValuesFileSets, "set-file", []string{}, "Set new values, where the key is the value path and the value is the path to the file with the value content", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                valuesFlagGroup,
@@ -313,7 +432,7 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.ValuesFilesPaths, "values", []string{}, "Additional values files", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.ValuesFilesPaths, "values", []string{}, "Additional values files. Can be local paths, HTTP(S) URLs or OCI references (oci://), in which case values.yaml of the OCI chart is used", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                valuesFlagGroup,
 			Type:                 cli.FlagTypeFile,
@@ -335,6 +454,27 @@ func newChartRenderCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := addStringArrayFlag(cmd, &cfg.ValuesJSONSets, "set-json", "Set new values, where the key is the value path and the value is JSON", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                valuesFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := addStringArrayFlag(cmd, &cfg.ValuesLiteralSets, "set-literal", "Set new values, where the key is the value path and the value is the value. The value is taken as is: commas, backslashes and equal signs in it are not parsed", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                valuesFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SkipSchemaValidation, "skip-schema-validation", false, "Don't validate values against values.schema.json of the chart and its subcharts", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                valuesFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		return nil
 	}
 
@@ -21,6 +21,8 @@ func newChartSecretCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*
 	cmd.AddCommand(newChartSecretKeyCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newChartSecretFileCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newChartSecretValuesFileCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newChartSecretDirCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newChartSecretRekeyCommand(ctx, afterAllCommandsBuiltFuncs))
 
 	return cmd
 }
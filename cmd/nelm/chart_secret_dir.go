@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/cli"
+)
+
+func newChartSecretDirCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cmd := cli.NewGroupCommand(
+		ctx,
+		"dir",
+		"Manage directories of chart secret files.",
+		"Manage directories of chart secret files.",
+		secretCmdGroup,
+		cli.GroupCommandOptions{},
+	)
+
+	cmd.AddCommand(newChartSecretDirEncryptCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newChartSecretDirDecryptCommand(ctx, afterAllCommandsBuiltFuncs))
+
+	return cmd
+}
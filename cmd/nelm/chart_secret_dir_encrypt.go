@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/cli"
+	"github.com/werf/nelm/pkg/action"
+)
+
+type chartSecretDirEncryptOptions struct {
+	action.SecretDirEncryptOptions
+
+	Dir      string
+	LogLevel string
+}
+
+func newChartSecretDirEncryptCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cfg := &chartSecretDirEncryptOptions{}
+
+	cmd := cli.NewSubCommand(
+		ctx,
+		"encrypt [options...] --secret-key secret-key dir",
+		"Encrypt all files in a directory in place.",
+		"Encrypt in place all files in a directory and its subdirectories. Already encrypted files are skipped, symlinks are not followed. Files are only written if all of them are encrypted successfully.",
+		65,
+		secretCmdGroup,
+		cli.SubCommandOptions{
+			Args: cobra.ExactArgs(1),
+			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return nil, cobra.ShellCompDirectiveFilterDirs
+			},
+		},
+		func(cmd *cobra.Command, args []string) error {
+			ctx = action.SetupLogging(ctx, cfg.LogLevel, action.DefaultSecretDirEncryptLogLevel)
+
+			cfg.Dir = args[0]
+
+			if err := checkSecretKeyFlag(cfg.SecretBackend, cfg.SecretKey); err != nil {
+				return err
+			}
+
+			if err := action.SecretDirEncrypt(ctx, cfg.Dir, cfg.SecretDirEncryptOptions); err != nil {
+				return fmt.Errorf("secret dir encrypt: %w", err)
+			}
+
+			return nil
+		},
+	)
+
+	afterAllCommandsBuiltFuncs[cmd] = func(cmd *cobra.Command) error {
+		if err := cli.AddFlag(cmd, &cfg.LogColorMode, "color-mode", action.DefaultLogColorMode, "Color mode for logs. "+allowedLogColorModesHelp(), cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                miscFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.DryRun, "dry-run", false, "Don't change anything, only show which files would be encrypted", cli.AddFlagOptions{
+			Group: mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.LogLevel, "log-level", action.DefaultSecretDirEncryptLogLevel, "Set log level. "+allowedLogLevelsHelp(), cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                miscFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := addSecretBackendFlags(cmd, &cfg.SecretBackend, &cfg.SecretAgeIdentityPath); err != nil {
+			return err
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key, required for the werf secret backend", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.TempDirPath, "temp-dir", "", "The directory for temporary files. By default, create a new directory in the default system directory for temporary files", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+			Group:                miscFlagGroup,
+			Type:                 cli.FlagTypeDir,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
@@ -38,6 +38,10 @@ func newChartSecretFileEditCommand(ctx context.Context, afterAllCommandsBuiltFun
 
 			cfg.File = args[0]
 
+			if err := checkSecretKeyFlag(cfg.SecretBackend, cfg.SecretKey); err != nil {
+				return err
+			}
+
 			if err := action.SecretFileEdit(ctx, cfg.File, cfg.SecretFileEditOptions); err != nil {
 				return fmt.Errorf("secret file edit: %w", err)
 			}
@@ -61,10 +65,27 @@ func newChartSecretFileEditCommand(ctx context.Context, afterAllCommandsBuiltFun
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.Editor, "editor", "", "Editor command to use, e.g. \"code --wait\". By default, $VISUAL, $EDITOR or one of vim, vi and nano is used", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := addSecretBackendFlags(cmd, &cfg.SecretBackend, &cfg.SecretAgeIdentityPath); err != nil {
+			return err
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key, required for the werf secret backend", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                mainFlagGroup,
-			Required:             true,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.ShowDiff, "show-diff", false, "Show the diff of decrypted data and ask for confirmation before saving changes", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
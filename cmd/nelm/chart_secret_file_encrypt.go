@@ -24,7 +24,7 @@ func newChartSecretFileEncryptCommand(ctx context.Context, afterAllCommandsBuilt
 		ctx,
 		"encrypt [options...] --secret-key secret-key file",
 		"Encrypt file and print result to stdout.",
-		"Encrypt file and print result to stdout.",
+		"Encrypt file and print result to stdout. Pass \"-\" instead of the file to read it from stdin.",
 		20,
 		secretCmdGroup,
 		cli.SubCommandOptions{
@@ -38,6 +38,10 @@ func newChartSecretFileEncryptCommand(ctx context.Context, afterAllCommandsBuilt
 
 			cfg.File = args[0]
 
+			if err := checkSecretKeyFlag(cfg.SecretBackend, cfg.SecretKey); err != nil {
+				return err
+			}
+
 			if err := action.SecretFileEncrypt(ctx, cfg.File, cfg.SecretFileEncryptOptions); err != nil {
 				return fmt.Errorf("secret file encrypt: %w", err)
 			}
@@ -61,17 +65,20 @@ func newChartSecretFileEncryptCommand(ctx context.Context, afterAllCommandsBuilt
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.OutputFilePath, "save-output-to", "", "Save encrypted output to a file", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.OutputFilePath, "save-output-to", "", "Save encrypted output to a file. \"-\" means stdout", cli.AddFlagOptions{
 			Type:  cli.FlagTypeFile,
 			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key", cli.AddFlagOptions{
+		if err := addSecretBackendFlags(cmd, &cfg.SecretBackend, &cfg.SecretAgeIdentityPath); err != nil {
+			return err
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key, required for the werf secret backend", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                mainFlagGroup,
-			Required:             true,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
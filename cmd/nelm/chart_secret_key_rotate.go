@@ -23,14 +23,12 @@ func newChartSecretKeyRotateCommand(ctx context.Context, afterAllCommandsBuiltFu
 		ctx,
 		"rotate [options...] --old-secret-key secret-key --new-secret-key secret-key [chart-dir]",
 		"Reencrypt secret files with a new secret key.",
-		"Decrypt with an old secret key, then encrypt with a new secret key chart files secret-values.yaml and secret/*.",
+		"Decrypt with an old secret key, then encrypt with a new secret key chart files secret-values.yaml and secret/*. Files are only written if all of them are reencrypted successfully.",
 		70,
 		secretCmdGroup,
 		cli.SubCommandOptions{
-			Args: cobra.MaximumNArgs(1),
-			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-				return nil, cobra.ShellCompDirectiveFilterDirs
-			},
+			Args:              cobra.MaximumNArgs(1),
+			ValidArgsFunction: completeChartDirs,
 		},
 		func(cmd *cobra.Command, args []string) error {
 			ctx = action.SetupLogging(ctx, cfg.LogLevel, action.DefaultSecretKeyRotateLogLevel)
@@ -62,16 +60,14 @@ func newChartSecretKeyRotateCommand(ctx context.Context, afterAllCommandsBuiltFu
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.NewSecretKey, "new-secret-key", "", "New secret key", cli.AddFlagOptions{
-			Group:    mainFlagGroup,
-			Required: true,
+		if err := cli.AddFlag(cmd, &cfg.NewSecretKey, "new-secret-key", "", "New secret key. By default, $WERF_SECRET_KEY or the .werf_secret_key file is used", cli.AddFlagOptions{
+			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.OldSecretKey, "old-secret-key", "", "Old secret key", cli.AddFlagOptions{
-			Group:    mainFlagGroup,
-			Required: true,
+		if err := cli.AddFlag(cmd, &cfg.OldSecretKey, "old-secret-key", "", "Old secret key. By default, $WERF_OLD_SECRET_KEY is used", cli.AddFlagOptions{
+			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
@@ -84,6 +80,13 @@ func newChartSecretKeyRotateCommand(ctx context.Context, afterAllCommandsBuiltFu
 			return fmt.Errorf("add flag: %w", err)
 		}
 
+		if err := cli.AddFlag(cmd, &cfg.SkipUndecryptable, "skip-undecryptable", false, "Leave files which can't be decrypted with the old secret key as is. By default, nothing is changed if any file can't be decrypted", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
 		if err := cli.AddFlag(cmd, &cfg.TempDirPath, "temp-dir", "", "The directory for temporary files. By default, create a new directory in the default system directory for temporary files", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
 			Group:                miscFlagGroup,
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// The same command as "chart secret key rotate", available as "chart secret rekey" too.
+func newChartSecretRekeyCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cmd := newChartSecretKeyRotateCommand(ctx, afterAllCommandsBuiltFuncs)
+	cmd.Use = "rekey" + strings.TrimPrefix(cmd.Use, "rotate")
+	cmd.Long += ` Alias of "chart secret key rotate".`
+
+	return cmd
+}
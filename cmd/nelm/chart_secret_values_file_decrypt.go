@@ -24,7 +24,7 @@ func newChartSecretValuesFileDecryptCommand(ctx context.Context, afterAllCommand
 		ctx,
 		"decrypt [options...] --secret-key secret-key values-file",
 		"Decrypt values file and print result to stdout.",
-		"Decrypt values file and print result to stdout.",
+		"Decrypt values file and print result to stdout. Pass \"-\" instead of the file to read it from stdin.",
 		40,
 		secretCmdGroup,
 		cli.SubCommandOptions{
@@ -38,6 +38,10 @@ func newChartSecretValuesFileDecryptCommand(ctx context.Context, afterAllCommand
 
 			cfg.ValuesFile = args[0]
 
+			if err := checkSecretKeyFlag(cfg.SecretBackend, cfg.SecretKey); err != nil {
+				return err
+			}
+
 			if err := action.SecretValuesFileDecrypt(ctx, cfg.ValuesFile, cfg.SecretValuesFileDecryptOptions); err != nil {
 				return fmt.Errorf("secret values file decrypt: %w", err)
 			}
@@ -61,17 +65,27 @@ func newChartSecretValuesFileDecryptCommand(ctx context.Context, afterAllCommand
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.OutputFilePath, "save-output-to", "", "Save decrypted output to a file", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.OutputFilePath, "save-output-to", "", "Save decrypted output to a file. \"-\" means stdout", cli.AddFlagOptions{
 			Type:  cli.FlagTypeFile,
 			Group: mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.Keys, "keys", []string{}, "Decrypt only values under these keys, e.g. \".db.password,.api.token\", and leave the rest of the values file as is", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := addSecretBackendFlags(cmd, &cfg.SecretBackend, &cfg.SecretAgeIdentityPath); err != nil {
+			return err
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key, required for the werf secret backend", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                mainFlagGroup,
-			Required:             true,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
@@ -38,6 +38,10 @@ func newChartSecretValuesFileEditCommand(ctx context.Context, afterAllCommandsBu
 
 			cfg.ValuesFile = args[0]
 
+			if err := checkSecretKeyFlag(cfg.SecretBackend, cfg.SecretKey); err != nil {
+				return err
+			}
+
 			if err := action.SecretValuesFileEdit(ctx, cfg.ValuesFile, cfg.SecretValuesFileEditOptions); err != nil {
 				return fmt.Errorf("secret values file edit: %w", err)
 			}
@@ -61,10 +65,34 @@ func newChartSecretValuesFileEditCommand(ctx context.Context, afterAllCommandsBu
 			return fmt.Errorf("add flag: %w", err)
 		}
 
-		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key", cli.AddFlagOptions{
+		if err := cli.AddFlag(cmd, &cfg.Editor, "editor", "", "Editor command to use, e.g. \"code --wait\". By default, $VISUAL, $EDITOR or one of vim, vi and nano is used", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.Keys, "keys", []string{}, "Decrypt for editing only values under these keys, e.g. \".db.password,.api.token\", the rest of the values file is edited as is", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := addSecretBackendFlags(cmd, &cfg.SecretBackend, &cfg.SecretAgeIdentityPath); err != nil {
+			return err
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.SecretKey, "secret-key", "", "Secret key, required for the werf secret backend", cli.AddFlagOptions{
 			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
 			Group:                mainFlagGroup,
-			Required:             true,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.ShowDiff, "show-diff", false, "Show the diff of decrypted data and ask for confirmation before saving changes", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagLocalEnvVarRegexes,
+			Group:                mainFlagGroup,
 		}); err != nil {
 			return fmt.Errorf("add flag: %w", err)
 		}
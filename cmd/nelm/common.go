@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/werf/common-go/pkg/cli"
 	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/nelm/pkg/log"
+	"github.com/werf/nelm/pkg/secret"
 )
 
 const (
@@ -15,10 +26,290 @@ const (
 
 var helmRootCmd *cobra.Command
 
+// environ returns the environment variables of the process by name.
+func environ() map[string]string {
+	env := map[string]string{}
+	for _, keyValue := range os.Environ() {
+		name, val, _ := strings.Cut(keyValue, "=")
+		env[name] = val
+	}
+
+	return env
+}
+
+func allowedCRDsPoliciesHelp() string {
+	return "Allowed: " + strings.Join(action.CRDsPolicies, ", ")
+}
+
 func allowedLogColorModesHelp() string {
 	return "Allowed: " + strings.Join(action.LogColorModes, ", ")
 }
 
+func allowedLogFormatsHelp() string {
+	return "Allowed: " + strings.Join(action.LogFormats, ", ")
+}
+
+func addLogFileFlags(cmd *cobra.Command, path *string, appendToFile *bool) error {
+	if err := cli.AddFlag(cmd, path, "log-file", "", "Also write logs down to the debug level to this file, regardless of --log-level. Colors are stripped", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+		Type:                 cli.FlagTypeFile,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	if err := cli.AddFlag(cmd, appendToFile, "log-file-append", false, "Append to the log file instead of truncating it", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+func addLogRedactFlags(cmd *cobra.Command, noRedact *bool, sensitiveKeys *[]string) error {
+	if err := cli.AddFlag(cmd, noRedact, "log-no-redact", false, "Don't redact Secret data and values of sensitive fields in trace logs of objects. Only for local debugging", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	if err := cli.AddFlag(cmd, sensitiveKeys, "log-redact-keys", log.DefaultSensitiveKeys, "Redact values of fields with names containing any of these, case-insensitively, in trace logs of objects", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+func addEventsFlags(cmd *cobra.Command, emitEvents *bool, involvedObject *string) error {
+	if err := cli.AddFlag(cmd, emitEvents, "emit-events", false, "Create Kubernetes Events in the release namespace when the release deploy starts, succeeds or fails", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	if err := cli.AddFlag(cmd, involvedObject, "events-involved-object", "", "The object in the release namespace which the Events are about, as <Kind>[.<group>]/<name>, e.g. Deployment.apps/myapp. Defaults to the release namespace", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+func addTrackTimeoutFlags(cmd *cobra.Command, creationTimeout, readinessTimeout *time.Duration) error {
+	if err := cli.AddFlag(cmd, creationTimeout, "track-creation-timeout", 0, "Fail if resource creation tracking did not finish in time. Defaults to --track-timeout", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                progressFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	if err := cli.AddFlag(cmd, readinessTimeout, "track-timeout", 0, "Fail if resource readiness tracking did not finish in time. Can be overridden for a resource with the \"werf.io/track-timeout\" annotation", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                progressFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+func addDeprecatedTrackTimeoutFlags(cmd *cobra.Command, deprecatedCreationTimeout, deprecatedReadinessTimeout *time.Duration) error {
+	if err := cli.AddFlag(cmd, deprecatedCreationTimeout, "resource-creation-timeout", 0, "Use --track-creation-timeout instead", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                progressFlagGroup,
+		Deprecated:           true,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	if err := cli.AddFlag(cmd, deprecatedReadinessTimeout, "resource-readiness-timeout", 0, "Use --track-timeout instead", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                progressFlagGroup,
+		Deprecated:           true,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+// The new flags take precedence if set.
+func applyDeprecatedTrackTimeoutFlags(creationTimeout, readinessTimeout *time.Duration, deprecatedCreationTimeout, deprecatedReadinessTimeout time.Duration) {
+	if *creationTimeout == 0 {
+		*creationTimeout = deprecatedCreationTimeout
+	}
+
+	if *readinessTimeout == 0 {
+		*readinessTimeout = deprecatedReadinessTimeout
+	}
+}
+
+func addLogFormatFlag(cmd *cobra.Command, dest *string) error {
+	if err := cli.AddFlag(cmd, dest, "log-format", action.DefaultLogFormat, "Format of logs. JSON logs are written to stderr, one object per line. "+allowedLogFormatsHelp(), cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
 func allowedLogLevelsHelp() string {
-	return "Allowed: " + strings.Join(action.LogLevels, ", ")
+	return "Allowed: " + strings.Join(action.LogLevels, ", ") + ". Levels of components can be overridden, e.g. \"info,kube=trace,plan=debug\". Components: " + strings.Join(action.LogComponents, ", ")
+}
+
+func allowedProgressModesHelp() string {
+	return "Allowed: " + strings.Join(action.ProgressModes, ", ")
+}
+
+func allowedShowLogsUntilHelp() string {
+	return "Allowed: " + strings.Join(action.ShowLogsUntilValues, ", ")
+}
+
+func allowedSecretBackendsHelp() string {
+	return "Allowed: " + strings.Join(secret.BackendNames, ", ")
+}
+
+func addAgeIdentityFlag(cmd *cobra.Command, dest *string, group *cli.FlagGroup) error {
+	if err := cli.AddFlag(cmd, dest, "age-identity", "", "File with age identities to decrypt secrets encrypted with the age secret backend. Files are encrypted for recipients of these identities", cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                group,
+		Type:                 cli.FlagTypeFile,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+func addSecretBackendFlags(cmd *cobra.Command, backend, ageIdentityPath *string) error {
+	if err := addAgeIdentityFlag(cmd, ageIdentityPath, mainFlagGroup); err != nil {
+		return err
+	}
+
+	if err := cli.AddFlag(cmd, backend, "secret-backend", secret.WerfBackendName, "Encrypt and decrypt with this secret backend. "+allowedSecretBackendsHelp(), cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+		Group:                mainFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+// The secret key is only needed for the werf secret backend.
+func checkSecretKeyFlag(backend, secretKey string) error {
+	if backend != secret.AgeBackendName && secretKey == "" {
+		return &action.UsageError{Err: fmt.Errorf(`required flag "secret-key" not set`)}
+	}
+
+	return nil
+}
+
+// Invalid arguments are usage errors, the same as invalid flags.
+func wrapArgsErrors(cmd *cobra.Command) {
+	if cmd.Args == nil {
+		return
+	}
+
+	validateArgs := cmd.Args
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if err := validateArgs(cmd, args); err != nil {
+			return &action.UsageError{Err: err}
+		}
+
+		return nil
+	}
+}
+
+var errInterrupted = errors.New("interrupted")
+
+// interruptibleContext returns a context which is canceled on the first SIGINT/SIGTERM, so that the
+// action can stop gracefully and finalize the release. The second signal aborts immediately.
+func interruptibleContext(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	signalCh := make(chan os.Signal, 2)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-signalCh:
+		case <-stopCh:
+			return
+		}
+
+		log.Default.Warn(ctx, "Interrupted, waiting for in-flight operations to finish. Interrupt again to abort immediately")
+		cancel(errInterrupted)
+
+		select {
+		case <-signalCh:
+		case <-stopCh:
+			return
+		}
+
+		log.Default.Error(ctx, "Error: aborted")
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signalCh)
+		close(stopCh)
+		cancel(nil)
+	}
+}
+
+// Like cli.AddFlag for []string, but values are not split by commas, which is required for values
+// like JSON.
+func addStringArrayFlag(cmd *cobra.Command, dest *[]string, name, help string, opts cli.AddFlagOptions) error {
+	if err := cli.AddFlag(cmd, dest, name, []string{}, help, opts); err != nil {
+		return err
+	}
+
+	cmd.Flags().Lookup(name).Value = &stringArrayValue{dest: dest}
+
+	return nil
+}
+
+var _ pflag.SliceValue = (*stringArrayValue)(nil)
+
+type stringArrayValue struct {
+	dest *[]string
+}
+
+func (v *stringArrayValue) Set(val string) error {
+	*v.dest = append(*v.dest, val)
+	return nil
+}
+
+func (v *stringArrayValue) Type() string {
+	return "stringArray"
+}
+
+func (v *stringArrayValue) String() string {
+	return "[" + strings.Join(*v.dest, ",") + "]"
+}
+
+func (v *stringArrayValue) Append(val string) error {
+	return v.Set(val)
+}
+
+func (v *stringArrayValue) Replace(vals []string) error {
+	*v.dest = vals
+	return nil
+}
+
+func (v *stringArrayValue) GetSlice() []string {
+	return *v.dest
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/pkg/action"
+)
+
+// Completions are computed on every Tab press, so listing is abandoned if it takes longer than this.
+const completionTimeout = time.Second
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// addFlagCompletions completes release names, namespaces and kube contexts for the commands which
+// have these flags.
+func addFlagCompletions(cmd *cobra.Command) error {
+	completionFuncs := map[string]completionFunc{
+		"release":      completeReleaseNames,
+		"namespace":    completeNamespaces,
+		"kube-context": completeKubeContexts,
+	}
+
+	for flagName, fn := range completionFuncs {
+		if cmd.Flags().Lookup(flagName) == nil {
+			continue
+		}
+
+		if err := cmd.RegisterFlagCompletionFunc(flagName, fn); err != nil {
+			return fmt.Errorf("register completion for flag %q: %w", flagName, err)
+		}
+	}
+
+	return nil
+}
+
+// completeChartDirs completes directories with Chart.yaml. Other directories are completed with a
+// trailing slash, so that charts can be found deeper.
+func completeChartDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	dir, prefix := filepath.Split(toComplete)
+
+	listDir := dir
+	if listDir == "" {
+		listDir = "."
+	}
+
+	entries, err := os.ReadDir(listDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+
+		path := dir + entry.Name()
+		if _, err := os.Stat(filepath.Join(path, chartutil.ChartfileName)); err == nil {
+			completions = append(completions, path)
+		} else {
+			completions = append(completions, path+string(filepath.Separator))
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeReleaseNames completes releases from the release storage in the namespace of the
+// --namespace flag, or of the kube context.
+func completeReleaseNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	releaseNames := completeWithTimeout(cmd.Context(), func(ctx context.Context) ([]string, error) {
+		kubeConfigPaths, _ := cmd.Flags().GetStringSlice("kube-config")
+		kubeImpersonateGroups, _ := cmd.Flags().GetStringSlice("kube-impersonate-group")
+		kubeSkipTLSVerify, _ := cmd.Flags().GetBool("no-verify-kube-tls")
+
+		result, err := action.ReleaseList(ctx, completionFlagString(cmd, "namespace"), action.ReleaseListOptions{
+			KubeAPIServerName:     completionFlagString(cmd, "kube-api-server"),
+			KubeCAPath:            completionFlagString(cmd, "kube-ca"),
+			KubeConfigBase64:      completionFlagString(cmd, "kube-config-base64"),
+			KubeConfigPaths:       kubeConfigPaths,
+			KubeContext:           completionFlagString(cmd, "kube-context"),
+			KubeImpersonateGroups: kubeImpersonateGroups,
+			KubeImpersonateUser:   completionFlagString(cmd, "kube-impersonate-user"),
+			KubeSkipTLSVerify:     kubeSkipTLSVerify,
+			KubeTLSServerName:     completionFlagString(cmd, "kube-api-server-tls-name"),
+			KubeToken:             completionFlagString(cmd, "kube-token"),
+			KubeTokenPath:         completionFlagString(cmd, "kube-token-path"),
+			OutputNoPrint:         true,
+			ReleaseStorageDriver:  completionFlagString(cmd, "release-storage"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var releaseNames []string
+		for _, rel := range result.Releases {
+			releaseNames = append(releaseNames, rel.Name)
+		}
+
+		return releaseNames, nil
+	})
+
+	return filterCompletions(releaseNames, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	namespaces := completeWithTimeout(cmd.Context(), func(ctx context.Context) ([]string, error) {
+		kubeConfigPaths, _ := cmd.Flags().GetStringSlice("kube-config")
+		kubeImpersonateGroups, _ := cmd.Flags().GetStringSlice("kube-impersonate-group")
+		kubeSkipTLSVerify, _ := cmd.Flags().GetBool("no-verify-kube-tls")
+
+		kubeConfig, err := kube.NewKubeConfig(ctx, splitKubeConfigPaths(kubeConfigPaths), kube.KubeConfigOptions{
+			CertificateAuthority:  completionFlagString(cmd, "kube-ca"),
+			CurrentContext:        completionFlagString(cmd, "kube-context"),
+			Impersonate:           completionFlagString(cmd, "kube-impersonate-user"),
+			ImpersonateGroups:     kubeImpersonateGroups,
+			InsecureSkipTLSVerify: kubeSkipTLSVerify,
+			KubeConfigBase64:      completionFlagString(cmd, "kube-config-base64"),
+			Server:                completionFlagString(cmd, "kube-api-server"),
+			TLSServerName:         completionFlagString(cmd, "kube-api-server-tls-name"),
+			Timeout:               completionTimeout.String(),
+			Token:                 completionFlagString(cmd, "kube-token"),
+			TokenFile:             completionFlagString(cmd, "kube-token-path"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := kubernetes.NewForConfig(kubeConfig.RestConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		namespaceList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		var namespaces []string
+		for _, ns := range namespaceList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+
+		return namespaces, nil
+	})
+
+	return filterCompletions(namespaces, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeKubeContexts only reads the kubeconfig, so it works even if the cluster is unreachable.
+func completeKubeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var (
+		config *api.Config
+		err    error
+	)
+	if kubeConfigBase64 := completionFlagString(cmd, "kube-config-base64"); kubeConfigBase64 != "" {
+		var data []byte
+		if data, err = base64.StdEncoding.DecodeString(kubeConfigBase64); err == nil {
+			config, err = clientcmd.Load(data)
+		}
+	} else {
+		kubeConfigPaths, _ := cmd.Flags().GetStringSlice("kube-config")
+
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		if paths := splitKubeConfigPaths(kubeConfigPaths); len(paths) > 0 {
+			loadingRules.Precedence = paths
+		}
+
+		config, err = loadingRules.Load()
+	}
+
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return filterCompletions(lo.Keys(config.Contexts), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWithTimeout returns no completions if listing fails or takes too long, e.g. when the
+// cluster is unreachable, instead of showing errors in the middle of the command line.
+func completeWithTimeout(ctx context.Context, list func(ctx context.Context) ([]string, error)) []string {
+	ctx = action.SetupLogging(ctx, action.SilentLogLevel, action.SilentLogLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	resultCh := make(chan []string, 1)
+	go func() {
+		result, err := list(ctx)
+		if err != nil {
+			result = nil
+		}
+
+		resultCh <- result
+	}()
+
+	// Not all clients respect the context, so don't wait for them.
+	select {
+	case result := <-resultCh:
+		return result
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func filterCompletions(completions []string, toComplete string) []string {
+	completions = lo.Filter(completions, func(completion string, _ int) bool {
+		return strings.HasPrefix(completion, toComplete)
+	})
+
+	return lo.Uniq(completions)
+}
+
+func completionFlagString(cmd *cobra.Command, name string) string {
+	val, _ := cmd.Flags().GetString(name)
+	return val
+}
+
+func splitKubeConfigPaths(paths []string) []string {
+	var splitPaths []string
+	for _, path := range paths {
+		splitPaths = append(splitPaths, filepath.SplitList(path)...)
+	}
+
+	return splitPaths
+}
@@ -8,19 +8,24 @@ import (
 
 	"github.com/chanced/caps"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 
 	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/common-go/pkg/cli"
 	"github.com/werf/logboek"
 	"github.com/werf/nelm/internal/common"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/telemetry"
 	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func main() {
 	ctx := logboek.NewContext(context.Background(), logboek.DefaultLogger())
 
+	telemetryInvocation := telemetry.NewInvocation()
+	ctx = telemetry.NewContext(ctx, telemetryInvocation)
+
 	cli.FlagEnvVarsPrefix = caps.ToScreamingSnake(common.Brand) + "_"
 	afterAllCommandsBuiltFuncs := make(map[*cobra.Command]func(cmd *cobra.Command) error)
 
@@ -28,35 +33,142 @@ func main() {
 	var err error
 	helmRootCmd, err = helm_v3.Init()
 	if err != nil {
-		abort(ctx, fmt.Errorf("init helm: %w", err), 1)
+		abort(ctx, fmt.Errorf("init helm: %w", err), exitCodeError)
 	}
 
 	rootCmd := NewRootCommand(ctx, afterAllCommandsBuiltFuncs)
 
+	// Close the log file even on panic.
+	defer closeLogFile()
+
+	var logFormat, logFilePath, telemetryMode string
+	var logFileAppend, logNoRedact bool
+	var logRedactKeys []string
 	for cmd, fn := range afterAllCommandsBuiltFuncs {
 		if err := fn(cmd); err != nil {
-			abort(ctx, err, 1)
+			abort(ctx, err, exitCodeError)
+		}
+
+		wrapArgsErrors(cmd)
+
+		if err := addFlagCompletions(cmd); err != nil {
+			abort(ctx, err, exitCodeError)
+		}
+
+		if err := addLogFormatFlag(cmd, &logFormat); err != nil {
+			abort(ctx, err, exitCodeError)
+		}
+
+		if err := addLogFileFlags(cmd, &logFilePath, &logFileAppend); err != nil {
+			abort(ctx, err, exitCodeError)
+		}
+
+		if err := addLogRedactFlags(cmd, &logNoRedact, &logRedactKeys); err != nil {
+			abort(ctx, err, exitCodeError)
+		}
+
+		if err := addTelemetryFlag(cmd, &telemetryMode); err != nil {
+			abort(ctx, err, exitCodeError)
+		}
+	}
+
+	var telemetrySession *telemetry.Session
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := action.SetupLogFormat(logFormat); err != nil {
+			return &action.UsageError{Err: err}
+		}
+
+		log.SetRedactOptions(log.RedactOptions{
+			Disable:       logNoRedact,
+			SensitiveKeys: logRedactKeys,
+		})
+
+		if logFilePath != "" {
+			var err error
+			if closeLogFile, err = action.SetupLogFile(logFilePath, logFileAppend); err != nil {
+				return err
+			}
+		}
+
+		if flag := cmd.Flags().Lookup("log-level"); flag != nil {
+			if err := action.ValidateLogLevel(flag.Value.String()); err != nil {
+				return &action.UsageError{Err: err}
+			}
+		}
+
+		if !lo.Contains(telemetry.Modes, telemetryMode) {
+			return &action.UsageError{Err: fmt.Errorf("unknown telemetry mode %q", telemetryMode)}
 		}
+
+		telemetryInvocation.SetCommand(telemetryCommandName(cmd))
+		telemetrySession = telemetry.StartSession(ctx, telemetry.SessionOptions{
+			ConfigDir: telemetryConfigDir(cmd),
+			Mode:      telemetryMode,
+		})
+
+		return nil
 	}
 
 	if unsupportedEnvVars := cli.FindUndefinedFlagEnvVarsInEnviron(); len(unsupportedEnvVars) > 0 {
-		abort(ctx, fmt.Errorf("unsupported environment variable(s): %s", strings.Join(unsupportedEnvVars, ",")), 1)
+		abort(ctx, fmt.Errorf("unsupported environment variable(s): %s", strings.Join(unsupportedEnvVars, ",")), exitCodeUsage)
 	}
 
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		var exitCode int
-		if errors.Is(err, action.ErrChangesPlanned) {
-			exitCode = 2
-		} else {
-			exitCode = 1
-		}
+	err = rootCmd.ExecuteContext(ctx)
+
+	// Planning changes is not a failure, it is only reported with the exit code.
+	if errors.Is(err, action.ErrChangesPlanned) {
+		telemetrySession.Finish(telemetryInvocation, nil)
+		exit(ctx, exitCodeChangesPlanned)
+	}
+
+	telemetrySession.Finish(telemetryInvocation, err)
+
+	if err != nil {
+		abort(ctx, err, exitCode(err))
+	}
+}
+
+const (
+	exitCodeError            = 1
+	exitCodeUsage            = 2
+	exitCodeChangesPlanned   = 2
+	exitCodeTemplate         = 3
+	exitCodeKubeConnection   = 4
+	exitCodeApply            = 5
+	exitCodeReadinessTimeout = 6
+)
 
-		abort(ctx, err, exitCode)
+// Errors can be wrapped into errors of another kind, e.g. a failed apply can be reported along
+// with a readiness timeout, so the kinds are checked in the order of precedence.
+func exitCode(err error) int {
+	switch {
+	case errors.As(err, new(*action.ReadinessTimeoutError)):
+		return exitCodeReadinessTimeout
+	case errors.As(err, new(*action.ApplyError)):
+		return exitCodeApply
+	case errors.As(err, new(*action.TemplateError)):
+		return exitCodeTemplate
+	case errors.As(err, new(*action.KubeConnectionError)):
+		return exitCodeKubeConnection
+	case errors.As(err, new(*action.UsageError)):
+		return exitCodeUsage
+	default:
+		return exitCodeError
 	}
 }
 
+// Replaced if the log file is used.
+var closeLogFile = func() error { return nil }
+
 func abort(ctx context.Context, err error, exitCode int) {
 	log.Default.WarnPop(ctx, "final")
 	log.Default.Error(ctx, "Error: %s", err)
+	closeLogFile()
+	os.Exit(exitCode)
+}
+
+func exit(ctx context.Context, exitCode int) {
+	log.Default.WarnPop(ctx, "final")
+	closeLogFile()
 	os.Exit(exitCode)
 }
@@ -19,11 +19,15 @@ func newReleaseCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobr
 	)
 
 	cmd.AddCommand(newReleaseInstallCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newReleaseDevelopCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newReleaseRollbackCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newReleaseUninstallCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newReleaseMigrateCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newReleaseHistoryCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newReleaseListCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newReleaseGetCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newReleaseStatusCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newReleaseCheckCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newPlanCommand(ctx, afterAllCommandsBuiltFuncs))
 
 	return cmd
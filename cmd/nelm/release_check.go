@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/cli"
+)
+
+func newReleaseCheckCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cmd := cli.NewGroupCommand(
+		ctx,
+		"check",
+		"Check releases for problems.",
+		"Check releases for problems.",
+		releaseCmdGroup,
+		cli.GroupCommandOptions{},
+	)
+
+	cmd.AddCommand(newReleaseCheckAPIsCommand(ctx, afterAllCommandsBuiltFuncs))
+
+	return cmd
+}
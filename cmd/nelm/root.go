@@ -9,6 +9,7 @@ import (
 
 	"github.com/werf/common-go/pkg/cli"
 	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/pkg/action"
 )
 
 func NewRootCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
@@ -23,11 +24,15 @@ func NewRootCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.C
 	cmd.SetUsageFunc(usageFunc)
 	cmd.SetUsageTemplate(usageTemplate)
 	cmd.SetHelpTemplate(helpTemplate)
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &action.UsageError{Err: err}
+	})
 
 	cmd.AddCommand(newReleaseCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newChartCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newRepoCommand(ctx, afterAllCommandsBuiltFuncs))
 	cmd.AddCommand(newVersionCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newTelemetryCommand(ctx, afterAllCommandsBuiltFuncs))
 
 	return cmd
 }
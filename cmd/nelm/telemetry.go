@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chanced/caps"
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/cli"
+	"github.com/werf/nelm/internal/telemetry"
+)
+
+func newTelemetryCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cmd := cli.NewGroupCommand(
+		ctx,
+		"telemetry",
+		"Manage anonymous usage telemetry.",
+		"Manage anonymous usage telemetry. Telemetry is disabled unless explicitly enabled.",
+		miscCmdGroup,
+		cli.GroupCommandOptions{},
+	)
+
+	cmd.AddCommand(newTelemetryStatusCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newTelemetryEnableCommand(ctx, afterAllCommandsBuiltFuncs))
+	cmd.AddCommand(newTelemetryDisableCommand(ctx, afterAllCommandsBuiltFuncs))
+
+	return cmd
+}
+
+func addTelemetryFlag(cmd *cobra.Command, dest *string) error {
+	if err := cli.AddFlag(cmd, dest, "telemetry", telemetry.ModeConsent, "Send anonymous usage telemetry for this command. By default, follow the choice made with \"telemetry enable\" or \"telemetry disable\". Allowed: "+strings.Join([]string{telemetry.ModeOn, telemetry.ModeOff}, ", "), cli.AddFlagOptions{
+		GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+		Group:                miscFlagGroup,
+	}); err != nil {
+		return fmt.Errorf("add flag: %w", err)
+	}
+
+	return nil
+}
+
+// The telemetry commands have --config-dir, while the other commands can only use the directory
+// from $NELM_CONFIG_DIR, which sets --config-dir of the telemetry commands too.
+func telemetryConfigDir(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("config-dir"); flag != nil {
+		return flag.Value.String()
+	}
+
+	return os.Getenv(caps.ToScreamingSnake(cli.FlagEnvVarsPrefix + "config-dir"))
+}
+
+func telemetryCommandName(cmd *cobra.Command) string {
+	var names []string
+	for c := cmd; c != nil && c.HasParent(); c = c.Parent() {
+		names = append([]string{c.Name()}, names...)
+	}
+
+	return strings.Join(names, " ")
+}
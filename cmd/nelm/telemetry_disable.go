@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/cli"
+	"github.com/werf/nelm/pkg/action"
+)
+
+type telemetryDisableConfig struct {
+	action.TelemetryDisableOptions
+
+	LogLevel string
+}
+
+func newTelemetryDisableCommand(ctx context.Context, afterAllCommandsBuiltFuncs map[*cobra.Command]func(cmd *cobra.Command) error) *cobra.Command {
+	cfg := &telemetryDisableConfig{}
+
+	cmd := cli.NewSubCommand(
+		ctx,
+		"disable [options...]",
+		"Disable anonymous usage telemetry and delete unsent events.",
+		"Disable anonymous usage telemetry and delete unsent events.",
+		0,
+		miscCmdGroup,
+		cli.SubCommandOptions{},
+		func(cmd *cobra.Command, args []string) error {
+			ctx = action.SetupLogging(ctx, cfg.LogLevel, action.DefaultTelemetryLogLevel)
+
+			if err := action.TelemetryDisable(ctx, cfg.TelemetryDisableOptions); err != nil {
+				return fmt.Errorf("telemetry disable: %w", err)
+			}
+
+			return nil
+		},
+	)
+
+	afterAllCommandsBuiltFuncs[cmd] = func(cmd *cobra.Command) error {
+		if err := cli.AddFlag(cmd, &cfg.ConfigDir, "config-dir", "", "The directory for the telemetry configuration, also used by all other commands if set with $NELM_CONFIG_DIR. By default, the user configuration directory is used", cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalEnvVarRegexes,
+			Group:                miscFlagGroup,
+			Type:                 cli.FlagTypeDir,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		if err := cli.AddFlag(cmd, &cfg.LogLevel, "log-level", action.DefaultTelemetryLogLevel, "Set log level. "+allowedLogLevelsHelp(), cli.AddFlagOptions{
+			GetEnvVarRegexesFunc: cli.GetFlagGlobalAndLocalEnvVarRegexes,
+			Group:                miscFlagGroup,
+		}); err != nil {
+			return fmt.Errorf("add flag: %w", err)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
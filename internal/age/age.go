@@ -0,0 +1,148 @@
+// Package age encrypts and decrypts files in the age v1 format (https://age-encryption.org/v1)
+// with X25519 recipients, so that files can be encrypted and decrypted interchangeably with age and
+// SOPS.
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	agelib "filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const headerVersionLine = "age-encryption.org/v1"
+
+var ErrNoMatchingIdentity = errors.New("no identity matched any of the recipients")
+
+type (
+	Recipient = agelib.X25519Recipient
+	Identity  = agelib.X25519Identity
+)
+
+func ParseRecipient(s string) (*Recipient, error) {
+	recipient, err := agelib.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age recipient: %w", err)
+	}
+
+	return recipient, nil
+}
+
+func GenerateIdentity() (*Identity, error) {
+	identity, err := agelib.GenerateX25519Identity()
+	if err != nil {
+		return nil, fmt.Errorf("error generating age identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func ParseIdentity(s string) (*Identity, error) {
+	identity, err := agelib.ParseX25519Identity(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// ParseIdentities parses identity files in the age-keygen format: one identity per line, empty
+// lines and lines starting with "#" are ignored.
+func ParseIdentities(data []byte) ([]*Identity, error) {
+	var identities []*Identity
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		identity, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing line %d: %w", lineNum, err)
+		}
+
+		identities = append(identities, identity)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading identities: %w", err)
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identities found")
+	}
+
+	return identities, nil
+}
+
+// IsEncrypted returns true if the data is an age encrypted file, armored or not.
+func IsEncrypted(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return IsArmored(data) || bytes.HasPrefix(data, []byte(headerVersionLine+"\n"))
+}
+
+// Encrypt returns the data encrypted for the recipients in the binary age format.
+func Encrypt(plaintext []byte, recipients ...*Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients specified")
+	}
+
+	var ageRecipients []agelib.Recipient
+	for _, recipient := range recipients {
+		ageRecipients = append(ageRecipients, recipient)
+	}
+
+	var result bytes.Buffer
+	w, err := agelib.Encrypt(&result, ageRecipients...)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting: %w", err)
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("error encrypting: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("error encrypting: %w", err)
+	}
+
+	return result.Bytes(), nil
+}
+
+// Decrypt decrypts armored or binary age encrypted data with any of the identities matching the
+// recipients.
+func Decrypt(ciphertext []byte, identities ...*Identity) ([]byte, error) {
+	var ageIdentities []agelib.Identity
+	for _, identity := range identities {
+		ageIdentities = append(ageIdentities, identity)
+	}
+
+	var src io.Reader = bytes.NewReader(ciphertext)
+	if IsArmored(ciphertext) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(ciphertext)))
+	}
+
+	r, err := agelib.Decrypt(src, ageIdentities...)
+	if err != nil {
+		var noMatchErr *agelib.NoIdentityMatchError
+		if errors.As(err, &noMatchErr) {
+			return nil, ErrNoMatchingIdentity
+		}
+
+		return nil, fmt.Errorf("error decrypting: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,13 @@
+package age_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAge(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Age Suite")
+}
@@ -0,0 +1,117 @@
+package age_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"strings"
+
+	agetest "c2sp.org/CCTV/age"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/age"
+)
+
+var _ = Describe("age", func() {
+	var identity, otherIdentity *age.Identity
+
+	BeforeEach(func() {
+		var err error
+		identity, err = age.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+
+		otherIdentity, err = age.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("encrypts and decrypts",
+		func(plaintext []byte, armored bool) {
+			encrypted, err := age.Encrypt(plaintext, otherIdentity.Recipient(), identity.Recipient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(age.IsEncrypted(encrypted)).To(BeTrue())
+
+			if armored {
+				encrypted = age.Armor(encrypted)
+				Expect(age.IsArmored(encrypted)).To(BeTrue())
+				Expect(age.IsEncrypted(encrypted)).To(BeTrue())
+			}
+
+			decrypted, err := age.Decrypt(encrypted, identity)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+		},
+		Entry("empty data", []byte{}, false),
+		Entry("short data", []byte("secret"), false),
+		Entry("short armored data", []byte("secret"), true),
+		Entry("data longer than a chunk", bytes.Repeat([]byte("secret"), 20000), false),
+		Entry("armored data longer than a chunk", bytes.Repeat([]byte("secret"), 20000), true),
+	)
+
+	It("fails to decrypt with a non-matching identity", func() {
+		encrypted, err := age.Encrypt([]byte("secret"), identity.Recipient())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = age.Decrypt(encrypted, otherIdentity)
+		Expect(err).To(MatchError(age.ErrNoMatchingIdentity))
+	})
+
+	It("parses identity files", func() {
+		identities, err := age.ParseIdentities([]byte("# created: 2024-01-01\n# public key: " + identity.Recipient().String() + "\n" + identity.String() + "\n\n" + otherIdentity.String() + "\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identities).To(HaveLen(2))
+		Expect(identities[0].String()).To(Equal(identity.String()))
+		Expect(identities[1].String()).To(Equal(otherIdentity.String()))
+
+		_, err = age.ParseIdentities([]byte("# no identities\n"))
+		Expect(err).To(HaveOccurred())
+
+		_, err = age.ParseIdentities([]byte(identity.Recipient().String() + "\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 1")))
+	})
+
+	It("decrypts the X25519 files of the age test vectors", func() {
+		vectors, err := fs.ReadDir(agetest.Vectors, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		var checked int
+		for _, vector := range vectors {
+			data, err := fs.ReadFile(agetest.Vectors, vector.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			header, file, found := bytes.Cut(data, []byte("\n\n"))
+			Expect(found).To(BeTrue(), vector.Name())
+
+			fields := map[string]string{}
+			for _, line := range strings.Split(string(header), "\n") {
+				key, value, _ := strings.Cut(line, ": ")
+				fields[key] = value
+			}
+
+			if fields["identity"] == "" {
+				continue
+			}
+
+			identities, err := age.ParseIdentities([]byte(fields["identity"]))
+			Expect(err).NotTo(HaveOccurred(), vector.Name())
+
+			plaintext, err := age.Decrypt(file, identities...)
+			switch fields["expect"] {
+			case "success":
+				Expect(err).NotTo(HaveOccurred(), vector.Name())
+
+				sum := sha256.Sum256(plaintext)
+				Expect(hex.EncodeToString(sum[:])).To(Equal(fields["payload"]), vector.Name())
+			case "no match":
+				Expect(err).To(MatchError(age.ErrNoMatchingIdentity), vector.Name())
+			default:
+				Expect(err).To(HaveOccurred(), vector.Name())
+			}
+
+			checked++
+		}
+
+		Expect(checked).To(BeNumerically(">", 50))
+	})
+})
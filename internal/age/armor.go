@@ -0,0 +1,35 @@
+package age
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"filippo.io/age/armor"
+)
+
+func IsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header))
+}
+
+// Armor returns the binary age encrypted data in the PEM-like ASCII armor, the way it is stored in
+// text files.
+func Armor(data []byte) []byte {
+	var result bytes.Buffer
+	w := armor.NewWriter(&result)
+	// Writes to a bytes.Buffer don't fail.
+	w.Write(data)
+	w.Close()
+	result.WriteString("\n")
+
+	return result.Bytes()
+}
+
+func Dearmor(data []byte) ([]byte, error) {
+	result, err := io.ReadAll(armor.NewReader(bytes.NewReader(bytes.TrimSpace(data))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age armor: %w", err)
+	}
+
+	return result, nil
+}
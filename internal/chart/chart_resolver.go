@@ -0,0 +1,181 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/downloader"
+	"github.com/werf/3p-helm/pkg/getter"
+	"github.com/werf/3p-helm/pkg/registry"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	resolvedChartArchiveDirName = "chart-archive"
+	resolvedChartDirName        = "chart"
+)
+
+type ResolveChartOptions struct {
+	Getters          getter.Providers
+	Keyring          string
+	Out              io.Writer
+	PlainHTTP        bool
+	RegistryClient   *registry.Client
+	RepositoryCache  string
+	RepositoryConfig string
+	TempDirPath      string
+	Verify           bool
+}
+
+// ResolveChart returns the path to the chart directory. Chart directories are returned as is.
+// Local chart archives, chart archives by "http(s)://" URLs and "oci://" references (optionally
+// pinned by digest, e.g. "oci://registry.example.com/charts/app@sha256:...") are downloaded and
+// unpacked into the temporary directory first.
+func ResolveChart(ctx context.Context, chartPath string, opts ResolveChartOptions) (string, error) {
+	var archivePath string
+	switch {
+	case registry.IsOCI(chartPath), strings.HasPrefix(chartPath, "http://"), strings.HasPrefix(chartPath, "https://"):
+		var err error
+		archivePath, err = downloadChartArchive(ctx, chartPath, opts)
+		if err != nil {
+			return "", fmt.Errorf("error downloading chart %q: %w", chartPath, err)
+		}
+	default:
+		if stat, err := os.Stat(chartPath); err != nil || stat.IsDir() {
+			return chartPath, nil
+		}
+
+		archivePath = chartPath
+
+		if opts.Verify {
+			log.Chart.Debug(ctx, "Verifying provenance of chart archive %q", archivePath)
+			if _, err := downloader.VerifyChart(archivePath, opts.Keyring); err != nil {
+				return "", fmt.Errorf("error verifying provenance of chart archive %q: %w", archivePath, err)
+			}
+		}
+	}
+
+	chartDir, err := expandChartArchive(ctx, archivePath, filepath.Join(opts.TempDirPath, resolvedChartDirName))
+	if err != nil {
+		return "", fmt.Errorf("error unpacking chart archive %q: %w", archivePath, err)
+	}
+
+	return chartDir, nil
+}
+
+func downloadChartArchive(ctx context.Context, ref string, opts ResolveChartOptions) (string, error) {
+	destDir := filepath.Join(opts.TempDirPath, resolvedChartArchiveDirName)
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating directory %q: %w", destDir, err)
+	}
+
+	if registry.IsOCI(ref) {
+		if repo, digest, found := strings.Cut(ref, "@"); found {
+			return pullChartArchiveByDigest(ctx, repo, digest, destDir, opts)
+		}
+	}
+
+	var version string
+	if registry.IsOCI(ref) {
+		ref, version = splitOCIRefTag(ref)
+	}
+
+	verify := downloader.VerifyNever
+	if opts.Verify {
+		verify = downloader.VerifyAlways
+	}
+
+	chartDownloader := &downloader.ChartDownloader{
+		Out:     opts.Out,
+		Verify:  verify,
+		Keyring: opts.Keyring,
+		Getters: opts.Getters,
+		Options: []getter.Option{
+			getter.WithRegistryClient(opts.RegistryClient),
+			getter.WithPlainHTTP(opts.PlainHTTP),
+		},
+		RegistryClient:   opts.RegistryClient,
+		RepositoryConfig: opts.RepositoryConfig,
+		RepositoryCache:  opts.RepositoryCache,
+	}
+
+	log.Chart.Debug(ctx, "Downloading chart %q", ref)
+	archivePath, _, err := chartDownloader.DownloadTo(ref, version, destDir)
+	if err != nil {
+		return "", err
+	}
+
+	return archivePath, nil
+}
+
+// The chart downloader resolves digests to tags, so charts pinned by digest are pulled from the
+// registry directly.
+func pullChartArchiveByDigest(ctx context.Context, repo, digest, destDir string, opts ResolveChartOptions) (string, error) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("unsupported digest %q, only sha256 digests are supported", digest)
+	}
+
+	repo, _ = splitOCIRefTag(repo)
+	ref := strings.TrimPrefix(repo, fmt.Sprintf("%s://", registry.OCIScheme)) + "@" + digest
+
+	log.Chart.Debug(ctx, "Pulling chart %q", ref)
+	result, err := opts.RegistryClient.Pull(ref, registry.PullOptWithProv(opts.Verify))
+	if err != nil {
+		return "", fmt.Errorf("error pulling chart %q: %w", ref, err)
+	}
+
+	archivePath := filepath.Join(destDir, fmt.Sprintf("%s-%s.tgz", result.Chart.Meta.Name, result.Chart.Meta.Version))
+	if err := os.WriteFile(archivePath, result.Chart.Data, 0o644); err != nil {
+		return "", fmt.Errorf("error saving chart archive %q: %w", archivePath, err)
+	}
+
+	if opts.Verify {
+		if err := os.WriteFile(archivePath+".prov", result.Prov.Data, 0o644); err != nil {
+			return "", fmt.Errorf("error saving provenance file %q: %w", archivePath+".prov", err)
+		}
+
+		if _, err := downloader.VerifyChart(archivePath, opts.Keyring); err != nil {
+			return "", fmt.Errorf("error verifying provenance of chart %q: %w", ref, err)
+		}
+	}
+
+	return archivePath, nil
+}
+
+// Splits "oci://registry:5000/charts/app:1.2.3" into "oci://registry:5000/charts/app" and "1.2.3".
+func splitOCIRefTag(ref string) (string, string) {
+	lastSlash := strings.LastIndex(ref, "/")
+	if lastColon := strings.LastIndex(ref, ":"); lastColon > lastSlash {
+		return ref[:lastColon], ref[lastColon+1:]
+	}
+
+	return ref, ""
+}
+
+func expandChartArchive(ctx context.Context, archivePath, destDir string) (string, error) {
+	if err := os.RemoveAll(destDir); err != nil {
+		return "", fmt.Errorf("error cleaning up directory %q: %w", destDir, err)
+	}
+
+	log.Chart.Debug(ctx, "Unpacking chart archive %q to %q", archivePath, destDir)
+	if err := chartutil.ExpandFile(destDir, archivePath); err != nil {
+		return "", err
+	}
+
+	// The archive contains a single directory named after the chart.
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		return "", fmt.Errorf("error reading directory %q: %w", destDir, err)
+	}
+
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("expected a single chart directory in the archive, got %d entries", len(entries))
+	}
+
+	return filepath.Join(destDir, entries[0].Name()), nil
+}
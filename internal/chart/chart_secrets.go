@@ -0,0 +1,543 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chart/loader"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/downloader"
+	"github.com/werf/3p-helm/pkg/werf/chartextender"
+	"github.com/werf/3p-helm/pkg/werf/file"
+	"github.com/werf/3p-helm/pkg/werf/secrets"
+	"github.com/werf/3p-helm/pkg/werf/secrets/runtimedata"
+	"github.com/werf/common-go/pkg/secret"
+	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/werf/common-go/pkg/secretvalues"
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/nelm/internal/age"
+	"github.com/werf/nelm/internal/sops"
+	"github.com/werf/nelm/pkg/log"
+	nelmsecret "github.com/werf/nelm/pkg/secret"
+)
+
+var _ file.ChartFileReader = (*secretsChartFileReader)(nil)
+
+// The chart loader only knows the werf secret format and only takes the werf secret key from the
+// environment or key files. So secret values files and secret files encrypted with age, or with
+// the werf secret key passed explicitly, are hidden from the loader and decrypted here instead.
+// Secrets of packaged subcharts are left to the loader.
+type secretsChartFileReader struct {
+	chartDir   string
+	identities []*age.Identity
+	// Nil if the werf secret key is not passed explicitly.
+	werfEncoder *secret.YamlEncoder
+	// By the path of the chart relative to the chart directory, e.g. "" for the chart itself or
+	// "charts/redis" for its subchart.
+	chartSecrets       map[string]*chartSecrets
+	customSecretValues []map[string]interface{}
+	// Secrets of the chart are left to the loader, and custom secret values files are read like the
+	// loader does, which doesn't decrypt them then.
+	decryptionDisabled         bool
+	withoutDefaultSecretValues bool
+}
+
+type secretsChartFileReaderOptions struct {
+	WithoutDefaultSecretValues bool
+}
+
+type chartSecrets struct {
+	defaultValues map[string]interface{}
+	files         map[string]string
+}
+
+func newSecretsChartFileReader(chartDir string, identities []*age.Identity, werfEncoder *secret.YamlEncoder, opts secretsChartFileReaderOptions) (*secretsChartFileReader, error) {
+	absChartDir, err := filepath.Abs(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path of %q: %w", chartDir, err)
+	}
+
+	return &secretsChartFileReader{
+		chartDir:                   absChartDir,
+		identities:                 identities,
+		werfEncoder:                werfEncoder,
+		chartSecrets:               map[string]*chartSecrets{},
+		decryptionDisabled:         secrets_manager.DisableSecretsDecryption,
+		withoutDefaultSecretValues: opts.WithoutDefaultSecretValues,
+	}, nil
+}
+
+// loadChart loads the chart from the chart directory like loader.LoadDir does, but reads the files
+// of the chart and of its dependencies with the reader itself.
+func (r *secretsChartFileReader) loadChart(ctx context.Context) (*chart.Chart, error) {
+	chartFiles, err := r.LoadChartDir(ctx, r.chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart dir %q: %w", r.chartDir, err)
+	}
+
+	chartTreeFiles, err := loader.LoadChartDependencies(ctx, r.LoadChartDir, r.chartDir, chartFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart dependencies of %q: %w", r.chartDir, err)
+	}
+
+	files := make([]*loader.BufferedFile, 0, len(chartTreeFiles))
+	for _, f := range chartTreeFiles {
+		files = append(files, &loader.BufferedFile{Name: f.Name, Data: f.Data})
+	}
+
+	return loader.LoadFiles(files, chart.LoadOptions{})
+}
+
+func (r *secretsChartFileReader) LocateChart(ctx context.Context, name string) (string, error) {
+	return name, nil
+}
+
+func (r *secretsChartFileReader) ReadChartFile(ctx context.Context, filePath string) ([]byte, error) {
+	return os.ReadFile(filePath)
+}
+
+func (r *secretsChartFileReader) LoadChartDir(ctx context.Context, dir string) ([]*file.ChartExtenderBufferedFile, error) {
+	files, err := loader.GetFilesFromLocalFilesystem(dir)
+	if err != nil {
+		return nil, fmt.Errorf("load files from filesystem: %w", err)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path of %q: %w", dir, err)
+	}
+	isRootChart := absDir == r.chartDir && !r.decryptionDisabled
+
+	var result []*file.ChartExtenderBufferedFile
+	for _, f := range files {
+		if isRootChart {
+			if taken, err := r.takeChartFile(ctx, dir, f.Name, f.Data); err != nil {
+				return nil, err
+			} else if taken {
+				continue
+			}
+		}
+
+		result = append(result, &file.ChartExtenderBufferedFile{Name: f.Name, Data: f.Data})
+	}
+
+	return result, nil
+}
+
+// Decrypts the file if it's a secret values file or a secret file of the chart or of its
+// subcharts, unless the file is left to the loader.
+func (r *secretsChartFileReader) takeChartFile(ctx context.Context, dir, name string, data []byte) (bool, error) {
+	chartPath, nameInChart := splitSubchartPath(name)
+	filePath := filepath.Join(dir, name)
+
+	switch {
+	case nameInChart == secrets.DefaultSecretValuesFileName && !r.withoutDefaultSecretValues:
+		values, err := r.decryptValues(ctx, filePath, data)
+		if err != nil || values == nil {
+			return false, err
+		}
+
+		r.secretsOf(chartPath).defaultValues = values
+	case util.IsSubpathOfBasePath(secrets.SecretDirName, nameInChart):
+		decryptedData, decrypted, err := r.decryptFile(ctx, filePath, data)
+		if err != nil || !decrypted {
+			return false, err
+		}
+
+		r.secretsOf(chartPath).files[filepath.ToSlash(util.GetRelativeToBaseFilepath(secrets.SecretDirName, nameInChart))] = decryptedData
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Decrypts custom secret values files. Unlike the secrets of the chart, none of them are left to
+// the chart loader, the ones it would decrypt are decrypted like the loader does.
+func (r *secretsChartFileReader) takeCustomSecretValuesFiles(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read custom secret values file %q: %w", path, err)
+		}
+
+		var values map[string]interface{}
+		if !r.decryptionDisabled {
+			values, err = r.decryptValues(ctx, path, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		if values == nil {
+			values, err = decryptValuesLikeLoader(ctx, path, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		r.customSecretValues = append(r.customSecretValues, values)
+	}
+
+	return nil
+}
+
+// decryptValuesLikeLoader decrypts the werf secret values file with the key from the environment
+// or key files, or leaves the values encrypted if secrets decryption is disabled.
+func decryptValuesLikeLoader(ctx context.Context, path string, data []byte) (map[string]interface{}, error) {
+	encoder, err := secrets_manager.Manager.GetYamlEncoder(ctx, secrets.SecretsWorkingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets yaml encoder: %w", err)
+	}
+
+	decryptedData, err := encoder.DecryptYamlData(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret values file %q: %w", path, err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(decryptedData, &values); err != nil {
+		return nil, fmt.Errorf("error unmarshaling secret values file %q: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// Returns nil values if the file is left to the loader.
+func (r *secretsChartFileReader) decryptValues(ctx context.Context, path string, data []byte) (map[string]interface{}, error) {
+	var (
+		decryptedData []byte
+		err           error
+	)
+	switch {
+	case sops.IsEncrypted(data):
+		if len(r.identities) == 0 {
+			return nil, fmt.Errorf("secret values file %q is encrypted with age, but no age identities specified", path)
+		}
+
+		log.Chart.Debug(ctx, "Decrypting age encrypted secret values file %q", path)
+		decryptedData, err = sops.DecryptValues(data, r.identities)
+	case nelmsecret.IsPartiallyEncryptedValues(data):
+		// The loader would try to decrypt the plaintext values too.
+		if secrets.DisableSecrets {
+			return nil, nil
+		}
+
+		encoder := r.werfEncoder
+		if encoder == nil {
+			encoder, err = secrets_manager.Manager.GetYamlEncoder(ctx, secrets.SecretsWorkingDir)
+			if err != nil {
+				return nil, fmt.Errorf("error getting secrets yaml encoder: %w", err)
+			}
+		}
+
+		log.Chart.Debug(ctx, "Decrypting partially encrypted secret values file %q", path)
+		decryptedData, err = nelmsecret.DecryptPartiallyEncryptedValues(data, encoder.Decrypt)
+	case r.werfEncoder != nil:
+		log.Chart.Debug(ctx, "Decrypting secret values file %q", path)
+		decryptedData, err = r.werfEncoder.DecryptYamlData(data)
+	default:
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret values file %q: %w", path, err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(decryptedData, &values); err != nil {
+		return nil, fmt.Errorf("error unmarshaling secret values file %q: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// Returns false if the file is left to the loader.
+func (r *secretsChartFileReader) decryptFile(ctx context.Context, path string, data []byte) (string, bool, error) {
+	var (
+		decryptedData []byte
+		err           error
+	)
+	switch {
+	case age.IsEncrypted(data):
+		if len(r.identities) == 0 {
+			return "", false, fmt.Errorf("secret file %q is encrypted with age, but no age identities specified", path)
+		}
+
+		log.Chart.Debug(ctx, "Decrypting age encrypted secret file %q", path)
+		decryptedData, err = age.Decrypt(data, r.identities...)
+	case r.werfEncoder != nil:
+		log.Chart.Debug(ctx, "Decrypting secret file %q", path)
+		decryptedData, err = r.werfEncoder.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
+	default:
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("error decrypting secret file %q: %w", path, err)
+	}
+
+	return string(decryptedData), true, nil
+}
+
+func (r *secretsChartFileReader) secretsOf(chartPath string) *chartSecrets {
+	if _, found := r.chartSecrets[chartPath]; !found {
+		r.chartSecrets[chartPath] = &chartSecrets{files: map[string]string{}}
+	}
+
+	return r.chartSecrets[chartPath]
+}
+
+func (r *secretsChartFileReader) empty() bool {
+	return len(r.chartSecrets) == 0 && len(r.customSecretValues) == 0
+}
+
+// attachTo adds the decrypted secrets to the runtime data of the loaded chart and its subcharts.
+func (r *secretsChartFileReader) attachTo(legacyChart *chart.Chart) error {
+	if len(r.customSecretValues) > 0 {
+		r.secretsOf("")
+	}
+
+	for chartPath, chartSecrets := range r.chartSecrets {
+		targetChart := findSubchart(legacyChart, chartPath)
+		if targetChart == nil {
+			return fmt.Errorf("subchart at %q of chart %q has secrets, but the subchart name doesn't match its directory", chartPath, legacyChart.Name())
+		}
+
+		var customSecretValues []map[string]interface{}
+		if chartPath == "" {
+			customSecretValues = r.customSecretValues
+		}
+
+		targetChart.SecretsRuntimeData = wrapRuntimeData(targetChart.SecretsRuntimeData, chartSecrets, customSecretValues)
+	}
+
+	return nil
+}
+
+// splitSubchartPath splits the path of the chart file into the path of the subchart the file
+// belongs to, e.g. "charts/redis/secret/password" into "charts/redis" and "secret/password".
+func splitSubchartPath(name string) (chartPath, nameInChart string) {
+	nameInChart = filepath.ToSlash(name)
+	for {
+		subchartName, rest, found := strings.Cut(strings.TrimPrefix(nameInChart, "charts/"), "/")
+		if !strings.HasPrefix(nameInChart, "charts/") || !found {
+			return chartPath, nameInChart
+		}
+
+		chartPath = strings.TrimPrefix(chartPath+"/charts/"+subchartName, "/")
+		nameInChart = rest
+	}
+}
+
+// findSubchart returns nil if the subchart is not found.
+func findSubchart(legacyChart *chart.Chart, chartPath string) *chart.Chart {
+	for _, subchartName := range strings.Split(chartPath, "/") {
+		if subchartName == "" || subchartName == "charts" {
+			continue
+		}
+
+		var found bool
+		for _, dep := range legacyChart.Dependencies() {
+			if dep.Name() == subchartName {
+				legacyChart = dep
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+	}
+
+	return legacyChart
+}
+
+// Secret values decrypted here take precedence over the ones decrypted by the loader, custom secret
+// values files over the default one, and later custom files over earlier ones.
+func wrapRuntimeData(data runtimedata.RuntimeData, chartSecrets *chartSecrets, customSecretValues []map[string]interface{}) runtimedata.RuntimeData {
+	values := data.GetDecryptedSecretValues()
+	for _, decryptedValues := range append([]map[string]interface{}{chartSecrets.defaultValues}, customSecretValues...) {
+		if decryptedValues != nil {
+			values = chartutil.CoalesceTables(decryptedValues, values)
+		}
+	}
+
+	files := map[string]string{}
+	for path, data := range data.GetDecryptedSecretFilesData() {
+		files[path] = data
+	}
+
+	toMask := data.GetSecretValuesToMask()
+	for path, data := range chartSecrets.files {
+		files[path] = data
+		toMask = append(toMask, data)
+	}
+	toMask = append(toMask, secretvalues.ExtractSecretValuesFromMap(values)...)
+
+	return &decryptedSecretsRuntimeData{
+		RuntimeData:              data,
+		decryptedSecretValues:    values,
+		decryptedSecretFilesData: files,
+		secretValuesToMask:       toMask,
+	}
+}
+
+type decryptedSecretsRuntimeData struct {
+	runtimedata.RuntimeData
+
+	decryptedSecretValues    map[string]interface{}
+	decryptedSecretFilesData map[string]string
+	secretValuesToMask       []string
+}
+
+func (d *decryptedSecretsRuntimeData) GetDecryptedSecretValues() map[string]interface{} {
+	return d.decryptedSecretValues
+}
+
+func (d *decryptedSecretsRuntimeData) GetDecryptedSecretFilesData() map[string]string {
+	return d.decryptedSecretFilesData
+}
+
+func (d *decryptedSecretsRuntimeData) GetSecretValuesToMask() []string {
+	return d.secretValuesToMask
+}
+
+type loadChartOptions struct {
+	AgeIdentityPath        string
+	ChartAppVersion        string
+	DefaultChartAPIVersion string
+	DefaultChartName       string
+	DefaultChartVersion    string
+	DependencyManager      *downloader.Manager
+	SecretKey              string
+	SecretKeyIgnore        bool
+	// Custom secret values files, which override the default secret values file of the chart.
+	SecretValuesFiles          []string
+	SecretWorkDir              string
+	WithoutDefaultSecretValues bool
+	WithoutDefaultValues       bool
+}
+
+func newLoadChartOptions(opts ChartTreeOptions) loadChartOptions {
+	return loadChartOptions{
+		AgeIdentityPath:            opts.AgeIdentityPath,
+		ChartAppVersion:            opts.ChartAppVersion,
+		DefaultChartAPIVersion:     opts.DefaultChartAPIVersion,
+		DefaultChartName:           opts.DefaultChartName,
+		DefaultChartVersion:        opts.DefaultChartVersion,
+		DependencyManager:          opts.DependencyManager,
+		SecretKey:                  opts.SecretKey,
+		SecretKeyIgnore:            opts.SecretKeyIgnore,
+		SecretValuesFiles:          opts.SecretValuesFiles,
+		SecretWorkDir:              opts.SecretWorkDir,
+		WithoutDefaultSecretValues: opts.DefaultSecretValuesDisable,
+		WithoutDefaultValues:       opts.DefaultValuesDisable,
+	}
+}
+
+// The chart loader and the werf secrets manager take their options from package globals. They are
+// set by setLoaderGlobals and used only under this lock.
+var loaderGlobalsLock sync.Mutex
+
+// setLoaderGlobals must be called with loaderGlobalsLock held.
+func setLoaderGlobals(chartPath string, opts loadChartOptions) {
+	chartextender.DefaultChartAPIVersion = opts.DefaultChartAPIVersion
+	chartextender.DefaultChartName = opts.DefaultChartName
+	chartextender.DefaultChartVersion = opts.DefaultChartVersion
+	chartextender.ChartAppVersion = opts.ChartAppVersion
+	loader.WithoutDefaultSecretValues = opts.WithoutDefaultSecretValues
+	loader.WithoutDefaultValues = opts.WithoutDefaultValues
+	secrets.CoalesceTablesFunc = chartutil.CoalesceTables
+	secrets.SecretsWorkingDir = opts.SecretWorkDir
+	secrets.ChartDir = chartPath
+	secrets_manager.DisableSecretsDecryption = opts.SecretKeyIgnore
+
+	if opts.DependencyManager != nil {
+		loader.SetChartPathFunc = opts.DependencyManager.SetChartPath
+		loader.DepsBuildFunc = opts.DependencyManager.Build
+	} else {
+		loader.SetChartPathFunc = nil
+		loader.DepsBuildFunc = nil
+	}
+}
+
+// loadChart loads the chart like loader.Load does, but decrypts secrets encrypted with age or with
+// the passed werf secret key without the loader, as well as all custom secret values files. Charts
+// are loaded one at a time, since the loader is configured with package globals.
+func loadChart(ctx context.Context, chartPath string, opts loadChartOptions) (*chart.Chart, error) {
+	loaderGlobalsLock.Lock()
+	defer loaderGlobalsLock.Unlock()
+
+	setLoaderGlobals(chartPath, opts)
+
+	if secrets.DisableSecrets {
+		return loader.Load(chartPath)
+	}
+
+	var identities []*age.Identity
+	if opts.AgeIdentityPath != "" {
+		data, err := os.ReadFile(opts.AgeIdentityPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading age identity file %q: %w", opts.AgeIdentityPath, err)
+		}
+
+		identities, err = age.ParseIdentities(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age identity file %q: %w", opts.AgeIdentityPath, err)
+		}
+	}
+
+	var werfEncoder *secret.YamlEncoder
+	if opts.SecretKey != "" {
+		encoder, err := secret.NewAesEncoder([]byte(opts.SecretKey))
+		if err != nil {
+			return nil, fmt.Errorf("error checking secret key: %w", err)
+		}
+
+		werfEncoder = secret.NewYamlEncoder(encoder)
+	}
+
+	reader, err := newSecretsChartFileReader(chartPath, identities, werfEncoder, secretsChartFileReaderOptions{
+		WithoutDefaultSecretValues: opts.WithoutDefaultSecretValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reader.takeCustomSecretValuesFiles(ctx, opts.SecretValuesFiles); err != nil {
+		return nil, err
+	}
+
+	var legacyChart *chart.Chart
+	if stat, err := os.Stat(chartPath); err == nil && stat.IsDir() && chart.CurrentChartType == chart.ChartTypeChart {
+		legacyChart, err = reader.loadChart(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		legacyChart, err = loader.Load(chartPath)
+		if err != nil || legacyChart == nil {
+			return legacyChart, err
+		}
+	}
+
+	if reader.empty() {
+		return legacyChart, nil
+	}
+
+	if err := reader.attachTo(legacyChart); err != nil {
+		return nil, err
+	}
+
+	return legacyChart, nil
+}
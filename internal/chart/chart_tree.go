@@ -1,48 +1,73 @@
 package chart
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"path/filepath"
 	"sort"
 	"strings"
-	"unicode"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/client-go/discovery"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
 	"github.com/werf/3p-helm/pkg/chart"
-	"github.com/werf/3p-helm/pkg/chart/loader"
 	"github.com/werf/3p-helm/pkg/chartutil"
+	helmcli "github.com/werf/3p-helm/pkg/cli"
 	"github.com/werf/3p-helm/pkg/cli/values"
 	"github.com/werf/3p-helm/pkg/downloader"
 	"github.com/werf/3p-helm/pkg/getter"
+	"github.com/werf/3p-helm/pkg/postrender"
+	"github.com/werf/3p-helm/pkg/registry"
+	helmrelease "github.com/werf/3p-helm/pkg/release"
 	"github.com/werf/3p-helm/pkg/releaseutil"
 	"github.com/werf/nelm/internal/common"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/matcher"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace string, revision int, deployType common.DeployType, actionConfig *action.Configuration, opts ChartTreeOptions) (*ChartTree, error) {
-	valOpts := &values.Options{
-		StringValues: opts.StringSetValues,
-		Values:       opts.SetValues,
-		FileValues:   opts.FileValues,
-		ValueFiles:   opts.ValuesFiles,
+	getters := opts.Getters
+	if getters == nil {
+		getters = getter.All(helmcli.New())
+	}
+
+	localValuesFiles, err := fetchRemoteValuesFiles(ctx, opts.ValuesFiles, getters, opts.RegistryClient, opts.TempDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading remote values files for chart tree at %q: %w", chartPath, err)
 	}
 
-	getters := getter.All(helm_v3.Settings)
+	valOpts := &values.Options{
+		StringValues:  opts.StringSetValues,
+		Values:        opts.SetValues,
+		FileValues:    opts.FileValues,
+		ValueFiles:    localValuesFiles,
+		JSONValues:    opts.JSONSetValues,
+		LiteralValues: opts.LiteralSetValues,
+	}
 
-	log.Default.Debug(ctx, "Merging values for chart tree at %q", chartPath)
+	log.Chart.Debug(ctx, "Merging values for chart tree at %q", chartPath)
 	releaseValues, err := valOpts.MergeValues(getters)
 	if err != nil {
 		return nil, fmt.Errorf("error merging values for chart tree at %q: %w", chartPath, err)
 	}
 
-	log.Default.Debug(ctx, "Loading chart at %q", chartPath)
-	legacyChart, err := loader.Load(chartPath)
+	envVals := envValues(opts.EnvValuesPrefix, opts.Env)
+	if len(envVals) > 0 {
+		log.Chart.Debug(ctx, "Merging %d values from environment variables with prefix %q for chart tree at %q", len(envVals), opts.EnvValuesPrefix, chartPath)
+		releaseValues = chartutil.MergeTables(releaseValues, mergeEnvValues(envVals))
+	}
+
+	log.Chart.Debug(ctx, "Loading chart at %q", chartPath)
+	legacyChart, err := loadChart(ctx, chartPath, newLoadChartOptions(opts))
 	if err != nil {
 		var e *downloader.ErrRepoNotFound
 		if errors.As(err, &e) {
@@ -65,7 +90,7 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 	}
 
 	if legacyChart.Metadata.Deprecated {
-		log.Default.Warn(ctx, `Chart "%s:%s" is deprecated`, legacyChart.Name(), legacyChart.Metadata.Version)
+		log.Chart.Warn(ctx, `Chart "%s:%s" is deprecated`, legacyChart.Name(), legacyChart.Metadata.Version)
 	}
 
 	caps, err := actionConfig.GetCapabilities()
@@ -81,30 +106,106 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 		isUpgrade = false
 	}
 
-	log.Default.Debug(ctx, "Rendering values for chart at %q", chartPath)
-	values, err := chartutil.ToRenderValues(legacyChart, releaseValues, chartutil.ReleaseOptions{
-		Name:      releaseName,
-		Namespace: releaseNamespace,
-		Revision:  revision,
-		IsInstall: !isUpgrade,
-		IsUpgrade: isUpgrade,
-	}, caps)
-	if err != nil {
+	if !opts.SkipSchemaValidation {
+		log.Chart.Debug(ctx, "Validating values for chart at %q", chartPath)
+		coalescedValues, err := chartutil.CoalesceValues(legacyChart, releaseValues)
+		if err != nil {
+			return nil, fmt.Errorf("error coalescing values for chart %q: %w", legacyChart.Name(), err)
+		}
+
+		if err := validateValuesSchema(legacyChart, coalescedValues, newValuesSources(opts, localValuesFiles, envVals)); err != nil {
+			return nil, fmt.Errorf("error validating values for chart %q: %w", legacyChart.Name(), err)
+		}
+	}
+
+	log.Chart.Debug(ctx, "Rendering values for chart at %q", chartPath)
+	var values chartutil.Values
+	// Values are already validated above, with more detailed errors than Helm gives.
+	if err := withoutSchemas(legacyChart, func() error {
+		var err error
+		values, err = chartutil.ToRenderValues(legacyChart, releaseValues, chartutil.ReleaseOptions{
+			Name:      releaseName,
+			Namespace: releaseNamespace,
+			Revision:  revision,
+			IsInstall: !isUpgrade,
+			IsUpgrade: isUpgrade,
+		}, caps)
+		return err
+	}); err != nil {
 		return nil, fmt.Errorf("error building values for chart %q: %w", legacyChart.Name(), err)
 	}
 
 	finalValues := values.AsMap()
 	hasClusterAccess := opts.Mapper != nil
 
-	log.Default.Debug(ctx, "Rendering resources for chart at %q", chartPath)
-	legacyHookResources, generalManifestsBuf, notes, err := actionConfig.RenderResources(legacyChart, values, "", "", opts.SubNotes, false, false, nil, hasClusterAccess, false)
-	if err != nil {
-		log.Default.Debug(ctx, generalManifestsBuf.String())
+	var (
+		legacyHookResources []*helmrelease.Hook
+		generalManifestsBuf *bytes.Buffer
+		renderedNotes       string
+	)
+
+	var renderCacheKeyHash string
+	if opts.RenderCacheDir != "" {
+		if reason := renderCacheBypassReason(legacyChart, values, hasClusterAccess); reason != "" {
+			log.Chart.Debug(ctx, "Not using render cache for chart at %q: %s", chartPath, reason)
+		} else if renderCacheKeyHash, err = renderCacheKey(legacyChart, values, opts.SubNotes, opts.StrictTemplates, hasClusterAccess); err != nil {
+			return nil, fmt.Errorf("error computing render cache key for chart %q: %w", legacyChart.Name(), err)
+		}
+	}
+
+	var (
+		cacheEntry *renderCacheEntry
+		cached     bool
+	)
+	if renderCacheKeyHash != "" {
+		cacheEntry, cached = loadRenderCacheEntry(ctx, opts.RenderCacheDir, renderCacheKeyHash)
+	}
+
+	if cached {
+		log.Chart.Debug(ctx, "Using cached rendering result %q for chart at %q", renderCacheKeyHash, chartPath)
+		legacyHookResources = cacheEntry.Hooks
+		generalManifestsBuf = bytes.NewBufferString(cacheEntry.Manifests)
+		renderedNotes = cacheEntry.Notes
+	} else {
+		if opts.StrictTemplates {
+			log.Chart.Debug(ctx, "Rendering templates in strict mode for chart at %q", chartPath)
+			if err := validateStrictTemplates(ctx, legacyChart, values, actionConfig, hasClusterAccess); err != nil {
+				return nil, fmt.Errorf("error rendering templates of chart %q: %w", legacyChart.Name(), err)
+			}
+		}
 
-		return nil, fmt.Errorf("error rendering resources for chart %q: %w", legacyChart.Name(), err)
+		log.Chart.Debug(ctx, "Rendering resources for chart at %q", chartPath)
+		if err := withNotesMarkers(legacyChart, func() error {
+			var err error
+			legacyHookResources, generalManifestsBuf, renderedNotes, err = actionConfig.RenderResources(legacyChart, values, "", "", opts.SubNotes, false, false, nil, hasClusterAccess, false)
+			return err
+		}); err != nil {
+			log.Chart.Debug(ctx, generalManifestsBuf.String())
+
+			return nil, fmt.Errorf("error rendering resources for chart %q: %w", legacyChart.Name(), err)
+		}
+
+		if renderCacheKeyHash != "" {
+			if err := saveRenderCacheEntry(opts.RenderCacheDir, renderCacheKeyHash, &renderCacheEntry{
+				Hooks:     legacyHookResources,
+				Manifests: generalManifestsBuf.String(),
+				Notes:     renderedNotes,
+			}); err != nil {
+				log.Chart.Warn(ctx, "Unable to cache rendering result for chart %q: %s", legacyChart.Name(), err)
+			}
+		}
 	}
 
-	notes = strings.TrimRightFunc(notes, unicode.IsSpace)
+	notes := splitNotes(renderedNotes)
+
+	// Post-rendered are only non-hook resources, same as in Helm.
+	if opts.PostRenderer != nil {
+		log.Chart.Debug(ctx, "Post-rendering resources for chart at %q", chartPath)
+		generalManifestsBuf, err = opts.PostRenderer.Run(generalManifestsBuf)
+		if err != nil {
+			return nil, fmt.Errorf("error post-rendering resources for chart %q: %w", legacyChart.Name(), err)
+		}
+	}
 
 	var standaloneCRDs []*resource.StandaloneCRD
 	for _, crd := range legacyChart.CRDObjects() {
@@ -114,6 +215,11 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 				DefaultNamespace: releaseNamespace,
 				Mapper:           opts.Mapper,
 			}); err != nil {
+				if opts.OnInvalidManifest != nil {
+					opts.OnInvalidManifest(crd.Filename, err)
+					continue
+				}
+
 				return nil, fmt.Errorf("error constructing standalone CRD for chart at %q: %w", chartPath, err)
 			} else {
 				standaloneCRDs = append(standaloneCRDs, res)
@@ -134,6 +240,11 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 				DiscoveryClient:  opts.DiscoveryClient,
 				FilePath:         hook.Path,
 			}); err != nil {
+				if opts.OnInvalidManifest != nil {
+					opts.OnInvalidManifest(hook.Path, err)
+					continue
+				}
+
 				return nil, fmt.Errorf("error constructing hook resource for chart at %q: %w", chartPath, err)
 			} else {
 				hookResources = append(hookResources, res)
@@ -141,10 +252,6 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 		}
 	}
 
-	sort.SliceStable(hookResources, func(i, j int) bool {
-		return resource.ResourceIDsSortHandler(hookResources[i].ResourceID, hookResources[j].ResourceID)
-	})
-
 	var generalResources []*resource.GeneralResource
 	for _, manifest := range releaseutil.SplitManifests(generalManifestsBuf.String()) {
 		if res, err := resource.NewGeneralResourceFromManifest(manifest, resource.GeneralResourceFromManifestOptions{
@@ -152,16 +259,60 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 			Mapper:           opts.Mapper,
 			DiscoveryClient:  opts.DiscoveryClient,
 		}); err != nil {
+			if opts.OnInvalidManifest != nil {
+				opts.OnInvalidManifest(manifestFilePath(manifest), err)
+				continue
+			}
+
 			return nil, fmt.Errorf("error constructing general resource for chart at %q: %w", chartPath, err)
+		} else if resource.IsHook(res.Unstructured().GetAnnotations()) {
+			// Helm knows only about helm.sh/hook, so hooks with werf.io/hook end up here.
+			hookResources = append(hookResources, resource.NewHookResource(res.Unstructured(), resource.HookResourceOptions{
+				FilePath:         res.FilePath(),
+				DefaultNamespace: releaseNamespace,
+				Mapper:           opts.Mapper,
+				DiscoveryClient:  opts.DiscoveryClient,
+			}))
 		} else {
 			generalResources = append(generalResources, res)
 		}
 	}
 
+	sort.SliceStable(hookResources, func(i, j int) bool {
+		return resource.ResourceIDsSortHandler(hookResources[i].ResourceID, hookResources[j].ResourceID)
+	})
+
 	sort.SliceStable(generalResources, func(i, j int) bool {
 		return resource.ResourceIDsSortHandler(generalResources[i].ResourceID, generalResources[j].ResourceID)
 	})
 
+	if len(opts.ShowOnlyFiles) > 0 {
+		standaloneCRDs, hookResources, generalResources, err = filterResourcesByFiles(ctx, chartPath, legacyChart.Name(), opts.ShowOnlyFiles, standaloneCRDs, hookResources, generalResources)
+		if err != nil {
+			return nil, fmt.Errorf("error filtering resources of chart %q by template files: %w", legacyChart.Name(), err)
+		}
+	}
+
+	var partial bool
+	if !opts.ResourceFilter.Empty() {
+		resourcesCount := len(standaloneCRDs) + len(hookResources) + len(generalResources)
+
+		standaloneCRDs = lo.Filter(standaloneCRDs, func(res *resource.StandaloneCRD, _ int) bool {
+			return opts.ResourceFilter.Match(res.ResourceID, res.Unstructured().GetLabels())
+		})
+		hookResources = lo.Filter(hookResources, func(res *resource.HookResource, _ int) bool {
+			return opts.ResourceFilter.Match(res.ResourceID, res.Unstructured().GetLabels())
+		})
+		generalResources = lo.Filter(generalResources, func(res *resource.GeneralResource, _ int) bool {
+			return opts.ResourceFilter.Match(res.ResourceID, res.Unstructured().GetLabels())
+		})
+
+		if filteredCount := len(standaloneCRDs) + len(hookResources) + len(generalResources); filteredCount < resourcesCount {
+			log.Chart.Debug(ctx, "Filtered out %d of %d resources of chart at %q", resourcesCount-filteredCount, resourcesCount, chartPath)
+			partial = true
+		}
+	}
+
 	return &ChartTree{
 		standaloneCRDs:   standaloneCRDs,
 		hookResources:    hookResources,
@@ -170,27 +321,92 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 		releaseValues:    releaseValues,
 		finalValues:      finalValues,
 		legacyChart:      legacyChart,
+		partial:          partial,
 	}, nil
 }
 
 type ChartTreeOptions struct {
+	// Secret values files and secret files encrypted with age are decrypted with identities from
+	// this file.
+	AgeIdentityPath string
 	Mapper          meta.ResettableRESTMapper
 	DiscoveryClient discovery.CachedDiscoveryInterface
+	ResourceFilter  *matcher.ResourceFilter
+	PostRenderer    postrender.PostRenderer
+	// Used to fetch remote values files. Defaults to getters built from Helm settings taken from the
+	// environment.
+	Getters getter.Providers
+	// Used to fetch values files from OCI registries.
+	RegistryClient *registry.Client
+	// Secret values files and secret files are decrypted with this werf secret key. If not set, the
+	// key is taken from $WERF_SECRET_KEY, the .werf_secret_key file in the secrets working directory
+	// or ~/.werf/global_secret_key.
+	SecretKey string
+	// Custom secret values files, which override the default secret values file of the chart.
+	SecretValuesFiles []string
+	// Don't use the default secret values file of the chart.
+	DefaultSecretValuesDisable bool
+	// Don't use the default values file of the chart.
+	DefaultValuesDisable bool
+	// Used for Chart.yaml fields missing in the chart.
+	DefaultChartAPIVersion string
+	DefaultChartName       string
+	DefaultChartVersion    string
+	// Overrides the app version of the chart.
+	ChartAppVersion string
+	// Downloads the dependencies of the chart if they are missing.
+	DependencyManager *downloader.Manager
+	// Leave werf secrets encrypted instead of decrypting them.
+	SecretKeyIgnore bool
+	// The werf secret key files are looked up in this directory.
+	SecretWorkDir string
+	// Remote values files are downloaded here. Must be private to the action. A new temporary
+	// directory is used if not specified.
+	TempDirPath string
+	// Keep only resources rendered from these template files. Paths are relative to the chart
+	// directory (e.g. "templates/app.yaml" or "charts/redis/templates/master.yaml"), or relative to
+	// the current directory or absolute if they point inside the chart directory, and can be glob
+	// patterns.
+	ShowOnlyFiles   []string
 	StringSetValues []string
 	SetValues       []string
 	FileValues      []string
 	ValuesFiles     []string
-	SubNotes        bool
+	// Environment variables from Env with this prefix are used as string values, with "__" in the
+	// rest of their names separating keys, e.g. "PREFIX_app__image__tag" for "app.image.tag". They
+	// have the lowest priority: values files and --set* values override them.
+	EnvValuesPrefix string
+	// Environment variables by name. The environment of the process isn't read.
+	Env map[string]string
+	// Values in the "<path>=<JSON>" form, e.g. `config={"a":[1,2]}`.
+	JSONSetValues []string
+	// Values in the "<path>=<value>" form, where the value is taken as is, without parsing commas,
+	// backslashes or nested keys in it.
+	LiteralSetValues []string
+	// Rendering results are cached here and reused if the chart, values and release options are the
+	// same. Charts using secrets or non-deterministic template functions (e.g. now or randAlphaNum)
+	// are not cached, as well as charts using the lookup function if there is cluster access.
+	RenderCacheDir string
+	SubNotes       bool
+	// Don't validate values against values.schema.json of the chart and its subcharts.
+	SkipSchemaValidation bool
+	// Fail on access to missing keys of maps in templates, instead of rendering "<no value>".
+	StrictTemplates bool
+	// If set, rendered manifests which can't be decoded are passed here and skipped, instead of
+	// failing, e.g. to report all of them at once. The file path is the template the manifest was
+	// rendered from.
+	OnInvalidManifest func(filePath string, err error)
 }
 
 type ChartTree struct {
 	standaloneCRDs   []*resource.StandaloneCRD
 	hookResources    []*resource.HookResource
 	generalResources []*resource.GeneralResource
-	notes            string
+	notes            []*ChartNotes
 	releaseValues    map[string]interface{}
 	finalValues      map[string]interface{}
 	legacyChart      *chart.Chart
+	partial          bool
 }
 
 func (t *ChartTree) Name() string {
@@ -213,7 +429,14 @@ func (t *ChartTree) GeneralResources() []*resource.GeneralResource {
 	return t.generalResources
 }
 
+// Notes returns notes of all charts concatenated, same as Helm does.
 func (t *ChartTree) Notes() string {
+	return JoinNotes(t.notes)
+}
+
+// NotesByChart returns notes of the chart and, if SubNotes is set, of its subcharts, the parent
+// chart goes before its subcharts.
+func (t *ChartTree) NotesByChart() []*ChartNotes {
 	return t.notes
 }
 
@@ -225,6 +448,148 @@ func (t *ChartTree) FinalValues() map[string]interface{} {
 	return t.finalValues
 }
 
+// Partial returns true if some of the chart resources were filtered out by the resource filter.
+func (t *ChartTree) Partial() bool {
+	return t.partial
+}
+
 func (t *ChartTree) LegacyChart() *chart.Chart {
 	return t.legacyChart
 }
+
+// ContentHash identifies the chart files, including subcharts, together with the values passed to
+// the release. It doesn't depend on the release revision or on the cluster state.
+func (t *ChartTree) ContentHash() (string, error) {
+	hash := sha256.New()
+
+	if err := writeChartContent(hash, t.legacyChart); err != nil {
+		return "", fmt.Errorf("error hashing chart %q: %w", t.legacyChart.Name(), err)
+	}
+
+	values, err := json.Marshal(t.releaseValues)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling release values: %w", err)
+	}
+	hash.Write(values)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Helm prefixes rendered manifests with the template they were rendered from.
+func manifestFilePath(manifest string) string {
+	firstLine, _, _ := strings.Cut(manifest, "\n")
+	if path, found := strings.CutPrefix(strings.TrimSpace(firstLine), "# Source: "); found {
+		return path
+	}
+
+	return ""
+}
+
+// Template files are matched against paths of templates, which Helm tracks for each rendered
+// manifest. Relative files are resolved against the current directory first, and used as relative
+// to the chart directory if they are not inside of it.
+func filterResourcesByFiles(
+	ctx context.Context,
+	chartPath, chartName string,
+	files []string,
+	standaloneCRDs []*resource.StandaloneCRD,
+	hookResources []*resource.HookResource,
+	generalResources []*resource.GeneralResource,
+) ([]*resource.StandaloneCRD, []*resource.HookResource, []*resource.GeneralResource, error) {
+	absChartPath, err := filepath.Abs(chartPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error getting absolute path of chart %q: %w", chartPath, err)
+	}
+
+	// Resources paths, as tracked by Helm, always start with the chart name.
+	var patterns []string
+	for _, file := range files {
+		pattern := filepath.Clean(file)
+
+		absPattern, err := filepath.Abs(pattern)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error getting absolute path of template file %q: %w", file, err)
+		}
+
+		if relPattern, err := filepath.Rel(absChartPath, absPattern); err == nil && relPattern != ".." && !strings.HasPrefix(relPattern, ".."+string(filepath.Separator)) {
+			pattern = relPattern
+		}
+
+		if !strings.HasPrefix(pattern, chartName+string(filepath.Separator)) {
+			pattern = filepath.Join(chartName, pattern)
+		}
+
+		patterns = append(patterns, filepath.ToSlash(pattern))
+	}
+
+	matchedPatterns := map[string]bool{}
+	match := func(path string) bool {
+		var matched bool
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, path); ok || pattern == path {
+				matchedPatterns[pattern] = true
+				matched = true
+			}
+		}
+
+		return matched
+	}
+
+	standaloneCRDs = lo.Filter(standaloneCRDs, func(res *resource.StandaloneCRD, _ int) bool {
+		return match(res.FilePath())
+	})
+	hookResources = lo.Filter(hookResources, func(res *resource.HookResource, _ int) bool {
+		return match(res.FilePath())
+	})
+	generalResources = lo.Filter(generalResources, func(res *resource.GeneralResource, _ int) bool {
+		return match(res.FilePath())
+	})
+
+	for _, pattern := range patterns {
+		if !matchedPatterns[pattern] {
+			log.Default.Warn(ctx, "No resources rendered from template file %q", pattern)
+		}
+	}
+
+	return standaloneCRDs, hookResources, generalResources, nil
+}
+
+func writeChartContent(w io.Writer, legacyChart *chart.Chart) error {
+	metadata, err := json.Marshal(legacyChart.Metadata)
+	if err != nil {
+		return fmt.Errorf("error marshaling chart metadata: %w", err)
+	}
+	w.Write(metadata)
+
+	values, err := json.Marshal(legacyChart.Values)
+	if err != nil {
+		return fmt.Errorf("error marshaling chart values: %w", err)
+	}
+	w.Write(values)
+	w.Write(legacyChart.Schema)
+
+	files := append(append([]*chart.File{}, legacyChart.Templates...), legacyChart.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	for _, file := range files {
+		fmt.Fprintf(w, "%s\x00%d\x00", file.Name, len(file.Data))
+		w.Write(file.Data)
+	}
+
+	deps := append([]*chart.Chart{}, legacyChart.Dependencies()...)
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].ChartFullPath() < deps[j].ChartFullPath()
+	})
+
+	for _, dep := range deps {
+		fmt.Fprintf(w, "%s\x00", dep.ChartFullPath())
+
+		if err := writeChartContent(w, dep); err != nil {
+			return fmt.Errorf("error hashing subchart %q: %w", dep.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package chart
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const dependencyRepositoryNamePrefix = "helm-manager-"
+
+// UpdateDependencies resolves dependencies from Chart.yaml, downloads them to charts/ and writes
+// Chart.lock with opts.DependencyManager, same as `helm dependency update`. Repositories indexes
+// are not updated if its SkipUpdate is set. The chart is loaded with the same options as by
+// NewChartTree.
+func UpdateDependencies(ctx context.Context, chartPath string, opts ChartTreeOptions) error {
+	loaderGlobalsLock.Lock()
+	defer loaderGlobalsLock.Unlock()
+
+	setLoaderGlobals(chartPath, newLoadChartOptions(opts))
+
+	log.Chart.Debug(ctx, "Updating dependencies of chart at %q", chartPath)
+	if err := opts.DependencyManager.Update(); err != nil {
+		if deps := dependenciesMentionedInError(chartPath, err); len(deps) > 0 {
+			return fmt.Errorf("error updating dependencies of chart at %q: %w (needed for %s)", chartPath, err, strings.Join(deps, ", "))
+		}
+
+		return fmt.Errorf("error updating dependencies of chart at %q: %w", chartPath, err)
+	}
+
+	return nil
+}
+
+// Returns the dependencies, whose repositories are mentioned in the error, so that it's clear which
+// dependency requires the unreachable repository.
+func dependenciesMentionedInError(chartPath string, err error) []string {
+	metadata, loadErr := chartutil.LoadChartfile(filepath.Join(chartPath, chartutil.ChartfileName))
+	if loadErr != nil {
+		return nil
+	}
+
+	var deps []string
+	for _, dep := range metadata.Dependencies {
+		repository := strings.TrimPrefix(strings.TrimPrefix(dep.Repository, "@"), "alias:")
+		if repository == "" || strings.HasPrefix(repository, "file://") {
+			continue
+		}
+
+		// Repositories not added with `helm repo add` are mentioned by the names generated by Helm.
+		repositoryHash := sha256.Sum256([]byte(repository))
+		generatedName := dependencyRepositoryNamePrefix + hex.EncodeToString(repositoryHash[:])
+
+		if !strings.Contains(err.Error(), repository) && !strings.Contains(err.Error(), generatedName) {
+			continue
+		}
+
+		deps = append(deps, fmt.Sprintf("dependency %q from repository %q", dep.Name, dep.Repository))
+	}
+
+	return deps
+}
@@ -0,0 +1,88 @@
+package chart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Separates keys of the value path in names of environment variables, since most shells don't
+// allow dots there.
+const envValuesKeySeparator = "__"
+
+type envValue struct {
+	name string
+	path []string
+	val  string
+}
+
+// envValues returns environment variables with the prefix as values, e.g. with the prefix "VALUES_"
+// the variable "VALUES_app__image__tag" sets "app.image.tag". Values are always strings and are
+// taken as is, so "=", commas and newlines in them are kept. The result is sorted by variable name.
+func envValues(prefix string, env map[string]string) []*envValue {
+	if prefix == "" {
+		return nil
+	}
+
+	var result []*envValue
+envLoop:
+	for name, val := range env {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		path := strings.Split(strings.TrimPrefix(name, prefix), envValuesKeySeparator)
+		for _, key := range path {
+			if key == "" {
+				continue envLoop
+			}
+		}
+
+		result = append(result, &envValue{name: name, path: path, val: val})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+
+	return result
+}
+
+// mergeEnvValues merges the values into a single map. If a key is set both as a value and as a
+// map, e.g. by "VALUES_app" and "VALUES_app__image", the map wins.
+func mergeEnvValues(envVals []*envValue) map[string]interface{} {
+	vals := map[string]interface{}{}
+	for _, envVal := range envVals {
+		envVal.setInto(vals)
+	}
+
+	return vals
+}
+
+func envValuesSources(envVals []*envValue) []*valuesSource {
+	var sources []*valuesSource
+	for _, envVal := range envVals {
+		vals := map[string]interface{}{}
+		envVal.setInto(vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("env var %q", envVal.name), values: vals})
+	}
+
+	return sources
+}
+
+func (v *envValue) setInto(vals map[string]interface{}) {
+	for _, key := range v.path[:len(v.path)-1] {
+		next, ok := vals[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			vals[key] = next
+		}
+
+		vals = next
+	}
+
+	lastKey := v.path[len(v.path)-1]
+	if _, isMap := vals[lastKey].(map[string]interface{}); !isMap {
+		vals[lastKey] = v.val
+	}
+}
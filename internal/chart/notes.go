@@ -0,0 +1,129 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const notesTemplateName = "templates/NOTES.txt"
+
+// Helm renders notes of all charts into a single string, so before rendering each NOTES.txt is
+// prefixed with a marker line, by which the rendered notes are split back. The newline after the
+// marker is optional, since "{{-" at the start of NOTES.txt trims it.
+var notesMarkerRegexp = regexp.MustCompile(`@@nelm-notes:([^@\n]*)@@\n?`)
+
+type ChartNotes struct {
+	// Path of the chart in the chart tree, e.g. "app" or "app/charts/redis".
+	ChartPath string
+	Notes     string
+}
+
+// RenderNotes renders NOTES.txt of the chart and, if subNotes is set, of its subcharts. Used when
+// there is no chart tree, e.g. for notes of the release being rolled back to.
+func RenderNotes(ctx context.Context, legacyChart *chart.Chart, releaseValues map[string]interface{}, releaseOpts chartutil.ReleaseOptions, actionConfig *action.Configuration, subNotes bool) ([]*ChartNotes, error) {
+	caps, err := actionConfig.GetCapabilities()
+	if err != nil {
+		return nil, fmt.Errorf("error getting capabilities for chart %q: %w", legacyChart.Name(), err)
+	}
+
+	var notes []*ChartNotes
+	if err := withoutSchemas(legacyChart, func() error {
+		values, err := chartutil.ToRenderValues(legacyChart, releaseValues, releaseOpts, caps)
+		if err != nil {
+			return fmt.Errorf("error building values: %w", err)
+		}
+
+		return withNotesMarkers(legacyChart, func() error {
+			log.Chart.Debug(ctx, "Rendering notes for chart %q", legacyChart.Name())
+			_, _, renderedNotes, err := actionConfig.RenderResources(legacyChart, values, "", "", subNotes, false, false, nil, true, false)
+			if err != nil {
+				return fmt.Errorf("error rendering templates: %w", err)
+			}
+
+			notes = splitNotes(renderedNotes)
+
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("error rendering notes for chart %q: %w", legacyChart.Name(), err)
+	}
+
+	return notes, nil
+}
+
+// JoinNotes concatenates notes of all charts, same as Helm does.
+func JoinNotes(notes []*ChartNotes) string {
+	var result []string
+	for _, n := range notes {
+		result = append(result, n.Notes)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+func withNotesMarkers(legacyChart *chart.Chart, fn func() error) error {
+	originalData := map[*chart.File][]byte{}
+
+	var mark func(c *chart.Chart)
+	mark = func(c *chart.Chart) {
+		for _, tmpl := range c.Templates {
+			if tmpl.Name != notesTemplateName {
+				continue
+			}
+
+			originalData[tmpl] = tmpl.Data
+			tmpl.Data = append([]byte(fmt.Sprintf("@@nelm-notes:%s@@\n", c.ChartFullPath())), tmpl.Data...)
+		}
+
+		for _, dep := range c.Dependencies() {
+			mark(dep)
+		}
+	}
+	mark(legacyChart)
+
+	defer func() {
+		for tmpl, data := range originalData {
+			tmpl.Data = data
+		}
+	}()
+
+	return fn()
+}
+
+// Returns non-empty notes, the parent chart goes before its subcharts.
+func splitNotes(renderedNotes string) []*ChartNotes {
+	var notes []*ChartNotes
+
+	matches := notesMarkerRegexp.FindAllStringSubmatchIndex(renderedNotes, -1)
+	for i, match := range matches {
+		end := len(renderedNotes)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		text := strings.TrimRightFunc(renderedNotes[match[1]:end], unicode.IsSpace)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		notes = append(notes, &ChartNotes{
+			ChartPath: renderedNotes[match[2]:match[3]],
+			Notes:     text,
+		})
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].ChartPath < notes[j].ChartPath
+	})
+
+	return notes
+}
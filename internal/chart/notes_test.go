@@ -0,0 +1,65 @@
+package chart_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/action"
+	helmchart "github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/werf/secrets"
+	"github.com/werf/nelm/internal/chart"
+)
+
+var _ = Describe("RenderNotes", func() {
+	var (
+		appChart     *helmchart.Chart
+		actionConfig *action.Configuration
+		releaseOpts  chartutil.ReleaseOptions
+	)
+
+	newChart := func(name, notes string) *helmchart.Chart {
+		return &helmchart.Chart{
+			Metadata: &helmchart.Metadata{APIVersion: helmchart.APIVersionV2, Name: name, Version: "0.1.0"},
+			Templates: []*helmchart.File{
+				{Name: "templates/NOTES.txt", Data: []byte(notes)},
+			},
+			Values:             map[string]interface{}{},
+			SecretsRuntimeData: secrets.NewSecretsRuntimeData(),
+		}
+	}
+
+	BeforeEach(func() {
+		actionConfig = &action.Configuration{Capabilities: chartutil.DefaultCapabilities.Copy()}
+		releaseOpts = chartutil.ReleaseOptions{Name: "app", Namespace: "app-ns", Revision: 1, IsInstall: true}
+	})
+
+	DescribeTable("splits notes by chart",
+		func(appNotes, redisNotes string, expectedAppNotes, expectedRedisNotes string) {
+			appChart = newChart("app", appNotes)
+			appChart.AddDependency(newChart("redis", redisNotes))
+
+			notes, err := chart.RenderNotes(context.Background(), appChart, map[string]interface{}{}, releaseOpts, actionConfig, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(notes).To(HaveExactElements(
+				HaveValue(Equal(chart.ChartNotes{ChartPath: "app", Notes: expectedAppNotes})),
+				HaveValue(Equal(chart.ChartNotes{ChartPath: "app/charts/redis", Notes: expectedRedisNotes})),
+			))
+		},
+		Entry("with plain notes",
+			"App notes.\n", "Redis notes.\n",
+			"App notes.", "Redis notes.",
+		),
+		Entry("with whitespace trimmed at the start of notes",
+			"{{- \"\" }}\nApp notes for {{ .Release.Name }}.\n", "{{- /* comment */ -}}\nRedis notes.\n",
+			"App notes for app.", "Redis notes.",
+		),
+		Entry("with whitespace trimmed at the end of notes",
+			"App notes.\n{{- \"\" -}}", "Redis notes.\n{{- \"\" -}}",
+			"App notes.", "Redis notes.",
+		),
+	)
+})
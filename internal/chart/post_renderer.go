@@ -0,0 +1,136 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/postrender"
+)
+
+const kustomizeRenderedManifestsFileName = "nelm-rendered-manifests.yaml"
+
+// NewPostRenderer returns nil if path is empty. If path is a directory, it must be a self-contained
+// kustomization directory, which is applied to the rendered manifests with the built-in kustomize.
+// Otherwise path is an executable, which receives rendered manifests on stdin and prints
+// post-rendered manifests to stdout.
+func NewPostRenderer(path string, args []string) (postrender.PostRenderer, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("post-renderer arguments are not supported for kustomization directory %q", path)
+		}
+
+		return NewKustomizePostRenderer(path), nil
+	}
+
+	postRenderer, err := postrender.NewExec(path, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating exec post-renderer: %w", err)
+	}
+
+	return postRenderer, nil
+}
+
+var _ postrender.PostRenderer = (*KustomizePostRenderer)(nil)
+
+func NewKustomizePostRenderer(kustomizationDir string) *KustomizePostRenderer {
+	return &KustomizePostRenderer{
+		kustomizationDir: kustomizationDir,
+	}
+}
+
+// KustomizePostRenderer copies the kustomization directory into memory, adds rendered manifests to
+// the resources of the kustomization and builds it.
+type KustomizePostRenderer struct {
+	kustomizationDir string
+}
+
+func (r *KustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	const rootDir = "/kustomization"
+
+	fsys := filesys.MakeFsInMemory()
+
+	if err := filepath.WalkDir(r.kustomizationDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(r.kustomizationDir, path)
+		if err != nil {
+			return fmt.Errorf("error getting relative path: %w", err)
+		}
+
+		if d.IsDir() {
+			return fsys.MkdirAll(filepath.Join(rootDir, relPath))
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file %q: %w", path, err)
+		}
+
+		return fsys.WriteFile(filepath.Join(rootDir, relPath), data)
+	}); err != nil {
+		return nil, fmt.Errorf("error copying kustomization directory %q: %w", r.kustomizationDir, err)
+	}
+
+	var kustomizationPath string
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if path := filepath.Join(rootDir, name); fsys.Exists(path) {
+			kustomizationPath = path
+			break
+		}
+	}
+
+	if kustomizationPath == "" {
+		return nil, fmt.Errorf("no kustomization file found in %q", r.kustomizationDir)
+	}
+
+	kustomizationData, err := fsys.ReadFile(kustomizationPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kustomization file: %w", err)
+	}
+
+	kustomization := map[string]interface{}{}
+	if err := yaml.Unmarshal(kustomizationData, &kustomization); err != nil {
+		return nil, fmt.Errorf("error unmarshalling kustomization file: %w", err)
+	}
+
+	resources, _ := kustomization["resources"].([]interface{})
+	kustomization["resources"] = append(resources, kustomizeRenderedManifestsFileName)
+
+	if kustomizationData, err = yaml.Marshal(kustomization); err != nil {
+		return nil, fmt.Errorf("error marshalling kustomization file: %w", err)
+	}
+
+	if err := fsys.WriteFile(kustomizationPath, kustomizationData); err != nil {
+		return nil, fmt.Errorf("error writing kustomization file: %w", err)
+	}
+
+	if err := fsys.WriteFile(filepath.Join(rootDir, kustomizeRenderedManifestsFileName), renderedManifests.Bytes()); err != nil {
+		return nil, fmt.Errorf("error writing rendered manifests: %w", err)
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fsys, rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("error building kustomization: %w", err)
+	}
+
+	result, err := resMap.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("error converting kustomization result to YAML: %w", err)
+	}
+
+	return bytes.NewBuffer(result), nil
+}
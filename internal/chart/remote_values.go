@@ -0,0 +1,118 @@
+package chart
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart/loader"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/getter"
+	"github.com/werf/3p-helm/pkg/registry"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const remoteValuesCacheDirName = "remote-values"
+
+// fetchRemoteValuesFiles downloads values files with URL schemes supported by the Helm getters (e.g.
+// "https://" or "oci://") and returns the paths with remote values files replaced by their local
+// copies. Other paths are returned as is. An OCI artifact must be a chart, its values.yaml is used
+// as the values file. Files are cached in cacheDir, which is expected to be private to the action, so
+// that the remote values are fetched again by the next action. A new temporary directory is created
+// if cacheDir is not specified.
+func fetchRemoteValuesFiles(ctx context.Context, paths []string, getters getter.Providers, registryClient *registry.Client, cacheDir string) ([]string, error) {
+	var result []string
+	for _, path := range paths {
+		u, err := url.Parse(path)
+		// Single-letter schemes are Windows drives.
+		if err != nil || len(u.Scheme) <= 1 {
+			result = append(result, path)
+			continue
+		}
+
+		g, err := getters.ByScheme(u.Scheme)
+		if err != nil {
+			result = append(result, path)
+			continue
+		}
+
+		if cacheDir == "" {
+			if cacheDir, err = os.MkdirTemp("", ""); err != nil {
+				return nil, fmt.Errorf("error creating temporary directory for remote values files: %w", err)
+			}
+		}
+
+		localPath, err := fetchRemoteValuesFile(ctx, path, u.Scheme, g, registryClient, cacheDir)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, localPath)
+	}
+
+	return result, nil
+}
+
+func fetchRemoteValuesFile(ctx context.Context, path, scheme string, g getter.Getter, registryClient *registry.Client, cacheDir string) (string, error) {
+	hash := sha256.Sum256([]byte(path))
+	localPath := filepath.Join(cacheDir, remoteValuesCacheDirName, hex.EncodeToString(hash[:])+".yaml")
+
+	if _, err := os.Stat(localPath); err == nil {
+		log.Chart.Debug(ctx, "Using cached remote values file %q from %q", path, localPath)
+		return localPath, nil
+	}
+
+	log.Chart.Debug(ctx, "Fetching remote values file %q", path)
+	getterOpts := []getter.Option{getter.WithURL(path)}
+	if registryClient != nil {
+		getterOpts = append(getterOpts, getter.WithRegistryClient(registryClient))
+	}
+
+	buf, err := g.Get(path, getterOpts...)
+	if err != nil {
+		return "", fmt.Errorf("error fetching remote values file %q: %w", path, err)
+	}
+
+	data := buf.Bytes()
+	if scheme == registry.OCIScheme {
+		if data, err = chartArchiveValues(data); err != nil {
+			return "", fmt.Errorf("error getting values from OCI artifact %q: %w", path, err)
+		}
+	}
+
+	if err := yaml.Unmarshal(data, &map[string]interface{}{}); err != nil {
+		return "", fmt.Errorf("error parsing remote values file %q: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o700); err != nil {
+		return "", fmt.Errorf("error creating directory for remote values files: %w", err)
+	}
+
+	if err := os.WriteFile(localPath, data, 0o600); err != nil {
+		return "", fmt.Errorf("error saving remote values file %q: %w", path, err)
+	}
+
+	return localPath, nil
+}
+
+func chartArchiveValues(data []byte) ([]byte, error) {
+	files, err := loader.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart archive: %w", err)
+	}
+
+	for _, file := range files {
+		if file.Name == chartutil.ValuesfileName {
+			return file.Data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no %s in chart archive", chartutil.ValuesfileName)
+}
@@ -0,0 +1,335 @@
+package chart
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	helmrelease "github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// Bump when the format of cache entries or the way the key is computed changes.
+const renderCacheVersion = "1"
+
+// Results of these template functions depend on the time, randomness or DNS, not only on the chart
+// and values, so charts using them are never cached.
+var nonDeterministicFuncs = []string{
+	"ago",
+	"bcrypt",
+	"encryptAES",
+	"genCA",
+	"genCAWithKey",
+	"genPrivateKey",
+	"genSelfSignedCert",
+	"genSelfSignedCertWithKey",
+	"genSignedCert",
+	"genSignedCertWithKey",
+	"getHostByName",
+	"htpasswd",
+	"now",
+	"randAlpha",
+	"randAlphaNum",
+	"randAscii",
+	"randBytes",
+	"randInt",
+	"randNumeric",
+	"shuffle",
+	"uuidv4",
+}
+
+type renderCacheEntry struct {
+	Hooks     []*helmrelease.Hook `json:"hooks"`
+	Manifests string              `json:"manifests"`
+	Notes     string              `json:"notes"`
+}
+
+// renderCacheKey returns the digest of everything the rendering result depends on: files of the
+// chart and its subcharts, render values (which include release options and capabilities) and
+// rendering options.
+func renderCacheKey(legacyChart *chart.Chart, values chartutil.Values, subNotes, strictTemplates, hasClusterAccess bool) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version=%s\nsubNotes=%t\nstrictTemplates=%t\nclusterAccess=%t\n", renderCacheVersion, subNotes, strictTemplates, hasClusterAccess)
+
+	if err := hashChart(h, legacyChart); err != nil {
+		return "", fmt.Errorf("error hashing chart %q: %w", legacyChart.Name(), err)
+	}
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling values: %w", err)
+	}
+	h.Write(valuesJSON)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashChart(h hash.Hash, c *chart.Chart) error {
+	metadataJSON, err := json.Marshal(c.Metadata)
+	if err != nil {
+		return fmt.Errorf("error marshaling metadata: %w", err)
+	}
+
+	valuesJSON, err := json.Marshal(c.Values)
+	if err != nil {
+		return fmt.Errorf("error marshaling values: %w", err)
+	}
+
+	fmt.Fprintf(h, "chart=%s\n", c.ChartFullPath())
+	h.Write(metadataJSON)
+	h.Write(valuesJSON)
+
+	for _, files := range [][]*chart.File{c.Templates, c.Files} {
+		sorted := append([]*chart.File{}, files...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+
+		for _, file := range sorted {
+			fmt.Fprintf(h, "file=%s:%d\n", file.Name, len(file.Data))
+			h.Write(file.Data)
+		}
+	}
+
+	deps := append([]*chart.Chart{}, c.Dependencies()...)
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].ChartFullPath() < deps[j].ChartFullPath()
+	})
+
+	for _, dep := range deps {
+		if err := hashChart(h, dep); err != nil {
+			return fmt.Errorf("error hashing subchart %q: %w", dep.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// renderCacheBypassReason returns why the rendering result of the chart must not be cached, or an
+// empty string if it can be cached. Decrypted secrets are never stored in the cache. The result of
+// the lookup function depends on the cluster state, so charts using it are cached only without
+// cluster access, when lookup returns nothing.
+func renderCacheBypassReason(legacyChart *chart.Chart, values chartutil.Values, hasClusterAccess bool) string {
+	if usesSecrets(legacyChart) {
+		return "secret values or secret files are used"
+	}
+
+	funcs, err := usedTemplateFuncs(legacyChart, values)
+	if err != nil {
+		return fmt.Sprintf("unable to find used template functions: %s", err)
+	}
+
+	if hasClusterAccess && funcs["lookup"] {
+		return "the lookup function is used"
+	}
+
+	for _, fn := range nonDeterministicFuncs {
+		if funcs[fn] {
+			return fmt.Sprintf("the non-deterministic %s function is used", fn)
+		}
+	}
+
+	return ""
+}
+
+func usesSecrets(c *chart.Chart) bool {
+	if c.SecretsRuntimeData != nil && (len(c.SecretsRuntimeData.GetDecryptedSecretValues()) > 0 || len(c.SecretsRuntimeData.GetDecryptedSecretFilesData()) > 0) {
+		return true
+	}
+
+	for _, dep := range c.Dependencies() {
+		if usesSecrets(dep) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usedTemplateFuncs returns names of functions called in templates of the chart and its subcharts.
+// Files and string values which look like templates are checked too, since they can be passed to
+// tpl. Those of them which aren't valid templates are skipped, since tpl would fail on them anyway.
+func usedTemplateFuncs(legacyChart *chart.Chart, values chartutil.Values) (map[string]bool, error) {
+	funcs := map[string]bool{}
+
+	var walkChart func(c *chart.Chart) error
+	walkChart = func(c *chart.Chart) error {
+		for _, tmpl := range c.Templates {
+			if err := collectTemplateFuncs(funcs, tmpl.Name, string(tmpl.Data)); err != nil {
+				return fmt.Errorf("error parsing template %q of chart %q: %w", tmpl.Name, c.ChartFullPath(), err)
+			}
+		}
+
+		for _, file := range c.Files {
+			if text := string(file.Data); strings.Contains(text, "{{") {
+				_ = collectTemplateFuncs(funcs, file.Name, text)
+			}
+		}
+
+		for _, dep := range c.Dependencies() {
+			if err := walkChart(dep); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walkChart(legacyChart); err != nil {
+		return nil, err
+	}
+
+	var walkValue func(val interface{})
+	walkValue = func(val interface{}) {
+		switch v := val.(type) {
+		case string:
+			if strings.Contains(v, "{{") {
+				_ = collectTemplateFuncs(funcs, "value", v)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walkValue(item)
+			}
+		case chartutil.Values:
+			for _, item := range v {
+				walkValue(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walkValue(item)
+			}
+		}
+	}
+	walkValue(values)
+
+	return funcs, nil
+}
+
+func collectTemplateFuncs(funcs map[string]bool, name, text string) error {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return err
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IdentifierNode:
+			funcs[n.Ident] = true
+		case *parse.IfNode:
+			walk(&n.BranchNode)
+		case *parse.RangeNode:
+			walk(&n.BranchNode)
+		case *parse.WithNode:
+			walk(&n.BranchNode)
+		case *parse.BranchNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+
+	for _, t := range trees {
+		walk(t.Root)
+	}
+
+	return nil
+}
+
+func loadRenderCacheEntry(ctx context.Context, cacheDir, key string) (*renderCacheEntry, bool) {
+	path := renderCacheEntryPath(cacheDir, key)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Chart.Warn(ctx, "Unable to read render cache entry %q: %s", path, err)
+		}
+
+		return nil, false
+	}
+
+	entry := &renderCacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		log.Chart.Warn(ctx, "Ignoring corrupted render cache entry %q: %s", path, err)
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func saveRenderCacheEntry(cacheDir, key string, entry *renderCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling render cache entry: %w", err)
+	}
+
+	// Rendered manifests might contain sensitive data, e.g. Secrets, so the cache is private.
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return fmt.Errorf("error creating render cache directory %q: %w", cacheDir, err)
+	}
+
+	// Written to a temporary file first, so that concurrent runs never read a partial entry.
+	tmpFile, err := os.CreateTemp(cacheDir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary render cache file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing temporary render cache file %q: %w", tmpFile.Name(), err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error closing temporary render cache file %q: %w", tmpFile.Name(), err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), renderCacheEntryPath(cacheDir, key)); err != nil {
+		return fmt.Errorf("error saving render cache entry: %w", err)
+	}
+
+	return nil
+}
+
+func renderCacheEntryPath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+".json")
+}
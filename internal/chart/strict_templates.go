@@ -0,0 +1,109 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/engine"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// validateStrictTemplates renders templates of the chart and its subcharts with missingkey=error,
+// so that access to missing keys of maps fails instead of producing "<no value>". Helm stops on the
+// first failed template, so if rendering fails, templates are rendered once more one by one to
+// report all failures at once.
+func validateStrictTemplates(ctx context.Context, legacyChart *chart.Chart, values chartutil.Values, actionConfig *action.Configuration, hasClusterAccess bool) error {
+	strictEngine := engine.Engine{Strict: true}
+	if hasClusterAccess && actionConfig.RESTClientGetter != nil {
+		restConfig, err := actionConfig.RESTClientGetter.ToRESTConfig()
+		if err != nil {
+			return fmt.Errorf("error getting REST config: %w", err)
+		}
+
+		strictEngine = engine.New(restConfig)
+		strictEngine.Strict = true
+	}
+
+	if _, err := strictEngine.Render(legacyChart, values); err == nil {
+		return nil
+	}
+
+	var (
+		errs     []error
+		seenErrs = map[string]bool{}
+	)
+	for _, tmpl := range nonPartialTemplates(legacyChart) {
+		log.Chart.Debug(ctx, "Rendering template %q in strict mode", tmpl.Name)
+		if err := withOnlyTemplate(legacyChart, tmpl, func() error {
+			_, err := strictEngine.Render(legacyChart, values)
+			return err
+		}); err != nil {
+			// Errors in partials are the same for every template including them.
+			if seenErrs[err.Error()] {
+				continue
+			}
+			seenErrs[err.Error()] = true
+
+			errs = append(errs, err)
+		}
+	}
+
+	return util.Multierrorf("strict rendering failed", errs)
+}
+
+func nonPartialTemplates(legacyChart *chart.Chart) []*chart.File {
+	var result []*chart.File
+	for _, tmpl := range legacyChart.Templates {
+		if !isPartialTemplate(tmpl) {
+			result = append(result, tmpl)
+		}
+	}
+
+	for _, dep := range legacyChart.Dependencies() {
+		result = append(result, nonPartialTemplates(dep)...)
+	}
+
+	return result
+}
+
+// withOnlyTemplate temporarily removes all templates except partials and the specified one from the
+// chart and its subcharts.
+func withOnlyTemplate(legacyChart *chart.Chart, onlyTmpl *chart.File, fn func() error) error {
+	originalTemplates := map[*chart.Chart][]*chart.File{}
+
+	var strip func(c *chart.Chart)
+	strip = func(c *chart.Chart) {
+		originalTemplates[c] = c.Templates
+
+		var templates []*chart.File
+		for _, tmpl := range c.Templates {
+			if tmpl == onlyTmpl || isPartialTemplate(tmpl) {
+				templates = append(templates, tmpl)
+			}
+		}
+		c.Templates = templates
+
+		for _, dep := range c.Dependencies() {
+			strip(dep)
+		}
+	}
+	strip(legacyChart)
+
+	defer func() {
+		for c, templates := range originalTemplates {
+			c.Templates = templates
+		}
+	}()
+
+	return fn()
+}
+
+func isPartialTemplate(tmpl *chart.File) bool {
+	return strings.HasPrefix(path.Base(tmpl.Name), "_")
+}
@@ -0,0 +1,229 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/strvals"
+)
+
+type ValuesSchemaViolation struct {
+	// JSON path of the offending value, e.g. ".image.tag". Values of subcharts are prefixed with the
+	// subchart name.
+	Path        string
+	Description string
+	// Values file, --set* flag or environment variable which defined the offending value, or "chart
+	// values" if it wasn't defined by the user.
+	Source string
+}
+
+func (v *ValuesSchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s (from %s)", v.Path, v.Description, v.Source)
+}
+
+type ValuesSchemaError struct {
+	Violations []*ValuesSchemaViolation
+}
+
+func (e *ValuesSchemaError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d values schema violation(s):", len(e.Violations))
+	for _, violation := range e.Violations {
+		fmt.Fprintf(b, "\n  %s", violation)
+	}
+
+	return b.String()
+}
+
+// valuesSource is a values file or a --set* flag, parsed on its own to find out which values it
+// defines.
+type valuesSource struct {
+	name   string
+	values map[string]interface{}
+}
+
+// Sources are returned in the order of increasing priority, same as they are merged by Helm.
+// localValuesFiles are opts.ValuesFiles with remote values files replaced by their local copies.
+func newValuesSources(opts ChartTreeOptions, localValuesFiles []string, envVals []*envValue) []*valuesSource {
+	sources := envValuesSources(envVals)
+
+	for i, path := range opts.ValuesFiles {
+		vals := map[string]interface{}{}
+		if data, err := os.ReadFile(localValuesFiles[i]); err == nil {
+			_ = yaml.Unmarshal(data, &vals)
+		}
+
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("values file %q", path), values: vals})
+	}
+
+	for _, value := range opts.JSONSetValues {
+		vals := map[string]interface{}{}
+		_ = strvals.ParseJSON(value, vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("--set-json %q", value), values: vals})
+	}
+
+	for _, value := range opts.SetValues {
+		vals := map[string]interface{}{}
+		_ = strvals.ParseInto(value, vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("--set %q", value), values: vals})
+	}
+
+	for _, value := range opts.StringSetValues {
+		vals := map[string]interface{}{}
+		_ = strvals.ParseIntoString(value, vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("--set-string %q", value), values: vals})
+	}
+
+	for _, value := range opts.FileValues {
+		// Only keys matter here, so the file itself is not read.
+		vals := map[string]interface{}{}
+		_ = strvals.ParseIntoString(value, vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("--set-file %q", value), values: vals})
+	}
+
+	for _, value := range opts.LiteralSetValues {
+		vals := map[string]interface{}{}
+		_ = strvals.ParseLiteralInto(value, vals)
+		sources = append(sources, &valuesSource{name: fmt.Sprintf("--set-literal %q", value), values: vals})
+	}
+
+	return sources
+}
+
+// validateValuesSchema validates coalesced values against the schemas of the chart and its
+// subcharts and returns all violations at once.
+func validateValuesSchema(legacyChart *chart.Chart, values map[string]interface{}, sources []*valuesSource) error {
+	violations, err := valuesSchemaViolations(legacyChart, values, nil, sources)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return &ValuesSchemaError{Violations: violations}
+	}
+
+	return nil
+}
+
+func valuesSchemaViolations(legacyChart *chart.Chart, values map[string]interface{}, pathPrefix []string, sources []*valuesSource) ([]*ValuesSchemaViolation, error) {
+	var violations []*ValuesSchemaViolation
+
+	if legacyChart.Schema != nil {
+		valuesJSON, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling values of chart %q: %w", legacyChart.Name(), err)
+		}
+
+		if valuesJSON, err = yaml.YAMLToJSON(valuesJSON); err != nil {
+			return nil, fmt.Errorf("error converting values of chart %q to JSON: %w", legacyChart.Name(), err)
+		}
+
+		if bytes.Equal(valuesJSON, []byte("null")) {
+			valuesJSON = []byte("{}")
+		}
+
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(legacyChart.Schema), gojsonschema.NewBytesLoader(valuesJSON))
+		if err != nil {
+			return nil, fmt.Errorf("error validating values against schema of chart %q: %w", legacyChart.Name(), err)
+		}
+
+		for _, resultErr := range result.Errors() {
+			var path []string
+			path = append(path, pathPrefix...)
+			if field := resultErr.Field(); field != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+				path = append(path, strings.Split(field, ".")...)
+			}
+
+			violations = append(violations, &ValuesSchemaViolation{
+				Path:        "." + strings.Join(path, "."),
+				Description: resultErr.Description(),
+				Source:      valuesPathSource(path, sources),
+			})
+		}
+	}
+
+	for _, subchart := range legacyChart.Dependencies() {
+		subchartValues, _ := values[subchart.Name()].(map[string]interface{})
+
+		subchartViolations, err := valuesSchemaViolations(subchart, subchartValues, append(append([]string{}, pathPrefix...), subchart.Name()), sources)
+		if err != nil {
+			return nil, err
+		}
+
+		violations = append(violations, subchartViolations...)
+	}
+
+	return violations, nil
+}
+
+// Returns the source with the highest priority which defines the value at the path or any of its
+// parents.
+func valuesPathSource(path []string, sources []*valuesSource) string {
+	for i := len(path); i > 0; i-- {
+		for j := len(sources) - 1; j >= 0; j-- {
+			if valuesPathExists(sources[j].values, path[:i]) {
+				return sources[j].name
+			}
+		}
+	}
+
+	return "chart values"
+}
+
+func valuesPathExists(values interface{}, path []string) bool {
+	current := values
+	for _, key := range path {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, found := v[key]
+			if !found {
+				return false
+			}
+
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return false
+			}
+
+			current = v[index]
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// withoutSchemas temporarily removes schemas from the chart and its subcharts, so that Helm doesn't
+// validate values on its own.
+func withoutSchemas(legacyChart *chart.Chart, fn func() error) error {
+	schemas := map[*chart.Chart][]byte{}
+
+	var strip func(c *chart.Chart)
+	strip = func(c *chart.Chart) {
+		schemas[c] = c.Schema
+		c.Schema = nil
+
+		for _, dep := range c.Dependencies() {
+			strip(dep)
+		}
+	}
+	strip(legacyChart)
+
+	defer func() {
+		for c, schema := range schemas {
+			c.Schema = schema
+		}
+	}()
+
+	return fn()
+}
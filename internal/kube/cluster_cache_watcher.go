@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jellydator/ttlcache/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
+)
+
+func newClusterCacheWatcher(dynamicClient dynamic.Interface, clusterCache *ttlcache.Cache[string, *clusterCacheEntry]) *clusterCacheWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &clusterCacheWatcher{
+		dynamicClient: dynamicClient,
+		clusterCache:  clusterCache,
+		ctx:           ctx,
+		cancel:        cancel,
+		watches:       map[clusterCacheWatchKey]struct{}{},
+	}
+}
+
+// clusterCacheWatcher watches kinds of cached resources per namespace and keeps the already cached
+// entries up to date. If a watch can't be started, e.g. because of RBAC, the cache works as if
+// there were no watcher.
+type clusterCacheWatcher struct {
+	dynamicClient dynamic.Interface
+	clusterCache  *ttlcache.Cache[string, *clusterCacheEntry]
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	watches map[clusterCacheWatchKey]struct{}
+	wg      sync.WaitGroup
+}
+
+type clusterCacheWatchKey struct {
+	gvr schema.GroupVersionResource
+	gvk schema.GroupVersionKind
+	// Empty for cluster-scoped resources.
+	namespace string
+	// Namespace used in the ResourceIDs of cached resources.
+	idNamespace string
+}
+
+func (w *clusterCacheWatcher) Watch(ctx context.Context, key clusterCacheWatchKey) {
+	w.mu.Lock()
+	if _, found := w.watches[key]; found || w.ctx.Err() != nil {
+		w.mu.Unlock()
+		return
+	}
+
+	// Unavailable watches are remembered too, to avoid retrying them on every Get.
+	w.watches[key] = struct{}{}
+	// Added under the lock, so that Close waits for the watch being started below.
+	w.wg.Add(1)
+	w.mu.Unlock()
+
+	var clientResource dynamic.ResourceInterface
+	if key.namespace != "" {
+		clientResource = w.dynamicClient.Resource(key.gvr).Namespace(key.namespace)
+	} else {
+		clientResource = w.dynamicClient.Resource(key.gvr)
+	}
+
+	// Started without holding the lock, since the request might take long and other kinds must not
+	// wait for it.
+	watcher, err := clientResource.Watch(w.ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Kube.Debug(ctx, "Can't watch %q (namespace: %q), resources of this kind won't be updated in cache: %s", key.gvr.String(), key.namespace, err)
+		w.wg.Done()
+
+		return
+	}
+
+	go func() {
+		defer w.wg.Done()
+		defer watcher.Stop()
+
+		w.handleEvents(key, watcher.ResultChan())
+
+		// Events might have been missed, so don't trust the cached entries anymore and allow the
+		// watch to be restarted on the next Get.
+		w.evict(key)
+
+		w.mu.Lock()
+		delete(w.watches, key)
+		w.mu.Unlock()
+	}()
+}
+
+func (w *clusterCacheWatcher) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.cancel()
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for cluster cache watches to stop: %w", ctx.Err())
+	}
+}
+
+func (w *clusterCacheWatcher) handleEvents(key clusterCacheWatchKey, events <-chan watch.Event) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+			case watch.Error:
+				return
+			default:
+				continue
+			}
+
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+
+			cacheKey := id.NewResourceID(obj.GetName(), key.idNamespace, key.gvk, id.ResourceIDOptions{}).VersionID()
+			if !w.clusterCache.Has(cacheKey) {
+				continue
+			}
+
+			if event.Type == watch.Deleted {
+				w.clusterCache.Delete(cacheKey)
+			} else {
+				w.clusterCache.Set(cacheKey, &clusterCacheEntry{obj: obj.DeepCopy()}, 0)
+			}
+		}
+	}
+}
+
+func (w *clusterCacheWatcher) evict(key clusterCacheWatchKey) {
+	prefix := fmt.Sprintf("%s:%s:%s:%s:", key.idNamespace, key.gvk.Group, key.gvk.Version, key.gvk.Kind)
+
+	for _, cacheKey := range w.clusterCache.Keys() {
+		if strings.HasPrefix(cacheKey, prefix) {
+			w.clusterCache.Delete(cacheKey)
+		}
+	}
+}
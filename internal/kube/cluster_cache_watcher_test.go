@@ -0,0 +1,192 @@
+package kube_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var _ = Describe("kube client in watch cache mode", func() {
+	var (
+		ctx        context.Context
+		cluster    *fake.Cluster
+		kubeClient *kube.KubeClient
+	)
+
+	configMapGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	resID := func(kind, name string) *id.ResourceID {
+		return id.NewResourceID(name, "app-ns", schema.GroupVersionKind{Version: "v1", Kind: kind}, id.ResourceIDOptions{Mapper: cluster.Mapper})
+	}
+
+	getCached := func(kind, name string) func() (*unstructured.Unstructured, error) {
+		return func() (*unstructured.Unstructured, error) {
+			return kubeClient.Get(ctx, resID(kind, name), kube.KubeClientGetOptions{TryCache: true})
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = fake.NewCluster(ctx,
+			unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: app, namespace: app-ns}, data: {key: old}}`),
+			unstructFromYAML(`{apiVersion: v1, kind: Secret, metadata: {name: app, namespace: app-ns}}`),
+		)
+	})
+
+	newKubeClient := func(dynamicClient ...dynamic.Interface) {
+		kubeClient = kube.NewKubeClient(nil, append(dynamicClient, cluster.Dynamic)[0], cluster.Discovery, cluster.Mapper, kube.KubeClientOptions{WatchCache: true})
+		DeferCleanup(func() {
+			Expect(kubeClient.Close(ctx)).To(Succeed())
+		})
+	}
+
+	updateConfigMap := func(value string) {
+		obj, err := cluster.Dynamic.Resource(configMapGVR).Namespace("app-ns").Get(ctx, "app", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedField(obj.Object, value, "data", "key")).To(Succeed())
+
+		_, err = cluster.Dynamic.Resource(configMapGVR).Namespace("app-ns").Update(ctx, obj, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	dataKey := func(obj *unstructured.Unstructured) string {
+		value, _, _ := unstructured.NestedString(obj.Object, "data", "key")
+		return value
+	}
+
+	It("updates and evicts cached resources on watch events", func() {
+		newKubeClient()
+
+		Expect(getCached("ConfigMap", "app")()).To(WithTransform(dataKey, Equal("old")))
+
+		updateConfigMap("new")
+		Eventually(getCached("ConfigMap", "app")).Should(WithTransform(dataKey, Equal("new")))
+
+		Expect(cluster.Dynamic.Resource(configMapGVR).Namespace("app-ns").Delete(ctx, "app", metav1.DeleteOptions{})).To(Succeed())
+		Eventually(func() error {
+			_, err := getCached("ConfigMap", "app")()
+			return err
+		}).Should(MatchError(ContainSubstring("not found")))
+	})
+
+	It("serves the cache as before if watching is forbidden", func() {
+		cluster.Dynamic.PrependWatchReactor("configmaps", func(action clienttesting.Action) (bool, watch.Interface, error) {
+			return true, nil, apierrors.NewForbidden(configMapGVR.GroupResource(), "", nil)
+		})
+		newKubeClient()
+
+		Expect(getCached("ConfigMap", "app")()).To(WithTransform(dataKey, Equal("old")))
+
+		updateConfigMap("new")
+		Consistently(getCached("ConfigMap", "app"), 200*time.Millisecond).Should(WithTransform(dataKey, Equal("old")))
+	})
+
+	It("doesn't block other kinds while a watch is being started", func() {
+		release := make(chan struct{})
+		newKubeClient(&slowWatchDynamicClient{
+			Interface: cluster.Dynamic,
+			resource:  configMapGVR,
+			release:   release,
+		})
+
+		configMapDone := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(configMapDone)
+
+			_, err := getCached("ConfigMap", "app")()
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		secretDone := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(secretDone)
+
+			_, err := getCached("Secret", "app")()
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		Eventually(secretDone).Should(BeClosed())
+		Consistently(configMapDone, 100*time.Millisecond).ShouldNot(BeClosed())
+
+		close(release)
+		Eventually(configMapDone).Should(BeClosed())
+	})
+
+	It("stops watching and drops the entries it kept up to date on Close", func() {
+		kubeClient = kube.NewKubeClient(nil, cluster.Dynamic, cluster.Discovery, cluster.Mapper, kube.KubeClientOptions{WatchCache: true})
+
+		_, err := getCached("ConfigMap", "app")()
+		Expect(err).NotTo(HaveOccurred())
+
+		closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		Expect(kubeClient.Close(closeCtx)).To(Succeed())
+
+		updateConfigMap("new")
+		Expect(getCached("ConfigMap", "app")()).To(WithTransform(dataKey, Equal("new")))
+
+		updateConfigMap("newer")
+		Consistently(getCached("ConfigMap", "app"), 200*time.Millisecond).Should(WithTransform(dataKey, Equal("new")))
+	})
+})
+
+// slowWatchDynamicClient starts watches of the resource only when released. The fake dynamic client
+// can't be used for this, since it serves all requests under a single lock.
+type slowWatchDynamicClient struct {
+	dynamic.Interface
+
+	resource schema.GroupVersionResource
+	release  chan struct{}
+}
+
+func (c *slowWatchDynamicClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	if resource != c.resource {
+		return c.Interface.Resource(resource)
+	}
+
+	return &slowWatchResourceClient{NamespaceableResourceInterface: c.Interface.Resource(resource), release: c.release}
+}
+
+type slowWatchResourceClient struct {
+	dynamic.NamespaceableResourceInterface
+
+	namespaced dynamic.ResourceInterface
+	release    chan struct{}
+}
+
+func (c *slowWatchResourceClient) Namespace(namespace string) dynamic.ResourceInterface {
+	return &slowWatchResourceClient{
+		NamespaceableResourceInterface: c.NamespaceableResourceInterface,
+		namespaced:                     c.NamespaceableResourceInterface.Namespace(namespace),
+		release:                        c.release,
+	}
+}
+
+func (c *slowWatchResourceClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return c.namespaced.Get(ctx, name, opts, subresources...)
+}
+
+func (c *slowWatchResourceClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return c.namespaced.Watch(ctx, opts)
+}
@@ -2,6 +2,7 @@ package kube
 
 import (
 	"path/filepath"
+	"time"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/tools/clientcmd"
@@ -15,6 +16,11 @@ var (
 	KubectlDiscoveryCacheSubdir = "discovery"
 )
 
+const DefaultGetManyConcurrency = 10
+
+// Resources not found are cached only for a short time, since they are likely to be created soon.
+const notFoundCacheTTL = 10 * time.Second
+
 func init() {
 	genericclioptions.ErrEmptyConfig = clientcmd.NewEmptyConfigError("missing or incomplete kubeconfig")
 }
@@ -0,0 +1,43 @@
+package kube
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// IsConnectionError returns true if the cluster is not configured, can't be reached or rejects the
+// credentials, as opposed to rejecting a particular request.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch {
+	case apierrors.IsUnauthorized(err),
+		clientcmd.IsEmptyConfig(err),
+		clientcmd.IsConfigurationInvalid(err):
+		return true
+	}
+
+	var (
+		urlErr         *url.Error
+		netErr         *net.OpError
+		dnsErr         *net.DNSError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		invalidCertErr x509.CertificateInvalidError
+	)
+
+	return errors.As(err, &urlErr) ||
+		errors.As(err, &netErr) ||
+		errors.As(err, &dnsErr) ||
+		errors.As(err, &unknownAuthErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCertErr)
+}
@@ -10,9 +10,11 @@ import (
 	"k8s.io/client-go/discovery/cached/disk"
 )
 
-func NewDiscoveryKubeClientFromKubeConfig(kubeConfig *KubeConfig) (*disk.CachedDiscoveryClient, error) {
+func NewDiscoveryKubeClientFromKubeConfig(kubeConfig *KubeConfig, opts DiscoveryKubeClientOptions) (*disk.CachedDiscoveryClient, error) {
 	var cacheDir string
-	if dir := os.Getenv(KubectlCacheDirEnv); dir != "" {
+	if opts.CacheDir != "" {
+		cacheDir = opts.CacheDir
+	} else if dir := os.Getenv(KubectlCacheDirEnv); dir != "" {
 		cacheDir = dir
 	} else {
 		cacheDir = DefaultKubectlCacheDir
@@ -24,6 +26,10 @@ func NewDiscoveryKubeClientFromKubeConfig(kubeConfig *KubeConfig) (*disk.CachedD
 	return disk.NewCachedDiscoveryClientForConfig(kubeConfig.RestConfig, discoveryCacheDir, httpCacheDir, time.Duration(6*time.Hour))
 }
 
+type DiscoveryKubeClientOptions struct {
+	CacheDir string
+}
+
 // Taken from: https://github.com/kubernetes/cli-runtime/blob/e447e205e17575154e7108dbd67e6965499488a0/pkg/genericclioptions/config_flags.go#L485
 func computeDiscoveryCacheDir(parentDir, host string) string {
 	schemelessHost := strings.Replace(strings.Replace(host, "https://", "", 1), "http://", "", 1)
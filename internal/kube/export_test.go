@@ -0,0 +1,12 @@
+package kube
+
+var NewKeyedMutex = newKeyedMutex
+
+type KeyedMutex = keyedMutex
+
+func (m *keyedMutex) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.entries)
+}
@@ -3,7 +3,6 @@ package kube
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sync"
 
 	"github.com/samber/lo"
@@ -14,11 +13,13 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/werf/nelm/pkg/log"
 )
 
 var addToScheme sync.Once
 
-func NewClientFactory(ctx context.Context, kubeConfig *KubeConfig) (*ClientFactory, error) {
+func NewClientFactory(ctx context.Context, kubeConfig *KubeConfig, opts ClientFactoryOptions) (*ClientFactory, error) {
 	addToScheme.Do(func() {
 		lo.Must0(apiextv1.AddToScheme(scheme.Scheme))
 		lo.Must0(apiextv1beta1.AddToScheme(scheme.Scheme))
@@ -38,14 +39,24 @@ func NewClientFactory(ctx context.Context, kubeConfig *KubeConfig) (*ClientFacto
 		return nil, fmt.Errorf("construct dynamic kubernetes client: %w", err)
 	}
 
-	discoveryClient, err := NewDiscoveryKubeClientFromKubeConfig(kubeConfig)
+	discoveryClient, err := NewDiscoveryKubeClientFromKubeConfig(kubeConfig, DiscoveryKubeClientOptions{
+		CacheDir: opts.DiscoveryCacheDir,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("construct discovery kubernetes client: %w", err)
 	}
 
-	mapper := reflect.ValueOf(NewKubeMapper(ctx, discoveryClient)).Interface().(meta.ResettableRESTMapper)
+	mapper := NewKubeMapper(ctx, discoveryClient)
 
-	kubeClient := NewKubeClient(staticClient, dynamicClient, discoveryClient, mapper)
+	if opts.RefreshDiscovery {
+		log.Kube.Debug(ctx, "Invalidating discovery cache")
+		discoveryClient.Invalidate()
+		mapper.Reset()
+	}
+
+	kubeClient := NewKubeClient(staticClient, dynamicClient, discoveryClient, mapper, KubeClientOptions{
+		WatchCache: opts.WatchCache,
+	})
 
 	legacyClientGetter := NewLegacyClientGetter(discoveryClient, mapper, kubeConfig.RestConfig, kubeConfig.LegacyClientConfig)
 
@@ -62,10 +73,17 @@ func NewClientFactory(ctx context.Context, kubeConfig *KubeConfig) (*ClientFacto
 	return clientFactory, nil
 }
 
+type ClientFactoryOptions struct {
+	DiscoveryCacheDir string
+	RefreshDiscovery  bool
+	// Keep resources cached by KubeClient up to date by watching them. Call Close to stop watching.
+	WatchCache bool
+}
+
 type ClientFactory struct {
 	discoveryClient    discovery.CachedDiscoveryInterface
 	dynamicClient      dynamic.Interface
-	kubeClient         KubeClienter
+	kubeClient         *KubeClient
 	kubeConfig         *KubeConfig
 	legacyClientGetter *LegacyClientGetter
 	mapper             meta.ResettableRESTMapper
@@ -99,3 +117,9 @@ func (f *ClientFactory) LegacyClientGetter() *LegacyClientGetter {
 func (f *ClientFactory) KubeConfig() *KubeConfig {
 	return f.kubeConfig
 }
+
+// Close stops the background activity of the clients, if any, waiting for it until ctx is
+// canceled.
+func (f *ClientFactory) Close(ctx context.Context) error {
+	return f.kubeClient.Close(ctx)
+}
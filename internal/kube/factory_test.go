@@ -0,0 +1,73 @@
+package kube_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/kube/fake"
+)
+
+var _ = Describe("client factory", func() {
+	It("refreshes the cached discovery if requested", func() {
+		ctx := context.Background()
+
+		server := fake.NewAPIServer(fake.APIResources)
+		DeferCleanup(server.Close)
+
+		tmpDir := GinkgoT().TempDir()
+		kubeConfigPath := filepath.Join(tmpDir, "kubeconfig")
+		Expect(os.WriteFile(kubeConfigPath, []byte(fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster: {server: %q}
+users:
+- name: test
+contexts:
+- name: test
+  context: {cluster: test, user: test}
+current-context: test
+`, server.URL)), 0o644)).To(Succeed())
+
+		serverGroups := func(refreshDiscovery bool) []string {
+			kubeConfig, err := kube.NewKubeConfig(ctx, []string{kubeConfigPath}, kube.KubeConfigOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+				DiscoveryCacheDir: filepath.Join(tmpDir, "cache"),
+				RefreshDiscovery:  refreshDiscovery,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(clientFactory.Close, context.Background())
+
+			groups, err := clientFactory.Discovery().ServerGroups()
+			Expect(err).NotTo(HaveOccurred())
+
+			var names []string
+			for _, group := range groups.Groups {
+				names = append(names, group.Name)
+			}
+
+			return names
+		}
+
+		Expect(serverGroups(false)).NotTo(ContainElement("example.com"))
+
+		server.SetAPIResources(append(fake.APIResources, &metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+		}))
+
+		Expect(serverGroups(false)).NotTo(ContainElement("example.com"))
+		Expect(serverGroups(true)).To(ContainElement("example.com"))
+		Expect(serverGroups(false)).To(ContainElement("example.com"))
+	})
+})
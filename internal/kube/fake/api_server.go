@@ -0,0 +1,127 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// NewAPIServer starts an HTTP server serving the version and the discovery of the API resources,
+// for tests which construct clients from a kubeconfig. Listing any resource returns an empty list,
+// getting any object returns NotFound. Close the server when done.
+func NewAPIServer(resources []*metav1.APIResourceList) *APIServer {
+	server := &APIServer{
+		resources: resources,
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+
+	return server
+}
+
+type APIServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	resources []*metav1.APIResourceList
+}
+
+// SetAPIResources changes the served API resources, e.g. as if a CRD was installed.
+func (s *APIServer) SetAPIResources(resources []*metav1.APIResourceList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resources = resources
+}
+
+func (s *APIServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	resources := s.resources
+	s.mu.Unlock()
+
+	path := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case path == "version":
+		writeJSON(w, http.StatusOK, version.Info{Major: "1", Minor: "29", GitVersion: "v1.29.3"})
+		return
+	case path == "api":
+		writeJSON(w, http.StatusOK, metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		})
+		return
+	case path == "apis":
+		groups := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}}
+		for _, list := range resources {
+			gv := lo.Must(schema.ParseGroupVersion(list.GroupVersion))
+			if gv.Group == "" {
+				continue
+			}
+
+			groupVersion := metav1.GroupVersionForDiscovery{GroupVersion: list.GroupVersion, Version: gv.Version}
+			groups.Groups = append(groups.Groups, metav1.APIGroup{
+				Name:             gv.Group,
+				Versions:         []metav1.GroupVersionForDiscovery{groupVersion},
+				PreferredVersion: groupVersion,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, groups)
+		return
+	}
+
+	var gvPath string
+	var rest []string
+	segments := strings.Split(path, "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		gvPath, rest = segments[1], segments[2:]
+	case len(segments) >= 3 && segments[0] == "apis":
+		gvPath, rest = segments[1]+"/"+segments[2], segments[3:]
+	}
+
+	for _, list := range resources {
+		if list.GroupVersion != gvPath {
+			continue
+		}
+
+		if len(rest) == 0 {
+			writeJSON(w, http.StatusOK, list)
+			return
+		}
+
+		if len(rest) >= 3 && rest[0] == "namespaces" {
+			rest = rest[2:]
+		}
+
+		if len(rest) == 1 && r.Method == http.MethodGet {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "List",
+				"metadata":   map[string]interface{}{},
+				"items":      []interface{}{},
+			})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusNotFound, metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Reason:   metav1.StatusReasonNotFound,
+		Code:     http.StatusNotFound,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(obj)
+}
@@ -0,0 +1,147 @@
+// Package fake provides an in-memory cluster for tests, served by the fake dynamic and discovery
+// clients of client-go and wrapped with the real KubeMapper and KubeClient.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/kube"
+)
+
+// APIResources are served by the fake discovery client.
+var APIResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			{Name: "events", Kind: "Event", Namespaced: true},
+			{Name: "namespaces", Kind: "Namespace"},
+			{Name: "nodes", Kind: "Node"},
+			{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", Namespaced: true},
+			{Name: "pods", Kind: "Pod", Namespaced: true},
+			{Name: "secrets", Kind: "Secret", Namespaced: true},
+			{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true},
+			{Name: "services", Kind: "Service", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "daemonsets", Kind: "DaemonSet", Namespaced: true},
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			{Name: "replicasets", Kind: "ReplicaSet", Namespaced: true},
+			{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "batch/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "cronjobs", Kind: "CronJob", Namespaced: true},
+			{Name: "jobs", Kind: "Job", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "apiextensions.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"},
+		},
+	},
+}
+
+func NewCluster(ctx context.Context, objects ...*unstructured.Unstructured) *Cluster {
+	discoveryClient := &DiscoveryClient{
+		CachedDiscoveryInterface: memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{Resources: APIResources},
+		}),
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, list := range APIResources {
+		gv := schema.FromAPIVersionAndKind(list.GroupVersion, "").GroupVersion()
+		for _, res := range list.APIResources {
+			listKinds[gv.WithResource(res.Name)] = res.Kind + "List"
+		}
+	}
+
+	var runtimeObjects []runtime.Object
+	for _, obj := range objects {
+		runtimeObjects = append(runtimeObjects, obj)
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, runtimeObjects...)
+	dynamicClient.PrependReactor("patch", "*", applyReactor(dynamicClient.Tracker()))
+
+	mapper := kube.NewKubeMapper(ctx, discoveryClient)
+
+	return &Cluster{
+		Discovery:  discoveryClient,
+		Dynamic:    dynamicClient,
+		Mapper:     mapper,
+		KubeClient: kube.NewKubeClient(nil, dynamicClient, discoveryClient, mapper, kube.KubeClientOptions{}),
+	}
+}
+
+type Cluster struct {
+	Discovery  *DiscoveryClient
+	Dynamic    *dynamicfake.FakeDynamicClient
+	Mapper     *kube.KubeMapper
+	KubeClient *kube.KubeClient
+}
+
+var _ discovery.CachedDiscoveryInterface = (*DiscoveryClient)(nil)
+
+// DiscoveryClient counts invalidations of the discovery cache.
+type DiscoveryClient struct {
+	discovery.CachedDiscoveryInterface
+
+	invalidations atomic.Int32
+}
+
+func (c *DiscoveryClient) Invalidate() {
+	c.invalidations.Add(1)
+	c.CachedDiscoveryInterface.Invalidate()
+}
+
+func (c *DiscoveryClient) Invalidations() int {
+	return int(c.invalidations.Load())
+}
+
+// The fake dynamic client only applies to existing objects, while server-side apply creates missing
+// ones.
+func applyReactor(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patchAction, ok := action.(clienttesting.PatchAction)
+		if !ok || patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		if _, err := tracker.Get(action.GetResource(), action.GetNamespace(), patchAction.GetName()); !errors.IsNotFound(err) {
+			return false, nil, nil
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(patchAction.GetPatch(), &obj.Object); err != nil {
+			return true, nil, err
+		}
+
+		if err := tracker.Create(action.GetResource(), obj, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+
+		return true, obj, nil
+	}
+}
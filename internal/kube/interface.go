@@ -10,6 +10,8 @@ import (
 
 type KubeClienter interface {
 	Get(ctx context.Context, resource *id.ResourceID, opts KubeClientGetOptions) (*unstructured.Unstructured, error)
+	GetOrNil(ctx context.Context, resource *id.ResourceID, opts KubeClientGetOptions) (*unstructured.Unstructured, bool, error)
+	GetMany(ctx context.Context, resources []*id.ResourceID, opts KubeClientGetManyOptions) (map[string]*unstructured.Unstructured, error)
 	Create(ctx context.Context, resource *id.ResourceID, unstruct *unstructured.Unstructured, opts KubeClientCreateOptions) (*unstructured.Unstructured, error)
 	Apply(ctx context.Context, resource *id.ResourceID, unstruct *unstructured.Unstructured, opts KubeClientApplyOptions) (*unstructured.Unstructured, error)
 	MergePatch(ctx context.Context, resource *id.ResourceID, patch []byte) (*unstructured.Unstructured, error)
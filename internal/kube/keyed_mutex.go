@@ -0,0 +1,71 @@
+package kube
+
+import "sync"
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{
+		entries: map[string]*keyedMutexEntry{},
+	}
+}
+
+// keyedMutex holds a mutex per key only while someone holds or waits for it, so that the
+// number of entries doesn't grow with the number of keys ever locked.
+type keyedMutex struct {
+	mu      sync.Mutex
+	entries map[string]*keyedMutexEntry
+}
+
+type keyedMutexEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func (m *keyedMutex) Locker(key string) sync.Locker {
+	return &keyedLocker{
+		keyedMutex: m,
+		key:        key,
+	}
+}
+
+func (m *keyedMutex) lock(key string) *keyedMutexEntry {
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &keyedMutexEntry{}
+		m.entries[key] = entry
+	}
+	entry.refs++
+	m.mu.Unlock()
+
+	entry.mu.Lock()
+
+	return entry
+}
+
+func (m *keyedMutex) unlock(key string, entry *keyedMutexEntry) {
+	entry.mu.Unlock()
+
+	m.mu.Lock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(m.entries, key)
+	}
+	m.mu.Unlock()
+}
+
+type keyedLocker struct {
+	keyedMutex *keyedMutex
+	key        string
+	entry      *keyedMutexEntry
+}
+
+func (l *keyedLocker) Lock() {
+	l.entry = l.keyedMutex.lock(l.key)
+}
+
+func (l *keyedLocker) Unlock() {
+	entry := l.entry
+	l.entry = nil
+
+	l.keyedMutex.unlock(l.key, entry)
+}
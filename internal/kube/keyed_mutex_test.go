@@ -0,0 +1,83 @@
+package kube_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/kube"
+)
+
+var _ = Describe("keyed mutex", func() {
+	It("excludes holders of the same key and drops all entries after a parallel storm", func() {
+		const (
+			goroutines = 64
+			iterations = 200
+			keys       = 8
+		)
+
+		mutex := kube.NewKeyedMutex()
+
+		var holders [keys]atomic.Int32
+		var counters [keys]int
+		var maxHolders atomic.Int32
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+
+				for i := 0; i < iterations; i++ {
+					k := (g + i) % keys
+					locker := mutex.Locker(fmt.Sprintf("key-%d", k))
+
+					locker.Lock()
+					if n := holders[k].Add(1); n > maxHolders.Load() {
+						maxHolders.Store(n)
+					}
+					counters[k]++
+					holders[k].Add(-1)
+					locker.Unlock()
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		Expect(maxHolders.Load()).To(BeEquivalentTo(1))
+
+		var total int
+		for _, c := range counters {
+			total += c
+		}
+		Expect(total).To(Equal(goroutines * iterations))
+
+		Expect(mutex.Len()).To(BeZero())
+	})
+
+	It("keeps the entry while someone waits for it", func() {
+		mutex := kube.NewKeyedMutex()
+
+		holder := mutex.Locker("key")
+		holder.Lock()
+		Expect(mutex.Len()).To(Equal(1))
+
+		acquired := make(chan struct{})
+		go func() {
+			waiter := mutex.Locker("key")
+			waiter.Lock()
+			close(acquired)
+			waiter.Unlock()
+		}()
+
+		Consistently(acquired).ShouldNot(BeClosed())
+		Expect(mutex.Len()).To(Equal(1))
+
+		holder.Unlock()
+		Eventually(acquired).Should(BeClosed())
+		Eventually(mutex.Len).Should(BeZero())
+	})
+})
@@ -7,6 +7,7 @@ import (
 
 	"github.com/jellydator/ttlcache/v3"
 	"github.com/samber/lo"
+	"github.com/sourcegraph/conc/pool"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,26 +19,37 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/werf/nelm/internal/common"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var _ KubeClienter = (*KubeClient)(nil)
 
-func NewKubeClient(staticClient kubernetes.Interface, dynamicClient dynamic.Interface, discoveryClient discovery.CachedDiscoveryInterface, mapper meta.ResettableRESTMapper) *KubeClient {
+func NewKubeClient(staticClient kubernetes.Interface, dynamicClient dynamic.Interface, discoveryClient discovery.CachedDiscoveryInterface, mapper meta.ResettableRESTMapper, opts KubeClientOptions) *KubeClient {
 	clusterCache := ttlcache.New[string, *clusterCacheEntry](
 		ttlcache.WithDisableTouchOnHit[string, *clusterCacheEntry](),
 	)
 
-	return &KubeClient{
+	client := &KubeClient{
 		staticClient:    staticClient,
 		dynamicClient:   dynamicClient,
 		discoveryClient: discoveryClient,
 		mapper:          mapper,
 		clusterCache:    clusterCache,
-		resourceLocks:   &sync.Map{},
+		resourceLocks:   newKeyedMutex(),
 	}
+
+	if opts.WatchCache {
+		client.watchCache = newClusterCacheWatcher(dynamicClient, clusterCache)
+	}
+
+	return client
+}
+
+type KubeClientOptions struct {
+	// Keep cached resources up to date by watching them in the cluster.
+	WatchCache bool
 }
 
 type KubeClient struct {
@@ -46,7 +58,8 @@ type KubeClient struct {
 	discoveryClient discovery.CachedDiscoveryInterface
 	mapper          meta.ResettableRESTMapper
 	clusterCache    *ttlcache.Cache[string, *clusterCacheEntry]
-	resourceLocks   *sync.Map
+	resourceLocks   *keyedMutex
+	watchCache      *clusterCacheWatcher
 }
 
 type KubeClientGetOptions struct {
@@ -66,7 +79,7 @@ func (c *KubeClient) Get(ctx context.Context, resource *id.ResourceID, opts Kube
 
 			resultObj := res.Value().obj
 
-			log.Default.TraceStruct(ctx, resultObj, "Got resource %q from cache:", resource.HumanID())
+			log.Kube.TraceStruct(ctx, resultObj, "Got resource %q from cache:", resource.HumanID())
 
 			return resultObj, nil
 		}
@@ -84,20 +97,86 @@ func (c *KubeClient) Get(ctx context.Context, resource *id.ResourceID, opts Kube
 
 	clientResource := c.clientResource(gvr, resource.Namespace(), namespaced)
 
-	log.Default.Debug(ctx, "Getting resource %q", resource.HumanID())
+	log.Kube.Debug(ctx, "Getting resource %q", resource.HumanID())
 	resultObj, err := clientResource.Get(ctx, resource.Name(), metav1.GetOptions{})
 	if err != nil {
-		c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{err: err}, 0)
+		if errors.IsNotFound(err) {
+			c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{err: err}, notFoundCacheTTL)
+			c.watch(ctx, resource, gvr, namespaced)
+		} else {
+			c.clusterCache.Delete(resource.VersionID())
+		}
+
 		return nil, fmt.Errorf("get resource %q: %w", resource.HumanID(), err)
 	}
 	c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{obj: resultObj.DeepCopy()}, 0)
+	c.watch(ctx, resource, gvr, namespaced)
 
-	log.Default.TraceStruct(ctx, resultObj, "Got resource %q via Kubernetes API:", resource.HumanID())
+	log.Kube.TraceStruct(ctx, resultObj, "Got resource %q via Kubernetes API:", resource.HumanID())
 
 	return resultObj, nil
 }
 
+// GetOrNil is like Get, but returns found=false instead of an error if the resource doesn't exist.
+func (c *KubeClient) GetOrNil(ctx context.Context, resource *id.ResourceID, opts KubeClientGetOptions) (obj *unstructured.Unstructured, found bool, err error) {
+	obj, err = c.Get(ctx, resource, opts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	return obj, true, nil
+}
+
+type KubeClientGetManyOptions struct {
+	Concurrency int
+	TryCache    bool
+}
+
+// GetMany gets resources in parallel and caches the results, including errors, so that subsequent
+// Get calls with TryCache don't hit the Kubernetes API. Returned objects are keyed by VersionID.
+func (c *KubeClient) GetMany(ctx context.Context, resources []*id.ResourceID, opts KubeClientGetManyOptions) (map[string]*unstructured.Unstructured, error) {
+	concurrency := lo.Ternary(opts.Concurrency > 0, opts.Concurrency, DefaultGetManyConcurrency)
+
+	var (
+		mu      sync.Mutex
+		results = make(map[string]*unstructured.Unstructured, len(resources))
+		errs    []error
+	)
+
+	getPool := pool.New().WithContext(ctx).WithMaxGoroutines(concurrency)
+	for _, res := range resources {
+		res := res
+		getPool.Go(func(ctx context.Context) error {
+			obj, err := c.Get(ctx, res, KubeClientGetOptions{
+				TryCache: opts.TryCache,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				results[res.VersionID()] = obj
+			}
+
+			return nil
+		})
+	}
+
+	if err := getPool.Wait(); err != nil {
+		return nil, fmt.Errorf("wait for get pool: %w", err)
+	}
+
+	return results, util.Multierrorf("get %d resources", errs, len(errs))
+}
+
 type KubeClientCreateOptions struct {
+	DryRun        bool
 	ForceReplicas *int
 }
 
@@ -122,28 +201,40 @@ func (c *KubeClient) Create(ctx context.Context, resource *id.ResourceID, unstru
 		unstructured.SetNestedField(unstruct.UnstructuredContent(), int64(*opts.ForceReplicas), "spec", "replicas")
 	}
 
-	log.Default.Debug(ctx, "Server-side applying resource %q", resource.HumanID())
+	var dryRun []string
+	if opts.DryRun {
+		dryRun = []string{metav1.DryRunAll}
+	}
+
+	log.Kube.Debug(ctx, "Server-side %sapplying resource %q", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID())
 	resultObj, err := clientResource.Apply(ctx, resource.Name(), unstruct, metav1.ApplyOptions{
+		DryRun:       dryRun,
 		Force:        true,
 		FieldManager: common.DefaultFieldManager,
 	})
 	if err != nil {
-		c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{err: err}, 0)
-		return nil, fmt.Errorf("server-side apply resource %q: %w", resource.HumanID(), err)
+		if !opts.DryRun {
+			c.clusterCache.Delete(resource.VersionID())
+		}
+		return nil, fmt.Errorf("server-side %sapply resource %q: %w", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID(), err)
+	}
+	if !opts.DryRun {
+		c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{obj: resultObj.DeepCopy()}, 0)
 	}
-	c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{obj: resultObj.DeepCopy()}, 0)
 
-	if util.IsCRDFromGR(gvr.GroupResource()) {
-		c.mapper.Reset()
+	if util.IsCRDFromGR(gvr.GroupResource()) && !opts.DryRun {
+		c.refreshMapperForCRD(ctx, resultObj)
 	}
 
-	log.Default.TraceStruct(ctx, resultObj, "Created resource %q via Kubernetes API:", resource.HumanID())
+	log.Kube.TraceStruct(ctx, resultObj, "%s resource %q via Kubernetes API:", lo.Ternary(opts.DryRun, "Dry-run created", "Created"), resource.HumanID())
 
 	return resultObj, nil
 }
 
 type KubeClientApplyOptions struct {
 	DryRun bool
+	// One of metav1.FieldValidation*. If empty, the server default is used.
+	FieldValidation string
 }
 
 func (c *KubeClient) Apply(ctx context.Context, resource *id.ResourceID, unstruct *unstructured.Unstructured, opts KubeClientApplyOptions) (*unstructured.Unstructured, error) {
@@ -168,15 +259,22 @@ func (c *KubeClient) Apply(ctx context.Context, resource *id.ResourceID, unstruc
 		dryRun = []string{metav1.DryRunAll}
 	}
 
-	log.Default.Debug(ctx, "Server-side %sapplying resource %q", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID())
-	resultObj, err := clientResource.Apply(ctx, resource.Name(), unstruct, metav1.ApplyOptions{
-		DryRun:       dryRun,
-		Force:        true,
-		FieldManager: common.DefaultFieldManager,
+	// Same as clientResource.Apply, but metav1.ApplyOptions can't specify the field validation.
+	data, err := unstruct.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshal resource %q: %w", resource.HumanID(), err)
+	}
+
+	log.Kube.Debug(ctx, "Server-side %sapplying resource %q", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID())
+	resultObj, err := clientResource.Patch(ctx, resource.Name(), types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:          dryRun,
+		Force:           lo.ToPtr(true),
+		FieldManager:    common.DefaultFieldManager,
+		FieldValidation: opts.FieldValidation,
 	})
 	if err != nil {
 		if !opts.DryRun {
-			c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{err: err}, 0)
+			c.clusterCache.Delete(resource.VersionID())
 		}
 		return nil, fmt.Errorf("server-side %sapply resource %q: %w", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID(), err)
 	}
@@ -185,10 +283,10 @@ func (c *KubeClient) Apply(ctx context.Context, resource *id.ResourceID, unstruc
 	}
 
 	if util.IsCRDFromGR(gvr.GroupResource()) && !opts.DryRun {
-		c.mapper.Reset()
+		c.refreshMapperForCRD(ctx, resultObj)
 	}
 
-	log.Default.TraceStruct(ctx, resultObj, "Server-side %sapplied resource %q via Kubernetes API:", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID())
+	log.Kube.TraceStruct(ctx, resultObj, "Server-side %sapplied resource %q via Kubernetes API:", lo.Ternary(opts.DryRun, "dry-run ", ""), resource.HumanID())
 
 	return resultObj, nil
 }
@@ -210,22 +308,22 @@ func (c *KubeClient) MergePatch(ctx context.Context, resource *id.ResourceID, pa
 
 	clientResource := c.clientResource(gvr, resource.Namespace(), namespaced)
 
-	log.Default.Debug(ctx, "Merge patching resource %q", resource.HumanID())
+	log.Kube.Debug(ctx, "Merge patching resource %q", resource.HumanID())
 	resultObj, err := clientResource.Patch(ctx, resource.Name(), types.MergePatchType, patch, metav1.PatchOptions{
 		FieldManager: common.DefaultFieldManager,
 	})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.Default.Debug(ctx, "Skipping merge patching, not found resource %q", resource.HumanID())
+			log.Kube.Debug(ctx, "Skipping merge patching, not found resource %q", resource.HumanID())
 			return nil, nil
 		}
 
-		c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{err: err}, 0)
+		c.clusterCache.Delete(resource.VersionID())
 		return nil, fmt.Errorf("merge patch resource %q: %w", resource.HumanID(), err)
 	}
 	c.clusterCache.Set(resource.VersionID(), &clusterCacheEntry{obj: resultObj.DeepCopy()}, 0)
 
-	log.Default.TraceStruct(ctx, resultObj, "Merge patched resource %q via Kubernetes API:", resource.HumanID())
+	log.Kube.TraceStruct(ctx, resultObj, "Merge patched resource %q via Kubernetes API:", resource.HumanID())
 
 	return resultObj, nil
 }
@@ -258,12 +356,12 @@ func (c *KubeClient) Delete(ctx context.Context, resource *id.ResourceID, opts K
 		propagationPolicy = lo.ToPtr(metav1.DeletePropagationForeground)
 	}
 
-	log.Default.Debug(ctx, "Deleting resource %q", resource.HumanID())
+	log.Kube.Debug(ctx, "Deleting resource %q", resource.HumanID())
 	if err := clientResource.Delete(ctx, resource.Name(), metav1.DeleteOptions{
 		PropagationPolicy: propagationPolicy,
 	}); err != nil {
 		if errors.IsNotFound(err) {
-			log.Default.Debug(ctx, "Skipping deletion, not found resource %q", resource.HumanID())
+			log.Kube.Debug(ctx, "Skipping deletion, not found resource %q", resource.HumanID())
 			return nil
 		}
 
@@ -274,9 +372,48 @@ func (c *KubeClient) Delete(ctx context.Context, resource *id.ResourceID, opts K
 	return nil
 }
 
-func (c *KubeClient) resourceLock(resource *id.ResourceID) *sync.Mutex {
-	lock, _ := c.resourceLocks.LoadOrStore(resource.VersionID(), &sync.Mutex{})
-	return lock.(*sync.Mutex)
+func (c *KubeClient) refreshMapperForCRD(ctx context.Context, crd *unstructured.Unstructured) {
+	if adder, ok := c.mapper.(crdMappingsAdder); ok {
+		if err := adder.AddCRDMappings(crd); err != nil {
+			log.Kube.Debug(ctx, "Unable to add mappings for CRD %q, resetting mapper: %s", crd.GetName(), err)
+		} else {
+			return
+		}
+	}
+
+	c.mapper.Reset()
+}
+
+// Close stops watches started in the watch cache mode and waits for them to finish until ctx is
+// canceled.
+func (c *KubeClient) Close(ctx context.Context) error {
+	if c.watchCache == nil {
+		return nil
+	}
+
+	return c.watchCache.Close(ctx)
+}
+
+func (c *KubeClient) watch(ctx context.Context, resource *id.ResourceID, gvr schema.GroupVersionResource, namespaced bool) {
+	if c.watchCache == nil {
+		return
+	}
+
+	var namespace string
+	if namespaced {
+		namespace = resource.Namespace()
+	}
+
+	c.watchCache.Watch(ctx, clusterCacheWatchKey{
+		gvr:         gvr,
+		gvk:         resource.GroupVersionKind(),
+		namespace:   namespace,
+		idNamespace: resource.Namespace(),
+	})
+}
+
+func (c *KubeClient) resourceLock(resource *id.ResourceID) sync.Locker {
+	return c.resourceLocks.Locker(resource.VersionID())
 }
 
 func (c *KubeClient) clientResource(gvr schema.GroupVersionResource, namespace string, namespaced bool) dynamic.ResourceInterface {
@@ -291,3 +428,7 @@ type clusterCacheEntry struct {
 	obj *unstructured.Unstructured
 	err error
 }
+
+type crdMappingsAdder interface {
+	AddCRDMappings(crd *unstructured.Unstructured) error
+}
@@ -9,7 +9,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/pkg/log"
 )
 
 type KubeConfigOptions struct {
@@ -33,6 +33,7 @@ type KubeConfigOptions struct {
 	TLSServerName         string
 	Timeout               string
 	Token                 string
+	TokenFile             string
 	Username              string
 }
 
@@ -46,6 +47,7 @@ func NewKubeConfig(ctx context.Context, kubeConfigPaths []string, opts KubeConfi
 			ImpersonateUID:    opts.ImpersonateUID,
 			Password:          opts.Password,
 			Token:             opts.Token,
+			TokenFile:         opts.TokenFile,
 			Username:          opts.Username,
 		},
 		ClusterDefaults: clientcmd.ClusterDefaults,
@@ -108,7 +110,7 @@ func NewKubeConfig(ctx context.Context, kubeConfigPaths []string, opts KubeConfi
 		RestConfig:         restConfig,
 	}
 
-	log.Default.TraceStruct(ctx, kubeConfig, "Constructed KubeConfig:")
+	log.Kube.TraceStruct(ctx, kubeConfig, "Constructed KubeConfig:")
 
 	return kubeConfig, nil
 }
@@ -2,20 +2,242 @@ package kube
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/restmapper"
 
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/pkg/log"
 )
 
-func NewKubeMapper(ctx context.Context, discoveryClient discovery.CachedDiscoveryInterface) meta.RESTMapper {
+var _ meta.ResettableRESTMapper = (*KubeMapper)(nil)
+
+func NewKubeMapper(ctx context.Context, discoveryClient discovery.CachedDiscoveryInterface) *KubeMapper {
 	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
 
 	expander := restmapper.NewShortcutExpander(mapper, discoveryClient, func(msg string) {
-		log.Default.Warn(ctx, msg)
+		log.Kube.Warn(ctx, msg)
 	})
 
-	return expander
+	return &KubeMapper{
+		delegate:  expander.(meta.ResettableRESTMapper),
+		crdMapper: meta.NewDefaultRESTMapper(nil),
+	}
+}
+
+// KubeMapper resolves kinds of applied CRDs straight from their specs, so that applying a CRD
+// doesn't require invalidating the whole discovery cache.
+type KubeMapper struct {
+	delegate        meta.ResettableRESTMapper
+	crdMapper       *meta.DefaultRESTMapper
+	mu              sync.RWMutex
+	autoInvalidated atomic.Bool
+}
+
+func (m *KubeMapper) AddCRDMappings(crd *unstructured.Unstructured) error {
+	group, _, err := unstructured.NestedString(crd.Object, "spec", "group")
+	if err != nil {
+		return fmt.Errorf("get spec.group of CRD %q: %w", crd.GetName(), err)
+	} else if group == "" {
+		return fmt.Errorf("no spec.group found in CRD %q", crd.GetName())
+	}
+
+	kind, _, err := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	if err != nil {
+		return fmt.Errorf("get spec.names.kind of CRD %q: %w", crd.GetName(), err)
+	} else if kind == "" {
+		return fmt.Errorf("no spec.names.kind found in CRD %q", crd.GetName())
+	}
+
+	plural, _, err := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	if err != nil {
+		return fmt.Errorf("get spec.names.plural of CRD %q: %w", crd.GetName(), err)
+	} else if plural == "" {
+		return fmt.Errorf("no spec.names.plural found in CRD %q", crd.GetName())
+	}
+
+	singular, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "singular")
+	if singular == "" {
+		singular = strings.ToLower(kind)
+	}
+
+	scopeName, _, _ := unstructured.NestedString(crd.Object, "spec", "scope")
+	scope := meta.RESTScopeRoot
+	if scopeName == "Namespaced" {
+		scope = meta.RESTScopeNamespace
+	}
+
+	var versions []string
+	if version, found, _ := unstructured.NestedString(crd.Object, "spec", "version"); found && version != "" {
+		versions = append(versions, version)
+	}
+
+	versionsList, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versionsList {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if served, found, _ := unstructured.NestedBool(version, "served"); found && !served {
+			continue
+		}
+
+		if name, _, _ := unstructured.NestedString(version, "name"); name != "" {
+			versions = append(versions, name)
+		}
+	}
+
+	if len(versions) == 0 {
+		return fmt.Errorf("no served versions found in CRD %q", crd.GetName())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, version := range versions {
+		gvk := schema.GroupVersionKind{Group: group, Version: version, Kind: kind}
+
+		m.crdMapper.AddSpecific(
+			gvk,
+			gvk.GroupVersion().WithResource(plural),
+			gvk.GroupVersion().WithResource(singular),
+			scope,
+		)
+	}
+
+	return nil
+}
+
+func (m *KubeMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	m.mu.RLock()
+	gvk, err := m.crdMapper.KindFor(resource)
+	m.mu.RUnlock()
+	if err == nil {
+		return gvk, nil
+	}
+
+	gvk, err = m.delegate.KindFor(resource)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.KindFor(resource)
+	}
+
+	return gvk, err
+}
+
+func (m *KubeMapper) KindsFor(resource schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	m.mu.RLock()
+	gvks, err := m.crdMapper.KindsFor(resource)
+	m.mu.RUnlock()
+	if err == nil {
+		return gvks, nil
+	}
+
+	gvks, err = m.delegate.KindsFor(resource)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.KindsFor(resource)
+	}
+
+	return gvks, err
+}
+
+func (m *KubeMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	m.mu.RLock()
+	gvr, err := m.crdMapper.ResourceFor(input)
+	m.mu.RUnlock()
+	if err == nil {
+		return gvr, nil
+	}
+
+	gvr, err = m.delegate.ResourceFor(input)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.ResourceFor(input)
+	}
+
+	return gvr, err
+}
+
+func (m *KubeMapper) ResourcesFor(input schema.GroupVersionResource) ([]schema.GroupVersionResource, error) {
+	m.mu.RLock()
+	gvrs, err := m.crdMapper.ResourcesFor(input)
+	m.mu.RUnlock()
+	if err == nil {
+		return gvrs, nil
+	}
+
+	gvrs, err = m.delegate.ResourcesFor(input)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.ResourcesFor(input)
+	}
+
+	return gvrs, err
+}
+
+func (m *KubeMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	m.mu.RLock()
+	mapping, err := m.crdMapper.RESTMapping(gk, versions...)
+	m.mu.RUnlock()
+	if err == nil {
+		return mapping, nil
+	}
+
+	mapping, err = m.delegate.RESTMapping(gk, versions...)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.RESTMapping(gk, versions...)
+	}
+
+	return mapping, err
+}
+
+func (m *KubeMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	m.mu.RLock()
+	mappings, err := m.crdMapper.RESTMappings(gk, versions...)
+	m.mu.RUnlock()
+	if err == nil && len(mappings) > 0 {
+		return mappings, nil
+	}
+
+	mappings, err = m.delegate.RESTMappings(gk, versions...)
+	if err != nil && m.shouldInvalidate(err) {
+		return m.delegate.RESTMappings(gk, versions...)
+	}
+
+	return mappings, err
+}
+
+func (m *KubeMapper) ResourceSingularizer(resource string) (string, error) {
+	m.mu.RLock()
+	singular, err := m.crdMapper.ResourceSingularizer(resource)
+	m.mu.RUnlock()
+	if err == nil {
+		return singular, nil
+	}
+
+	return m.delegate.ResourceSingularizer(resource)
+}
+
+func (m *KubeMapper) Reset() {
+	m.mu.Lock()
+	m.crdMapper = meta.NewDefaultRESTMapper(nil)
+	m.mu.Unlock()
+
+	m.delegate.Reset()
+}
+
+// Discovery cache might be stale, e.g. if CRDs were installed out-of-band, so the first "no
+// matches for kind" error leads to a single discovery invalidation per mapper.
+func (m *KubeMapper) shouldInvalidate(err error) bool {
+	if !meta.IsNoMatchError(err) || !m.autoInvalidated.CompareAndSwap(false, true) {
+		return false
+	}
+
+	m.delegate.Reset()
+
+	return true
 }
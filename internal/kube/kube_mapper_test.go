@@ -0,0 +1,103 @@
+package kube_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var _ = Describe("kube mapper", func() {
+	crdID := func(cluster *fake.Cluster, name string) *id.ResourceID {
+		return id.NewResourceID(name, "", schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, id.ResourceIDOptions{Mapper: cluster.Mapper})
+	}
+
+	It("resolves kinds of applied CRDs without invalidating discovery", func() {
+		ctx := context.Background()
+		cluster := fake.NewCluster(ctx)
+
+		const crdsCount = 5
+		for i := range crdsCount {
+			crd := unstructFromYAML(fmt.Sprintf(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets%[1]d.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    kind: Widget%[1]d
+    plural: widgets%[1]d
+  versions:
+  - name: v1alpha1
+    served: false
+  - name: v1
+    served: true
+`, i))
+
+			_, err := cluster.KubeClient.Apply(ctx, crdID(cluster, crd.GetName()), crd, kube.KubeClientApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(cluster.Discovery.Invalidations()).To(BeZero())
+
+		for i := range crdsCount {
+			mapping, err := cluster.Mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: fmt.Sprintf("Widget%d", i)}, "v1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapping.Resource).To(Equal(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: fmt.Sprintf("widgets%d", i)}))
+			Expect(mapping.Scope).To(Equal(meta.RESTScopeNamespace))
+
+			_, err = cluster.Mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: fmt.Sprintf("Widget%d", i)}, "v1alpha1")
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("invalidates stale discovery once and resolves kinds installed out-of-band", func() {
+		ctx := context.Background()
+
+		fakeDiscovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: fake.APIResources}}
+		discoveryClient := &fake.DiscoveryClient{CachedDiscoveryInterface: memory.NewMemCacheClient(fakeDiscovery)}
+		mapper := kube.NewKubeMapper(ctx, discoveryClient)
+
+		// Discovery is cached before the CRD is installed.
+		_, err := mapper.RESTMapping(schema.GroupKind{Kind: "ConfigMap"}, "v1")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeDiscovery.Resources = append(fakeDiscovery.Resources, &metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+		})
+
+		mapping, err := mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping.Resource).To(Equal(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}))
+		Expect(discoveryClient.Invalidations()).To(Equal(1))
+
+		_, err = mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: "Gadget"}, "v1")
+		Expect(meta.IsNoMatchError(err)).To(BeTrue())
+		Expect(discoveryClient.Invalidations()).To(Equal(1))
+	})
+
+	It("falls back to resetting the mapper for CRDs without kind", func() {
+		ctx := context.Background()
+		cluster := fake.NewCluster(ctx)
+
+		crd := unstructFromYAML(`{apiVersion: apiextensions.k8s.io/v1, kind: CustomResourceDefinition, metadata: {name: broken.example.com}, spec: {group: example.com}}`)
+		_, err := cluster.KubeClient.Apply(ctx, crdID(cluster, crd.GetName()), crd, kube.KubeClientApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cluster.Discovery.Invalidations()).To(Equal(1))
+	})
+})
@@ -0,0 +1,58 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MatchProtectedContext returns the first pattern matching either the kube context name or the
+// cluster URL. Patterns are globs, where "*" matches any sequence of characters, including "/".
+func MatchProtectedContext(contextName, clusterURL string, patterns []string) (pattern string, matched bool) {
+	for _, pattern := range patterns {
+		regex := protectedContextPatternRegex(pattern)
+
+		if (contextName != "" && regex.MatchString(contextName)) || (clusterURL != "" && regex.MatchString(clusterURL)) {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// LoadProtectedContextPatterns reads patterns from the file, one per line. Empty lines and lines
+// starting with "#" are skipped.
+func LoadProtectedContextPatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading protected contexts file %q: %w", path, err)
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning protected contexts file %q: %w", path, err)
+	}
+
+	return patterns, nil
+}
+
+func protectedContextPatternRegex(pattern string) *regexp.Regexp {
+	regex := regexp.QuoteMeta(pattern)
+	regex = strings.ReplaceAll(regex, `\*`, ".*")
+	regex = strings.ReplaceAll(regex, `\?`, ".")
+
+	return regexp.MustCompile("^" + regex + "$")
+}
@@ -0,0 +1,39 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// IsTransientError returns true if the request failed because of the apiserver or the network being
+// temporarily unavailable or overloaded, so that the same request might succeed if retried.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch {
+	case apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsServiceUnavailable(err),
+		utilnet.IsConnectionReset(err),
+		utilnet.IsConnectionRefused(err),
+		utilnet.IsProbableEOF(err),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
@@ -1,7 +0,0 @@
-package log
-
-var (
-	Default        Logger = DefaultLogboek
-	DefaultLogboek        = NewLogboekLogger()
-	DefaultNull           = NewNullLogger()
-)
@@ -12,23 +12,31 @@ import (
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
 	HiddenInsignificantOutput  = "<hidden insignificant output>"
 	HiddenInsignificantChanges = "<hidden insignificant changes>"
-	HiddenSensitiveOutput      = "<hidden sensitive output>"
-	HiddenSensitiveChanges     = "<hidden sensitive changes>"
 )
 
+type CalculatePlannedChangesOptions struct {
+	// Unchanged lines shown around each change in diffs. Default is used if negative.
+	DiffContextLines int
+	// Show sensitive data of the resources in diffs instead of their hashes.
+	ShowSensitiveDiffs bool
+}
+
 func CalculatePlannedChanges(
 	releaseName string,
 	releaseNamespace string,
+	releaseNamespaceInfo *info.DeployableReleaseNamespaceInfo,
 	standaloneCRDsInfos []*info.DeployableStandaloneCRDInfo,
 	hookResourcesInfos []*info.DeployableHookResourceInfo,
 	generalResourcesInfos []*info.DeployableGeneralResourceInfo,
 	prevReleaseGeneralResourceInfos []*info.DeployablePrevReleaseGeneralResourceInfo,
 	prevRelFailed bool,
+	opts CalculatePlannedChangesOptions,
 ) (
 	createdChanges []*CreatedResourceChange,
 	recreatedChanges []*RecreatedResourceChange,
@@ -41,19 +49,25 @@ func CalculatePlannedChanges(
 
 	allChanges := make([]any, 0)
 
-	if changes, present := standaloneCRDChanges(standaloneCRDsInfos); present {
+	if releaseNamespaceInfo != nil {
+		if change, present := releaseNamespaceChange(releaseNamespaceInfo, opts); present {
+			allChanges = append(allChanges, change)
+		}
+	}
+
+	if changes, present := standaloneCRDChanges(standaloneCRDsInfos, opts); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := hookResourcesChanges(hookResourcesInfos, prevRelFailed, releaseName, releaseNamespace); present {
+	if changes, present := hookResourcesChanges(hookResourcesInfos, prevRelFailed, releaseName, releaseNamespace, opts); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := generalResourcesChanges(generalResourcesInfos, prevRelFailed, releaseName, releaseNamespace); present {
+	if changes, present := generalResourcesChanges(generalResourcesInfos, prevRelFailed, releaseName, releaseNamespace, opts); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := prevReleaseGeneralResourcesChanges(prevReleaseGeneralResourceInfos, curReleaseExistResourcesUIDs, releaseName, releaseNamespace); present {
+	if changes, present := prevReleaseGeneralResourcesChanges(prevReleaseGeneralResourceInfos, curReleaseExistResourcesUIDs, releaseName, releaseNamespace, opts); present {
 		allChanges = append(allChanges, changes...)
 	}
 
@@ -81,11 +95,39 @@ func CalculatePlannedChanges(
 	return createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, true
 }
 
-func standaloneCRDChanges(infos []*info.DeployableStandaloneCRDInfo) (changes []any, present bool) {
+func releaseNamespaceChange(info *info.DeployableReleaseNamespaceInfo, opts CalculatePlannedChangesOptions) (change any, present bool) {
+	if info.ShouldCreate() {
+		return &CreatedResourceChange{
+			ResourceID: info.ResourceID,
+			Udiff:      HiddenInsignificantOutput,
+		}, true
+	} else if info.ShouldUpdate() {
+		uDiff, nonEmptyDiff := updateUnifiedDiff(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured(), nil, false, opts.DiffContextLines)
+		if !nonEmptyDiff {
+			uDiff = HiddenInsignificantChanges
+		}
+
+		return &UpdatedResourceChange{
+			ResourceID: info.ResourceID,
+			Udiff:      uDiff,
+		}, true
+	} else if info.ShouldApply() {
+		return &AppliedResourceChange{
+			ResourceID: info.ResourceID,
+			Udiff:      HiddenInsignificantOutput,
+		}, true
+	}
+
+	return nil, false
+}
+
+func standaloneCRDChanges(infos []*info.DeployableStandaloneCRDInfo, opts CalculatePlannedChangesOptions) (changes []any, present bool) {
 	for _, info := range infos {
 		create := info.ShouldCreate()
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
+		ignoredFields, _ := info.Resource().IgnoreFields()
+		redact := !opts.ShowSensitiveDiffs && resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
 
 		if create {
 			uDiff := HiddenInsignificantOutput
@@ -95,7 +137,7 @@ func standaloneCRDChanges(infos []*info.DeployableStandaloneCRDInfo) (changes []
 				Udiff:      uDiff,
 			})
 		} else if update {
-			uDiff, nonEmptyDiff := util.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured()))
+			uDiff, nonEmptyDiff := updateUnifiedDiff(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured(), ignoredFields, redact, opts.DiffContextLines)
 			if !nonEmptyDiff {
 				uDiff = HiddenInsignificantChanges
 			}
@@ -117,25 +159,24 @@ func standaloneCRDChanges(infos []*info.DeployableStandaloneCRDInfo) (changes []
 	return changes, len(changes) > 0
 }
 
-func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string) (changes []any, present bool) {
+func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string, opts CalculatePlannedChangesOptions) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := util.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSensitive := resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
+		redact := !opts.ShowSensitiveDiffs && resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
 		create := info.ShouldCreate()
 		recreate := info.ShouldRecreate()
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
 		cleanup := info.ShouldCleanup(releaseName, releaseNamespace)
 		cleanupOnFailure := info.ShouldCleanupOnFailed(prevRelFailed, releaseName, releaseNamespace)
+		ignoredFields, _ := info.Resource().IgnoreFields()
 
 		if create {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &CreatedResourceChange{
@@ -143,15 +184,14 @@ func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFaile
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				DryCreateErr:       info.DryCreateErr(),
 			})
 		} else if recreate {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &RecreatedResourceChange{
@@ -162,12 +202,8 @@ func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFaile
 			})
 		} else if update {
 			var uDiff string
-			if ud, nonEmpty := util.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured())); nonEmpty {
-				if isSensitive {
-					uDiff = HiddenSensitiveChanges
-				} else {
-					uDiff = ud
-				}
+			if ud, nonEmpty := updateUnifiedDiff(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured(), ignoredFields, redact, opts.DiffContextLines); nonEmpty {
+				uDiff = ud
 			} else {
 				uDiff = HiddenInsignificantChanges
 			}
@@ -182,10 +218,8 @@ func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFaile
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &AppliedResourceChange{
@@ -200,25 +234,25 @@ func hookResourcesChanges(infos []*info.DeployableHookResourceInfo, prevRelFaile
 	return changes, len(changes) > 0
 }
 
-func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string) (changes []any, present bool) {
+func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string, opts CalculatePlannedChangesOptions) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := util.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSensitive := resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
+		redact := !opts.ShowSensitiveDiffs && resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
 		create := info.ShouldCreate()
 		recreate := info.ShouldRecreate()
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
+		adopt := info.ShouldAdopt()
 		cleanup := info.ShouldCleanup(releaseName, releaseNamespace)
 		cleanupOnFailure := info.ShouldCleanupOnFailed(prevRelFailed, releaseName, releaseNamespace)
+		ignoredFields, _ := info.Resource().IgnoreFields()
 
 		if create {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &CreatedResourceChange{
@@ -226,15 +260,14 @@ func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRe
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				DryCreateErr:       info.DryCreateErr(),
 			})
 		} else if recreate {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &RecreatedResourceChange{
@@ -245,12 +278,8 @@ func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRe
 			})
 		} else if update {
 			var uDiff string
-			if ud, nonEmpty := util.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured())); nonEmpty {
-				if isSensitive {
-					uDiff = HiddenSensitiveChanges
-				} else {
-					uDiff = ud
-				}
+			if ud, nonEmpty := updateUnifiedDiff(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured(), ignoredFields, redact, opts.DiffContextLines); nonEmpty {
+				uDiff = ud
 			} else {
 				uDiff = HiddenInsignificantChanges
 			}
@@ -260,15 +289,14 @@ func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRe
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				Adopted:            adopt,
 			})
 		} else if apply {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(util.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured(), redact), opts.DiffContextLines))
 			}
 
 			changes = append(changes, &AppliedResourceChange{
@@ -276,6 +304,7 @@ func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRe
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				Adopted:            adopt,
 			})
 		}
 	}
@@ -283,20 +312,18 @@ func generalResourcesChanges(infos []*info.DeployableGeneralResourceInfo, prevRe
 	return changes, len(changes) > 0
 }
 
-func prevReleaseGeneralResourcesChanges(infos []*info.DeployablePrevReleaseGeneralResourceInfo, curReleaseExistResourcesUIDs []types.UID, releaseName, releaseNamespace string) (changes []any, present bool) {
+func prevReleaseGeneralResourcesChanges(infos []*info.DeployablePrevReleaseGeneralResourceInfo, curReleaseExistResourcesUIDs []types.UID, releaseName, releaseNamespace string, opts CalculatePlannedChangesOptions) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := util.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSensitive := resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
+		redact := !opts.ShowSensitiveDiffs && resource.IsSensitive(info.ResourceID.GroupVersionKind().GroupKind(), info.Resource().Unstructured().GetAnnotations())
 		delete := info.ShouldDelete(curReleaseExistResourcesUIDs, releaseName, releaseNamespace)
 
 		if delete {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSensitive {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(util.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), ""))
+				uDiff = lo.Must(util.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured(), redact), "", opts.DiffContextLines))
 			}
 
 			changes = append(changes, &DeletedResourceChange{
@@ -309,7 +336,18 @@ func prevReleaseGeneralResourcesChanges(infos []*info.DeployablePrevReleaseGener
 	return changes, len(changes) > 0
 }
 
-func diffableResource(unstruct *unstructured.Unstructured) string {
+// Ignored fields are never changed on update, so they are excluded from the diff.
+func updateUnifiedDiff(live, dryApply *unstructured.Unstructured, ignoredFields []*resource.FieldPath, redact bool, contextLines int) (uDiff string, nonEmpty bool) {
+	return util.ColoredUnifiedDiff(diffableResource(resource.WithoutFields(live, ignoredFields), redact), diffableResource(resource.WithoutFields(dryApply, ignoredFields), redact), contextLines)
+}
+
+// Sensitive data is replaced with its hash if redact is set.
+func diffableResource(unstruct *unstructured.Unstructured, redact bool) string {
+	// Must be done before the werf.io annotations are removed, since they tell what is sensitive.
+	if redact {
+		unstruct = &unstructured.Unstructured{Object: log.RedactSensitiveResource(unstruct.Object)}
+	}
+
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "creationTimestamp")
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "generation")
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "resourceVersion")
@@ -355,6 +393,9 @@ type CreatedResourceChange struct {
 	Udiff              string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	// DryCreateErr is set if the server rejected the resource on dry-run creation, so creating it
+	// is expected to fail.
+	DryCreateErr error
 }
 
 type RecreatedResourceChange struct {
@@ -371,6 +412,7 @@ type UpdatedResourceChange struct {
 	Udiff              string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	Adopted            bool
 }
 
 type AppliedResourceChange struct {
@@ -379,6 +421,7 @@ type AppliedResourceChange struct {
 	Udiff              string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	Adopted            bool
 }
 
 type DeletedResourceChange struct {
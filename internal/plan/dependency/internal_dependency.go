@@ -19,15 +19,19 @@ func NewInternalDependency(matchNames, matchNamespaces, matchGroups, matchVersio
 	return &InternalDependency{
 		ResourceMatcher: resMatcher,
 		ResourceState:   resourceState,
+		Source:          opts.Source,
 	}
 }
 
 type InternalDependencyOptions struct {
 	DefaultNamespace string
 	ResourceState    ResourceState
+	Source           string
 }
 
 type InternalDependency struct {
 	*matcher.ResourceMatcher
 	ResourceState ResourceState
+	// Where the dependency comes from, e.g. the annotation, in the human-readable form.
+	Source string
 }
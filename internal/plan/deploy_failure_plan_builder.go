@@ -16,6 +16,7 @@ import (
 	"github.com/werf/nelm/internal/plan/operation"
 	info "github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
 )
 
@@ -50,12 +51,22 @@ func NewDeployFailurePlanBuilder(
 		deployPlan:           deployPlan,
 		plan:                 plan,
 		deletionTimeout:      opts.DeletionTimeout,
+		cleanupOnFail:        opts.CleanupOnFail,
 	}
 }
 
 type DeployFailurePlanBuilderOptions struct {
 	PrevRelease     *release.Release
 	DeletionTimeout time.Duration
+	CleanupOnFail   bool
+}
+
+// KeptOnFailResource is a resource created by the failed deploy, which is intentionally not deleted
+// with CleanupOnFail.
+type KeptOnFailResource struct {
+	*id.ResourceID
+
+	Reason string
 }
 
 type DeployFailurePlanBuilder struct {
@@ -73,11 +84,18 @@ type DeployFailurePlanBuilder struct {
 	deployPlan           *Plan
 	plan                 *Plan
 	deletionTimeout      time.Duration
+	cleanupOnFail        bool
+
+	cleanupOnFailResources []*id.ResourceID
+	keptOnFailResources    []*KeptOnFailResource
 }
 
 func (b *DeployFailurePlanBuilder) Build(ctx context.Context) (*Plan, error) {
-	opFailRelease := operation.NewFailReleaseOperation(b.newRelease, b.history)
-	b.plan.AddOperation(opFailRelease)
+	// Pre-plan hooks run before the pending release is created, so there might be no release to fail.
+	if op, found := b.deployPlan.Operation("%s", operation.TypeCreatePendingReleaseOperation+"/"+b.newRelease.ID()); !found || op.Status() == operation.StatusCompleted {
+		opFailRelease := operation.NewFailReleaseOperation(b.newRelease, b.history)
+		b.plan.AddOperation(opFailRelease)
+	}
 
 	var prevReleaseFailed bool
 	if b.prevRelease != nil {
@@ -192,5 +210,127 @@ func (b *DeployFailurePlanBuilder) Build(ctx context.Context) (*Plan, error) {
 		}
 	}
 
+	if b.cleanupOnFail {
+		if err := b.setupCleanupOnFailOperations(); err != nil {
+			return nil, fmt.Errorf("error setting up cleanup on fail operations: %w", err)
+		}
+	}
+
 	return b.plan, nil
 }
+
+// CleanupOnFailResources returns resources created by the failed deploy, which are deleted by the
+// plan. Available after Build.
+func (b *DeployFailurePlanBuilder) CleanupOnFailResources() []*id.ResourceID {
+	return b.cleanupOnFailResources
+}
+
+// KeptOnFailResources returns resources created by the failed deploy, which are not deleted by the
+// plan. Available after Build.
+func (b *DeployFailurePlanBuilder) KeptOnFailResources() []*KeptOnFailResource {
+	return b.keptOnFailResources
+}
+
+// Only resources which didn't exist before the deploy are deleted. Recreated resources existed
+// before, so they are kept.
+func (b *DeployFailurePlanBuilder) setupCleanupOnFailOperations() error {
+	completedOps, found, err := b.deployPlan.WorthyCompletedOperations()
+	if err != nil {
+		return fmt.Errorf("error getting completed operations: %w", err)
+	} else if !found {
+		return nil
+	}
+
+	seenResources := map[string]bool{}
+
+	for _, op := range completedOps {
+		var recreated bool
+		switch op.Type() {
+		case operation.TypeCreateResourceOperation, operation.TypeExtraPostCreateResourceOperation:
+		case operation.TypeRecreateResourceOperation, operation.TypeExtraPostRecreateResourceOperation:
+			recreated = true
+		default:
+			continue
+		}
+
+		resID := op.(interface{ ResourceID() *id.ResourceID }).ResourceID()
+		if seenResources[resID.ID()] {
+			continue
+		}
+		seenResources[resID.ID()] = true
+
+		var keepReason string
+		if recreated {
+			keepReason = "existed before the deploy"
+		} else if util.IsCRDFromGK(resID.GroupVersionKind().GroupKind()) {
+			keepReason = "deleting CRD would delete all of its custom resources"
+		} else if b.keepOnDelete(resID) {
+			keepReason = "resource policy is keep"
+		}
+
+		if keepReason != "" {
+			b.keptOnFailResources = append(b.keptOnFailResources, &KeptOnFailResource{
+				ResourceID: resID,
+				Reason:     keepReason,
+			})
+
+			continue
+		}
+
+		b.cleanupOnFailResources = append(b.cleanupOnFailResources, resID)
+
+		// Might already be deleted because of the delete policy.
+		if _, found := b.plan.Operation(operation.TypeDeleteResourceOperation + "/" + resID.ID()); found {
+			continue
+		}
+
+		cleanupOp := operation.NewDeleteResourceOperation(
+			resID,
+			b.kubeClient,
+			operation.DeleteResourceOperationOptions{},
+		)
+		b.plan.AddOperation(cleanupOp)
+
+		taskState := kdutil.NewConcurrent(
+			statestore.NewAbsenceTaskState(
+				resID.Name(),
+				resID.Namespace(),
+				resID.GroupVersionKind(),
+				statestore.AbsenceTaskStateOptions{},
+			),
+		)
+		b.taskStore.AddAbsenceTaskState(taskState)
+
+		trackDeletionOp := operation.NewTrackResourceAbsenceOperation(
+			resID,
+			taskState,
+			b.dynamicClient,
+			b.mapper,
+			operation.TrackResourceAbsenceOperationOptions{
+				Timeout: b.deletionTimeout,
+			},
+		)
+		b.plan.AddOperation(trackDeletionOp)
+		if err := b.plan.AddDependency(cleanupOp.ID(), trackDeletionOp.ID()); err != nil {
+			return fmt.Errorf("error adding dependency: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *DeployFailurePlanBuilder) keepOnDelete(resID *id.ResourceID) bool {
+	for _, info := range b.generalResourceInfos {
+		if info.ID() == resID.ID() {
+			return info.ShouldKeepOnDelete(b.newRelease.Name(), b.releaseNamespace)
+		}
+	}
+
+	for _, info := range b.hookResourceInfos {
+		if info.ID() == resID.ID() {
+			return info.ShouldKeepOnDelete(b.newRelease.Name(), b.releaseNamespace)
+		}
+	}
+
+	return false
+}
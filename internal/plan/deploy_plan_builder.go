@@ -2,6 +2,7 @@ package plan
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -21,16 +23,20 @@ import (
 	kdutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan/dependency"
 	"github.com/werf/nelm/internal/plan/operation"
 	info "github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
 	resid "github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/track"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var StageOpNamesOrdered = []string{
+	StageOpNamePrefixReleaseNamespace,
+	StageOpNamePrefixPrePlanHooks,
 	StageOpNamePrefixInit,
 	StageOpNamePrefixStandaloneCRDs,
 	StageOpNamePrefixHookCRDs,
@@ -39,21 +45,81 @@ var StageOpNamesOrdered = []string{
 	StageOpNamePrefixGeneralResources,
 	StageOpNamePrefixPostHookCRDs,
 	StageOpNamePrefixPostHookResources,
+	StageOpNamePrefixPostReadinessHooks,
 	StageOpNamePrefixFinal,
 }
 
 const (
-	StageOpNamePrefixInit              = operation.TypeStageOperation + "/initialization"
-	StageOpNamePrefixStandaloneCRDs    = operation.TypeStageOperation + "/standalone-crds"
-	StageOpNamePrefixHookCRDs          = operation.TypeStageOperation + "/pre-hook-crds"
-	StageOpNamePrefixHookResources     = operation.TypeStageOperation + "/pre-hook-resources"
-	StageOpNamePrefixGeneralCRDs       = operation.TypeStageOperation + "/general-crds"
-	StageOpNamePrefixGeneralResources  = operation.TypeStageOperation + "/general-resources"
-	StageOpNamePrefixPostHookCRDs      = operation.TypeStageOperation + "/post-hook-crds"
-	StageOpNamePrefixPostHookResources = operation.TypeStageOperation + "/post-hooks-resources"
-	StageOpNamePrefixFinal             = operation.TypeStageOperation + "/finalization"
+	StageOpNamePrefixReleaseNamespace   = operation.TypeStageOperation + "/release-namespace"
+	StageOpNamePrefixPrePlanHooks       = operation.TypeStageOperation + "/pre-plan-hooks"
+	StageOpNamePrefixInit               = operation.TypeStageOperation + "/initialization"
+	StageOpNamePrefixStandaloneCRDs     = operation.TypeStageOperation + "/standalone-crds"
+	StageOpNamePrefixHookCRDs           = operation.TypeStageOperation + "/pre-hook-crds"
+	StageOpNamePrefixHookResources      = operation.TypeStageOperation + "/pre-hook-resources"
+	StageOpNamePrefixGeneralCRDs        = operation.TypeStageOperation + "/general-crds"
+	StageOpNamePrefixGeneralResources   = operation.TypeStageOperation + "/general-resources"
+	StageOpNamePrefixPostHookCRDs       = operation.TypeStageOperation + "/post-hook-crds"
+	StageOpNamePrefixPostHookResources  = operation.TypeStageOperation + "/post-hooks-resources"
+	StageOpNamePrefixPostReadinessHooks = operation.TypeStageOperation + "/post-readiness-hooks"
+	StageOpNamePrefixFinal              = operation.TypeStageOperation + "/finalization"
 )
 
+func stageOpNameIndex(opID string) int {
+	_, index := lo.Must2(lo.FindIndexOf(StageOpNamesOrdered, func(name string) bool {
+		return strings.HasPrefix(opID, name+"/")
+	}))
+
+	return index
+}
+
+// Returns the weight from the "weight:<weight>" part of the stage operation ID, if any.
+func stageOpWeight(opID string) *int {
+	for _, idSplit := range strings.Split(opID, "/") {
+		parts := strings.SplitN(idSplit, ":", 2)
+
+		if parts[0] != "weight" {
+			continue
+		}
+
+		return lo.ToPtr(lo.Must(strconv.Atoi(parts[1])))
+	}
+
+	return nil
+}
+
+// Returns the index from the "serial:<index>" part of the stage operation ID, or 0 if there is
+// none.
+func stageOpSerial(opID string) int {
+	for _, idSplit := range strings.Split(opID, "/") {
+		parts := strings.SplitN(idSplit, ":", 2)
+
+		if parts[0] != "serial" {
+			continue
+		}
+
+		return lo.Must(strconv.Atoi(parts[1]))
+	}
+
+	return 0
+}
+
+func execAfterStageOpNamePrefix(execAfter string) string {
+	switch execAfter {
+	case resource.ExecAfterStandaloneCRDs:
+		return StageOpNamePrefixStandaloneCRDs
+	case resource.ExecAfterPreHooks:
+		return StageOpNamePrefixHookResources
+	case resource.ExecAfterGeneralCRDs:
+		return StageOpNamePrefixGeneralCRDs
+	case resource.ExecAfterGeneralResources:
+		return StageOpNamePrefixGeneralResources
+	case resource.ExecAfterPostHooks:
+		return StageOpNamePrefixPostHookResources
+	default:
+		panic(fmt.Sprintf("unexpected exec-after stage %q", execAfter))
+	}
+}
+
 func NewDeployPlanBuilder(
 	releaseNamespace string,
 	deployType common.DeployType,
@@ -100,6 +166,18 @@ func NewDeployPlanBuilder(
 		return false
 	})
 
+	execHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *info.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnExec()
+	})
+
+	prePlanHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *info.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnPrePlan()
+	})
+
+	postReadinessHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *info.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnPostReadiness()
+	})
+
 	prePostHookResourcesIDs := lo.FilterMap(hookResourcesInfos, func(info *info.DeployableHookResourceInfo, _ int) (*resid.ResourceID, bool) {
 		res := info.Resource()
 
@@ -117,6 +195,11 @@ func NewDeployPlanBuilder(
 
 	curReleaseExistResourcesUIDs, _ := CurrentReleaseExistingResourcesUIDs(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos)
 
+	creationTimeout := opts.CreationTimeout
+	if creationTimeout == 0 {
+		creationTimeout = opts.ReadinessTimeout
+	}
+
 	return &DeployPlanBuilder{
 		taskStore:                       taskStore,
 		logStore:                        logStore,
@@ -126,6 +209,9 @@ func NewDeployPlanBuilder(
 		standaloneCRDsInfos:             standaloneCRDsInfos,
 		preHookResourcesInfos:           preHookResourcesInfos,
 		postHookResourcesInfos:          postHookResourcesInfos,
+		execHookResourcesInfos:          execHookResourcesInfos,
+		prePlanHookResourcesInfos:       prePlanHookResourcesInfos,
+		postReadinessHookResourcesInfos: postReadinessHookResourcesInfos,
 		prePostHookResourcesIDs:         prePostHookResourcesIDs,
 		generalResourcesInfos:           generalResourcesInfos,
 		prevReleaseGeneralResourceInfos: prevReleaseGeneralResourceInfos,
@@ -139,9 +225,16 @@ func NewDeployPlanBuilder(
 		dynamicClient:                   dynamicClient,
 		discoveryClient:                 discoveryClient,
 		mapper:                          mapper,
-		creationTimeout:                 opts.CreationTimeout,
+		creationTimeout:                 creationTimeout,
 		readinessTimeout:                opts.ReadinessTimeout,
 		deletionTimeout:                 opts.DeletionTimeout,
+		readyStableFor:                  opts.ReadyStableFor,
+		crdPolicy:                       opts.CRDPolicy,
+		ignoreLogs:                      opts.IgnoreLogs,
+		logFollower:                     opts.LogFollower,
+		forceReplace:                    opts.ForceReplace,
+		releaseNamespaceInfo:            opts.ReleaseNamespaceInfo,
+		lockReleaseOp:                   opts.LockReleaseOperation,
 	}
 }
 
@@ -151,6 +244,20 @@ type DeployPlanBuilderOptions struct {
 	CreationTimeout     time.Duration
 	ReadinessTimeout    time.Duration
 	DeletionTimeout     time.Duration
+	ReadyStableFor      time.Duration
+	CRDPolicy           resource.CRDPolicy
+	// Don't save container logs at all, e.g. if they are not going to be shown.
+	IgnoreLogs bool
+	// If set, logs of the pods are followed after their resources are ready.
+	LogFollower *track.LogFollower
+	// Replace all resources with changed immutable fields, as if they had the
+	// werf.io/replace-on-immutable-change annotation.
+	ForceReplace bool
+	// If set, the release namespace is created or updated before anything else.
+	ReleaseNamespaceInfo *info.DeployableReleaseNamespaceInfo
+	// If set, executed right after the release namespace is created. Needed if the release
+	// couldn't be locked in advance, because the release namespace didn't exist.
+	LockReleaseOperation *operation.LockReleaseOperation
 }
 
 type DeployPlanBuilder struct {
@@ -161,6 +268,9 @@ type DeployPlanBuilder struct {
 	standaloneCRDsInfos             []*info.DeployableStandaloneCRDInfo
 	preHookResourcesInfos           []*info.DeployableHookResourceInfo
 	postHookResourcesInfos          []*info.DeployableHookResourceInfo
+	execHookResourcesInfos          []*info.DeployableHookResourceInfo
+	prePlanHookResourcesInfos       []*info.DeployableHookResourceInfo
+	postReadinessHookResourcesInfos []*info.DeployableHookResourceInfo
 	prePostHookResourcesIDs         []*resid.ResourceID
 	generalResourcesInfos           []*info.DeployableGeneralResourceInfo
 	prevReleaseGeneralResourceInfos []*info.DeployablePrevReleaseGeneralResourceInfo
@@ -177,57 +287,105 @@ type DeployPlanBuilder struct {
 	creationTimeout                 time.Duration
 	readinessTimeout                time.Duration
 	deletionTimeout                 time.Duration
+	readyStableFor                  time.Duration
+	crdPolicy                       resource.CRDPolicy
+	ignoreLogs                      bool
+	logFollower                     *track.LogFollower
+	forceReplace                    bool
+	releaseNamespaceInfo            *info.DeployableReleaseNamespaceInfo
+	lockReleaseOp                   *operation.LockReleaseOperation
+
+	plan          *Plan
+	keptResources []*KeptResource
+}
 
-	plan *Plan
+// KeptResource is a resource which would've been deleted by the deploy, but is kept because of
+// its resource policy or because it's not owned by the release anymore.
+type KeptResource struct {
+	*resid.ResourceID
+
+	Reason string
 }
 
 func (b *DeployPlanBuilder) Build(ctx context.Context) (*Plan, error) {
-	log.Default.Debug(ctx, "Setting up init operations")
+	log.Plan.Debug(ctx, "Setting up release namespace operations")
+	if err := b.setupReleaseNamespaceOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up release namespace operations: %w", err)
+	}
+
+	log.Plan.Debug(ctx, "Setting up init operations")
 	if err := b.setupInitOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up init operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up standalone CRDs operations")
+	log.Plan.Debug(ctx, "Setting up standalone CRDs operations")
 	if err := b.setupStandaloneCRDsOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up standalone CRDs operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up pre hook resources operations")
+	hookInfos := lo.UniqBy(lo.Union(b.preHookResourcesInfos, b.postHookResourcesInfos), func(info *info.DeployableHookResourceInfo) string {
+		return info.ID()
+	})
+	for _, info := range hookInfos {
+		if hookWeight, weight, conflicting := info.Resource().ConflictingWeights(); conflicting {
+			log.Plan.Warn(ctx, "Hook %q has both werf.io/weight=%d and helm.sh/hook-weight=%d annotations, werf.io/weight is used", info.HumanID(), weight, hookWeight)
+		}
+	}
+
+	log.Plan.Debug(ctx, "Setting up pre hook resources operations")
 	if err := b.setupPreHookResourcesOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up pre hooks operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up general resources operations")
+	log.Plan.Debug(ctx, "Setting up general resources operations")
 	if err := b.setupGeneralResourcesOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up general resources operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up post hook resources operations")
+	log.Plan.Debug(ctx, "Setting up post hook resources operations")
 	if err := b.setupPostHookResourcesOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up post hooks operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up prev release general resources operations")
+	log.Plan.Debug(ctx, "Setting up pre-plan and post-readiness hook resources operations")
+	if err := b.setupHookStageOperations(b.prePlanHookResourcesInfos, StageOpNamePrefixPrePlanHooks); err != nil {
+		return b.plan, fmt.Errorf("error setting up pre-plan hooks operations: %w", err)
+	}
+	if err := b.setupHookStageOperations(b.postReadinessHookResourcesInfos, StageOpNamePrefixPostReadinessHooks); err != nil {
+		return b.plan, fmt.Errorf("error setting up post-readiness hooks operations: %w", err)
+	}
+
+	log.Plan.Debug(ctx, "Setting up exec hook resources operations")
+	if err := b.setupExecHookResourcesOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up exec hooks operations: %w", err)
+	}
+
+	log.Plan.Debug(ctx, "Setting up prev release general resources operations")
 	if err := b.setupPrevReleaseGeneralResourcesOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up prev release general resources operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Setting up finalization operations")
+	log.Plan.Debug(ctx, "Setting up finalization operations")
 	if err := b.setupFinalizationOperations(); err != nil {
 		return b.plan, fmt.Errorf("error setting up finalization operations: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Connecting stages")
+	log.Plan.Debug(ctx, "Connecting stages")
 	if err := b.connectStages(); err != nil {
 		return b.plan, fmt.Errorf("error connecting stages: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Connecting internal dependencies")
+	log.Plan.Debug(ctx, "Connecting exec hooks")
+	if err := b.connectExecHooks(); err != nil {
+		return b.plan, fmt.Errorf("error connecting exec hooks: %w", err)
+	}
+
+	log.Plan.Debug(ctx, "Connecting internal dependencies")
 	if err := b.connectInternalDependencies(); err != nil {
 		return b.plan, fmt.Errorf("error connecting internal dependencies: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Optimizing plan")
+	log.Plan.Debug(ctx, "Optimizing plan")
 	if err := b.plan.Optimize(); err != nil {
 		return b.plan, fmt.Errorf("error optimizing plan: %w", err)
 	}
@@ -235,6 +393,111 @@ func (b *DeployPlanBuilder) Build(ctx context.Context) (*Plan, error) {
 	return b.plan, nil
 }
 
+// KeptResources returns resources which are not deleted by the plan because of their resource
+// policy or ownership. Available after Build.
+func (b *DeployPlanBuilder) KeptResources() []*KeptResource {
+	return b.keptResources
+}
+
+func (b *DeployPlanBuilder) addKeptResource(resID *resid.ResourceID, reason string) {
+	if _, found := lo.Find(b.keptResources, func(res *KeptResource) bool {
+		return res.ID() == resID.ID()
+	}); found {
+		return
+	}
+
+	b.keptResources = append(b.keptResources, &KeptResource{
+		ResourceID: resID,
+		Reason:     reason,
+	})
+}
+
+func (b *DeployPlanBuilder) setupReleaseNamespaceOperations() error {
+	if b.releaseNamespaceInfo == nil {
+		return nil
+	}
+
+	info := b.releaseNamespaceInfo
+	stageStartOpID := StageOpNamePrefixReleaseNamespace + "/" + StageOpNameSuffixStart
+	stageEndOpID := StageOpNamePrefixReleaseNamespace + "/" + StageOpNameSuffixEnd
+
+	if info.ShouldCreate() {
+		opCreate := operation.NewCreateResourceOperation(
+			info.ResourceID,
+			info.Resource().Unstructured(),
+			b.kubeClient,
+			operation.CreateResourceOperationOptions{
+				ManageableBy: info.Resource().ManageableBy(),
+			},
+		)
+		b.plan.AddStagedOperation(opCreate, stageStartOpID, stageEndOpID)
+
+		taskState := kdutil.NewConcurrent(
+			statestore.NewPresenceTaskState(
+				info.Name(),
+				info.Namespace(),
+				info.GroupVersionKind(),
+				statestore.PresenceTaskStateOptions{},
+			),
+		)
+		b.taskStore.AddPresenceTaskState(taskState)
+
+		opTrackPresence := operation.NewTrackResourcePresenceOperation(
+			info.ResourceID,
+			taskState,
+			b.dynamicClient,
+			b.mapper,
+			operation.TrackResourcePresenceOperationOptions{
+				Timeout: b.creationTimeout,
+			},
+		)
+		b.plan.AddStagedOperation(opTrackPresence, stageStartOpID, stageEndOpID)
+		lo.Must0(b.plan.AddDependency(opCreate.ID(), opTrackPresence.ID()))
+
+		if b.lockReleaseOp != nil {
+			b.plan.AddStagedOperation(b.lockReleaseOp, stageStartOpID, stageEndOpID)
+			lo.Must0(b.plan.AddDependency(opTrackPresence.ID(), b.lockReleaseOp.ID()))
+		}
+
+		return nil
+	}
+
+	var opDeploy operation.Operation
+	if info.ShouldUpdate() {
+		var err error
+		opDeploy, err = operation.NewUpdateResourceOperation(
+			info.ResourceID,
+			info.Resource().Unstructured(),
+			b.kubeClient,
+			operation.UpdateResourceOperationOptions{
+				ManageableBy: info.Resource().ManageableBy(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error creating update resource operation: %w", err)
+		}
+	} else if info.ShouldApply() {
+		var err error
+		opDeploy, err = operation.NewApplyResourceOperation(
+			info.ResourceID,
+			info.Resource().Unstructured(),
+			b.kubeClient,
+			operation.ApplyResourceOperationOptions{
+				ManageableBy: info.Resource().ManageableBy(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error creating apply resource operation: %w", err)
+		}
+	}
+
+	if opDeploy != nil {
+		b.plan.AddStagedOperation(opDeploy, stageStartOpID, stageEndOpID)
+	}
+
+	return nil
+}
+
 func (b *DeployPlanBuilder) setupInitOperations() error {
 	opCreatePendingRel := operation.NewCreatePendingReleaseOperation(b.newRelease, b.deployType, b.history)
 	b.plan.AddStagedOperation(
@@ -248,9 +511,11 @@ func (b *DeployPlanBuilder) setupInitOperations() error {
 
 func (b *DeployPlanBuilder) setupStandaloneCRDsOperations() error {
 	for _, info := range b.standaloneCRDsInfos {
+		policy := info.Resource().Policy(b.crdPolicy)
+
 		create := info.ShouldCreate()
-		update := info.ShouldUpdate()
-		apply := info.ShouldApply()
+		update := info.ShouldUpdate() && policy == resource.CRDPolicyUpdate
+		apply := info.ShouldApply() && policy == resource.CRDPolicyUpdate
 
 		var opDeploy operation.Operation
 		if create {
@@ -296,6 +561,22 @@ func (b *DeployPlanBuilder) setupStandaloneCRDsOperations() error {
 				StageOpNamePrefixStandaloneCRDs+"/"+StageOpNameSuffixStart,
 				StageOpNamePrefixStandaloneCRDs+"/"+StageOpNameSuffixEnd,
 			)
+
+			if policy == resource.CRDPolicyUpdate {
+				opTrackEstablished := operation.NewTrackCRDEstablishedOperation(
+					info.ResourceID,
+					b.kubeClient,
+					operation.TrackCRDEstablishedOperationOptions{
+						Timeout: b.readinessTimeout,
+					},
+				)
+				b.plan.AddStagedOperation(
+					opTrackEstablished,
+					StageOpNamePrefixStandaloneCRDs+"/"+StageOpNameSuffixStart,
+					StageOpNamePrefixStandaloneCRDs+"/"+StageOpNameSuffixEnd,
+				)
+				lo.Must0(b.plan.AddDependency(opDeploy.ID(), opTrackEstablished.ID()))
+			}
 		}
 	}
 
@@ -310,24 +591,27 @@ func (b *DeployPlanBuilder) setupPreHookResourcesOperations() error {
 	weights := lo.Keys(weighedInfos)
 	sort.Ints(weights)
 
+	if len(weights) == 0 {
+		return nil
+	}
+
+	eventStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookCRDs, weights[0], StageOpNameSuffixStart)
+	eventStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookResources, weights[len(weights)-1], StageOpNameSuffixEnd)
+
 	for _, weight := range weights {
 		crdInfos := lo.Filter(weighedInfos[weight], func(info *info.DeployableHookResourceInfo, _ int) bool {
 			return util.IsCRDFromGK(info.GroupVersionKind().GroupKind())
 		})
-		crdsStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookCRDs, weight, StageOpNameSuffixStart)
-		crdsStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookCRDs, weight, StageOpNameSuffixEnd)
 
-		if err := b.setupHookOperations(crdInfos, crdsStageStartOpID, crdsStageEndOpID, true); err != nil {
+		if err := b.setupWeightHookOperations(crdInfos, StageOpNamePrefixHookCRDs, weight, eventStageStartOpID, eventStageEndOpID, true); err != nil {
 			return fmt.Errorf("error setting up hook crds operations: %w", err)
 		}
 
 		resourceInfos := lo.Filter(weighedInfos[weight], func(info *info.DeployableHookResourceInfo, _ int) bool {
 			return !util.IsCRDFromGK(info.GroupVersionKind().GroupKind())
 		})
-		resourcesStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookResources, weight, StageOpNameSuffixStart)
-		resourcesStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixHookResources, weight, StageOpNameSuffixEnd)
 
-		if err := b.setupHookOperations(resourceInfos, resourcesStageStartOpID, resourcesStageEndOpID, true); err != nil {
+		if err := b.setupWeightHookOperations(resourceInfos, StageOpNamePrefixHookResources, weight, eventStageStartOpID, eventStageEndOpID, true); err != nil {
 			return fmt.Errorf("error setting up hook resources operations: %w", err)
 		}
 	}
@@ -343,24 +627,27 @@ func (b *DeployPlanBuilder) setupPostHookResourcesOperations() error {
 	weights := lo.Keys(weighedInfos)
 	sort.Ints(weights)
 
+	if len(weights) == 0 {
+		return nil
+	}
+
+	eventStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookCRDs, weights[0], StageOpNameSuffixStart)
+	eventStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookResources, weights[len(weights)-1], StageOpNameSuffixEnd)
+
 	for _, weight := range weights {
 		crdInfos := lo.Filter(weighedInfos[weight], func(info *info.DeployableHookResourceInfo, _ int) bool {
 			return util.IsCRDFromGK(info.GroupVersionKind().GroupKind())
 		})
-		crdsStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookCRDs, weight, StageOpNameSuffixStart)
-		crdsStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookCRDs, weight, StageOpNameSuffixEnd)
 
-		if err := b.setupHookOperations(crdInfos, crdsStageStartOpID, crdsStageEndOpID, false); err != nil {
+		if err := b.setupWeightHookOperations(crdInfos, StageOpNamePrefixPostHookCRDs, weight, eventStageStartOpID, eventStageEndOpID, false); err != nil {
 			return fmt.Errorf("error setting up hook crds operations: %w", err)
 		}
 
 		resourceInfos := lo.Filter(weighedInfos[weight], func(info *info.DeployableHookResourceInfo, _ int) bool {
 			return !util.IsCRDFromGK(info.GroupVersionKind().GroupKind())
 		})
-		resourcesStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookResources, weight, StageOpNameSuffixStart)
-		resourcesStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", StageOpNamePrefixPostHookResources, weight, StageOpNameSuffixEnd)
 
-		if err := b.setupHookOperations(resourceInfos, resourcesStageStartOpID, resourcesStageEndOpID, false); err != nil {
+		if err := b.setupWeightHookOperations(resourceInfos, StageOpNamePrefixPostHookResources, weight, eventStageStartOpID, eventStageEndOpID, false); err != nil {
 			return fmt.Errorf("error setting up hook resources operations: %w", err)
 		}
 	}
@@ -368,6 +655,58 @@ func (b *DeployPlanBuilder) setupPostHookResourcesOperations() error {
 	return nil
 }
 
+// Pre-plan and post-readiness hooks have their own stages, split only by weight.
+func (b *DeployPlanBuilder) setupHookStageOperations(infos []*info.DeployableHookResourceInfo, stagePrefix string) error {
+	weighedInfos := lo.GroupBy(infos, func(info *info.DeployableHookResourceInfo) int {
+		return info.Resource().Weight()
+	})
+
+	weights := lo.Keys(weighedInfos)
+	sort.Ints(weights)
+
+	if len(weights) == 0 {
+		return nil
+	}
+
+	eventStageStartOpID := fmt.Sprintf("%s/weight:%d/%s", stagePrefix, weights[0], StageOpNameSuffixStart)
+	eventStageEndOpID := fmt.Sprintf("%s/weight:%d/%s", stagePrefix, weights[len(weights)-1], StageOpNameSuffixEnd)
+
+	for _, weight := range weights {
+		if err := b.setupWeightHookOperations(weighedInfos[weight], stagePrefix, weight, eventStageStartOpID, eventStageEndOpID, true); err != nil {
+			return fmt.Errorf("error setting up hook operations: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Hooks of the same weight run in parallel, except for hooks with werf.io/hook-parallel=false:
+// each of them gets its own stage after the stage of the other hooks of this weight, so it runs
+// alone.
+func (b *DeployPlanBuilder) setupWeightHookOperations(infos []*info.DeployableHookResourceInfo, stagePrefix string, weight int, eventStageStartOpID, eventStageEndOpID string, pre bool) error {
+	parallelInfos, serialInfos := lo.FilterReject(infos, func(info *info.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().Parallel()
+	})
+
+	stageStartOpID := fmt.Sprintf("%s/weight:%d/%s", stagePrefix, weight, StageOpNameSuffixStart)
+	stageEndOpID := fmt.Sprintf("%s/weight:%d/%s", stagePrefix, weight, StageOpNameSuffixEnd)
+
+	if err := b.setupHookOperations(parallelInfos, stageStartOpID, stageEndOpID, eventStageStartOpID, eventStageEndOpID, pre); err != nil {
+		return err
+	}
+
+	for i, serialInfo := range serialInfos {
+		stageStartOpID := fmt.Sprintf("%s/weight:%d/serial:%d/%s", stagePrefix, weight, i+1, StageOpNameSuffixStart)
+		stageEndOpID := fmt.Sprintf("%s/weight:%d/serial:%d/%s", stagePrefix, weight, i+1, StageOpNameSuffixEnd)
+
+		if err := b.setupHookOperations([]*info.DeployableHookResourceInfo{serialInfo}, stageStartOpID, stageEndOpID, eventStageStartOpID, eventStageEndOpID, pre); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (b *DeployPlanBuilder) setupGeneralResourcesOperations() error {
 	weighedInfos := lo.GroupBy(b.generalResourcesInfos, func(info *info.DeployableGeneralResourceInfo) int {
 		return info.Resource().Weight()
@@ -401,9 +740,67 @@ func (b *DeployPlanBuilder) setupGeneralResourcesOperations() error {
 	return nil
 }
 
+func (b *DeployPlanBuilder) setupExecHookResourcesOperations() error {
+	for _, info := range b.execHookResourcesInfos {
+		var noActivityTimeout time.Duration
+		if timeout, set := info.Resource().NoActivityTimeout(); set {
+			noActivityTimeout = *timeout
+		}
+		timeout := b.readinessTimeout
+		if t, set := info.Resource().TrackTimeout(); set {
+			timeout = t
+		}
+		keep := info.ShouldKeepOnDelete(b.newRelease.Name(), b.releaseNamespace)
+		if keep && (info.Resource().DeleteOnSucceeded() || info.Resource().DeleteOnFailed()) {
+			b.addKeptResource(info.ResourceID, "resource policy is keep")
+		}
+
+		absenceTaskState := kdutil.NewConcurrent(
+			statestore.NewAbsenceTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.AbsenceTaskStateOptions{}),
+		)
+		b.taskStore.AddAbsenceTaskState(absenceTaskState)
+
+		readinessTaskState := kdutil.NewConcurrent(
+			statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
+				FailMode:                info.Resource().FailMode(),
+				TotalAllowFailuresCount: info.Resource().FailuresAllowed(),
+			}),
+		)
+		b.taskStore.AddReadinessTaskState(readinessTaskState)
+
+		opExec := operation.NewExecJobOperation(
+			info.ResourceID,
+			info.Resource().Unstructured(),
+			absenceTaskState,
+			readinessTaskState,
+			b.logStore,
+			b.kubeClient,
+			b.staticClient,
+			b.dynamicClient,
+			b.discoveryClient,
+			b.mapper,
+			operation.ExecJobOperationOptions{
+				ManageableBy:         info.Resource().ManageableBy(),
+				Timeout:              timeout,
+				NoActivityTimeout:    noActivityTimeout,
+				DeletionTrackTimeout: b.deletionTimeout,
+				SaveEvents:           info.Resource().ShowServiceMessages(),
+				DeleteOnSucceeded:    info.Resource().DeleteOnSucceeded() && !keep,
+				DeleteOnFailed:       info.Resource().DeleteOnFailed() && !keep,
+			},
+		)
+		b.plan.AddOperation(opExec)
+	}
+
+	return nil
+}
+
 func (b *DeployPlanBuilder) setupPrevReleaseGeneralResourcesOperations() error {
 	for _, info := range b.prevReleaseGeneralResourceInfos {
 		delete := info.ShouldDelete(b.curReleaseExistingResourcesUIDs, b.newRelease.Name(), b.releaseNamespace)
+		if reason, keep := info.KeepReason(b.curReleaseExistingResourcesUIDs, b.newRelease.Name(), b.releaseNamespace); keep {
+			b.addKeptResource(info.ResourceID, reason)
+		}
 
 		if delete {
 			opDelete := operation.NewDeleteResourceOperation(
@@ -445,8 +842,105 @@ func (b *DeployPlanBuilder) setupPrevReleaseGeneralResourcesOperations() error {
 	return nil
 }
 
+// Operations waiting for external dependencies are shared by all resources depending on them.
+func (b *DeployPlanBuilder) setupExternalDependenciesOperations(externalDeps []*dependency.ExternalDependency, opDeploy operation.Operation) {
+	for _, dep := range externalDeps {
+		taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *kdutil.Concurrent[*statestore.PresenceTaskState]) bool {
+			var found bool
+
+			ts.RTransaction(func(pts *statestore.PresenceTaskState) {
+				if pts.Name() == dep.Name() &&
+					pts.Namespace() == dep.Namespace() &&
+					pts.GroupVersionKind() == dep.GroupVersionKind() {
+					found = true
+				}
+			})
+
+			return found
+		})
+
+		if !taskStateFound {
+			taskState = kdutil.NewConcurrent(
+				statestore.NewPresenceTaskState(
+					dep.Name(),
+					dep.Namespace(),
+					dep.GroupVersionKind(),
+					statestore.PresenceTaskStateOptions{},
+				),
+			)
+			b.taskStore.AddPresenceTaskState(taskState)
+		}
+
+		opTrackPresence := operation.NewTrackResourcePresenceOperation(
+			dep.ResourceID,
+			taskState,
+			b.dynamicClient,
+			b.mapper,
+			operation.TrackResourcePresenceOperationOptions{
+				Timeout:            b.creationTimeout,
+				ExternalDependency: true,
+			},
+		)
+
+		b.plan.AddInStagedOperation(
+			opTrackPresence,
+			StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+		)
+
+		if dep.ResourceState != dependency.ResourceStateReady {
+			lo.Must0(b.plan.AddDependency(opTrackPresence.ID(), opDeploy.ID()))
+			continue
+		}
+
+		opTrackReadinessID := operation.TypeTrackResourceReadinessOperation + "/" + dep.ID()
+		if _, found := b.plan.Operation("%s", opTrackReadinessID); !found {
+			readinessTaskState := kdutil.NewConcurrent(
+				statestore.NewReadinessTaskState(dep.Name(), dep.Namespace(), dep.GroupVersionKind(), statestore.ReadinessTaskStateOptions{}),
+			)
+			b.taskStore.AddReadinessTaskState(readinessTaskState)
+
+			opTrackReadiness := operation.NewTrackResourceReadinessOperation(
+				dep.ResourceID,
+				readinessTaskState,
+				b.logStore,
+				b.staticClient,
+				b.dynamicClient,
+				b.discoveryClient,
+				b.mapper,
+				operation.TrackResourceReadinessOperationOptions{
+					Timeout:            b.readinessTimeout,
+					IgnoreLogs:         true,
+					ExternalDependency: true,
+				},
+			)
+
+			b.plan.AddInStagedOperation(
+				opTrackReadiness,
+				StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+			)
+			lo.Must0(b.plan.AddDependency(opTrackPresence.ID(), opTrackReadiness.ID()))
+		}
+
+		lo.Must0(b.plan.AddDependency(opTrackReadinessID, opDeploy.ID()))
+	}
+}
+
 func (b *DeployPlanBuilder) setupFinalizationOperations() error {
-	opUpdateSucceededRel := operation.NewSucceedReleaseOperation(b.newRelease, b.history)
+	ops, _, err := b.plan.Operations()
+	if err != nil {
+		return fmt.Errorf("error getting operations: %w", err)
+	}
+
+	var degradableOps []operation.DegradableOperation
+	for _, op := range ops {
+		if degradableOp, ok := op.(operation.DegradableOperation); ok {
+			degradableOps = append(degradableOps, degradableOp)
+		}
+	}
+
+	opUpdateSucceededRel := operation.NewSucceedReleaseOperation(b.newRelease, b.history, operation.SucceedReleaseOperationOptions{
+		DegradableOperations: degradableOps,
+	})
 	b.plan.AddStagedOperation(
 		opUpdateSucceededRel,
 		StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
@@ -480,6 +974,13 @@ func (b *DeployPlanBuilder) connectInternalDependencies() error {
 		),
 	)
 
+	preHookResourcesIDs := lo.Map(b.preHookResourcesInfos, func(info *info.DeployableHookResourceInfo, _ int) string {
+		return info.ID()
+	})
+	generalResourcesIDs := lo.Map(b.generalResourcesInfos, func(info *info.DeployableGeneralResourceInfo, _ int) string {
+		return info.ID()
+	})
+
 	for _, info := range hookInfos {
 		var opDeploy operation.Operation
 		if info.ShouldCreate() {
@@ -515,23 +1016,61 @@ func (b *DeployPlanBuilder) connectInternalDependencies() error {
 				continue
 			}
 
+			var dependOnResID *resid.ResourceID
 			dependOnOp, found := lo.Find(dependOnOpCandidates, func(op operation.Operation) bool {
 				_, id := lo.Must2(strings.Cut(op.ID(), "/"))
 
-				resID := resid.NewResourceIDFromID(id, resid.ResourceIDOptions{
+				dependOnResID = resid.NewResourceIDFromID(id, resid.ResourceIDOptions{
 					DefaultNamespace: b.releaseNamespace,
 					Mapper:           b.mapper,
 				})
 
-				return dep.Match(resID)
+				return dep.Match(dependOnResID)
 			})
 			if !found {
 				continue
 			}
 
-			if err := b.plan.AddDependency(dependOnOp.ID(), opDeploy.ID()); err != nil {
+			if lo.Contains(preHookResourcesIDs, info.ID()) && lo.Contains(generalResourcesIDs, dependOnResID.ID()) {
+				// Auto-detected dependencies are best-effort, so just ignore the ones that can't be satisfied.
+				if !lo.Contains(manualInternalDeps, dep) {
+					continue
+				}
+
+				return fmt.Errorf("pre hook %q can't depend on general resource %q, since general resources are deployed after pre hooks", info.HumanID(), dependOnResID.HumanID())
+			}
+
+			if err := b.plan.AddDependencyWithReason(dependOnOp.ID(), opDeploy.ID(), internalDependencyReason(info.HumanID(), dep)); err != nil {
+				var cycleErr *DependencyCycleError
+				if errors.As(err, &cycleErr) {
+					if !lo.Contains(manualInternalDeps, dep) {
+						continue
+					}
+
+					// Reported by plan validation, along with all the other unsatisfiable dependencies.
+					b.plan.AddUnsatisfiableDependency(cycleErr)
+					continue
+				}
+
 				return fmt.Errorf("error adding dependency: %w", err)
 			}
+
+			// Dependents of a resource with werf.io/deploy-delay wait for the delay too.
+			if opSleep, found := b.deployDelayOperation(dependOnResID, !lo.Contains(preHookResourcesIDs, info.ID())); found {
+				if err := b.plan.AddDependencyWithReason(opSleep.ID(), opDeploy.ID(), "annotation \"werf.io/deploy-delay\" on "+dependOnResID.HumanID()); err != nil {
+					var cycleErr *DependencyCycleError
+					if errors.As(err, &cycleErr) {
+						if !lo.Contains(manualInternalDeps, dep) {
+							continue
+						}
+
+						b.plan.AddUnsatisfiableDependency(cycleErr)
+						continue
+					}
+
+					return fmt.Errorf("error adding dependency: %w", err)
+				}
+			}
 		}
 	}
 
@@ -571,77 +1110,161 @@ func (b *DeployPlanBuilder) connectInternalDependencies() error {
 				continue
 			}
 
+			var dependOnResID *resid.ResourceID
 			dependOnOp, found := lo.Find(dependOnOpCandidates, func(op operation.Operation) bool {
 				_, id := lo.Must2(strings.Cut(op.ID(), "/"))
 
-				resID := resid.NewResourceIDFromID(id, resid.ResourceIDOptions{
+				dependOnResID = resid.NewResourceIDFromID(id, resid.ResourceIDOptions{
 					DefaultNamespace: b.releaseNamespace,
 					Mapper:           b.mapper,
 				})
 
-				return dep.Match(resID)
+				return dep.Match(dependOnResID)
 			})
 			if !found {
 				continue
 			}
 
-			if err := b.plan.AddDependency(dependOnOp.ID(), opDeploy.ID()); err != nil {
+			if err := b.plan.AddDependencyWithReason(dependOnOp.ID(), opDeploy.ID(), internalDependencyReason(info.HumanID(), dep)); err != nil {
+				var cycleErr *DependencyCycleError
+				if errors.As(err, &cycleErr) {
+					if !lo.Contains(manualInternalDeps, dep) {
+						continue
+					}
+
+					// Reported by plan validation, along with all the other unsatisfiable dependencies.
+					b.plan.AddUnsatisfiableDependency(cycleErr)
+					continue
+				}
+
 				return fmt.Errorf("error adding dependency: %w", err)
 			}
+
+			// Dependents of a resource with werf.io/deploy-delay wait for the delay too.
+			if opSleep, found := b.deployDelayOperation(dependOnResID, false); found {
+				if err := b.plan.AddDependencyWithReason(opSleep.ID(), opDeploy.ID(), "annotation \"werf.io/deploy-delay\" on "+dependOnResID.HumanID()); err != nil {
+					return fmt.Errorf("error adding dependency: %w", err)
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
-func (b *DeployPlanBuilder) connectStages() error {
-	opsStagesRegex := regexp.MustCompile(fmt.Sprintf(`^(%s)/`, strings.Join(StageOpNamesOrdered, "|")))
+// deployDelayOperation returns the sleep operation of a resource with werf.io/deploy-delay. A hook,
+// which is both a pre and a post hook, is deployed again after general resources, with its own
+// extra post sleep operation, and post hooks depending on it wait for that one instead.
+func (b *DeployPlanBuilder) deployDelayOperation(resID *resid.ResourceID, forPostHook bool) (operation.Operation, bool) {
+	if forPostHook {
+		if opSleep, found := b.plan.Operation(operation.TypeExtraPostSleepOperation + "/" + resID.ID()); found {
+			return opSleep, true
+		}
+	}
 
-	opsStages, found, err := b.plan.OperationsMatch(opsStagesRegex)
+	return b.plan.Operation(operation.TypeSleepOperation + "/" + resID.ID())
+}
+
+func internalDependencyReason(dependentHumanID string, dep *dependency.InternalDependency) string {
+	if dep.Source == "" {
+		return "auto-detected dependency of " + dependentHumanID
+	}
+
+	return dep.Source + " on " + dependentHumanID
+}
+
+func (b *DeployPlanBuilder) connectStages() error {
+	opsStages, found, err := b.sortedStageOperations()
 	if err != nil {
-		return fmt.Errorf("error looking for operations by regex: %w", err)
+		return fmt.Errorf("error getting sorted stage operations: %w", err)
 	} else if !found {
 		return nil
 	}
 
-	sort.Slice(opsStages, func(i, j int) bool {
-		iID := opsStages[i].ID()
-		_, iIndex := lo.Must2(lo.FindIndexOf(StageOpNamesOrdered, func(name string) bool {
-			return strings.HasPrefix(iID, name+"/")
-		}))
+	for i := 0; i < len(opsStages); i++ {
+		if i == 0 {
+			continue
+		}
 
-		jID := opsStages[j].ID()
-		_, jIndex := lo.Must2(lo.FindIndexOf(StageOpNamesOrdered, func(name string) bool {
-			return strings.HasPrefix(jID, name+"/")
-		}))
+		if err := b.plan.AddDependency(opsStages[i-1].ID(), opsStages[i].ID()); err != nil {
+			return fmt.Errorf("error adding dependency: %w", err)
+		}
+	}
 
-		if iIndex == jIndex {
-			var iWeight *int
-			for _, iIDSplit := range strings.Split(iID, "/") {
-				parts := strings.SplitN(iIDSplit, ":", 2)
+	return nil
+}
 
-				if parts[0] != "weight" {
-					continue
-				}
+// Exec hooks are run right after the last operation of the stage specified in werf.io/exec-after
+// (or of the closest preceding non-empty stage) and block the next stage until they complete.
+func (b *DeployPlanBuilder) connectExecHooks() error {
+	if len(b.execHookResourcesInfos) == 0 {
+		return nil
+	}
+
+	opsStages, found, err := b.sortedStageOperations()
+	if err != nil {
+		return fmt.Errorf("error getting sorted stage operations: %w", err)
+	} else if !found {
+		return nil
+	}
+
+	for _, info := range b.execHookResourcesInfos {
+		execAfterStageIndex := lo.IndexOf(StageOpNamesOrdered, execAfterStageOpNamePrefix(info.Resource().ExecAfter()))
 
-				iWeight = lo.ToPtr(lo.Must(strconv.Atoi(parts[1])))
-				break
+		var lastStageOpBefore, firstStageOpAfter operation.Operation
+		for _, op := range opsStages {
+			if stageOpNameIndex(op.ID()) <= execAfterStageIndex {
+				lastStageOpBefore = op
+			} else if firstStageOpAfter == nil {
+				firstStageOpAfter = op
 			}
+		}
 
-			var jWeight *int
-			for _, jIDSplit := range strings.Split(jID, "/") {
-				parts := strings.SplitN(jIDSplit, ":", 2)
+		opExecID := operation.TypeExecJobOperation + "/" + info.ID()
 
-				if parts[0] != "weight" {
-					continue
-				}
+		if lastStageOpBefore != nil {
+			if err := b.plan.AddDependency(lastStageOpBefore.ID(), opExecID); err != nil {
+				return fmt.Errorf("error adding dependency: %w", err)
+			}
+		}
 
-				jWeight = lo.ToPtr(lo.Must(strconv.Atoi(parts[1])))
-				break
+		if firstStageOpAfter != nil {
+			if err := b.plan.AddDependency(opExecID, firstStageOpAfter.ID()); err != nil {
+				return fmt.Errorf("error adding dependency: %w", err)
 			}
+		}
+	}
+
+	return nil
+}
+
+func (b *DeployPlanBuilder) sortedStageOperations() (opsStages []operation.Operation, found bool, err error) {
+	opsStagesRegex := regexp.MustCompile(fmt.Sprintf(`^(%s)/`, strings.Join(StageOpNamesOrdered, "|")))
+
+	opsStages, found, err = b.plan.OperationsMatch(opsStagesRegex)
+	if err != nil {
+		return nil, false, fmt.Errorf("error looking for operations by regex: %w", err)
+	} else if !found {
+		return nil, false, nil
+	}
+
+	sort.Slice(opsStages, func(i, j int) bool {
+		iID := opsStages[i].ID()
+		iIndex := stageOpNameIndex(iID)
+
+		jID := opsStages[j].ID()
+		jIndex := stageOpNameIndex(jID)
+
+		if iIndex == jIndex {
+			iWeight := stageOpWeight(iID)
+			jWeight := stageOpWeight(jID)
 
 			if iWeight != nil && jWeight != nil {
 				if *iWeight == *jWeight {
+					if iSerial, jSerial := stageOpSerial(iID), stageOpSerial(jID); iSerial != jSerial {
+						return iSerial < jSerial
+					}
+
 					return strings.HasSuffix(iID, "/"+StageOpNameSuffixStart)
 				}
 
@@ -654,20 +1277,12 @@ func (b *DeployPlanBuilder) connectStages() error {
 		return iIndex < jIndex
 	})
 
-	for i := 0; i < len(opsStages); i++ {
-		if i == 0 {
-			continue
-		}
-
-		if err := b.plan.AddDependency(opsStages[i-1].ID(), opsStages[i].ID()); err != nil {
-			return fmt.Errorf("error adding dependency: %w", err)
-		}
-	}
-
-	return nil
+	return opsStages, true, nil
 }
 
-func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookResourceInfo, stageStartOpID, stageEndOpID string, pre bool) error {
+// Hooks with manual internal dependencies aren't bound to their weight stage, but still can't leave
+// the stage of their hook event.
+func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookResourceInfo, stageStartOpID, stageEndOpID, eventStageStartOpID, eventStageEndOpID string, pre bool) error {
 	var prevReleaseFailed bool
 	if b.prevRelease != nil {
 		prevReleaseFailed = b.prevRelease.Failed()
@@ -686,6 +1301,9 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
 		cleanup := info.ShouldCleanup(b.newRelease.Name(), b.releaseNamespace)
+		if (info.Resource().DeleteOnSucceeded() || info.Resource().DeleteOnFailed()) && info.ShouldKeepOnDelete(b.newRelease.Name(), b.releaseNamespace) {
+			b.addKeptResource(info.ResourceID, "resource policy is keep")
+		}
 		var trackReadiness bool
 		if track := info.ShouldTrackReadiness(prevReleaseFailed); track && !extraPost {
 			trackReadiness = true
@@ -704,6 +1322,14 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 		if r, set := info.Resource().DefaultReplicasOnCreation(); set {
 			forceReplicas = &r
 		}
+		var retries *int
+		if r, set := info.Resource().OperationRetries(); set {
+			retries = &r
+		}
+		var replaceOp *operation.RecreateResourceOperation
+		if b.forceReplace || info.Resource().ReplaceOnImmutableChange() {
+			replaceOp = b.replaceOnImmutableChangeOperation(info.ResourceID, info.Resource().Unstructured(), info.Resource().ManageableBy(), forceReplicas, extraPost)
+		}
 
 		var opDeploy operation.Operation
 		if create {
@@ -715,6 +1341,7 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 					ManageableBy:  info.Resource().ManageableBy(),
 					ForceReplicas: forceReplicas,
 					ExtraPost:     extraPost,
+					Retries:       retries,
 				},
 			)
 		} else if recreate {
@@ -744,8 +1371,9 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 				info.Resource().Unstructured(),
 				b.kubeClient,
 				operation.UpdateResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
-					ExtraPost:    extraPost,
+					ManageableBy:             info.Resource().ManageableBy(),
+					ExtraPost:                extraPost,
+					ReplaceOnImmutableChange: replaceOp,
 				},
 			)
 			if err != nil {
@@ -758,8 +1386,10 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 				info.Resource().Unstructured(),
 				b.kubeClient,
 				operation.ApplyResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
-					ExtraPost:    extraPost,
+					ManageableBy:             info.Resource().ManageableBy(),
+					ExtraPost:                extraPost,
+					Retries:                  retries,
+					ReplaceOnImmutableChange: replaceOp,
 				},
 			)
 			if err != nil {
@@ -771,8 +1401,8 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 			if manIntDepsSet {
 				b.plan.AddStagedOperation(
 					opDeploy,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-					StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
+					eventStageStartOpID,
+					eventStageEndOpID,
 				)
 			} else {
 				b.plan.AddStagedOperation(
@@ -783,51 +1413,30 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 			}
 		}
 
-		if extDepsSet && opDeploy != nil {
-			for _, dep := range externalDeps {
-				taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *kdutil.Concurrent[*statestore.PresenceTaskState]) bool {
-					var found bool
-
-					ts.RTransaction(func(pts *statestore.PresenceTaskState) {
-						if pts.Name() == dep.Name() &&
-							pts.Namespace() == dep.Namespace() &&
-							pts.GroupVersionKind() == dep.GroupVersionKind() {
-							found = true
-						}
-					})
-
-					return found
-				})
-
-				if !taskStateFound {
-					taskState = kdutil.NewConcurrent(
-						statestore.NewPresenceTaskState(
-							dep.Name(),
-							dep.Namespace(),
-							dep.GroupVersionKind(),
-							statestore.PresenceTaskStateOptions{},
-						),
-					)
-					b.taskStore.AddPresenceTaskState(taskState)
-				}
+		if delay, set := info.Resource().DeployDelay(); set && delay > 0 && opDeploy != nil {
+			opSleep := operation.NewSleepOperation(info.ID(), delay, operation.SleepOperationOptions{
+				HumanName: info.HumanID(),
+				ExtraPost: extraPost,
+			})
 
-				opTrackReadiness := operation.NewTrackResourcePresenceOperation(
-					dep.ResourceID,
-					taskState,
-					b.dynamicClient,
-					b.mapper,
-					operation.TrackResourcePresenceOperationOptions{
-						Timeout: b.readinessTimeout,
-					},
+			if manIntDepsSet {
+				b.plan.AddStagedOperation(
+					opSleep,
+					eventStageStartOpID,
+					eventStageEndOpID,
 				)
-
-				b.plan.AddInStagedOperation(
-					opTrackReadiness,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+			} else {
+				b.plan.AddStagedOperation(
+					opSleep,
+					stageStartOpID,
+					stageEndOpID,
 				)
-
-				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), opDeploy.ID()))
 			}
+			lo.Must0(b.plan.AddDependency(opDeploy.ID(), opSleep.ID()))
+		}
+
+		if extDepsSet && opDeploy != nil {
+			b.setupExternalDependenciesOperations(externalDeps, opDeploy)
 		}
 
 		var opTrackReadiness *operation.TrackResourceReadinessOperation
@@ -841,6 +1450,16 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 			if timeout, set := info.Resource().NoActivityTimeout(); set {
 				noActivityTimeout = *timeout
 			}
+			readyStableFor := b.readyStableFor
+			if duration, set := info.Resource().ReadyStableFor(); set {
+				readyStableFor = duration
+			}
+			timeout := b.readinessTimeout
+			if t, set := info.Resource().TrackTimeout(); set {
+				timeout = t
+			}
+			readyConditions, _ := info.Resource().TrackReadyConditions()
+			failureConditions, _ := info.Resource().TrackFailureConditions()
 
 			taskState := kdutil.NewConcurrent(
 				statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
@@ -859,22 +1478,28 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 				b.discoveryClient,
 				b.mapper,
 				operation.TrackResourceReadinessOperationOptions{
-					Timeout:                                  b.readinessTimeout,
+					Timeout:                                  timeout,
 					NoActivityTimeout:                        noActivityTimeout,
 					IgnoreReadinessProbeFailsByContainerName: ignoreReadinessProbes,
 					SaveLogsOnlyForContainers:                showLogsOnlyFor,
 					SaveLogsByRegex:                          logRegex,
 					SaveLogsByRegexForContainers:             logRegexesFor,
-					IgnoreLogs:                               info.Resource().SkipLogs(),
+					IgnoreLogs:                               b.ignoreLogs || info.Resource().SkipLogs(),
 					IgnoreLogsForContainers:                  skipLogsFor,
+					LogFollower:                              b.logFollower,
 					SaveEvents:                               info.Resource().ShowServiceMessages(),
+					StrictReadiness:                          info.Resource().StrictReadiness(),
+					ReadyStableFor:                           readyStableFor,
+					ReadyConditions:                          readyConditions,
+					FailureConditions:                        failureConditions,
+					NonFatal:                                 !info.Resource().FatalTrackFailure(),
 				},
 			)
 			if manIntDepsSet {
 				b.plan.AddStagedOperation(
 					opTrackReadiness,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-					StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
+					eventStageStartOpID,
+					eventStageEndOpID,
 				)
 			} else {
 				b.plan.AddStagedOperation(
@@ -894,6 +1519,7 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*info.DeployableHookReso
 				b.kubeClient,
 				operation.DeleteResourceOperationOptions{
 					ExtraPost: extraPost,
+					Retries:   retries,
 				},
 			)
 
@@ -952,6 +1578,9 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
 		cleanup := info.ShouldCleanup(b.newRelease.Name(), b.releaseNamespace)
+		if (info.Resource().DeleteOnSucceeded() || info.Resource().DeleteOnFailed()) && info.ShouldKeepOnDelete(b.newRelease.Name(), b.releaseNamespace) {
+			b.addKeptResource(info.ResourceID, "resource policy is keep")
+		}
 		trackReadiness := info.ShouldTrackReadiness(prevReleaseFailed)
 		_, manIntDepsSet := info.Resource().ManualInternalDependencies()
 		externalDeps, extDepsSet, err := info.Resource().ExternalDependencies()
@@ -962,6 +1591,14 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 		if r, set := info.Resource().DefaultReplicasOnCreation(); set {
 			forceReplicas = &r
 		}
+		var retries *int
+		if r, set := info.Resource().OperationRetries(); set {
+			retries = &r
+		}
+		var replaceOp *operation.RecreateResourceOperation
+		if b.forceReplace || info.Resource().ReplaceOnImmutableChange() {
+			replaceOp = b.replaceOnImmutableChangeOperation(info.ResourceID, info.Resource().Unstructured(), info.Resource().ManageableBy(), forceReplicas, false)
+		}
 
 		var opDeploy operation.Operation
 		if create {
@@ -972,6 +1609,7 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 				operation.CreateResourceOperationOptions{
 					ManageableBy:  info.Resource().ManageableBy(),
 					ForceReplicas: forceReplicas,
+					Retries:       retries,
 				},
 			)
 		} else if recreate {
@@ -1000,7 +1638,9 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 				info.Resource().Unstructured(),
 				b.kubeClient,
 				operation.UpdateResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
+					ManageableBy:             info.Resource().ManageableBy(),
+					Adopt:                    info.ShouldAdopt(),
+					ReplaceOnImmutableChange: replaceOp,
 				},
 			)
 			if err != nil {
@@ -1013,7 +1653,10 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 				info.Resource().Unstructured(),
 				b.kubeClient,
 				operation.ApplyResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
+					ManageableBy:             info.Resource().ManageableBy(),
+					Retries:                  retries,
+					Adopt:                    info.ShouldAdopt(),
+					ReplaceOnImmutableChange: replaceOp,
 				},
 			)
 			if err != nil {
@@ -1037,51 +1680,29 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 			}
 		}
 
-		if extDepsSet && opDeploy != nil {
-			for _, dep := range externalDeps {
-				taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *kdutil.Concurrent[*statestore.PresenceTaskState]) bool {
-					var found bool
-
-					ts.RTransaction(func(pts *statestore.PresenceTaskState) {
-						if pts.Name() == dep.Name() &&
-							pts.Namespace() == dep.Namespace() &&
-							pts.GroupVersionKind() == dep.GroupVersionKind() {
-							found = true
-						}
-					})
-
-					return found
-				})
-
-				if !taskStateFound {
-					taskState = kdutil.NewConcurrent(
-						statestore.NewPresenceTaskState(
-							dep.Name(),
-							dep.Namespace(),
-							dep.GroupVersionKind(),
-							statestore.PresenceTaskStateOptions{},
-						),
-					)
-					b.taskStore.AddPresenceTaskState(taskState)
-				}
-
-				opTrackReadiness := operation.NewTrackResourcePresenceOperation(
-					dep.ResourceID,
-					taskState,
-					b.dynamicClient,
-					b.mapper,
-					operation.TrackResourcePresenceOperationOptions{
-						Timeout: b.readinessTimeout,
-					},
-				)
+		if delay, set := info.Resource().DeployDelay(); set && delay > 0 && opDeploy != nil {
+			opSleep := operation.NewSleepOperation(info.ID(), delay, operation.SleepOperationOptions{
+				HumanName: info.HumanID(),
+			})
 
-				b.plan.AddInStagedOperation(
-					opTrackReadiness,
+			if manIntDepsSet {
+				b.plan.AddStagedOperation(
+					opSleep,
 					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+					StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
+				)
+			} else {
+				b.plan.AddStagedOperation(
+					opSleep,
+					stageStartOpID,
+					stageEndOpID,
 				)
-
-				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), opDeploy.ID()))
 			}
+			lo.Must0(b.plan.AddDependency(opDeploy.ID(), opSleep.ID()))
+		}
+
+		if extDepsSet && opDeploy != nil {
+			b.setupExternalDependenciesOperations(externalDeps, opDeploy)
 		}
 
 		var opTrackReadiness *operation.TrackResourceReadinessOperation
@@ -1095,6 +1716,16 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 			if timeout, set := info.Resource().NoActivityTimeout(); set {
 				noActivityTimeout = *timeout
 			}
+			readyStableFor := b.readyStableFor
+			if duration, set := info.Resource().ReadyStableFor(); set {
+				readyStableFor = duration
+			}
+			timeout := b.readinessTimeout
+			if t, set := info.Resource().TrackTimeout(); set {
+				timeout = t
+			}
+			readyConditions, _ := info.Resource().TrackReadyConditions()
+			failureConditions, _ := info.Resource().TrackFailureConditions()
 
 			taskState := kdutil.NewConcurrent(
 				statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
@@ -1113,15 +1744,21 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 				b.discoveryClient,
 				b.mapper,
 				operation.TrackResourceReadinessOperationOptions{
-					Timeout:                                  b.readinessTimeout,
+					Timeout:                                  timeout,
 					NoActivityTimeout:                        noActivityTimeout,
 					IgnoreReadinessProbeFailsByContainerName: ignoreReadinessProbes,
 					SaveLogsOnlyForContainers:                showLogsOnlyFor,
 					SaveLogsByRegex:                          logRegex,
 					SaveLogsByRegexForContainers:             logRegexesFor,
-					IgnoreLogs:                               info.Resource().SkipLogs(),
+					IgnoreLogs:                               b.ignoreLogs || info.Resource().SkipLogs(),
 					IgnoreLogsForContainers:                  skipLogsFor,
+					LogFollower:                              b.logFollower,
 					SaveEvents:                               info.Resource().ShowServiceMessages(),
+					StrictReadiness:                          info.Resource().StrictReadiness(),
+					ReadyStableFor:                           readyStableFor,
+					ReadyConditions:                          readyConditions,
+					FailureConditions:                        failureConditions,
+					NonFatal:                                 !info.Resource().FatalTrackFailure(),
 				},
 			)
 			if manIntDepsSet {
@@ -1146,7 +1783,9 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 			cleanupOp := operation.NewDeleteResourceOperation(
 				info.ResourceID,
 				b.kubeClient,
-				operation.DeleteResourceOperationOptions{},
+				operation.DeleteResourceOperationOptions{
+					Retries: retries,
+				},
 			)
 
 			if trackReadiness {
@@ -1190,3 +1829,27 @@ func (b *DeployPlanBuilder) setupGeneralOperations(infos []*info.DeployableGener
 
 	return nil
 }
+
+// Changes of immutable fields are normally detected during planning and the resource is recreated
+// right away. Replacing is a fallback for when they couldn't be detected, so the absence of the
+// resource is tracked without showing it in the progress tables.
+func (b *DeployPlanBuilder) replaceOnImmutableChangeOperation(resID *resid.ResourceID, unstruct *unstructured.Unstructured, manageableBy resource.ManageableBy, forceReplicas *int, extraPost bool) *operation.RecreateResourceOperation {
+	absenceTaskState := kdutil.NewConcurrent(
+		statestore.NewAbsenceTaskState(resID.Name(), resID.Namespace(), resID.GroupVersionKind(), statestore.AbsenceTaskStateOptions{}),
+	)
+
+	return operation.NewRecreateResourceOperation(
+		resID,
+		unstruct,
+		absenceTaskState,
+		b.kubeClient,
+		b.dynamicClient,
+		b.mapper,
+		operation.RecreateResourceOperationOptions{
+			ManageableBy:         manageableBy,
+			ForceReplicas:        forceReplicas,
+			DeletionTrackTimeout: b.deletionTimeout,
+			ExtraPost:            extraPost,
+		},
+	)
+}
@@ -0,0 +1,453 @@
+package plan_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/storage"
+	"github.com/werf/3p-helm/pkg/storage/driver"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	kdutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/plan/resourceinfo"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("deploy plan builder", func() {
+	var (
+		ctx     context.Context
+		logs    *bytes.Buffer
+		cluster *fake.Cluster
+	)
+
+	BeforeEach(func() {
+		logs = &bytes.Buffer{}
+		ctx = logboek.NewContext(context.Background(), logboek.NewLogger(logs, logs))
+		cluster = fake.NewCluster(ctx)
+	})
+
+	hook := func(manifest string) *resource.HookResource {
+		return resource.NewHookResource(unstructFromYAML(manifest), resource.HookResourceOptions{
+			DefaultNamespace: "app-ns",
+			Mapper:           cluster.Mapper,
+		})
+	}
+
+	general := func(manifest string) *resource.GeneralResource {
+		return resource.NewGeneralResource(unstructFromYAML(manifest), resource.GeneralResourceOptions{
+			DefaultNamespace: "app-ns",
+			Mapper:           cluster.Mapper,
+		})
+	}
+
+	newBuilder := func(hooks []*resource.HookResource, generals, prevGenerals []*resource.GeneralResource, opts plan.DeployPlanBuilderOptions) *plan.DeployPlanBuilder {
+		var hookInfos []*resourceinfo.DeployableHookResourceInfo
+		for _, res := range hooks {
+			info, err := resourceinfo.NewDeployableHookResourceInfo(ctx, res, "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableHookResourceInfoOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			hookInfos = append(hookInfos, info)
+		}
+
+		var generalInfos []*resourceinfo.DeployableGeneralResourceInfo
+		for _, res := range generals {
+			info, err := resourceinfo.NewDeployableGeneralResourceInfo(ctx, res, "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableGeneralResourceInfoOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			generalInfos = append(generalInfos, info)
+		}
+
+		var prevGeneralInfos []*resourceinfo.DeployablePrevReleaseGeneralResourceInfo
+		for _, res := range prevGenerals {
+			info, err := resourceinfo.NewDeployablePrevReleaseGeneralResourceInfo(ctx, res, "app-ns", cluster.KubeClient, cluster.Mapper)
+			Expect(err).NotTo(HaveOccurred())
+
+			prevGeneralInfos = append(prevGeneralInfos, info)
+		}
+
+		rel, err := release.NewRelease("app", "app-ns", 1, nil, &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}}, hooks, generals, "", release.ReleaseOptions{Mapper: cluster.Mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		history, err := release.NewHistory("app", "app-ns", storage.Init(driver.NewMemory()), release.HistoryOptions{Mapper: cluster.Mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		return plan.NewDeployPlanBuilder(
+			"app-ns",
+			common.DeployTypeInitial,
+			statestore.NewTaskStore(),
+			kdutil.NewConcurrent(logstore.NewLogStore()),
+			nil,
+			hookInfos,
+			generalInfos,
+			prevGeneralInfos,
+			rel,
+			history,
+			cluster.KubeClient,
+			kubefake.NewSimpleClientset(),
+			cluster.Dynamic,
+			cluster.Discovery,
+			cluster.Mapper,
+			opts,
+		)
+	}
+
+	build := func(hooks []*resource.HookResource, generals []*resource.GeneralResource) (*plan.Plan, error) {
+		return newBuilder(hooks, generals, nil, plan.DeployPlanBuilderOptions{}).Build(ctx)
+	}
+
+	Describe("release namespace", func() {
+		releaseNamespaceInfo := func(adopt bool) *resourceinfo.DeployableReleaseNamespaceInfo {
+			res := resource.NewReleaseNamespace(unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns}}`), resource.ReleaseNamespaceOptions{
+				Mapper: cluster.Mapper,
+			})
+
+			info, err := resourceinfo.NewDeployableReleaseNamespaceInfo(ctx, res, "app", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableReleaseNamespaceInfoOptions{
+				Adopt: adopt,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			return info
+		}
+
+		It("creates the missing namespace and locks the release before anything else", func() {
+			deployPlan, err := newBuilder(nil, []*resource.GeneralResource{
+				general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config}}`),
+			}, nil, plan.DeployPlanBuilderOptions{
+				ReleaseNamespaceInfo: releaseNamespaceInfo(false),
+				LockReleaseOperation: operation.NewLockReleaseOperation("app", &fakeReleaseLocker{}),
+			}).Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployPlan.Validate()).To(Succeed())
+
+			Expect(dependsOn(deployPlan, "track-resource-presence/default::Namespace:app-ns", "create/default::Namespace:app-ns")).To(BeTrue())
+			Expect(dependsOn(deployPlan, "lock-release/app", "track-resource-presence/default::Namespace:app-ns")).To(BeTrue())
+			Expect(dependsOn(deployPlan, "create-pending-release/", "lock-release/app")).To(BeTrue())
+			Expect(dependsOn(deployPlan, plan.StageOpNamePrefixInit, "lock-release/app")).To(BeTrue())
+			Expect(dependsOn(deployPlan, "create/app-ns::ConfigMap:config", "lock-release/app")).To(BeTrue())
+		})
+
+		It("doesn't touch the existing namespace not owned by the release", func() {
+			cluster = fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns}}`))
+
+			deployPlan, err := newBuilder(nil, nil, nil, plan.DeployPlanBuilderOptions{
+				ReleaseNamespaceInfo: releaseNamespaceInfo(false),
+			}).Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(operationIDs(deployPlan)).NotTo(ContainElement(ContainSubstring("Namespace:app-ns")))
+		})
+
+		It("takes over the existing namespace if adopted", func() {
+			cluster = fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns}}`))
+
+			deployPlan, err := newBuilder(nil, nil, nil, plan.DeployPlanBuilderOptions{
+				ReleaseNamespaceInfo: releaseNamespaceInfo(true),
+			}).Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(operationIDs(deployPlan)).To(ContainElement(MatchRegexp(`^(update|apply)/default::Namespace:app-ns$`)))
+			Expect(dependsOn(deployPlan, "create-pending-release/", "::Namespace:app-ns")).To(BeTrue())
+		})
+
+		It("shows the missing namespace among the planned changes to be confirmed", func() {
+			created, _, _, _, _, anyChanges := plan.CalculatePlannedChanges("app", "app-ns", releaseNamespaceInfo(false), nil, nil, nil, nil, false, plan.CalculatePlannedChangesOptions{})
+
+			Expect(anyChanges).To(BeTrue())
+			Expect(created).To(HaveLen(1))
+			Expect(created[0].ResourceID.Name()).To(Equal("app-ns"))
+		})
+
+		It("doesn't show the existing namespace not owned by the release among the planned changes", func() {
+			cluster = fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns}}`))
+
+			_, _, _, _, _, anyChanges := plan.CalculatePlannedChanges("app", "app-ns", releaseNamespaceInfo(false), nil, nil, nil, nil, false, plan.CalculatePlannedChangesOptions{})
+
+			Expect(anyChanges).To(BeFalse())
+		})
+	})
+
+	It("orders a hook after a hook of the same event it depends on, despite weights", func() {
+		deployPlan, err := build([]*resource.HookResource{
+			hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/hook-weight: "-10", werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+			hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: pre-install, helm.sh/hook-weight: "10"}}}`),
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(dependsOn(deployPlan, "create/app-ns:batch:Job:migrate", "create/app-ns::ConfigMap:config")).To(BeTrue())
+		Expect(dependsOn(deployPlan, "create/app-ns:batch:Job:migrate", plan.StageOpNamePrefixHookCRDs)).To(BeTrue())
+		Expect(dependsOn(deployPlan, plan.StageOpNamePrefixFinal, "track-resource-readiness/app-ns:batch:Job:migrate")).To(BeTrue())
+	})
+
+	It("warns if werf.io/weight and helm.sh/hook-weight of a hook conflict", func() {
+		_, err := build([]*resource.HookResource{
+			hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: pre-install, helm.sh/hook-weight: "5", werf.io/weight: "10"}}}`),
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(logs.String()).To(ContainSubstring("has both werf.io/weight=10 and helm.sh/hook-weight=5 annotations"))
+	})
+
+	It("doesn't warn if werf.io/weight and helm.sh/hook-weight of a hook are the same", func() {
+		_, err := build([]*resource.HookResource{
+			hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: pre-install, helm.sh/hook-weight: "5", werf.io/weight: "5"}}}`),
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(logs.String()).NotTo(ContainSubstring("werf.io/weight"))
+	})
+
+	It("fails if a pre hook depends on a general resource", func() {
+		_, err := build([]*resource.HookResource{
+			hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+		}, []*resource.GeneralResource{
+			general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config}}`),
+		})
+		Expect(err).To(MatchError(ContainSubstring(`pre hook "Job/migrate" can't depend on general resource "ConfigMap/config"`)))
+	})
+
+	It("fails if a pre hook depends on a post hook", func() {
+		deployPlan, err := build([]*resource.HookResource{
+			hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, werf.io/deploy-dependency-notify: "state=present,kind=Job,name=notify"}}}`),
+			hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: notify, annotations: {helm.sh/hook: post-install}}}`),
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployPlan.Validate()).To(MatchError(And(ContainSubstring("dependency cycle"), ContainSubstring("Job/notify"))))
+	})
+
+	Describe("with resource and delete policies", func() {
+		type keptCase struct {
+			hook, general string
+			deleteOps     []string
+			noDeleteOps   []string
+			keptReason    string
+		}
+
+		DescribeTable("deletes resources or reports them as kept",
+			func(c keptCase) {
+				var hooks []*resource.HookResource
+				if c.hook != "" {
+					hooks = append(hooks, hook(c.hook))
+				}
+
+				var generals []*resource.GeneralResource
+				if c.general != "" {
+					generals = append(generals, general(c.general))
+				}
+
+				builder := newBuilder(hooks, generals, nil, plan.DeployPlanBuilderOptions{})
+				deployPlan, err := builder.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				ids := operationIDs(deployPlan)
+				for _, id := range c.deleteOps {
+					Expect(ids).To(ContainElement(id))
+				}
+				for _, id := range c.noDeleteOps {
+					Expect(ids).NotTo(ContainElement(id))
+				}
+
+				if c.keptReason == "" {
+					Expect(builder.KeptResources()).To(BeEmpty())
+				} else {
+					Expect(builder.KeptResources()).To(HaveLen(1))
+					Expect(builder.KeptResources()[0].Reason).To(Equal(c.keptReason))
+				}
+			},
+			Entry("hook deleted on success", keptCase{
+				hook:      `{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/hook-delete-policy: hook-succeeded}}}`,
+				deleteOps: []string{"delete/app-ns:batch:Job:migrate"},
+			}),
+			Entry("hook deleted on success with keep policy", keptCase{
+				hook:        `{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/hook-delete-policy: hook-succeeded, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:migrate"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("hook deleted on success by werf.io/delete-policy with keep policy", keptCase{
+				hook:        `{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, werf.io/delete-policy: "before-creation,succeeded,failed", helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:migrate"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("hook deleted on failure with keep policy", keptCase{
+				hook:        `{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/hook-delete-policy: hook-failed, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:migrate"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("hook recreated before creation with keep policy", keptCase{
+				hook:        `{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/hook-delete-policy: before-hook-creation, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:migrate"},
+			}),
+			Entry("pre and post hook deleted on success", keptCase{
+				hook:      `{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: "pre-install,post-install", helm.sh/hook-delete-policy: hook-succeeded}}}`,
+				deleteOps: []string{"extra-post-delete/app-ns::ConfigMap:config"},
+			}),
+			Entry("pre and post hook deleted on success with keep policy", keptCase{
+				hook:        `{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: "pre-install,post-install", helm.sh/hook-delete-policy: hook-succeeded, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns::ConfigMap:config", "extra-post-delete/app-ns::ConfigMap:config"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("general resource deleted on success", keptCase{
+				general:   `{apiVersion: batch/v1, kind: Job, metadata: {name: job, annotations: {werf.io/delete-policy: succeeded}}}`,
+				deleteOps: []string{"delete/app-ns:batch:Job:job"},
+			}),
+			Entry("general resource deleted on success with keep policy", keptCase{
+				general:     `{apiVersion: batch/v1, kind: Job, metadata: {name: job, annotations: {werf.io/delete-policy: succeeded, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:job"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("general resource deleted on failure with keep policy", keptCase{
+				general:     `{apiVersion: batch/v1, kind: Job, metadata: {name: job, annotations: {werf.io/delete-policy: failed, helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns:batch:Job:job"},
+				keptReason:  "resource policy is keep",
+			}),
+			Entry("general resource with keep policy only", keptCase{
+				general:     `{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/resource-policy: keep}}}`,
+				noDeleteOps: []string{"delete/app-ns::ConfigMap:config"},
+			}),
+		)
+
+		DescribeTable("deletes resources removed from the release or reports them as kept",
+			func(annotations, keptReason string) {
+				manifest := `{apiVersion: v1, kind: ConfigMap, metadata: {name: old, namespace: app-ns, labels: {app.kubernetes.io/managed-by: Helm}, annotations: {` + annotations + `}}}`
+				cluster = fake.NewCluster(ctx, unstructFromYAML(manifest))
+
+				builder := newBuilder(nil, nil, []*resource.GeneralResource{general(manifest)}, plan.DeployPlanBuilderOptions{})
+				deployPlan, err := builder.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				if keptReason == "" {
+					Expect(operationIDs(deployPlan)).To(ContainElement("delete/app-ns::ConfigMap:old"))
+					Expect(builder.KeptResources()).To(BeEmpty())
+				} else {
+					Expect(operationIDs(deployPlan)).NotTo(ContainElement("delete/app-ns::ConfigMap:old"))
+					Expect(builder.KeptResources()).To(HaveLen(1))
+					Expect(builder.KeptResources()[0].Reason).To(Equal(keptReason))
+				}
+			},
+			Entry("owned by the release", `meta.helm.sh/release-name: app, meta.helm.sh/release-namespace: app-ns`, ""),
+			Entry("owned by the release with keep policy", `meta.helm.sh/release-name: app, meta.helm.sh/release-namespace: app-ns, helm.sh/resource-policy: keep`, "resource policy is keep"),
+			Entry("owned by another release", `meta.helm.sh/release-name: other, meta.helm.sh/release-namespace: app-ns`, "not owned by the release anymore"),
+			Entry("owned by another release with keep policy", `meta.helm.sh/release-name: other, meta.helm.sh/release-namespace: app-ns, helm.sh/resource-policy: keep`, "resource policy is keep"),
+		)
+	})
+
+	Describe("with werf.io/deploy-delay on a dependency", func() {
+		It("makes a general resource wait for the delay of a general resource", func() {
+			deployPlan, err := build(nil, []*resource.GeneralResource{
+				general(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, annotations: {werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+				general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {werf.io/deploy-delay: "10s"}}}`),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(directlyDependsOn(deployPlan, "create/app-ns:apps:Deployment:api", "sleep/app-ns::ConfigMap:config")).To(BeTrue())
+		})
+
+		It("makes a hook wait for the delay of a hook of the same event", func() {
+			deployPlan, err := build([]*resource.HookResource{
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: post-install, werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+				hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: post-install, werf.io/deploy-delay: "10s"}}}`),
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(directlyDependsOn(deployPlan, "create/app-ns:batch:Job:migrate", "sleep/app-ns::ConfigMap:config")).To(BeTrue())
+		})
+
+		It("makes a post hook wait for the delay of the post deploy of a pre and post hook", func() {
+			deployPlan, err := build([]*resource.HookResource{
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: post-install, helm.sh/hook-weight: "10", werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+				hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: "pre-install,post-install", werf.io/deploy-delay: "10s"}}}`),
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(directlyDependsOn(deployPlan, "create/app-ns:batch:Job:migrate", "extra-post-sleep/app-ns::ConfigMap:config")).To(BeTrue())
+			Expect(dependsOn(deployPlan, "extra-post-sleep/app-ns::ConfigMap:config", "extra-post-create/app-ns::ConfigMap:config")).To(BeTrue())
+			Expect(deployPlan.Validate()).To(Succeed())
+		})
+
+		It("makes a general resource wait for the delay of the pre deploy of a pre and post hook", func() {
+			deployPlan, err := build([]*resource.HookResource{
+				hook(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, annotations: {helm.sh/hook: "pre-install,post-install", werf.io/deploy-delay: "10s"}}}`),
+			}, []*resource.GeneralResource{
+				general(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, annotations: {werf.io/deploy-dependency-config: "state=present,kind=ConfigMap,name=config"}}}`),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependsOn(deployPlan, "create/app-ns:apps:Deployment:api", "sleep/app-ns::ConfigMap:config")).To(BeTrue())
+			Expect(directlyDependsOn(deployPlan, "create/app-ns:apps:Deployment:api", "extra-post-sleep/app-ns::ConfigMap:config")).To(BeFalse())
+			Expect(deployPlan.Validate()).To(Succeed())
+		})
+	})
+})
+
+type fakeReleaseLocker struct{}
+
+func (l *fakeReleaseLocker) LockRelease(ctx context.Context, releaseName string) (lockgate.LockHandle, error) {
+	return lockgate.LockHandle{LockName: "release/" + releaseName}, nil
+}
+
+func operationIDs(p *plan.Plan) []string {
+	predecessors, err := p.PredecessorMap()
+	Expect(err).NotTo(HaveOccurred())
+
+	return lo.Keys(predecessors)
+}
+
+// directlyDependsOn reports whether the operation "from" has a dependency on the operation "to".
+// Edges implied by other dependencies are removed by the plan optimization, so this is only true
+// if nothing else orders the operations.
+func directlyDependsOn(p *plan.Plan, from, to string) bool {
+	predecessors, err := p.PredecessorMap()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(predecessors).To(HaveKey(from))
+
+	_, found := predecessors[from][to]
+
+	return found
+}
+
+// dependsOn reports whether an operation whose ID contains "to" is reachable from an operation
+// whose ID contains "from", going back through the dependencies.
+func dependsOn(p *plan.Plan, from, to string) bool {
+	predecessors, err := p.PredecessorMap()
+	Expect(err).NotTo(HaveOccurred())
+
+	var queue []string
+	for id := range predecessors {
+		if strings.Contains(id, from) {
+			queue = append(queue, id)
+		}
+	}
+	Expect(queue).NotTo(BeEmpty(), "no operation matches %q", from)
+
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for predecessor := range predecessors[id] {
+			if strings.Contains(predecessor, to) && !strings.Contains(predecessor, from) {
+				return true
+			}
+
+			if !visited[predecessor] {
+				visited[predecessor] = true
+				queue = append(queue, predecessor)
+			}
+		}
+	}
+
+	return false
+}
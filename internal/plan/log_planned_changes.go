@@ -5,10 +5,15 @@ import (
 	"fmt"
 
 	"github.com/gookit/color"
+	"github.com/samber/lo"
 
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/pkg/log"
 )
 
+type LogPlannedChangesOptions struct {
+	ShowDiff bool
+}
+
 func LogPlannedChanges(
 	ctx context.Context,
 	releaseName string,
@@ -19,78 +24,101 @@ func LogPlannedChanges(
 	updatedChanges []*UpdatedResourceChange,
 	appliedChanges []*AppliedResourceChange,
 	deletedChanges []*DeletedResourceChange,
+	opts LogPlannedChangesOptions,
 ) {
 	totalChangesLen := len(createdChanges) + len(recreatedChanges) + len(updatedChanges) + len(appliedChanges) + len(deletedChanges)
 
 	if totalChangesLen == 0 {
 		if releaseChangesPlanned {
-			log.Default.Info(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("No changes planned, but will create release %q (namespace: %q)", releaseName, releaseNamespace)))
+			log.Plan.Info(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("No changes planned, but will create release %q (namespace: %q)", releaseName, releaseNamespace)))
 		} else {
-			log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("No changes planned for release %q (namespace: %q)", releaseName, releaseNamespace)))
+			log.Plan.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("No changes planned for release %q (namespace: %q)", releaseName, releaseNamespace)))
 		}
 
 		return
 	}
 
-	log.Default.Info(ctx, "")
+	log.Plan.Info(ctx, "")
 
 	for _, change := range createdChanges {
-		log.Default.InfoBlock(ctx, createStyle("Create ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
-			func() {
-				log.Default.Info(ctx, "%s", change.Udiff)
-			},
-		)
+		logPlannedChange(ctx, createStyle("Create ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)+failureEnding(change.DryCreateErr), change.Udiff, opts.ShowDiff)
 	}
 
 	for _, change := range recreatedChanges {
-		log.Default.InfoBlock(ctx, recreateStyle("Recreate ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
-			func() {
-				log.Default.Info(ctx, "%s", change.Udiff)
-			},
-		)
+		logPlannedChange(ctx, recreateStyle("Recreate ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure), change.Udiff, opts.ShowDiff)
 	}
 
 	for _, change := range updatedChanges {
-		log.Default.InfoBlock(ctx, updateStyle("Update ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
-			func() {
-				log.Default.Info(ctx, "%s", change.Udiff)
-			},
-		)
+		if change.Adopted {
+			logPlannedChange(ctx, adoptStyle("Adopt ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure), change.Udiff, opts.ShowDiff)
+			continue
+		}
+
+		logPlannedChange(ctx, updateStyle("Update ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure), change.Udiff, opts.ShowDiff)
 	}
 
 	for _, change := range appliedChanges {
-		log.Default.InfoBlock(ctx, applyStyle("Blindly apply ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
-			func() {
-				log.Default.Info(ctx, "%s", change.Udiff)
-			},
-		)
+		if change.Adopted {
+			logPlannedChange(ctx, adoptStyle("Adopt ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure), change.Udiff, opts.ShowDiff)
+			continue
+		}
+
+		logPlannedChange(ctx, applyStyle("Blindly apply ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure), change.Udiff, opts.ShowDiff)
 	}
 
 	for _, change := range deletedChanges {
-		log.Default.InfoBlock(ctx, deleteStyle("Delete ")+resourceStyle(change.ResourceID.HumanID())).Do(
-			func() {
-				log.Default.Info(ctx, "%s", change.Udiff)
-			},
-		)
+		logPlannedChange(ctx, deleteStyle("Delete ")+resourceStyle(change.ResourceID.HumanID()), change.Udiff, opts.ShowDiff)
 	}
 
-	log.Default.Info(ctx, color.Bold.Render("Planned changes summary")+" for release %q (namespace: %q):", releaseName, releaseNamespace)
+	if !opts.ShowDiff {
+		log.Plan.Info(ctx, "")
+	}
+
+	adoptedUpdatesLen := lo.CountBy(updatedChanges, func(change *UpdatedResourceChange) bool {
+		return change.Adopted
+	})
+	adoptedAppliesLen := lo.CountBy(appliedChanges, func(change *AppliedResourceChange) bool {
+		return change.Adopted
+	})
+
+	log.Plan.Info(ctx, color.Bold.Render("Planned changes summary")+" for release %q (namespace: %q):", releaseName, releaseNamespace)
 	if len(createdChanges) > 0 {
-		log.Default.Info(ctx, "- "+createStyle("create:")+" %d resource(s)", len(createdChanges))
+		log.Plan.Info(ctx, "- "+createStyle("create:")+" %d resource(s)", len(createdChanges))
+	}
+	if failingCreatesLen := lo.CountBy(createdChanges, func(change *CreatedResourceChange) bool {
+		return change.DryCreateErr != nil
+	}); failingCreatesLen > 0 {
+		log.Plan.Info(ctx, "- "+failStyle("expected to fail:")+" %d resource(s)", failingCreatesLen)
 	}
 	if len(recreatedChanges) > 0 {
-		log.Default.Info(ctx, "- "+recreateStyle("recreate:")+" %d resource(s)", len(recreatedChanges))
+		log.Plan.Info(ctx, "- "+recreateStyle("recreate:")+" %d resource(s)", len(recreatedChanges))
 	}
-	if len(updatedChanges) > 0 {
-		log.Default.Info(ctx, "- "+updateStyle("update:")+" %d resource(s)", len(updatedChanges))
+	if adoptedLen := adoptedUpdatesLen + adoptedAppliesLen; adoptedLen > 0 {
+		log.Plan.Info(ctx, "- "+adoptStyle("adopt:")+" %d resource(s)", adoptedLen)
 	}
-	if len(appliedChanges) > 0 {
-		log.Default.Info(ctx, "- "+applyStyle("blindly apply:")+" %d resource(s)", len(appliedChanges))
+	if updatesLen := len(updatedChanges) - adoptedUpdatesLen; updatesLen > 0 {
+		log.Plan.Info(ctx, "- "+updateStyle("update:")+" %d resource(s)", updatesLen)
+	}
+	if appliesLen := len(appliedChanges) - adoptedAppliesLen; appliesLen > 0 {
+		log.Plan.Info(ctx, "- "+applyStyle("blindly apply:")+" %d resource(s)", appliesLen)
 	}
 	if len(deletedChanges) > 0 {
-		log.Default.Info(ctx, "- "+deleteStyle("delete:")+" %d resource(s)", len(deletedChanges))
+		log.Plan.Info(ctx, "- "+deleteStyle("delete:")+" %d resource(s)", len(deletedChanges))
 	}
-	log.Default.Info(ctx, "")
+	log.Plan.Info(ctx, "")
+}
+
+func logPlannedChange(ctx context.Context, header, uDiff string, showDiff bool) {
+	if !showDiff {
+		log.Plan.Info(ctx, "%s", header)
+		return
+	}
+
+	log.Plan.InfoBlock(ctx, header).Do(
+		func() {
+			log.Plan.Info(ctx, "%s", uDiff)
+		},
+	)
 }
 
 func createStyle(text string) string {
@@ -109,6 +137,10 @@ func applyStyle(text string) string {
 	return color.Style{color.Bold, color.LightYellow}.Render(text)
 }
 
+func adoptStyle(text string) string {
+	return color.Style{color.Bold, color.Cyan}.Render(text)
+}
+
 func deleteStyle(text string) string {
 	return color.Style{color.Bold, color.Red}.Render(text)
 }
@@ -117,6 +149,18 @@ func resourceStyle(text string) string {
 	return color.Style{color.Bold}.Render(text)
 }
 
+func failStyle(text string) string {
+	return color.Style{color.Bold, color.Red}.Render(text)
+}
+
+func failureEnding(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return ", " + failStyle("expected to fail") + ": " + err.Error()
+}
+
 func ending(cleanupOnSuccess, cleanupOnFailure bool) string {
 	if cleanupOnSuccess && cleanupOnFailure {
 		return " and " + deleteStyle("delete") + " it"
@@ -0,0 +1,67 @@
+package operation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var (
+	admissionWebhookDeniedRegex  = regexp.MustCompile(`(?s)admission webhook "([^"]+)" denied the request:\s*(.*)$`)
+	admissionWebhookFailedRegex  = regexp.MustCompile(`(?s)failed calling webhook "([^"]+)":\s*(.*)$`)
+	admissionWebhookServiceRegex = regexp.MustCompile(`https://([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.svc\b`)
+)
+
+func humanizeAdmissionWebhookError(resource *id.ResourceID, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+
+	if matches := admissionWebhookDeniedRegex.FindStringSubmatch(msg); matches != nil {
+		return &AdmissionWebhookError{
+			msg: fmt.Sprintf("resource %q rejected by admission webhook %q: %s", resource.HumanID(), matches[1], strings.TrimSpace(matches[2])),
+			err: err,
+		}
+	}
+
+	if matches := admissionWebhookFailedRegex.FindStringSubmatch(msg); matches != nil {
+		webhookMsg := strings.TrimSpace(matches[2])
+
+		if !strings.Contains(webhookMsg, "deadline exceeded") &&
+			!strings.Contains(webhookMsg, "Timeout") &&
+			!strings.Contains(webhookMsg, "timeout") &&
+			!strings.Contains(webhookMsg, "connection refused") &&
+			!strings.Contains(webhookMsg, "no endpoints available") {
+			return err
+		}
+
+		hint := "the service backing the webhook might be down"
+		if svcMatches := admissionWebhookServiceRegex.FindStringSubmatch(webhookMsg); svcMatches != nil {
+			hint = fmt.Sprintf("the service %q (namespace: %q) backing the webhook might be down", svcMatches[1], svcMatches[3])
+		}
+
+		return &AdmissionWebhookError{
+			msg: fmt.Sprintf("resource %q not admitted, admission webhook %q is unavailable (%s): %s", resource.HumanID(), matches[1], hint, webhookMsg),
+			err: err,
+		}
+	}
+
+	return err
+}
+
+type AdmissionWebhookError struct {
+	msg string
+	err error
+}
+
+func (e *AdmissionWebhookError) Error() string {
+	return e.msg
+}
+
+func (e *AdmissionWebhookError) Unwrap() error {
+	return e.err
+}
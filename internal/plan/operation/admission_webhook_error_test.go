@@ -0,0 +1,82 @@
+package operation_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var _ = Describe("admission webhook errors", func() {
+	applyWithError := func(apiErr error) error {
+		ctx := context.Background()
+		cluster := fake.NewCluster(ctx)
+		cluster.Dynamic.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apiErr
+		})
+
+		obj := unstructFromYAML(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: app}}`)
+		resID := id.NewResourceIDFromUnstruct(obj, id.ResourceIDOptions{Mapper: cluster.Mapper})
+
+		op, err := operation.NewApplyResourceOperation(resID, obj, cluster.KubeClient, operation.ApplyResourceOperationOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = op.Execute(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(op.Status()).To(Equal(operation.StatusFailed))
+
+		return err
+	}
+
+	statusErr := func(code int32, msg string) error {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{Status: metav1.StatusFailure, Code: code, Message: msg}}
+	}
+
+	It("reports denials with the webhook name and its message", func() {
+		err := applyWithError(statusErr(400, `admission webhook "validate.policy.example.com" denied the request: replicas must not exceed 10`))
+
+		Expect(err.Error()).To(Equal(`error applying resource: resource "app/Deployment/web" rejected by admission webhook "validate.policy.example.com": replicas must not exceed 10`))
+
+		var webhookErr *operation.AdmissionWebhookError
+		Expect(errors.As(err, &webhookErr)).To(BeTrue())
+	})
+
+	It("reports webhook timeouts with the backing service", func() {
+		err := applyWithError(statusErr(500, `Internal error occurred: failed calling webhook "validate.policy.example.com": failed to call webhook: Post "https://policy-webhook.policy-system.svc:443/validate?timeout=10s": context deadline exceeded`))
+
+		Expect(err.Error()).To(ContainSubstring(`resource "app/Deployment/web" not admitted, admission webhook "validate.policy.example.com" is unavailable`))
+		Expect(err.Error()).To(ContainSubstring(`the service "policy-webhook" (namespace: "policy-system") backing the webhook might be down`))
+	})
+
+	It("gives a generic hint if the webhook service is unknown", func() {
+		err := applyWithError(statusErr(500, `Internal error occurred: failed calling webhook "mutate.example.com": failed to call webhook: Post "https://10.0.0.1/mutate": dial tcp 10.0.0.1:443: connect: connection refused`))
+
+		Expect(err.Error()).To(ContainSubstring(`admission webhook "mutate.example.com" is unavailable (the service backing the webhook might be down)`))
+	})
+
+	It("passes unrelated errors through unchanged", func() {
+		original := statusErr(422, `Deployment.apps "web" is invalid: spec.template.metadata.labels: Invalid value: map[string]string{"app":"other"}: selector does not match template labels`)
+		err := applyWithError(original)
+
+		Expect(err.Error()).To(HaveSuffix(original.Error()))
+
+		var webhookErr *operation.AdmissionWebhookError
+		Expect(errors.As(err, &webhookErr)).To(BeFalse())
+	})
+
+	It("passes webhook call failures which are not timeouts through unchanged", func() {
+		original := statusErr(500, `Internal error occurred: failed calling webhook "mutate.example.com": failed to call webhook: the server responded with 500: bad payload`)
+		err := applyWithError(original)
+
+		Expect(err.Error()).To(HaveSuffix(original.Error()))
+	})
+})
@@ -9,9 +9,10 @@ import (
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
-var _ Operation = (*ApplyResourceOperation)(nil)
+var _ RetriableOperation = (*ApplyResourceOperation)(nil)
 
 const (
 	TypeApplyResourceOperation          = "apply"
@@ -30,12 +31,20 @@ func NewApplyResourceOperation(
 		kubeClient:   kubeClient,
 		manageableBy: opts.ManageableBy,
 		extraPost:    opts.ExtraPost,
+		adopt:        opts.Adopt,
+		replaceOp:    opts.ReplaceOnImmutableChange,
+		retries:      opts.Retries,
 	}, nil
 }
 
 type ApplyResourceOperationOptions struct {
 	ManageableBy resource.ManageableBy
 	ExtraPost    bool
+	Adopt        bool
+	Retries      *int
+	// If set, the resource is replaced by this operation when applying fails because of changed
+	// immutable fields.
+	ReplaceOnImmutableChange *RecreateResourceOperation
 }
 
 type ApplyResourceOperation struct {
@@ -44,19 +53,44 @@ type ApplyResourceOperation struct {
 	kubeClient   kube.KubeClienter
 	manageableBy resource.ManageableBy
 	extraPost    bool
+	adopt        bool
+	replaceOp    *RecreateResourceOperation
+	retries      *int
 	status       Status
 }
 
 func (o *ApplyResourceOperation) Execute(ctx context.Context) error {
+	o.status = StatusUnknown
+
 	if _, err := o.kubeClient.Apply(ctx, o.resource, o.unstruct, kube.KubeClientApplyOptions{}); err != nil {
+		if o.replaceOp != nil && isImmutableErr(err) {
+			log.Plan.Warn(ctx, "Warning: immutable fields of %s changed, replacing it", o.resource.HumanID())
+
+			if err := o.replaceOp.Execute(ctx); err != nil {
+				o.status = StatusFailed
+				return fmt.Errorf("error replacing resource: %w", err)
+			}
+			o.status = StatusCompleted
+
+			return nil
+		}
+
 		o.status = StatusFailed
-		return fmt.Errorf("error applying resource: %w", err)
+		return fmt.Errorf("error applying resource: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
 	}
 	o.status = StatusCompleted
 
 	return nil
 }
 
+func (o *ApplyResourceOperation) Retries() (retries int, set bool) {
+	if o.retries == nil {
+		return 0, false
+	}
+
+	return *o.retries, true
+}
+
 func (o *ApplyResourceOperation) ID() string {
 	if o.extraPost {
 		return TypeExtraPostApplyResourceOperation + "/" + o.resource.ID()
@@ -65,8 +99,20 @@ func (o *ApplyResourceOperation) ID() string {
 	return TypeApplyResourceOperation + "/" + o.resource.ID()
 }
 
+func (o *ApplyResourceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *ApplyResourceOperation) HumanID() string {
-	return "apply resource: " + o.resource.HumanID()
+	if o.adopt {
+		return "adopt resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+	}
+
+	return "apply resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+}
+
+func (o *ApplyResourceOperation) ManifestHash() string {
+	return resource.ManifestHash(o.unstruct)
 }
 
 func (o *ApplyResourceOperation) Status() Status {
@@ -0,0 +1,217 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// Readiness of resources with custom track conditions is determined only by these conditions,
+// which are evaluated against the live object on every change of it.
+func (o *TrackResourceReadinessOperation) trackConditions(ctx context.Context) error {
+	client, err := o.resourceClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if o.timeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, o.timeout)
+		defer timeoutCancel()
+	}
+
+	var noActivityTimer *time.Timer
+	if o.noActivityTimeout > 0 {
+		noActivityTimer = time.AfterFunc(o.noActivityTimeout, func() {
+			cancel(fmt.Errorf("no activity for %s", o.noActivityTimeout))
+		})
+		defer noActivityTimer.Stop()
+	}
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", o.resource.Name()).String()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = fieldSelector
+			return client.List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = fieldSelector
+			return client.Watch(ctx, opts)
+		},
+	}
+
+	var (
+		failedCondition *resource.TrackCondition
+		lastResult      string
+	)
+
+	_, watchErr := watchtools.UntilWithSync(ctx, listWatch, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || event.Type == watch.Deleted {
+			return false, nil
+		}
+
+		if noActivityTimer != nil {
+			noActivityTimer.Reset(o.noActivityTimeout)
+		}
+
+		result, err := EvaluateTrackConditions(obj.Object, o.readyConditions, o.failureConditions)
+		if err != nil {
+			log.Track.Debug(ctx, "Unable to evaluate track conditions of %s: %s", o.resource.HumanID(), err)
+			return false, nil
+		}
+
+		if msg := result.String(); msg != lastResult {
+			lastResult = msg
+			o.addRootResourceEvent(msg)
+		}
+
+		if result.Failed != nil {
+			failedCondition = result.Failed
+			return true, nil
+		}
+
+		return result.Ready, nil
+	})
+
+	if watchErr != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			watchErr = cause
+		}
+
+		err := fmt.Errorf("wait for %s: %w", strings.Join(conditionsStrings(o.readyConditions), " and "), watchErr)
+		o.setRootResourceFailed(err)
+
+		return err
+	}
+
+	if failedCondition != nil {
+		err := fmt.Errorf("failure condition %q holds", failedCondition)
+		o.setRootResourceFailed(err)
+
+		return err
+	}
+
+	o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+		ts.ResourceState(ts.Name(), ts.Namespace(), ts.GroupVersionKind()).RWTransaction(func(rs *statestore.ResourceState) {
+			rs.SetStatus(statestore.ResourceStatusReady)
+		})
+		ts.SetStatus(statestore.ReadinessTaskStatusReady)
+	})
+
+	return nil
+}
+
+func (o *TrackResourceReadinessOperation) resourceClient() (dynamic.ResourceInterface, error) {
+	gvr, err := o.resource.GroupVersionResource()
+	if err != nil {
+		return nil, fmt.Errorf("get resource of %s: %w", o.resource.HumanID(), err)
+	}
+
+	namespaced, err := o.resource.Namespaced()
+	if err != nil {
+		return nil, fmt.Errorf("check whether %s is namespaced: %w", o.resource.HumanID(), err)
+	}
+
+	if namespaced {
+		return o.dynamicClient.Resource(gvr).Namespace(o.resource.Namespace()), nil
+	}
+
+	return o.dynamicClient.Resource(gvr), nil
+}
+
+func (o *TrackResourceReadinessOperation) addRootResourceEvent(msg string) {
+	o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+		ts.ResourceState(ts.Name(), ts.Namespace(), ts.GroupVersionKind()).RWTransaction(func(rs *statestore.ResourceState) {
+			rs.AddEvent(msg, time.Now())
+		})
+	})
+}
+
+func (o *TrackResourceReadinessOperation) setRootResourceFailed(err error) {
+	o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+		ts.ResourceState(ts.Name(), ts.Namespace(), ts.GroupVersionKind()).RWTransaction(func(rs *statestore.ResourceState) {
+			rs.AddError(err, "", time.Now())
+			rs.SetStatus(statestore.ResourceStatusFailed)
+		})
+		ts.SetStatus(statestore.ReadinessTaskStatusFailed)
+	})
+}
+
+type TrackConditionsResult struct {
+	Ready bool
+	// The first failure condition which holds, if any.
+	Failed *resource.TrackCondition
+	// Ready conditions which don't hold yet.
+	Pending []*resource.TrackCondition
+}
+
+func (r *TrackConditionsResult) String() string {
+	switch {
+	case r.Failed != nil:
+		return fmt.Sprintf("failure condition %q holds", r.Failed)
+	case r.Ready:
+		return "all track conditions hold"
+	default:
+		return fmt.Sprintf("waiting for %s", strings.Join(conditionsStrings(r.Pending), " and "))
+	}
+}
+
+// EvaluateTrackConditions considers the object ready if all ready conditions hold, unless any of
+// the failure conditions holds.
+func EvaluateTrackConditions(obj map[string]interface{}, readyConditions, failureConditions []*resource.TrackCondition) (*TrackConditionsResult, error) {
+	result := &TrackConditionsResult{}
+
+	for _, cond := range failureConditions {
+		holds, err := cond.Holds(obj)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate failure condition %q: %w", cond, err)
+		}
+
+		if holds {
+			result.Failed = cond
+			return result, nil
+		}
+	}
+
+	for _, cond := range readyConditions {
+		holds, err := cond.Holds(obj)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate ready condition %q: %w", cond, err)
+		}
+
+		if !holds {
+			result.Pending = append(result.Pending, cond)
+		}
+	}
+
+	result.Ready = len(result.Pending) == 0
+
+	return result, nil
+}
+
+func conditionsStrings(conditions []*resource.TrackCondition) []string {
+	var result []string
+	for _, cond := range conditions {
+		result = append(result, fmt.Sprintf("%q", cond))
+	}
+
+	return result
+}
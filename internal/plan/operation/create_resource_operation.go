@@ -10,9 +10,10 @@ import (
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
-var _ Operation = (*CreateResourceOperation)(nil)
+var _ RetriableOperation = (*CreateResourceOperation)(nil)
 
 const (
 	TypeCreateResourceOperation          = "create"
@@ -32,6 +33,7 @@ func NewCreateResourceOperation(
 		manageableBy:  opts.ManageableBy,
 		extraPost:     opts.ExtraPost,
 		forceReplicas: opts.ForceReplicas,
+		retries:       opts.Retries,
 	}
 }
 
@@ -39,6 +41,7 @@ type CreateResourceOperationOptions struct {
 	ManageableBy  resource.ManageableBy
 	ForceReplicas *int
 	ExtraPost     bool
+	Retries       *int
 }
 
 type CreateResourceOperation struct {
@@ -48,22 +51,25 @@ type CreateResourceOperation struct {
 	manageableBy  resource.ManageableBy
 	forceReplicas *int
 	extraPost     bool
+	retries       *int
 	status        Status
 }
 
 func (o *CreateResourceOperation) Execute(ctx context.Context) error {
+	o.status = StatusUnknown
+
 	if _, err := o.kubeClient.Create(ctx, o.resource, o.unstruct, kube.KubeClientCreateOptions{
 		ForceReplicas: o.forceReplicas,
 	}); err != nil {
 		if errors.IsAlreadyExists(err) {
 			if _, err := o.kubeClient.Apply(ctx, o.resource, o.unstruct, kube.KubeClientApplyOptions{}); err != nil {
 				o.status = StatusFailed
-				return fmt.Errorf("error applying resource: %w", err)
+				return fmt.Errorf("error applying resource: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
 			}
 		}
 
 		o.status = StatusFailed
-		return fmt.Errorf("error creating resource: %w", err)
+		return fmt.Errorf("error creating resource: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
 	}
 
 	o.status = StatusCompleted
@@ -71,6 +77,14 @@ func (o *CreateResourceOperation) Execute(ctx context.Context) error {
 	return nil
 }
 
+func (o *CreateResourceOperation) Retries() (retries int, set bool) {
+	if o.retries == nil {
+		return 0, false
+	}
+
+	return *o.retries, true
+}
+
 func (o *CreateResourceOperation) ID() string {
 	if o.extraPost {
 		return TypeExtraPostCreateResourceOperation + "/" + o.resource.ID()
@@ -79,8 +93,16 @@ func (o *CreateResourceOperation) ID() string {
 	return TypeCreateResourceOperation + "/" + o.resource.ID()
 }
 
+func (o *CreateResourceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *CreateResourceOperation) HumanID() string {
-	return "create resource: " + o.resource.HumanID()
+	return "create resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+}
+
+func (o *CreateResourceOperation) ManifestHash() string {
+	return resource.ManifestHash(o.unstruct)
 }
 
 func (o *CreateResourceOperation) Status() Status {
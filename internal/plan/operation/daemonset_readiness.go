@@ -0,0 +1,234 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	daemonSetReadinessPollPeriod = 3 * time.Second
+
+	nodeExclusionReasonCordoned = "cordoned"
+	nodeExclusionReasonNotReady = "not ready"
+)
+
+var daemonSetGroupKind = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+
+// Kubedog considers a DaemonSet ready only when pods on all nodes are ready, which never happens
+// if some nodes are cordoned or broken. Along with kubedog, evaluate readiness ourselves, taking
+// into account unavailable nodes and maxUnavailable of the update strategy, and stop tracking as
+// soon as either of us considers the DaemonSet ready.
+func (o *TrackResourceReadinessOperation) trackDaemonSet(ctx context.Context, tracker *dyntracker.DynamicReadinessTracker) error {
+	trackCtx, trackCtxCancelFn := context.WithCancel(ctx)
+	defer trackCtxCancelFn()
+
+	trackErrCh := make(chan error, 1)
+	go func() {
+		trackErrCh <- tracker.Track(trackCtx)
+	}()
+
+	ticker := time.NewTicker(daemonSetReadinessPollPeriod)
+	defer ticker.Stop()
+
+	var lastExclusionMsg string
+	for {
+		select {
+		case err := <-trackErrCh:
+			return err
+		case <-ticker.C:
+		}
+
+		readiness, err := o.daemonSetReadiness(ctx)
+		if err != nil {
+			log.Track.Debug(ctx, "Unable to evaluate readiness of %s: %s", o.resource.HumanID(), err)
+			continue
+		}
+
+		if msg := readiness.ExclusionMessage(); msg != "" && msg != lastExclusionMsg {
+			lastExclusionMsg = msg
+			log.Track.Info(ctx, "%s: %s", o.resource.HumanID(), msg)
+		}
+
+		if !readiness.Ready {
+			continue
+		}
+
+		o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+			ts.SetStatus(statestore.ReadinessTaskStatusReady)
+		})
+
+		trackCtxCancelFn()
+		<-trackErrCh
+
+		return nil
+	}
+}
+
+func (o *TrackResourceReadinessOperation) daemonSetReadiness(ctx context.Context) (*DaemonSetReadiness, error) {
+	daemonSet, err := o.staticClient.AppsV1().DaemonSets(o.resource.Namespace()).Get(ctx, o.resource.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting daemonset: %w", err)
+	}
+
+	// Listing nodes or pods might be forbidden, then just don't exclude any nodes.
+	var (
+		nodes []corev1.Node
+		pods  []corev1.Pod
+	)
+	if nodeList, err := o.staticClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err != nil {
+		log.Track.Debug(ctx, "Unable to list nodes, no nodes will be excluded from readiness evaluation of %s: %s", o.resource.HumanID(), err)
+	} else if pods, err = o.daemonSetPods(ctx, daemonSet); err != nil {
+		log.Track.Debug(ctx, "Unable to list pods, no nodes will be excluded from readiness evaluation of %s: %s", o.resource.HumanID(), err)
+	} else {
+		nodes = nodeList.Items
+	}
+
+	return EvaluateDaemonSetReadiness(daemonSet, nodes, pods), nil
+}
+
+func (o *TrackResourceReadinessOperation) daemonSetPods(ctx context.Context, daemonSet *appsv1.DaemonSet) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing daemonset selector: %w", err)
+	}
+
+	podList, err := o.staticClient.CoreV1().Pods(daemonSet.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing daemonset pods: %w", err)
+	}
+
+	return podList.Items, nil
+}
+
+type DaemonSetReadiness struct {
+	Ready          bool
+	Desired        int
+	Required       int
+	MaxUnavailable int
+	ExcludedNodes  map[string]int
+}
+
+func (r *DaemonSetReadiness) ExclusionMessage() string {
+	var excluded int
+	var reasons []string
+	for reason, count := range r.ExcludedNodes {
+		excluded += count
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(reasons)
+
+	if excluded == 0 && r.MaxUnavailable == 0 {
+		return ""
+	}
+
+	msg := fmt.Sprintf("%d of %d pods required to be ready", r.Required, r.Desired)
+	if excluded > 0 {
+		msg += fmt.Sprintf(", %d nodes excluded (%s)", excluded, strings.Join(reasons, ", "))
+	}
+	if r.MaxUnavailable > 0 {
+		msg += fmt.Sprintf(", %d unavailable pods tolerated", r.MaxUnavailable)
+	}
+
+	return msg
+}
+
+// EvaluateDaemonSetReadiness considers the DaemonSet ready if enough of its pods are updated and
+// ready. Cordoned and not ready nodes running pods of the DaemonSet are not expected to run ready
+// pods, and up to maxUnavailable of the rolling update strategy pods are allowed to be unavailable.
+// At least one pod is required to be ready, unless the DaemonSet has no nodes to run on.
+//
+// Only nodes with pods of the DaemonSet are excluded, since other nodes, e.g. tainted or not
+// matching the node affinity, don't count towards desiredNumberScheduled in the first place.
+func EvaluateDaemonSetReadiness(daemonSet *appsv1.DaemonSet, nodes []corev1.Node, pods []corev1.Pod) *DaemonSetReadiness {
+	readiness := &DaemonSetReadiness{
+		Desired:       int(daemonSet.Status.DesiredNumberScheduled),
+		ExcludedNodes: map[string]int{},
+	}
+
+	scheduledNodes := map[string]bool{}
+	for _, pod := range pods {
+		if controller := metav1.GetControllerOf(&pod); controller == nil || controller.UID != daemonSet.UID {
+			continue
+		}
+
+		if pod.Spec.NodeName != "" {
+			scheduledNodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	var excluded int
+	for _, node := range nodes {
+		if !scheduledNodes[node.Name] || excluded >= readiness.Desired {
+			continue
+		}
+
+		if node.Spec.Unschedulable {
+			readiness.ExcludedNodes[nodeExclusionReasonCordoned]++
+			excluded++
+		} else if !nodeReady(node) {
+			readiness.ExcludedNodes[nodeExclusionReasonNotReady]++
+			excluded++
+		}
+	}
+
+	target := readiness.Desired - excluded
+	if target < 0 {
+		target = 0
+	}
+
+	readiness.MaxUnavailable = daemonSetMaxUnavailable(daemonSet, readiness.Desired)
+
+	readiness.Required = target - readiness.MaxUnavailable
+	if readiness.Required < 1 && target > 0 {
+		readiness.Required = 1
+	} else if readiness.Required < 0 {
+		readiness.Required = 0
+	}
+
+	readiness.Ready = daemonSet.Status.ObservedGeneration >= daemonSet.Generation &&
+		int(daemonSet.Status.UpdatedNumberScheduled) >= readiness.Required &&
+		int(daemonSet.Status.NumberReady) >= readiness.Required
+
+	return readiness
+}
+
+func daemonSetMaxUnavailable(daemonSet *appsv1.DaemonSet, desired int) int {
+	if daemonSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return 0
+	}
+
+	maxUnavailable := intstr.FromInt32(1)
+	if rollingUpdate := daemonSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.MaxUnavailable != nil {
+		maxUnavailable = *rollingUpdate.MaxUnavailable
+	}
+
+	value, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, desired, true)
+	if err != nil || value < 0 {
+		return 0
+	}
+
+	return value
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,200 @@
+package operation_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/resource"
+)
+
+const daemonSetUID = types.UID("daemonset-uid")
+
+var _ = Describe("daemonset readiness", func() {
+	type entry struct {
+		desired        int32
+		ready          int32
+		maxUnavailable *intstr.IntOrString
+		onDelete       bool
+		nodes          []corev1.Node
+		pods           []corev1.Pod
+
+		expectedReady    bool
+		expectedRequired int
+		expectedExcluded map[string]int
+	}
+
+	DescribeTable("evaluates readiness",
+		func(e entry) {
+			daemonSet := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "app-ns", UID: daemonSetUID},
+				Status: appsv1.DaemonSetStatus{
+					DesiredNumberScheduled: e.desired,
+					NumberReady:            e.ready,
+					UpdatedNumberScheduled: e.ready,
+				},
+			}
+
+			if e.onDelete {
+				daemonSet.Spec.UpdateStrategy.Type = appsv1.OnDeleteDaemonSetStrategyType
+			} else {
+				daemonSet.Spec.UpdateStrategy.Type = appsv1.RollingUpdateDaemonSetStrategyType
+				daemonSet.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateDaemonSet{MaxUnavailable: e.maxUnavailable}
+			}
+
+			readiness := operation.EvaluateDaemonSetReadiness(daemonSet, e.nodes, e.pods)
+
+			Expect(readiness.Ready).To(Equal(e.expectedReady))
+			Expect(readiness.Required).To(Equal(e.expectedRequired))
+			Expect(readiness.ExcludedNodes).To(Equal(orEmpty(e.expectedExcluded)))
+		},
+		Entry("all pods ready", entry{
+			desired: 3, ready: 3, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes: readyNodes(3), pods: daemonSetPods(3),
+			expectedReady: true, expectedRequired: 3,
+		}),
+		Entry("a pod not ready without maxUnavailable", entry{
+			desired: 3, ready: 2, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes: readyNodes(3), pods: daemonSetPods(3),
+			expectedReady: false, expectedRequired: 3,
+		}),
+		Entry("default maxUnavailable of 1", entry{
+			desired: 3, ready: 2,
+			nodes: readyNodes(3), pods: daemonSetPods(3),
+			expectedReady: true, expectedRequired: 2,
+		}),
+		Entry("absolute maxUnavailable", entry{
+			desired: 5, ready: 3, maxUnavailable: ptr.To(intstr.FromInt32(2)),
+			nodes: readyNodes(5), pods: daemonSetPods(5),
+			expectedReady: true, expectedRequired: 3,
+		}),
+		Entry("percentage maxUnavailable rounds up", entry{
+			desired: 10, ready: 7, maxUnavailable: ptr.To(intstr.FromString("25%")),
+			nodes: readyNodes(10), pods: daemonSetPods(10),
+			expectedReady: true, expectedRequired: 7,
+		}),
+		Entry("percentage maxUnavailable not satisfied", entry{
+			desired: 10, ready: 6, maxUnavailable: ptr.To(intstr.FromString("25%")),
+			nodes: readyNodes(10), pods: daemonSetPods(10),
+			expectedReady: false, expectedRequired: 7,
+		}),
+		Entry("maxUnavailable ignored with OnDelete strategy", entry{
+			desired: 3, ready: 2, onDelete: true,
+			nodes: readyNodes(3), pods: daemonSetPods(3),
+			expectedReady: false, expectedRequired: 3,
+		}),
+		Entry("cordoned node running a pod is tolerated", entry{
+			desired: 3, ready: 2, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes: append(readyNodes(2), cordonedNode("node-2")), pods: daemonSetPods(3),
+			expectedReady: true, expectedRequired: 2, expectedExcluded: map[string]int{"cordoned": 1},
+		}),
+		Entry("not ready node running a pod is tolerated", entry{
+			desired: 3, ready: 2, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes: append(readyNodes(2), notReadyNode("node-2")), pods: daemonSetPods(3),
+			expectedReady: true, expectedRequired: 2, expectedExcluded: map[string]int{"not ready": 1},
+		}),
+		Entry("cordoned nodes without pods of the daemonset aren't excluded", entry{
+			desired: 2, ready: 1, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes:         append(readyNodes(2), cordonedNode("node-2"), cordonedNode("node-3")),
+			pods:          daemonSetPods(2),
+			expectedReady: false, expectedRequired: 2,
+		}),
+		Entry("nodes with pods of other owners aren't excluded", entry{
+			desired: 1, ready: 0, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes:         []corev1.Node{cordonedNode("node-0"), cordonedNode("node-1")},
+			pods:          []corev1.Pod{daemonSetPod("node-0"), foreignPod("node-1")},
+			expectedReady: true, expectedRequired: 0, expectedExcluded: map[string]int{"cordoned": 1},
+		}),
+		Entry("a single scheduled pod on many unavailable nodes is still required", entry{
+			desired: 2, ready: 0, maxUnavailable: ptr.To(intstr.FromInt32(0)),
+			nodes:         append(readyNodes(1), cordonedNode("node-1"), cordonedNode("node-2"), cordonedNode("node-3")),
+			pods:          daemonSetPods(2),
+			expectedReady: false, expectedRequired: 1, expectedExcluded: map[string]int{"cordoned": 1},
+		}),
+		Entry("no nodes to run on", entry{
+			desired: 0, ready: 0,
+			nodes:         []corev1.Node{cordonedNode("node-0")},
+			expectedReady: true, expectedRequired: 0,
+		}),
+	)
+
+	It("is strict if requested by the annotation", func() {
+		res := resource.NewGeneralResource(unstructFromYAML(`{apiVersion: apps/v1, kind: DaemonSet, metadata: {name: agent, namespace: app-ns, annotations: {werf.io/strict-readiness: "true"}}}`), resource.GeneralResourceOptions{})
+		Expect(res.StrictReadiness()).To(BeTrue())
+
+		res = resource.NewGeneralResource(unstructFromYAML(`{apiVersion: apps/v1, kind: DaemonSet, metadata: {name: agent, namespace: app-ns}}`), resource.GeneralResourceOptions{})
+		Expect(res.StrictReadiness()).To(BeFalse())
+	})
+})
+
+func orEmpty(m map[string]int) map[string]int {
+	if m == nil {
+		return map[string]int{}
+	}
+
+	return m
+}
+
+func readyNodes(count int) []corev1.Node {
+	var nodes []corev1.Node
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return nodes
+}
+
+func cordonedNode(name string) corev1.Node {
+	node := readyNodes(1)[0]
+	node.Name = name
+	node.Spec.Unschedulable = true
+
+	return node
+}
+
+func notReadyNode(name string) corev1.Node {
+	node := readyNodes(1)[0]
+	node.Name = name
+	node.Status.Conditions[0].Status = corev1.ConditionFalse
+
+	return node
+}
+
+func daemonSetPods(count int) []corev1.Pod {
+	var pods []corev1.Pod
+	for i := 0; i < count; i++ {
+		pods = append(pods, daemonSetPod(fmt.Sprintf("node-%d", i)))
+	}
+
+	return pods
+}
+
+func daemonSetPod(nodeName string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "agent-" + nodeName,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", UID: daemonSetUID, Controller: ptr.To(true)}},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func foreignPod(nodeName string) corev1.Pod {
+	pod := daemonSetPod(nodeName)
+	pod.OwnerReferences[0].UID = "other-uid"
+
+	return pod
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/werf/nelm/internal/resource/id"
 )
 
-var _ Operation = (*DeleteResourceOperation)(nil)
+var _ RetriableOperation = (*DeleteResourceOperation)(nil)
 
 const (
 	TypeDeleteResourceOperation          = "delete"
@@ -24,21 +24,26 @@ func NewDeleteResourceOperation(
 		resource:   resource,
 		kubeClient: kubeClient,
 		extraPost:  opts.ExtraPost,
+		retries:    opts.Retries,
 	}
 }
 
 type DeleteResourceOperationOptions struct {
 	ExtraPost bool
+	Retries   *int
 }
 
 type DeleteResourceOperation struct {
 	resource   *id.ResourceID
 	kubeClient kube.KubeClienter
 	extraPost  bool
+	retries    *int
 	status     Status
 }
 
 func (o *DeleteResourceOperation) Execute(ctx context.Context) error {
+	o.status = StatusUnknown
+
 	if err := o.kubeClient.Delete(ctx, o.resource, kube.KubeClientDeleteOptions{}); err != nil {
 		o.status = StatusFailed
 		return fmt.Errorf("error deleting resource: %w", err)
@@ -49,6 +54,14 @@ func (o *DeleteResourceOperation) Execute(ctx context.Context) error {
 	return nil
 }
 
+func (o *DeleteResourceOperation) Retries() (retries int, set bool) {
+	if o.retries == nil {
+		return 0, false
+	}
+
+	return *o.retries, true
+}
+
 func (o *DeleteResourceOperation) ID() string {
 	if o.extraPost {
 		return TypeExtraPostDeleteResourceOperation + "/" + o.resource.ID()
@@ -57,6 +70,10 @@ func (o *DeleteResourceOperation) ID() string {
 	return TypeDeleteResourceOperation + "/" + o.resource.ID()
 }
 
+func (o *DeleteResourceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *DeleteResourceOperation) HumanID() string {
 	return "delete resource: " + o.resource.HumanID()
 }
@@ -0,0 +1,224 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
+)
+
+var _ TrackOperation = (*ExecJobOperation)(nil)
+
+const (
+	TypeExecJobOperation = "exec-job"
+
+	execJobFailureLogsTailLines = 50
+)
+
+func NewExecJobOperation(
+	resource *id.ResourceID,
+	unstruct *unstructured.Unstructured,
+	absenceTaskState *util.Concurrent[*statestore.AbsenceTaskState],
+	readinessTaskState *util.Concurrent[*statestore.ReadinessTaskState],
+	logStore *util.Concurrent[*logstore.LogStore],
+	kubeClient kube.KubeClienter,
+	staticClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	discoveryClient discovery.CachedDiscoveryInterface,
+	mapper meta.ResettableRESTMapper,
+	opts ExecJobOperationOptions,
+) *ExecJobOperation {
+	return &ExecJobOperation{
+		resource:         resource,
+		unstruct:         unstruct,
+		absenceTaskState: absenceTaskState,
+		kubeClient:       kubeClient,
+		dynamicClient:    dynamicClient,
+		staticClient:     staticClient,
+		mapper:           mapper,
+		trackOp: NewTrackResourceReadinessOperation(
+			resource,
+			readinessTaskState,
+			logStore,
+			staticClient,
+			dynamicClient,
+			discoveryClient,
+			mapper,
+			TrackResourceReadinessOperationOptions{
+				Timeout:           opts.Timeout,
+				NoActivityTimeout: opts.NoActivityTimeout,
+				SaveEvents:        opts.SaveEvents,
+			},
+		),
+		manageableBy:         opts.ManageableBy,
+		deletionTrackTimeout: opts.DeletionTrackTimeout,
+		deleteOnSucceeded:    opts.DeleteOnSucceeded,
+		deleteOnFailed:       opts.DeleteOnFailed,
+	}
+}
+
+type ExecJobOperationOptions struct {
+	ManageableBy         resource.ManageableBy
+	Timeout              time.Duration
+	NoActivityTimeout    time.Duration
+	DeletionTrackTimeout time.Duration
+	SaveEvents           bool
+	DeleteOnSucceeded    bool
+	DeleteOnFailed       bool
+}
+
+// ExecJobOperation runs the Job to completion: deletes the Job left from the previous run, creates
+// it, waits for it to complete while its logs are being printed, then deletes it according to the
+// delete policy.
+type ExecJobOperation struct {
+	resource             *id.ResourceID
+	unstruct             *unstructured.Unstructured
+	absenceTaskState     *util.Concurrent[*statestore.AbsenceTaskState]
+	kubeClient           kube.KubeClienter
+	staticClient         kubernetes.Interface
+	dynamicClient        dynamic.Interface
+	mapper               meta.ResettableRESTMapper
+	trackOp              *TrackResourceReadinessOperation
+	manageableBy         resource.ManageableBy
+	deletionTrackTimeout time.Duration
+	deleteOnSucceeded    bool
+	deleteOnFailed       bool
+
+	status Status
+}
+
+func (o *ExecJobOperation) Execute(ctx context.Context) error {
+	o.status = StatusUnknown
+
+	if err := o.delete(ctx); err != nil {
+		o.status = StatusFailed
+		return fmt.Errorf("error deleting job from the previous run: %w", err)
+	}
+
+	if _, err := o.kubeClient.Create(ctx, o.resource, o.unstruct, kube.KubeClientCreateOptions{}); err != nil {
+		o.status = StatusFailed
+		return fmt.Errorf("error creating job: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
+	}
+
+	if trackErr := o.trackOp.Execute(ctx); trackErr != nil {
+		o.status = StatusFailed
+
+		if logs := o.lastPodLogs(ctx); logs != "" {
+			trackErr = fmt.Errorf("%w\n\n%s", trackErr, logs)
+		}
+
+		if o.deleteOnFailed {
+			if err := o.delete(ctx); err != nil {
+				log.Plan.Warn(ctx, "Unable to delete failed %s: %s", o.resource.HumanID(), err)
+			}
+		}
+
+		return fmt.Errorf("error running job: %w", trackErr)
+	}
+
+	if o.deleteOnSucceeded {
+		if err := o.delete(ctx); err != nil {
+			o.status = StatusFailed
+			return fmt.Errorf("error deleting succeeded job: %w", err)
+		}
+	}
+
+	o.status = StatusCompleted
+
+	return nil
+}
+
+func (o *ExecJobOperation) delete(ctx context.Context) error {
+	if err := o.kubeClient.Delete(ctx, o.resource, kube.KubeClientDeleteOptions{}); err != nil {
+		return fmt.Errorf("error deleting resource: %w", err)
+	}
+
+	tracker := dyntracker.NewDynamicAbsenceTracker(o.absenceTaskState, o.dynamicClient, o.mapper, dyntracker.DynamicAbsenceTrackerOptions{
+		Timeout: o.deletionTrackTimeout,
+	})
+
+	if err := tracker.Track(ctx); err != nil {
+		return fmt.Errorf("track resource absence: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the last lines of logs of all containers of the most recent pod of the Job. Errors are
+// not returned, since the logs are only supplementary to the actual error.
+func (o *ExecJobOperation) lastPodLogs(ctx context.Context) string {
+	_, pods, err := o.trackOp.jobWithPods(ctx)
+	if err != nil || len(pods) == 0 {
+		return ""
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+	pod := pods[len(pods)-1]
+
+	var containers []corev1.Container
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	b := &strings.Builder{}
+	for _, container := range containers {
+		logs, err := o.staticClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: container.Name,
+			TailLines: lo.ToPtr(int64(execJobFailureLogsTailLines)),
+		}).DoRaw(ctx)
+		if err != nil || len(strings.TrimSpace(string(logs))) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "Last logs of container %q of pod %q:\n%s\n", container.Name, pod.Name, strings.TrimRight(string(logs), "\n"))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (o *ExecJobOperation) ID() string {
+	return TypeExecJobOperation + "/" + o.resource.ID()
+}
+
+func (o *ExecJobOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
+func (o *ExecJobOperation) HumanID() string {
+	return "exec job: " + o.resource.HumanID()
+}
+
+func (o *ExecJobOperation) Status() Status {
+	return o.status
+}
+
+func (o *ExecJobOperation) Type() Type {
+	return TypeExecJobOperation
+}
+
+func (o *ExecJobOperation) Timeout() time.Duration {
+	return o.trackOp.Timeout()
+}
+
+func (o *ExecJobOperation) Empty() bool {
+	return false
+}
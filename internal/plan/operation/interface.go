@@ -1,6 +1,11 @@
 package operation
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/werf/nelm/internal/resource/id"
+)
 
 type Operation interface {
 	Execute(ctx context.Context) error
@@ -11,6 +16,28 @@ type Operation interface {
 	Empty() bool
 }
 
+// RetriableOperation is an operation which can be executed again if it failed with a transient
+// error. Retries() returns the number of retries requested for this operation specifically, if any.
+type RetriableOperation interface {
+	Operation
+	Retries() (retries int, set bool)
+}
+
+// TrackOperation is an operation which waits for a resource to reach some state. Timeout() returns
+// the effective timeout of waiting, zero means no timeout.
+type TrackOperation interface {
+	Operation
+	Timeout() time.Duration
+}
+
+// DegradableOperation is an operation which doesn't fail if its resource fails, the resource is
+// considered degraded instead. DegradationError() returns nil if the resource is not degraded.
+type DegradableOperation interface {
+	Operation
+	ResourceID() *id.ResourceID
+	DegradationError() error
+}
+
 type Status string
 
 const (
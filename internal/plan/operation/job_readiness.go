@@ -0,0 +1,197 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	jobReadinessPollPeriod = 3 * time.Second
+	// How many polls in a row a container must be stuck with the same non-retriable error before
+	// the Job is considered failed. Some of these errors, e.g. a ConfigMap not created yet, might go
+	// away by themselves.
+	jobPodErrorPollsThreshold = 5
+
+	defaultJobBackoffLimit = 6
+)
+
+var (
+	jobGroupKind = schema.GroupKind{Group: "batch", Kind: "Job"}
+
+	// Reasons of waiting containers which won't go away without changing the Job or the cluster.
+	nonRetriableContainerWaitingReasons = map[string]struct{}{
+		"CreateContainerConfigError": {},
+		"CreateContainerError":       {},
+		"ErrImageNeverPull":          {},
+		"InvalidImageName":           {},
+	}
+)
+
+// Kubedog waits for a failed Job until the timeout, if the Job has no activeDeadlineSeconds. Along
+// with kubedog, evaluate failures of the Job ourselves and stop tracking as soon as the Job can't
+// succeed anymore. Success is still determined by kubedog.
+func (o *TrackResourceReadinessOperation) trackJob(ctx context.Context, tracker *dyntracker.DynamicReadinessTracker) error {
+	trackCtx, trackCtxCancelFn := context.WithCancel(ctx)
+	defer trackCtxCancelFn()
+
+	trackErrCh := make(chan error, 1)
+	go func() {
+		trackErrCh <- tracker.Track(trackCtx)
+	}()
+
+	ticker := time.NewTicker(jobReadinessPollPeriod)
+	defer ticker.Stop()
+
+	var lastPodErrorMsg string
+	var podErrorPolls int
+	for {
+		select {
+		case err := <-trackErrCh:
+			return err
+		case <-ticker.C:
+		}
+
+		job, pods, err := o.jobWithPods(ctx)
+		if err != nil {
+			log.Track.Debug(ctx, "Unable to evaluate failures of %s: %s", o.resource.HumanID(), err)
+			continue
+		}
+
+		failure := EvaluateJobFailure(job, pods)
+		if failure == nil {
+			podErrorPolls = 0
+			continue
+		}
+
+		if !failure.Permanent {
+			if failure.Message == lastPodErrorMsg {
+				podErrorPolls++
+			} else {
+				lastPodErrorMsg = failure.Message
+				podErrorPolls = 1
+			}
+
+			if podErrorPolls < jobPodErrorPollsThreshold {
+				continue
+			}
+		}
+
+		o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+			ts.SetStatus(statestore.ReadinessTaskStatusFailed)
+		})
+
+		trackCtxCancelFn()
+		<-trackErrCh
+
+		return errors.New(failure.Message)
+	}
+}
+
+func (o *TrackResourceReadinessOperation) jobWithPods(ctx context.Context) (*batchv1.Job, []corev1.Pod, error) {
+	job, err := o.staticClient.BatchV1().Jobs(o.resource.Namespace()).Get(ctx, o.resource.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting job: %w", err)
+	}
+
+	if job.Spec.Selector == nil {
+		return job, nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing job selector: %w", err)
+	}
+
+	podList, err := o.staticClient.CoreV1().Pods(o.resource.Namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing job pods: %w", err)
+	}
+
+	return job, podList.Items, nil
+}
+
+type JobFailure struct {
+	Message string
+	// If false, the failure is caused by a pod error, which is permanent only if it doesn't go away
+	// for some time.
+	Permanent bool
+}
+
+// EvaluateJobFailure returns nil if the Job didn't fail and still might succeed. The Job is failed
+// if it has the Failed condition or its backoffLimit is exceeded. Pods of the Job stuck with
+// errors like CreateContainerConfigError are reported as non-permanent failures.
+func EvaluateJobFailure(job *batchv1.Job, pods []corev1.Pod) *JobFailure {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobComplete && cond.Status == corev1.ConditionTrue {
+			return nil
+		}
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type != batchv1.JobFailed || cond.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		msg := "job failed"
+		if cond.Reason != "" {
+			msg += ": " + cond.Reason
+		}
+		if cond.Message != "" {
+			msg += ": " + cond.Message
+		}
+
+		return &JobFailure{Message: msg, Permanent: true}
+	}
+
+	backoffLimit := int32(defaultJobBackoffLimit)
+	if job.Spec.BackoffLimit != nil {
+		backoffLimit = *job.Spec.BackoffLimit
+	}
+
+	if job.Status.Failed > backoffLimit {
+		return &JobFailure{
+			Message:   fmt.Sprintf("job failed: %d pods failed, backoffLimit is %d", job.Status.Failed, backoffLimit),
+			Permanent: true,
+		}
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	for _, pod := range pods {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting == nil {
+				continue
+			}
+
+			if _, nonRetriable := nonRetriableContainerWaitingReasons[status.State.Waiting.Reason]; !nonRetriable {
+				continue
+			}
+
+			msg := fmt.Sprintf("container %q of pod %q can't start: %s", status.Name, pod.Name, status.State.Waiting.Reason)
+			if status.State.Waiting.Message != "" {
+				msg += ": " + status.State.Waiting.Message
+			}
+
+			return &JobFailure{Message: msg}
+		}
+	}
+
+	return nil
+}
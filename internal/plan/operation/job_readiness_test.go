@@ -0,0 +1,123 @@
+package operation_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+var _ = Describe("job failure", func() {
+	type entry struct {
+		backoffLimit *int32
+		failed       int32
+		conditions   []batchv1.JobCondition
+		pods         []corev1.Pod
+
+		expectedFailure *operation.JobFailure
+	}
+
+	DescribeTable("evaluates failure",
+		func(e entry) {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "app-ns"},
+				Spec:       batchv1.JobSpec{BackoffLimit: e.backoffLimit},
+				Status: batchv1.JobStatus{
+					Conditions: e.conditions,
+					Failed:     e.failed,
+				},
+			}
+
+			Expect(operation.EvaluateJobFailure(job, e.pods)).To(Equal(e.expectedFailure))
+		},
+		Entry("running without failed pods", entry{
+			pods: []corev1.Pod{jobPod("migrate-a", nil, runningContainer("main"))},
+		}),
+		Entry("failed pods within backoffLimit", entry{
+			backoffLimit: ptr.To[int32](2), failed: 2,
+		}),
+		Entry("backoffLimit exceeded", entry{
+			backoffLimit: ptr.To[int32](2), failed: 3,
+			expectedFailure: &operation.JobFailure{Message: "job failed: 3 pods failed, backoffLimit is 2", Permanent: true},
+		}),
+		Entry("backoffLimit of 0 exceeded by the first failed pod", entry{
+			backoffLimit: ptr.To[int32](0), failed: 1,
+			expectedFailure: &operation.JobFailure{Message: "job failed: 1 pods failed, backoffLimit is 0", Permanent: true},
+		}),
+		Entry("failed pods within the default backoffLimit", entry{
+			failed: 6,
+		}),
+		Entry("default backoffLimit exceeded", entry{
+			failed:          7,
+			expectedFailure: &operation.JobFailure{Message: "job failed: 7 pods failed, backoffLimit is 6", Permanent: true},
+		}),
+		Entry("failed condition", entry{
+			conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded", Message: "Job was active longer than specified deadline"},
+			},
+			expectedFailure: &operation.JobFailure{Message: "job failed: DeadlineExceeded: Job was active longer than specified deadline", Permanent: true},
+		}),
+		Entry("false failed condition", entry{
+			conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}},
+		}),
+		Entry("completed with failed pods", entry{
+			backoffLimit: ptr.To[int32](0), failed: 1,
+			conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		}),
+		Entry("container with CreateContainerConfigError", entry{
+			pods: []corev1.Pod{
+				jobPod("migrate-a", nil, waitingContainer("main", "CreateContainerConfigError", `configmap "config" not found`)),
+			},
+			expectedFailure: &operation.JobFailure{Message: `container "main" of pod "migrate-a" can't start: CreateContainerConfigError: configmap "config" not found`},
+		}),
+		Entry("init container with CreateContainerConfigError", entry{
+			pods: []corev1.Pod{
+				jobPod("migrate-a", []corev1.ContainerStatus{waitingContainer("init", "CreateContainerConfigError", "")}, waitingContainer("main", "PodInitializing", "")),
+			},
+			expectedFailure: &operation.JobFailure{Message: `container "init" of pod "migrate-a" can't start: CreateContainerConfigError`},
+		}),
+		Entry("first pod by name with a non-retriable error", entry{
+			pods: []corev1.Pod{
+				jobPod("migrate-b", nil, waitingContainer("main", "InvalidImageName", "")),
+				jobPod("migrate-a", nil, waitingContainer("main", "CreateContainerError", "")),
+			},
+			expectedFailure: &operation.JobFailure{Message: `container "main" of pod "migrate-a" can't start: CreateContainerError`},
+		}),
+		Entry("container with a retriable error", entry{
+			pods: []corev1.Pod{jobPod("migrate-a", nil, waitingContainer("main", "ImagePullBackOff", "Back-off pulling image"))},
+		}),
+		Entry("backoffLimit exceeded takes precedence over pod errors", entry{
+			backoffLimit: ptr.To[int32](0), failed: 1,
+			pods:            []corev1.Pod{jobPod("migrate-a", nil, waitingContainer("main", "CreateContainerConfigError", ""))},
+			expectedFailure: &operation.JobFailure{Message: "job failed: 1 pods failed, backoffLimit is 0", Permanent: true},
+		}),
+	)
+})
+
+func jobPod(name string, initContainers []corev1.ContainerStatus, containers ...corev1.ContainerStatus) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app-ns"},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: initContainers,
+			ContainerStatuses:     containers,
+		},
+	}
+}
+
+func runningContainer(name string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func waitingContainer(name, reason, message string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}},
+	}
+}
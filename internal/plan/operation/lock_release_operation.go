@@ -0,0 +1,71 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/lockgate"
+)
+
+var _ Operation = (*LockReleaseOperation)(nil)
+
+const TypeLockReleaseOperation = "lock-release"
+
+type ReleaseLocker interface {
+	LockRelease(ctx context.Context, releaseName string) (lockgate.LockHandle, error)
+}
+
+func NewLockReleaseOperation(releaseName string, locker ReleaseLocker) *LockReleaseOperation {
+	return &LockReleaseOperation{
+		releaseName: releaseName,
+		locker:      locker,
+	}
+}
+
+// LockReleaseOperation locks the release, if it couldn't be locked before the plan was executed,
+// because the release namespace, where the lock is stored, didn't exist yet. The lock is released
+// by the caller.
+type LockReleaseOperation struct {
+	releaseName string
+	locker      ReleaseLocker
+	handle      lockgate.LockHandle
+	status      Status
+}
+
+func (o *LockReleaseOperation) Execute(ctx context.Context) error {
+	handle, err := o.locker.LockRelease(ctx, o.releaseName)
+	if err != nil {
+		o.status = StatusFailed
+		return fmt.Errorf("error locking release %q: %w", o.releaseName, err)
+	}
+
+	o.handle = handle
+	o.status = StatusCompleted
+
+	return nil
+}
+
+// Handle returns the handle of the acquired lock, if the release was locked.
+func (o *LockReleaseOperation) Handle() (handle lockgate.LockHandle, locked bool) {
+	return o.handle, o.status == StatusCompleted
+}
+
+func (o *LockReleaseOperation) ID() string {
+	return TypeLockReleaseOperation + "/" + o.releaseName
+}
+
+func (o *LockReleaseOperation) HumanID() string {
+	return "lock release: " + o.releaseName
+}
+
+func (o *LockReleaseOperation) Status() Status {
+	return o.status
+}
+
+func (o *LockReleaseOperation) Type() Type {
+	return TypeLockReleaseOperation
+}
+
+func (o *LockReleaseOperation) Empty() bool {
+	return false
+}
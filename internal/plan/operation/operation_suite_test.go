@@ -0,0 +1,26 @@
+package operation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestOperation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Operation Suite")
+}
+
+func unstructFromYAML(manifest string) *unstructured.Unstructured {
+	// Decoded like objects from the cluster, with integers as int64.
+	data, err := yaml.YAMLToJSON([]byte(manifest))
+	Expect(err).NotTo(HaveOccurred())
+
+	obj := &unstructured.Unstructured{}
+	Expect(obj.UnmarshalJSON(data)).To(Succeed())
+
+	return obj
+}
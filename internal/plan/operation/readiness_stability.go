@@ -0,0 +1,188 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+const readinessStabilityPollPeriod = 2 * time.Second
+
+var (
+	deploymentGroupKind  = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	statefulSetGroupKind = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	replicaSetGroupKind  = schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	podGroupKind         = schema.GroupKind{Group: "", Kind: "Pod"}
+)
+
+// Resources might become ready and regress right after, e.g. when a container crashes soon after
+// passing its readiness probe once. After the resource is considered ready, keep evaluating its
+// readiness until it stays ready continuously for readyStableFor.
+func (o *TrackResourceReadinessOperation) waitReadinessStable(ctx context.Context, deadline time.Time) error {
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	log.Track.Debug(ctx, "Waiting for %s to stay ready for %s", o.resource.HumanID(), o.readyStableFor)
+
+	stability := NewReadinessStability(o.readyStableFor, time.Now())
+
+	ticker := time.NewTicker(min(readinessStabilityPollPeriod, o.readyStableFor))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("resource didn't stay ready for %s: %w", o.readyStableFor, context.Cause(ctx))
+		case <-ticker.C:
+		}
+
+		ready, reason, err := o.currentReadiness(ctx)
+		if err != nil {
+			log.Track.Debug(ctx, "Unable to evaluate readiness of %s: %s", o.resource.HumanID(), err)
+			continue
+		}
+
+		now := time.Now()
+		if regressed := stability.Observe(now, ready); regressed {
+			log.Track.Warn(ctx, "%s is not ready anymore (%s), waiting for it to stay ready for %s", o.resource.HumanID(), reason, o.readyStableFor)
+		}
+
+		if stability.Stable(now) {
+			o.stabilizationDuration = stability.Elapsed(now)
+			return nil
+		}
+	}
+}
+
+func (o *TrackResourceReadinessOperation) currentReadiness(ctx context.Context) (ready bool, reason string, err error) {
+	gvk := o.resource.GroupVersionKind()
+
+	if gvk.GroupKind() == daemonSetGroupKind && !o.strictReadiness {
+		readiness, err := o.daemonSetReadiness(ctx)
+		if err != nil {
+			return false, "", err
+		}
+
+		return readiness.Ready, fmt.Sprintf("%d of %d pods required to be ready", readiness.Required, readiness.Desired), nil
+	}
+
+	mapping, err := o.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, "", fmt.Errorf("error getting resource mapping: %w", err)
+	}
+
+	var obj *unstructured.Unstructured
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		obj, err = o.dynamicClient.Resource(mapping.Resource).Namespace(o.resource.Namespace()).Get(ctx, o.resource.Name(), metav1.GetOptions{})
+	} else {
+		obj, err = o.dynamicClient.Resource(mapping.Resource).Get(ctx, o.resource.Name(), metav1.GetOptions{})
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("error getting resource: %w", err)
+	}
+
+	ready, reason = EvaluateResourceReadiness(obj)
+
+	return ready, reason, nil
+}
+
+// EvaluateResourceReadiness is a simplified readiness check, used only to detect regressions of
+// resources which already were considered ready. Resources of unknown kinds without the Ready
+// condition are always considered ready.
+func EvaluateResourceReadiness(obj *unstructured.Unstructured) (ready bool, reason string) {
+	if observedGeneration, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found && observedGeneration < obj.GetGeneration() {
+		return false, "the latest changes are not observed yet"
+	}
+
+	switch obj.GroupVersionKind().GroupKind() {
+	case deploymentGroupKind, statefulSetGroupKind, replicaSetGroupKind:
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+
+		return readyReplicas >= replicas, fmt.Sprintf("%d of %d replicas ready", readyReplicas, replicas)
+	case daemonSetGroupKind:
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		numberReady, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+
+		return numberReady >= desired, fmt.Sprintf("%d of %d pods ready", numberReady, desired)
+	case jobGroupKind:
+		return true, ""
+	case podGroupKind:
+		return conditionStatus(obj, "Ready") == "True", "pod is not ready"
+	default:
+		if status := conditionStatus(obj, "Ready"); status != "" {
+			return status == "True", "Ready condition is " + status
+		}
+
+		return true, ""
+	}
+}
+
+func conditionStatus(obj *unstructured.Unstructured, condType string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != condType {
+			continue
+		}
+
+		status, _ := cond["status"].(string)
+
+		return status
+	}
+
+	return ""
+}
+
+func NewReadinessStability(window time.Duration, readyAt time.Time) *ReadinessStability {
+	return &ReadinessStability{
+		window:     window,
+		firstReady: readyAt,
+		readySince: readyAt,
+	}
+}
+
+// ReadinessStability decides whether the resource, which became ready at readyAt, stayed ready for
+// long enough. Times are passed in explicitly, so that it doesn't depend on the real clock.
+type ReadinessStability struct {
+	window     time.Duration
+	firstReady time.Time
+	// Zero if the resource is not ready.
+	readySince time.Time
+}
+
+// Observe records readiness of the resource at the moment now and returns true if the resource
+// regressed from ready to not ready. Any regression restarts the stability window.
+func (s *ReadinessStability) Observe(now time.Time, ready bool) (regressed bool) {
+	switch {
+	case ready && s.readySince.IsZero():
+		s.readySince = now
+	case !ready && !s.readySince.IsZero():
+		s.readySince = time.Time{}
+		return true
+	}
+
+	return false
+}
+
+func (s *ReadinessStability) Stable(now time.Time) bool {
+	return !s.readySince.IsZero() && now.Sub(s.readySince) >= s.window
+}
+
+// Elapsed returns time passed since the resource became ready for the first time.
+func (s *ReadinessStability) Elapsed(now time.Time) time.Duration {
+	return now.Sub(s.firstReady)
+}
@@ -0,0 +1,112 @@
+package operation_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+// fakeClock is advanced by the test only.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Advance(d time.Duration) time.Time {
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+var _ = Describe("readiness stability", func() {
+	var clock *fakeClock
+
+	BeforeEach(func() {
+		clock = &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	})
+
+	It("is stable once the resource stays ready for the window", func() {
+		stability := operation.NewReadinessStability(30*time.Second, clock.now)
+
+		for i := 0; i < 14; i++ {
+			now := clock.Advance(2 * time.Second)
+			Expect(stability.Observe(now, true)).To(BeFalse())
+			Expect(stability.Stable(now)).To(BeFalse(), "stable after %s", stability.Elapsed(now))
+		}
+
+		now := clock.Advance(2 * time.Second)
+		Expect(stability.Observe(now, true)).To(BeFalse())
+		Expect(stability.Stable(now)).To(BeTrue())
+		Expect(stability.Elapsed(now)).To(Equal(30 * time.Second))
+	})
+
+	It("restarts the window when the resource regresses", func() {
+		stability := operation.NewReadinessStability(30*time.Second, clock.now)
+
+		now := clock.Advance(20 * time.Second)
+		Expect(stability.Observe(now, true)).To(BeFalse())
+
+		now = clock.Advance(5 * time.Second)
+		Expect(stability.Observe(now, false)).To(BeTrue())
+		Expect(stability.Stable(now)).To(BeFalse())
+
+		now = clock.Advance(10 * time.Second)
+		Expect(stability.Observe(now, false)).To(BeFalse(), "still not ready is not a new regression")
+		Expect(stability.Stable(now)).To(BeFalse())
+
+		now = clock.Advance(5 * time.Second)
+		Expect(stability.Observe(now, true)).To(BeFalse())
+		Expect(stability.Stable(now)).To(BeFalse())
+
+		now = clock.Advance(29 * time.Second)
+		Expect(stability.Observe(now, true)).To(BeFalse())
+		Expect(stability.Stable(now)).To(BeFalse())
+
+		now = clock.Advance(time.Second)
+		Expect(stability.Observe(now, true)).To(BeFalse())
+		Expect(stability.Stable(now)).To(BeTrue())
+		Expect(stability.Elapsed(now)).To(Equal(70*time.Second), "measured since the resource became ready for the first time")
+	})
+
+	It("is not stable while the resource is not ready, even after the window", func() {
+		stability := operation.NewReadinessStability(10*time.Second, clock.now)
+
+		now := clock.Advance(time.Second)
+		Expect(stability.Observe(now, false)).To(BeTrue())
+
+		now = clock.Advance(time.Minute)
+		Expect(stability.Observe(now, false)).To(BeFalse())
+		Expect(stability.Stable(now)).To(BeFalse())
+	})
+
+	It("is stable right away with a zero window", func() {
+		stability := operation.NewReadinessStability(0, clock.now)
+
+		Expect(stability.Stable(clock.now)).To(BeTrue())
+		Expect(stability.Elapsed(clock.now)).To(BeZero())
+	})
+
+	DescribeTable("evaluates readiness of resources",
+		func(manifest string, expectedReady bool) {
+			ready, _ := operation.EvaluateResourceReadiness(unstructFromYAML(manifest))
+			Expect(ready).To(Equal(expectedReady))
+		},
+		Entry("deployment with all replicas ready",
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, generation: 2}, spec: {replicas: 3}, status: {observedGeneration: 2, readyReplicas: 3}}`, true),
+		Entry("deployment with a replica not ready",
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, generation: 2}, spec: {replicas: 3}, status: {observedGeneration: 2, readyReplicas: 2}}`, false),
+		Entry("deployment with changes not observed yet",
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, generation: 3}, spec: {replicas: 3}, status: {observedGeneration: 2, readyReplicas: 3}}`, false),
+		Entry("statefulset with one replica by default",
+			`{apiVersion: apps/v1, kind: StatefulSet, metadata: {name: db}, status: {readyReplicas: 1}}`, true),
+		Entry("daemonset with a pod not ready",
+			`{apiVersion: apps/v1, kind: DaemonSet, metadata: {name: agent}, status: {desiredNumberScheduled: 3, numberReady: 2}}`, false),
+		Entry("pod not ready",
+			`{apiVersion: v1, kind: Pod, metadata: {name: api}, status: {conditions: [{type: Ready, status: "False"}]}}`, false),
+		Entry("custom resource with the Ready condition",
+			`{apiVersion: example.com/v1, kind: Database, metadata: {name: db}, status: {conditions: [{type: Ready, status: "True"}]}}`, true),
+		Entry("custom resource without conditions",
+			`{apiVersion: example.com/v1, kind: Database, metadata: {name: db}}`, true),
+	)
+})
@@ -0,0 +1,132 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+)
+
+// Expiration of the parent context, e.g. due to the timeout of the whole deploy, is not a track
+// timeout of the resource.
+func (o *TrackResourceReadinessOperation) timedOut(ctx context.Context, startedAt time.Time, err error) bool {
+	if o.timeout == 0 || ctx.Err() != nil {
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || time.Since(startedAt) >= o.timeout
+}
+
+// Replaces the bare "context deadline exceeded" with the last known state of the resources which
+// are still not ready.
+func (o *TrackResourceReadinessOperation) timeoutError(err error) error {
+	var notReady []string
+	o.taskState.RTransaction(func(ts *statestore.ReadinessTaskState) {
+		readyPods, totalPods := 0, 0
+		for _, crs := range ts.ResourceStates() {
+			crs.RTransaction(func(rs *statestore.ResourceState) {
+				if rs.GroupVersionKind().GroupKind() == (schema.GroupKind{Kind: "Pod"}) {
+					totalPods++
+					if rs.Status() == statestore.ResourceStatusReady {
+						readyPods++
+					}
+				}
+			})
+		}
+
+		for _, crs := range ts.ResourceStates() {
+			crs.RTransaction(func(rs *statestore.ResourceState) {
+				if rs.Status() == statestore.ResourceStatusReady {
+					return
+				}
+
+				var details []string
+
+				isRoot := rs.Name() == ts.Name() && rs.Namespace() == ts.Namespace() && rs.GroupVersionKind() == ts.GroupVersionKind()
+				if isRoot && totalPods > 0 {
+					if requiredPods, found := requiredReplicas(rs); found {
+						details = append(details, fmt.Sprintf("ready replicas %d/%d", readyPods, requiredPods))
+					}
+				}
+
+				if msg := lastWarning(rs); msg != "" {
+					details = append(details, "last warning: "+msg)
+				}
+
+				human := fmt.Sprintf("%s/%s", rs.GroupVersionKind().Kind, rs.Name())
+				if len(details) > 0 {
+					human += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+				}
+
+				notReady = append(notReady, human)
+			})
+		}
+	})
+
+	if len(notReady) == 0 {
+		return &TrackTimeoutError{
+			msg: fmt.Sprintf("timed out after %s: %s", o.timeout, err),
+			err: err,
+		}
+	}
+
+	return &TrackTimeoutError{
+		msg: fmt.Sprintf("timed out after %s, still not ready: %s: %s", o.timeout, strings.Join(notReady, "; "), err),
+		err: err,
+	}
+}
+
+// TrackTimeoutError means the resource didn't become ready within its track timeout.
+type TrackTimeoutError struct {
+	msg string
+	err error
+}
+
+func (e *TrackTimeoutError) Error() string {
+	return e.msg
+}
+
+func (e *TrackTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// The last error of the resource, or the last event if there are no errors.
+func lastWarning(rs *statestore.ResourceState) string {
+	var (
+		msg     string
+		msgTime time.Time
+	)
+
+	for _, errs := range rs.Errors() {
+		for _, err := range errs {
+			if !err.Time.Before(msgTime) {
+				msg, msgTime = err.Err.Error(), err.Time
+			}
+		}
+	}
+
+	if msg == "" {
+		for _, event := range rs.Events() {
+			if !event.Time.Before(msgTime) {
+				msg, msgTime = event.Message, event.Time
+			}
+		}
+	}
+
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+func requiredReplicas(rs *statestore.ResourceState) (int, bool) {
+	for _, attr := range rs.Attributes() {
+		if attr.Name() == statestore.AttributeNameRequiredReplicas {
+			return attr.(*statestore.Attribute[int]).Value, true
+		}
+	}
+
+	return 0, false
+}
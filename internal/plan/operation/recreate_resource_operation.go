@@ -3,9 +3,12 @@ package operation
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/dynamic"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var _ Operation = (*RecreateResourceOperation)(nil)
@@ -92,7 +96,7 @@ func (o *RecreateResourceOperation) Execute(ctx context.Context) error {
 		ForceReplicas: o.forceReplicas,
 	}); err != nil {
 		o.status = StatusFailed
-		return fmt.Errorf("error creating resource: %w", err)
+		return fmt.Errorf("error creating resource: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
 	}
 
 	o.status = StatusCompleted
@@ -108,8 +112,16 @@ func (o *RecreateResourceOperation) ID() string {
 	return TypeRecreateResourceOperation + "/" + o.resource.ID()
 }
 
+func (o *RecreateResourceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *RecreateResourceOperation) HumanID() string {
-	return "recreate resource: " + o.resource.HumanID()
+	return "recreate resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+}
+
+func (o *RecreateResourceOperation) ManifestHash() string {
+	return resource.ManifestHash(o.unstruct)
 }
 
 func (o *RecreateResourceOperation) Status() Status {
@@ -127,3 +139,7 @@ func (o *RecreateResourceOperation) Type() Type {
 func (o *RecreateResourceOperation) Empty() bool {
 	return false
 }
+
+func isImmutableErr(err error) bool {
+	return err != nil && errors.IsInvalid(err) && strings.Contains(err.Error(), validation.FieldImmutableErrorMsg)
+}
@@ -0,0 +1,89 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var _ Operation = (*SleepOperation)(nil)
+
+const (
+	TypeSleepOperation          = "sleep"
+	TypeExtraPostSleepOperation = "extra-post-sleep"
+)
+
+func NewSleepOperation(name string, duration time.Duration, opts SleepOperationOptions) *SleepOperation {
+	humanName := opts.HumanName
+	if humanName == "" {
+		humanName = name
+	}
+
+	return &SleepOperation{
+		name:      name,
+		humanName: humanName,
+		duration:  duration,
+		extraPost: opts.ExtraPost,
+	}
+}
+
+type SleepOperationOptions struct {
+	HumanName string
+	ExtraPost bool
+}
+
+// SleepOperation just waits, e.g. to give an external controller, which has no status to track,
+// time to process a resource before the dependent resources are deployed.
+type SleepOperation struct {
+	name      string
+	humanName string
+	duration  time.Duration
+	extraPost bool
+	status    Status
+}
+
+func (o *SleepOperation) Execute(ctx context.Context) error {
+	o.status = StatusUnknown
+
+	timer := time.NewTimer(o.duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		o.status = StatusFailed
+		return fmt.Errorf("sleep interrupted: %w", context.Cause(ctx))
+	}
+
+	o.status = StatusCompleted
+
+	return nil
+}
+
+func (o *SleepOperation) ID() string {
+	if o.extraPost {
+		return TypeExtraPostSleepOperation + "/" + o.name
+	}
+
+	return TypeSleepOperation + "/" + o.name
+}
+
+func (o *SleepOperation) HumanID() string {
+	return fmt.Sprintf("sleep for %s: %s", o.duration, o.humanName)
+}
+
+func (o *SleepOperation) Status() Status {
+	return o.status
+}
+
+func (o *SleepOperation) Type() Type {
+	if o.extraPost {
+		return TypeExtraPostSleepOperation
+	}
+
+	return TypeSleepOperation
+}
+
+func (o *SleepOperation) Empty() bool {
+	return false
+}
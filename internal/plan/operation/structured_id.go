@@ -0,0 +1,99 @@
+package operation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+// StructuredID is the stable machine-readable identity of an operation. Its String() form is the
+// operation ID:
+//
+//   - "<type>/<namespace>:<group>:<kind>:<name>" for operations on resources,
+//   - "<type>/<namespace>:<name>:<qualifier>" for operations on releases, where the qualifier is
+//     the release revision,
+//   - "<type>/<qualifier>" for stage operations, where the qualifier is the stage name.
+//
+// Resource version is not a part of the identity, so changing API versions of resources doesn't
+// change IDs of their operations.
+type StructuredID struct {
+	Type      Type   `json:"type"`
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Qualifier string `json:"qualifier,omitempty"`
+}
+
+// NewStructuredID is the only place where structured IDs of operations are constructed, so that
+// the plan, progress events and reports always agree on them.
+func NewStructuredID(op Operation) StructuredID {
+	if resOp, ok := op.(interface{ ResourceID() *id.ResourceID }); ok {
+		resID := resOp.ResourceID()
+
+		return StructuredID{
+			Type:      op.Type(),
+			Group:     resID.GroupVersionKind().Group,
+			Kind:      resID.GroupVersionKind().Kind,
+			Namespace: resID.Namespace(),
+			Name:      resID.Name(),
+		}
+	}
+
+	structuredID, err := ParseStructuredID(op.ID())
+	if err != nil {
+		return StructuredID{
+			Type:      op.Type(),
+			Qualifier: op.ID(),
+		}
+	}
+
+	return structuredID
+}
+
+// ParseStructuredID parses an operation ID in its string form.
+func ParseStructuredID(opID string) (StructuredID, error) {
+	opType, rest, found := strings.Cut(opID, "/")
+	if !found || opType == "" || rest == "" {
+		return StructuredID{}, fmt.Errorf("invalid operation ID %q: expected \"<type>/<...>\"", opID)
+	}
+
+	if opType == TypeStageOperation {
+		return StructuredID{
+			Type:      Type(opType),
+			Qualifier: rest,
+		}, nil
+	}
+
+	switch parts := strings.Split(rest, ":"); len(parts) {
+	case 4:
+		return StructuredID{
+			Type:      Type(opType),
+			Namespace: parts[0],
+			Group:     parts[1],
+			Kind:      parts[2],
+			Name:      parts[3],
+		}, nil
+	case 3:
+		return StructuredID{
+			Type:      Type(opType),
+			Namespace: parts[0],
+			Name:      parts[1],
+			Qualifier: parts[2],
+		}, nil
+	default:
+		return StructuredID{}, fmt.Errorf("invalid operation ID %q: unexpected number of \":\"-separated parts", opID)
+	}
+}
+
+func (i StructuredID) String() string {
+	switch {
+	case i.Kind != "":
+		return fmt.Sprintf("%s/%s:%s:%s:%s", i.Type, i.Namespace, i.Group, i.Kind, i.Name)
+	case i.Name != "":
+		return fmt.Sprintf("%s/%s:%s:%s", i.Type, i.Namespace, i.Name, i.Qualifier)
+	default:
+		return fmt.Sprintf("%s/%s", i.Type, i.Qualifier)
+	}
+}
@@ -3,6 +3,8 @@ package operation
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/werf/nelm/internal/release"
 )
@@ -14,20 +16,42 @@ const TypeSucceedReleaseOperation = "succeed-release"
 func NewSucceedReleaseOperation(
 	rel *release.Release,
 	history release.Historier,
+	opts SucceedReleaseOperationOptions,
 ) *SucceedReleaseOperation {
 	return &SucceedReleaseOperation{
-		release: rel,
-		history: history,
+		release:       rel,
+		history:       history,
+		degradableOps: opts.DegradableOperations,
 	}
 }
 
+type SucceedReleaseOperationOptions struct {
+	// Degraded resources of these operations are recorded in the release info annotations.
+	DegradableOperations []DegradableOperation
+}
+
 type SucceedReleaseOperation struct {
-	release *release.Release
-	history release.Historier
-	status  Status
+	release       *release.Release
+	history       release.Historier
+	degradableOps []DegradableOperation
+	status        Status
 }
 
 func (o *SucceedReleaseOperation) Execute(ctx context.Context) error {
+	var degradedResources []string
+	for _, op := range o.degradableOps {
+		if op.DegradationError() != nil {
+			degradedResources = append(degradedResources, op.ResourceID().HumanID())
+		}
+	}
+
+	if len(degradedResources) > 0 {
+		sort.Strings(degradedResources)
+		o.release.SetInfoAnnotations(map[string]string{
+			release.DegradedResourcesInfoAnnotation: strings.Join(degradedResources, ","),
+		})
+	}
+
 	o.release.Succeed()
 
 	if err := o.history.UpdateRelease(ctx, o.release); err != nil {
@@ -0,0 +1,149 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var _ TrackOperation = (*TrackCRDEstablishedOperation)(nil)
+
+const (
+	TypeTrackCRDEstablishedOperation = "track-crd-established"
+
+	defaultTrackCRDEstablishedPollPeriod = time.Second
+)
+
+func NewTrackCRDEstablishedOperation(
+	resource *id.ResourceID,
+	kubeClient kube.KubeClienter,
+	opts TrackCRDEstablishedOperationOptions,
+) *TrackCRDEstablishedOperation {
+	pollPeriod := opts.PollPeriod
+	if pollPeriod == 0 {
+		pollPeriod = defaultTrackCRDEstablishedPollPeriod
+	}
+
+	return &TrackCRDEstablishedOperation{
+		resource:   resource,
+		kubeClient: kubeClient,
+		timeout:    opts.Timeout,
+		pollPeriod: pollPeriod,
+	}
+}
+
+type TrackCRDEstablishedOperationOptions struct {
+	Timeout    time.Duration
+	PollPeriod time.Duration
+}
+
+// TrackCRDEstablishedOperation waits for the Established condition of the CRD, after which its
+// custom resources can be created. The generic readiness tracking doesn't know about this
+// condition.
+type TrackCRDEstablishedOperation struct {
+	resource   *id.ResourceID
+	kubeClient kube.KubeClienter
+	timeout    time.Duration
+	pollPeriod time.Duration
+
+	status Status
+}
+
+func (o *TrackCRDEstablishedOperation) Execute(ctx context.Context) error {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	var lastReason string
+	if err := wait.PollUntilContextCancel(ctx, o.pollPeriod, true, func(ctx context.Context) (bool, error) {
+		obj, err := o.kubeClient.Get(ctx, o.resource, kube.KubeClientGetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("error getting CRD: %w", err)
+		}
+
+		established, reason, err := crdEstablished(obj)
+		lastReason = reason
+
+		return established, err
+	}); err != nil {
+		o.status = StatusFailed
+
+		if lastReason != "" {
+			return fmt.Errorf("track CRD established condition: %w (%s)", err, lastReason)
+		}
+
+		return fmt.Errorf("track CRD established condition: %w", err)
+	}
+
+	o.status = StatusCompleted
+
+	return nil
+}
+
+// Returns an error if the CRD will never be established, e.g. if its names conflict with another
+// CRD.
+func crdEstablished(obj *unstructured.Unstructured) (established bool, reason string, err error) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		condType, _ := condition["type"].(string)
+		condStatus, _ := condition["status"].(string)
+		condMessage, _ := condition["message"].(string)
+
+		switch condType {
+		case "Established":
+			if condStatus == "True" {
+				return true, "", nil
+			}
+
+			reason = condMessage
+		case "NamesAccepted":
+			if condStatus == "False" {
+				return false, condMessage, fmt.Errorf("CRD names not accepted: %s", condMessage)
+			}
+		}
+	}
+
+	return false, reason, nil
+}
+
+func (o *TrackCRDEstablishedOperation) ID() string {
+	return TypeTrackCRDEstablishedOperation + "/" + o.resource.ID()
+}
+
+func (o *TrackCRDEstablishedOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
+func (o *TrackCRDEstablishedOperation) HumanID() string {
+	return "track CRD established: " + o.resource.HumanID()
+}
+
+func (o *TrackCRDEstablishedOperation) Status() Status {
+	return o.status
+}
+
+func (o *TrackCRDEstablishedOperation) Type() Type {
+	return TypeTrackCRDEstablishedOperation
+}
+
+func (o *TrackCRDEstablishedOperation) Timeout() time.Duration {
+	return o.timeout
+}
+
+func (o *TrackCRDEstablishedOperation) Empty() bool {
+	return false
+}
@@ -14,7 +14,7 @@ import (
 	"github.com/werf/nelm/internal/resource/id"
 )
 
-var _ Operation = (*TrackResourceAbsenceOperation)(nil)
+var _ TrackOperation = (*TrackResourceAbsenceOperation)(nil)
 
 const TypeTrackResourceAbsenceOperation = "track-resource-absence"
 
@@ -70,6 +70,10 @@ func (o *TrackResourceAbsenceOperation) ID() string {
 	return TypeTrackResourceAbsenceOperation + "/" + o.resource.ID()
 }
 
+func (o *TrackResourceAbsenceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourceAbsenceOperation) HumanID() string {
 	return "track resource absence: " + o.resource.HumanID()
 }
@@ -82,6 +86,10 @@ func (o *TrackResourceAbsenceOperation) Type() Type {
 	return TypeTrackResourceAbsenceOperation
 }
 
+func (o *TrackResourceAbsenceOperation) Timeout() time.Duration {
+	return o.timeout
+}
+
 func (o *TrackResourceAbsenceOperation) Empty() bool {
 	return false
 }
@@ -14,7 +14,7 @@ import (
 	"github.com/werf/nelm/internal/resource/id"
 )
 
-var _ Operation = (*TrackResourcePresenceOperation)(nil)
+var _ TrackOperation = (*TrackResourcePresenceOperation)(nil)
 
 const TypeTrackResourcePresenceOperation = "track-resource-presence"
 
@@ -26,27 +26,31 @@ func NewTrackResourcePresenceOperation(
 	opts TrackResourcePresenceOperationOptions,
 ) *TrackResourcePresenceOperation {
 	return &TrackResourcePresenceOperation{
-		resource:      resource,
-		taskState:     taskState,
-		dynamicClient: dynamicClient,
-		mapper:        mapper,
-		timeout:       opts.Timeout,
-		pollPeriod:    opts.PollPeriod,
+		resource:           resource,
+		taskState:          taskState,
+		dynamicClient:      dynamicClient,
+		mapper:             mapper,
+		timeout:            opts.Timeout,
+		pollPeriod:         opts.PollPeriod,
+		externalDependency: opts.ExternalDependency,
 	}
 }
 
 type TrackResourcePresenceOperationOptions struct {
 	Timeout    time.Duration
 	PollPeriod time.Duration
+	// The resource is not managed by the release, but something in the release depends on it.
+	ExternalDependency bool
 }
 
 type TrackResourcePresenceOperation struct {
-	resource      *id.ResourceID
-	taskState     *util.Concurrent[*statestore.PresenceTaskState]
-	dynamicClient dynamic.Interface
-	mapper        meta.ResettableRESTMapper
-	timeout       time.Duration
-	pollPeriod    time.Duration
+	resource           *id.ResourceID
+	taskState          *util.Concurrent[*statestore.PresenceTaskState]
+	dynamicClient      dynamic.Interface
+	mapper             meta.ResettableRESTMapper
+	timeout            time.Duration
+	pollPeriod         time.Duration
+	externalDependency bool
 
 	status Status
 }
@@ -57,8 +61,19 @@ func (o *TrackResourcePresenceOperation) Execute(ctx context.Context) error {
 		PollPeriod: o.pollPeriod,
 	})
 
+	startedAt := time.Now()
+
 	if err := tracker.Track(ctx); err != nil {
 		o.status = StatusFailed
+
+		if o.externalDependency {
+			if o.timeout > 0 && time.Since(startedAt) >= o.timeout {
+				return fmt.Errorf("external dependency %q didn't appear in %s: %w", o.resource.HumanID(), o.timeout, err)
+			}
+
+			return fmt.Errorf("wait for external dependency %q to appear: %w", o.resource.HumanID(), err)
+		}
+
 		return fmt.Errorf("track resource presence: %w", err)
 	}
 
@@ -70,6 +85,10 @@ func (o *TrackResourcePresenceOperation) ID() string {
 	return TypeTrackResourcePresenceOperation + "/" + o.resource.ID()
 }
 
+func (o *TrackResourcePresenceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourcePresenceOperation) HumanID() string {
 	return "track resource presence: " + o.resource.HumanID()
 }
@@ -82,6 +101,10 @@ func (o *TrackResourcePresenceOperation) Type() Type {
 	return TypeTrackResourcePresenceOperation
 }
 
+func (o *TrackResourcePresenceOperation) Timeout() time.Duration {
+	return o.timeout
+}
+
 func (o *TrackResourcePresenceOperation) Empty() bool {
 	return false
 }
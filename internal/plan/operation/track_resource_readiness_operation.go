@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -15,10 +16,16 @@ import (
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/track"
+	"github.com/werf/nelm/pkg/log"
 )
 
-var _ Operation = (*TrackResourceReadinessOperation)(nil)
+var (
+	_ TrackOperation      = (*TrackResourceReadinessOperation)(nil)
+	_ DegradableOperation = (*TrackResourceReadinessOperation)(nil)
+)
 
 const TypeTrackResourceReadinessOperation = "track-resource-readiness"
 
@@ -50,6 +57,13 @@ func NewTrackResourceReadinessOperation(
 		ignoreLogs:                               opts.IgnoreLogs,
 		ignoreLogsForContainers:                  opts.IgnoreLogsForContainers,
 		saveEvents:                               opts.SaveEvents,
+		strictReadiness:                          opts.StrictReadiness,
+		readyStableFor:                           opts.ReadyStableFor,
+		readyConditions:                          opts.ReadyConditions,
+		failureConditions:                        opts.FailureConditions,
+		nonFatal:                                 opts.NonFatal,
+		externalDependency:                       opts.ExternalDependency,
+		logFollower:                              opts.LogFollower,
 	}
 }
 
@@ -64,6 +78,18 @@ type TrackResourceReadinessOperationOptions struct {
 	IgnoreLogs                               bool
 	IgnoreLogsForContainers                  []string
 	SaveEvents                               bool
+	StrictReadiness                          bool
+	ReadyStableFor                           time.Duration
+	// If set, readiness is determined only by these conditions instead of kubedog.
+	ReadyConditions   []*resource.TrackCondition
+	FailureConditions []*resource.TrackCondition
+	// If set, the operation doesn't fail if the resource fails to become ready, the resource is
+	// considered degraded instead.
+	NonFatal bool
+	// The resource is not managed by the release, but something in the release depends on it.
+	ExternalDependency bool
+	// If set, logs of the pods are followed after the resource is ready.
+	LogFollower *track.LogFollower
 }
 
 type TrackResourceReadinessOperation struct {
@@ -84,11 +110,54 @@ type TrackResourceReadinessOperation struct {
 	ignoreLogs                               bool
 	ignoreLogsForContainers                  []string
 	saveEvents                               bool
+	strictReadiness                          bool
+	readyStableFor                           time.Duration
+	readyConditions                          []*resource.TrackCondition
+	failureConditions                        []*resource.TrackCondition
+	nonFatal                                 bool
+	externalDependency                       bool
+	logFollower                              *track.LogFollower
 
-	status Status
+	stabilizationDuration time.Duration
+	degradationErr        error
+	status                Status
 }
 
 func (o *TrackResourceReadinessOperation) Execute(ctx context.Context) error {
+	err := o.execute(ctx)
+	if err != nil && o.externalDependency {
+		err = fmt.Errorf("wait for external dependency %q to become ready: %w", o.resource.HumanID(), err)
+	}
+
+	if err == nil || !o.nonFatal || ctx.Err() != nil {
+		return err
+	}
+
+	log.Track.Warn(ctx, "Warning: %s is degraded, but its track failures are not fatal, continuing: %s", o.resource.HumanID(), err)
+
+	o.degradationErr = err
+	o.status = StatusCompleted
+
+	return nil
+}
+
+func (o *TrackResourceReadinessOperation) execute(ctx context.Context) error {
+	startedAt := time.Now()
+
+	if len(o.readyConditions) > 0 {
+		if err := o.trackConditions(ctx); err != nil {
+			o.status = StatusFailed
+
+			if o.timedOut(ctx, startedAt, err) {
+				err = o.timeoutError(err)
+			}
+
+			return fmt.Errorf("track resource readiness: %w", err)
+		}
+
+		return o.completeTracking(ctx, startedAt)
+	}
+
 	tracker, err := dyntracker.NewDynamicReadinessTracker(ctx, o.taskState, o.logStore, o.staticClient, o.dynamicClient, o.discoveryClient, o.mapper, dyntracker.DynamicReadinessTrackerOptions{
 		Timeout:                                  o.timeout,
 		NoActivityTimeout:                        o.noActivityTimeout,
@@ -105,19 +174,91 @@ func (o *TrackResourceReadinessOperation) Execute(ctx context.Context) error {
 		return fmt.Errorf("create readiness tracker: %w", err)
 	}
 
-	if err := tracker.Track(ctx); err != nil {
+	var trackErr error
+	switch groupKind := o.resource.GroupVersionKind().GroupKind(); {
+	case groupKind == daemonSetGroupKind && !o.strictReadiness:
+		trackErr = o.trackDaemonSet(ctx, tracker)
+	case groupKind == jobGroupKind:
+		trackErr = o.trackJob(ctx, tracker)
+	default:
+		trackErr = tracker.Track(ctx)
+	}
+
+	if trackErr != nil {
 		o.status = StatusFailed
-		return fmt.Errorf("track resource readiness: %w", err)
+
+		if o.timedOut(ctx, startedAt, trackErr) {
+			trackErr = o.timeoutError(trackErr)
+		}
+
+		return fmt.Errorf("track resource readiness: %w", trackErr)
 	}
 
+	return o.completeTracking(ctx, startedAt)
+}
+
+func (o *TrackResourceReadinessOperation) completeTracking(ctx context.Context, startedAt time.Time) error {
+	if o.readyStableFor > 0 {
+		var deadline time.Time
+		if o.timeout > 0 {
+			deadline = startedAt.Add(o.timeout)
+		}
+
+		if err := o.waitReadinessStable(ctx, deadline); err != nil {
+			o.status = StatusFailed
+			return fmt.Errorf("wait for resource readiness to stabilize: %w", err)
+		}
+	}
+
+	o.followLogs()
+
 	o.status = StatusCompleted
 	return nil
 }
 
+func (o *TrackResourceReadinessOperation) followLogs() {
+	if o.logFollower == nil || o.ignoreLogs {
+		return
+	}
+
+	o.taskState.RTransaction(func(ts *statestore.ReadinessTaskState) {
+		for _, crs := range ts.ResourceStates() {
+			crs.RTransaction(func(rs *statestore.ResourceState) {
+				if rs.GroupVersionKind().GroupKind() != (schema.GroupKind{Kind: "Pod"}) {
+					return
+				}
+
+				o.logFollower.Follow(rs.Name(), rs.Namespace(), track.LogFollowerOptions{
+					OnlyForContainers:    o.saveLogsOnlyForContainers,
+					IgnoreForContainers:  o.ignoreLogsForContainers,
+					ByRegex:              o.saveLogsByRegex,
+					ByRegexForContainers: o.saveLogsByRegexForContainers,
+				})
+			})
+		}
+	})
+}
+
+// StabilizationDuration returns how long it took for the resource to become stable after it became
+// ready for the first time. Zero if the stability window is not configured.
+func (o *TrackResourceReadinessOperation) StabilizationDuration() time.Duration {
+	return o.stabilizationDuration
+}
+
+// DegradationError returns why the resource failed to become ready, if its track failures are not
+// fatal. Nil if the resource is not degraded.
+func (o *TrackResourceReadinessOperation) DegradationError() error {
+	return o.degradationErr
+}
+
 func (o *TrackResourceReadinessOperation) ID() string {
 	return TypeTrackResourceReadinessOperation + "/" + o.resource.ID()
 }
 
+func (o *TrackResourceReadinessOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourceReadinessOperation) HumanID() string {
 	return "track resource readiness: " + o.resource.HumanID()
 }
@@ -130,6 +271,10 @@ func (o *TrackResourceReadinessOperation) Type() Type {
 	return TypeTrackResourceReadinessOperation
 }
 
+func (o *TrackResourceReadinessOperation) Timeout() time.Duration {
+	return o.timeout
+}
+
 func (o *TrackResourceReadinessOperation) Empty() bool {
 	return false
 }
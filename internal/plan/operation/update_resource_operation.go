@@ -9,6 +9,7 @@ import (
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var _ Operation = (*UpdateResourceOperation)(nil)
@@ -30,12 +31,18 @@ func NewUpdateResourceOperation(
 		kubeClient:   kubeClient,
 		manageableBy: opts.ManageableBy,
 		extraPost:    opts.ExtraPost,
+		adopt:        opts.Adopt,
+		replaceOp:    opts.ReplaceOnImmutableChange,
 	}, nil
 }
 
 type UpdateResourceOperationOptions struct {
 	ManageableBy resource.ManageableBy
 	ExtraPost    bool
+	Adopt        bool
+	// If set, the resource is replaced by this operation when applying fails because of changed
+	// immutable fields.
+	ReplaceOnImmutableChange *RecreateResourceOperation
 }
 
 type UpdateResourceOperation struct {
@@ -44,13 +51,27 @@ type UpdateResourceOperation struct {
 	kubeClient   kube.KubeClienter
 	manageableBy resource.ManageableBy
 	extraPost    bool
+	adopt        bool
+	replaceOp    *RecreateResourceOperation
 	status       Status
 }
 
 func (o *UpdateResourceOperation) Execute(ctx context.Context) error {
 	if _, err := o.kubeClient.Apply(ctx, o.resource, o.unstruct, kube.KubeClientApplyOptions{}); err != nil {
+		if o.replaceOp != nil && isImmutableErr(err) {
+			log.Plan.Warn(ctx, "Warning: immutable fields of %s changed, replacing it", o.resource.HumanID())
+
+			if err := o.replaceOp.Execute(ctx); err != nil {
+				o.status = StatusFailed
+				return fmt.Errorf("error replacing resource: %w", err)
+			}
+			o.status = StatusCompleted
+
+			return nil
+		}
+
 		o.status = StatusFailed
-		return fmt.Errorf("error applying resource: %w", err)
+		return fmt.Errorf("error applying resource: %w", log.RedactSensitiveError(humanizeAdmissionWebhookError(o.resource, err), o.unstruct.Object))
 	}
 	o.status = StatusCompleted
 
@@ -65,8 +86,20 @@ func (o *UpdateResourceOperation) ID() string {
 	return TypeUpdateResourceOperation + "/" + o.resource.ID()
 }
 
+func (o *UpdateResourceOperation) ResourceID() *id.ResourceID {
+	return o.resource
+}
+
 func (o *UpdateResourceOperation) HumanID() string {
-	return "update resource: " + o.resource.HumanID()
+	if o.adopt {
+		return "adopt resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+	}
+
+	return "update resource: " + o.resource.HumanID() + " (manifest hash: " + o.ManifestHash() + ")"
+}
+
+func (o *UpdateResourceOperation) ManifestHash() string {
+	return resource.ManifestHash(o.unstruct)
 }
 
 func (o *UpdateResourceOperation) Status() Status {
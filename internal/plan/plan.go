@@ -3,27 +3,39 @@ package plan
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/dominikbraun/graph"
-	"github.com/dominikbraun/graph/draw"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 
 	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/util"
 )
 
 func NewPlan() *Plan {
 	planGraph := graph.New(func(t operation.Operation) string { return t.ID() }, graph.Acyclic(), graph.PreventCycles(), graph.Directed())
 
 	return &Plan{
-		graph: planGraph,
+		graph:             planGraph,
+		resumedOpsIDs:     map[string]struct{}{},
+		timings:           map[string]*OperationTiming{},
+		dependencyReasons: map[string]string{},
+		operationsStages:  map[string]string{},
 	}
 }
 
 type Plan struct {
-	graph graph.Graph[string, operation.Operation]
+	graph                     graph.Graph[string, operation.Operation]
+	resumedOpsIDs             map[string]struct{}
+	timings                   map[string]*OperationTiming
+	timingsMu                 sync.Mutex
+	dependencyReasons         map[string]string
+	unsatisfiableDependencies []*DependencyCycleError
+	operationsStages          map[string]string
 }
 
 func (p *Plan) Operation(idFormat string, a ...any) (op operation.Operation, found bool) {
@@ -38,6 +50,10 @@ func (p *Plan) Operation(idFormat string, a ...any) (op operation.Operation, fou
 		}
 	}
 
+	if _, resumed := p.resumedOpsIDs[opID]; resumed {
+		return &resumedOperation{Operation: vertex}, true
+	}
+
 	return vertex, true
 }
 
@@ -58,19 +74,32 @@ func (p *Plan) OperationsMatch(regex *regexp.Regexp) (ops []operation.Operation,
 	return ops, len(ops) > 0, nil
 }
 
+// Operations returns operations in topological order. Ties are broken by operation IDs, so the order
+// is the same for the same plan.
 func (p *Plan) Operations() (operations []operation.Operation, found bool, err error) {
-	adjMap, err := p.graph.AdjacencyMap()
+	opsIDs, err := p.sortedOperationsIDs()
 	if err != nil {
-		return nil, false, fmt.Errorf("error getting adjacency map: %w", err)
+		return nil, false, err
 	}
 
-	for opID := range adjMap {
+	for _, opID := range opsIDs {
 		operations = append(operations, lo.Must(p.Operation(opID)))
 	}
 
 	return operations, len(operations) > 0, nil
 }
 
+func (p *Plan) sortedOperationsIDs() ([]string, error) {
+	opsIDs, err := graph.StableTopologicalSort(p.graph, func(a, b string) bool {
+		return a < b
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error sorting operations topologically: %w", err)
+	}
+
+	return opsIDs, nil
+}
+
 func (p *Plan) CompletedOperations() (completedOps []operation.Operation, found bool, err error) {
 	ops, found, err := p.Operations()
 	if err != nil {
@@ -141,8 +170,16 @@ func (p *Plan) WorthyCompletedOperations() (worthyCompletedOps []operation.Opera
 			operation.TypeExtraPostRecreateResourceOperation,
 			operation.TypeExtraPostApplyResourceOperation,
 			operation.TypeExtraPostUpdateResourceOperation,
-			operation.TypeExtraPostDeleteResourceOperation:
+			operation.TypeExtraPostDeleteResourceOperation,
+			operation.TypeExecJobOperation:
 			worthyCompletedOps = append(worthyCompletedOps, op)
+		case operation.TypeTrackResourceReadinessOperation:
+			// Only worth reporting if it took time for the resource to become stable or if the
+			// resource is degraded.
+			trackOp := op.(*operation.TrackResourceReadinessOperation)
+			if trackOp.StabilizationDuration() > 0 || trackOp.DegradationError() != nil {
+				worthyCompletedOps = append(worthyCompletedOps, op)
+			}
 		}
 	}
 
@@ -178,7 +215,8 @@ func (p *Plan) WorthyCanceledOperations() (worthyCanceledOps []operation.Operati
 			operation.TypeRecreateResourceOperation,
 			operation.TypeUpdateResourceOperation,
 			operation.TypeApplyResourceOperation,
-			operation.TypeDeleteResourceOperation:
+			operation.TypeDeleteResourceOperation,
+			operation.TypeExecJobOperation:
 			worthyCanceledOps = append(worthyCanceledOps, op)
 		}
 	}
@@ -199,6 +237,7 @@ func (p *Plan) AddOperation(op operation.Operation) {
 
 func (p *Plan) AddStagedOperation(op operation.Operation, stageInID, stageOutID string) {
 	p.AddOperation(op)
+	p.operationsStages[op.ID()] = strings.TrimSuffix(stageInID, "/"+StageOpNameSuffixStart)
 
 	if _, found := p.Operation(stageInID); !found {
 		op := operation.NewStageOperation(stageInID)
@@ -237,14 +276,26 @@ func (p *Plan) AddOutStagedOperation(op operation.Operation, stageOutID string)
 }
 
 func (p *Plan) AddDependency(fromOpID, toOpID string) error {
+	return p.AddDependencyWithReason(fromOpID, toOpID, "")
+}
+
+// AddDependencyWithReason is the same as AddDependency, but also remembers why the dependency is
+// needed, so that it can be explained if the dependency turns out to be a part of a cycle.
+func (p *Plan) AddDependencyWithReason(fromOpID, toOpID, reason string) error {
 	if err := p.graph.AddEdge(fromOpID, toOpID); err != nil {
 		if errors.Is(err, graph.ErrEdgeAlreadyExists) {
 			return nil
+		} else if errors.Is(err, graph.ErrEdgeCreatesCycle) {
+			return p.newDependencyCycleError(fromOpID, toOpID, reason)
 		} else {
 			return fmt.Errorf("error adding edge from %q to %q: %w", fromOpID, toOpID, err)
 		}
 	}
 
+	if reason != "" {
+		p.dependencyReasons[dependencyKey(fromOpID, toOpID)] = reason
+	}
+
 	return nil
 }
 
@@ -259,27 +310,98 @@ func (p *Plan) Optimize() error {
 	return nil
 }
 
+// DOT returns the plan as a DOT graph. Operations are in the same order as in Operations() and
+// edges are sorted by IDs, so the output is the same for the same plan. Operations are colored by
+// their status and shaped by their type, edges from and to stages are dashed. Track operations are
+// labeled with their timeouts.
 func (p *Plan) DOT() ([]byte, error) {
+	opsIDs, err := p.sortedOperationsIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	adjMap, err := p.graph.AdjacencyMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjacency map: %w", err)
+	}
+
 	b := &bytes.Buffer{}
+	b.WriteString("strict digraph {\n")
+	b.WriteString("\trankdir=\"LR\";\n")
+
+	for _, opID := range opsIDs {
+		op := lo.Must(p.Operation("%s", opID))
+		if trackOp, ok := op.(operation.TrackOperation); ok {
+			label := fmt.Sprintf("%s\\ntimeout: %s", opID, trackOperationTimeout(trackOp))
+			fmt.Fprintf(b, "\t%s [shape=%q, style=\"filled\", fillcolor=%q, label=%s];\n", dotID(opID), dotOperationShape(op), dotOperationColor(op), dotID(label))
+		} else {
+			fmt.Fprintf(b, "\t%s [shape=%q, style=\"filled\", fillcolor=%q];\n", dotID(opID), dotOperationShape(op), dotOperationColor(op))
+		}
 
-	if err := draw.DOT(
-		p.graph,
-		b,
-		draw.GraphAttribute("rankdir", "LR"),
-	); err != nil {
-		return nil, fmt.Errorf("error drawing DOT graph: %w", err)
+		toOpsIDs := lo.Keys(adjMap[opID])
+		sort.Strings(toOpsIDs)
+
+		for _, toOpID := range toOpsIDs {
+			toOp := lo.Must(p.Operation("%s", toOpID))
+			if op.Type() == operation.TypeStageOperation || toOp.Type() == operation.TypeStageOperation {
+				fmt.Fprintf(b, "\t%s -> %s [style=\"dashed\", color=\"grey40\"];\n", dotID(opID), dotID(toOpID))
+			} else {
+				fmt.Fprintf(b, "\t%s -> %s;\n", dotID(opID), dotID(toOpID))
+			}
+		}
 	}
 
+	b.WriteString("}\n")
+
 	return b.Bytes(), nil
 }
 
+func dotOperationColor(op operation.Operation) string {
+	switch op.Status() {
+	case operation.StatusCompleted:
+		return "palegreen"
+	case operation.StatusFailed:
+		return "salmon"
+	default:
+		return "lightgrey"
+	}
+}
+
+func dotOperationShape(op operation.Operation) string {
+	switch op.Type() {
+	case operation.TypeStageOperation:
+		return "cds"
+	case operation.TypeCreatePendingReleaseOperation,
+		operation.TypeSucceedReleaseOperation,
+		operation.TypeFailReleaseOperation,
+		operation.TypeSupersedeReleaseOperation:
+		return "folder"
+	case operation.TypeTrackResourceReadinessOperation,
+		operation.TypeTrackResourcePresenceOperation,
+		operation.TypeTrackResourceAbsenceOperation,
+		operation.TypeTrackCRDEstablishedOperation:
+		return "ellipse"
+	case operation.TypeSleepOperation,
+		operation.TypeExtraPostSleepOperation:
+		return "hexagon"
+	case operation.TypeExecJobOperation:
+		return "component"
+	default:
+		return "box"
+	}
+}
+
+func dotID(id string) string {
+	return `"` + strings.ReplaceAll(id, `"`, `\"`) + `"`
+}
+
 func (p *Plan) SaveDOT(path string) error {
 	dot, err := p.DOT()
 	if err != nil {
 		return fmt.Errorf("error getting DOT graph: %w", err)
 	}
 
-	if err := os.WriteFile(path, dot, 0o644); err != nil {
+	if err := util.WriteFileAtomic(path, dot, 0o644); err != nil {
 		return fmt.Errorf("error writing DOT graph file at %q: %w", path, err)
 	}
 
@@ -305,11 +427,13 @@ func (p *Plan) Useless() (bool, error) {
 			operation.TypeTrackResourceReadinessOperation,
 			operation.TypeTrackResourcePresenceOperation,
 			operation.TypeTrackResourceAbsenceOperation,
+			operation.TypeTrackCRDEstablishedOperation,
 			operation.TypeExtraPostCreateResourceOperation,
 			operation.TypeExtraPostRecreateResourceOperation,
 			operation.TypeExtraPostApplyResourceOperation,
 			operation.TypeExtraPostUpdateResourceOperation,
-			operation.TypeExtraPostDeleteResourceOperation:
+			operation.TypeExtraPostDeleteResourceOperation,
+			operation.TypeExecJobOperation:
 			if !op.Empty() {
 				return false, nil
 			}
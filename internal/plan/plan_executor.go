@@ -9,39 +9,107 @@ import (
 	"github.com/samber/lo"
 	"github.com/sourcegraph/conc/pool"
 
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/plan/operation"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func NewPlanExecutor(plan *Plan, opts PlanExecutorOptions) *PlanExecutor {
 	return &PlanExecutor{
-		plan:               plan,
-		networkParallelism: lo.Max([]int{opts.NetworkParallelism, 1}),
+		plan:                 plan,
+		opsSemaphore:         newSemaphore(opts.Parallelism),
+		trackOpsSemaphore:    newSemaphore(opts.TrackParallelism),
+		onOperationCompleted: opts.OnOperationCompleted,
+		interruptGracePeriod: opts.InterruptGracePeriod,
+		progressReporter:     opts.ProgressReporter,
+		retryPolicy:          opts.RetryPolicy,
 	}
 }
 
 type PlanExecutorOptions struct {
-	NetworkParallelism int
+	// Limit of operations, except tracking operations, to run in parallel. 0 means unlimited.
+	Parallelism int
+	// Limit of tracking operations to run in parallel. They are not counted against Parallelism,
+	// so that waiting for many resources doesn't block applying other resources. 0 means unlimited.
+	TrackParallelism     int
+	OnOperationCompleted func(ctx context.Context, op operation.Operation)
+	// When the context passed to Execute is canceled, no new operations are started, and the
+	// in-flight ones are given this much time to finish before they are canceled too.
+	InterruptGracePeriod time.Duration
+	ProgressReporter     ProgressReporter
+	// Applies only to operations implementing operation.RetriableOperation.
+	RetryPolicy OperationRetryPolicy
+}
+
+type OperationRetryPolicy struct {
+	// How many times a failed operation is retried, unless overridden for the operation. 0 means
+	// no retries.
+	Retries int
+	// Delay before the first retry, doubled for each next retry.
+	Backoff time.Duration
+	// Defaults to kube.IsTransientError.
+	IsRetriable func(err error) bool
 }
 
 type PlanExecutor struct {
-	plan               *Plan
-	networkParallelism int
+	plan                 *Plan
+	opsSemaphore         semaphore
+	trackOpsSemaphore    semaphore
+	onOperationCompleted func(ctx context.Context, op operation.Operation)
+	interruptGracePeriod time.Duration
+	progressReporter     ProgressReporter
+	retryPolicy          OperationRetryPolicy
 }
 
 func (e *PlanExecutor) Execute(parentCtx context.Context) error {
-	ctx, ctxCancelFn := context.WithCancel(parentCtx)
+	// Operations don't inherit cancellation of parentCtx, so that in-flight operations can be
+	// given a grace period to finish when parentCtx is canceled.
+	ctx, ctxCancelFn := context.WithCancel(context.WithoutCancel(parentCtx))
+	defer ctxCancelFn()
+
+	executionFinishedCh := make(chan struct{})
+	defer close(executionFinishedCh)
+
+	go func() {
+		select {
+		case <-parentCtx.Done():
+		case <-executionFinishedCh:
+			return
+		}
+
+		select {
+		case <-time.After(e.interruptGracePeriod):
+			ctxCancelFn()
+		case <-executionFinishedCh:
+		}
+	}()
 
 	opsMap, err := e.plan.PredecessorMap()
 	if err != nil {
 		return fmt.Errorf("error getting plan predecessor map: %w", err)
 	}
 
-	workerPool := pool.New().WithContext(ctx).WithMaxGoroutines(e.networkParallelism).WithCancelOnError().WithFirstError()
+	var progress *progressDispatcher
+	if e.progressReporter != nil {
+		var totalOps int
+		for opID := range opsMap {
+			if lo.Must(e.plan.Operation(opID)).Type() != operation.TypeStageOperation {
+				totalOps++
+			}
+		}
+
+		progress = newProgressDispatcher(parentCtx, e.progressReporter, totalOps)
+	}
+
+	workerPool := pool.New().WithContext(ctx).WithCancelOnError().WithFirstError()
 	completedOpsIDsCh := make(chan string, 100000)
 
 	for i := 0; len(opsMap) > 0; i++ {
+		if parentCtx.Err() != nil {
+			break
+		}
+
 		if i > 0 {
 			if ctx.Err() != nil {
 				break
@@ -65,18 +133,25 @@ func (e *PlanExecutor) Execute(parentCtx context.Context) error {
 		for _, opID := range executableOpsIDs {
 			opID := opID
 			delete(opsMap, opID)
-			e.execOperation(opID, completedOpsIDsCh, workerPool, ctxCancelFn)
+			e.execOperation(parentCtx, opID, completedOpsIDsCh, workerPool, ctxCancelFn, progress)
 		}
 	}
 
-	if err := workerPool.Wait(); err != nil {
+	err = workerPool.Wait()
+	progress.close()
+
+	if err != nil {
 		return fmt.Errorf("error waiting for operations completion: %w", err)
 	}
 
+	if parentCtx.Err() != nil {
+		return fmt.Errorf("plan execution interrupted: %w", context.Cause(parentCtx))
+	}
+
 	return nil
 }
 
-func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string, workerPool *pool.ContextPool, ctxCancelFn context.CancelFunc) {
+func (e *PlanExecutor) execOperation(parentCtx context.Context, opID string, completedOpsIDsCh chan string, workerPool *pool.ContextPool, ctxCancelFn context.CancelFunc, progress *progressDispatcher) {
 	workerPool.Go(func(ctx context.Context) error {
 		failed := true
 		defer func() {
@@ -87,6 +162,26 @@ func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string,
 
 		op := lo.Must(e.plan.Operation(opID))
 
+		sem := e.opsSemaphore
+		switch op.Type() {
+		case operation.TypeTrackResourceReadinessOperation,
+			operation.TypeTrackResourcePresenceOperation,
+			operation.TypeTrackResourceAbsenceOperation,
+			operation.TypeTrackCRDEstablishedOperation:
+			sem = e.trackOpsSemaphore
+		}
+
+		if err := sem.acquire(ctx); err != nil {
+			return fmt.Errorf("error waiting for operation to start: %w", err)
+		}
+		defer sem.release()
+
+		// Interrupted while waiting for the semaphore. The operation stays not started.
+		if parentCtx.Err() != nil {
+			failed = false
+			return nil
+		}
+
 		switch op.Type() {
 		case operation.TypeCreateResourceOperation,
 			operation.TypeRecreateResourceOperation,
@@ -97,12 +192,27 @@ func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string,
 			operation.TypeExtraPostRecreateResourceOperation,
 			operation.TypeExtraPostApplyResourceOperation,
 			operation.TypeExtraPostUpdateResourceOperation,
-			operation.TypeExtraPostDeleteResourceOperation:
-			log.Default.Debug(ctx, util.Capitalize(op.HumanID()))
+			operation.TypeExtraPostDeleteResourceOperation,
+			operation.TypeExecJobOperation:
+			log.Plan.Debug(ctx, util.Capitalize(op.HumanID()))
+		}
+
+		e.plan.recordOperationStarted(opID)
+		progress.operationStarted(op)
+		err := e.executeOperation(parentCtx, ctx, op)
+		e.plan.recordOperationFinished(opID)
+		if err != nil {
+			progress.operationFailed(op, err)
+			return &OperationError{
+				Operation: op,
+				err:       err,
+			}
 		}
 
-		if err := op.Execute(ctx); err != nil {
-			return fmt.Errorf("error executing operation: %w", err)
+		progress.operationCompleted(op)
+
+		if e.onOperationCompleted != nil {
+			e.onOperationCompleted(ctx, op)
 		}
 
 		completedOpsIDsCh <- opID
@@ -112,6 +222,56 @@ func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string,
 	})
 }
 
+func (e *PlanExecutor) executeOperation(parentCtx, ctx context.Context, op operation.Operation) error {
+	retriableOp, retriable := op.(operation.RetriableOperation)
+	if !retriable {
+		return op.Execute(ctx)
+	}
+
+	retries := e.retryPolicy.Retries
+	if r, set := retriableOp.Retries(); set {
+		retries = r
+	}
+
+	isRetriable := e.retryPolicy.IsRetriable
+	if isRetriable == nil {
+		isRetriable = kube.IsTransientError
+	}
+
+	backoff := e.retryPolicy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op.Execute(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// Don't retry after being interrupted, only let the in-flight attempt finish.
+		if attempt > retries || !isRetriable(err) || parentCtx.Err() != nil {
+			return attemptsError(attempt, err)
+		}
+
+		log.Plan.Warn(ctx, "Retrying %s in %s (attempt %d/%d failed): %s", op.HumanID(), backoff, attempt, retries+1, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-parentCtx.Done():
+			return attemptsError(attempt, err)
+		case <-ctx.Done():
+			return attemptsError(attempt, err)
+		}
+
+		backoff *= 2
+	}
+}
+
+func attemptsError(attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+}
+
 func (e *PlanExecutor) findExecutableOpsIDs(opsMap map[string]map[string]graph.Edge[string]) []string {
 	var executableOpsIDs []string
 	for opID, edgeMap := range opsMap {
@@ -122,3 +282,48 @@ func (e *PlanExecutor) findExecutableOpsIDs(opsMap map[string]map[string]graph.E
 
 	return executableOpsIDs
 }
+
+// OperationError is returned for each failed operation, so that the caller can tell which kinds of
+// operations failed.
+type OperationError struct {
+	Operation operation.Operation
+	err       error
+}
+
+func (e *OperationError) Error() string {
+	return "error executing operation: " + e.err.Error()
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.err
+}
+
+// A nil semaphore doesn't limit anything.
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+
+	return make(semaphore, limit)
+}
+
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
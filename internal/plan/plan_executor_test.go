@@ -0,0 +1,125 @@
+package plan_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+var _ = Describe("plan executor", func() {
+	const opsCount = 10
+
+	execute := func(opts plan.PlanExecutorOptions, opType operation.Type) int32 {
+		counter := &concurrencyCounter{allEntered: make(chan struct{}), total: opsCount}
+
+		p := plan.NewPlan()
+		for i := 0; i < opsCount; i++ {
+			p.AddOperation(&countingOperation{name: fmt.Sprintf("op-%d", i), opType: opType, counter: counter})
+		}
+
+		Expect(plan.NewPlanExecutor(p, opts).Execute(context.Background())).To(Succeed())
+
+		return counter.max.Load()
+	}
+
+	DescribeTable("limits operations running in parallel",
+		func(opts plan.PlanExecutorOptions, opType string, expectedMax int) {
+			Expect(execute(opts, operation.Type(opType))).To(BeNumerically("==", expectedMax))
+		},
+		Entry("with the limit", plan.PlanExecutorOptions{Parallelism: 3}, "counting", 3),
+		Entry("with the limit of one", plan.PlanExecutorOptions{Parallelism: 1}, "counting", 1),
+		Entry("not with the tracking limit", plan.PlanExecutorOptions{Parallelism: 3, TrackParallelism: 1}, "counting", 3),
+		Entry("with the tracking limit for tracking operations", plan.PlanExecutorOptions{Parallelism: 1, TrackParallelism: 2}, operation.TypeTrackResourceReadinessOperation, 2),
+	)
+
+	DescribeTable("doesn't limit operations running in parallel",
+		func(opts plan.PlanExecutorOptions, opType string) {
+			Expect(execute(opts, operation.Type(opType))).To(BeNumerically("==", opsCount))
+		},
+		Entry("with zero options", plan.PlanExecutorOptions{}, "counting"),
+		Entry("with a negative limit", plan.PlanExecutorOptions{Parallelism: -1}, "counting"),
+		Entry("with zero tracking limit for tracking operations", plan.PlanExecutorOptions{Parallelism: 1}, operation.TypeTrackResourceReadinessOperation),
+	)
+})
+
+type concurrencyCounter struct {
+	mu         sync.Mutex
+	current    int32
+	max        atomic.Int32
+	total      int32
+	allEntered chan struct{}
+}
+
+func (c *concurrencyCounter) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current++
+	if c.current > c.max.Load() {
+		c.max.Store(c.current)
+	}
+
+	if c.current == c.total {
+		close(c.allEntered)
+	}
+}
+
+func (c *concurrencyCounter) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current--
+}
+
+var _ operation.Operation = (*countingOperation)(nil)
+
+type countingOperation struct {
+	name    string
+	opType  operation.Type
+	counter *concurrencyCounter
+	status  operation.Status
+}
+
+// Holds on until all operations are running, to make sure unlimited operations overlap, or for a
+// while, if they are limited.
+func (o *countingOperation) Execute(ctx context.Context) error {
+	o.counter.enter()
+	defer o.counter.leave()
+
+	select {
+	case <-o.counter.allEntered:
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	o.status = operation.StatusCompleted
+
+	return nil
+}
+
+func (o *countingOperation) ID() string {
+	return "counting/" + o.name
+}
+
+func (o *countingOperation) HumanID() string {
+	return "counting: " + o.name
+}
+
+func (o *countingOperation) Status() operation.Status {
+	return o.status
+}
+
+func (o *countingOperation) Type() operation.Type {
+	return o.opType
+}
+
+func (o *countingOperation) Empty() bool {
+	return false
+}
@@ -0,0 +1,155 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/util"
+)
+
+type planJSON struct {
+	Operations []*planOperationJSON `json:"operations"`
+	Edges      []*planEdgeJSON      `json:"edges"`
+}
+
+type planOperationJSON struct {
+	ID           string                 `json:"id"`
+	StructuredID operation.StructuredID `json:"structuredID"`
+	Type         string                 `json:"type"`
+	HumanID      string                 `json:"humanID"`
+	Status       string                 `json:"status,omitempty"`
+	Resource     *planResourceJSON      `json:"resource,omitempty"`
+	// Only for track operations, "none" if there is no timeout.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+type planResourceJSON struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type planEdgeJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type resourceOperation interface {
+	ResourceID() *id.ResourceID
+}
+
+// JSON returns the plan as a JSON document. Operations and edges are sorted by IDs to make the
+// output stable across runs.
+func (p *Plan) JSON() ([]byte, error) {
+	adjMap, err := p.graph.AdjacencyMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjacency map: %w", err)
+	}
+
+	result := &planJSON{
+		Operations: []*planOperationJSON{},
+		Edges:      []*planEdgeJSON{},
+	}
+
+	for opID, edges := range adjMap {
+		op, _ := p.Operation(opID)
+		result.Operations = append(result.Operations, newPlanOperationJSON(op))
+
+		for toOpID := range edges {
+			result.Edges = append(result.Edges, &planEdgeJSON{
+				From: opID,
+				To:   toOpID,
+			})
+		}
+	}
+
+	sort.Slice(result.Operations, func(i, j int) bool {
+		return result.Operations[i].ID < result.Operations[j].ID
+	})
+
+	sort.Slice(result.Edges, func(i, j int) bool {
+		if result.Edges[i].From != result.Edges[j].From {
+			return result.Edges[i].From < result.Edges[j].From
+		}
+
+		return result.Edges[i].To < result.Edges[j].To
+	})
+
+	data, err := json.MarshalIndent(result, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling plan to JSON: %w", err)
+	}
+
+	return data, nil
+}
+
+func (p *Plan) SaveJSON(path string) error {
+	data, err := p.JSON()
+	if err != nil {
+		return fmt.Errorf("error getting plan JSON: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing plan JSON file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (p *Plan) YAML() ([]byte, error) {
+	data, err := p.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("error getting plan JSON: %w", err)
+	}
+
+	data, err = yaml.JSONToYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("error converting plan JSON to YAML: %w", err)
+	}
+
+	return data, nil
+}
+
+func newPlanOperationJSON(op operation.Operation) *planOperationJSON {
+	result := &planOperationJSON{
+		ID:           op.ID(),
+		StructuredID: operation.NewStructuredID(op),
+		Type:         string(op.Type()),
+		HumanID:      op.HumanID(),
+		Status:       string(op.Status()),
+	}
+
+	if resOp, ok := op.(resourceOperation); ok {
+		resID := resOp.ResourceID()
+		gvk := resID.GroupVersionKind()
+
+		result.Resource = &planResourceJSON{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: resID.Namespace(),
+			Name:      resID.Name(),
+		}
+	}
+
+	if trackOp, ok := op.(operation.TrackOperation); ok {
+		result.Timeout = trackOperationTimeout(trackOp)
+	}
+
+	return result
+}
+
+func trackOperationTimeout(op operation.TrackOperation) string {
+	if op.Timeout() == 0 {
+		return "none"
+	}
+
+	return op.Timeout().String()
+}
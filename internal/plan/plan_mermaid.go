@@ -0,0 +1,195 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/util"
+)
+
+var mermaidInvalidIDCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Mermaid returns the plan as a Mermaid flowchart. Stage operations are drawn as subgraphs
+// containing the operations staged between them instead of as separate nodes.
+func (p *Plan) Mermaid() ([]byte, error) {
+	adjMap, err := p.graph.AdjacencyMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjacency map: %w", err)
+	}
+
+	predMap, err := p.graph.PredecessorMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting predecessor map: %w", err)
+	}
+
+	opsIDs := make([]string, 0, len(adjMap))
+	for opID := range adjMap {
+		opsIDs = append(opsIDs, opID)
+	}
+	sort.Strings(opsIDs)
+
+	ids := newMermaidIDs()
+
+	// Stage operations are replaced with their stages, other operations are assigned to the stage
+	// they are staged in.
+	stageOf := map[string]string{}
+	vertexOf := map[string]string{}
+	stagesMembers := map[string][]string{}
+	var stages []string
+	for _, opID := range opsIDs {
+		if stage, isStageOp := mermaidStage(lo.Must(p.Operation(opID))); isStageOp {
+			if _, found := stagesMembers[stage]; !found {
+				stagesMembers[stage] = nil
+				stages = append(stages, stage)
+			}
+
+			vertexOf[opID] = stage
+			continue
+		}
+
+		vertexOf[opID] = opID
+
+		for predOpID := range predMap[opID] {
+			stage, isStageOp := mermaidStage(lo.Must(p.Operation(predOpID)))
+			if !isStageOp || !strings.HasSuffix(predOpID, "/"+StageOpNameSuffixStart) {
+				continue
+			}
+
+			if current, found := stageOf[opID]; !found || stage < current {
+				stageOf[opID] = stage
+			}
+		}
+	}
+
+	var ungroupedOpsIDs []string
+	for _, opID := range opsIDs {
+		if vertexOf[opID] != opID {
+			continue
+		}
+
+		if stage, found := stageOf[opID]; found {
+			stagesMembers[stage] = append(stagesMembers[stage], opID)
+		} else {
+			ungroupedOpsIDs = append(ungroupedOpsIDs, opID)
+		}
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("graph LR\n")
+
+	for _, opID := range ungroupedOpsIDs {
+		fmt.Fprintf(b, "  %s\n", mermaidNode(ids.get(opID), lo.Must(p.Operation(opID)).HumanID()))
+	}
+
+	for _, stage := range stages {
+		fmt.Fprintf(b, "  subgraph %s %s\n", ids.get(stage), mermaidLabel(strings.TrimPrefix(stage, operation.TypeStageOperation+"/")))
+		for _, opID := range stagesMembers[stage] {
+			fmt.Fprintf(b, "    %s\n", mermaidNode(ids.get(opID), lo.Must(p.Operation(opID)).HumanID()))
+		}
+		b.WriteString("  end\n")
+	}
+
+	edges := map[[2]string]struct{}{}
+	for _, fromOpID := range opsIDs {
+		for toOpID := range adjMap[fromOpID] {
+			from, to := vertexOf[fromOpID], vertexOf[toOpID]
+
+			// Edges between a stage and its own operations are implied by the subgraph.
+			if from == to || stageOf[toOpID] == from || stageOf[fromOpID] == to {
+				continue
+			}
+
+			edges[[2]string{from, to}] = struct{}{}
+		}
+	}
+
+	sortedEdges := make([][2]string, 0, len(edges))
+	for edge := range edges {
+		sortedEdges = append(sortedEdges, edge)
+	}
+	sort.Slice(sortedEdges, func(i, j int) bool {
+		if sortedEdges[i][0] != sortedEdges[j][0] {
+			return sortedEdges[i][0] < sortedEdges[j][0]
+		}
+
+		return sortedEdges[i][1] < sortedEdges[j][1]
+	})
+
+	for _, edge := range sortedEdges {
+		fmt.Fprintf(b, "  %s --> %s\n", ids.get(edge[0]), ids.get(edge[1]))
+	}
+
+	return []byte(b.String()), nil
+}
+
+func (p *Plan) SaveMermaid(path string) error {
+	data, err := p.Mermaid()
+	if err != nil {
+		return fmt.Errorf("error getting Mermaid graph: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing Mermaid graph file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func mermaidStage(op operation.Operation) (stage string, isStageOp bool) {
+	if op.Type() != operation.TypeStageOperation {
+		return "", false
+	}
+
+	stage = strings.TrimSuffix(op.ID(), "/"+StageOpNameSuffixStart)
+	stage = strings.TrimSuffix(stage, "/"+StageOpNameSuffixEnd)
+
+	return stage, true
+}
+
+func mermaidNode(id, label string) string {
+	return id + mermaidLabel(label)
+}
+
+func mermaidLabel(label string) string {
+	return `["` + strings.ReplaceAll(label, `"`, "#quot;") + `"]`
+}
+
+func newMermaidIDs() *mermaidIDs {
+	return &mermaidIDs{
+		byName: map[string]string{},
+		taken:  map[string]struct{}{},
+	}
+}
+
+// Mermaid node IDs can't contain slashes, colons and many other characters found in operation IDs,
+// so operation IDs are sanitized, keeping the sanitized IDs unique.
+type mermaidIDs struct {
+	byName map[string]string
+	taken  map[string]struct{}
+}
+
+func (m *mermaidIDs) get(name string) string {
+	if id, found := m.byName[name]; found {
+		return id
+	}
+
+	base := "op_" + mermaidInvalidIDCharsRegex.ReplaceAllString(name, "_")
+	id := base
+	for i := 2; ; i++ {
+		if _, taken := m.taken[id]; !taken {
+			break
+		}
+
+		id = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	m.byName[name] = id
+	m.taken[id] = struct{}{}
+
+	return id
+}
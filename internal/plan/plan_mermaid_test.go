@@ -0,0 +1,40 @@
+package plan_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+var _ = Describe("plan Mermaid graph", func() {
+	It("draws stages as subgraphs and sanitizes node IDs", func() {
+		hooksStage := operation.TypeStageOperation + "/pre-hook-resources/weight:-5"
+		resourcesStage := plan.StageOpNamePrefixGeneralResources
+
+		p := plan.NewPlan()
+		p.AddStagedOperation(&countingOperation{name: "app-ns:batch/v1:Job:migrate"}, hooksStage+"/"+plan.StageOpNameSuffixStart, hooksStage+"/"+plan.StageOpNameSuffixEnd)
+		p.AddStagedOperation(&countingOperation{name: "app-ns:apps/v1:Deployment:web"}, resourcesStage+"/"+plan.StageOpNameSuffixStart, resourcesStage+"/"+plan.StageOpNameSuffixEnd)
+		p.AddStagedOperation(&countingOperation{name: "app-ns:apps/v1/Deployment/web"}, resourcesStage+"/"+plan.StageOpNameSuffixStart, resourcesStage+"/"+plan.StageOpNameSuffixEnd)
+		p.AddOperation(&countingOperation{name: `say "done"`})
+
+		Expect(p.AddDependency(hooksStage+"/"+plan.StageOpNameSuffixEnd, resourcesStage+"/"+plan.StageOpNameSuffixStart)).To(Succeed())
+		Expect(p.AddDependency(resourcesStage+"/"+plan.StageOpNameSuffixEnd, `counting/say "done"`)).To(Succeed())
+
+		got, err := p.Mermaid()
+		Expect(err).NotTo(HaveOccurred())
+
+		goldenPath := filepath.Join("testdata", "plan.golden.mmd")
+		if os.Getenv("UPDATE_GOLDEN") != "" {
+			Expect(os.WriteFile(goldenPath, got, 0o644)).To(Succeed())
+		}
+
+		expected, err := os.ReadFile(goldenPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(got)).To(Equal(string(expected)))
+	})
+})
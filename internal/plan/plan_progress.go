@@ -0,0 +1,186 @@
+package plan
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// ProgressReporter receives progress of the plan execution. Methods are never called concurrently.
+// Stage operations are reported only via OnStageComplete and are not counted as operations.
+type ProgressReporter interface {
+	OnOperationStart(ctx context.Context, progress OperationProgress)
+	OnOperationComplete(ctx context.Context, progress OperationProgress)
+	OnOperationFailed(ctx context.Context, progress OperationProgress, err error)
+	OnStageComplete(ctx context.Context, progress StageProgress)
+}
+
+type OperationProgress struct {
+	OperationID   string
+	StructuredID  operation.StructuredID
+	OperationType string
+	HumanID       string
+	// Resource fields are empty if the operation doesn't operate on a resource.
+	ResourceName      string
+	ResourceNamespace string
+	ResourceGVK       schema.GroupVersionKind
+	ExecutionProgress
+}
+
+type StageProgress struct {
+	Stage string
+	ExecutionProgress
+}
+
+type ExecutionProgress struct {
+	CompletedOperations int
+	FailedOperations    int
+	// Includes operations in progress.
+	RemainingOperations int
+	TotalOperations     int
+}
+
+func NewLogProgressReporter() *LogProgressReporter {
+	return &LogProgressReporter{}
+}
+
+var _ ProgressReporter = (*LogProgressReporter)(nil)
+
+// LogProgressReporter logs the number of done operations every time an operation is done.
+type LogProgressReporter struct{}
+
+func (r *LogProgressReporter) OnOperationStart(ctx context.Context, progress OperationProgress) {}
+
+func (r *LogProgressReporter) OnOperationComplete(ctx context.Context, progress OperationProgress) {
+	log.Track.Info(ctx, "%d/%d operations done", progress.CompletedOperations, progress.TotalOperations)
+}
+
+func (r *LogProgressReporter) OnOperationFailed(ctx context.Context, progress OperationProgress, err error) {
+	log.Track.Info(ctx, "%d/%d operations done, %d failed", progress.CompletedOperations, progress.TotalOperations, progress.FailedOperations)
+}
+
+func (r *LogProgressReporter) OnStageComplete(ctx context.Context, progress StageProgress) {}
+
+func newProgressDispatcher(ctx context.Context, reporter ProgressReporter, totalOps int) *progressDispatcher {
+	d := &progressDispatcher{
+		ctx:      ctx,
+		reporter: reporter,
+		progress: ExecutionProgress{
+			RemainingOperations: totalOps,
+			TotalOperations:     totalOps,
+		},
+		callbacksCh: make(chan func(), 1000),
+		doneCh:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(d.doneCh)
+
+		for callback := range d.callbacksCh {
+			callback()
+		}
+	}()
+
+	return d
+}
+
+// Callbacks are called in order from a single goroutine, so that reporters don't need locking.
+// A nil dispatcher does nothing.
+type progressDispatcher struct {
+	ctx         context.Context
+	reporter    ProgressReporter
+	progress    ExecutionProgress
+	mu          sync.Mutex
+	callbacksCh chan func()
+	doneCh      chan struct{}
+}
+
+func (d *progressDispatcher) operationStarted(op operation.Operation) {
+	if d == nil || op.Type() == operation.TypeStageOperation {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	progress := newOperationProgress(op, d.progress)
+	d.callbacksCh <- func() { d.reporter.OnOperationStart(d.ctx, progress) }
+}
+
+func (d *progressDispatcher) operationCompleted(op operation.Operation) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if op.Type() == operation.TypeStageOperation {
+		if !strings.HasSuffix(op.ID(), "/"+StageOpNameSuffixEnd) {
+			return
+		}
+
+		progress := StageProgress{
+			Stage:             strings.TrimSuffix(op.ID(), "/"+StageOpNameSuffixEnd),
+			ExecutionProgress: d.progress,
+		}
+		d.callbacksCh <- func() { d.reporter.OnStageComplete(d.ctx, progress) }
+
+		return
+	}
+
+	d.progress.CompletedOperations++
+	d.progress.RemainingOperations--
+
+	progress := newOperationProgress(op, d.progress)
+	d.callbacksCh <- func() { d.reporter.OnOperationComplete(d.ctx, progress) }
+}
+
+func (d *progressDispatcher) operationFailed(op operation.Operation, err error) {
+	if d == nil || op.Type() == operation.TypeStageOperation {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.progress.FailedOperations++
+	d.progress.RemainingOperations--
+
+	progress := newOperationProgress(op, d.progress)
+	d.callbacksCh <- func() { d.reporter.OnOperationFailed(d.ctx, progress, err) }
+}
+
+// Waits for all callbacks to be called.
+func (d *progressDispatcher) close() {
+	if d == nil {
+		return
+	}
+
+	close(d.callbacksCh)
+	<-d.doneCh
+}
+
+func newOperationProgress(op operation.Operation, execProgress ExecutionProgress) OperationProgress {
+	progress := OperationProgress{
+		OperationID:       op.ID(),
+		StructuredID:      operation.NewStructuredID(op),
+		OperationType:     string(op.Type()),
+		HumanID:           op.HumanID(),
+		ExecutionProgress: execProgress,
+	}
+
+	if resOp, ok := op.(resourceOperation); ok {
+		res := resOp.ResourceID()
+		progress.ResourceName = res.Name()
+		progress.ResourceNamespace = res.Namespace()
+		progress.ResourceGVK = res.GroupVersionKind()
+	}
+
+	return progress
+}
@@ -0,0 +1,179 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const planStateConfigMapDataKey = "state"
+
+type PlanState struct {
+	ChartContentHash    string   `json:"chartContentHash"`
+	CompletedOperations []string `json:"completedOperations"`
+}
+
+// MarkResumed marks operations completed by the interrupted deploy as completed, so that they are
+// not executed again. Returns the number of operations of this plan that were marked.
+func (p *Plan) MarkResumed(state *PlanState) int {
+	var resumed int
+	for _, opID := range state.CompletedOperations {
+		if _, err := p.graph.Vertex(opID); err != nil {
+			continue
+		}
+
+		p.resumedOpsIDs[opID] = struct{}{}
+		resumed++
+	}
+
+	return resumed
+}
+
+var _ operation.Operation = (*resumedOperation)(nil)
+
+type resumedOperation struct {
+	operation.Operation
+}
+
+func (o *resumedOperation) Execute(ctx context.Context) error {
+	return nil
+}
+
+func (o *resumedOperation) Status() operation.Status {
+	return operation.StatusCompleted
+}
+
+func NewPlanStateStore(releaseName, releaseNamespace, chartContentHash string, staticClient kubernetes.Interface) *PlanStateStore {
+	return &PlanStateStore{
+		configMapName:    "nelm.plan-state." + releaseName,
+		releaseNamespace: releaseNamespace,
+		chartContentHash: chartContentHash,
+		staticClient:     staticClient,
+		completedOpsIDs:  map[string]struct{}{},
+	}
+}
+
+// PlanStateStore persists IDs of completed operations of the deploy plan in a ConfigMap in the
+// release namespace, so that an interrupted deploy can be resumed.
+type PlanStateStore struct {
+	configMapName    string
+	releaseNamespace string
+	chartContentHash string
+	staticClient     kubernetes.Interface
+
+	mu              sync.Mutex
+	completedOpsIDs map[string]struct{}
+	saved           bool
+}
+
+// Load returns the state saved by the interrupted deploy. Resuming is refused if the chart or the
+// values changed since then.
+func (s *PlanStateStore) Load(ctx context.Context) (state *PlanState, found bool, err error) {
+	cm, err := s.staticClient.CoreV1().ConfigMaps(s.releaseNamespace).Get(ctx, s.configMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("error getting plan state configmap %q: %w", s.configMapName, err)
+	}
+
+	state = &PlanState{}
+	if err := json.Unmarshal([]byte(cm.Data[planStateConfigMapDataKey]), state); err != nil {
+		return nil, false, fmt.Errorf("error unmarshaling plan state from configmap %q: %w", s.configMapName, err)
+	}
+
+	if state.ChartContentHash != s.chartContentHash {
+		return nil, false, fmt.Errorf("refusing to resume: chart content or values changed since the interrupted deploy (saved content hash %q, current content hash %q); delete configmap %q in namespace %q to deploy from scratch", state.ChartContentHash, s.chartContentHash, s.configMapName, s.releaseNamespace)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, opID := range state.CompletedOperations {
+		s.completedOpsIDs[opID] = struct{}{}
+	}
+	s.saved = true
+
+	return state, true, nil
+}
+
+// Record saves the state after the operation is completed. Failing to save the state doesn't fail
+// the deploy.
+func (s *PlanStateStore) Record(ctx context.Context, op operation.Operation) {
+	if op.Empty() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completedOpsIDs[op.ID()] = struct{}{}
+
+	if err := s.save(ctx); err != nil {
+		log.Plan.Warn(ctx, "Warning: unable to save plan state after operation %q: %s", op.ID(), err)
+	}
+}
+
+func (s *PlanStateStore) Delete(ctx context.Context) error {
+	if err := s.staticClient.CoreV1().ConfigMaps(s.releaseNamespace).Delete(ctx, s.configMapName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("error deleting plan state configmap %q: %w", s.configMapName, err)
+	}
+
+	return nil
+}
+
+func (s *PlanStateStore) save(ctx context.Context) error {
+	state := &PlanState{
+		ChartContentHash: s.chartContentHash,
+	}
+
+	for opID := range s.completedOpsIDs {
+		state.CompletedOperations = append(state.CompletedOperations, opID)
+	}
+	sort.Strings(state.CompletedOperations)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshaling plan state: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.configMapName,
+			Namespace: s.releaseNamespace,
+		},
+		Data: map[string]string{
+			planStateConfigMapDataKey: string(data),
+		},
+	}
+
+	// Save the state even if the deploy is being interrupted right now.
+	ctx = context.WithoutCancel(ctx)
+
+	if !s.saved {
+		if _, err := s.staticClient.CoreV1().ConfigMaps(s.releaseNamespace).Create(ctx, cm, metav1.CreateOptions{}); err == nil {
+			s.saved = true
+			return nil
+		} else if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("error creating plan state configmap %q: %w", s.configMapName, err)
+		}
+	}
+
+	if _, err := s.staticClient.CoreV1().ConfigMaps(s.releaseNamespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error updating plan state configmap %q: %w", s.configMapName, err)
+	}
+	s.saved = true
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package plan_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+var _ = Describe("plan", func() {
+	// Stages with dependencies, which leave many valid topological orders.
+	opsIDs := []string{"init", "crds/start", "crds/end", "pre/start", "pre/end", "main/start", "main/end", "post", "cleanup-a", "cleanup-b", "cleanup-c"}
+	dependencies := [][2]string{
+		{"init", "crds/start"},
+		{"crds/start", "crds/end"},
+		{"crds/end", "pre/start"},
+		{"pre/start", "pre/end"},
+		{"pre/end", "main/start"},
+		{"main/start", "main/end"},
+		{"main/end", "post"},
+		{"init", "cleanup-c"},
+		{"init", "cleanup-a"},
+		{"cleanup-a", "cleanup-b"},
+	}
+
+	buildPlan := func(rnd *rand.Rand) *plan.Plan {
+		ids := append([]string{}, opsIDs...)
+		rnd.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+		deps := append([][2]string{}, dependencies...)
+		rnd.Shuffle(len(deps), func(i, j int) { deps[i], deps[j] = deps[j], deps[i] })
+
+		p := plan.NewPlan()
+		for _, id := range ids {
+			p.AddOperation(operation.NewStageOperation(id))
+		}
+
+		for _, dep := range deps {
+			Expect(p.AddDependency(dep[0], dep[1])).To(Succeed())
+		}
+
+		return p
+	}
+
+	operationsIDs := func(p *plan.Plan) []string {
+		ops, found, err := p.Operations()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+
+		return lo.Map(ops, func(op operation.Operation, _ int) string {
+			return op.ID()
+		})
+	}
+
+	It("returns operations and DOT graphs in the same order across constructions", func() {
+		rnd := rand.New(rand.NewSource(GinkgoRandomSeed()))
+
+		firstPlan := buildPlan(rnd)
+		expectedIDs := operationsIDs(firstPlan)
+		expectedDOT, err := firstPlan.DOT()
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 100; i++ {
+			p := buildPlan(rnd)
+
+			Expect(operationsIDs(p)).To(Equal(expectedIDs))
+
+			dot, err := p.DOT()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(dot)).To(Equal(string(expectedDOT)))
+		}
+	})
+
+	It("returns operations in topological order", func() {
+		ids := operationsIDs(buildPlan(rand.New(rand.NewSource(GinkgoRandomSeed()))))
+
+		for _, dep := range dependencies {
+			Expect(lo.IndexOf(ids, dep[0])).To(BeNumerically("<", lo.IndexOf(ids, dep[1])), "%s must go before %s", dep[0], dep[1])
+		}
+	})
+})
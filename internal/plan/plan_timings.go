@@ -0,0 +1,143 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+const timingsReportSlowestOpsCount = 10
+
+type OperationTiming struct {
+	OperationID string
+	HumanID     string
+	Started     time.Time
+	Finished    time.Time
+}
+
+func (t *OperationTiming) Duration() time.Duration {
+	if t.Finished.IsZero() {
+		return 0
+	}
+
+	return t.Finished.Sub(t.Started)
+}
+
+type TimingsReport struct {
+	Total time.Duration
+	// Up to 10 slowest operations, slowest first.
+	Slowest []*OperationTiming
+	// The chain of operations which determined the total duration, in the order of execution.
+	CriticalPath []*OperationTiming
+}
+
+func (p *Plan) recordOperationStarted(opID string) {
+	p.timingsMu.Lock()
+	defer p.timingsMu.Unlock()
+
+	p.timings[opID] = &OperationTiming{
+		OperationID: opID,
+		Started:     time.Now(),
+	}
+}
+
+func (p *Plan) recordOperationFinished(opID string) {
+	p.timingsMu.Lock()
+	defer p.timingsMu.Unlock()
+
+	if timing, found := p.timings[opID]; found {
+		timing.Finished = time.Now()
+	}
+}
+
+// OperationTiming returns when the operation was started and finished during the plan execution.
+func (p *Plan) OperationTiming(opID string) (timing OperationTiming, found bool) {
+	p.timingsMu.Lock()
+	defer p.timingsMu.Unlock()
+
+	t, found := p.timings[opID]
+	if !found {
+		return OperationTiming{}, false
+	}
+
+	return *t, true
+}
+
+// TimingsReport returns timings of the executed operations. Stage operations are not included.
+func (p *Plan) TimingsReport() (*TimingsReport, error) {
+	predMap, err := p.graph.PredecessorMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting predecessor map: %w", err)
+	}
+
+	timings := map[string]*OperationTiming{}
+	for opID := range predMap {
+		timing, found := p.OperationTiming(opID)
+		if !found || timing.Finished.IsZero() {
+			continue
+		}
+
+		timing.HumanID = lo.Must(p.Operation(opID)).HumanID()
+		timings[opID] = &timing
+	}
+
+	report := &TimingsReport{}
+	if len(timings) == 0 {
+		return report, nil
+	}
+
+	var first, last *OperationTiming
+	var worthyTimings []*OperationTiming
+	for opID, timing := range timings {
+		if first == nil || timing.Started.Before(first.Started) {
+			first = timing
+		}
+
+		if last == nil || timing.Finished.After(last.Finished) || (timing.Finished.Equal(last.Finished) && timing.OperationID < last.OperationID) {
+			last = timing
+		}
+
+		if lo.Must(p.Operation(opID)).Type() != operation.TypeStageOperation {
+			worthyTimings = append(worthyTimings, timing)
+		}
+	}
+
+	report.Total = last.Finished.Sub(first.Started)
+
+	sort.Slice(worthyTimings, func(i, j int) bool {
+		if worthyTimings[i].Duration() != worthyTimings[j].Duration() {
+			return worthyTimings[i].Duration() > worthyTimings[j].Duration()
+		}
+
+		return worthyTimings[i].OperationID < worthyTimings[j].OperationID
+	})
+	report.Slowest = lo.Subset(worthyTimings, 0, timingsReportSlowestOpsCount)
+
+	// Walk back from the operation finished last, each time following the predecessor which
+	// finished last, since it's the one the operation was waiting for.
+	for current := last; current != nil; {
+		if lo.Must(p.Operation(current.OperationID)).Type() != operation.TypeStageOperation {
+			report.CriticalPath = append([]*OperationTiming{current}, report.CriticalPath...)
+		}
+
+		var next *OperationTiming
+		for predOpID := range predMap[current.OperationID] {
+			pred, found := timings[predOpID]
+			if !found {
+				continue
+			}
+
+			if next == nil || pred.Finished.After(next.Finished) || (pred.Finished.Equal(next.Finished) && pred.OperationID < next.OperationID) {
+				next = pred
+			}
+		}
+
+		current = next
+	}
+
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+// DependencyCycleError means that the dependency can't be satisfied, because the operation it
+// should be added to is already required to run before the operation it depends on.
+type DependencyCycleError struct {
+	// Operations of the cycle, the first one is repeated at the end.
+	OperationsHumanIDs []string
+	// Reasons[i] explains the dependency of OperationsHumanIDs[i+1] on OperationsHumanIDs[i].
+	Reasons []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "dependency cycle: %s", strings.Join(e.OperationsHumanIDs, " → "))
+
+	for i, reason := range e.Reasons {
+		if reason == "" {
+			continue
+		}
+
+		fmt.Fprintf(b, "\n  %s → %s: %s", e.OperationsHumanIDs[i], e.OperationsHumanIDs[i+1], reason)
+	}
+
+	return b.String()
+}
+
+func (e *DependencyCycleError) Unwrap() error {
+	return graph.ErrEdgeCreatesCycle
+}
+
+// AddUnsatisfiableDependency records the dependency which couldn't be added to the plan because of
+// the cycle. Validate() will fail because of it.
+func (p *Plan) AddUnsatisfiableDependency(err *DependencyCycleError) {
+	p.unsatisfiableDependencies = append(p.unsatisfiableDependencies, err)
+}
+
+// Validate checks that the plan can be executed. Must be called before executing the plan.
+func (p *Plan) Validate() error {
+	var errs []error
+	for _, err := range p.unsatisfiableDependencies {
+		errs = append(errs, err)
+	}
+
+	if _, err := graph.TopologicalSort(p.graph); err != nil {
+		errs = append(errs, fmt.Errorf("error sorting plan operations topologically: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("plan can't be executed: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (p *Plan) newDependencyCycleError(fromOpID, toOpID, reason string) *DependencyCycleError {
+	// The edge creates a cycle, so there must be a path back from toOpID to fromOpID.
+	pathBack, err := graph.ShortestPath(p.graph, toOpID, fromOpID)
+	if err != nil {
+		pathBack = []string{toOpID, fromOpID}
+	}
+
+	cycle := append([]string{fromOpID}, pathBack...)
+
+	cycleErr := &DependencyCycleError{}
+	for i, opID := range cycle {
+		if i > 0 && isStageOpID(opID) && i < len(cycle)-1 {
+			continue
+		}
+
+		cycleErr.OperationsHumanIDs = append(cycleErr.OperationsHumanIDs, p.operationHumanID(opID))
+	}
+
+	// Stages are not interesting by themselves, so all edges from one non-stage operation to the next
+	// one through the stages are squashed into one.
+	var stagesPassed []string
+	var reasons []string
+	for i := 1; i < len(cycle); i++ {
+		if i == 1 {
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		} else if r := p.dependencyReasons[dependencyKey(cycle[i-1], cycle[i])]; r != "" {
+			reasons = append(reasons, r)
+		}
+
+		if isStageOpID(cycle[i]) && i < len(cycle)-1 {
+			stagesPassed = append(stagesPassed, cycle[i])
+			continue
+		}
+
+		if len(stagesPassed) > 0 {
+			reasons = append(reasons, fmt.Sprintf("deploy stages order: %s", strings.Join(stagesPassed, " → ")))
+		}
+
+		cycleErr.Reasons = append(cycleErr.Reasons, strings.Join(lo.Uniq(reasons), "; "))
+		stagesPassed = nil
+		reasons = nil
+	}
+
+	return cycleErr
+}
+
+func (p *Plan) operationHumanID(opID string) string {
+	if op, found := p.Operation("%s", opID); found {
+		return op.HumanID()
+	}
+
+	return opID
+}
+
+func isStageOpID(opID string) bool {
+	return strings.HasPrefix(opID, operation.TypeStageOperation+"/")
+}
+
+func dependencyKey(fromOpID, toOpID string) string {
+	return fromOpID + " -> " + toOpID
+}
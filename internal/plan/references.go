@@ -0,0 +1,237 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+type ReferencedResource struct {
+	ResourceID   *id.ResourceID
+	Unstructured *unstructured.Unstructured
+}
+
+type CheckReferencesOptions struct {
+	Strict bool
+}
+
+// CheckReferences verifies that ServiceAccounts, image pull Secrets, ConfigMaps and Secrets
+// referenced by pod templates of workloads either exist in the cluster or are deployed with the
+// same release. Missing references are reported as warnings, or as an error in strict mode.
+func CheckReferences(ctx context.Context, resources []*ReferencedResource, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper, opts CheckReferencesOptions) error {
+	deployed := map[string]bool{}
+	for _, res := range resources {
+		deployed[referenceKey(res.ResourceID.GroupVersionKind().Kind, res.ResourceID.Namespace(), res.ResourceID.Name())] = true
+	}
+
+	var errs []error
+	for _, res := range resources {
+		podSpec, found := podSpecOfWorkload(res.Unstructured)
+		if !found {
+			continue
+		}
+
+		var missing []string
+		for _, ref := range podSpecReferences(podSpec) {
+			if deployed[referenceKey(ref.kind, res.ResourceID.Namespace(), ref.name)] {
+				continue
+			}
+
+			refID := id.NewResourceID(ref.name, res.ResourceID.Namespace(), schema.GroupVersionKind{Version: "v1", Kind: ref.kind}, id.ResourceIDOptions{
+				Mapper: mapper,
+			})
+
+			_, found, err := kubeClient.GetOrNil(ctx, refID, kube.KubeClientGetOptions{
+				TryCache: true,
+			})
+			if err != nil {
+				if opts.Strict || !isUncheckableReferenceErr(err) {
+					return fmt.Errorf("error getting %s referenced by %q: %w", ref, res.ResourceID.HumanID(), err)
+				}
+
+				log.Plan.Warn(ctx, "Warning: Can't check %s referenced by %q: %s", ref, res.ResourceID.HumanID(), err)
+
+				continue
+			}
+
+			if !found {
+				missing = append(missing, ref.String())
+			}
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		err := fmt.Errorf("resource %q references missing %s, which are neither in the cluster nor in the release", res.ResourceID.HumanID(), strings.Join(missing, ", "))
+		if opts.Strict {
+			errs = append(errs, err)
+		} else {
+			log.Plan.Warn(ctx, "Warning: %s", util.Capitalize(err.Error()))
+		}
+	}
+
+	return util.Multierrorf("references check failed", errs)
+}
+
+// The reference can't be checked, e.g. because of RBAC, but this doesn't mean that it's broken.
+func isUncheckableReferenceErr(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) || apierrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
+
+type podSpecReference struct {
+	kind string
+	name string
+}
+
+func (r podSpecReference) String() string {
+	return fmt.Sprintf("%s %q", r.kind, r.name)
+}
+
+func referenceKey(kind, namespace, name string) string {
+	return kind + ":" + namespace + ":" + name
+}
+
+func podSpecOfWorkload(unstruct *unstructured.Unstructured) (map[string]interface{}, bool) {
+	gvk := unstruct.GroupVersionKind()
+
+	var fields []string
+	switch gvk.GroupKind() {
+	case schema.GroupKind{Kind: "Pod"}:
+		fields = []string{"spec"}
+	case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+		fields = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		schema.GroupKind{Group: "apps", Kind: "StatefulSet"},
+		schema.GroupKind{Group: "apps", Kind: "DaemonSet"},
+		schema.GroupKind{Group: "apps", Kind: "ReplicaSet"},
+		schema.GroupKind{Kind: "ReplicationController"},
+		schema.GroupKind{Group: "batch", Kind: "Job"}:
+		fields = []string{"spec", "template", "spec"}
+	default:
+		return nil, false
+	}
+
+	podSpec, found, err := unstructured.NestedMap(unstruct.Object, fields...)
+	if err != nil || !found {
+		return nil, false
+	}
+
+	return podSpec, true
+}
+
+func podSpecReferences(podSpec map[string]interface{}) []podSpecReference {
+	refs := map[podSpecReference]struct{}{}
+	add := func(kind, name string) {
+		if name != "" {
+			refs[podSpecReference{kind: kind, name: name}] = struct{}{}
+		}
+	}
+
+	// The default ServiceAccount is created automatically in every namespace.
+	if name, _, _ := unstructured.NestedString(podSpec, "serviceAccountName"); name != "default" {
+		add("ServiceAccount", name)
+	}
+
+	for _, secret := range nestedMaps(podSpec, "imagePullSecrets") {
+		name, _, _ := unstructured.NestedString(secret, "name")
+		add("Secret", name)
+	}
+
+	for _, volume := range nestedMaps(podSpec, "volumes") {
+		if name, optional := referenceFrom(volume, "name", "configMap"); !optional {
+			add("ConfigMap", name)
+		}
+
+		if name, optional := referenceFrom(volume, "secretName", "secret"); !optional {
+			add("Secret", name)
+		}
+
+		for _, source := range nestedMaps(volume, "projected", "sources") {
+			if name, optional := referenceFrom(source, "name", "configMap"); !optional {
+				add("ConfigMap", name)
+			}
+
+			if name, optional := referenceFrom(source, "name", "secret"); !optional {
+				add("Secret", name)
+			}
+		}
+	}
+
+	containers := append(nestedMaps(podSpec, "initContainers"), nestedMaps(podSpec, "containers")...)
+	for _, container := range containers {
+		for _, envFrom := range nestedMaps(container, "envFrom") {
+			if name, optional := referenceFrom(envFrom, "name", "configMapRef"); !optional {
+				add("ConfigMap", name)
+			}
+
+			if name, optional := referenceFrom(envFrom, "name", "secretRef"); !optional {
+				add("Secret", name)
+			}
+		}
+
+		for _, env := range nestedMaps(container, "env") {
+			if name, optional := referenceFrom(env, "name", "valueFrom", "configMapKeyRef"); !optional {
+				add("ConfigMap", name)
+			}
+
+			if name, optional := referenceFrom(env, "name", "valueFrom", "secretKeyRef"); !optional {
+				add("Secret", name)
+			}
+		}
+	}
+
+	result := make([]podSpecReference, 0, len(refs))
+	for ref := range refs {
+		result = append(result, ref)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].kind != result[j].kind {
+			return result[i].kind < result[j].kind
+		}
+
+		return result[i].name < result[j].name
+	})
+
+	return result
+}
+
+func referenceFrom(obj map[string]interface{}, nameField string, fields ...string) (name string, optional bool) {
+	ref, found, err := unstructured.NestedMap(obj, fields...)
+	if err != nil || !found {
+		return "", true
+	}
+
+	optional, _, _ = unstructured.NestedBool(ref, "optional")
+	name, _, _ = unstructured.NestedString(ref, nameField)
+
+	return name, optional
+}
+
+func nestedMaps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	slice, found, err := unstructured.NestedSlice(obj, fields...)
+	if err != nil || !found {
+		return nil
+	}
+
+	var result []map[string]interface{}
+	for _, item := range slice {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
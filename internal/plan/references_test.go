@@ -0,0 +1,94 @@
+package plan_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+var _ = Describe("references check", func() {
+	var (
+		ctx     context.Context
+		logs    *bytes.Buffer
+		cluster *fake.Cluster
+	)
+
+	BeforeEach(func() {
+		logs = &bytes.Buffer{}
+		ctx = logboek.NewContext(context.Background(), logboek.NewLogger(logs, logs))
+		cluster = fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: present, namespace: app-ns}}`))
+	})
+
+	failSecretGets := func(err error) {
+		cluster.Dynamic.PrependReactor("get", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, err
+		})
+	}
+
+	check := func(strict bool) error {
+		deployment := unstructFromYAML(`
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: app-ns}
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        envFrom:
+        - configMapRef: {name: present}
+        - secretRef: {name: credentials}
+`)
+
+		return plan.CheckReferences(ctx, []*plan.ReferencedResource{
+			{
+				ResourceID:   id.NewResourceIDFromUnstruct(deployment, id.ResourceIDOptions{Mapper: cluster.Mapper}),
+				Unstructured: deployment,
+			},
+		}, cluster.KubeClient, cluster.Mapper, plan.CheckReferencesOptions{Strict: strict})
+	}
+
+	secrets := schema.GroupResource{Resource: "secrets"}
+
+	DescribeTable("warns instead of failing if a reference can't be checked in non-strict mode",
+		func(err error) {
+			failSecretGets(err)
+
+			Expect(check(false)).To(Succeed())
+			Expect(logs.String()).To(ContainSubstring(`Can't check Secret "credentials" referenced by "app-ns/Deployment/web"`))
+		},
+		Entry("forbidden", apierrors.NewForbidden(secrets, "credentials", errors.New("RBAC denied"))),
+		Entry("unauthorized", apierrors.NewUnauthorized("token expired")),
+	)
+
+	It("fails if a reference can't be checked in strict mode", func() {
+		failSecretGets(apierrors.NewForbidden(secrets, "credentials", errors.New("RBAC denied")))
+
+		Expect(check(true)).To(MatchError(ContainSubstring(`error getting Secret "credentials" referenced by "app-ns/Deployment/web"`)))
+	})
+
+	It("fails on other errors in non-strict mode", func() {
+		failSecretGets(apierrors.NewInternalError(errors.New("etcd unavailable")))
+
+		Expect(check(false)).To(MatchError(ContainSubstring(`error getting Secret "credentials" referenced by "app-ns/Deployment/web"`)))
+	})
+
+	It("warns about missing references in non-strict mode and fails in strict mode", func() {
+		Expect(check(false)).To(Succeed())
+		Expect(logs.String()).To(ContainSubstring(`references missing Secret "credentials"`))
+
+		Expect(check(true)).To(MatchError(ContainSubstring(`references missing Secret "credentials"`)))
+	})
+})
@@ -11,6 +11,8 @@ import (
 
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func BuildDeployableResourceInfos(
@@ -24,6 +26,7 @@ func BuildDeployableResourceInfos(
 	kubeClient kube.KubeClienter,
 	mapper meta.ResettableRESTMapper,
 	parallelism int,
+	opts BuildDeployableResourceInfosOptions,
 ) (
 	releaseNamespaceInfo *DeployableReleaseNamespaceInfo,
 	standaloneCRDsInfos []*DeployableStandaloneCRDInfo,
@@ -34,6 +37,29 @@ func BuildDeployableResourceInfos(
 ) {
 	totalResourcesCount := len(standaloneCRDs) + len(hookResources) + len(generalResources) + len(prevReleaseGeneralResources)
 
+	prefetchResources := make([]*id.ResourceID, 0, totalResourcesCount)
+	for _, res := range standaloneCRDs {
+		prefetchResources = append(prefetchResources, res.ResourceID)
+	}
+	for _, res := range hookResources {
+		prefetchResources = append(prefetchResources, res.ResourceID)
+	}
+	for _, res := range generalResources {
+		prefetchResources = append(prefetchResources, res.ResourceID)
+	}
+	for _, res := range prevReleaseGeneralResources {
+		prefetchResources = append(prefetchResources, res.ResourceID)
+	}
+
+	// Not found resources are cached too, other errors will be handled when constructing the
+	// resource infos.
+	if _, err := kubeClient.GetMany(ctx, prefetchResources, kube.KubeClientGetManyOptions{
+		Concurrency: parallelism,
+		TryCache:    true,
+	}); err != nil {
+		log.Plan.Debug(ctx, "Prefetching resources finished with errors: %s", err)
+	}
+
 	routines := lo.Max([]int{len(standaloneCRDs) / lo.Max([]int{totalResourcesCount, 1}) * parallelism, 1})
 	standaloneCRDsPool := pool.NewWithResults[*DeployableStandaloneCRDInfo]().WithContext(ctx).WithMaxGoroutines(routines).WithCancelOnError().WithFirstError()
 	for _, res := range standaloneCRDs {
@@ -52,7 +78,10 @@ func BuildDeployableResourceInfos(
 	for _, res := range hookResources {
 		res := res
 		hookResourcesPool.Go(func(ctx context.Context) (*DeployableHookResourceInfo, error) {
-			if info, err := NewDeployableHookResourceInfo(ctx, res, releaseNamespace, kubeClient, mapper); err != nil {
+			if info, err := NewDeployableHookResourceInfo(ctx, res, releaseNamespace, kubeClient, mapper, DeployableHookResourceInfoOptions{
+				DryRunCreate: opts.DryRunNewResources,
+				ForceReplace: opts.ForceReplace,
+			}); err != nil {
 				return nil, fmt.Errorf("error constructing hook resource info: %w", err)
 			} else {
 				return info, nil
@@ -65,7 +94,10 @@ func BuildDeployableResourceInfos(
 	for _, res := range generalResources {
 		res := res
 		generalResourcesPool.Go(func(ctx context.Context) (*DeployableGeneralResourceInfo, error) {
-			if info, err := NewDeployableGeneralResourceInfo(ctx, res, releaseNamespace, kubeClient, mapper); err != nil {
+			if info, err := NewDeployableGeneralResourceInfo(ctx, res, releaseNamespace, kubeClient, mapper, DeployableGeneralResourceInfoOptions{
+				DryRunCreate: opts.DryRunNewResources,
+				ForceReplace: opts.ForceReplace,
+			}); err != nil {
 				return nil, fmt.Errorf("error constructing general resource info: %w", err)
 			} else {
 				return info, nil
@@ -106,6 +138,15 @@ func BuildDeployableResourceInfos(
 		return nil, nil, nil, nil, nil, fmt.Errorf("error waiting for general resources pool: %w", err)
 	}
 
+	if opts.ReleaseNamespace != nil {
+		releaseNamespaceInfo, err = NewDeployableReleaseNamespaceInfo(ctx, opts.ReleaseNamespace, releaseName, kubeClient, mapper, DeployableReleaseNamespaceInfoOptions{
+			Adopt: opts.AdoptReleaseNamespace,
+		})
+		if err != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("error constructing release namespace info: %w", err)
+		}
+	}
+
 	sort.SliceStable(standaloneCRDsInfos, func(i, j int) bool {
 		return resource.ResourceIDsSortHandler(standaloneCRDsInfos[i].ResourceID, standaloneCRDsInfos[j].ResourceID)
 	})
@@ -124,3 +165,11 @@ func BuildDeployableResourceInfos(
 
 	return releaseNamespaceInfo, standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos, prevReleaseGeneralResourceInfos, nil
 }
+
+type BuildDeployableResourceInfosOptions struct {
+	DryRunNewResources bool
+	ForceReplace       bool
+	// If set, the release namespace info is built too.
+	ReleaseNamespace      *resource.ReleaseNamespace
+	AdoptReleaseNamespace bool
+}
@@ -12,21 +12,38 @@ import (
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
-func NewDeployableGeneralResourceInfo(ctx context.Context, res *resource.GeneralResource, releaseNamespace string, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper) (*DeployableGeneralResourceInfo, error) {
-	getObj, getErr := kubeClient.Get(ctx, res.ResourceID, kube.KubeClientGetOptions{
+func NewDeployableGeneralResourceInfo(ctx context.Context, res *resource.GeneralResource, releaseNamespace string, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper, opts DeployableGeneralResourceInfoOptions) (*DeployableGeneralResourceInfo, error) {
+	ignoredFields, ignoreFieldsErrs := res.IgnoreFields()
+	for _, err := range ignoreFieldsErrs {
+		log.Plan.Warn(ctx, "Warning: %s, the path is skipped for resource %q", util.Capitalize(err.Error()), res.HumanID())
+	}
+
+	getObj, found, getErr := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{
 		TryCache: true,
 	})
-	if getErr != nil {
-		if isNotFoundErr(getErr) || isNoSuchKindErr(getErr) {
-			return &DeployableGeneralResourceInfo{
-				ResourceID: res.ResourceID,
-				resource:   res,
-			}, nil
-		} else {
-			return nil, fmt.Errorf("error getting general resource: %w", getErr)
+	if getErr != nil && !isNoSuchKindErr(getErr) {
+		return nil, fmt.Errorf("error getting general resource: %w", getErr)
+	}
+
+	if !found {
+		var dryCreateErr error
+		if getErr == nil && opts.DryRunCreate {
+			if _, err := kubeClient.Create(ctx, res.ResourceID, res.Unstructured(), kube.KubeClientCreateOptions{
+				DryRun: true,
+			}); err != nil && !isNotFoundErr(err) && !isNoSuchKindErr(err) {
+				dryCreateErr = log.RedactSensitiveError(err, res.Unstructured().Object)
+				log.Plan.Debug(ctx, "Dry creating general resource %q failed: %s", res.HumanID(), dryCreateErr)
+			}
 		}
+
+		return &DeployableGeneralResourceInfo{
+			ResourceID:   res.ResourceID,
+			resource:     res,
+			dryCreateErr: dryCreateErr,
+		}, nil
 	}
 	getResource := resource.NewRemoteResource(getObj, resource.RemoteResourceOptions{
 		FallbackNamespace: releaseNamespace,
@@ -37,11 +54,14 @@ func NewDeployableGeneralResourceInfo(ctx context.Context, res *resource.General
 		return nil, fmt.Errorf("error fixing managed fields for resource %q: %w", res.HumanID(), err)
 	}
 
+	res = res.WithIgnoredFieldsFrom(getObj)
+
 	dryApplyObj, dryApplyErr := kubeClient.Apply(ctx, res.ResourceID, res.Unstructured(), kube.KubeClientApplyOptions{
 		DryRun: true,
 	})
-	if dryApplyErr != nil && isImmutableErr(dryApplyErr) && !res.Recreate() {
-		return nil, fmt.Errorf("error dry applying general resource: %w", dryApplyErr)
+	replace := isImmutableErr(dryApplyErr) && (opts.ForceReplace || res.ReplaceOnImmutableChange())
+	if dryApplyErr != nil && isImmutableErr(dryApplyErr) && !res.Recreate() && !replace {
+		return nil, fmt.Errorf("error dry applying general resource, set annotation \"werf.io/replace-on-immutable-change: true\" to replace it instead: %w", log.RedactSensitiveError(dryApplyErr, res.Unstructured().Object))
 	}
 	var dryApplyResource *resource.RemoteResource
 	if dryApplyObj != nil {
@@ -61,7 +81,7 @@ func NewDeployableGeneralResourceInfo(ctx context.Context, res *resource.General
 			upToDateStatus = resource.UpToDateStatusUnknown
 		}
 	} else {
-		different, err := util.ResourcesReallyDiffer(getResource.Unstructured(), dryApplyResource.Unstructured())
+		different, err := util.ResourcesReallyDiffer(resource.WithoutFields(getResource.Unstructured(), ignoredFields), resource.WithoutFields(dryApplyResource.Unstructured(), ignoredFields))
 		if err != nil {
 			return nil, fmt.Errorf("error diffing live and dry-apply versions of resource %q: %w", res.HumanID(), err)
 		}
@@ -81,9 +101,15 @@ func NewDeployableGeneralResourceInfo(ctx context.Context, res *resource.General
 		dryApplyErr:      dryApplyErr,
 		exists:           getResource != nil,
 		upToDate:         upToDateStatus,
+		replace:          replace,
 	}, nil
 }
 
+type DeployableGeneralResourceInfoOptions struct {
+	DryRunCreate bool
+	ForceReplace bool
+}
+
 type DeployableGeneralResourceInfo struct {
 	*id.ResourceID
 
@@ -92,9 +118,12 @@ type DeployableGeneralResourceInfo struct {
 	getResource      *resource.RemoteResource
 	dryApplyResource *resource.RemoteResource
 	dryApplyErr      error
+	dryCreateErr     error
 
 	exists   bool
 	upToDate resource.UpToDateStatus
+	replace  bool
+	adopt    bool
 }
 
 func (i *DeployableGeneralResourceInfo) Resource() *resource.GeneralResource {
@@ -109,20 +138,31 @@ func (i *DeployableGeneralResourceInfo) DryApplyResource() *resource.RemoteResou
 	return i.dryApplyResource
 }
 
+// DryCreateErr is the error the server returned when the resource was created in dry-run mode.
+func (i *DeployableGeneralResourceInfo) DryCreateErr() error {
+	return i.dryCreateErr
+}
+
 func (i *DeployableGeneralResourceInfo) ShouldCreate() bool {
 	return !i.exists
 }
 
 func (i *DeployableGeneralResourceInfo) ShouldRecreate() bool {
-	return i.exists && i.resource.Recreate()
+	return i.exists && (i.resource.Recreate() || i.replace)
 }
 
 func (i *DeployableGeneralResourceInfo) ShouldUpdate() bool {
-	return i.exists && i.upToDate == resource.UpToDateStatusNo && !i.resource.Recreate()
+	return i.exists && i.upToDate == resource.UpToDateStatusNo && !i.resource.Recreate() && !i.replace
 }
 
 func (i *DeployableGeneralResourceInfo) ShouldApply() bool {
-	return i.exists && i.upToDate == resource.UpToDateStatusUnknown && !i.resource.Recreate()
+	return i.exists && i.upToDate == resource.UpToDateStatusUnknown && !i.resource.Recreate() && !i.replace
+}
+
+// ShouldAdopt returns true if the resource exists in the cluster, but is not owned by the release
+// yet, and the release will take ownership of it.
+func (i *DeployableGeneralResourceInfo) ShouldAdopt() bool {
+	return i.exists && i.adopt
 }
 
 func (i *DeployableGeneralResourceInfo) ShouldCleanup(releaseName, releaseNamespace string) bool {
@@ -162,7 +202,7 @@ func (i *DeployableGeneralResourceInfo) ForceReplicas() (replicas int, set bool)
 
 func (i *DeployableGeneralResourceInfo) LiveUID() (uid types.UID, found bool) {
 	if !i.exists {
-		return types.UID(0), false
+		return "", false
 	}
 
 	return i.getResource.Unstructured().GetUID(), true
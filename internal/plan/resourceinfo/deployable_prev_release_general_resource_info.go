@@ -14,18 +14,18 @@ import (
 )
 
 func NewDeployablePrevReleaseGeneralResourceInfo(ctx context.Context, res *resource.GeneralResource, releaseNamespace string, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper) (*DeployablePrevReleaseGeneralResourceInfo, error) {
-	getObj, getErr := kubeClient.Get(ctx, res.ResourceID, kube.KubeClientGetOptions{
+	getObj, found, getErr := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{
 		TryCache: true,
 	})
-	if getErr != nil {
-		if isNotFoundErr(getErr) || isNoSuchKindErr(getErr) {
-			return &DeployablePrevReleaseGeneralResourceInfo{
-				ResourceID: res.ResourceID,
-				resource:   res,
-			}, nil
-		} else {
-			return nil, fmt.Errorf("error getting previous release general resource: %w", getErr)
-		}
+	if getErr != nil && !isNoSuchKindErr(getErr) {
+		return nil, fmt.Errorf("error getting previous release general resource: %w", getErr)
+	}
+
+	if !found {
+		return &DeployablePrevReleaseGeneralResourceInfo{
+			ResourceID: res.ResourceID,
+			resource:   res,
+		}, nil
 	}
 	getResource := resource.NewRemoteResource(getObj, resource.RemoteResourceOptions{
 		FallbackNamespace: releaseNamespace,
@@ -72,3 +72,21 @@ func (i *DeployablePrevReleaseGeneralResourceInfo) ShouldDelete(curReleaseExisti
 
 	return !lo.Contains(curReleaseExistingResourcesUIDs, i.getResource.Unstructured().GetUID())
 }
+
+// KeepReason returns why the resource, which is still in the cluster but not in the current
+// release, is not deleted.
+func (i *DeployablePrevReleaseGeneralResourceInfo) KeepReason(curReleaseExistingResourcesUIDs []types.UID, releaseName, releaseNamespace string) (reason string, keep bool) {
+	if !i.exists || lo.Contains(curReleaseExistingResourcesUIDs, i.getResource.Unstructured().GetUID()) {
+		return "", false
+	}
+
+	if !i.ShouldKeepOnDelete(releaseName, releaseNamespace) {
+		return "", false
+	}
+
+	if !i.resource.KeepOnDelete() && i.getResource.Orphaned(releaseName, releaseNamespace) {
+		return "not owned by the release anymore", true
+	}
+
+	return "resource policy is keep", true
+}
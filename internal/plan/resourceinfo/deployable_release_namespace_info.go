@@ -12,25 +12,41 @@ import (
 	"github.com/werf/nelm/internal/util"
 )
 
-func NewDeployableReleaseNamespaceInfo(ctx context.Context, res *resource.ReleaseNamespace, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper) (*DeployableReleaseNamespaceInfo, error) {
-	getObj, getErr := kubeClient.Get(ctx, res.ResourceID, kube.KubeClientGetOptions{
+// An existing release namespace is changed only if it is owned by the release, i.e. was created
+// by it, or if it is adopted. The namespace is marked as owned by the release in both cases.
+func NewDeployableReleaseNamespaceInfo(ctx context.Context, res *resource.ReleaseNamespace, releaseName string, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper, opts DeployableReleaseNamespaceInfoOptions) (*DeployableReleaseNamespaceInfo, error) {
+	res = res.OwnedBy(releaseName)
+
+	getObj, found, getErr := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{
 		TryCache: true,
 	})
 	if getErr != nil {
-		if isNotFoundErr(getErr) {
-			return &DeployableReleaseNamespaceInfo{
-				ResourceID: res.ResourceID,
-				resource:   res,
-			}, nil
-		} else {
-			return nil, fmt.Errorf("error getting release namespace: %w", getErr)
-		}
+		return nil, fmt.Errorf("error getting release namespace: %w", getErr)
+	}
+
+	if !found {
+		return &DeployableReleaseNamespaceInfo{
+			ResourceID: res.ResourceID,
+			resource:   res,
+			managed:    true,
+		}, nil
 	}
 	getResource := resource.NewRemoteResource(getObj, resource.RemoteResourceOptions{
 		FallbackNamespace: res.Name(),
 		Mapper:            mapper,
 	})
 
+	owner, _ := getResource.ReleaseNamespaceOwner()
+	if owner != releaseName && !opts.Adopt {
+		return &DeployableReleaseNamespaceInfo{
+			ResourceID:  res.ResourceID,
+			resource:    res,
+			getResource: getResource,
+			exists:      true,
+			upToDate:    resource.UpToDateStatusUnknown,
+		}, nil
+	}
+
 	if err := fixManagedFieldsInCluster(ctx, res.Name(), getObj, getResource, kubeClient, mapper); err != nil {
 		return nil, fmt.Errorf("error fixing managed fields for resource %q: %w", res.HumanID(), err)
 	}
@@ -47,9 +63,7 @@ func NewDeployableReleaseNamespaceInfo(ctx context.Context, res *resource.Releas
 	}
 
 	var upToDateStatus resource.UpToDateStatus
-	if getResource == nil {
-		upToDateStatus = resource.UpToDateStatusNo
-	} else if dryApplyResource == nil {
+	if dryApplyResource == nil {
 		upToDateStatus = resource.UpToDateStatusUnknown
 	} else {
 		different, err := util.ResourcesReallyDiffer(getResource.Unstructured(), dryApplyResource.Unstructured())
@@ -69,11 +83,18 @@ func NewDeployableReleaseNamespaceInfo(ctx context.Context, res *resource.Releas
 		resource:         res,
 		getResource:      getResource,
 		dryApplyResource: dryApplyResource,
-		exists:           getResource != nil,
+		exists:           true,
+		managed:          true,
 		upToDate:         upToDateStatus,
 	}, nil
 }
 
+type DeployableReleaseNamespaceInfoOptions struct {
+	// Change the existing namespace and take its ownership, even if it is not owned by the
+	// release.
+	Adopt bool
+}
+
 type DeployableReleaseNamespaceInfo struct {
 	*id.ResourceID
 	resource *resource.ReleaseNamespace
@@ -82,6 +103,7 @@ type DeployableReleaseNamespaceInfo struct {
 	dryApplyResource *resource.RemoteResource
 
 	exists   bool
+	managed  bool
 	upToDate resource.UpToDateStatus
 }
 
@@ -97,18 +119,24 @@ func (i *DeployableReleaseNamespaceInfo) DryApplyResource() *resource.RemoteReso
 	return i.dryApplyResource
 }
 
+func (i *DeployableReleaseNamespaceInfo) Exists() bool {
+	return i.exists
+}
+
+// Managed returns true if the namespace is going to be created, or if it is owned or adopted by
+// the release. Otherwise the namespace is left untouched.
+func (i *DeployableReleaseNamespaceInfo) Managed() bool {
+	return i.managed
+}
+
 func (i *DeployableReleaseNamespaceInfo) ShouldCreate() bool {
 	return !i.exists
 }
 
 func (i *DeployableReleaseNamespaceInfo) ShouldUpdate() bool {
-	return i.exists && i.upToDate == resource.UpToDateStatusNo
+	return i.exists && i.managed && i.upToDate == resource.UpToDateStatusNo
 }
 
 func (i *DeployableReleaseNamespaceInfo) ShouldApply() bool {
-	return i.exists && i.upToDate == resource.UpToDateStatusUnknown
-}
-
-func (i *DeployableReleaseNamespaceInfo) ShouldKeepOnDelete(releaseName, releaseNamespace string) bool {
-	return i.exists && i.getResource.KeepOnDelete(releaseName, releaseNamespace)
+	return i.exists && i.managed && i.upToDate == resource.UpToDateStatusUnknown
 }
@@ -0,0 +1,156 @@
+package resourceinfo_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan/resourceinfo"
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("dry-run creation of new resources", func() {
+	var (
+		ctx     context.Context
+		cluster *fake.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = fake.NewCluster(ctx)
+
+		cluster.Dynamic.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if action.(clienttesting.PatchAction).GetName() != "rejected" {
+				return false, nil, nil
+			}
+
+			return true, nil, &apierrors.StatusError{ErrStatus: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    403,
+				Reason:  metav1.StatusReasonForbidden,
+				Message: `admission webhook "validate.policy.example.com" denied the request: missing owner label`,
+			}}
+		})
+	})
+
+	general := func(name string) *resource.GeneralResource {
+		return resource.NewGeneralResource(unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: `+name+`}}`), resource.GeneralResourceOptions{
+			DefaultNamespace: "app-ns",
+			Mapper:           cluster.Mapper,
+		})
+	}
+
+	hook := func(name string) *resource.HookResource {
+		return resource.NewHookResource(unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: `+name+`, annotations: {helm.sh/hook: pre-install}}}`), resource.HookResourceOptions{
+			DefaultNamespace: "app-ns",
+			Mapper:           cluster.Mapper,
+		})
+	}
+
+	It("records the error on a general resource instead of failing", func() {
+		info, err := resourceinfo.NewDeployableGeneralResourceInfo(ctx, general("rejected"), "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableGeneralResourceInfoOptions{
+			DryRunCreate: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ShouldCreate()).To(BeTrue())
+		Expect(info.DryCreateErr()).To(MatchError(ContainSubstring("missing owner label")))
+	})
+
+	It("records the error on a hook resource instead of failing", func() {
+		info, err := resourceinfo.NewDeployableHookResourceInfo(ctx, hook("rejected"), "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableHookResourceInfoOptions{
+			DryRunCreate: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ShouldCreate()).To(BeTrue())
+		Expect(info.DryCreateErr()).To(MatchError(ContainSubstring("missing owner label")))
+	})
+
+	It("records no error if the server accepts the resource", func() {
+		info, err := resourceinfo.NewDeployableGeneralResourceInfo(ctx, general("accepted"), "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableGeneralResourceInfoOptions{
+			DryRunCreate: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ShouldCreate()).To(BeTrue())
+		Expect(info.DryCreateErr()).NotTo(HaveOccurred())
+	})
+
+	It("does not dry-run create unless requested", func() {
+		info, err := resourceinfo.NewDeployableGeneralResourceInfo(ctx, general("rejected"), "app-ns", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableGeneralResourceInfoOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.DryCreateErr()).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("release namespace", func() {
+	var ctx context.Context
+
+	releaseNamespace := func(cluster *fake.Cluster) *resource.ReleaseNamespace {
+		return resource.NewReleaseNamespace(unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns, labels: {istio-injection: enabled}}}`), resource.ReleaseNamespaceOptions{
+			Mapper: cluster.Mapper,
+		})
+	}
+
+	liveNamespace := func(cluster *fake.Cluster) map[string]interface{} {
+		obj, err := cluster.KubeClient.Get(ctx, releaseNamespace(cluster).ResourceID, kube.KubeClientGetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		return obj.Object
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("is created and owned by the release if missing", func() {
+		cluster := fake.NewCluster(ctx)
+
+		info, err := resourceinfo.NewDeployableReleaseNamespaceInfo(ctx, releaseNamespace(cluster), "app", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableReleaseNamespaceInfoOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ShouldCreate()).To(BeTrue())
+		Expect(info.Managed()).To(BeTrue())
+		Expect(info.Resource().Unstructured().GetAnnotations()).To(HaveKeyWithValue("werf.io/release-namespace-owner", "app"))
+		Expect(info.Resource().Unstructured().GetLabels()).To(HaveKeyWithValue("istio-injection", "enabled"))
+	})
+
+	It("is updated if owned by the release", func() {
+		cluster := fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns, annotations: {werf.io/release-namespace-owner: app}}}`))
+
+		info, err := resourceinfo.NewDeployableReleaseNamespaceInfo(ctx, releaseNamespace(cluster), "app", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableReleaseNamespaceInfoOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ShouldCreate()).To(BeFalse())
+		Expect(info.Managed()).To(BeTrue())
+		Expect(info.ShouldUpdate() || info.ShouldApply()).To(BeTrue())
+	})
+
+	It("is left untouched if not owned by the release", func() {
+		cluster := fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns, annotations: {werf.io/release-namespace-owner: other}}}`))
+
+		info, err := resourceinfo.NewDeployableReleaseNamespaceInfo(ctx, releaseNamespace(cluster), "app", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableReleaseNamespaceInfoOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Exists()).To(BeTrue())
+		Expect(info.Managed()).To(BeFalse())
+		Expect(info.ShouldCreate()).To(BeFalse())
+		Expect(info.ShouldUpdate()).To(BeFalse())
+		Expect(info.ShouldApply()).To(BeFalse())
+		Expect(liveNamespace(cluster)).NotTo(HaveKey("labels"))
+	})
+
+	It("is taken over if adopted", func() {
+		cluster := fake.NewCluster(ctx, unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns}}`))
+
+		info, err := resourceinfo.NewDeployableReleaseNamespaceInfo(ctx, releaseNamespace(cluster), "app", cluster.KubeClient, cluster.Mapper, resourceinfo.DeployableReleaseNamespaceInfoOptions{
+			Adopt: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Managed()).To(BeTrue())
+		Expect(info.ShouldUpdate() || info.ShouldApply()).To(BeTrue())
+		Expect(info.Resource().Unstructured().GetAnnotations()).To(HaveKeyWithValue("werf.io/release-namespace-owner", "app"))
+	})
+})
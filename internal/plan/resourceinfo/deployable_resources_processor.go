@@ -14,10 +14,11 @@ import (
 
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/plan/dependency"
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func NewDeployableResourcesProcessor(
@@ -52,6 +53,13 @@ func NewDeployableResourcesProcessor(
 		mapper:                            opts.Mapper,
 		discoveryClient:                   opts.DiscoveryClient,
 		allowClusterAccess:                opts.AllowClusterAccess,
+		dryRunNewResources:                opts.DryRunNewResources,
+		resourceSizeLimit:                 lo.Ternary(opts.ResourceSizeLimit > 0, opts.ResourceSizeLimit, resource.DefaultSizeLimit),
+		crdPolicy:                         lo.Ternary(opts.CRDPolicy != "", opts.CRDPolicy, resource.DefaultCRDPolicy),
+		strictFieldValidation:             opts.StrictFieldValidation,
+		autoAdopt:                         opts.AutoAdopt,
+		forceAdoption:                     opts.ForceAdoption,
+		forceReplace:                      opts.ForceReplace,
 		networkParallelism:                lo.Max([]int{opts.NetworkParallelism, 1}),
 		hookResourceTransformers:          hookResourceTransformers,
 		generalResourceTransformers:       generalResourceTransformers,
@@ -60,6 +68,8 @@ func NewDeployableResourcesProcessor(
 		deployableStandaloneCRDsPatchers:  deployableStandaloneCRDsPatchers,
 		deployableHookResourcePatchers:    deployableHookResourcePatchers,
 		deployableGeneralResourcePatchers: deployableGeneralResourcePatchers,
+		releaseNamespaceResource:          opts.ReleaseNamespace,
+		adoptReleaseNamespace:             opts.AdoptReleaseNamespace,
 	}
 }
 
@@ -76,6 +86,18 @@ type DeployableResourcesProcessorOptions struct {
 	Mapper                            meta.ResettableRESTMapper
 	DiscoveryClient                   discovery.CachedDiscoveryInterface
 	AllowClusterAccess                bool
+	DryRunNewResources                bool
+	ResourceSizeLimit                 int
+	CRDPolicy                         resource.CRDPolicy
+	StrictFieldValidation             bool
+	AutoAdopt                         bool
+	ForceAdoption                     bool
+	ForceReplace                      bool
+	// The release namespace, which is created if missing. If not set, the release namespace is
+	// left as it is.
+	ReleaseNamespace *resource.ReleaseNamespace
+	// Change the existing release namespace even if it is not owned by the release.
+	AdoptReleaseNamespace bool
 }
 
 type DeployableResourcesProcessor struct {
@@ -91,6 +113,16 @@ type DeployableResourcesProcessor struct {
 	discoveryClient         discovery.CachedDiscoveryInterface
 	networkParallelism      int
 	allowClusterAccess      bool
+	dryRunNewResources      bool
+	resourceSizeLimit       int
+	crdPolicy               resource.CRDPolicy
+	strictFieldValidation   bool
+	autoAdopt               bool
+	forceAdoption           bool
+	forceReplace            bool
+
+	releaseNamespaceResource *resource.ReleaseNamespace
+	adoptReleaseNamespace    bool
 
 	hookResourceTransformers    []resource.ResourceTransformer
 	generalResourceTransformers []resource.ResourceTransformer
@@ -118,68 +150,85 @@ type DeployableResourcesProcessor struct {
 
 // TODO(ilya-lesikov): optimize. Avoid excessive deep copies.
 func (p *DeployableResourcesProcessor) Process(ctx context.Context) error {
-	log.Default.Debug(ctx, "Transforming hook resources")
+	log.Plan.Debug(ctx, "Transforming hook resources")
 	if err := p.transformHookResources(ctx); err != nil {
 		return fmt.Errorf("error transforming hook resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Transforming general resources")
+	log.Plan.Debug(ctx, "Transforming general resources")
 	if err := p.transformGeneralResources(ctx); err != nil {
 		return fmt.Errorf("error transforming general resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Validating resources")
+	log.Plan.Debug(ctx, "Validating resources")
 	if err := p.validateResources(); err != nil {
 		return fmt.Errorf("error validating resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building releasable resources")
+	log.Plan.Debug(ctx, "Building releasable resources")
 	if err := p.validateNoDuplicates(); err != nil {
 		return fmt.Errorf("error validating for no duplicated resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building releasable hook resources")
+	log.Plan.Debug(ctx, "Validating depends-on references")
+	if err := p.validateDependsOnReferences(); err != nil {
+		return fmt.Errorf("error validating depends-on references: %w", err)
+	}
+
+	log.Plan.Debug(ctx, "Building releasable hook resources")
 	if err := p.buildReleasableHookResources(ctx); err != nil {
 		return fmt.Errorf("error building releasable hook resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building releasable general resources")
+	log.Plan.Debug(ctx, "Building releasable general resources")
 	if err := p.buildReleasableGeneralResources(ctx); err != nil {
 		return fmt.Errorf("error building releasable general resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Validating releasable resources")
+	log.Plan.Debug(ctx, "Validating releasable resources")
 	if err := p.validateReleasableResources(); err != nil {
 		return fmt.Errorf("error validating releasable resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building deployable standalone CRDs")
+	log.Plan.Debug(ctx, "Building deployable standalone CRDs")
 	if err := p.buildDeployableStandaloneCRDs(ctx); err != nil {
 		return fmt.Errorf("error building deployable standalone crds: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building deployable hook resources")
+	log.Plan.Debug(ctx, "Building deployable hook resources")
 	if err := p.buildDeployableHookResources(ctx); err != nil {
 		return fmt.Errorf("error building deployable hook resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Building deployable general resources")
+	log.Plan.Debug(ctx, "Building deployable general resources")
 	if err := p.buildDeployableGeneralResources(ctx); err != nil {
 		return fmt.Errorf("error building deployable general resources: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Validating deployable resources")
+	log.Plan.Debug(ctx, "Validating deployable resources")
 	if err := p.validateDeployableResources(); err != nil {
 		return fmt.Errorf("error validating deployable resources: %w", err)
 	}
 
+	log.Plan.Debug(ctx, "Validating deployable resources sizes")
+	if err := p.validateDeployableResourcesSizes(); err != nil {
+		return fmt.Errorf("error validating deployable resources sizes: %w", err)
+	}
+
+	if p.allowClusterAccess && p.strictFieldValidation {
+		log.Plan.Debug(ctx, "Validating deployable resources fields strictly")
+		if err := p.validateDeployableResourcesFieldsStrictly(ctx); err != nil {
+			return fmt.Errorf("error validating deployable resources fields: %w", err)
+		}
+	}
+
 	if p.allowClusterAccess {
-		log.Default.Debug(ctx, "Building deployable resource infos")
+		log.Plan.Debug(ctx, "Building deployable resource infos")
 		if err := p.buildDeployableResourceInfos(ctx); err != nil {
 			return fmt.Errorf("error building deployable resource infos: %w", err)
 		}
 
-		log.Default.Debug(ctx, "Validating adoptable resources")
+		log.Plan.Debug(ctx, "Validating adoptable resources")
 		if err := p.validateAdoptableResources(); err != nil {
 			return fmt.Errorf("error validating adoptable resources: %w", err)
 		}
@@ -196,6 +245,11 @@ func (p *DeployableResourcesProcessor) ReleasableGeneralResources() []*resource.
 	return p.releasableGeneralResources
 }
 
+// DeployableReleaseNamespaceInfo returns nil if the release namespace wasn't passed to the processor.
+func (p *DeployableResourcesProcessor) DeployableReleaseNamespaceInfo() *DeployableReleaseNamespaceInfo {
+	return p.deployableReleaseNamespaceInfo
+}
+
 func (p *DeployableResourcesProcessor) DeployableStandaloneCRDsInfos() []*DeployableStandaloneCRDInfo {
 	return p.deployableStandaloneCRDsInfos
 }
@@ -422,6 +476,11 @@ func (p *DeployableResourcesProcessor) buildDeployableStandaloneCRDs(ctx context
 	var patchedResources []*resource.StandaloneCRD
 
 	for _, res := range p.standaloneCRDs {
+		if res.Policy(p.crdPolicy) == resource.CRDPolicySkip {
+			log.Plan.Debug(ctx, "Skipping standalone CRD %q due to CRD policy %q", res.HumanID(), resource.CRDPolicySkip)
+			continue
+		}
+
 		patchedRes := res
 
 		var deepCopied bool
@@ -477,11 +536,11 @@ func (p *DeployableResourcesProcessor) buildDeployableHookResources(ctx context.
 	matchingHookResources := lo.Filter(p.hookResources, func(res *resource.HookResource, _ int) bool {
 		switch p.deployType {
 		case common.DeployTypeInitial, common.DeployTypeInstall:
-			return res.OnPreInstall() || res.OnPostInstall()
+			return res.OnPreInstall() || res.OnPostInstall() || res.OnExec() || res.OnPrePlan() || res.OnPostReadiness()
 		case common.DeployTypeUpgrade:
-			return res.OnPreUpgrade() || res.OnPostUpgrade()
+			return res.OnPreUpgrade() || res.OnPostUpgrade() || res.OnExec() || res.OnPrePlan() || res.OnPostReadiness()
 		case common.DeployTypeRollback:
-			return res.OnPreRollback() || res.OnPostRollback()
+			return res.OnPreRollback() || res.OnPostRollback() || res.OnExec() || res.OnPrePlan() || res.OnPostReadiness()
 		}
 
 		return false
@@ -609,6 +668,12 @@ func (p *DeployableResourcesProcessor) buildDeployableResourceInfos(ctx context.
 		p.kubeClient,
 		p.mapper,
 		p.networkParallelism,
+		BuildDeployableResourceInfosOptions{
+			DryRunNewResources:    p.dryRunNewResources,
+			ForceReplace:          p.forceReplace,
+			ReleaseNamespace:      p.releaseNamespaceResource,
+			AdoptReleaseNamespace: p.adoptReleaseNamespace,
+		},
 	)
 	if err != nil {
 		return fmt.Errorf("error building deployable resource infos: %w", err)
@@ -683,6 +748,52 @@ func (p *DeployableResourcesProcessor) validateDeployableResources() error {
 	return util.Multierrorf("deployable resources validation failed", errs)
 }
 
+func (p *DeployableResourcesProcessor) validateDeployableResourcesFieldsStrictly(ctx context.Context) error {
+	var resources []*strictFieldValidationResource
+
+	for _, res := range p.deployableHookResources {
+		resources = append(resources, &strictFieldValidationResource{
+			resourceID: res.ResourceID,
+			unstruct:   res.Unstructured(),
+			filePath:   res.FilePath(),
+		})
+	}
+
+	for _, res := range p.deployableGeneralResources {
+		resources = append(resources, &strictFieldValidationResource{
+			resourceID: res.ResourceID,
+			unstruct:   res.Unstructured(),
+			filePath:   res.FilePath(),
+		})
+	}
+
+	return validateFieldsStrictly(ctx, resources, p.kubeClient, p.networkParallelism)
+}
+
+func (p *DeployableResourcesProcessor) validateDeployableResourcesSizes() error {
+	var errs []error
+
+	for _, res := range p.deployableStandaloneCRDs {
+		if err := resource.ValidateSize(res.Unstructured(), p.resourceSizeLimit); err != nil {
+			errs = append(errs, fmt.Errorf("error validating size of resource %q: %w", res.HumanID(), err))
+		}
+	}
+
+	for _, res := range p.deployableHookResources {
+		if err := resource.ValidateSize(res.Unstructured(), p.resourceSizeLimit); err != nil {
+			errs = append(errs, fmt.Errorf("error validating size of resource %q: %w", res.HumanID(), err))
+		}
+	}
+
+	for _, res := range p.deployableGeneralResources {
+		if err := resource.ValidateSize(res.Unstructured(), p.resourceSizeLimit); err != nil {
+			errs = append(errs, fmt.Errorf("error validating size of resource %q: %w", res.HumanID(), err))
+		}
+	}
+
+	return util.Multierrorf("deployable resources size validation failed", errs)
+}
+
 func (p *DeployableResourcesProcessor) validateNoDuplicates() error {
 	var resources []*id.ResourceID
 
@@ -700,7 +811,7 @@ func (p *DeployableResourcesProcessor) validateNoDuplicates() error {
 
 	for _, res := range resources {
 		if res.GroupVersionKind() == (schema.GroupVersionKind{Kind: "Namespace", Version: "v1"}) && res.Name() == p.releaseNamespace {
-			return fmt.Errorf("release namespace %q cannot be deployed as part of the release")
+			return fmt.Errorf("release namespace %q cannot be deployed as part of the release", p.releaseNamespace)
 		}
 	}
 
@@ -728,6 +839,42 @@ func (p *DeployableResourcesProcessor) validateNoDuplicates() error {
 	return nil
 }
 
+// Resources outside of the release must be explicitly marked as external in the "werf.io/depends-on"
+// annotation, otherwise a typo in the reference would silently drop the ordering.
+func (p *DeployableResourcesProcessor) validateDependsOnReferences() error {
+	var resources []*id.ResourceID
+	for _, res := range p.standaloneCRDs {
+		resources = append(resources, res.ResourceID)
+	}
+
+	for _, res := range p.hookResources {
+		resources = append(resources, res.ResourceID)
+	}
+
+	for _, res := range p.generalResources {
+		resources = append(resources, res.ResourceID)
+	}
+
+	var errs []error
+	validate := func(humanID string, deps []*dependency.InternalDependency) {
+		for _, dep := range deps {
+			if !lo.ContainsBy(resources, dep.Match) {
+				errs = append(errs, fmt.Errorf("%s of resource %q references resource not found in the release, prefix it with \"external:\" if it is not managed by the release", dep.Source, humanID))
+			}
+		}
+	}
+
+	for _, res := range p.hookResources {
+		validate(res.HumanID(), res.DependsOnInternalDependencies())
+	}
+
+	for _, res := range p.generalResources {
+		validate(res.HumanID(), res.DependsOnInternalDependencies())
+	}
+
+	return util.Multierrorf("depends-on references validation failed", errs)
+}
+
 func (p *DeployableResourcesProcessor) validateAdoptableResources() error {
 	var errs []error
 	for _, genResInfo := range p.deployableGeneralResourcesInfos {
@@ -735,9 +882,22 @@ func (p *DeployableResourcesProcessor) validateAdoptableResources() error {
 			continue
 		}
 
-		if adoptable, nonAdoptableReason := genResInfo.LiveResource().AdoptableBy(p.releaseName, p.releaseNamespace); !adoptable {
+		adoptable, nonAdoptableReason := genResInfo.LiveResource().AdoptableBy(p.releaseName, p.releaseNamespace)
+		if adoptable {
+			continue
+		}
+
+		if ownerName, ownerNamespace, owned := genResInfo.LiveResource().OwnerRelease(); owned {
+			if !p.forceAdoption {
+				errs = append(errs, fmt.Errorf("resource %q is owned by another release %q (namespace: %q), refusing to adopt it without forced adoption", genResInfo.HumanID(), ownerName, ownerNamespace))
+				continue
+			}
+		} else if !p.autoAdopt && !genResInfo.Resource().AdoptionAllowedBy(p.releaseName) {
 			errs = append(errs, fmt.Errorf("resource %q is not adoptable: %s", genResInfo.HumanID(), nonAdoptableReason))
+			continue
 		}
+
+		genResInfo.adopt = true
 	}
 
 	return util.Multierrorf("adoption validation failed", errs)
@@ -0,0 +1,85 @@
+package resourceinfo_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan/resourceinfo"
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("adoption of existing resources", func() {
+	var (
+		ctx     context.Context
+		cluster *fake.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = fake.NewCluster(ctx,
+			unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: unowned, namespace: app-ns}}`),
+			unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: foreign, namespace: app-ns, annotations: {meta.helm.sh/release-name: other, meta.helm.sh/release-namespace: other-ns}}}`),
+		)
+	})
+
+	DescribeTable("adopts resources only when allowed",
+		func(manifest string, autoAdopt, forceAdoption bool, expectedErr string) {
+			res := resource.NewGeneralResource(unstructFromYAML(manifest), resource.GeneralResourceOptions{
+				DefaultNamespace: "app-ns",
+				Mapper:           cluster.Mapper,
+			})
+
+			processor := resourceinfo.NewDeployableResourcesProcessor(common.DeployTypeUpgrade, "app", "app-ns", nil, nil, []*resource.GeneralResource{res}, nil, resourceinfo.DeployableResourcesProcessorOptions{
+				KubeClient:         cluster.KubeClient,
+				Mapper:             cluster.Mapper,
+				DiscoveryClient:    cluster.Discovery,
+				AllowClusterAccess: true,
+				AutoAdopt:          autoAdopt,
+				ForceAdoption:      forceAdoption,
+			})
+
+			err := processor.Process(ctx)
+			if expectedErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(processor.DeployableGeneralResourcesInfos()).To(HaveExactElements(
+				WithTransform((*resourceinfo.DeployableGeneralResourceInfo).ShouldAdopt, BeTrue()),
+			))
+		},
+		Entry("an unowned resource without adoption",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: unowned}}`, false, false,
+			`resource "ConfigMap/unowned" is not adoptable`,
+		),
+		Entry("an unowned resource with auto-adoption",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: unowned}}`, true, false,
+			"",
+		),
+		Entry("an unowned resource allowing adoption by the release",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: unowned, annotations: {werf.io/allow-adoption-by-release: app}}}`, false, false,
+			"",
+		),
+		Entry("an unowned resource with forced adoption only",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: unowned}}`, false, true,
+			`resource "ConfigMap/unowned" is not adoptable`,
+		),
+		Entry("a resource owned by another release with auto-adoption",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: foreign}}`, true, false,
+			`is owned by another release "other" (namespace: "other-ns")`,
+		),
+		Entry("a resource owned by another release allowing adoption by the release",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: foreign, annotations: {werf.io/allow-adoption-by-release: app}}}`, false, false,
+			`is owned by another release "other" (namespace: "other-ns")`,
+		),
+		Entry("a resource owned by another release with forced adoption",
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: foreign}}`, false, true,
+			"",
+		),
+	)
+})
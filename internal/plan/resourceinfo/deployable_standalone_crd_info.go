@@ -11,21 +11,27 @@ import (
 	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func NewDeployableStandaloneCRDInfo(ctx context.Context, res *resource.StandaloneCRD, releaseNamespace string, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper) (*DeployableStandaloneCRDInfo, error) {
-	getObj, getErr := kubeClient.Get(ctx, res.ResourceID, kube.KubeClientGetOptions{
+	ignoredFields, ignoreFieldsErrs := res.IgnoreFields()
+	for _, err := range ignoreFieldsErrs {
+		log.Plan.Warn(ctx, "Warning: %s, the path is skipped for resource %q", util.Capitalize(err.Error()), res.HumanID())
+	}
+
+	getObj, found, getErr := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{
 		TryCache: true,
 	})
 	if getErr != nil {
-		if isNotFoundErr(getErr) {
-			return &DeployableStandaloneCRDInfo{
-				ResourceID: res.ResourceID,
-				resource:   res,
-			}, nil
-		} else {
-			return nil, fmt.Errorf("error getting standalone CRD: %w", getErr)
-		}
+		return nil, fmt.Errorf("error getting standalone CRD: %w", getErr)
+	}
+
+	if !found {
+		return &DeployableStandaloneCRDInfo{
+			ResourceID: res.ResourceID,
+			resource:   res,
+		}, nil
 	}
 	getResource := resource.NewRemoteResource(getObj, resource.RemoteResourceOptions{
 		FallbackNamespace: releaseNamespace,
@@ -36,6 +42,8 @@ func NewDeployableStandaloneCRDInfo(ctx context.Context, res *resource.Standalon
 		return nil, fmt.Errorf("error fixing managed fields for resource %q: %w", res.HumanID(), err)
 	}
 
+	res = res.WithIgnoredFieldsFrom(getObj)
+
 	dryApplyObj, _ := kubeClient.Apply(ctx, res.ResourceID, res.Unstructured(), kube.KubeClientApplyOptions{
 		DryRun: true,
 	})
@@ -53,7 +61,7 @@ func NewDeployableStandaloneCRDInfo(ctx context.Context, res *resource.Standalon
 	} else if dryApplyResource == nil {
 		upToDateStatus = resource.UpToDateStatusUnknown
 	} else {
-		different, err := util.ResourcesReallyDiffer(getResource.Unstructured(), dryApplyResource.Unstructured())
+		different, err := util.ResourcesReallyDiffer(resource.WithoutFields(getResource.Unstructured(), ignoredFields), resource.WithoutFields(dryApplyResource.Unstructured(), ignoredFields))
 		if err != nil {
 			return nil, fmt.Errorf("error diffing live and dry-apply versions of resource %q: %w", res.HumanID(), err)
 		}
@@ -112,7 +120,7 @@ func (i *DeployableStandaloneCRDInfo) ShouldApply() bool {
 
 func (i *DeployableStandaloneCRDInfo) LiveUID() (uid types.UID, found bool) {
 	if !i.exists {
-		return types.UID(0), false
+		return "", false
 	}
 
 	return i.getResource.Unstructured().GetUID(), true
@@ -0,0 +1,22 @@
+package resourceinfo_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestResourceInfo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ResourceInfo Suite")
+}
+
+func unstructFromYAML(manifest string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	Expect(yaml.Unmarshal([]byte(manifest), &obj.Object)).To(Succeed())
+
+	return obj
+}
@@ -0,0 +1,84 @@
+package resourceinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/conc/pool"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// Messages of the Kubernetes API for unknown and duplicate fields, for server-side apply and for
+// other requests.
+var strictFieldValidationErrMarkers = []string{
+	"field not declared in schema",
+	"unknown field",
+	"duplicate field",
+}
+
+type strictFieldValidationResource struct {
+	resourceID *id.ResourceID
+	unstruct   *unstructured.Unstructured
+	filePath   string
+}
+
+// validateFieldsStrictly dry-run applies resources with strict field validation and reports
+// unknown and duplicate fields of all resources at once. Other errors are ignored here, they are
+// reported on deploy as usual.
+func validateFieldsStrictly(ctx context.Context, resources []*strictFieldValidationResource, kubeClient kube.KubeClienter, parallelism int) error {
+	validationPool := pool.NewWithResults[error]().WithContext(ctx).WithMaxGoroutines(parallelism)
+	for _, res := range resources {
+		res := res
+		validationPool.Go(func(ctx context.Context) (error, error) {
+			_, err := kubeClient.Apply(ctx, res.resourceID, res.unstruct, kube.KubeClientApplyOptions{
+				DryRun:          true,
+				FieldValidation: metav1.FieldValidationStrict,
+			})
+			if err == nil {
+				return nil, nil
+			}
+
+			if !isStrictFieldValidationErr(err) {
+				log.Plan.Debug(ctx, "Ignoring error of strict field validation for resource %q: %s", res.resourceID.HumanID(), err)
+				return nil, nil
+			}
+
+			if res.filePath != "" {
+				return fmt.Errorf("resource %q from %q: %w", res.resourceID.HumanID(), res.filePath, err), nil
+			}
+
+			return fmt.Errorf("resource %q: %w", res.resourceID.HumanID(), err), nil
+		})
+	}
+
+	results, err := validationPool.Wait()
+	if err != nil {
+		return fmt.Errorf("error validating resource fields: %w", err)
+	}
+
+	var errs []error
+	for _, resErr := range results {
+		if resErr != nil {
+			errs = append(errs, resErr)
+		}
+	}
+
+	return util.Multierrorf("strict field validation failed", errs)
+}
+
+func isStrictFieldValidationErr(err error) bool {
+	for _, marker := range strictFieldValidationErrMarkers {
+		if strings.Contains(err.Error(), marker) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -12,8 +12,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/pkg/log"
 )
 
 func isImmutableErr(err error) bool {
@@ -43,7 +43,7 @@ func fixManagedFieldsInCluster(ctx context.Context, namespace string, getObj *un
 		return fmt.Errorf("error marshaling fixed managed fields: %w", err)
 	}
 
-	log.Default.Debug(ctx, "Fixing managed fields for resource %q", getResource.HumanID())
+	log.Plan.Debug(ctx, "Fixing managed fields for resource %q", getResource.HumanID())
 	getObj, err = kubeClient.MergePatch(ctx, getResource.ResourceID, patch)
 	if err != nil {
 		return fmt.Errorf("error patching managed fields: %w", err)
@@ -0,0 +1,146 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
+)
+
+type StageSummary struct {
+	// Stage operation ID without the "/start" suffix, e.g. "stage/general-resources/weight:-5".
+	Stage string
+	// Nil if resources of the stage are not grouped by weight.
+	Weight         *int
+	ResourcesCount int
+	Kinds          []string
+}
+
+// StagesSummary returns the stages having resources to deploy or delete, in the order of their
+// execution.
+func (p *Plan) StagesSummary() []*StageSummary {
+	summaries := map[string]*StageSummary{}
+	stagesResources := map[string]map[string]struct{}{}
+
+	for opID, stage := range p.operationsStages {
+		op, found := p.Operation("%s", opID)
+		if !found {
+			continue
+		}
+
+		switch op.Type() {
+		case operation.TypeCreateResourceOperation,
+			operation.TypeRecreateResourceOperation,
+			operation.TypeUpdateResourceOperation,
+			operation.TypeApplyResourceOperation,
+			operation.TypeDeleteResourceOperation,
+			operation.TypeExtraPostCreateResourceOperation,
+			operation.TypeExtraPostRecreateResourceOperation,
+			operation.TypeExtraPostApplyResourceOperation,
+			operation.TypeExtraPostUpdateResourceOperation,
+			operation.TypeExtraPostDeleteResourceOperation:
+		default:
+			continue
+		}
+
+		resOp, ok := op.(interface{ ResourceID() *id.ResourceID })
+		if !ok {
+			continue
+		}
+
+		summary, found := summaries[stage]
+		if !found {
+			summary = &StageSummary{
+				Stage:  stage,
+				Weight: stageOpWeight(stage),
+			}
+			summaries[stage] = summary
+			stagesResources[stage] = map[string]struct{}{}
+		}
+
+		resID := resOp.ResourceID()
+		if _, counted := stagesResources[stage][resID.ID()]; counted {
+			continue
+		}
+		stagesResources[stage][resID.ID()] = struct{}{}
+
+		summary.ResourcesCount++
+		if kind := resID.GroupVersionKind().Kind; !lo.Contains(summary.Kinds, kind) {
+			summary.Kinds = append(summary.Kinds, kind)
+		}
+	}
+
+	result := lo.Values(summaries)
+	for _, summary := range result {
+		sort.Strings(summary.Kinds)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		iIndex := stageNameIndex(result[i].Stage)
+		jIndex := stageNameIndex(result[j].Stage)
+
+		if iIndex != jIndex {
+			return iIndex < jIndex
+		}
+
+		if result[i].Weight != nil && result[j].Weight != nil && *result[i].Weight != *result[j].Weight {
+			return *result[i].Weight < *result[j].Weight
+		}
+
+		if iSerial, jSerial := stageOpSerial(result[i].Stage), stageOpSerial(result[j].Stage); iSerial != jSerial {
+			return iSerial < jSerial
+		}
+
+		return result[i].Stage < result[j].Stage
+	})
+
+	return result
+}
+
+func LogStagesSummary(ctx context.Context, summaries []*StageSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.AppendHeader(prtable.Row{"Stage", "Weight", "Resources", "Kinds"})
+
+	for _, summary := range summaries {
+		var weight string
+		if summary.Weight != nil {
+			weight = fmt.Sprint(*summary.Weight)
+		}
+
+		table.AppendRow(prtable.Row{
+			strings.TrimPrefix(strings.Replace(summary.Stage, "/weight:"+weight, "", 1), operation.TypeStageOperation+"/"),
+			weight,
+			summary.ResourcesCount,
+			strings.Join(summary.Kinds, ", "),
+		})
+	}
+
+	log.Plan.Info(ctx, color.Style{color.Bold}.Render("Deploy stages:"))
+	log.Plan.Info(ctx, "%s", table.Render())
+	log.Plan.Info(ctx, "")
+}
+
+// Like stageOpNameIndex, but returns len(StageOpNamesOrdered) for unknown stages instead of
+// panicking.
+func stageNameIndex(stage string) int {
+	if _, index, found := lo.FindIndexOf(StageOpNamesOrdered, func(name string) bool {
+		return strings.HasPrefix(stage+"/", name+"/")
+	}); found {
+		return index
+	}
+
+	return len(StageOpNamesOrdered)
+}
@@ -0,0 +1,279 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/3p-helm/pkg/releaseutil"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+type UninstallStepType string
+
+const (
+	UninstallStepTypeRunHook         UninstallStepType = "run-hook"
+	UninstallStepTypeDelete          UninstallStepType = "delete"
+	UninstallStepTypeDeleteHook      UninstallStepType = "delete-hook"
+	UninstallStepTypeKeep            UninstallStepType = "keep"
+	UninstallStepTypeSkip            UninstallStepType = "skip"
+	UninstallStepTypeDeleteNamespace UninstallStepType = "delete-namespace"
+	UninstallStepTypeKeepNamespace   UninstallStepType = "keep-namespace"
+)
+
+type UninstallStep struct {
+	Type     UninstallStepType `json:"type"`
+	Resource string            `json:"resource"`
+	Reason   string            `json:"reason,omitempty"`
+}
+
+func NewUninstallStepsBuilder(releaseNamespace string, rel *release.Release, kubeClient kube.KubeClienter, mapper meta.ResettableRESTMapper, opts UninstallStepsBuilderOptions) *UninstallStepsBuilder {
+	return &UninstallStepsBuilder{
+		releaseNamespace:       releaseNamespace,
+		release:                rel,
+		kubeClient:             kubeClient,
+		mapper:                 mapper,
+		deleteHooks:            opts.DeleteHooks,
+		deleteReleaseNamespace: opts.DeleteReleaseNamespace,
+	}
+}
+
+type UninstallStepsBuilderOptions struct {
+	DeleteHooks            bool
+	DeleteReleaseNamespace bool
+}
+
+type UninstallStepsBuilder struct {
+	releaseNamespace       string
+	release                *release.Release
+	kubeClient             kube.KubeClienter
+	mapper                 meta.ResettableRESTMapper
+	deleteHooks            bool
+	deleteReleaseNamespace bool
+}
+
+// Simulates the uninstall sequence: pre-delete hooks, general resources in uninstall order,
+// post-delete hooks, hooks cleanup and finally the release namespace.
+func (b *UninstallStepsBuilder) Build(ctx context.Context) ([]*UninstallStep, error) {
+	var steps []*UninstallStep
+
+	hooks := b.release.HookResources()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Weight() != hooks[j].Weight() {
+			return hooks[i].Weight() < hooks[j].Weight()
+		}
+
+		return hooks[i].Name() < hooks[j].Name()
+	})
+
+	for _, hook := range hooks {
+		if !hook.OnPreDelete() {
+			continue
+		}
+
+		steps = append(steps, &UninstallStep{
+			Type:     UninstallStepTypeRunHook,
+			Resource: hook.HumanID(),
+			Reason:   fmt.Sprintf("pre-delete hook, weight %d", hook.Weight()),
+		})
+	}
+
+	generals := append([]*resource.GeneralResource{}, b.release.GeneralResources()...)
+	sort.SliceStable(generals, func(i, j int) bool {
+		return uninstallKindOrder(generals[i].GroupVersionKind().Kind) < uninstallKindOrder(generals[j].GroupVersionKind().Kind)
+	})
+
+	for _, res := range generals {
+		step, err := b.resourceDeletionStep(ctx, res.ResourceID, res.KeepOnDelete(), UninstallStepTypeDelete)
+		if err != nil {
+			return nil, fmt.Errorf("error building uninstall step for resource %q: %w", res.HumanID(), err)
+		}
+
+		steps = append(steps, step)
+	}
+
+	for _, hook := range hooks {
+		if !hook.OnPostDelete() {
+			continue
+		}
+
+		steps = append(steps, &UninstallStep{
+			Type:     UninstallStepTypeRunHook,
+			Resource: hook.HumanID(),
+			Reason:   fmt.Sprintf("post-delete hook, weight %d", hook.Weight()),
+		})
+	}
+
+	if b.deleteHooks {
+		for _, hook := range hooks {
+			step, err := b.resourceDeletionStep(ctx, hook.ResourceID, hook.KeepOnDelete(), UninstallStepTypeDeleteHook)
+			if err != nil {
+				return nil, fmt.Errorf("error building uninstall step for hook %q: %w", hook.HumanID(), err)
+			}
+
+			steps = append(steps, step)
+		}
+	}
+
+	if step, err := b.namespaceStep(ctx); err != nil {
+		return nil, fmt.Errorf("error building uninstall step for release namespace: %w", err)
+	} else if step != nil {
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func (b *UninstallStepsBuilder) resourceDeletionStep(ctx context.Context, resID *id.ResourceID, keep bool, deleteType UninstallStepType) (*UninstallStep, error) {
+	if keep {
+		return &UninstallStep{
+			Type:     UninstallStepTypeKeep,
+			Resource: resID.HumanID(),
+			Reason:   "policy keep",
+		}, nil
+	}
+
+	obj, found, err := b.kubeClient.GetOrNil(ctx, resID, kube.KubeClientGetOptions{
+		TryCache: true,
+	})
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("error getting resource %q: %w", resID.HumanID(), err)
+	}
+
+	if !found {
+		return &UninstallStep{
+			Type:     UninstallStepTypeSkip,
+			Resource: resID.HumanID(),
+			Reason:   "not found in cluster",
+		}, nil
+	}
+
+	remote := resource.NewRemoteResource(obj, resource.RemoteResourceOptions{
+		FallbackNamespace: b.releaseNamespace,
+		Mapper:            b.mapper,
+	})
+
+	if remote.KeepOnDelete(b.release.Name(), b.releaseNamespace) {
+		return &UninstallStep{
+			Type:     UninstallStepTypeKeep,
+			Resource: resID.HumanID(),
+			Reason:   "policy keep or not owned by release in cluster",
+		}, nil
+	}
+
+	return &UninstallStep{
+		Type:     deleteType,
+		Resource: resID.HumanID(),
+		Reason:   finalizersReason(obj),
+	}, nil
+}
+
+func (b *UninstallStepsBuilder) namespaceStep(ctx context.Context) (*UninstallStep, error) {
+	nsID := id.NewResourceID(
+		b.releaseNamespace,
+		"",
+		schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		id.ResourceIDOptions{Mapper: b.mapper},
+	)
+
+	obj, found, err := b.kubeClient.GetOrNil(ctx, nsID, kube.KubeClientGetOptions{
+		TryCache: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting release namespace %q: %w", b.releaseNamespace, err)
+	} else if !found {
+		return nil, nil
+	}
+
+	if !b.deleteReleaseNamespace {
+		reason := "not created by the release and namespace deletion not requested"
+		if owner, _ := resource.NewRemoteResource(obj, resource.RemoteResourceOptions{Mapper: b.mapper}).ReleaseNamespaceOwner(); owner == b.release.Name() {
+			reason = "created by the release, but namespace deletion not requested"
+		}
+
+		return &UninstallStep{
+			Type:     UninstallStepTypeKeepNamespace,
+			Resource: nsID.HumanID(),
+			Reason:   reason,
+		}, nil
+	}
+
+	return &UninstallStep{
+		Type:     UninstallStepTypeDeleteNamespace,
+		Resource: nsID.HumanID(),
+		Reason:   finalizersReason(obj),
+	}, nil
+}
+
+func LogUninstallSteps(ctx context.Context, releaseName, releaseNamespace string, steps []*UninstallStep) {
+	log.Plan.Info(ctx, color.Bold.Render("Planned uninstall steps")+" for release %q (namespace: %q):", releaseName, releaseNamespace)
+
+	for i, step := range steps {
+		var action string
+		switch step.Type {
+		case UninstallStepTypeRunHook:
+			action = applyStyle("run hook")
+		case UninstallStepTypeDelete:
+			action = deleteStyle("delete")
+		case UninstallStepTypeDeleteHook:
+			action = deleteStyle("delete hook")
+		case UninstallStepTypeKeep:
+			action = createStyle("keep")
+		case UninstallStepTypeSkip:
+			action = "skip"
+		case UninstallStepTypeDeleteNamespace:
+			action = deleteStyle("delete namespace")
+		case UninstallStepTypeKeepNamespace:
+			action = createStyle("keep namespace")
+		}
+
+		line := fmt.Sprintf("%d. %s %s", i+1, action, resourceStyle(step.Resource))
+		if step.Reason != "" {
+			line += fmt.Sprintf(" (%s)", step.Reason)
+		}
+
+		log.Plan.Info(ctx, "%s", line)
+	}
+}
+
+func SaveUninstallSteps(path string, steps []*UninstallStep) error {
+	data, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling uninstall steps: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing uninstall steps to %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func finalizersReason(obj *unstructured.Unstructured) string {
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		return fmt.Sprintf("wait for finalizers: %s", strings.Join(finalizers, ", "))
+	}
+
+	return ""
+}
+
+func uninstallKindOrder(kind string) int {
+	if i := lo.IndexOf(releaseutil.UninstallOrder, kind); i != -1 {
+		return i
+	}
+
+	return len(releaseutil.UninstallOrder)
+}
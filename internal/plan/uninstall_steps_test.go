@@ -0,0 +1,136 @@
+package plan_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+)
+
+const (
+	uninstallReleaseName      = "app"
+	uninstallReleaseNamespace = "app-ns"
+)
+
+var _ = Describe("uninstall steps", func() {
+	var (
+		ctx     context.Context
+		cluster *fake.Cluster
+		rel     *release.Release
+	)
+
+	owned := func(manifest string) *unstructured.Unstructured {
+		obj := unstructFromYAML(manifest)
+		obj.SetAnnotations(mergeMaps(obj.GetAnnotations(), map[string]string{
+			"meta.helm.sh/release-name":      uninstallReleaseName,
+			"meta.helm.sh/release-namespace": uninstallReleaseNamespace,
+		}))
+		obj.SetLabels(mergeMaps(obj.GetLabels(), map[string]string{"app.kubernetes.io/managed-by": "Helm"}))
+
+		return obj
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		cluster = fake.NewCluster(ctx,
+			unstructFromYAML(`{apiVersion: v1, kind: Namespace, metadata: {name: app-ns, finalizers: [kubernetes]}}`),
+			owned(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: app-ns, finalizers: [example.com/cleanup]}}`),
+			owned(`{apiVersion: v1, kind: Service, metadata: {name: web, namespace: app-ns}}`),
+			owned(`{apiVersion: v1, kind: ConfigMap, metadata: {name: kept-in-cluster, namespace: app-ns, annotations: {helm.sh/resource-policy: keep}}}`),
+			unstructFromYAML(`{apiVersion: v1, kind: Secret, metadata: {name: foreign, namespace: app-ns, annotations: {meta.helm.sh/release-name: other}}}`),
+			owned(`{apiVersion: batch/v1, kind: Job, metadata: {name: pre-cleanup, namespace: app-ns, annotations: {helm.sh/hook: pre-delete}}}`),
+			owned(`{apiVersion: batch/v1, kind: Job, metadata: {name: post-notify, namespace: app-ns, annotations: {helm.sh/hook: post-delete}}}`),
+		)
+
+		general := func(manifest string) *resource.GeneralResource {
+			return resource.NewGeneralResource(unstructFromYAML(manifest), resource.GeneralResourceOptions{
+				DefaultNamespace: uninstallReleaseNamespace,
+				Mapper:           cluster.Mapper,
+			})
+		}
+
+		hook := func(manifest string) *resource.HookResource {
+			return resource.NewHookResource(unstructFromYAML(manifest), resource.HookResourceOptions{
+				DefaultNamespace: uninstallReleaseNamespace,
+				Mapper:           cluster.Mapper,
+			})
+		}
+
+		var err error
+		rel, err = release.NewRelease(uninstallReleaseName, uninstallReleaseNamespace, 1, nil, &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}},
+			[]*resource.HookResource{
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: pre-cleanup, annotations: {helm.sh/hook: pre-delete, helm.sh/hook-weight: "-5"}}}`),
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: pre-backup, annotations: {helm.sh/hook: pre-delete, helm.sh/hook-weight: "10"}}}`),
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: post-notify, annotations: {helm.sh/hook: post-delete}}}`),
+				hook(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install, helm.sh/resource-policy: keep}}}`),
+			},
+			[]*resource.GeneralResource{
+				general(`{apiVersion: v1, kind: Service, metadata: {name: web}}`),
+				general(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: web}}`),
+				general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: kept-in-chart, annotations: {helm.sh/resource-policy: keep}}}`),
+				general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: kept-in-cluster}}`),
+				general(`{apiVersion: v1, kind: Secret, metadata: {name: foreign}}`),
+				general(`{apiVersion: v1, kind: ConfigMap, metadata: {name: missing}}`),
+			},
+			"",
+			release.ReleaseOptions{Mapper: cluster.Mapper},
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	buildSteps := func(opts plan.UninstallStepsBuilderOptions) []*plan.UninstallStep {
+		steps, err := plan.NewUninstallStepsBuilder(uninstallReleaseNamespace, rel, cluster.KubeClient, cluster.Mapper, opts).Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		return steps
+	}
+
+	expectGolden := func(steps []*plan.UninstallStep, goldenFile string) {
+		outPath := filepath.Join(GinkgoT().TempDir(), "steps.json")
+		Expect(plan.SaveUninstallSteps(outPath, steps)).To(Succeed())
+
+		got, err := os.ReadFile(outPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		goldenPath := filepath.Join("testdata", goldenFile)
+		if os.Getenv("UPDATE_GOLDEN") != "" {
+			Expect(os.WriteFile(goldenPath, got, 0o644)).To(Succeed())
+		}
+
+		expected, err := os.ReadFile(goldenPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(got)).To(Equal(string(expected)))
+	}
+
+	It("orders hooks, keeps policied and foreign resources, skips missing ones and deletes the namespace", func() {
+		expectGolden(buildSteps(plan.UninstallStepsBuilderOptions{
+			DeleteHooks:            true,
+			DeleteReleaseNamespace: true,
+		}), "uninstall_steps_full.golden.json")
+	})
+
+	It("keeps hooks and the namespace unless their deletion is requested", func() {
+		expectGolden(buildSteps(plan.UninstallStepsBuilderOptions{}), "uninstall_steps_default.golden.json")
+	})
+})
+
+func mergeMaps(maps ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+
+	return result
+}
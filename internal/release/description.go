@@ -0,0 +1,29 @@
+package release
+
+import (
+	"fmt"
+
+	"github.com/werf/nelm/internal/common"
+)
+
+const MaxDescriptionLength = 512
+
+func DefaultDescription(deployType common.DeployType, chartName, chartVersion string) string {
+	switch deployType {
+	case common.DeployTypeUpgrade:
+		return fmt.Sprintf("Upgrade to chart %s-%s", chartName, chartVersion)
+	case common.DeployTypeRollback:
+		return fmt.Sprintf("Rollback to chart %s-%s", chartName, chartVersion)
+	default:
+		return fmt.Sprintf("Install chart %s-%s", chartName, chartVersion)
+	}
+}
+
+func TruncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= MaxDescriptionLength {
+		return description
+	}
+
+	return string(runes[:MaxDescriptionLength-3]) + "..."
+}
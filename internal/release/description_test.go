@@ -0,0 +1,69 @@
+package release_test
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/release"
+)
+
+var _ = Describe("release description", func() {
+	DescribeTable("defaults to the deploy type and chart",
+		func(deployType common.DeployType, expected string) {
+			Expect(release.DefaultDescription(deployType, "app", "1.2.3")).To(Equal(expected))
+		},
+		Entry("initial", common.DeployTypeInitial, "Install chart app-1.2.3"),
+		Entry("install", common.DeployTypeInstall, "Install chart app-1.2.3"),
+		Entry("upgrade", common.DeployTypeUpgrade, "Upgrade to chart app-1.2.3"),
+		Entry("rollback", common.DeployTypeRollback, "Rollback to chart app-1.2.3"),
+	)
+
+	DescribeTable("is truncated to the maximum length",
+		func(description, expected string) {
+			Expect(release.TruncateDescription(description)).To(Equal(expected))
+		},
+		Entry("empty", "", ""),
+		Entry("short", "Deploy hotfix", "Deploy hotfix"),
+		Entry("exactly at the limit", strings.Repeat("a", release.MaxDescriptionLength), strings.Repeat("a", release.MaxDescriptionLength)),
+		Entry("over the limit", strings.Repeat("a", release.MaxDescriptionLength+1), strings.Repeat("a", release.MaxDescriptionLength-3)+"..."),
+		Entry("multibyte over the limit", strings.Repeat("я", release.MaxDescriptionLength+1), strings.Repeat("я", release.MaxDescriptionLength-3)+"..."),
+	)
+
+	It("never splits a multibyte character", func() {
+		truncated := release.TruncateDescription(strings.Repeat("ab", release.MaxDescriptionLength) + "日本")
+		Expect(utf8.ValidString(truncated)).To(BeTrue())
+		Expect(utf8.RuneCountInString(truncated)).To(Equal(release.MaxDescriptionLength))
+	})
+
+	It("is truncated when a release is constructed", func() {
+		rel, err := release.NewRelease("app", "app-ns", 1, nil, legacyChart(), nil, nil, "", release.ReleaseOptions{
+			Description: strings.Repeat("a", release.MaxDescriptionLength*2),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utf8.RuneCountInString(rel.Description())).To(Equal(release.MaxDescriptionLength))
+	})
+
+	It("survives conversion to a legacy release and back", func() {
+		rel, err := release.NewRelease("app", "app-ns", 1, nil, legacyChart(), nil, nil, "", release.ReleaseOptions{
+			Description: "Deploy hotfix for INC-42",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		legacyRel, err := release.NewLegacyReleaseFromRelease(rel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(legacyRel.Info.Description).To(Equal("Deploy hotfix for INC-42"))
+
+		restored, err := release.NewReleaseFromLegacyRelease(legacyRel, release.ReleaseFromLegacyReleaseOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Description()).To(Equal("Deploy hotfix for INC-42"))
+	})
+})
+
+func legacyChart() *chart.Chart {
+	return &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}}
+}
@@ -0,0 +1,143 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	EventReasonDeployStarted   = "ReleaseDeployStarted"
+	EventReasonDeploySucceeded = "ReleaseDeploySucceeded"
+	EventReasonDeployFailed    = "ReleaseDeployFailed"
+)
+
+// Longer messages are rejected by the Events API.
+const eventMessageLimit = 1024
+
+const eventSourceComponent = "nelm"
+
+func NewEventRecorder(releaseName, releaseNamespace string, staticClient kubernetes.Interface, opts EventRecorderOptions) *EventRecorder {
+	involvedObject := opts.InvolvedObject
+	if involvedObject == nil {
+		involvedObject = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       releaseNamespace,
+		}
+	}
+
+	host, _ := os.Hostname()
+
+	return &EventRecorder{
+		releaseName:      releaseName,
+		releaseNamespace: releaseNamespace,
+		staticClient:     staticClient,
+		involvedObject:   *involvedObject,
+		host:             host,
+	}
+}
+
+type EventRecorderOptions struct {
+	// Defaults to the release namespace.
+	InvolvedObject *corev1.ObjectReference
+}
+
+// EventRecorder creates Kubernetes Events about the deploy lifecycle of a release in the release
+// namespace. Errors are only logged, since Events are informational.
+type EventRecorder struct {
+	releaseName      string
+	releaseNamespace string
+	staticClient     kubernetes.Interface
+	involvedObject   corev1.ObjectReference
+	host             string
+}
+
+// Started records that the action, e.g. "install", of the release revision started.
+func (r *EventRecorder) Started(ctx context.Context, action string, revision int) {
+	r.record(ctx, corev1.EventTypeNormal, EventReasonDeployStarted, fmt.Sprintf("Release %q revision %d %s started", r.releaseName, revision, action))
+}
+
+func (r *EventRecorder) Succeeded(ctx context.Context, action string, revision int) {
+	r.record(ctx, corev1.EventTypeNormal, EventReasonDeploySucceeded, fmt.Sprintf("Release %q revision %d %s succeeded", r.releaseName, revision, action))
+}
+
+// Failed records that the action failed, with the failed operations and the errors, if any.
+func (r *EventRecorder) Failed(ctx context.Context, action string, revision int, failedOps []string, errs []error) {
+	msg := fmt.Sprintf("Release %q revision %d %s failed", r.releaseName, revision, action)
+
+	if len(failedOps) > 0 {
+		msg += fmt.Sprintf("; failed operations: %s", strings.Join(failedOps, ", "))
+	}
+
+	if len(errs) > 0 {
+		var errMsgs []string
+		for _, err := range errs {
+			errMsgs = append(errMsgs, strings.Join(strings.Fields(err.Error()), " "))
+		}
+
+		msg += fmt.Sprintf("; errors: %s", strings.Join(errMsgs, "; "))
+	}
+
+	r.record(ctx, corev1.EventTypeWarning, EventReasonDeployFailed, msg)
+}
+
+func (r *EventRecorder) record(ctx context.Context, eventType, reason, msg string) {
+	now := metav1.Now()
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", r.involvedObject.Name, now.UnixNano()),
+			Namespace: r.releaseNamespace,
+		},
+		InvolvedObject: r.involvedObject,
+		Reason:         reason,
+		Message:        truncateEventMessage(msg),
+		Type:           eventType,
+		Source: corev1.EventSource{
+			Component: eventSourceComponent,
+			Host:      r.host,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	log.Release.Debug(ctx, "Creating event %q: %s", reason, event.Message)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if _, err := r.staticClient.CoreV1().Events(r.releaseNamespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		// The release namespace is not created yet on the first deploy.
+		if errors.IsNotFound(err) {
+			log.Release.Debug(ctx, "Skipped creating event %q: %s", reason, err)
+			return
+		}
+
+		log.Release.Warn(ctx, "Warning: create event %q for release %q (namespace: %q): %s", reason, r.releaseName, r.releaseNamespace, err)
+	}
+}
+
+func truncateEventMessage(msg string) string {
+	if len(msg) <= eventMessageLimit {
+		return msg
+	}
+
+	const suffix = "..."
+
+	msg = msg[:eventMessageLimit-len(suffix)]
+	// Don't cut multibyte characters in half.
+	msg = strings.ToValidUTF8(msg, "")
+
+	return msg + suffix
+}
@@ -2,6 +2,9 @@ package release
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -12,10 +15,22 @@ import (
 	helmrelease "github.com/werf/3p-helm/pkg/release"
 	"github.com/werf/3p-helm/pkg/releaseutil"
 	"github.com/werf/3p-helm/pkg/storage/driver"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var _ Historier = (*History)(nil)
 
+// Set once per deploy, to recognize the revision created by the same deploy on retries.
+const DeployIDInfoAnnotation = "werf.io/deploy-id"
+
+// Comma-separated resources which failed to become ready, but whose track failures are not fatal.
+// Not kept between revisions.
+const DegradedResourcesInfoAnnotation = "werf.io/degraded-resources"
+
+// Set on the release revision whose resources were migrated to be managed by Nelm.
+const MigratedInfoAnnotation = "werf.io/migrated-by-nelm"
+
 func NewHistory(releaseName, releaseNamespace string, historyStorage LegacyStorage, opts HistoryOptions) (*History, error) {
 	legacyRels, err := historyStorage.Query(map[string]string{"name": releaseName, "owner": "helm"})
 	if err != nil && err != driver.ErrReleaseNotFound {
@@ -169,6 +184,89 @@ legacyRelLoop:
 	return rel, true, nil
 }
 
+func (h *History) Releases() ([]*Release, error) {
+	var rels []*Release
+	for _, legacyRel := range h.legacyReleases {
+		rel, err := NewReleaseFromLegacyRelease(legacyRel, ReleaseFromLegacyReleaseOptions{
+			Mapper:          h.mapper,
+			DiscoveryClient: h.discoveryClient,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error constructing release from legacy release: %w", err)
+		}
+
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
+
+// PruneReleases deletes the oldest superseded revisions until no more than maxReleases are left.
+// Failed revisions followed by a newer revision are superseded too. Pending revisions, the last
+// revision and the last successfully deployed revision are never deleted, so more revisions than
+// maxReleases might be left.
+func (h *History) PruneReleases(ctx context.Context, maxReleases int) error {
+	h.updateLock.Lock()
+	defer h.updateLock.Unlock()
+
+	if maxReleases <= 0 || len(h.legacyReleases) <= maxReleases {
+		return nil
+	}
+
+	keep := map[int]bool{
+		h.legacyReleases[len(h.legacyReleases)-1].Version: true,
+	}
+	for i := len(h.legacyReleases) - 1; i >= 0; i-- {
+		if h.legacyReleases[i].Info.Status == helmrelease.StatusDeployed {
+			keep[h.legacyReleases[i].Version] = true
+			break
+		}
+	}
+
+	var (
+		errs      []error
+		remaining []*helmrelease.Release
+	)
+	excess := len(h.legacyReleases) - maxReleases
+	for _, legacyRel := range h.legacyReleases {
+		if excess == 0 || keep[legacyRel.Version] || !supersededStatus(legacyRel.Info.Status) {
+			remaining = append(remaining, legacyRel)
+			continue
+		}
+
+		log.Release.Debug(ctx, "Deleting release %q (namespace: %q, revision: %d) from history", legacyRel.Name, legacyRel.Namespace, legacyRel.Version)
+		if _, err := h.storage.Delete(legacyRel.Name, legacyRel.Version); err != nil {
+			errs = append(errs, fmt.Errorf("error deleting release %q (namespace: %q, revision: %d): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err))
+			remaining = append(remaining, legacyRel)
+			continue
+		}
+
+		excess--
+	}
+
+	h.legacyReleases = remaining
+
+	return util.Multierrorf("error pruning history of release %q (namespace: %q)", errs, h.releaseName, h.releaseNamespace)
+}
+
+// Whether a revision with this status, if it's not the last one, is replaced by a newer revision.
+func supersededStatus(status helmrelease.Status) bool {
+	switch status {
+	case helmrelease.StatusDeployed,
+		helmrelease.StatusSuperseded,
+		helmrelease.StatusFailed:
+		return true
+	case helmrelease.StatusUnknown,
+		helmrelease.StatusUninstalled,
+		helmrelease.StatusUninstalling,
+		helmrelease.StatusPendingInstall,
+		helmrelease.StatusPendingUpgrade,
+		helmrelease.StatusPendingRollback:
+	}
+
+	return false
+}
+
 func (h *History) Empty() bool {
 	return len(h.legacyReleases) == 0
 }
@@ -182,15 +280,91 @@ func (h *History) CreateRelease(ctx context.Context, rel *Release) error {
 		return fmt.Errorf("error constructing legacy release from release: %w", err)
 	}
 
+	if stored, found := h.storedBySameDeploy(legacyRel); found {
+		if legacyReleaseContentHash(stored) == legacyReleaseContentHash(legacyRel) {
+			log.Release.Debug(ctx, "Release %q (namespace: %q, revision: %d) already stored by this deploy, reusing it", legacyRel.Name, legacyRel.Namespace, legacyRel.Version)
+			h.addLegacyRelease(stored)
+			return nil
+		}
+
+		log.Release.Debug(ctx, "Release %q (namespace: %q, revision: %d) already stored by this deploy with different content, overwriting it", legacyRel.Name, legacyRel.Namespace, legacyRel.Version)
+		if err := h.storage.Update(legacyRel); err != nil {
+			return fmt.Errorf("error updating release %q (namespace: %q, revision: %d): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
+		}
+		h.addLegacyRelease(legacyRel)
+
+		return nil
+	}
+
 	if err := h.storage.Create(legacyRel); err != nil {
+		// The write might have succeeded despite the error, e.g. on client-side timeout.
+		if stored, found := h.storedBySameDeploy(legacyRel); found && legacyReleaseContentHash(stored) == legacyReleaseContentHash(legacyRel) {
+			log.Release.Debug(ctx, "Release %q (namespace: %q, revision: %d) stored despite the error, reusing it: %s", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
+			h.addLegacyRelease(stored)
+			return nil
+		}
+
 		return fmt.Errorf("error creating release %q (namespace: %q, revision: %q): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
 	}
 
-	h.legacyReleases = append(h.legacyReleases, legacyRel)
+	h.addLegacyRelease(legacyRel)
 
 	return nil
 }
 
+// Returns the latest stored revision if it has the same revision number and was created by the same
+// deploy.
+func (h *History) storedBySameDeploy(legacyRel *helmrelease.Release) (*helmrelease.Release, bool) {
+	deployID := legacyRel.Info.Annotations[DeployIDInfoAnnotation]
+	if deployID == "" {
+		return nil, false
+	}
+
+	storedRels, err := h.storage.Query(map[string]string{"name": legacyRel.Name, "owner": "helm"})
+	if err != nil || len(storedRels) == 0 {
+		return nil, false
+	}
+	releaseutil.SortByRevision(storedRels)
+	latest := storedRels[len(storedRels)-1]
+
+	if latest.Version != legacyRel.Version ||
+		latest.Info == nil ||
+		latest.Info.Annotations[DeployIDInfoAnnotation] != deployID {
+		return nil, false
+	}
+
+	return latest, true
+}
+
+func (h *History) addLegacyRelease(legacyRel *helmrelease.Release) {
+	if _, i, found := lo.FindIndexOf(h.legacyReleases, func(r *helmrelease.Release) bool {
+		return r.Version == legacyRel.Version
+	}); found {
+		h.legacyReleases[i] = legacyRel
+	} else {
+		h.legacyReleases = append(h.legacyReleases, legacyRel)
+	}
+}
+
+func legacyReleaseContentHash(legacyRel *helmrelease.Release) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(legacyRel.Manifest))
+	for _, hook := range legacyRel.Hooks {
+		hash.Write([]byte(hook.Manifest))
+	}
+
+	if config, err := json.Marshal(legacyRel.Config); err == nil {
+		hash.Write(config)
+	}
+
+	if legacyRel.Chart != nil && legacyRel.Chart.Metadata != nil {
+		hash.Write([]byte(legacyRel.Chart.Metadata.Name + "-" + legacyRel.Chart.Metadata.Version))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 func (h *History) UpdateRelease(ctx context.Context, rel *Release) error {
 	h.updateLock.Lock()
 	defer h.updateLock.Unlock()
@@ -219,6 +393,7 @@ type LegacyStorage interface {
 	Create(rls *helmrelease.Release) error
 	Update(rls *helmrelease.Release) error
 	Query(labels map[string]string) ([]*helmrelease.Release, error)
+	Delete(name string, version int) (*helmrelease.Release, error)
 }
 
 type Historier interface {
@@ -0,0 +1,162 @@
+package release_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	helmrelease "github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/3p-helm/pkg/storage"
+	"github.com/werf/3p-helm/pkg/storage/driver"
+	"github.com/werf/nelm/internal/release"
+)
+
+var _ = Describe("release history", func() {
+	var (
+		ctx     context.Context
+		storage *flakyStorage
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = newFlakyStorage()
+	})
+
+	newHistory := func() *release.History {
+		history, err := release.NewHistory("app", "app-ns", storage, release.HistoryOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		return history
+	}
+
+	newRelease := func(revision int, deployID string, values map[string]interface{}) *release.Release {
+		rel, err := release.NewRelease("app", "app-ns", revision, values, legacyChart(), nil, nil, "", release.ReleaseOptions{
+			InfoAnnotations: map[string]string{release.DeployIDInfoAnnotation: deployID},
+			Status:          helmrelease.StatusPendingInstall,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return rel
+	}
+
+	storedRevisions := func() []*helmrelease.Release {
+		rels, err := storage.Query(map[string]string{"name": "app", "owner": "helm"})
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		return rels
+	}
+
+	values := map[string]interface{}{"replicas": 1}
+
+	It("keeps a revision written despite a write timeout", func() {
+		storage.failNextCreateAfterWrite = true
+
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-1", values))).To(Succeed())
+		Expect(storedRevisions()).To(HaveLen(1))
+	})
+
+	It("reuses the revision stored by an earlier attempt of the same deploy", func() {
+		legacyRel, err := release.NewLegacyReleaseFromRelease(newRelease(1, "deploy-1", values))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(storage.Storage.Create(legacyRel)).To(Succeed())
+
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-1", values))).To(Succeed())
+		Expect(storedRevisions()).To(HaveLen(1))
+	})
+
+	It("overwrites the revision stored by an earlier attempt of the same deploy with new content", func() {
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-1", values))).To(Succeed())
+
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-1", map[string]interface{}{"replicas": 2}))).To(Succeed())
+
+		rels := storedRevisions()
+		Expect(rels).To(HaveLen(1))
+		Expect(rels[0].Config).To(HaveKeyWithValue("replicas", 2))
+	})
+
+	It("doesn't reuse a revision stored by another deploy", func() {
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-1", values))).To(Succeed())
+
+		Expect(newHistory().CreateRelease(ctx, newRelease(1, "deploy-2", values))).To(MatchError(ContainSubstring("error creating release")))
+		Expect(storedRevisions()).To(HaveLen(1))
+		Expect(storedRevisions()[0].Info.Annotations).To(HaveKeyWithValue(release.DeployIDInfoAnnotation, "deploy-1"))
+	})
+
+	DescribeTable("prunes superseded revisions",
+		func(maxReleases int, statuses []helmrelease.Status, expectedRevisions []int) {
+			for i, status := range statuses {
+				legacyRel, err := release.NewLegacyReleaseFromRelease(newRelease(i+1, "", values))
+				Expect(err).NotTo(HaveOccurred())
+				legacyRel.Info.Status = status
+				Expect(storage.Storage.Create(legacyRel)).To(Succeed())
+			}
+
+			history := newHistory()
+			Expect(history.PruneReleases(ctx, maxReleases)).To(Succeed())
+
+			var revisions []int
+			for _, legacyRel := range storedRevisions() {
+				revisions = append(revisions, legacyRel.Version)
+			}
+			Expect(revisions).To(ConsistOf(expectedRevisions))
+
+			rels, err := history.Releases()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rels).To(HaveLen(len(expectedRevisions)))
+		},
+		Entry("within the limit",
+			3,
+			[]helmrelease.Status{helmrelease.StatusSuperseded, helmrelease.StatusDeployed},
+			[]int{1, 2},
+		),
+		Entry("oldest first",
+			3,
+			[]helmrelease.Status{helmrelease.StatusSuperseded, helmrelease.StatusSuperseded, helmrelease.StatusSuperseded, helmrelease.StatusSuperseded, helmrelease.StatusDeployed},
+			[]int{3, 4, 5},
+		),
+		Entry("failed ones of a release which keeps failing, except the last deployed and the last revision",
+			2,
+			[]helmrelease.Status{helmrelease.StatusSuperseded, helmrelease.StatusDeployed, helmrelease.StatusFailed, helmrelease.StatusFailed, helmrelease.StatusFailed},
+			[]int{2, 5},
+		),
+		Entry("except pending ones",
+			2,
+			[]helmrelease.Status{helmrelease.StatusSuperseded, helmrelease.StatusPendingUpgrade, helmrelease.StatusSuperseded, helmrelease.StatusDeployed},
+			[]int{2, 4},
+		),
+		Entry("except uninstalled ones",
+			1,
+			[]helmrelease.Status{helmrelease.StatusUninstalled, helmrelease.StatusFailed, helmrelease.StatusFailed},
+			[]int{1, 3},
+		),
+	)
+})
+
+// flakyStorage can store a release and then fail as if the write timed out on the client side.
+type flakyStorage struct {
+	*storage.Storage
+
+	failNextCreateAfterWrite bool
+}
+
+func newFlakyStorage() *flakyStorage {
+	return &flakyStorage{Storage: storage.Init(driver.NewMemory())}
+}
+
+func (s *flakyStorage) Create(rls *helmrelease.Release) error {
+	if err := s.Storage.Create(rls); err != nil {
+		return err
+	}
+
+	if s.failNextCreateAfterWrite {
+		s.failNextCreateAfterWrite = false
+		return errors.New("context deadline exceeded")
+	}
+
+	return nil
+}
@@ -39,6 +39,7 @@ func NewLegacyReleaseFromRelease(rel *Release) (*helmrelease.Release, error) {
 		Version:   rel.Revision(),
 		Info: &helmrelease.Info{
 			Annotations:   rel.InfoAnnotations(),
+			Description:   rel.Description(),
 			FirstDeployed: time.Time{Time: rel.FirstDeployed()},
 			LastDeployed:  time.Time{Time: rel.LastDeployed()},
 			Status:        rel.Status(),
@@ -7,6 +7,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/client-go/discovery"
 
@@ -44,6 +45,8 @@ func NewRelease(name, namespace string, revision int, values map[string]interfac
 		opts.InfoAnnotations = map[string]string{}
 	}
 
+	description := TruncateDescription(opts.Description)
+
 	return &Release{
 		name:             name,
 		namespace:        namespace,
@@ -58,6 +61,7 @@ func NewRelease(name, namespace string, revision int, values map[string]interfac
 		chartName:        legacyChart.Metadata.Name,
 		chartVersion:     legacyChart.Metadata.Version,
 		infoAnnotations:  opts.InfoAnnotations,
+		description:      description,
 		hookResources:    hookResources,
 		generalResources: generalResources,
 		notes:            notes,
@@ -65,6 +69,7 @@ func NewRelease(name, namespace string, revision int, values map[string]interfac
 }
 
 type ReleaseOptions struct {
+	Description     string
 	InfoAnnotations map[string]string
 	Status          helmrelease.Status
 	FirstDeployed   time.Time
@@ -101,6 +106,7 @@ func NewReleaseFromLegacyRelease(legacyRelease *helmrelease.Release, opts Releas
 	}
 
 	rel, err := NewRelease(legacyRelease.Name, legacyRelease.Namespace, legacyRelease.Version, legacyRelease.Config, legacyRelease.Chart, hookResources, generalResources, legacyRelease.Info.Notes, ReleaseOptions{
+		Description:     legacyRelease.Info.Description,
 		InfoAnnotations: legacyRelease.Info.Annotations,
 		Status:          legacyRelease.Info.Status,
 		FirstDeployed:   legacyRelease.Info.FirstDeployed.Time,
@@ -134,6 +140,7 @@ type Release struct {
 	chartName       string
 	chartVersion    string
 	infoAnnotations map[string]string
+	description     string
 
 	hookResources    []*resource.HookResource
 	generalResources []*resource.GeneralResource
@@ -200,6 +207,14 @@ func (r *Release) InfoAnnotations() map[string]string {
 	return r.infoAnnotations
 }
 
+func (r *Release) SetInfoAnnotations(annotations map[string]string) {
+	r.infoAnnotations = lo.Assign(r.infoAnnotations, annotations)
+}
+
+func (r *Release) Description() string {
+	return r.description
+}
+
 func (r *Release) ID() string {
 	return fmt.Sprintf("%s:%s:%d", r.namespace, r.name, r.revision)
 }
@@ -245,6 +260,17 @@ func (r *Release) Failed() bool {
 	return false
 }
 
+func (r *Release) Pending() bool {
+	switch r.status {
+	case helmrelease.StatusPendingInstall,
+		helmrelease.StatusPendingUpgrade,
+		helmrelease.StatusPendingRollback:
+		return true
+	}
+
+	return false
+}
+
 func (r *Release) Pend(deployType common.DeployType) {
 	r.status = helmrelease.StatusPendingInstall
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +35,51 @@ const (
 	ManageableBySingleRelease ManageableBy = "manageable-by-single-release"
 )
 
+// Hooks of this type are not run on install/upgrade/rollback events, but as a separate Job between
+// deploy stages, specified by the werf.io/exec-after annotation.
+const HookTypeExec = "exec"
+
+const (
+	// Run after the deploy is planned, but before anything is changed in the cluster, e.g. to
+	// check whether the deploy is approved.
+	HookTypePrePlan = "pre-plan"
+	// Run after all resources and post-hooks are ready, but before the release is marked as
+	// deployed, e.g. to run smoke tests.
+	HookTypePostReadiness = "post-readiness"
+)
+
+const (
+	ExecAfterStandaloneCRDs   = "standalone-crds"
+	ExecAfterPreHooks         = "pre-hooks"
+	ExecAfterGeneralCRDs      = "general-crds"
+	ExecAfterGeneralResources = "general-resources"
+	ExecAfterPostHooks        = "post-hooks"
+)
+
+var ExecAfterStages = []string{
+	ExecAfterStandaloneCRDs,
+	ExecAfterPreHooks,
+	ExecAfterGeneralCRDs,
+	ExecAfterGeneralResources,
+	ExecAfterPostHooks,
+}
+
+// How CRDs from the crds/ directory of the chart are deployed.
+type CRDPolicy string
+
+const (
+	// Don't deploy the CRD at all, e.g. if it is managed by someone else.
+	CRDPolicySkip CRDPolicy = "skip"
+	// Create the CRD if it doesn't exist, but never update it, same as Helm does.
+	CRDPolicyCreate CRDPolicy = "create"
+	// Create or update the CRD and wait for it to be established.
+	CRDPolicyUpdate CRDPolicy = "update"
+)
+
+const DefaultCRDPolicy = CRDPolicyUpdate
+
+var CRDPolicies = []CRDPolicy{CRDPolicySkip, CRDPolicyCreate, CRDPolicyUpdate}
+
 var (
 	annotationKeyHumanReleaseName   = "meta.helm.sh/release-name"
 	annotationKeyPatternReleaseName = regexp.MustCompile(`^meta.helm.sh/release-name$`)
@@ -51,7 +97,7 @@ var (
 
 var (
 	annotationKeyHumanHook   = "helm.sh/hook"
-	annotationKeyPatternHook = regexp.MustCompile(`^helm.sh/hook$`)
+	annotationKeyPatternHook = regexp.MustCompile(`^(helm.sh|werf.io)/hook$`)
 )
 
 var (
@@ -79,6 +125,11 @@ var (
 	annotationKeyPatternFailMode = regexp.MustCompile(`^werf.io/fail-mode$`)
 )
 
+var (
+	annotationKeyHumanFatalTrackFailure   = "werf.io/fatal-track-failure"
+	annotationKeyPatternFatalTrackFailure = regexp.MustCompile(`^werf.io/fatal-track-failure$`)
+)
+
 var (
 	annotationKeyHumanFailuresAllowedPerReplica   = "werf.io/failures-allowed-per-replica"
 	annotationKeyPatternFailuresAllowedPerReplica = regexp.MustCompile(`^werf.io/failures-allowed-per-replica$`)
@@ -104,6 +155,11 @@ var (
 	annotationKeyPatternNoActivityTimeout = regexp.MustCompile(`^werf.io/no-activity-timeout$`)
 )
 
+var (
+	annotationKeyHumanReadyStableFor   = "werf.io/ready-stable-for"
+	annotationKeyPatternReadyStableFor = regexp.MustCompile(`^werf.io/ready-stable-for$`)
+)
+
 var (
 	annotationKeyHumanShowLogsOnlyForContainers   = "werf.io/show-logs-only-for-containers"
 	annotationKeyPatternShowLogsOnlyForContainers = regexp.MustCompile(`^werf.io/show-logs-only-for-containers$`)
@@ -124,6 +180,36 @@ var (
 	annotationKeyPatternSkipLogsForContainers = regexp.MustCompile(`^werf.io/skip-logs-for-containers$`)
 )
 
+var (
+	annotationKeyHumanStrictReadiness   = "werf.io/strict-readiness"
+	annotationKeyPatternStrictReadiness = regexp.MustCompile(`^werf.io/strict-readiness$`)
+)
+
+var (
+	annotationKeyHumanTrackCondition   = "werf.io/track-condition"
+	annotationKeyPatternTrackCondition = regexp.MustCompile(`^werf.io/track-condition$`)
+)
+
+var (
+	annotationKeyHumanTrackJSONPath   = "werf.io/track-jsonpath"
+	annotationKeyPatternTrackJSONPath = regexp.MustCompile(`^werf.io/track-jsonpath$`)
+)
+
+var (
+	annotationKeyHumanTrackFailureCondition   = "werf.io/track-failure-condition"
+	annotationKeyPatternTrackFailureCondition = regexp.MustCompile(`^werf.io/track-failure-condition$`)
+)
+
+var (
+	annotationKeyHumanTrackFailureJSONPath   = "werf.io/track-failure-jsonpath"
+	annotationKeyPatternTrackFailureJSONPath = regexp.MustCompile(`^werf.io/track-failure-jsonpath$`)
+)
+
+var (
+	annotationKeyHumanTrackTimeout   = "werf.io/track-timeout"
+	annotationKeyPatternTrackTimeout = regexp.MustCompile(`^werf.io/track-timeout$`)
+)
+
 var (
 	annotationKeyHumanTrackTerminationMode   = "werf.io/track-termination-mode"
 	annotationKeyPatternTrackTerminationMode = regexp.MustCompile(`^werf.io/track-termination-mode$`)
@@ -149,6 +235,11 @@ var (
 	annotationKeyPatternDependency = regexp.MustCompile(`^(?P<id>.+).dependency.werf.io$`)
 )
 
+var (
+	annotationKeyHumanDependsOn   = "werf.io/depends-on"
+	annotationKeyPatternDependsOn = regexp.MustCompile(`^werf.io/depends-on$`)
+)
+
 var (
 	annotationKeyHumanExternalDependency   = "<name>.external-dependency.werf.io"
 	annotationKeyPatternExternalDependency = regexp.MustCompile(`^(?P<id>.+).external-dependency.werf.io$`)
@@ -164,12 +255,86 @@ var (
 	annotationKeyPatternLegacyExternalDependencyNamespace = regexp.MustCompile(`^(?P<id>.+).external-dependency.werf.io/namespace$`)
 )
 
+var (
+	annotationKeyHumanExternalDependencyState   = "<name>.external-dependency.werf.io/state"
+	annotationKeyPatternExternalDependencyState = regexp.MustCompile(`^(?P<id>.+).external-dependency.werf.io/state$`)
+)
+
 var (
 	annotationKeyHumanSensitive   = "werf.io/sensitive"
 	annotationKeyPatternSensitive = regexp.MustCompile(`^werf.io/sensitive$`)
 )
 
+var (
+	annotationKeyHumanReplaceOnImmutableChange   = "werf.io/replace-on-immutable-change"
+	annotationKeyPatternReplaceOnImmutableChange = regexp.MustCompile(`^werf.io/replace-on-immutable-change$`)
+)
+
+var (
+	annotationKeyHumanIgnoreFields   = "werf.io/ignore-fields"
+	annotationKeyPatternIgnoreFields = regexp.MustCompile(`^werf.io/ignore-fields$`)
+)
+
+var (
+	annotationKeyHumanManifestHash   = "werf.io/manifest-hash"
+	annotationKeyPatternManifestHash = regexp.MustCompile(`^werf.io/manifest-hash$`)
+)
+
+var (
+	annotationKeyHumanDeployDelay   = "werf.io/deploy-delay"
+	annotationKeyPatternDeployDelay = regexp.MustCompile(`^werf.io/deploy-delay$`)
+)
+
+var (
+	annotationKeyHumanExecAfter   = "werf.io/exec-after"
+	annotationKeyPatternExecAfter = regexp.MustCompile(`^werf.io/exec-after$`)
+)
+
+var (
+	annotationKeyHumanHookParallel   = "werf.io/hook-parallel"
+	annotationKeyPatternHookParallel = regexp.MustCompile(`^werf.io/hook-parallel$`)
+)
+
+var (
+	annotationKeyHumanCRDPolicy   = "werf.io/crd-policy"
+	annotationKeyPatternCRDPolicy = regexp.MustCompile(`^werf.io/crd-policy$`)
+)
+
+var (
+	annotationKeyHumanAllowAdoptionByRelease   = "werf.io/allow-adoption-by-release"
+	annotationKeyPatternAllowAdoptionByRelease = regexp.MustCompile(`^werf.io/allow-adoption-by-release$`)
+)
+
+var (
+	annotationKeyHumanOperationRetries   = "werf.io/operation-retries"
+	annotationKeyPatternOperationRetries = regexp.MustCompile(`^werf.io/operation-retries$`)
+)
+
+// Set on the release namespace by the release which created or adopted it.
+var (
+	annotationKeyHumanReleaseNamespaceOwner   = "werf.io/release-namespace-owner"
+	annotationKeyPatternReleaseNamespaceOwner = regexp.MustCompile(`^werf.io/release-namespace-owner$`)
+)
+
+func validateCRDPolicy(res *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(res.GetAnnotations(), annotationKeyPatternCRDPolicy); found {
+		if !lo.Contains(CRDPolicies, CRDPolicy(value)) {
+			return fmt.Errorf("invalid value %q for annotation %q, expected one of: %s", value, key, strings.Join(lo.Map(CRDPolicies, func(p CRDPolicy, _ int) string {
+				return string(p)
+			}), ", "))
+		}
+	}
+
+	return nil
+}
+
 func validateHook(res *unstructured.Unstructured) error {
+	if keys := lo.Filter(lo.Keys(res.GetAnnotations()), func(key string, _ int) bool {
+		return annotationKeyPatternHook.MatchString(key)
+	}); len(keys) > 1 {
+		return fmt.Errorf("annotations %q and %q can't be used together", annotationKeyHumanHook, "werf.io/hook")
+	}
+
 	if key, value, found := FindAnnotationOrLabelByKeyPattern(res.GetAnnotations(), annotationKeyPatternHook); found {
 		if value == "" {
 			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty string value", value, key)
@@ -192,6 +357,14 @@ func validateHook(res *unstructured.Unstructured) error {
 				string(helmrelease.HookPostDelete),
 				string(helmrelease.HookTest),
 				"test-success":
+			case HookTypeExec:
+				if err := validateExecHook(res, value, key); err != nil {
+					return err
+				}
+			case HookTypePrePlan, HookTypePostReadiness:
+				if strings.Contains(value, ",") {
+					return fmt.Errorf("invalid value %q for annotation %q, hook type %q can't be combined with other hook types", value, key, hookType)
+				}
 			default:
 				return fmt.Errorf("value %q for annotation %q is not supported", value, key)
 			}
@@ -203,6 +376,41 @@ func validateHook(res *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateHookParallel(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternHookParallel); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty boolean value", value, key)
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected boolean value", value, key)
+		}
+	}
+
+	return nil
+}
+
+func validateExecHook(res *unstructured.Unstructured, hookValue, hookKey string) error {
+	if strings.Contains(hookValue, ",") {
+		return fmt.Errorf("invalid value %q for annotation %q, hook type %q can't be combined with other hook types", hookValue, hookKey, HookTypeExec)
+	}
+
+	if gk := res.GroupVersionKind().GroupKind(); gk.Group != "batch" || gk.Kind != "Job" {
+		return fmt.Errorf("hook type %q is supported only for batch/Job resources", HookTypeExec)
+	}
+
+	key, value, found := FindAnnotationOrLabelByKeyPattern(res.GetAnnotations(), annotationKeyPatternExecAfter)
+	if !found {
+		return fmt.Errorf("annotation %q is required for hooks of type %q", annotationKeyHumanExecAfter, HookTypeExec)
+	}
+
+	if !lo.Contains(ExecAfterStages, value) {
+		return fmt.Errorf("invalid value %q for annotation %q, expected one of: %s", value, key, strings.Join(ExecAfterStages, ", "))
+	}
+
+	return nil
+}
+
 func validateWeight(unstruct *unstructured.Unstructured) error {
 	if IsHook(unstruct.GetAnnotations()) {
 		if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternHookWeight); found {
@@ -306,6 +514,50 @@ func validateReplicasOnCreation(unstruct *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateDeployDelay(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDeployDelay); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty duration value", value, key)
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected valid duration", value, key)
+		}
+
+		if duration < 0 {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-negative duration value", value, key)
+		}
+	}
+
+	return nil
+}
+
+func validateAllowAdoptionByRelease(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternAllowAdoptionByRelease); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty release name", value, key)
+		}
+	}
+
+	return nil
+}
+
+func validateOperationRetries(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternOperationRetries); found {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, value must be a number", value, key)
+		}
+
+		if retries < 0 {
+			return fmt.Errorf("invalid value %q for annotation %q, value must be a positive number or zero", value, key)
+		}
+	}
+
+	return nil
+}
+
 func validateTrack(unstruct *unstructured.Unstructured) error {
 	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFailMode); found {
 		if value == "" {
@@ -321,6 +573,16 @@ func validateTrack(unstruct *unstructured.Unstructured) error {
 		}
 	}
 
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFatalTrackFailure); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty boolean value", value, key)
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected boolean value", value, key)
+		}
+	}
+
 	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFailuresAllowedPerReplica); found {
 		if value == "" {
 			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty integer value", value, key)
@@ -450,6 +712,31 @@ func validateTrack(unstruct *unstructured.Unstructured) error {
 		}
 	}
 
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternStrictReadiness); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty boolean value", value, key)
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected boolean value", value, key)
+		}
+	}
+
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReadyStableFor); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty duration value", value, key)
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected valid duration", value, key)
+		}
+
+		if duration < 0 {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-negative duration value", value, key)
+		}
+	}
+
 	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternSkipLogsForContainers); found {
 		if value == "" {
 			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty string value", value, key)
@@ -478,6 +765,43 @@ func validateTrack(unstruct *unstructured.Unstructured) error {
 		}
 	}
 
+	for _, pattern := range []*regexp.Regexp{annotationKeyPatternTrackCondition, annotationKeyPatternTrackFailureCondition} {
+		if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), pattern); found {
+			if _, err := ParseStatusTrackCondition(value); err != nil {
+				return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
+			}
+		}
+	}
+
+	for _, pattern := range []*regexp.Regexp{annotationKeyPatternTrackJSONPath, annotationKeyPatternTrackFailureJSONPath} {
+		if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), pattern); found {
+			if _, err := ParseJSONPathTrackCondition(value); err != nil {
+				return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
+			}
+		}
+	}
+
+	if _, readySet := trackReadyConditions(unstruct); !readySet {
+		if _, failureSet := trackFailureConditions(unstruct); failureSet {
+			return fmt.Errorf("annotations %q and %q require annotation %q or %q", annotationKeyHumanTrackFailureCondition, annotationKeyHumanTrackFailureJSONPath, annotationKeyHumanTrackCondition, annotationKeyHumanTrackJSONPath)
+		}
+	}
+
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternTrackTimeout); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty duration value", value, key)
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected valid duration", value, key)
+		}
+
+		if duration < 0 {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-negative duration value", value, key)
+		}
+	}
+
 	return nil
 }
 
@@ -504,7 +828,7 @@ func validateDeployDependencies(unstruct *unstructured.Unstructured) error {
 
 			properties, err := util.ParseProperties(context.TODO(), value)
 			if err != nil {
-				return fmt.Errorf("invalid value %q for annotation %q: %w", err)
+				return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
 			}
 
 			if !lo.Some(lo.Keys(properties), []string{"group", "version", "kind", "name", "namespace"}) {
@@ -524,7 +848,7 @@ func validateDeployDependencies(unstruct *unstructured.Unstructured) error {
 							return fmt.Errorf("invalid value %q for property %q, expected non-empty string value", pv, propKey)
 						}
 					case bool:
-						return fmt.Errorf("invalid boolean value %q for property %q, expected string value", pv, propKey)
+						return fmt.Errorf("invalid boolean value %t for property %q, expected string value", pv, propKey)
 					default:
 						panic(fmt.Sprintf("unexpected type %T for property %q", pv, propKey))
 					}
@@ -539,7 +863,7 @@ func validateDeployDependencies(unstruct *unstructured.Unstructured) error {
 							return fmt.Errorf("unknown value %q for property %q", pv, propKey)
 						}
 					case bool:
-						return fmt.Errorf("invalid boolean value %q for property %q, expected string value", pv, propKey)
+						return fmt.Errorf("invalid boolean value %t for property %q, expected string value", pv, propKey)
 					default:
 						panic(fmt.Sprintf("unexpected type %T for property %q", pv, propKey))
 					}
@@ -553,6 +877,21 @@ func validateDeployDependencies(unstruct *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateDependsOn(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDependsOn); found {
+		entries, err := parseDependsOn(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
+		}
+
+		if len(entries) == 0 {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty comma-separated list of resources", value, key)
+		}
+	}
+
+	return nil
+}
+
 func validateInternalDependencies(unstruct *unstructured.Unstructured) error {
 	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDependency); found {
 		for key, value := range annotations {
@@ -672,6 +1011,25 @@ func validateExternalDependencies(unstruct *unstructured.Unstructured) error {
 		}
 	}
 
+	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternExternalDependencyState); found {
+		for key, value := range annotations {
+			switch dependency.ResourceState(value) {
+			case dependency.ResourceStatePresent, dependency.ResourceStateReady:
+			default:
+				return fmt.Errorf("invalid value %q for annotation %q, expected %q or %q", value, key, dependency.ResourceStatePresent, dependency.ResourceStateReady)
+			}
+
+			depID := annotationKeyPatternExternalDependencyState.FindStringSubmatch(key)[annotationKeyPatternExternalDependencyState.SubexpIndex("id")]
+
+			depKey, legacyDepKey := depID+".external-dependency.werf.io", depID+".external-dependency.werf.io/resource"
+			if _, found := unstruct.GetAnnotations()[depKey]; !found {
+				if _, found := unstruct.GetAnnotations()[legacyDepKey]; !found {
+					return fmt.Errorf("annotation %q requires annotation %q or %q", key, depKey, legacyDepKey)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -689,6 +1047,20 @@ func validateSensitive(unstruct *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateReplaceOnImmutableChange(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReplaceOnImmutableChange); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty boolean value", value, key)
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected boolean value", value, key)
+		}
+	}
+
+	return nil
+}
+
 func on(unstruct *unstructured.Unstructured, phases ...string) bool {
 	_, value := lo.Must2(FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternHook))
 	valPhases := lo.Map(strings.Split(value, ","), func(p string, _ int) string {
@@ -742,6 +1114,42 @@ func onTest(unstruct *unstructured.Unstructured) bool {
 	return on(unstruct, string(helmrelease.HookTest), "test-success")
 }
 
+func onExec(unstruct *unstructured.Unstructured) bool {
+	return on(unstruct, HookTypeExec)
+}
+
+func onPrePlan(unstruct *unstructured.Unstructured) bool {
+	return on(unstruct, HookTypePrePlan)
+}
+
+func onPostReadiness(unstruct *unstructured.Unstructured) bool {
+	return on(unstruct, HookTypePostReadiness)
+}
+
+func crdPolicy(unstruct *unstructured.Unstructured) (policy CRDPolicy, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternCRDPolicy)
+	if !found {
+		return "", false
+	}
+
+	return CRDPolicy(value), true
+}
+
+func hookParallel(unstruct *unstructured.Unstructured) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternHookParallel)
+	if !found {
+		return true
+	}
+
+	return lo.Must(strconv.ParseBool(value))
+}
+
+func execAfter(unstruct *unstructured.Unstructured) string {
+	_, value, _ := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternExecAfter)
+
+	return value
+}
+
 func onPreAnything(unstruct *unstructured.Unstructured) bool {
 	return onPreInstall(unstruct) || onPreUpgrade(unstruct) || onPreRollback(unstruct) || onPreDelete(unstruct)
 }
@@ -786,6 +1194,15 @@ func recreate(unstruct *unstructured.Unstructured) bool {
 	return lo.Contains(deletePolicies, common.DeletePolicyBeforeCreation)
 }
 
+func replaceOnImmutableChange(unstruct *unstructured.Unstructured) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReplaceOnImmutableChange)
+	if !found {
+		return false
+	}
+
+	return lo.Must(strconv.ParseBool(value))
+}
+
 func defaultReplicasOnCreation(unstruct *unstructured.Unstructured) (replicas int, set bool) {
 	if util.IsCRDFromGK(unstruct.GroupVersionKind().GroupKind()) {
 		return 0, false
@@ -810,6 +1227,17 @@ func failMode(unstruct *unstructured.Unstructured) multitrack.FailMode {
 	return multitrack.FailMode(value)
 }
 
+// If not fatal, the resource failing to become ready doesn't fail the release, the resource is
+// reported as degraded instead.
+func fatalTrackFailure(unstruct *unstructured.Unstructured) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFatalTrackFailure)
+	if !found {
+		return true
+	}
+
+	return lo.Must(strconv.ParseBool(value))
+}
+
 func failuresAllowed(unstruct *unstructured.Unstructured) int {
 	if unstruct.GetKind() == "Job" {
 		return 0
@@ -894,6 +1322,39 @@ func noActivityTimeout(unstruct *unstructured.Unstructured) (timeout *time.Durat
 	return &t, true
 }
 
+func deployDelay(unstruct *unstructured.Unstructured) (delay time.Duration, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDeployDelay)
+	if !found {
+		return 0, false
+	}
+
+	return lo.Must(time.ParseDuration(value)), true
+}
+
+func operationRetries(unstruct *unstructured.Unstructured) (retries int, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternOperationRetries)
+	if !found {
+		return 0, false
+	}
+
+	return lo.Must(strconv.Atoi(value)), true
+}
+
+func adoptionAllowedByRelease(unstruct *unstructured.Unstructured, releaseName string) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternAllowAdoptionByRelease)
+
+	return found && value == releaseName
+}
+
+func readyStableFor(unstruct *unstructured.Unstructured) (duration time.Duration, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReadyStableFor)
+	if !found {
+		return 0, false
+	}
+
+	return lo.Must(time.ParseDuration(value)), true
+}
+
 func showLogsOnlyForContainers(unstruct *unstructured.Unstructured) (containers []string, set bool) {
 	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternShowLogsOnlyForContainers)
 	if !found {
@@ -929,6 +1390,17 @@ func skipLogs(unstruct *unstructured.Unstructured) bool {
 	return skipLogs
 }
 
+func strictReadiness(unstruct *unstructured.Unstructured) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternStrictReadiness)
+	if !found {
+		return false
+	}
+
+	strictReadiness := lo.Must(strconv.ParseBool(value))
+
+	return strictReadiness
+}
+
 func skipLogsForContainers(unstruct *unstructured.Unstructured) (containers []string, set bool) {
 	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternSkipLogsForContainers)
 	if !found {
@@ -942,6 +1414,92 @@ func skipLogsForContainers(unstruct *unstructured.Unstructured) (containers []st
 	return containers, true
 }
 
+// Invalid paths don't fail the deploy, they are returned as errors to be reported as warnings.
+func ignoreFields(unstruct *unstructured.Unstructured) (paths []*FieldPath, errs []error) {
+	key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternIgnoreFields)
+	if !found {
+		return nil, nil
+	}
+
+	for _, rawPath := range strings.Split(value, ",") {
+		if strings.TrimSpace(rawPath) == "" {
+			continue
+		}
+
+		path, err := ParseFieldPath(rawPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid path %q in annotation %q: %w", strings.TrimSpace(rawPath), key, err))
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, errs
+}
+
+// Returns the copy of the object with the ignored fields set to their live values, so that they
+// are neither changed on apply nor shown in the diff. Returns nil if there is nothing to copy.
+func copyIgnoredFields(unstruct, live *unstructured.Unstructured) *unstructured.Unstructured {
+	paths, _ := ignoreFields(unstruct)
+	if len(paths) == 0 || live == nil {
+		return nil
+	}
+
+	result := unstruct.DeepCopy()
+	for _, path := range paths {
+		path.CopyFrom(result.Object, live.Object)
+	}
+
+	return result
+}
+
+// WithoutFields returns the copy of the object without the specified fields.
+func WithoutFields(unstruct *unstructured.Unstructured, paths []*FieldPath) *unstructured.Unstructured {
+	if len(paths) == 0 {
+		return unstruct
+	}
+
+	result := unstruct.DeepCopy()
+	for _, path := range paths {
+		path.Remove(result.Object)
+	}
+
+	return result
+}
+
+// All of these must hold for the resource to be ready.
+func trackReadyConditions(unstruct *unstructured.Unstructured) (conditions []*TrackCondition, set bool) {
+	return trackConditions(unstruct, annotationKeyPatternTrackCondition, annotationKeyPatternTrackJSONPath)
+}
+
+// The resource failed if any of these holds.
+func trackFailureConditions(unstruct *unstructured.Unstructured) (conditions []*TrackCondition, set bool) {
+	return trackConditions(unstruct, annotationKeyPatternTrackFailureCondition, annotationKeyPatternTrackFailureJSONPath)
+}
+
+func trackConditions(unstruct *unstructured.Unstructured, statusConditionPattern, jsonPathPattern *regexp.Regexp) (conditions []*TrackCondition, set bool) {
+	if _, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), statusConditionPattern); found {
+		conditions = append(conditions, lo.Must(ParseStatusTrackCondition(value)))
+	}
+
+	if _, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), jsonPathPattern); found {
+		conditions = append(conditions, lo.Must(ParseJSONPathTrackCondition(value)))
+	}
+
+	return conditions, len(conditions) > 0
+}
+
+// Zero means no timeout.
+func trackTimeout(unstruct *unstructured.Unstructured) (timeout time.Duration, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternTrackTimeout)
+	if !found {
+		return 0, false
+	}
+
+	return lo.Must(time.ParseDuration(value)), true
+}
+
 func trackTerminationMode(unstruct *unstructured.Unstructured) multitrack.TrackTerminationMode {
 	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternTrackTerminationMode)
 	if !found {
@@ -987,6 +1545,20 @@ func adoptableBy(unstruct *unstructured.Unstructured, releaseName, releaseNamesp
 	return len(nonAdoptableReasons) == 0, nonAdoptableReason
 }
 
+// Returns the release which created or adopted the release namespace, if any.
+func releaseNamespaceOwner(unstruct *unstructured.Unstructured) (releaseName string, found bool) {
+	_, releaseName, found = FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReleaseNamespaceOwner)
+	return releaseName, found
+}
+
+// Returns the release owning the resource according to its Helm annotations, if any.
+func ownerRelease(unstruct *unstructured.Unstructured) (releaseName, releaseNamespace string, found bool) {
+	_, releaseName, nameFound := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReleaseName)
+	_, releaseNamespace, namespaceFound := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReleaseNamespace)
+
+	return releaseName, releaseNamespace, nameFound || namespaceFound
+}
+
 func fixManagedFields(unstruct *unstructured.Unstructured) (changed bool, err error) {
 	managedFields := unstruct.GetManagedFields()
 	if len(managedFields) == 0 {
@@ -1144,6 +1716,25 @@ func weight(unstruct *unstructured.Unstructured) int {
 	return weight
 }
 
+// Both werf.io/weight and helm.sh/hook-weight set on a hook, but with different values. In this
+// case werf.io/weight is used.
+func conflictingHookWeights(unstruct *unstructured.Unstructured) (hookWeight, weight int, conflicting bool) {
+	if !IsHook(unstruct.GetAnnotations()) {
+		return 0, 0, false
+	}
+
+	_, hookWeightValue, hookWeightFound := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternHookWeight)
+	_, weightValue, weightFound := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternWeight)
+	if !hookWeightFound || !weightFound {
+		return 0, 0, false
+	}
+
+	hookWeight = lo.Must(strconv.Atoi(hookWeightValue))
+	weight = lo.Must(strconv.Atoi(weightValue))
+
+	return hookWeight, weight, hookWeight != weight
+}
+
 func deletePolicies(annotations map[string]string) []common.DeletePolicy {
 	var deletePolicies []common.DeletePolicy
 	if IsHook(annotations) {
@@ -1224,6 +1815,7 @@ func manualInternalDependencies(unstruct *unstructured.Unstructured, defaultName
 				[]string{gvk.Kind},
 				dependency.InternalDependencyOptions{
 					DefaultNamespace: defaultNamespace,
+					Source:           fmt.Sprintf("annotation %q", key),
 				},
 			)
 			deps[depID] = dep
@@ -1271,15 +1863,55 @@ func manualInternalDependencies(unstruct *unstructured.Unstructured, defaultName
 				dependency.InternalDependencyOptions{
 					DefaultNamespace: defaultNamespace,
 					ResourceState:    dependency.ResourceState(properties["state"].(string)),
+					Source:           fmt.Sprintf("annotation %q", key),
 				},
 			)
 			deps[depID] = dep
 		}
 	}
 
+	for _, dep := range dependsOnInternalDependencies(unstruct, defaultNamespace) {
+		deps[annotationKeyHumanDependsOn+":"+dep.Source] = dep
+	}
+
 	return lo.Values(deps), len(deps) > 0
 }
 
+// Only the dependencies on the resources of the release, i.e. without the "external:" prefix.
+func dependsOnInternalDependencies(unstruct *unstructured.Unstructured, defaultNamespace string) []*dependency.InternalDependency {
+	key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDependsOn)
+	if !found {
+		return nil
+	}
+
+	var deps []*dependency.InternalDependency
+	for _, entry := range lo.Must(parseDependsOn(value)) {
+		if entry.External {
+			continue
+		}
+
+		var groups []string
+		if entry.Group != "" {
+			groups = []string{entry.Group}
+		}
+
+		deps = append(deps, dependency.NewInternalDependency(
+			[]string{entry.Name},
+			[]string{entry.Namespace},
+			groups,
+			nil,
+			[]string{entry.Kind},
+			dependency.InternalDependencyOptions{
+				DefaultNamespace: defaultNamespace,
+				ResourceState:    entry.State,
+				Source:           fmt.Sprintf("annotation %q (%s)", key, entry),
+			},
+		))
+	}
+
+	return deps
+}
+
 func autoInternalDependencies(unstruct *unstructured.Unstructured, defaultNamespace string) (dependencies []*dependency.InternalDependency, set bool) {
 	depDetector := dependency.NewInternalDependencyDetector(dependency.InternalDependencyDetectorOptions{
 		DefaultNamespace: defaultNamespace,
@@ -1300,9 +1932,20 @@ func externalDependencies(unstruct *unstructured.Unstructured, defaultNamespace
 		if err != nil {
 			return nil, false, fmt.Errorf("error getting legacy external dependencies: %w", err)
 		}
+
+		dependsOnDeps, err := dependsOnExternalDeps(unstruct, defaultNamespace, mapper)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting external dependencies from annotation %q: %w", annotationKeyHumanDependsOn, err)
+		}
+
+		legacyExtDeps = lo.Assign(legacyExtDeps, dependsOnDeps)
 	}
 
 	duplResult := lo.Values(lo.Assign(legacyExtDeps, deps))
+	// If the same resource is specified more than once, waiting for its readiness wins.
+	sort.SliceStable(duplResult, func(i, j int) bool {
+		return duplResult[i].ResourceState == dependency.ResourceStateReady && duplResult[j].ResourceState != dependency.ResourceStateReady
+	})
 	uniqResult := lo.UniqBy(duplResult, func(d *dependency.ExternalDependency) string {
 		return d.ID()
 	})
@@ -1348,6 +1991,7 @@ func externalDeps(unstruct *unstructured.Unstructured, defaultNamespace string,
 				dependency.ExternalDependencyOptions{
 					DefaultNamespace: defaultNamespace,
 					Mapper:           mapper,
+					ResourceState:    externalDependencyState(unstruct, depID),
 				},
 			)
 
@@ -1358,6 +2002,133 @@ func externalDeps(unstruct *unstructured.Unstructured, defaultNamespace string,
 	return deps
 }
 
+// The version of the resource is not specified in the annotation, so the preferred one is used.
+func dependsOnExternalDeps(unstruct *unstructured.Unstructured, defaultNamespace string, mapper meta.ResettableRESTMapper) (map[string]*dependency.ExternalDependency, error) {
+	deps := map[string]*dependency.ExternalDependency{}
+
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDependsOn)
+	if !found {
+		return deps, nil
+	}
+
+	for _, entry := range lo.Must(parseDependsOn(value)) {
+		if !entry.External {
+			continue
+		}
+
+		mapping, err := mapper.RESTMapping(schema.GroupKind{Group: entry.Group, Kind: entry.Kind})
+		if err != nil {
+			return nil, fmt.Errorf("error getting resource mapping for %q: %w", entry, err)
+		}
+
+		deps[annotationKeyHumanDependsOn+":"+entry.String()] = dependency.NewExternalDependency(
+			entry.Name,
+			entry.Namespace,
+			mapping.GroupVersionKind,
+			dependency.ExternalDependencyOptions{
+				DefaultNamespace: defaultNamespace,
+				Mapper:           mapper,
+				ResourceState:    entry.State,
+			},
+		)
+	}
+
+	return deps, nil
+}
+
+// dependsOnEntry is a single resource in the comma-separated list of the "werf.io/depends-on"
+// annotation: [external:][<namespace>/]<kind>[.<group>]/<name>[:present|ready].
+type dependsOnEntry struct {
+	External  bool
+	Namespace string
+	Kind      string
+	Group     string
+	Name      string
+	State     dependency.ResourceState
+	raw       string
+}
+
+func (e *dependsOnEntry) String() string {
+	return e.raw
+}
+
+func parseDependsOn(value string) ([]*dependsOnEntry, error) {
+	var entries []*dependsOnEntry
+	for _, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		entry, err := parseDependsOnEntry(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource %q: %w", raw, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseDependsOnEntry(raw string) (*dependsOnEntry, error) {
+	entry := &dependsOnEntry{
+		State: dependency.ResourceStatePresent,
+		raw:   raw,
+	}
+
+	ref, external := strings.CutPrefix(raw, "external:")
+	entry.External = external
+
+	ref, state, stateFound := strings.Cut(ref, ":")
+	if stateFound {
+		switch dependency.ResourceState(state) {
+		case dependency.ResourceStatePresent, dependency.ResourceStateReady:
+			entry.State = dependency.ResourceState(state)
+		default:
+			return nil, fmt.Errorf("unknown state %q, expected %q or %q", state, dependency.ResourceStatePresent, dependency.ResourceStateReady)
+		}
+	}
+
+	parts := strings.Split(ref, "/")
+	switch len(parts) {
+	case 2:
+		entry.Kind, entry.Name = parts[0], parts[1]
+	case 3:
+		entry.Namespace, entry.Kind, entry.Name = parts[0], parts[1], parts[2]
+
+		if entry.Namespace == "" {
+			return nil, fmt.Errorf("namespace can't be empty")
+		}
+	default:
+		return nil, fmt.Errorf("expected [external:][<namespace>/]<kind>[.<group>]/<name>[:present|ready]")
+	}
+
+	entry.Kind, entry.Group, _ = strings.Cut(entry.Kind, ".")
+	if entry.Kind == "" {
+		return nil, fmt.Errorf("kind can't be empty")
+	}
+
+	if entry.Name == "" {
+		return nil, fmt.Errorf("name can't be empty")
+	}
+
+	return entry, nil
+}
+
+// Empty if the state is not specified.
+func externalDependencyState(unstruct *unstructured.Unstructured, depID string) dependency.ResourceState {
+	annotations, _ := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternExternalDependencyState)
+	for key, value := range annotations {
+		matches := annotationKeyPatternExternalDependencyState.FindStringSubmatch(key)
+		if matches[annotationKeyPatternExternalDependencyState.SubexpIndex("id")] == depID {
+			return dependency.ResourceState(value)
+		}
+	}
+
+	return ""
+}
+
 func legacyExternalDeps(unstruct *unstructured.Unstructured, defaultNamespace string, mapper meta.ResettableRESTMapper, discoveryClient discovery.CachedDiscoveryInterface) (map[string]*dependency.ExternalDependency, error) {
 	deps := map[string]*dependency.ExternalDependency{}
 
@@ -1409,6 +2180,7 @@ func legacyExternalDeps(unstruct *unstructured.Unstructured, defaultNamespace st
 			dependency.ExternalDependencyOptions{
 				DefaultNamespace: defaultNamespace,
 				Mapper:           mapper,
+				ResourceState:    externalDependencyState(unstruct, extDepID),
 			},
 		)
 		deps[extDepID] = dep
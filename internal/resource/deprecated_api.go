@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DeprecatedAPI is an apiVersion of a kind which is deprecated or removed in Kubernetes.
+type DeprecatedAPI struct {
+	GroupVersionKind schema.GroupVersionKind
+	// The Kubernetes version the apiVersion is deprecated in.
+	DeprecatedIn *semver.Version
+	// The Kubernetes version the apiVersion is no longer served in.
+	RemovedIn *semver.Version
+	// The apiVersion to migrate to. Empty if the kind is removed without a replacement.
+	Replacement schema.GroupVersion
+}
+
+// Deprecated reports whether the apiVersion is deprecated or already removed in the Kubernetes version.
+func (a *DeprecatedAPI) Deprecated(kubeVersion *semver.Version) bool {
+	return !kubeVersion.LessThan(a.DeprecatedIn)
+}
+
+// Removed reports whether the apiVersion is no longer served in the Kubernetes version.
+func (a *DeprecatedAPI) Removed(kubeVersion *semver.Version) bool {
+	return !kubeVersion.LessThan(a.RemovedIn)
+}
+
+// FindDeprecatedAPI returns the deprecation of the apiVersion of the kind, if it is deprecated or
+// removed in the Kubernetes version. Only the major and minor components of the version matter.
+func FindDeprecatedAPI(gvk schema.GroupVersionKind, kubeVersion *semver.Version) (*DeprecatedAPI, bool) {
+	kubeVersion = semver.New(kubeVersion.Major(), kubeVersion.Minor(), 0, "", "")
+
+	for _, api := range deprecatedAPIs {
+		if api.GroupVersionKind == gvk && api.Deprecated(kubeVersion) {
+			return api, true
+		}
+	}
+
+	return nil, false
+}
+
+// From https://kubernetes.io/docs/reference/using-api/deprecation-guide/.
+var deprecatedAPIs = buildDeprecatedAPIs([]struct {
+	groupVersion string
+	kinds        []string
+	deprecatedIn string
+	removedIn    string
+	replacement  string
+}{
+	{"extensions/v1beta1", []string{"DaemonSet", "Deployment", "ReplicaSet"}, "1.9", "1.16", "apps/v1"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, "1.9", "1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, "1.10", "1.16", "policy/v1beta1"},
+	{"apps/v1beta1", []string{"Deployment", "StatefulSet"}, "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", []string{"DaemonSet", "Deployment", "ReplicaSet", "StatefulSet"}, "1.9", "1.16", "apps/v1"},
+	{"admissionregistration.k8s.io/v1beta1", []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"}, "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", []string{"CustomResourceDefinition"}, "1.16", "1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", []string{"APIService"}, "1.19", "1.22", "apiregistration.k8s.io/v1"},
+	{"authentication.k8s.io/v1beta1", []string{"TokenReview"}, "1.19", "1.22", "authentication.k8s.io/v1"},
+	{"authorization.k8s.io/v1beta1", []string{"LocalSubjectAccessReview", "SelfSubjectAccessReview", "SubjectAccessReview"}, "1.19", "1.22", "authorization.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", []string{"CertificateSigningRequest"}, "1.19", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", []string{"Lease"}, "1.19", "1.22", "coordination.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"Ingress"}, "1.14", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", []string{"Ingress", "IngressClass"}, "1.19", "1.22", "networking.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", []string{"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"}, "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", []string{"PriorityClass"}, "1.14", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, "1.19", "1.22", "storage.k8s.io/v1"},
+	{"batch/v1beta1", []string{"CronJob"}, "1.21", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", []string{"EndpointSlice"}, "1.21", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", []string{"Event"}, "1.22", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", []string{"HorizontalPodAutoscaler"}, "1.22", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", []string{"PodDisruptionBudget"}, "1.21", "1.25", "policy/v1"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, "1.21", "1.25", ""},
+	{"node.k8s.io/v1beta1", []string{"RuntimeClass"}, "1.20", "1.25", "node.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{"autoscaling/v2beta2", []string{"HorizontalPodAutoscaler"}, "1.23", "1.26", "autoscaling/v2"},
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, "1.24", "1.27", "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+})
+
+func buildDeprecatedAPIs(entries []struct {
+	groupVersion string
+	kinds        []string
+	deprecatedIn string
+	removedIn    string
+	replacement  string
+},
+) []*DeprecatedAPI {
+	var apis []*DeprecatedAPI
+	for _, entry := range entries {
+		gv, err := schema.ParseGroupVersion(entry.groupVersion)
+		if err != nil {
+			panic(fmt.Sprintf("parse group version %q: %s", entry.groupVersion, err))
+		}
+
+		var replacement schema.GroupVersion
+		if entry.replacement != "" {
+			if replacement, err = schema.ParseGroupVersion(entry.replacement); err != nil {
+				panic(fmt.Sprintf("parse group version %q: %s", entry.replacement, err))
+			}
+		}
+
+		for _, kind := range entry.kinds {
+			apis = append(apis, &DeprecatedAPI{
+				GroupVersionKind: gv.WithKind(kind),
+				DeprecatedIn:     semver.MustParse(entry.deprecatedIn),
+				RemovedIn:        semver.MustParse(entry.removedIn),
+				Replacement:      replacement,
+			})
+		}
+	}
+
+	return apis
+}
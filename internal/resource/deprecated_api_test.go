@@ -0,0 +1,60 @@
+package resource_test
+
+import (
+	"github.com/Masterminds/semver/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("deprecated apiVersions", func() {
+	cronJobV1beta1 := schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}
+
+	DescribeTable("finds the deprecation of the apiVersion in the Kubernetes version",
+		func(gvk schema.GroupVersionKind, kubeVersion string, expectFound, expectRemoved bool) {
+			v := semver.MustParse(kubeVersion)
+
+			api, found := resource.FindDeprecatedAPI(gvk, v)
+			Expect(found).To(Equal(expectFound))
+
+			if expectFound {
+				Expect(api.GroupVersionKind).To(Equal(gvk))
+				Expect(api.Removed(v)).To(Equal(expectRemoved))
+			}
+		},
+		Entry("before deprecatedIn", cronJobV1beta1, "1.20", false, false),
+		Entry("at the last patch before deprecatedIn", cronJobV1beta1, "1.20.15", false, false),
+		Entry("exactly at deprecatedIn", cronJobV1beta1, "1.21", true, false),
+		Entry("at a prerelease of deprecatedIn", cronJobV1beta1, "1.21.0-rc.1", true, false),
+		Entry("between deprecatedIn and removedIn", cronJobV1beta1, "1.23.4", true, false),
+		Entry("at the last minor before removedIn", cronJobV1beta1, "1.24.17", true, false),
+		Entry("exactly at removedIn", cronJobV1beta1, "1.25", true, true),
+		Entry("at a vendor build of removedIn", cronJobV1beta1, "v1.25.3-gke.100", true, true),
+		Entry("after removedIn", cronJobV1beta1, "1.30", true, true),
+		Entry("for the replacement apiVersion", schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, "1.30", false, false),
+		Entry("for a kind not deprecated in the same apiVersion", schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Job"}, "1.30", false, false),
+		Entry("for a core apiVersion", schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "1.30", false, false),
+	)
+
+	DescribeTable("reports the replacement apiVersion",
+		func(gvk schema.GroupVersionKind, expectReplacement schema.GroupVersion) {
+			api, found := resource.FindDeprecatedAPI(gvk, semver.MustParse("1.30"))
+			Expect(found).To(BeTrue())
+			Expect(api.Replacement).To(Equal(expectReplacement))
+		},
+		Entry("moved to another group", schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}, schema.GroupVersion{Group: "networking.k8s.io", Version: "v1"}),
+		Entry("moved to another version", cronJobV1beta1, schema.GroupVersion{Group: "batch", Version: "v1"}),
+		Entry("removed without a replacement", schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}, schema.GroupVersion{}),
+	)
+
+	It("reports the same kind deprecated in different groups separately", func() {
+		extensionsPSP, found := resource.FindDeprecatedAPI(schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "PodSecurityPolicy"}, semver.MustParse("1.10"))
+		Expect(found).To(BeTrue())
+		Expect(extensionsPSP.RemovedIn.String()).To(Equal("1.16.0"))
+
+		_, found = resource.FindDeprecatedAPI(schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}, semver.MustParse("1.10"))
+		Expect(found).To(BeFalse())
+	})
+})
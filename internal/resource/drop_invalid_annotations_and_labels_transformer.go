@@ -5,7 +5,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/pkg/log"
 )
 
 var _ ResourceTransformer = (*DropInvalidAnnotationsAndLabelsTransformer)(nil)
@@ -30,7 +30,7 @@ func (t *DropInvalidAnnotationsAndLabelsTransformer) Transform(ctx context.Conte
 	for annoKey, rawAnnoValue := range annotations {
 		annoValue, valIsString := rawAnnoValue.(string)
 		if !valIsString {
-			log.Default.Warn(ctx, "Dropped invalid annotation %q in resource %q (%s): key is not a string", annoKey, info.Obj.GetName(), info.Obj.GroupVersionKind().String())
+			log.Plan.Warn(ctx, "Dropped invalid annotation %q in resource %q (%s): key is not a string", annoKey, info.Obj.GetName(), info.Obj.GroupVersionKind().String())
 			continue
 		}
 
@@ -43,7 +43,7 @@ func (t *DropInvalidAnnotationsAndLabelsTransformer) Transform(ctx context.Conte
 	for labelKey, rawLabelValue := range labels {
 		labelValue, valIsString := rawLabelValue.(string)
 		if !valIsString {
-			log.Default.Warn(ctx, "Dropped invalid label %q in resource %q (%s): key is not a string", labelKey, info.Obj.GetName(), info.Obj.GroupVersionKind().String())
+			log.Plan.Warn(ctx, "Dropped invalid label %q in resource %q (%s): key is not a string", labelKey, info.Obj.GetName(), info.Obj.GroupVersionKind().String())
 			continue
 		}
 
@@ -0,0 +1,234 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FieldPath points to fields of the object, e.g. "spec.template.metadata.annotations.injected" or
+// "webhooks[*].clientConfig.caBundle". Keys with dots or brackets are quoted in brackets, e.g.
+// `metadata.annotations["sidecar.istio.io/status"]`. "[*]" matches all elements of the list,
+// "[N]" only the N-th one.
+type FieldPath struct {
+	path     string
+	segments []fieldPathSegment
+}
+
+type fieldPathSegment struct {
+	key      string
+	index    int
+	wildcard bool
+	isIndex  bool
+}
+
+func ParseFieldPath(path string) (*FieldPath, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if s == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	if s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+
+	var segments []fieldPathSegment
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+
+			end := strings.IndexAny(s[i:], ".[")
+			if end == -1 {
+				end = len(s) - i
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("empty key")
+			}
+
+			segments = append(segments, fieldPathSegment{key: s[i : i+end]})
+			i += end
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed bracket")
+			}
+
+			inner := s[i+1 : i+end]
+
+			// Quoted keys can have brackets inside.
+			if len(inner) > 0 && (inner[0] == '"' || inner[0] == '\'') {
+				closingQuote := strings.IndexByte(s[i+2:], inner[0])
+				if closingQuote == -1 || i+2+closingQuote+1 >= len(s) || s[i+2+closingQuote+1] != ']' {
+					return nil, fmt.Errorf("unclosed quote")
+				}
+
+				key := s[i+2 : i+2+closingQuote]
+				if key == "" {
+					return nil, fmt.Errorf("empty key")
+				}
+
+				segments = append(segments, fieldPathSegment{key: key})
+				i += 2 + closingQuote + 2
+
+				continue
+			}
+
+			switch inner {
+			case "*":
+				segments = append(segments, fieldPathSegment{isIndex: true, wildcard: true})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid list index %q, expected non-negative integer or *", inner)
+				}
+
+				segments = append(segments, fieldPathSegment{isIndex: true, index: index})
+			}
+
+			i += end + 1
+		default:
+			return nil, fmt.Errorf("unexpected character %q after %q", s[i], s[:i])
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no fields specified")
+	}
+
+	return &FieldPath{
+		path:     strings.TrimSpace(path),
+		segments: segments,
+	}, nil
+}
+
+func (p *FieldPath) String() string {
+	return p.path
+}
+
+// CopyFrom sets the fields of the object to the values of the same fields of another object.
+// Fields missing in another object are removed. Lists are never created and their elements are
+// never removed, so only the existing elements are changed.
+func (p *FieldPath) CopyFrom(obj, from map[string]interface{}) {
+	copyField(obj, true, from, true, p.segments)
+}
+
+// Remove removes the fields from the object.
+func (p *FieldPath) Remove(obj map[string]interface{}) {
+	removeField(obj, p.segments)
+}
+
+// Returns the new value of the destination and whether it should exist at all. Values of
+// unexpected types are left as is.
+func copyField(dst interface{}, dstFound bool, src interface{}, srcFound bool, segments []fieldPathSegment) (interface{}, bool) {
+	if len(segments) == 0 {
+		if !srcFound {
+			return nil, false
+		}
+
+		return runtime.DeepCopyJSONValue(src), true
+	}
+
+	segment := segments[0]
+
+	if !segment.isIndex {
+		srcMap, _ := src.(map[string]interface{})
+
+		dstMap, ok := dst.(map[string]interface{})
+		if dstFound && !ok {
+			return dst, dstFound
+		} else if !dstFound {
+			if srcMap == nil {
+				return dst, dstFound
+			}
+
+			dstMap = map[string]interface{}{}
+		}
+
+		srcVal, srcValFound := srcMap[segment.key]
+		dstVal, dstValFound := dstMap[segment.key]
+
+		if newVal, keep := copyField(dstVal, dstValFound, srcVal, srcValFound, segments[1:]); keep {
+			dstMap[segment.key] = newVal
+		} else {
+			delete(dstMap, segment.key)
+		}
+
+		if !dstFound && len(dstMap) == 0 {
+			return nil, false
+		}
+
+		return dstMap, true
+	}
+
+	dstList, ok := dst.([]interface{})
+	if !dstFound || !ok {
+		return dst, dstFound
+	}
+
+	srcList, _ := src.([]interface{})
+
+	for _, i := range segment.indexes(len(dstList)) {
+		var (
+			srcVal      interface{}
+			srcValFound bool
+		)
+		if i < len(srcList) {
+			srcVal, srcValFound = srcList[i], true
+		}
+
+		if newVal, keep := copyField(dstList[i], true, srcVal, srcValFound, segments[1:]); keep {
+			dstList[i] = newVal
+		}
+	}
+
+	return dstList, true
+}
+
+func removeField(obj interface{}, segments []fieldPathSegment) {
+	segment := segments[0]
+
+	if !segment.isIndex {
+		objMap, ok := obj.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		if len(segments) == 1 {
+			delete(objMap, segment.key)
+		} else if val, found := objMap[segment.key]; found {
+			removeField(val, segments[1:])
+		}
+
+		return
+	}
+
+	objList, ok := obj.([]interface{})
+	if !ok || len(segments) == 1 {
+		return
+	}
+
+	for _, i := range segment.indexes(len(objList)) {
+		removeField(objList[i], segments[1:])
+	}
+}
+
+func (s fieldPathSegment) indexes(length int) []int {
+	if s.wildcard {
+		var indexes []int
+		for i := 0; i < length; i++ {
+			indexes = append(indexes, i)
+		}
+
+		return indexes
+	}
+
+	if s.index < length {
+		return []int{s.index}
+	}
+
+	return nil
+}
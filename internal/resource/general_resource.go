@@ -88,10 +88,26 @@ func (r *GeneralResource) Validate() error {
 		return fmt.Errorf("error validating replicas on creation for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateDeployDelay(r.unstruct); err != nil {
+		return fmt.Errorf("error validating deploy delay for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateOperationRetries(r.unstruct); err != nil {
+		return fmt.Errorf("error validating operation retries for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateAllowAdoptionByRelease(r.unstruct); err != nil {
+		return fmt.Errorf("error validating allow adoption by release for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateDeletePolicy(r.unstruct); err != nil {
 		return fmt.Errorf("error validating delete policy for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateReplaceOnImmutableChange(r.unstruct); err != nil {
+		return fmt.Errorf("error validating replace on immutable change for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateResourcePolicy(r.unstruct); err != nil {
 		return fmt.Errorf("error validating resource policy for resource %q: %w", r.HumanID(), err)
 	}
@@ -108,6 +124,10 @@ func (r *GeneralResource) Validate() error {
 		return fmt.Errorf("error validating deploy dependencies for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateDependsOn(r.unstruct); err != nil {
+		return fmt.Errorf("error validating depends-on annotation for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateInternalDependencies(r.unstruct); err != nil {
 		return fmt.Errorf("error validating internal dependencies for resource %q: %w", r.HumanID(), err)
 	}
@@ -135,6 +155,12 @@ func (r *GeneralResource) Recreate() bool {
 	return recreate(r.unstruct)
 }
 
+// ReplaceOnImmutableChange returns true if the resource should be deleted and created again when
+// its immutable fields are changed.
+func (r *GeneralResource) ReplaceOnImmutableChange() bool {
+	return replaceOnImmutableChange(r.unstruct)
+}
+
 func (r *GeneralResource) DefaultReplicasOnCreation() (replicas int, set bool) {
 	return defaultReplicasOnCreation(r.unstruct)
 }
@@ -155,6 +181,10 @@ func (r *GeneralResource) FailMode() multitrack.FailMode {
 	return failMode(r.unstruct)
 }
 
+func (r *GeneralResource) FatalTrackFailure() bool {
+	return fatalTrackFailure(r.unstruct)
+}
+
 func (r *GeneralResource) FailuresAllowed() int {
 	return failuresAllowed(r.unstruct)
 }
@@ -179,6 +209,22 @@ func (r *GeneralResource) ShowLogsOnlyForContainers() (containers []string, set
 	return showLogsOnlyForContainers(r.unstruct)
 }
 
+func (r *GeneralResource) DeployDelay() (delay time.Duration, set bool) {
+	return deployDelay(r.unstruct)
+}
+
+func (r *GeneralResource) OperationRetries() (retries int, set bool) {
+	return operationRetries(r.unstruct)
+}
+
+func (r *GeneralResource) AdoptionAllowedBy(releaseName string) bool {
+	return adoptionAllowedByRelease(r.unstruct, releaseName)
+}
+
+func (r *GeneralResource) ReadyStableFor() (duration time.Duration, set bool) {
+	return readyStableFor(r.unstruct)
+}
+
 func (r *GeneralResource) ShowServiceMessages() bool {
 	return showServiceMessages(r.unstruct)
 }
@@ -187,10 +233,47 @@ func (r *GeneralResource) SkipLogs() bool {
 	return skipLogs(r.unstruct)
 }
 
+func (r *GeneralResource) IgnoreFields() (paths []*FieldPath, errs []error) {
+	return ignoreFields(r.unstruct)
+}
+
+// WithIgnoredFieldsFrom returns the resource with the ignored fields set to their values from the
+// live object.
+func (r *GeneralResource) WithIgnoredFieldsFrom(live *unstructured.Unstructured) *GeneralResource {
+	unstruct := copyIgnoredFields(r.unstruct, live)
+	if unstruct == nil {
+		return r
+	}
+
+	return &GeneralResource{
+		ResourceID:       r.ResourceID,
+		unstruct:         unstruct,
+		defaultNamespace: r.defaultNamespace,
+		mapper:           r.mapper,
+		discoveryClient:  r.discoveryClient,
+	}
+}
+
 func (r *GeneralResource) SkipLogsForContainers() (containers []string, set bool) {
 	return skipLogsForContainers(r.unstruct)
 }
 
+func (r *GeneralResource) StrictReadiness() bool {
+	return strictReadiness(r.unstruct)
+}
+
+func (r *GeneralResource) TrackReadyConditions() (conditions []*TrackCondition, set bool) {
+	return trackReadyConditions(r.unstruct)
+}
+
+func (r *GeneralResource) TrackFailureConditions() (conditions []*TrackCondition, set bool) {
+	return trackFailureConditions(r.unstruct)
+}
+
+func (r *GeneralResource) TrackTimeout() (timeout time.Duration, set bool) {
+	return trackTimeout(r.unstruct)
+}
+
 func (r *GeneralResource) TrackTerminationMode() multitrack.TrackTerminationMode {
 	return trackTerminationMode(r.unstruct)
 }
@@ -203,6 +286,12 @@ func (r *GeneralResource) ManualInternalDependencies() (dependencies []*dependen
 	return manualInternalDependencies(r.unstruct, r.defaultNamespace)
 }
 
+// DependsOnInternalDependencies returns only the dependencies from the "werf.io/depends-on"
+// annotation on the resources of the release. They are also included in ManualInternalDependencies.
+func (r *GeneralResource) DependsOnInternalDependencies() []*dependency.InternalDependency {
+	return dependsOnInternalDependencies(r.unstruct, r.defaultNamespace)
+}
+
 func (r *GeneralResource) AutoInternalDependencies() (dependencies []*dependency.InternalDependency, set bool) {
 	return autoInternalDependencies(r.unstruct, r.defaultNamespace)
 }
@@ -88,14 +88,30 @@ func (r *HookResource) Validate() error {
 		return fmt.Errorf("error validating hook for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateHookParallel(r.unstruct); err != nil {
+		return fmt.Errorf("error validating hook parallel for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateReplicasOnCreation(r.unstruct); err != nil {
 		return fmt.Errorf("error validating replicas on creation for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateDeployDelay(r.unstruct); err != nil {
+		return fmt.Errorf("error validating deploy delay for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateOperationRetries(r.unstruct); err != nil {
+		return fmt.Errorf("error validating operation retries for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateDeletePolicy(r.unstruct); err != nil {
 		return fmt.Errorf("error validating delete policy for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateReplaceOnImmutableChange(r.unstruct); err != nil {
+		return fmt.Errorf("error validating replace on immutable change for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateResourcePolicy(r.unstruct); err != nil {
 		return fmt.Errorf("error validating resource policy for resource %q: %w", r.HumanID(), err)
 	}
@@ -112,6 +128,10 @@ func (r *HookResource) Validate() error {
 		return fmt.Errorf("error validating deploy dependencies for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateDependsOn(r.unstruct); err != nil {
+		return fmt.Errorf("error validating depends-on annotation for resource %q: %w", r.HumanID(), err)
+	}
+
 	if err := validateInternalDependencies(r.unstruct); err != nil {
 		return fmt.Errorf("error validating internal dependencies for resource %q: %w", r.HumanID(), err)
 	}
@@ -143,6 +163,12 @@ func (r *HookResource) Recreate() bool {
 	return recreate(r.unstruct)
 }
 
+// ReplaceOnImmutableChange returns true if the resource should be deleted and created again when
+// its immutable fields are changed.
+func (r *HookResource) ReplaceOnImmutableChange() bool {
+	return replaceOnImmutableChange(r.unstruct)
+}
+
 func (r *HookResource) DefaultReplicasOnCreation() (replicas int, set bool) {
 	return defaultReplicasOnCreation(r.unstruct)
 }
@@ -163,6 +189,10 @@ func (r *HookResource) FailMode() multitrack.FailMode {
 	return failMode(r.unstruct)
 }
 
+func (r *HookResource) FatalTrackFailure() bool {
+	return fatalTrackFailure(r.unstruct)
+}
+
 func (r *HookResource) FailuresAllowed() int {
 	return failuresAllowed(r.unstruct)
 }
@@ -187,6 +217,18 @@ func (r *HookResource) ShowLogsOnlyForContainers() (containers []string, set boo
 	return showLogsOnlyForContainers(r.unstruct)
 }
 
+func (r *HookResource) DeployDelay() (delay time.Duration, set bool) {
+	return deployDelay(r.unstruct)
+}
+
+func (r *HookResource) OperationRetries() (retries int, set bool) {
+	return operationRetries(r.unstruct)
+}
+
+func (r *HookResource) ReadyStableFor() (duration time.Duration, set bool) {
+	return readyStableFor(r.unstruct)
+}
+
 func (r *HookResource) ShowServiceMessages() bool {
 	return showServiceMessages(r.unstruct)
 }
@@ -195,10 +237,47 @@ func (r *HookResource) SkipLogs() bool {
 	return skipLogs(r.unstruct)
 }
 
+func (r *HookResource) IgnoreFields() (paths []*FieldPath, errs []error) {
+	return ignoreFields(r.unstruct)
+}
+
+// WithIgnoredFieldsFrom returns the resource with the ignored fields set to their values from the
+// live object.
+func (r *HookResource) WithIgnoredFieldsFrom(live *unstructured.Unstructured) *HookResource {
+	unstruct := copyIgnoredFields(r.unstruct, live)
+	if unstruct == nil {
+		return r
+	}
+
+	return &HookResource{
+		ResourceID:       r.ResourceID,
+		unstruct:         unstruct,
+		defaultNamespace: r.defaultNamespace,
+		mapper:           r.mapper,
+		discoveryClient:  r.discoveryClient,
+	}
+}
+
 func (r *HookResource) SkipLogsForContainers() (containers []string, set bool) {
 	return skipLogsForContainers(r.unstruct)
 }
 
+func (r *HookResource) StrictReadiness() bool {
+	return strictReadiness(r.unstruct)
+}
+
+func (r *HookResource) TrackReadyConditions() (conditions []*TrackCondition, set bool) {
+	return trackReadyConditions(r.unstruct)
+}
+
+func (r *HookResource) TrackFailureConditions() (conditions []*TrackCondition, set bool) {
+	return trackFailureConditions(r.unstruct)
+}
+
+func (r *HookResource) TrackTimeout() (timeout time.Duration, set bool) {
+	return trackTimeout(r.unstruct)
+}
+
 func (r *HookResource) TrackTerminationMode() multitrack.TrackTerminationMode {
 	return trackTerminationMode(r.unstruct)
 }
@@ -207,10 +286,20 @@ func (r *HookResource) Weight() int {
 	return weight(r.unstruct)
 }
 
+func (r *HookResource) ConflictingWeights() (hookWeight, weight int, conflicting bool) {
+	return conflictingHookWeights(r.unstruct)
+}
+
 func (r *HookResource) ManualInternalDependencies() (dependencies []*dependency.InternalDependency, set bool) {
 	return manualInternalDependencies(r.unstruct, r.defaultNamespace)
 }
 
+// DependsOnInternalDependencies returns only the dependencies from the "werf.io/depends-on"
+// annotation on the resources of the release. They are also included in ManualInternalDependencies.
+func (r *HookResource) DependsOnInternalDependencies() []*dependency.InternalDependency {
+	return dependsOnInternalDependencies(r.unstruct, r.defaultNamespace)
+}
+
 func (r *HookResource) AutoInternalDependencies() (dependencies []*dependency.InternalDependency, set bool) {
 	return autoInternalDependencies(r.unstruct, r.defaultNamespace)
 }
@@ -260,6 +349,28 @@ func (r *HookResource) OnTest() bool {
 	return onTest(r.unstruct)
 }
 
+func (r *HookResource) OnExec() bool {
+	return onExec(r.unstruct)
+}
+
+func (r *HookResource) OnPrePlan() bool {
+	return onPrePlan(r.unstruct)
+}
+
+func (r *HookResource) OnPostReadiness() bool {
+	return onPostReadiness(r.unstruct)
+}
+
+// Parallel returns whether the hook can run together with other hooks of the same weight.
+func (r *HookResource) Parallel() bool {
+	return hookParallel(r.unstruct)
+}
+
+// ExecAfter returns the stage after which the exec hook is run.
+func (r *HookResource) ExecAfter() string {
+	return execAfter(r.unstruct)
+}
+
 func (r *HookResource) OnPreAnything() bool {
 	return onPreAnything(r.unstruct)
 }
@@ -0,0 +1,99 @@
+package resource
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/werf/nelm/internal/util"
+)
+
+type knownAnnotation struct {
+	human   string
+	pattern *regexp.Regexp
+}
+
+// All annotations in the werf.io and helm.sh domains which are used by werf or Helm.
+var knownAnnotations = []knownAnnotation{
+	{annotationKeyHumanReleaseName, annotationKeyPatternReleaseName},
+	{annotationKeyHumanReleaseNamespace, annotationKeyPatternReleaseNamespace},
+	{annotationKeyHumanHook, annotationKeyPatternHook},
+	{annotationKeyHumanResourcePolicy, annotationKeyPatternResourcePolicy},
+	{annotationKeyHumanDeletePolicy, annotationKeyPatternDeletePolicy},
+	{annotationKeyHumanHookDeletePolicy, annotationKeyPatternHookDeletePolicy},
+	{annotationKeyHumanReplicasOnCreation, annotationKeyPatternReplicasOnCreation},
+	{annotationKeyHumanFailMode, annotationKeyPatternFailMode},
+	{annotationKeyHumanFatalTrackFailure, annotationKeyPatternFatalTrackFailure},
+	{annotationKeyHumanFailuresAllowedPerReplica, annotationKeyPatternFailuresAllowedPerReplica},
+	{annotationKeyHumanIgnoreReadinessProbeFailsFor, annotationKeyPatternIgnoreReadinessProbeFailsFor},
+	{annotationKeyHumanLogRegex, annotationKeyPatternLogRegex},
+	{annotationKeyHumanLogRegexFor, annotationKeyPatternLogRegexFor},
+	{annotationKeyHumanNoActivityTimeout, annotationKeyPatternNoActivityTimeout},
+	{annotationKeyHumanReadyStableFor, annotationKeyPatternReadyStableFor},
+	{annotationKeyHumanShowLogsOnlyForContainers, annotationKeyPatternShowLogsOnlyForContainers},
+	{annotationKeyHumanShowServiceMessages, annotationKeyPatternShowServiceMessages},
+	{annotationKeyHumanSkipLogs, annotationKeyPatternSkipLogs},
+	{annotationKeyHumanSkipLogsForContainers, annotationKeyPatternSkipLogsForContainers},
+	{annotationKeyHumanStrictReadiness, annotationKeyPatternStrictReadiness},
+	{annotationKeyHumanTrackCondition, annotationKeyPatternTrackCondition},
+	{annotationKeyHumanTrackJSONPath, annotationKeyPatternTrackJSONPath},
+	{annotationKeyHumanTrackFailureCondition, annotationKeyPatternTrackFailureCondition},
+	{annotationKeyHumanTrackFailureJSONPath, annotationKeyPatternTrackFailureJSONPath},
+	{annotationKeyHumanTrackTimeout, annotationKeyPatternTrackTimeout},
+	{annotationKeyHumanTrackTerminationMode, annotationKeyPatternTrackTerminationMode},
+	{annotationKeyHumanWeight, annotationKeyPatternWeight},
+	{annotationKeyHumanHookWeight, annotationKeyPatternHookWeight},
+	{annotationKeyHumanDeployDependency, annotationKeyPatternDeployDependency},
+	{annotationKeyHumanDependency, annotationKeyPatternDependency},
+	{annotationKeyHumanDependsOn, annotationKeyPatternDependsOn},
+	{annotationKeyHumanExternalDependency, annotationKeyPatternExternalDependency},
+	{annotationKeyHumanLegacyExternalDependencyResource, annotationKeyPatternLegacyExternalDependencyResource},
+	{annotationKeyHumanLegacyExternalDependencyNamespace, annotationKeyPatternLegacyExternalDependencyNamespace},
+	{annotationKeyHumanExternalDependencyState, annotationKeyPatternExternalDependencyState},
+	{annotationKeyHumanSensitive, annotationKeyPatternSensitive},
+	{annotationKeyHumanReplaceOnImmutableChange, annotationKeyPatternReplaceOnImmutableChange},
+	{annotationKeyHumanIgnoreFields, annotationKeyPatternIgnoreFields},
+	{annotationKeyHumanManifestHash, annotationKeyPatternManifestHash},
+	{annotationKeyHumanDeployDelay, annotationKeyPatternDeployDelay},
+	{annotationKeyHumanExecAfter, annotationKeyPatternExecAfter},
+	{annotationKeyHumanHookParallel, annotationKeyPatternHookParallel},
+	{annotationKeyHumanCRDPolicy, annotationKeyPatternCRDPolicy},
+	{annotationKeyHumanAllowAdoptionByRelease, annotationKeyPatternAllowAdoptionByRelease},
+	{annotationKeyHumanOperationRetries, annotationKeyPatternOperationRetries},
+	{annotationKeyHumanReleaseNamespaceOwner, annotationKeyPatternReleaseNamespaceOwner},
+	{"werf.io/hook", annotationKeyPatternHook},
+	// Set by werf on all deployed resources.
+	{"werf.io/version", regexp.MustCompile(`^werf.io/version$`)},
+}
+
+// Annotations which are this close to a known one are considered misspelled.
+const maxAnnotationTypoDistance = 3
+
+// UnknownAnnotation reports whether the annotation is in the werf.io or helm.sh domain, but is
+// not used by werf or Helm, which is likely a typo. If so, the closest known annotation is
+// suggested, if any is close enough.
+func UnknownAnnotation(key string) (unknown bool, suggestion string) {
+	domain, _, _ := strings.Cut(key, "/")
+	if domain != "werf.io" && domain != "helm.sh" && !strings.HasSuffix(domain, "dependency.werf.io") {
+		return false, ""
+	}
+
+	for _, known := range knownAnnotations {
+		if known.pattern.MatchString(key) {
+			return false, ""
+		}
+	}
+
+	bestDistance := maxAnnotationTypoDistance + 1
+	for _, known := range knownAnnotations {
+		if strings.Contains(known.human, "<") {
+			continue
+		}
+
+		if distance := util.EditDistance(key, known.human); distance < bestDistance {
+			bestDistance = distance
+			suggestion = known.human
+		}
+	}
+
+	return true, suggestion
+}
@@ -0,0 +1,213 @@
+package resource
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/werf/kubedog/pkg/tracker/generic"
+)
+
+type LiveStatusType string
+
+const (
+	LiveStatusReady     LiveStatusType = "Ready"
+	LiveStatusNotReady  LiveStatusType = "NotReady"
+	LiveStatusCompleted LiveStatusType = "Completed"
+	LiveStatusRunning   LiveStatusType = "Running"
+	LiveStatusFailed    LiveStatusType = "Failed"
+	LiveStatusPresent   LiveStatusType = "Present"
+	LiveStatusMissing   LiveStatusType = "MISSING"
+)
+
+type LiveStatus struct {
+	Type LiveStatusType
+	// Replicas or completions, e.g. "2/3". Empty if not applicable.
+	Progress string
+}
+
+// Healthy returns false if the resource is missing, failed or not ready yet.
+func (s *LiveStatus) Healthy() bool {
+	switch s.Type {
+	case LiveStatusReady, LiveStatusCompleted, LiveStatusPresent:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *LiveStatus) String() string {
+	if s.Progress == "" {
+		return string(s.Type)
+	}
+
+	return fmt.Sprintf("%s %s", s.Type, s.Progress)
+}
+
+// EvaluateLiveStatus evaluates the current readiness of the live resource once, without waiting
+// for anything. Pass nil if the resource doesn't exist in the cluster.
+func EvaluateLiveStatus(unstruct *unstructured.Unstructured) (*LiveStatus, error) {
+	if unstruct == nil {
+		return &LiveStatus{Type: LiveStatusMissing}, nil
+	}
+
+	gk := unstruct.GroupVersionKind().GroupKind()
+
+	switch {
+	case gk.Group == "apps" && gk.Kind == "Deployment":
+		deploy := &appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, deploy); err != nil {
+			return nil, fmt.Errorf("error converting unstructured to Deployment: %w", err)
+		}
+
+		return evaluateDeploymentLiveStatus(deploy), nil
+	case gk.Group == "apps" && gk.Kind == "StatefulSet":
+		sts := &appsv1.StatefulSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, sts); err != nil {
+			return nil, fmt.Errorf("error converting unstructured to StatefulSet: %w", err)
+		}
+
+		return evaluateStatefulSetLiveStatus(sts), nil
+	case gk.Group == "apps" && gk.Kind == "DaemonSet":
+		ds := &appsv1.DaemonSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, ds); err != nil {
+			return nil, fmt.Errorf("error converting unstructured to DaemonSet: %w", err)
+		}
+
+		return evaluateDaemonSetLiveStatus(ds), nil
+	case gk.Group == "batch" && gk.Kind == "Job":
+		job := &batchv1.Job{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, job); err != nil {
+			return nil, fmt.Errorf("error converting unstructured to Job: %w", err)
+		}
+
+		return evaluateJobLiveStatus(job), nil
+	case gk.Group == "" && gk.Kind == "Pod":
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, pod); err != nil {
+			return nil, fmt.Errorf("error converting unstructured to Pod: %w", err)
+		}
+
+		return evaluatePodLiveStatus(pod), nil
+	}
+
+	status, err := generic.NewResourceStatus(unstruct)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating resource status: %w", err)
+	}
+
+	switch {
+	case status.Indicator == nil:
+		return &LiveStatus{Type: LiveStatusPresent}, nil
+	case status.IsFailed():
+		return &LiveStatus{Type: LiveStatusFailed}, nil
+	case status.IsReady():
+		return &LiveStatus{Type: LiveStatusReady}, nil
+	default:
+		return &LiveStatus{Type: LiveStatusNotReady}, nil
+	}
+}
+
+func evaluateDeploymentLiveStatus(deploy *appsv1.Deployment) *LiveStatus {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	status := &LiveStatus{
+		Type:     LiveStatusNotReady,
+		Progress: fmt.Sprintf("%d/%d", deploy.Status.ReadyReplicas, replicas),
+	}
+
+	if deploy.Status.ObservedGeneration >= deploy.Generation &&
+		deploy.Status.UpdatedReplicas >= replicas &&
+		deploy.Status.AvailableReplicas >= replicas &&
+		deploy.Status.Replicas == deploy.Status.UpdatedReplicas {
+		status.Type = LiveStatusReady
+	}
+
+	return status
+}
+
+func evaluateStatefulSetLiveStatus(sts *appsv1.StatefulSet) *LiveStatus {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	status := &LiveStatus{
+		Type:     LiveStatusNotReady,
+		Progress: fmt.Sprintf("%d/%d", sts.Status.ReadyReplicas, replicas),
+	}
+
+	updated := sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType ||
+		sts.Status.UpdateRevision == "" ||
+		sts.Status.CurrentRevision == sts.Status.UpdateRevision
+
+	if sts.Status.ObservedGeneration >= sts.Generation && sts.Status.ReadyReplicas >= replicas && updated {
+		status.Type = LiveStatusReady
+	}
+
+	return status
+}
+
+func evaluateDaemonSetLiveStatus(ds *appsv1.DaemonSet) *LiveStatus {
+	status := &LiveStatus{
+		Type:     LiveStatusNotReady,
+		Progress: fmt.Sprintf("%d/%d", ds.Status.NumberReady, ds.Status.DesiredNumberScheduled),
+	}
+
+	if ds.Status.ObservedGeneration >= ds.Generation &&
+		ds.Status.UpdatedNumberScheduled >= ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberReady >= ds.Status.DesiredNumberScheduled {
+		status.Type = LiveStatusReady
+	}
+
+	return status
+}
+
+func evaluateJobLiveStatus(job *batchv1.Job) *LiveStatus {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return &LiveStatus{Type: LiveStatusCompleted}
+		case batchv1.JobFailed:
+			return &LiveStatus{Type: LiveStatusFailed}
+		}
+	}
+
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+
+	return &LiveStatus{
+		Type:     LiveStatusRunning,
+		Progress: fmt.Sprintf("%d/%d", job.Status.Succeeded, completions),
+	}
+}
+
+func evaluatePodLiveStatus(pod *corev1.Pod) *LiveStatus {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return &LiveStatus{Type: LiveStatusCompleted}
+	case corev1.PodFailed:
+		return &LiveStatus{Type: LiveStatusFailed}
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return &LiveStatus{Type: LiveStatusReady}
+		}
+	}
+
+	return &LiveStatus{Type: LiveStatusNotReady}
+}
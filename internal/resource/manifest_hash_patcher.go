@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ ResourcePatcher = (*ManifestHashPatcher)(nil)
+
+const TypeManifestHashPatcher ResourcePatcherType = "manifest-hash-patcher"
+
+func NewManifestHashPatcher() *ManifestHashPatcher {
+	return &ManifestHashPatcher{}
+}
+
+type ManifestHashPatcher struct{}
+
+func (p *ManifestHashPatcher) Match(ctx context.Context, info *ResourcePatcherResourceInfo) (bool, error) {
+	return true, nil
+}
+
+func (p *ManifestHashPatcher) Patch(ctx context.Context, info *ResourcePatcherResourceInfo) (*unstructured.Unstructured, error) {
+	setAnnotationsAndLabels(info.Obj, map[string]string{
+		annotationKeyHumanManifestHash: ManifestHash(info.Obj),
+	}, nil)
+
+	return info.Obj, nil
+}
+
+func (p *ManifestHashPatcher) Type() ResourcePatcherType {
+	return TypeManifestHashPatcher
+}
@@ -0,0 +1,75 @@
+package resource_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("manifest hash", func() {
+	const manifest = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  annotations:
+    team: platform
+data:
+  key: value
+  other: value
+`
+
+	It("is stable across renders of identical input", func() {
+		reordered := unstructFromYAML(`
+kind: ConfigMap
+data:
+  other: value
+  key: value
+metadata:
+  annotations:
+    team: platform
+  name: app
+apiVersion: v1
+`)
+
+		hash := resource.ManifestHash(unstructFromYAML(manifest))
+		Expect(hash).To(HaveLen(12))
+		Expect(resource.ManifestHash(unstructFromYAML(manifest))).To(Equal(hash))
+		Expect(resource.ManifestHash(reordered)).To(Equal(hash))
+	})
+
+	It("changes with the manifest", func() {
+		changed := unstructFromYAML(manifest)
+		changed.Object["data"].(map[string]interface{})["key"] = "changed"
+
+		Expect(resource.ManifestHash(changed)).NotTo(Equal(resource.ManifestHash(unstructFromYAML(manifest))))
+	})
+
+	It("is added as the werf.io/manifest-hash annotation, which doesn't affect the hash", func() {
+		ctx := context.Background()
+		obj := unstructFromYAML(manifest)
+		hash := resource.ManifestHash(obj)
+
+		patcher := resource.NewManifestHashPatcher()
+		patched, err := patcher.Patch(ctx, &resource.ResourcePatcherResourceInfo{Obj: obj.DeepCopy()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched.GetAnnotations()).To(HaveKeyWithValue("werf.io/manifest-hash", hash))
+		Expect(patched.GetAnnotations()).To(HaveKeyWithValue("team", "platform"))
+		Expect(resource.ManifestHash(patched)).To(Equal(hash))
+
+		repatched, err := patcher.Patch(ctx, &resource.ResourcePatcherResourceInfo{Obj: patched.DeepCopy()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repatched.GetAnnotations()).To(Equal(patched.GetAnnotations()))
+	})
+
+	It("ignores the annotation when it's the only one", func() {
+		obj := unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: app}}`)
+		withAnnotation := obj.DeepCopy()
+		withAnnotation.SetAnnotations(map[string]string{"werf.io/manifest-hash": "0123456789ab"})
+
+		Expect(resource.ManifestHash(withAnnotation)).To(Equal(resource.ManifestHash(obj)))
+	})
+})
@@ -0,0 +1,139 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+// NewResourceFilter creates a filter from the include and exclude selectors. A selector is either
+// "<kind>/<name>", or "<group>/<kind>/<namespace>/<name>", where any part can be "*" and the group
+// and the namespace can be empty, or a label selector, e.g. "app=backend,tier!=cache". A resource
+// passes the filter if it matches any of the include selectors (or there are none) and none of the
+// exclude selectors.
+func NewResourceFilter(includeSelectors, excludeSelectors []string) (*ResourceFilter, error) {
+	filter := &ResourceFilter{}
+
+	for _, s := range includeSelectors {
+		selector, err := parseResourceSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing include selector %q: %w", s, err)
+		}
+
+		filter.include = append(filter.include, selector)
+	}
+
+	for _, s := range excludeSelectors {
+		selector, err := parseResourceSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing exclude selector %q: %w", s, err)
+		}
+
+		filter.exclude = append(filter.exclude, selector)
+	}
+
+	return filter, nil
+}
+
+type ResourceFilter struct {
+	include []*resourceSelector
+	exclude []*resourceSelector
+}
+
+func (f *ResourceFilter) Empty() bool {
+	return f == nil || (len(f.include) == 0 && len(f.exclude) == 0)
+}
+
+func (f *ResourceFilter) Match(resource *id.ResourceID, resourceLabels map[string]string) bool {
+	if f.Empty() {
+		return true
+	}
+
+	if len(f.include) > 0 {
+		var included bool
+		for _, selector := range f.include {
+			if selector.match(resource, resourceLabels) {
+				included = true
+				break
+			}
+		}
+
+		if !included {
+			return false
+		}
+	}
+
+	for _, selector := range f.exclude {
+		if selector.match(resource, resourceLabels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseResourceSelector(s string) (*resourceSelector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("selector is empty")
+	}
+
+	if strings.ContainsAny(s, "=!(") || !strings.Contains(s, "/") {
+		labelSelector, err := labels.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing label selector: %w", err)
+		}
+
+		return &resourceSelector{labelSelector: labelSelector}, nil
+	}
+
+	parts := strings.Split(s, "/")
+
+	switch len(parts) {
+	case 2:
+		return &resourceSelector{
+			group:     "*",
+			kind:      parts[0],
+			namespace: "*",
+			name:      parts[1],
+		}, nil
+	case 4:
+		return &resourceSelector{
+			group:     parts[0],
+			kind:      parts[1],
+			namespace: parts[2],
+			name:      parts[3],
+		}, nil
+	default:
+		return nil, fmt.Errorf(`expected "<kind>/<name>", "<group>/<kind>/<namespace>/<name>" or a label selector`)
+	}
+}
+
+type resourceSelector struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+
+	labelSelector labels.Selector
+}
+
+func (s *resourceSelector) match(resource *id.ResourceID, resourceLabels map[string]string) bool {
+	if s.labelSelector != nil {
+		return s.labelSelector.Matches(labels.Set(resourceLabels))
+	}
+
+	gvk := resource.GroupVersionKind()
+
+	return selectorPartMatch(s.group, gvk.Group) &&
+		(s.kind == "*" || strings.EqualFold(s.kind, gvk.Kind)) &&
+		selectorPartMatch(s.namespace, resource.Namespace()) &&
+		selectorPartMatch(s.name, resource.Name())
+}
+
+func selectorPartMatch(selectorPart, value string) bool {
+	return selectorPart == "*" || selectorPart == value
+}
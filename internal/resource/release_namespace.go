@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
@@ -42,6 +43,21 @@ func (r *ReleaseNamespace) Unstructured() *unstructured.Unstructured {
 	return r.unstruct
 }
 
+// OwnedBy returns a copy of the namespace marked as created or adopted by the release. Labels and
+// annotations of the namespace are then kept up to date by the deploys of this release.
+func (r *ReleaseNamespace) OwnedBy(releaseName string) *ReleaseNamespace {
+	unstruct := r.unstruct.DeepCopy()
+	unstruct.SetAnnotations(lo.Assign(unstruct.GetAnnotations(), map[string]string{
+		annotationKeyHumanReleaseNamespaceOwner: releaseName,
+	}))
+
+	return &ReleaseNamespace{
+		ResourceID: r.ResourceID,
+		unstruct:   unstruct,
+		mapper:     r.mapper,
+	}
+}
+
 func (r *ReleaseNamespace) ManageableBy() ManageableBy {
 	return ManageableByAnyone
 }
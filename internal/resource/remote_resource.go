@@ -50,6 +50,15 @@ func (r *RemoteResource) AdoptableBy(releaseName, releaseNamespace string) (adop
 	return adoptableBy(r.unstruct, releaseName, releaseNamespace)
 }
 
+func (r *RemoteResource) OwnerRelease() (releaseName, releaseNamespace string, found bool) {
+	return ownerRelease(r.unstruct)
+}
+
+// ReleaseNamespaceOwner returns the release which created or adopted the namespace, if any.
+func (r *RemoteResource) ReleaseNamespaceOwner() (releaseName string, found bool) {
+	return releaseNamespaceOwner(r.unstruct)
+}
+
 func (r *RemoteResource) KeepOnDelete(releaseName, releaseNamespace string) bool {
 	if err := validateResourcePolicy(r.unstruct); err != nil {
 		return true
@@ -57,3 +66,7 @@ func (r *RemoteResource) KeepOnDelete(releaseName, releaseNamespace string) bool
 
 	return keepOnDelete(r.unstruct) || orphaned(r.unstruct, releaseName, releaseNamespace)
 }
+
+func (r *RemoteResource) Orphaned(releaseName, releaseNamespace string) bool {
+	return orphaned(r.unstruct, releaseName, releaseNamespace)
+}
@@ -0,0 +1,51 @@
+package resource_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm/internal/resource"
+)
+
+var _ = Describe("resource size validation", func() {
+	// configMapOfSize returns a ConfigMap whose JSON encoding is exactly size bytes.
+	configMapOfSize := func(size int) *unstructured.Unstructured {
+		obj := unstructFromYAML(`{apiVersion: v1, kind: ConfigMap, metadata: {name: app}, data: {payload: ""}}`)
+
+		data, err := json.Marshal(obj.UnstructuredContent())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(BeNumerically(">=", len(data)))
+
+		Expect(unstructured.SetNestedField(obj.Object, strings.Repeat("a", size-len(data)), "data", "payload")).To(Succeed())
+
+		data, err = json.Marshal(obj.UnstructuredContent())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveLen(size))
+
+		return obj
+	}
+
+	DescribeTable("accepts resources up to the limit",
+		func(size, limit int) {
+			Expect(resource.ValidateSize(configMapOfSize(size), limit)).To(Succeed())
+		},
+		Entry("well below the limit", 100, 1000),
+		Entry("exactly at the limit", 1000, 1000),
+		Entry("exactly at the default limit", resource.DefaultSizeLimit, resource.DefaultSizeLimit),
+	)
+
+	DescribeTable("rejects resources over the limit with human-readable sizes",
+		func(size, limit int, expectedMessage string) {
+			err := resource.ValidateSize(configMapOfSize(size), limit)
+			Expect(err).To(MatchError(HavePrefix(expectedMessage)))
+			Expect(err).To(MatchError(ContainSubstring("split the data into several resources")))
+		},
+		Entry("by one byte", 1001, 1000, "resource is 1001 bytes, which exceeds the limit of 1000 bytes"),
+		Entry("in KiB", 4096, 2048, "resource is 4.00 KiB (4096 bytes), which exceeds the limit of 2.00 KiB (2048 bytes)"),
+		Entry("over the default limit", resource.DefaultSizeLimit+1, resource.DefaultSizeLimit, "resource is 1.00 MiB (1048577 bytes), which exceeds the limit of 1.00 MiB (1048576 bytes)"),
+	)
+})
@@ -72,9 +72,45 @@ type StandaloneCRD struct {
 }
 
 func (r *StandaloneCRD) Validate() error {
+	if err := validateCRDPolicy(r.unstruct); err != nil {
+		return fmt.Errorf("error validating CRD policy for standalone CRD %q: %w", r.HumanID(), err)
+	}
+
 	return nil
 }
 
+// Policy returns the policy from the werf.io/crd-policy annotation of the CRD, or the default one.
+func (r *StandaloneCRD) Policy(defaultPolicy CRDPolicy) CRDPolicy {
+	if policy, set := crdPolicy(r.unstruct); set {
+		return policy
+	}
+
+	if defaultPolicy == "" {
+		return DefaultCRDPolicy
+	}
+
+	return defaultPolicy
+}
+
+func (r *StandaloneCRD) IgnoreFields() (paths []*FieldPath, errs []error) {
+	return ignoreFields(r.unstruct)
+}
+
+// WithIgnoredFieldsFrom returns the CRD with the ignored fields set to their values from the live
+// object.
+func (r *StandaloneCRD) WithIgnoredFieldsFrom(live *unstructured.Unstructured) *StandaloneCRD {
+	unstruct := copyIgnoredFields(r.unstruct, live)
+	if unstruct == nil {
+		return r
+	}
+
+	return &StandaloneCRD{
+		ResourceID: r.ResourceID,
+		unstruct:   unstruct,
+		mapper:     r.mapper,
+	}
+}
+
 func (r *StandaloneCRD) Unstructured() *unstructured.Unstructured {
 	return r.unstruct
 }
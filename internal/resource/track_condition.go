@@ -0,0 +1,115 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// TrackCondition is an expression evaluated against the live object to determine whether it is
+// ready or failed, e.g. "Synced=True" for the status condition or ".status.phase=Running" for
+// the JSONPath.
+type TrackCondition struct {
+	conditionType string
+	jsonPath      *jsonpath.JSONPath
+	expr          string
+	value         string
+}
+
+// ParseStatusTrackCondition parses "<type>=<status>", which holds if the status of the condition
+// of this type in .status.conditions matches, case-insensitively.
+func ParseStatusTrackCondition(s string) (*TrackCondition, error) {
+	conditionType, value, found := strings.Cut(s, "=")
+	conditionType, value = strings.TrimSpace(conditionType), strings.TrimSpace(value)
+	if !found || conditionType == "" || value == "" {
+		return nil, fmt.Errorf("expected <type>=<status>, e.g. Ready=True")
+	}
+
+	return &TrackCondition{
+		conditionType: conditionType,
+		expr:          s,
+		value:         value,
+	}, nil
+}
+
+// ParseJSONPathTrackCondition parses "<jsonpath>=<value>", which holds if any of the values found
+// by the JSONPath matches. The value is separated by the last "=", so that JSONPath filters like
+// `.status.conditions[?(@.type=="Ready")].status=True` can be used.
+func ParseJSONPathTrackCondition(s string) (*TrackCondition, error) {
+	sepIndex := strings.LastIndex(s, "=")
+	if sepIndex == -1 {
+		return nil, fmt.Errorf("expected <jsonpath>=<value>, e.g. .status.phase=Running")
+	}
+
+	path, value := strings.TrimSpace(s[:sepIndex]), strings.TrimSpace(s[sepIndex+1:])
+	if path == "" || value == "" {
+		return nil, fmt.Errorf("expected <jsonpath>=<value>, e.g. .status.phase=Running")
+	}
+
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+
+	jsonPath := jsonpath.New(s).AllowMissingKeys(true)
+	if err := jsonPath.Parse(path); err != nil {
+		return nil, fmt.Errorf("parse jsonpath %q: %w", path, err)
+	}
+
+	return &TrackCondition{
+		jsonPath: jsonPath,
+		expr:     s,
+		value:    value,
+	}, nil
+}
+
+// Holds evaluates the condition against the object. Missing fields don't match anything.
+func (c *TrackCondition) Holds(obj map[string]interface{}) (bool, error) {
+	if c.jsonPath == nil {
+		return c.statusConditionHolds(obj), nil
+	}
+
+	results, err := c.jsonPath.FindResults(obj)
+	if err != nil {
+		return false, fmt.Errorf("evaluate jsonpath of %q: %w", c.expr, err)
+	}
+
+	for _, result := range results {
+		for _, val := range result {
+			buf := &bytes.Buffer{}
+			if err := c.jsonPath.PrintResults(buf, []reflect.Value{val}); err != nil {
+				return false, fmt.Errorf("print jsonpath result of %q: %w", c.expr, err)
+			}
+
+			if buf.String() == c.value {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (c *TrackCondition) String() string {
+	return c.expr
+}
+
+func (c *TrackCondition) statusConditionHolds(obj map[string]interface{}) bool {
+	status, _ := obj["status"].(map[string]interface{})
+	conditions, _ := status["conditions"].([]interface{})
+
+	for _, cond := range conditions {
+		cond, ok := cond.(map[string]interface{})
+		if !ok || cond["type"] != c.conditionType {
+			continue
+		}
+
+		condStatus, _ := cond["status"].(string)
+
+		return strings.EqualFold(condStatus, c.value)
+	}
+
+	return false
+}
@@ -1,6 +1,10 @@
 package resource
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,6 +14,36 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// Defaults to the ConfigMap/Secret data limit, which is below the etcd request size limit with
+// room left for the encoding overhead.
+const DefaultSizeLimit = 1024 * 1024
+
+// Size of the resource is calculated from its JSON encoding, since this is what is sent to the
+// Kubernetes API server.
+func ValidateSize(unstruct *unstructured.Unstructured, limit int) error {
+	data, err := json.Marshal(unstruct.UnstructuredContent())
+	if err != nil {
+		return fmt.Errorf("error marshalling resource to JSON: %w", err)
+	}
+
+	if len(data) <= limit {
+		return nil
+	}
+
+	return fmt.Errorf("resource is %s, which exceeds the limit of %s: split the data into several resources or deliver it to the pods using a volume instead; raise the limit only if the Kubernetes API server and etcd are configured to accept bigger objects", humanizeBytes(len(data)), humanizeBytes(limit))
+}
+
+func humanizeBytes(size int) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MiB (%d bytes)", float64(size)/(1024*1024), size)
+	case size >= 1024:
+		return fmt.Sprintf("%.2f KiB (%d bytes)", float64(size)/1024, size)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
 func IsSensitive(groupKind schema.GroupKind, annotations map[string]string) bool {
 	if _, value, found := FindAnnotationOrLabelByKeyPattern(annotations, annotationKeyPatternSensitive); found {
 		sensitive := lo.Must(strconv.ParseBool(value))
@@ -26,6 +60,27 @@ func IsSensitive(groupKind schema.GroupKind, annotations map[string]string) bool
 	return false
 }
 
+// Short hash of the manifest, which ignores the manifest hash annotation itself.
+func ManifestHash(unstruct *unstructured.Unstructured) string {
+	obj := unstruct.DeepCopy()
+
+	if key, _, found := FindAnnotationOrLabelByKeyPattern(obj.GetAnnotations(), annotationKeyPatternManifestHash); found {
+		annos := obj.GetAnnotations()
+		delete(annos, key)
+
+		if len(annos) == 0 {
+			annos = nil
+		}
+
+		obj.SetAnnotations(annos)
+	}
+
+	data := lo.Must(json.Marshal(obj.Object))
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])[:12]
+}
+
 func IsHook(annotations map[string]string) bool {
 	_, _, found := FindAnnotationOrLabelByKeyPattern(annotations, annotationKeyPatternHook)
 	return found
@@ -0,0 +1,490 @@
+// Package sops encrypts and decrypts YAML values files with age keys in the SOPS format, so that
+// such files can be decrypted and edited with SOPS too.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml_v3 "gopkg.in/yaml.v3"
+
+	"github.com/werf/nelm/internal/age"
+)
+
+const (
+	MetadataKey = "sops"
+
+	version           = "3.8.1"
+	unencryptedSuffix = "_unencrypted"
+	dataKeySize       = 32
+	ivSize            = 32
+)
+
+var encryptedValueRegexp = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// IsEncrypted returns true if the values file has the SOPS metadata.
+func IsEncrypted(data []byte) bool {
+	root, err := parseRoot(data)
+	if err != nil || root == nil {
+		return false
+	}
+
+	_, metadata := mappingValue(root, MetadataKey)
+
+	return metadata != nil && metadata.Kind == yaml_v3.MappingNode
+}
+
+// EncryptValues encrypts all values of the values file, except values under keys with the
+// "_unencrypted" suffix, with a new data key. The data key is encrypted for each of the recipients.
+// Comments are dropped, since SOPS expects them encrypted too.
+func EncryptValues(data []byte, recipients []*age.Recipient) ([]byte, error) {
+	root, err := parseRoot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		root = &yaml_v3.Node{Kind: yaml_v3.MappingNode, Tag: "!!map"}
+	}
+
+	if _, metadata := mappingValue(root, MetadataKey); metadata != nil {
+		return nil, fmt.Errorf("values are already encrypted")
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("error generating data key: %w", err)
+	}
+
+	mac := sha512.New()
+	if err := walkValues(root, nil, func(node *yaml_v3.Node, path []string) error {
+		value, valueType, err := scalarValue(node)
+		if err != nil {
+			return fmt.Errorf("error encrypting value at %q: %w", strings.Join(path, "."), err)
+		}
+		mac.Write([]byte(macValue(value, valueType)))
+
+		if isUnencrypted(path) {
+			return nil
+		}
+
+		encrypted, err := encryptValue(dataKey, value, valueType, additionalData(path))
+		if err != nil {
+			return fmt.Errorf("error encrypting value at %q: %w", strings.Join(path, "."), err)
+		}
+
+		setString(node, encrypted)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	lastModified := time.Now().UTC().Format(time.RFC3339)
+
+	encryptedMAC, err := encryptValue(dataKey, strings.ToUpper(hex.EncodeToString(mac.Sum(nil))), "str", lastModified)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting MAC: %w", err)
+	}
+
+	metadata := &yaml_v3.Node{Kind: yaml_v3.MappingNode, Tag: "!!map"}
+
+	ageEntries := &yaml_v3.Node{Kind: yaml_v3.SequenceNode, Tag: "!!seq"}
+	for _, recipient := range recipients {
+		encryptedDataKey, err := age.Encrypt(dataKey, recipient)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting data key for recipient %q: %w", recipient, err)
+		}
+
+		entry := &yaml_v3.Node{Kind: yaml_v3.MappingNode, Tag: "!!map"}
+		appendMappingValue(entry, "recipient", stringNode(recipient.String()))
+		appendMappingValue(entry, "enc", &yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!str", Value: string(age.Armor(encryptedDataKey)), Style: yaml_v3.LiteralStyle})
+		ageEntries.Content = append(ageEntries.Content, entry)
+	}
+
+	appendMappingValue(metadata, "age", ageEntries)
+	appendMappingValue(metadata, "lastmodified", stringNode(lastModified))
+	appendMappingValue(metadata, "mac", stringNode(encryptedMAC))
+	appendMappingValue(metadata, "unencrypted_suffix", stringNode(unencryptedSuffix))
+	appendMappingValue(metadata, "version", stringNode(version))
+	appendMappingValue(root, MetadataKey, metadata)
+
+	return marshalRoot(root)
+}
+
+// DecryptValues decrypts the values file with any of the identities matching its age recipients and
+// verifies the MAC of the values.
+func DecryptValues(data []byte, identities []*age.Identity) ([]byte, error) {
+	root, err := parseRoot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata *yaml_v3.Node
+	if root != nil {
+		_, metadata = mappingValue(root, MetadataKey)
+	}
+	if metadata == nil || metadata.Kind != yaml_v3.MappingNode {
+		return nil, fmt.Errorf("no %q metadata found, values are not encrypted with SOPS", MetadataKey)
+	}
+
+	dataKey, err := decryptDataKey(metadata, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	removeMappingValue(root, MetadataKey)
+
+	mac := sha512.New()
+	if err := walkValues(root, nil, func(node *yaml_v3.Node, path []string) error {
+		if isUnencrypted(path) {
+			value, valueType, err := scalarValue(node)
+			if err != nil {
+				return fmt.Errorf("error reading value at %q: %w", strings.Join(path, "."), err)
+			}
+			mac.Write([]byte(macValue(value, valueType)))
+
+			return nil
+		}
+
+		if node.ShortTag() != "!!str" {
+			return fmt.Errorf("value at %q is not encrypted", strings.Join(path, "."))
+		}
+
+		value, valueType, err := decryptValue(dataKey, node.Value, additionalData(path))
+		if err != nil {
+			return fmt.Errorf("error decrypting value at %q: %w", strings.Join(path, "."), err)
+		}
+		mac.Write([]byte(macValue(value, valueType)))
+
+		return setTypedValue(node, value, valueType)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := verifyMAC(metadata, dataKey, mac); err != nil {
+		return nil, err
+	}
+
+	return marshalRoot(root)
+}
+
+func decryptDataKey(metadata *yaml_v3.Node, identities []*age.Identity) ([]byte, error) {
+	_, ageEntries := mappingValue(metadata, "age")
+	if ageEntries == nil || ageEntries.Kind != yaml_v3.SequenceNode || len(ageEntries.Content) == 0 {
+		return nil, fmt.Errorf("values are not encrypted with age keys")
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identities specified")
+	}
+
+	var recipients []string
+	for _, entry := range ageEntries.Content {
+		_, recipient := mappingValue(entry, "recipient")
+		_, enc := mappingValue(entry, "enc")
+		if recipient == nil || enc == nil {
+			continue
+		}
+		recipients = append(recipients, recipient.Value)
+
+		dataKey, err := age.Decrypt([]byte(enc.Value), identities...)
+		if err == nil {
+			return dataKey, nil
+		} else if err != age.ErrNoMatchingIdentity {
+			return nil, fmt.Errorf("error decrypting data key for recipient %q: %w", recipient.Value, err)
+		}
+	}
+
+	return nil, fmt.Errorf("none of the age identities matches recipients %s", strings.Join(recipients, ", "))
+}
+
+func verifyMAC(metadata *yaml_v3.Node, dataKey []byte, mac hash.Hash) error {
+	_, lastModified := mappingValue(metadata, "lastmodified")
+	_, encryptedMAC := mappingValue(metadata, "mac")
+	if lastModified == nil || encryptedMAC == nil {
+		return fmt.Errorf("no MAC found in %q metadata", MetadataKey)
+	}
+
+	expectedMAC, _, err := decryptValue(dataKey, encryptedMAC.Value, lastModified.Value)
+	if err != nil {
+		return fmt.Errorf("error decrypting MAC: %w", err)
+	}
+
+	if !strings.EqualFold(expectedMAC, hex.EncodeToString(mac.Sum(nil))) {
+		return fmt.Errorf("MAC mismatch, values were modified without reencryption")
+	}
+
+	return nil
+}
+
+// Values are encrypted with AES-256-GCM with 32 byte nonces, the path to the value is used as
+// additional data, so that encrypted values can't be moved around.
+func encryptValue(key []byte, value, valueType, additionalData string) (string, error) {
+	if value == "" && valueType == "str" {
+		return "", nil
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, ivSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("error generating iv: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType,
+	), nil
+}
+
+func decryptValue(key []byte, encrypted, additionalData string) (string, string, error) {
+	if encrypted == "" {
+		return "", "str", nil
+	}
+
+	match := encryptedValueRegexp.FindStringSubmatch(encrypted)
+	if match == nil {
+		return "", "", fmt.Errorf("value doesn't match the SOPS encrypted value format")
+	}
+
+	var parts [3][]byte
+	for i, encoded := range match[1:4] {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", "", fmt.Errorf("error decoding encrypted value: %w", err)
+		}
+		parts[i] = decoded
+	}
+	ciphertext, iv, tag := parts[0], parts[1], parts[2]
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(iv) != ivSize {
+		return "", "", fmt.Errorf("unexpected iv size %d", len(iv))
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", fmt.Errorf("error decrypting value: %w", err)
+	}
+
+	return string(plaintext), match[4], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, ivSize)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	return gcm, nil
+}
+
+func additionalData(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+func isUnencrypted(path []string) bool {
+	for _, key := range path {
+		if strings.HasSuffix(key, unencryptedSuffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SOPS hashes booleans the way Python prints them.
+func macValue(value, valueType string) string {
+	if valueType == "bool" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			if b {
+				return "True"
+			}
+			return "False"
+		}
+	}
+
+	return value
+}
+
+func scalarValue(node *yaml_v3.Node) (string, string, error) {
+	switch node.ShortTag() {
+	case "!!int":
+		var v int64
+		if err := node.Decode(&v); err != nil {
+			return "", "", err
+		}
+
+		return strconv.FormatInt(v, 10), "int", nil
+	case "!!float":
+		var v float64
+		if err := node.Decode(&v); err != nil {
+			return "", "", err
+		}
+
+		return strconv.FormatFloat(v, 'f', -1, 64), "float", nil
+	case "!!bool":
+		var v bool
+		if err := node.Decode(&v); err != nil {
+			return "", "", err
+		}
+
+		return strconv.FormatBool(v), "bool", nil
+	default:
+		var v string
+		if err := node.Decode(&v); err != nil {
+			return "", "", err
+		}
+
+		return v, "str", nil
+	}
+}
+
+func setTypedValue(node *yaml_v3.Node, value, valueType string) error {
+	var v interface{}
+	var err error
+	switch valueType {
+	case "int":
+		v, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		v, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		v, err = strconv.ParseBool(value)
+	case "str":
+		v = value
+	default:
+		return fmt.Errorf("unsupported value type %q", valueType)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error parsing %s value: %w", valueType, err)
+	}
+
+	if err := node.Encode(v); err != nil {
+		return fmt.Errorf("error encoding value: %w", err)
+	}
+
+	return nil
+}
+
+func setString(node *yaml_v3.Node, value string) {
+	*node = yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// Calls fn for each non-null scalar. Only mapping keys are parts of the path, as in SOPS.
+func walkValues(node *yaml_v3.Node, path []string, fn func(node *yaml_v3.Node, path []string) error) error {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+
+	switch node.Kind {
+	case yaml_v3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			node.Content[i].HeadComment, node.Content[i].LineComment, node.Content[i].FootComment = "", "", ""
+
+			if err := walkValues(node.Content[i+1], append(append([]string{}, path...), node.Content[i].Value), fn); err != nil {
+				return err
+			}
+		}
+	case yaml_v3.SequenceNode:
+		for _, child := range node.Content {
+			if err := walkValues(child, path, fn); err != nil {
+				return err
+			}
+		}
+	case yaml_v3.AliasNode:
+		return fmt.Errorf("aliases are not supported")
+	case yaml_v3.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return nil
+		}
+
+		return fn(node, path)
+	}
+
+	return nil
+}
+
+func parseRoot(data []byte) (*yaml_v3.Node, error) {
+	var doc yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal values: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml_v3.MappingNode {
+		return nil, fmt.Errorf("values must be a mapping")
+	}
+
+	return root, nil
+}
+
+func marshalRoot(root *yaml_v3.Node) ([]byte, error) {
+	var result bytes.Buffer
+	encoder := yaml_v3.NewEncoder(&result)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("unable to marshal values: %w", err)
+	}
+
+	return result.Bytes(), nil
+}
+
+func mappingValue(node *yaml_v3.Node, key string) (int, *yaml_v3.Node) {
+	if node.Kind != yaml_v3.MappingNode {
+		return -1, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i, node.Content[i+1]
+		}
+	}
+
+	return -1, nil
+}
+
+func appendMappingValue(node *yaml_v3.Node, key string, value *yaml_v3.Node) {
+	node.Content = append(node.Content, stringNode(key), value)
+}
+
+func removeMappingValue(node *yaml_v3.Node, key string) {
+	if i, _ := mappingValue(node, key); i >= 0 {
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+	}
+}
+
+func stringNode(value string) *yaml_v3.Node {
+	return &yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,13 @@
+package sops_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSops(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sops Suite")
+}
@@ -0,0 +1,119 @@
+package sops_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/nelm/internal/age"
+	"github.com/werf/nelm/internal/sops"
+)
+
+var _ = Describe("sops", func() {
+	const values = `db:
+  password: secret
+  port: 5432
+  ratio: 0.5
+  enabled: true
+  hosts:
+    - db-1
+    - db-2
+  user_unencrypted: app
+`
+
+	var identity, otherIdentity *age.Identity
+
+	BeforeEach(func() {
+		var err error
+		identity, err = age.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+
+		otherIdentity, err = age.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	encrypt := func() string {
+		encrypted, err := sops.EncryptValues([]byte(values), []*age.Recipient{otherIdentity.Recipient(), identity.Recipient()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sops.IsEncrypted(encrypted)).To(BeTrue())
+
+		return string(encrypted)
+	}
+
+	It("encrypts and decrypts values keeping their types", func() {
+		encrypted := encrypt()
+		Expect(encrypted).NotTo(ContainSubstring("secret"))
+		Expect(encrypted).NotTo(ContainSubstring("db-1"))
+		Expect(encrypted).To(ContainSubstring("user_unencrypted: app"))
+		Expect(encrypted).To(ContainSubstring("type:int]"))
+		Expect(encrypted).To(ContainSubstring("type:bool]"))
+
+		decrypted, err := sops.DecryptValues([]byte(encrypted), []*age.Identity{identity})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sops.IsEncrypted(decrypted)).To(BeFalse())
+
+		var expected, actual map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(values), &expected)).To(Succeed())
+		Expect(yaml.Unmarshal(decrypted, &actual)).To(Succeed())
+		Expect(actual).To(Equal(expected))
+	})
+
+	It("fails to decrypt with a non-matching identity", func() {
+		stranger, err := age.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = sops.DecryptValues([]byte(encrypt()), []*age.Identity{stranger})
+		Expect(err).To(MatchError(ContainSubstring("none of the age identities matches")))
+	})
+
+	It("fails to decrypt values moved to other keys", func() {
+		encrypted := encrypt()
+
+		var hosts []string
+		for _, line := range strings.Split(encrypted, "\n") {
+			if strings.HasPrefix(line, "    - ENC[") {
+				hosts = append(hosts, line)
+			}
+		}
+		Expect(hosts).To(HaveLen(2))
+
+		swapped := strings.NewReplacer(hosts[0], hosts[1], hosts[1], hosts[0]).Replace(encrypted)
+
+		_, err := sops.DecryptValues([]byte(swapped), []*age.Identity{identity})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails to decrypt values with changed unencrypted values", func() {
+		tampered := strings.Replace(encrypt(), "user_unencrypted: app", "user_unencrypted: admin", 1)
+
+		_, err := sops.DecryptValues([]byte(tampered), []*age.Identity{identity})
+		Expect(err).To(MatchError(ContainSubstring("MAC")))
+	})
+
+	// The fixture has what SOPS writes and EncryptValues doesn't: encrypted comments, booleans
+	// encrypted as "True", empty lists of other key sources and 4-space indentation.
+	It("decrypts values in the SOPS layout with the age key file", func() {
+		keyData, err := os.ReadFile(filepath.Join("testdata", "key.txt"))
+		Expect(err).NotTo(HaveOccurred())
+
+		identities, err := age.ParseIdentities(keyData)
+		Expect(err).NotTo(HaveOccurred())
+
+		encrypted, err := os.ReadFile(filepath.Join("testdata", "values.sops.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sops.IsEncrypted(encrypted)).To(BeTrue())
+
+		decrypted, err := sops.DecryptValues(encrypted, identities)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(decrypted)).NotTo(ContainSubstring("ENC["))
+
+		var expected, actual map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(values+"  empty: \"\"\n"), &expected)).To(Succeed())
+		Expect(yaml.Unmarshal(decrypted, &actual)).To(Succeed())
+		Expect(actual).To(Equal(expected))
+	})
+})
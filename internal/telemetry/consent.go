@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/util"
+)
+
+const (
+	consentFileName = "telemetry.json"
+	spoolFileName   = "telemetry-spool.jsonl"
+)
+
+type Consent struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func DefaultConfigDir() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user config dir: %w", err)
+	}
+
+	return filepath.Join(userConfigDir, strings.ToLower(common.Brand)), nil
+}
+
+// LoadConsent returns disabled consent if it was never given.
+func LoadConsent(configDir string) (*Consent, error) {
+	consent := &Consent{}
+
+	data, err := os.ReadFile(filepath.Join(configDir, consentFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return consent, nil
+		}
+
+		return nil, fmt.Errorf("error reading telemetry consent: %w", err)
+	}
+
+	if err := json.Unmarshal(data, consent); err != nil {
+		return nil, fmt.Errorf("error unmarshaling telemetry consent: %w", err)
+	}
+
+	return consent, nil
+}
+
+func SaveConsent(configDir string, consent *Consent) error {
+	data, err := json.MarshalIndent(consent, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling telemetry consent: %w", err)
+	}
+
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return fmt.Errorf("error creating config dir %q: %w", configDir, err)
+	}
+
+	if err := util.WriteFileAtomic(filepath.Join(configDir, consentFileName), data, 0o644); err != nil {
+		return fmt.Errorf("error writing telemetry consent: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/werf/nelm/internal/common"
+)
+
+const SchemaVersion = 1
+
+const (
+	ErrorClassCanceled = "canceled"
+	ErrorClassTimeout  = "timeout"
+	ErrorClassKubeAPI  = "kube-api"
+	ErrorClassOther    = "other"
+)
+
+// Event is the only payload ever sent. It must never contain chart, release or namespace names,
+// values, flag values, hostnames, usernames or anything else identifying the user or the cluster.
+type Event struct {
+	// Version of this schema. Incremented on any change of the fields.
+	SchemaVersion int `json:"schemaVersion"`
+	// Random ID generated for every invocation, not related to the user or the host.
+	InvocationID string `json:"invocationId"`
+	// Command path without arguments and flags, e.g. "release install".
+	Command string `json:"command"`
+	// Version of the binary.
+	Version string `json:"version"`
+	// GOOS and GOARCH of the binary.
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// Start time of the command, truncated to the hour.
+	StartedAt time.Time `json:"startedAt"`
+	// Duration of the command in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+	// Number of release resources the command worked with, if applicable.
+	ResourceCount int `json:"resourceCount,omitempty"`
+	// Empty if the command succeeded, otherwise one of: canceled, timeout, kube-api, other.
+	ErrorClass string `json:"errorClass,omitempty"`
+}
+
+func NewInvocation() *Invocation {
+	return &Invocation{
+		startedAt: time.Now(),
+	}
+}
+
+// Invocation collects data about the current command. All methods are safe to call on nil.
+type Invocation struct {
+	startedAt time.Time
+
+	mu            sync.Mutex
+	command       string
+	resourceCount int
+}
+
+func (i *Invocation) SetCommand(command string) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.command = command
+}
+
+func (i *Invocation) SetResourceCount(count int) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.resourceCount = count
+}
+
+func (i *Invocation) Event(err error) Event {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return Event{
+		SchemaVersion: SchemaVersion,
+		InvocationID:  uuid.NewString(),
+		Command:       i.command,
+		Version:       common.Version,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		StartedAt:     i.startedAt.UTC().Truncate(time.Hour),
+		DurationMs:    time.Since(i.startedAt).Milliseconds(),
+		ResourceCount: i.resourceCount,
+		ErrorClass:    errorClass(err),
+	}
+}
+
+func errorClass(err error) string {
+	var apiStatus apierrors.APIStatus
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &apiStatus):
+		return ErrorClassKubeAPI
+	default:
+		return ErrorClassOther
+	}
+}
+
+type invocationCtxKey struct{}
+
+func NewContext(ctx context.Context, invocation *Invocation) context.Context {
+	return context.WithValue(ctx, invocationCtxKey{}, invocation)
+}
+
+// FromContext returns nil if there is no invocation in the context, which is fine to use.
+func FromContext(ctx context.Context) *Invocation {
+	invocation, _ := ctx.Value(invocationCtxKey{}).(*Invocation)
+	return invocation
+}
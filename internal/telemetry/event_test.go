@@ -0,0 +1,90 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/internal/telemetry"
+)
+
+var _ = Describe("event", func() {
+	goldenPath := filepath.Join("testdata", "event.golden.json")
+
+	readGolden := func() []byte {
+		expected, err := os.ReadFile(goldenPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		return expected
+	}
+
+	It("marshals to the documented schema", func() {
+		got, err := json.MarshalIndent(telemetry.Event{
+			SchemaVersion: telemetry.SchemaVersion,
+			InvocationID:  "2f6b8c4e-4f0e-4c4a-9d55-0c6f3b1e7a21",
+			Command:       "release install",
+			Version:       "1.2.3",
+			OS:            "linux",
+			Arch:          "amd64",
+			StartedAt:     time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC),
+			DurationMs:    4210,
+			ResourceCount: 12,
+			ErrorClass:    telemetry.ErrorClassTimeout,
+		}, "", "  ")
+		Expect(err).NotTo(HaveOccurred())
+		got = append(got, '\n')
+
+		if os.Getenv("UPDATE_GOLDEN") != "" {
+			Expect(os.WriteFile(goldenPath, got, 0o644)).To(Succeed())
+		}
+
+		Expect(string(got)).To(Equal(string(readGolden())))
+	})
+
+	DescribeTable("contains only the fields of the schema",
+		func(err error, expectErrorClass string) {
+			invocation := telemetry.NewInvocation()
+			invocation.SetCommand("release install")
+			invocation.SetResourceCount(12)
+
+			data, marshalErr := json.Marshal(invocation.Event(err))
+			Expect(marshalErr).NotTo(HaveOccurred())
+
+			var event, golden map[string]any
+			Expect(json.Unmarshal(data, &event)).To(Succeed())
+			Expect(json.Unmarshal(readGolden(), &golden)).To(Succeed())
+
+			for key, value := range event {
+				Expect(golden).To(HaveKey(key))
+				Expect(value).To(BeAssignableToTypeOf(golden[key]), "field %q", key)
+			}
+
+			Expect(event).To(HaveKeyWithValue("command", "release install"))
+			Expect(event).To(HaveKeyWithValue("resourceCount", BeNumerically("==", 12)))
+
+			startedAt, parseErr := time.Parse(time.RFC3339, event["startedAt"].(string))
+			Expect(parseErr).NotTo(HaveOccurred())
+			Expect(startedAt).To(Equal(startedAt.Truncate(time.Hour)))
+
+			if expectErrorClass == "" {
+				Expect(event).NotTo(HaveKey("errorClass"))
+			} else {
+				Expect(event).To(HaveKeyWithValue("errorClass", expectErrorClass))
+				Expect(string(data)).NotTo(ContainSubstring(err.Error()))
+			}
+		},
+		Entry("on success", nil, ""),
+		Entry("on cancel", fmt.Errorf("deploy: %w", context.Canceled), telemetry.ErrorClassCanceled),
+		Entry("on timeout", fmt.Errorf("track: %w", context.DeadlineExceeded), telemetry.ErrorClassTimeout),
+		Entry("on a Kubernetes API error", fmt.Errorf("apply: %w", apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "db-password", fmt.Errorf("denied"))), telemetry.ErrorClassKubeAPI),
+		Entry("on any other error, without its message", fmt.Errorf("render chart %q: failed", "my-secret-chart"), telemetry.ErrorClassOther),
+	)
+})
@@ -0,0 +1,225 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	ModeConsent = ""
+	ModeOn      = "on"
+	ModeOff     = "off"
+)
+
+var Modes = []string{ModeConsent, ModeOn, ModeOff}
+
+const (
+	// Hard limit for sending spooled events. The command never waits for telemetry longer than
+	// this, counting from the start of the command.
+	flushBudget      = time.Second
+	maxSpooledEvents = 1000
+)
+
+type SessionOptions struct {
+	ConfigDir string
+	Mode      string
+}
+
+// StartSession starts sending events spooled by previous commands in the background. Returns nil
+// if telemetry is disabled, or if anything goes wrong: telemetry must never fail the command.
+func StartSession(ctx context.Context, opts SessionOptions) *Session {
+	if opts.Mode == ModeOff {
+		return nil
+	}
+
+	if opts.ConfigDir == "" {
+		configDir, err := DefaultConfigDir()
+		if err != nil {
+			return nil
+		}
+
+		opts.ConfigDir = configDir
+	}
+
+	consent, err := LoadConsent(opts.ConfigDir)
+	if err != nil {
+		return nil
+	}
+
+	if !consent.Enabled && opts.Mode != ModeOn {
+		return nil
+	}
+
+	session := &Session{
+		spoolPath: filepath.Join(opts.ConfigDir, spoolFileName),
+		endpoint:  consent.Endpoint,
+		flushDone: make(chan struct{}),
+	}
+
+	flushCtx, flushCtxCancelFn := context.WithTimeout(context.WithoutCancel(ctx), flushBudget)
+	go func() {
+		defer close(session.flushDone)
+		defer flushCtxCancelFn()
+
+		session.flush(flushCtx)
+	}()
+
+	return session
+}
+
+// Session spools events to a local file and sends them to the configured endpoint. Events that
+// weren't sent in time are sent by one of the next commands.
+type Session struct {
+	spoolPath string
+	endpoint  string
+	flushDone chan struct{}
+}
+
+// Finish waits for the background sending, which is bounded by the flush budget, and spools the
+// event of the current command to be sent by the next one. The event is spooled only after the
+// sending is done, since sent events are removed by rewriting the spool. Safe to call on nil.
+func (s *Session) Finish(invocation *Invocation, err error) {
+	if s == nil || invocation == nil {
+		return
+	}
+
+	event := invocation.Event(err)
+
+	<-s.flushDone
+
+	s.spool(event)
+}
+
+func (s *Session) spool(event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.spoolPath), 0o755); err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(s.spoolPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	file.Write(append(data, '\n'))
+}
+
+func (s *Session) flush(ctx context.Context) {
+	if s.endpoint == "" {
+		if err := s.trimSpool(0); err != nil {
+			log.Default.Debug(ctx, "Unable to trim telemetry spool: %s", err)
+		}
+
+		return
+	}
+
+	events := readSpool(s.spoolPath)
+	if len(events) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return
+	}
+
+	// Events left in the spool are sent again by the next command.
+	if err := s.trimSpool(len(events)); err != nil {
+		log.Default.Debug(ctx, "Unable to remove sent events from telemetry spool: %s", err)
+	}
+}
+
+// Remove sent events from the spool and keep it bounded. Events spooled by other commands in the
+// meantime are kept.
+func (s *Session) trimSpool(sent int) error {
+	events := readSpool(s.spoolPath)
+	if sent > len(events) {
+		sent = len(events)
+	}
+	events = events[sent:]
+
+	if len(events) <= maxSpooledEvents && sent == 0 {
+		return nil
+	}
+
+	if len(events) > maxSpooledEvents {
+		events = events[len(events)-maxSpooledEvents:]
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.Write(event)
+		buf.WriteByte('\n')
+	}
+
+	if err := util.WriteFileAtomic(s.spoolPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error writing spool: %w", err)
+	}
+
+	return nil
+}
+
+func readSpool(path string) []json.RawMessage {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var events []json.RawMessage
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !json.Valid(line) {
+			continue
+		}
+
+		events = append(events, append(json.RawMessage{}, line...))
+	}
+
+	return events
+}
+
+// SpooledEventsCount returns the number of events not sent yet.
+func SpooledEventsCount(configDir string) int {
+	return len(readSpool(filepath.Join(configDir, spoolFileName)))
+}
+
+func DeleteSpool(configDir string) error {
+	if err := os.Remove(filepath.Join(configDir, spoolFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting telemetry spool: %w", err)
+	}
+
+	return nil
+}
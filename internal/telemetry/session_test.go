@@ -0,0 +1,98 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/telemetry"
+)
+
+var _ = Describe("session", func() {
+	var (
+		ctx       context.Context
+		configDir string
+		server    *httptest.Server
+		mu        sync.Mutex
+		requests  [][]json.RawMessage
+	)
+
+	spoolPath := func() string {
+		return filepath.Join(configDir, "telemetry-spool.jsonl")
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		configDir = GinkgoT().TempDir()
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []json.RawMessage
+			Expect(json.Unmarshal(body, &events)).To(Succeed())
+
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, events)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	finishCommand := func(opts telemetry.SessionOptions) {
+		invocation := telemetry.NewInvocation()
+		invocation.SetCommand("release install")
+		invocation.SetResourceCount(3)
+
+		telemetry.StartSession(ctx, opts).Finish(invocation, nil)
+	}
+
+	DescribeTable("sends and spools nothing when disabled",
+		func(consent *telemetry.Consent, mode string) {
+			if consent != nil {
+				consent.Endpoint = server.URL
+				Expect(telemetry.SaveConsent(configDir, consent)).To(Succeed())
+			}
+
+			Expect(telemetry.StartSession(ctx, telemetry.SessionOptions{ConfigDir: configDir, Mode: mode})).To(BeNil())
+
+			finishCommand(telemetry.SessionOptions{ConfigDir: configDir, Mode: mode})
+
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(requests).To(BeEmpty())
+			Expect(spoolPath()).NotTo(BeAnExistingFile())
+		},
+		Entry("without consent", nil, telemetry.ModeConsent),
+		Entry("with consent withdrawn", &telemetry.Consent{Enabled: false}, telemetry.ModeConsent),
+		Entry("with consent given, but turned off for the command", &telemetry.Consent{Enabled: true}, telemetry.ModeOff),
+	)
+
+	It("sends events spooled by previous commands and spools the event of the current one", func() {
+		Expect(telemetry.SaveConsent(configDir, &telemetry.Consent{Enabled: true, Endpoint: server.URL})).To(Succeed())
+		Expect(os.WriteFile(spoolPath(), []byte(`{"command":"release list"}`+"\n"), 0o644)).To(Succeed())
+
+		finishCommand(telemetry.SessionOptions{ConfigDir: configDir})
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0]).To(HaveLen(1))
+		Expect(requests[0][0]).To(MatchJSON(`{"command":"release list"}`))
+
+		Expect(telemetry.SpooledEventsCount(configDir)).To(Equal(1))
+
+		spool, err := os.ReadFile(spoolPath())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(spool)).To(ContainSubstring(`"command":"release install"`))
+	})
+})
@@ -0,0 +1,165 @@
+package track
+
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
+	kdutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+func NewLogFollower(ctx context.Context, staticClient kubernetes.Interface, logStore *kdutil.Concurrent[*logstore.LogStore]) *LogFollower {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &LogFollower{
+		ctx:          ctx,
+		cancel:       cancel,
+		staticClient: staticClient,
+		logStore:     logStore,
+		followed:     make(map[string]bool),
+	}
+}
+
+// LogFollower keeps saving container logs of pods into the log store until stopped. The readiness
+// tracker stops saving logs as soon as the resource is ready, so this is used to show logs until
+// the end of the deploy.
+type LogFollower struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	staticClient kubernetes.Interface
+	logStore     *kdutil.Concurrent[*logstore.LogStore]
+
+	mu       sync.Mutex
+	followed map[string]bool
+	wg       sync.WaitGroup
+}
+
+type LogFollowerOptions struct {
+	OnlyForContainers    []string
+	IgnoreForContainers  []string
+	ByRegex              *regexp.Regexp
+	ByRegexForContainers map[string]*regexp.Regexp
+}
+
+// Follow starts saving logs of the pod containers written from now on. Pods which are already
+// followed are skipped.
+func (f *LogFollower) Follow(podName, namespace string, opts LogFollowerOptions) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := podKey(podName, namespace)
+	if f.followed[key] || f.ctx.Err() != nil {
+		return
+	}
+	f.followed[key] = true
+
+	since := metav1.Now()
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		pod, err := f.staticClient.CoreV1().Pods(namespace).Get(f.ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			log.Track.Debug(f.ctx, "Not following logs of pod %q (namespace: %q): get pod: %s", podName, namespace, err)
+			return
+		}
+
+		for _, container := range pod.Spec.Containers {
+			if len(opts.OnlyForContainers) > 0 && !lo.Contains(opts.OnlyForContainers, container.Name) {
+				continue
+			}
+
+			if lo.Contains(opts.IgnoreForContainers, container.Name) {
+				continue
+			}
+
+			regex := opts.ByRegex
+			if r, found := opts.ByRegexForContainers[container.Name]; found {
+				regex = r
+			}
+
+			f.wg.Add(1)
+			go func() {
+				defer f.wg.Done()
+				f.followContainer(podName, namespace, container.Name, since, regex)
+			}()
+		}
+	}()
+}
+
+// Stop stops following logs and waits until all lines received so far are saved.
+func (f *LogFollower) Stop() {
+	f.cancel()
+	f.wg.Wait()
+}
+
+func (f *LogFollower) followContainer(podName, namespace, container string, since metav1.Time, regex *regexp.Regexp) {
+	stream, err := f.staticClient.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		SinceTime:  &since,
+		Timestamps: true,
+	}).Stream(f.ctx)
+	if err != nil {
+		log.Track.Debug(f.ctx, "Not following logs of container %q of pod %q (namespace: %q): %s", container, podName, namespace, err)
+		return
+	}
+	defer stream.Close()
+
+	resourceLogs := f.resourceLogs(podName, namespace)
+	source := "container/" + container
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		timestamp, line, _ := strings.Cut(scanner.Text(), " ")
+
+		if regex != nil && !regex.MatchString(line) {
+			continue
+		}
+
+		lineTime, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			lineTime = time.Now()
+		}
+
+		resourceLogs.RWTransaction(func(rl *logstore.ResourceLogs) {
+			rl.AddLogLine(line, source, lineTime)
+		})
+	}
+}
+
+// Lines must be saved to the same resource logs the readiness tracker saved them to, since printed
+// lines are counted per resource logs.
+func (f *LogFollower) resourceLogs(podName, namespace string) *kdutil.Concurrent[*logstore.ResourceLogs] {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	var result *kdutil.Concurrent[*logstore.ResourceLogs]
+	f.logStore.RWTransaction(func(ls *logstore.LogStore) {
+		for _, crl := range ls.ResourcesLogs() {
+			crl.RTransaction(func(rl *logstore.ResourceLogs) {
+				if rl.Name() == podName && rl.Namespace() == namespace && rl.GroupVersionKind() == podGVK {
+					result = crl
+				}
+			})
+		}
+
+		if result == nil {
+			result = kdutil.NewConcurrent(logstore.NewResourceLogs(podName, namespace, podGVK))
+			ls.AddResourceLogs(result)
+		}
+	})
+
+	return result
+}
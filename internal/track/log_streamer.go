@@ -0,0 +1,196 @@
+package track
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	kdutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+type ShowLogsUntil string
+
+const (
+	// Stop showing logs of the pods of the resource once the resource is ready.
+	ShowLogsUntilReady ShowLogsUntil = "ready"
+	// Show logs of the pods until the end of the deploy.
+	ShowLogsUntilEnd ShowLogsUntil = "end"
+)
+
+func NewLogStreamer(taskStore *statestore.TaskStore, logStore *kdutil.Concurrent[*logstore.LogStore], opts LogStreamerOptions) *LogStreamer {
+	defaultNamespace := lo.WithoutEmpty([]string{opts.DefaultNamespace, v1.NamespaceDefault})[0]
+
+	until := opts.Until
+	if until == "" {
+		until = ShowLogsUntilReady
+	}
+
+	return &LogStreamer{
+		taskStore:        taskStore,
+		logStore:         logStore,
+		defaultNamespace: defaultNamespace,
+		colorize:         opts.Colorize,
+		tail:             opts.Tail,
+		until:            until,
+		nextLogPointers:  make(map[string]int),
+		finishedSources:  make(map[string]bool),
+	}
+}
+
+type LogStreamerOptions struct {
+	DefaultNamespace string
+	Colorize         bool
+	// At most this many last lines of each container are printed at a time, older lines are
+	// skipped. No limit if 0.
+	Tail int
+	// Defaults to ShowLogsUntilReady.
+	Until ShowLogsUntil
+}
+
+// LogStreamer prints new lines of container logs from the log store, each line prefixed with the
+// tracked resource, the pod and the container.
+type LogStreamer struct {
+	taskStore *statestore.TaskStore
+	logStore  *kdutil.Concurrent[*logstore.LogStore]
+
+	defaultNamespace string
+	colorize         bool
+	tail             int
+	until            ShowLogsUntil
+
+	nextLogPointers map[string]int
+	finishedSources map[string]bool
+}
+
+// Stream prints log lines saved since the previous call. Not safe for concurrent use.
+func (s *LogStreamer) Stream(ctx context.Context) {
+	owners := s.podOwners()
+
+	type pendingLines struct {
+		prefix  string
+		skipped int
+		lines   []string
+	}
+
+	var pending []*pendingLines
+	s.logStore.RTransaction(func(ls *logstore.LogStore) {
+		for _, crl := range ls.ResourcesLogs() {
+			crl.RTransaction(func(rl *logstore.ResourceLogs) {
+				owner := owners[podKey(rl.Name(), rl.Namespace())]
+
+				for source, logLines := range rl.LogLines() {
+					key := fmt.Sprintf("%s:%s:%s", rl.Namespace(), rl.Name(), source)
+					if s.finishedSources[key] {
+						continue
+					}
+
+					nextLogPointer := s.nextLogPointers[key]
+					s.nextLogPointers[key] = len(logLines)
+
+					if owner != nil && owner.ready && s.until == ShowLogsUntilReady {
+						s.finishedSources[key] = true
+					}
+
+					if nextLogPointer >= len(logLines) {
+						continue
+					}
+
+					newLines := logLines[nextLogPointer:]
+
+					p := &pendingLines{
+						prefix: s.buildPrefix(owner, rl, source),
+					}
+
+					if s.tail > 0 && len(newLines) > s.tail {
+						p.skipped = len(newLines) - s.tail
+						newLines = newLines[p.skipped:]
+					}
+
+					for _, line := range newLines {
+						p.lines = append(p.lines, line.Line)
+					}
+
+					pending = append(pending, p)
+				}
+			})
+		}
+	})
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].prefix < pending[j].prefix
+	})
+
+	for _, p := range pending {
+		if p.skipped > 0 {
+			log.Default.Info(ctx, "%s ... %d lines skipped", p.prefix, p.skipped)
+		}
+
+		for _, line := range p.lines {
+			log.Default.Info(ctx, "%s %s", p.prefix, line)
+		}
+	}
+}
+
+type podOwner struct {
+	kind  string
+	name  string
+	ready bool
+}
+
+// Pods are saved in the log store by themselves, so find which tracked resources they belong to.
+func (s *LogStreamer) podOwners() map[string]*podOwner {
+	owners := map[string]*podOwner{}
+
+	for _, crts := range s.taskStore.ReadinessTasksStates() {
+		crts.RTransaction(func(rts *statestore.ReadinessTaskState) {
+			owner := &podOwner{
+				kind:  rts.GroupVersionKind().Kind,
+				name:  rts.Name(),
+				ready: rts.Status() == statestore.ReadinessTaskStatusReady,
+			}
+
+			for _, crs := range rts.ResourceStates() {
+				crs.RTransaction(func(rs *statestore.ResourceState) {
+					if rs.GroupVersionKind().GroupKind() == (schema.GroupKind{Kind: "Pod"}) {
+						owners[podKey(rs.Name(), rs.Namespace())] = owner
+					}
+				})
+			}
+		})
+	}
+
+	return owners
+}
+
+func (s *LogStreamer) buildPrefix(owner *podOwner, resourceLogs *logstore.ResourceLogs, source string) string {
+	var parts []string
+	if owner != nil && owner.kind != "Pod" {
+		parts = append(parts, owner.kind+"/"+owner.name)
+	}
+
+	parts = append(parts, "pod/"+resourceLogs.Name(), source)
+
+	if resourceLogs.Namespace() != s.defaultNamespace {
+		parts = append(parts, "namespace/"+resourceLogs.Namespace())
+	}
+
+	prefix := "[" + strings.Join(parts, " ") + "]"
+	if s.colorize {
+		prefix = color.New(color.Blue).Sprintf(prefix)
+	}
+
+	return prefix
+}
+
+func podKey(name, namespace string) string {
+	return namespace + "/" + name
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/chanced/caps"
 	"github.com/gookit/color"
@@ -13,40 +14,39 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
-	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
 	kdutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
 )
 
 type TablesBuilder struct {
 	taskStore *statestore.TaskStore
-	logStore  *kdutil.Concurrent[*logstore.LogStore]
 
 	defaultNamespace      string
 	maxProgressTableWidth int
 	maxLogEventTableWidth int
 	colorize              bool
 
-	nextLogPointers    map[string]int
 	nextEventPointers  map[string]int
 	hideReadinessTasks map[string]bool
 	hidePresenceTasks  map[string]bool
 	hideAbsenceTasks   map[string]bool
+	taskStartTimes     map[string]time.Time
+	taskEndTimes       map[string]time.Time
 }
 
-func NewTablesBuilder(taskStore *statestore.TaskStore, logStore *kdutil.Concurrent[*logstore.LogStore], opts TablesBuilderOptions) *TablesBuilder {
+func NewTablesBuilder(taskStore *statestore.TaskStore, opts TablesBuilderOptions) *TablesBuilder {
 	defaultNamespace := lo.WithoutEmpty([]string{opts.DefaultNamespace, v1.NamespaceDefault})[0]
 
 	builder := &TablesBuilder{
 		taskStore:          taskStore,
-		logStore:           logStore,
 		defaultNamespace:   defaultNamespace,
 		colorize:           opts.Colorize,
-		nextLogPointers:    make(map[string]int),
 		nextEventPointers:  make(map[string]int),
 		hideReadinessTasks: make(map[string]bool),
 		hidePresenceTasks:  make(map[string]bool),
 		hideAbsenceTasks:   make(map[string]bool),
+		taskStartTimes:     make(map[string]time.Time),
+		taskEndTimes:       make(map[string]time.Time),
 	}
 
 	builder.SetMaxTableWidth(opts.MaxTableWidth)
@@ -93,50 +93,6 @@ func (b *TablesBuilder) BuildProgressTable() (table prtable.Writer, notEmpty boo
 	return table, true
 }
 
-func (b *TablesBuilder) BuildLogTables() (tables map[string]prtable.Writer, nonEmpty bool) {
-	tables = make(map[string]prtable.Writer)
-
-	b.logStore.RTransaction(func(ls *logstore.LogStore) {
-		for _, crl := range ls.ResourcesLogs() {
-			crl.RTransaction(func(rl *logstore.ResourceLogs) {
-				for source, logLines := range rl.LogLines() {
-					table := prtable.NewWriter()
-					setLogTableStyle(table, b.maxLogEventTableWidth)
-
-					header := buildLogsHeader(rl, source, b.defaultNamespace, b.colorize)
-
-					nextLogPointer, found := b.nextLogPointers[header]
-					if !found {
-						nextLogPointer = 0
-					}
-
-					for i, logLine := range logLines {
-						if i < nextLogPointer {
-							continue
-						}
-
-						table.AppendRow(prtable.Row{logLine.Line})
-
-						nextLogPointer++
-					}
-
-					b.nextLogPointers[header] = nextLogPointer
-
-					if table.Length() != 0 {
-						tables[header] = table
-					}
-				}
-			})
-		}
-	})
-
-	if len(tables) == 0 {
-		return nil, false
-	}
-
-	return tables, true
-}
-
 func (b *TablesBuilder) BuildEventTables() (tables map[string]prtable.Writer, nonEmpty bool) {
 	tables = make(map[string]prtable.Writer)
 
@@ -478,33 +434,6 @@ func setProgressTableStyle(table prtable.Writer, tableWidth int) {
 	})
 }
 
-func setLogTableStyle(table prtable.Writer, tableWidth int) {
-	style := prtable.StyleBoxDefault
-	style.PaddingLeft = ""
-	style.PaddingRight = ""
-
-	columnConfigs := []prtable.ColumnConfig{
-		{
-			Number: 1,
-		},
-	}
-
-	paddingsWidth := len(columnConfigs) * (len(style.PaddingLeft) + len(style.PaddingRight))
-	columnsWidth := tableWidth - paddingsWidth
-
-	columnConfigs[0].WidthMax = columnsWidth
-
-	table.SetColumnConfigs(columnConfigs)
-	table.SetStyle(prtable.Style{
-		Box:     style,
-		Color:   prtable.ColorOptionsDefault,
-		Format:  prtable.FormatOptions{},
-		HTML:    prtable.DefaultHTMLOptions,
-		Options: prtable.OptionsNoBordersAndSeparators,
-		Title:   prtable.TitleOptionsDefault,
-	})
-}
-
 func setEventTableStyle(table prtable.Writer, tableWidth int) {
 	style := prtable.StyleBoxDefault
 	style.PaddingLeft = ""
@@ -532,20 +461,6 @@ func setEventTableStyle(table prtable.Writer, tableWidth int) {
 	})
 }
 
-func buildLogsHeader(resourceLogs *logstore.ResourceLogs, source, defaultNamespace string, colorize bool) string {
-	result := "Logs for " + resourceLogs.GroupVersionKind().Kind + "/" + resourceLogs.Name() + ", " + source
-
-	if resourceLogs.Namespace() != defaultNamespace {
-		result += ", namespace: " + resourceLogs.Namespace()
-	}
-
-	if colorize {
-		result = color.New(color.Bold, color.Blue).Sprintf(result)
-	}
-
-	return result
-}
-
 func buildEventsHeader(resourceState *statestore.ResourceState, defaultNamespace string, colorize bool) string {
 	result := "Events for " + resourceState.GroupVersionKind().Kind + "/" + resourceState.Name()
 
@@ -0,0 +1,227 @@
+package track
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+)
+
+// BuildStatusTable builds the table with a row for every tracked resource, which is meant to be
+// redrawn in place. Unlike the progress table, resources are not hidden when done.
+func (b *TablesBuilder) BuildStatusTable(now time.Time) (table prtable.Writer, notEmpty bool) {
+	table = prtable.NewWriter()
+	setStatusTableStyle(table, b.maxProgressTableWidth)
+
+	var rows []prtable.Row
+
+	crtss := b.taskStore.ReadinessTasksStates()
+	sortReadinessTaskStates(crtss)
+
+	for _, crts := range crtss {
+		crts.RTransaction(func(rts *statestore.ReadinessTaskState) {
+			done := rts.Status() != statestore.ReadinessTaskStatusProgressing
+			readyPods := calculateReadyPods(rts)
+
+			var (
+				rootState      *statestore.ResourceState
+				resourceStates []*statestore.ResourceState
+			)
+			for _, crs := range rts.ResourceStates() {
+				crs.RTransaction(func(rs *statestore.ResourceState) {
+					resourceStates = append(resourceStates, rs)
+
+					if rts.Name() == rs.Name() && rts.Namespace() == rs.Namespace() && rts.GroupVersionKind() == rs.GroupVersionKind() {
+						rootState = rs
+					}
+				})
+			}
+
+			readyCell := "-"
+			if rootState != nil && readyPods != nil {
+				if requiredPods, found := requiredReplicas(rootState); found {
+					readyCell = fmt.Sprintf("%d/%d", *readyPods, requiredPods)
+				}
+			}
+
+			rows = append(rows, prtable.Row{
+				b.buildStatusResourceCell(rts.Name(), rts.Namespace()),
+				rts.GroupVersionKind().Kind,
+				buildReadinessRootResourceStateCell(rts, b.colorize),
+				readyCell,
+				buildLastEventCell(resourceStates, b.colorize),
+				b.buildElapsedCell(rts.UUID(), done, now),
+			})
+		})
+	}
+
+	cptss := b.taskStore.PresenceTasksStates()
+	sortPresenceTaskStates(cptss)
+
+	for _, cpts := range cptss {
+		cpts.RTransaction(func(pts *statestore.PresenceTaskState) {
+			pts.ResourceState().RTransaction(func(rs *statestore.ResourceState) {
+				rows = append(rows, prtable.Row{
+					b.buildStatusResourceCell(rs.Name(), rs.Namespace()),
+					rs.GroupVersionKind().Kind,
+					buildPresenceRootResourceStateCell(pts, b.colorize),
+					"-",
+					buildLastEventCell([]*statestore.ResourceState{rs}, b.colorize),
+					b.buildElapsedCell(pts.UUID(), pts.Status() != statestore.PresenceTaskStatusProgressing, now),
+				})
+			})
+		})
+	}
+
+	catss := b.taskStore.AbsenceTasksStates()
+	sortAbsenceTaskStates(catss)
+
+	for _, cats := range catss {
+		cats.RTransaction(func(ats *statestore.AbsenceTaskState) {
+			ats.ResourceState().RTransaction(func(rs *statestore.ResourceState) {
+				rows = append(rows, prtable.Row{
+					b.buildStatusResourceCell(rs.Name(), rs.Namespace()),
+					rs.GroupVersionKind().Kind,
+					buildAbsenceRootResourceStateCell(ats, b.colorize),
+					"-",
+					buildLastEventCell([]*statestore.ResourceState{rs}, b.colorize),
+					b.buildElapsedCell(ats.UUID(), ats.Status() != statestore.AbsenceTaskStatusProgressing, now),
+				})
+			})
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, false
+	}
+
+	table.AppendRow(buildStatusHeaderRow(b.colorize))
+	table.AppendRows(rows)
+
+	return table, true
+}
+
+func (b *TablesBuilder) buildStatusResourceCell(name, namespace string) string {
+	if namespace != "" && namespace != b.defaultNamespace {
+		return namespace + "/" + name
+	}
+
+	return name
+}
+
+// Elapsed time is counted from the first time the task is seen and stops when the task is done.
+func (b *TablesBuilder) buildElapsedCell(taskUUID string, done bool, now time.Time) string {
+	startedAt, found := b.taskStartTimes[taskUUID]
+	if !found {
+		startedAt = now
+		b.taskStartTimes[taskUUID] = startedAt
+	}
+
+	endedAt, found := b.taskEndTimes[taskUUID]
+	if !found {
+		endedAt = now
+
+		if done {
+			b.taskEndTimes[taskUUID] = endedAt
+		}
+	}
+
+	return endedAt.Sub(startedAt).Round(time.Second).String()
+}
+
+func buildStatusHeaderRow(colorize bool) prtable.Row {
+	var row prtable.Row
+	for _, column := range []string{"RESOURCE", "KIND", "STATE", "READY", "LAST EVENT", "ELAPSED"} {
+		if colorize {
+			column = color.New(color.Bold).Sprintf(column)
+		}
+
+		row = append(row, column)
+	}
+
+	return row
+}
+
+// The last event or error of any of the resources.
+func buildLastEventCell(resourceStates []*statestore.ResourceState, colorize bool) string {
+	var (
+		lastMsg  string
+		lastTime time.Time
+		isErr    bool
+	)
+
+	for _, rs := range resourceStates {
+		for _, event := range rs.Events() {
+			if !event.Time.Before(lastTime) {
+				lastMsg, lastTime, isErr = event.Message, event.Time, false
+			}
+		}
+
+		for _, errs := range rs.Errors() {
+			for _, err := range errs {
+				if !err.Time.Before(lastTime) {
+					lastMsg, lastTime, isErr = err.Err.Error(), err.Time, true
+				}
+			}
+		}
+	}
+
+	lastMsg = strings.Join(strings.Fields(lastMsg), " ")
+
+	if isErr && colorize {
+		lastMsg = color.New(color.Red).Sprintf(lastMsg)
+	}
+
+	return lastMsg
+}
+
+func requiredReplicas(resourceState *statestore.ResourceState) (int, bool) {
+	for _, attr := range resourceState.Attributes() {
+		if attr.Name() == statestore.AttributeNameRequiredReplicas {
+			return attr.(*statestore.Attribute[int]).Value, true
+		}
+	}
+
+	return 0, false
+}
+
+// Cells are never wrapped, so that the height of the table is known.
+func setStatusTableStyle(table prtable.Writer, tableWidth int) {
+	style := prtable.StyleBoxDefault
+	style.PaddingLeft = ""
+	style.PaddingRight = "  "
+
+	columnConfigs := []prtable.ColumnConfig{
+		{Number: 1},
+		{Number: 2, WidthMax: 24},
+		{Number: 3, WidthMax: 7},
+		{Number: 4, WidthMax: 7},
+		{Number: 5},
+		{Number: 6, WidthMax: 8},
+	}
+
+	paddingsWidth := len(columnConfigs) * (len(style.PaddingLeft) + len(style.PaddingRight))
+	restWidth := tableWidth - paddingsWidth - columnConfigs[1].WidthMax - columnConfigs[2].WidthMax - columnConfigs[3].WidthMax - columnConfigs[5].WidthMax
+
+	columnConfigs[0].WidthMax = restWidth * 4 / 10
+	columnConfigs[4].WidthMax = restWidth * 6 / 10
+
+	for i := range columnConfigs {
+		columnConfigs[i].WidthMaxEnforcer = text.Trim
+	}
+
+	table.SetColumnConfigs(columnConfigs)
+	table.SetStyle(prtable.Style{
+		Box:     style,
+		Color:   prtable.ColorOptionsDefault,
+		Format:  prtable.FormatOptions{},
+		HTML:    prtable.DefaultHTMLOptions,
+		Options: prtable.OptionsNoBordersAndSeparators,
+		Title:   prtable.TitleOptionsDefault,
+	})
+}
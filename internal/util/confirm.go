@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Confirm writes the prompt to out and reads a line from in. "y" and "yes" confirm, "n" and "no"
+// decline, in any case. An empty answer, as well as the end of input without an answer, means
+// defaultYes. Any other answer declines.
+func Confirm(in io.Reader, out io.Writer, prompt string, defaultYes bool) (bool, error) {
+	choices := "[y/N]"
+	if defaultYes {
+		choices = "[Y/n]"
+	}
+
+	if _, err := fmt.Fprintf(out, "%s %s: ", prompt, choices); err != nil {
+		return false, fmt.Errorf("error writing prompt: %w", err)
+	}
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("error reading answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
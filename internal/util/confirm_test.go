@@ -0,0 +1,61 @@
+package util_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/util"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("stdin closed")
+}
+
+var _ = Describe("confirmation", func() {
+	DescribeTable("answers",
+		func(input string, defaultYes, expectConfirmed bool) {
+			out := &bytes.Buffer{}
+
+			confirmed, err := util.Confirm(strings.NewReader(input), out, "Continue?", defaultYes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(confirmed).To(Equal(expectConfirmed))
+		},
+		Entry("y", "y\n", false, true),
+		Entry("yes", "yes\n", false, true),
+		Entry("uppercase Y", "Y\n", false, true),
+		Entry("padded yes", "  Yes  \n", false, true),
+		Entry("n with yes by default", "n\n", true, false),
+		Entry("no with yes by default", "no\n", true, false),
+		Entry("other answer with yes by default", "sure\n", true, false),
+		Entry("empty with no by default", "\n", false, false),
+		Entry("empty with yes by default", "\n", true, true),
+		Entry("end of input with no by default", "", false, false),
+		Entry("end of input with yes by default", "", true, true),
+		Entry("answer without newline", "y", false, true),
+		Entry("only the first line", "n\ny\n", false, false),
+	)
+
+	It("shows the default choice in the prompt", func() {
+		out := &bytes.Buffer{}
+		_, err := util.Confirm(strings.NewReader("\n"), out, "Save changes?", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(Equal("Save changes? [Y/n]: "))
+
+		out.Reset()
+		_, err = util.Confirm(strings.NewReader("\n"), out, "Continue?", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(Equal("Continue? [y/N]: "))
+	})
+
+	It("returns read errors", func() {
+		confirmed, err := util.Confirm(failingReader{}, &bytes.Buffer{}, "Continue?", true)
+		Expect(err).To(MatchError(ContainSubstring("stdin closed")))
+		Expect(confirmed).To(BeFalse())
+	})
+})
@@ -14,13 +14,17 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 )
 
-func ColoredUnifiedDiff(from, to string) (uDiff string, present bool) {
+func ColoredUnifiedDiff(from, to string, contextLines int) (uDiff string, present bool) {
+	if contextLines < 0 {
+		contextLines = udiff.DefaultContextLines
+	}
+
 	edits := myers.ComputeEdits(from, to)
 	if len(edits) == 0 {
 		return "", false
 	}
 
-	uncoloredUDiff := lo.Must1(udiff.ToUnified("", "", from, edits, udiff.DefaultContextLines))
+	uncoloredUDiff := lo.Must1(udiff.ToUnified("", "", from, edits, contextLines))
 
 	var uDiffLines []string
 	var firstHunkHeaderStripped bool
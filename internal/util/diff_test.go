@@ -0,0 +1,35 @@
+package util_test
+
+import (
+	"strings"
+
+	"github.com/gookit/color"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/util"
+)
+
+var _ = Describe("colored unified diff", func() {
+	from := "a\nb\nc\nd\ne\nf\ng\n"
+	to := "a\nb\nc\nD\ne\nf\ng\n"
+
+	diffLines := func(contextLines int) []string {
+		uDiff, present := util.ColoredUnifiedDiff(from, to, contextLines)
+		Expect(present).To(BeTrue())
+
+		return strings.Split(strings.TrimSuffix(color.ClearCode(uDiff), "\n"), "\n")
+	}
+
+	It("shows changed lines only with 0 context lines", func() {
+		Expect(diffLines(0)).To(Equal([]string{"- d", "+ D"}))
+	})
+
+	It("shows the requested number of context lines", func() {
+		Expect(diffLines(1)).To(Equal([]string{"  c", "- d", "+ D", "  e"}))
+	})
+
+	It("shows the default number of context lines if negative", func() {
+		Expect(diffLines(-1)).To(Equal([]string{"  a", "  b", "  c", "- d", "+ D", "  e", "  f", "  g"}))
+	})
+})
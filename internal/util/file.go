@@ -0,0 +1,132 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// WriteFileAtomic writes data to a temporary file in the same directory and then renames it to
+// path, so that an interrupted write never leaves a truncated file at path. Permissions of an
+// existing file at path are preserved.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomicFunc(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFileAtomicFunc is like WriteFileAtomic, but the content is written by writeFn. If writeFn
+// fails, path is left untouched.
+func WriteFileAtomicFunc(path string, perm os.FileMode, writeFn func(w io.Writer) error) error {
+	tmpPath, err := stageFile(path, perm, writeFn)
+	if err != nil {
+		return err
+	}
+
+	if err := renameReplacing(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error renaming temporary file to %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// WriteFilesAtomic is like WriteFileAtomic, but for multiple files. All files are written to
+// temporary files first, and only then renamed, so that if writing any of them fails, none of the
+// files is changed.
+func WriteFilesAtomic(files map[string][]byte, perm os.FileMode) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	tmpPaths := map[string]string{}
+	defer func() {
+		for _, tmpPath := range tmpPaths {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	for _, path := range paths {
+		tmpPath, err := stageFile(path, perm, func(w io.Writer) error {
+			_, err := w.Write(files[path])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		tmpPaths[path] = tmpPath
+	}
+
+	for _, path := range paths {
+		if err := renameReplacing(tmpPaths[path], path); err != nil {
+			return fmt.Errorf("error renaming temporary file to %q: %w", path, err)
+		}
+
+		delete(tmpPaths, path)
+	}
+
+	return nil
+}
+
+// Writes the content to a temporary file next to path, with permissions of the existing file at
+// path if any, and returns the path of the temporary file.
+func stageFile(path string, perm os.FileMode, writeFn func(w io.Writer) error) (string, error) {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file for %q: %w", path, err)
+	}
+	tmpPath := tmpFile.Name()
+
+	staged := false
+	defer func() {
+		if !staged {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := writeFn(tmpFile); err != nil {
+		return "", fmt.Errorf("error writing temporary file for %q: %w", path, err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		return "", fmt.Errorf("error syncing temporary file for %q: %w", path, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("error closing temporary file for %q: %w", path, err)
+	}
+
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return "", fmt.Errorf("error setting permissions of temporary file for %q: %w", path, err)
+	}
+	staged = true
+
+	return tmpPath, nil
+}
+
+// On Windows renaming over an existing file fails if the file is opened by someone else, so retry
+// after removing it. This loses atomicity, but only on Windows and only in this rare case.
+func renameReplacing(oldPath, newPath string) error {
+	err := os.Rename(oldPath, newPath)
+	if err == nil || runtime.GOOS != "windows" {
+		return err
+	}
+
+	if removeErr := os.Remove(newPath); removeErr != nil && !os.IsNotExist(removeErr) {
+		return err
+	}
+
+	return os.Rename(oldPath, newPath)
+}
@@ -0,0 +1,104 @@
+package util_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/util"
+)
+
+var _ = Describe("atomic file writes", func() {
+	var (
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		path = filepath.Join(dir, "graph.dot")
+	})
+
+	dirEntries := func() []string {
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		return names
+	}
+
+	It("leaves the existing file untouched if writing fails midway", func() {
+		Expect(os.WriteFile(path, []byte("old content"), 0o600)).To(Succeed())
+
+		err := util.WriteFileAtomicFunc(path, 0o644, func(w io.Writer) error {
+			if _, err := w.Write([]byte("partial")); err != nil {
+				return err
+			}
+
+			return errors.New("disk full")
+		})
+		Expect(err).To(MatchError(ContainSubstring("disk full")))
+
+		Expect(os.ReadFile(path)).To(Equal([]byte("old content")))
+		Expect(dirEntries()).To(ConsistOf("graph.dot"))
+	})
+
+	It("doesn't create the file if writing fails midway", func() {
+		err := util.WriteFileAtomicFunc(path, 0o644, func(w io.Writer) error {
+			return errors.New("disk full")
+		})
+		Expect(err).To(HaveOccurred())
+
+		Expect(dirEntries()).To(BeEmpty())
+	})
+
+	It("replaces the file and keeps its permissions", func() {
+		Expect(os.WriteFile(path, []byte("old content"), 0o600)).To(Succeed())
+
+		Expect(util.WriteFileAtomic(path, []byte("new content"), 0o644)).To(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte("new content")))
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+		Expect(dirEntries()).To(ConsistOf("graph.dot"))
+	})
+
+	It("fails if the directory doesn't exist", func() {
+		Expect(util.WriteFileAtomic(filepath.Join(dir, "missing", "graph.dot"), []byte("content"), 0o644)).NotTo(Succeed())
+	})
+
+	It("replaces multiple files", func() {
+		Expect(os.WriteFile(path, []byte("old content"), 0o600)).To(Succeed())
+		otherPath := filepath.Join(dir, "other.dot")
+
+		Expect(util.WriteFilesAtomic(map[string][]byte{
+			path:      []byte("new content"),
+			otherPath: []byte("other content"),
+		}, 0o644)).To(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte("new content")))
+		Expect(os.ReadFile(otherPath)).To(Equal([]byte("other content")))
+		Expect(dirEntries()).To(ConsistOf("graph.dot", "other.dot"))
+	})
+
+	It("changes none of multiple files if writing any of them fails", func() {
+		Expect(os.WriteFile(path, []byte("old content"), 0o600)).To(Succeed())
+
+		Expect(util.WriteFilesAtomic(map[string][]byte{
+			path:                                   []byte("new content"),
+			filepath.Join(dir, "missing", "z.dot"): []byte("content"),
+		}, 0o644)).NotTo(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte("old content")))
+		Expect(dirEntries()).To(ConsistOf("graph.dot"))
+	})
+})
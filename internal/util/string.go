@@ -9,3 +9,31 @@ func Capitalize(s string) string {
 
 	return strings.ToUpper(s[:1]) + s[1:]
 }
+
+// EditDistance is the Levenshtein distance between the strings: the number of single character
+// insertions, deletions and substitutions needed to turn one into the other.
+func EditDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(br)]
+}
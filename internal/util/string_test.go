@@ -0,0 +1,35 @@
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/util"
+)
+
+var _ = Describe("strings", func() {
+	DescribeTable("computes the edit distance in both directions",
+		func(a, b string, expected int) {
+			Expect(util.EditDistance(a, b)).To(Equal(expected))
+			Expect(util.EditDistance(b, a)).To(Equal(expected))
+		},
+		Entry("equal strings", "werf.io/weight", "werf.io/weight", 0),
+		Entry("both empty", "", "", 0),
+		Entry("one empty", "", "weight", 6),
+		Entry("one substitution", "werf.io/weigth", "werf.io/weight", 2),
+		Entry("one insertion", "werf.io/wight", "werf.io/weight", 1),
+		Entry("one deletion", "werf.io/weightt", "werf.io/weight", 1),
+		Entry("different case", "Weight", "weight", 1),
+		Entry("completely different", "abc", "xyz", 3),
+		Entry("multibyte characters counted once", "вес", "вёс", 1),
+	)
+
+	DescribeTable("capitalizes the first letter",
+		func(s, expected string) {
+			Expect(util.Capitalize(s)).To(Equal(expected))
+		},
+		Entry("empty", "", ""),
+		Entry("lowercase", "deploy", "Deploy"),
+		Entry("already capitalized", "Deploy", "Deploy"),
+	)
+})
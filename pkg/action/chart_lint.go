@@ -2,39 +2,40 @@ package action
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/goccy/go-yaml"
+	"github.com/gookit/color"
 	"github.com/samber/lo"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
-	"github.com/werf/3p-helm/pkg/chart/loader"
 	"github.com/werf/3p-helm/pkg/chartutil"
 	"github.com/werf/3p-helm/pkg/downloader"
 	"github.com/werf/3p-helm/pkg/getter"
-	"github.com/werf/3p-helm/pkg/registry"
 	"github.com/werf/3p-helm/pkg/storage"
 	"github.com/werf/3p-helm/pkg/storage/driver"
-	"github.com/werf/3p-helm/pkg/werf/chartextender"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
-	"github.com/werf/common-go/pkg/secrets_manager"
 	"github.com/werf/logboek"
 	"github.com/werf/nelm/internal/chart"
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
-	DefaultChartLintLogLevel = InfoLogLevel
+	DefaultChartLintLogLevel     = InfoLogLevel
+	DefaultChartLintOutputFormat = TextOutputFormat
 )
 
 type ChartLintOptions struct {
@@ -57,51 +58,59 @@ type ChartLintOptions struct {
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
+	KubeDiscoveryCacheDir        string
+	KubeImpersonateGroups        []string
+	KubeImpersonateUser          string
 	KubeQPSLimit                 int
+	KubeRefreshDiscovery         bool
 	KubeSkipTLSVerify            bool
 	KubeTLSServerName            string
 	KubeToken                    string
+	KubeTokenPath                string
 	Remote                       bool
 	LocalKubeVersion             string
 	LogColorMode                 string
 	LogRegistryStreamOut         io.Writer
 	NetworkParallelism           int
+	OutputFormat                 string
+	OutputNoPrint                bool
 	RegistryCredentialsPath      string
 	ReleaseName                  string
 	ReleaseNamespace             string
 	ReleaseStorageDriver         string
+	ResourceSizeLimit            int
+	SecretAgeIdentityPath        string
 	SecretKey                    string
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	SecretWorkDir                string
-	TempDirPath                  string
-	ValuesFileSets               []string
-	ValuesFilesPaths             []string
-	ValuesSets                   []string
-	ValuesStringSets             []string
+	// Fail on warnings too, not only on errors.
+	Strict           bool
+	TempDirPath      string
+	ValuesEnv        map[string]string
+	ValuesEnvPrefix  string
+	ValuesFileSets   []string
+	ValuesFilesPaths []string
+	ValuesSets       []string
+	ValuesStringSets []string
 }
 
-func ChartLint(ctx context.Context, opts ChartLintOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
+// ChartLint renders the chart and checks the result. Found issues are returned even if the lint
+// fails because of them.
+func ChartLint(ctx context.Context, opts ChartLintOptions) (*ChartLintResultV1, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("get current working directory: %w", err)
+		return nil, fmt.Errorf("get current working directory: %w", err)
 	}
 
 	currentUser, err := user.Current()
 	if err != nil {
-		return fmt.Errorf("get current user: %w", err)
+		return nil, fmt.Errorf("get current user: %w", err)
 	}
 
 	opts, err = applyChartLintOptionsDefaults(opts, currentDir, currentUser)
 	if err != nil {
-		return fmt.Errorf("build chart lint options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return nil, &UsageError{Err: fmt.Errorf("build chart lint options: %w", err)}
 	}
 
 	var clientFactory *kube.ClientFactory
@@ -121,6 +130,8 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 			BurstLimit:            opts.KubeBurstLimit,
 			CertificateAuthority:  opts.KubeCAPath,
 			CurrentContext:        opts.KubeContext,
+			Impersonate:           opts.KubeImpersonateUser,
+			ImpersonateGroups:     opts.KubeImpersonateGroups,
 			InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 			KubeConfigBase64:      opts.KubeConfigBase64,
 			Namespace:             opts.ReleaseNamespace,
@@ -128,38 +139,28 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 			Server:                opts.KubeAPIServerName,
 			TLSServerName:         opts.KubeTLSServerName,
 			Token:                 opts.KubeToken,
+			TokenFile:             opts.KubeTokenPath,
 		})
 		if err != nil {
-			return fmt.Errorf("construct kube config: %w", err)
+			return nil, fmt.Errorf("construct kube config: %w", err)
 		}
 
-		clientFactory, err = kube.NewClientFactory(ctx, kubeConfig)
+		clientFactory, err = kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+			DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+			RefreshDiscovery:  opts.KubeRefreshDiscovery,
+		})
 		if err != nil {
-			return fmt.Errorf("construct kube client factory: %w", err)
+			return nil, fmt.Errorf("construct kube client factory: %w", err)
 		}
 
 		restClientGetter = clientFactory.LegacyClientGetter()
 	}
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
-
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-		registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-	}
-
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
-	}
+	helmSettings := newHelmSettings(ctx)
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	helmRegistryClient, err := newHelmRegistryClient(ctx, opts.LogRegistryStreamOut, opts.RegistryCredentialsPath, opts.ChartRepositoryInsecure)
 	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		return nil, fmt.Errorf("construct registry client: %w", err)
 	}
 
 	helmActionConfig := &action.Configuration{}
@@ -171,7 +172,7 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 			log.Default.Debug(ctx, format, a...)
 		},
 	); err != nil {
-		return fmt.Errorf("helm action config init: %w", err)
+		return nil, fmt.Errorf("helm action config init: %w", err)
 	}
 
 	if !opts.Remote {
@@ -182,7 +183,7 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 
 		kubeVersion, err := chartutil.ParseKubeVersion(opts.LocalKubeVersion)
 		if err != nil {
-			return fmt.Errorf("parse local kube version %q: %w", opts.LocalKubeVersion, err)
+			return nil, fmt.Errorf("parse local kube version %q: %w", opts.LocalKubeVersion, err)
 		}
 
 		helmActionConfig.Capabilities.KubeVersion = *kubeVersion
@@ -190,18 +191,6 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 
 	helmReleaseStorage := helmActionConfig.Releases
 
-	chartextender.DefaultChartAPIVersion = opts.DefaultChartAPIVersion
-	chartextender.DefaultChartName = opts.DefaultChartName
-	chartextender.DefaultChartVersion = opts.DefaultChartVersion
-	chartextender.ChartAppVersion = opts.ChartAppVersion
-	loader.WithoutDefaultSecretValues = opts.DefaultSecretValuesDisable
-	loader.WithoutDefaultValues = opts.DefaultValuesDisable
-	secrets.CoalesceTablesFunc = chartutil.CoalesceTables
-	secrets.SecretsWorkingDir = opts.SecretWorkDir
-	loader.SecretValuesFiles = opts.SecretValuesPaths
-	secrets.ChartDir = opts.ChartDirPath
-	secrets_manager.DisableSecretsDecryption = opts.SecretKeyIgnore
-
 	var historyOptions release.HistoryOptions
 	if opts.Remote {
 		historyOptions.Mapper = clientFactory.Mapper()
@@ -215,17 +204,17 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 		historyOptions,
 	)
 	if err != nil {
-		return fmt.Errorf("construct release history: %w", err)
+		return nil, fmt.Errorf("construct release history: %w", err)
 	}
 
 	prevRelease, prevReleaseFound, err := history.LastRelease()
 	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
+		return nil, fmt.Errorf("get last release: %w", err)
 	}
 
 	_, prevDeployedReleaseFound, err := history.LastDeployedRelease()
 	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
+		return nil, fmt.Errorf("get last deployed release: %w", err)
 	}
 
 	var newRevision int
@@ -244,11 +233,36 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 		deployType = common.DeployTypeInitial
 	}
 
+	var issues []*ChartLintIssue
+
 	chartTreeOptions := chart.ChartTreeOptions{
-		StringSetValues: opts.ValuesStringSets,
-		SetValues:       opts.ValuesSets,
-		FileValues:      opts.ValuesFileSets,
-		ValuesFiles:     opts.ValuesFilesPaths,
+		AgeIdentityPath:            opts.SecretAgeIdentityPath,
+		StringSetValues:            opts.ValuesStringSets,
+		SetValues:                  opts.ValuesSets,
+		FileValues:                 opts.ValuesFileSets,
+		Env:                        opts.ValuesEnv,
+		EnvValuesPrefix:            opts.ValuesEnvPrefix,
+		ValuesFiles:                opts.ValuesFilesPaths,
+		Getters:                    getter.All(helmSettings),
+		SecretKey:                  opts.SecretKey,
+		SecretValuesFiles:          opts.SecretValuesPaths,
+		DefaultSecretValuesDisable: opts.DefaultSecretValuesDisable,
+		ChartAppVersion:            opts.ChartAppVersion,
+		DefaultChartAPIVersion:     opts.DefaultChartAPIVersion,
+		DefaultChartName:           opts.DefaultChartName,
+		DefaultChartVersion:        opts.DefaultChartVersion,
+		DefaultValuesDisable:       opts.DefaultValuesDisable,
+		SecretKeyIgnore:            opts.SecretKeyIgnore,
+		SecretWorkDir:              opts.SecretWorkDir,
+		TempDirPath:                opts.TempDirPath,
+		OnInvalidManifest: func(filePath string, err error) {
+			issues = append(issues, &ChartLintIssue{
+				Severity: ChartLintSeverityError,
+				Check:    ChartLintCheckYAML,
+				Path:     filePath,
+				Message:  err.Error(),
+			})
+		},
 	}
 	if opts.Remote {
 		chartTreeOptions.Mapper = clientFactory.Mapper()
@@ -267,23 +281,88 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 		RepositoryCache:   helmSettings.RepositoryCache,
 		Debug:             helmSettings.Debug,
 	}
-	loader.SetChartPathFunc = downloader.SetChartPath
-	loader.DepsBuildFunc = downloader.Build
+	chartTreeOptions.DependencyManager = downloader
+
+	newChartTree := func() (*chart.ChartTree, error) {
+		return chart.NewChartTree(
+			ctx,
+			opts.ChartDirPath,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			newRevision,
+			deployType,
+			helmActionConfig,
+			chartTreeOptions,
+		)
+	}
+
+	stopLintUnknownHooks := lintUnknownHooks()
+
+	chartTree, err := newChartTree()
+
+	var valuesSchemaErr *chart.ValuesSchemaError
+	if errors.As(err, &valuesSchemaErr) {
+		issues = append(issues, lintValuesSchema(valuesSchemaErr)...)
+
+		// Check the rest of the chart anyway.
+		chartTreeOptions.SkipSchemaValidation = true
+		chartTree, err = newChartTree()
+	}
+
+	issues = append(issues, stopLintUnknownHooks()...)
 
-	chartTree, err := chart.NewChartTree(
-		ctx,
-		opts.ChartDirPath,
-		opts.ReleaseName,
-		opts.ReleaseNamespace,
-		newRevision,
-		deployType,
-		helmActionConfig,
-		chartTreeOptions,
-	)
 	if err != nil {
-		return fmt.Errorf("construct chart tree: %w", err)
+		issues = append(issues, lintRenderError(err))
+	} else {
+		resources := chartTreeLintedResources(chartTree)
+
+		issues = append(issues, lintDuplicateResources(resources)...)
+		issues = append(issues, lintAnnotations(resources)...)
+
+		if opts.Remote {
+			if kubeVersion, err := serverKubeVersion(clientFactory.Discovery()); err != nil {
+				issues = append(issues, &ChartLintIssue{
+					Severity: ChartLintSeverityWarning,
+					Check:    ChartLintCheckAPIVersions,
+					Message:  fmt.Sprintf("unable to check apiVersions: %s", err),
+				})
+			} else {
+				issues = append(issues, lintAPIVersions(resources, kubeVersion, newAPIDiscovery(clientFactory.Discovery()))...)
+			}
+		} else if kubeVersion, err := parseKubeVersion(opts.LocalKubeVersion); err == nil {
+			issues = append(issues, lintAPIVersions(resources, kubeVersion, nil)...)
+		}
+
+		// Resources processing fails on the first problem, so it's useful only if there are no errors.
+		if !lo.ContainsBy(issues, func(issue *ChartLintIssue) bool {
+			return issue.Severity == ChartLintSeverityError
+		}) {
+			if err := processLintedResources(ctx, chartTree, prevReleaseFound, prevRelease, deployType, clientFactory, opts); err != nil {
+				issues = append(issues, &ChartLintIssue{
+					Severity: ChartLintSeverityError,
+					Check:    ChartLintCheckResources,
+					Message:  err.Error(),
+				})
+			}
+		}
 	}
 
+	result := newChartLintResult(issues)
+
+	if !opts.OutputNoPrint {
+		if err := printChartLintResult(ctx, result, opts.OutputFormat, opts.LogColorMode); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Errors > 0 || (opts.Strict && result.Warnings > 0) {
+		return result, fmt.Errorf("chart lint found %d error(s) and %d warning(s)", result.Errors, result.Warnings)
+	}
+
+	return result, nil
+}
+
+func processLintedResources(ctx context.Context, chartTree *chart.ChartTree, prevReleaseFound bool, prevRelease *release.Release, deployType common.DeployType, clientFactory *kube.ClientFactory, opts ChartLintOptions) error {
 	var prevRelGeneralResources []*resource.GeneralResource
 	if prevReleaseFound {
 		prevRelGeneralResources = prevRelease.GeneralResources()
@@ -291,6 +370,7 @@ func ChartLint(ctx context.Context, opts ChartLintOptions) error {
 
 	resProcessorOptions := resourceinfo.DeployableResourcesProcessorOptions{
 		NetworkParallelism: opts.NetworkParallelism,
+		ResourceSizeLimit:  opts.ResourceSizeLimit,
 		ReleasableHookResourcePatchers: []resource.ResourcePatcher{
 			resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 		},
@@ -405,5 +485,116 @@ func applyChartLintOptionsDefaults(opts ChartLintOptions, currentDir string, cur
 		opts.RegistryCredentialsPath = DefaultRegistryCredentialsPath
 	}
 
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultChartLintOutputFormat
+	}
+
 	return opts, nil
 }
+
+func printChartLintResult(ctx context.Context, result *ChartLintResultV1, format, logColorMode string) error {
+	var colorLevel color.Level
+	if logColorMode != LogColorModeOff {
+		colorLevel = color.DetectColorLevel()
+	}
+
+	switch format {
+	case JsonOutputFormat:
+		b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+		if err != nil {
+			return fmt.Errorf("marshal result to json: %w", err)
+		}
+
+		if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+			return fmt.Errorf("write result to output: %w", err)
+		}
+	case YamlOutputFormat:
+		b, err := yaml.MarshalContext(ctx, result)
+		if err != nil {
+			return fmt.Errorf("marshal result to yaml: %w", err)
+		}
+
+		if err := writeWithSyntaxHighlight(os.Stdout, string(b), YamlOutputFormat, colorLevel); err != nil {
+			return fmt.Errorf("write result to output: %w", err)
+		}
+	case TextOutputFormat:
+		if len(result.Issues) == 0 {
+			log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("No issues found in the chart"))
+			return nil
+		}
+
+		if _, err := os.Stdout.Write([]byte(renderChartLintText(result, logColorMode == LogColorModeOn))); err != nil {
+			return fmt.Errorf("write result to output: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	return nil
+}
+
+// Issues are grouped by severity, errors first.
+func renderChartLintText(result *ChartLintResultV1, colorize bool) string {
+	b := &strings.Builder{}
+
+	for _, group := range []struct {
+		severity ChartLintSeverity
+		title    string
+		style    color.Style
+	}{
+		{ChartLintSeverityError, "Errors", color.Style{color.Bold, color.Red}},
+		{ChartLintSeverityWarning, "Warnings", color.Style{color.Bold, color.Yellow}},
+	} {
+		issues := lo.Filter(result.Issues, func(issue *ChartLintIssue, _ int) bool {
+			return issue.Severity == group.severity
+		})
+		if len(issues) == 0 {
+			continue
+		}
+
+		title := fmt.Sprintf("%s (%d):", group.title, len(issues))
+		if colorize {
+			title = group.style.Render(title)
+		}
+
+		fmt.Fprintln(b, title)
+		for _, issue := range issues {
+			fmt.Fprintf(b, "  %s\n", issue)
+		}
+	}
+
+	return b.String()
+}
+
+func newChartLintResult(issues []*ChartLintIssue) *ChartLintResultV1 {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+
+	result := &ChartLintResultV1{
+		ApiVersion: ChartLintResultApiVersionV1,
+		Issues:     []*ChartLintIssue{},
+	}
+
+	for _, issue := range issues {
+		switch issue.Severity {
+		case ChartLintSeverityError:
+			result.Errors++
+		case ChartLintSeverityWarning:
+			result.Warnings++
+		}
+
+		result.Issues = append(result.Issues, issue)
+	}
+
+	return result
+}
+
+const ChartLintResultApiVersionV1 = "v1"
+
+type ChartLintResultV1 struct {
+	ApiVersion string            `json:"apiVersion"`
+	Errors     int               `json:"errors"`
+	Warnings   int               `json:"warnings"`
+	Issues     []*ChartLintIssue `json:"issues"`
+}
@@ -0,0 +1,292 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm/internal/chart"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+)
+
+type ChartLintSeverity string
+
+const (
+	ChartLintSeverityError   ChartLintSeverity = "error"
+	ChartLintSeverityWarning ChartLintSeverity = "warning"
+)
+
+const (
+	// The chart can't be loaded or its templates can't be rendered.
+	ChartLintCheckRender = "render"
+	// A template renders invalid YAML or not a Kubernetes resource.
+	ChartLintCheckYAML = "yaml"
+	// Values don't match values.schema.json of the chart or its subcharts.
+	ChartLintCheckValuesSchema = "values-schema"
+	// Several resources of the release have the same kind, namespace and name.
+	ChartLintCheckDuplicateResources = "duplicate-resources"
+	// Unknown werf.io/helm.sh annotations or invalid values of known ones.
+	ChartLintCheckAnnotations = "annotations"
+	// Invalid hook annotations.
+	ChartLintCheckHooks = "hooks"
+	// The apiVersion is deprecated or removed in the Kubernetes version, not served by the cluster or
+	// is not the preferred one.
+	ChartLintCheckAPIVersions = "api-versions"
+	// Resources are rejected by the cluster or can't be deployed for other reasons.
+	ChartLintCheckResources = "resources"
+)
+
+type ChartLintIssue struct {
+	Severity ChartLintSeverity `json:"severity"`
+	Check    string            `json:"check"`
+	// The template file the resource is rendered from, prefixed with the chart name like in the
+	// "# Source:" comments of rendered manifests, e.g. "mychart/templates/app.yaml", if known.
+	Path     string `json:"path,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i *ChartLintIssue) String() string {
+	var parts []string
+	if i.Path != "" {
+		parts = append(parts, i.Path)
+	}
+
+	if i.Resource != "" {
+		parts = append(parts, i.Resource)
+	}
+
+	return fmt.Sprintf("%s [%s]", strings.Join(append(parts, i.Message), ": "), i.Check)
+}
+
+type lintedResource struct {
+	*id.ResourceID
+
+	unstruct *unstructured.Unstructured
+	validate func() error
+	hook     bool
+}
+
+func chartTreeLintedResources(chartTree *chart.ChartTree) []*lintedResource {
+	var resources []*lintedResource
+
+	for _, res := range chartTree.StandaloneCRDs() {
+		resources = append(resources, &lintedResource{ResourceID: res.ResourceID, unstruct: res.Unstructured(), validate: res.Validate})
+	}
+
+	for _, res := range chartTree.HookResources() {
+		resources = append(resources, &lintedResource{ResourceID: res.ResourceID, unstruct: res.Unstructured(), validate: res.Validate, hook: true})
+	}
+
+	for _, res := range chartTree.GeneralResources() {
+		resources = append(resources, &lintedResource{ResourceID: res.ResourceID, unstruct: res.Unstructured(), validate: res.Validate})
+	}
+
+	return resources
+}
+
+func newChartLintIssue(severity ChartLintSeverity, check string, res *lintedResource, format string, a ...interface{}) *ChartLintIssue {
+	issue := &ChartLintIssue{
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, a...),
+	}
+
+	if res != nil {
+		issue.Path = res.FilePath()
+		issue.Resource = res.HumanID()
+	}
+
+	return issue
+}
+
+// Helm fails rendering as a whole if any template renders invalid YAML, but the template is named
+// in the error.
+var yamlParseErrorRegex = regexp.MustCompile(`YAML parse error on ([^:\s]+):`)
+
+func lintRenderError(err error) *ChartLintIssue {
+	if match := yamlParseErrorRegex.FindStringSubmatch(err.Error()); match != nil {
+		return &ChartLintIssue{
+			Severity: ChartLintSeverityError,
+			Check:    ChartLintCheckYAML,
+			Path:     match[1],
+			Message:  err.Error(),
+		}
+	}
+
+	return &ChartLintIssue{
+		Severity: ChartLintSeverityError,
+		Check:    ChartLintCheckRender,
+		Message:  err.Error(),
+	}
+}
+
+// Helm skips hooks of unknown types with only a log message, so the resource silently disappears
+// from the release. The message is caught to report such hooks.
+var unknownHookLogRegex = regexp.MustCompile(`skipping unknown hook: ("(?:[^"\\]|\\.)*")`)
+
+type unknownHooksWatcher struct {
+	io.Writer
+
+	hookTypes []string
+}
+
+func (w *unknownHooksWatcher) Write(p []byte) (int, error) {
+	for _, match := range unknownHookLogRegex.FindAllStringSubmatch(string(p), -1) {
+		if hookType, err := strconv.Unquote(match[1]); err == nil {
+			w.hookTypes = append(w.hookTypes, hookType)
+		}
+	}
+
+	return w.Writer.Write(p)
+}
+
+// lintUnknownHooks watches the standard logger until the returned function is called, which
+// returns the issues for hooks of unknown types skipped meanwhile.
+func lintUnknownHooks() func() []*ChartLintIssue {
+	prevWriter := stdlog.Writer()
+	watcher := &unknownHooksWatcher{Writer: prevWriter}
+	stdlog.SetOutput(watcher)
+
+	return func() []*ChartLintIssue {
+		stdlog.SetOutput(prevWriter)
+
+		var issues []*ChartLintIssue
+		for _, hookType := range lo.Uniq(watcher.hookTypes) {
+			issues = append(issues, &ChartLintIssue{
+				Severity: ChartLintSeverityError,
+				Check:    ChartLintCheckHooks,
+				Message:  fmt.Sprintf("unknown hook type %q, resources with this hook are not deployed", hookType),
+			})
+		}
+
+		return issues
+	}
+}
+
+func lintValuesSchema(schemaErr *chart.ValuesSchemaError) []*ChartLintIssue {
+	var issues []*ChartLintIssue
+	for _, violation := range schemaErr.Violations {
+		issues = append(issues, &ChartLintIssue{
+			Severity: ChartLintSeverityError,
+			Check:    ChartLintCheckValuesSchema,
+			Message:  violation.String(),
+		})
+	}
+
+	return issues
+}
+
+func lintDuplicateResources(resources []*lintedResource) []*ChartLintIssue {
+	resourcesByID := lo.GroupBy(resources, func(res *lintedResource) string {
+		return res.ID()
+	})
+
+	var issues []*ChartLintIssue
+	for _, res := range resources {
+		duplicates := resourcesByID[res.ID()]
+		if len(duplicates) < 2 {
+			continue
+		}
+
+		otherPaths := lo.Uniq(lo.FilterMap(duplicates, func(dup *lintedResource, _ int) (string, bool) {
+			return dup.FilePath(), dup != res && dup.FilePath() != ""
+		}))
+
+		msg := "resource is rendered more than once"
+		if len(otherPaths) > 0 {
+			msg = fmt.Sprintf("resource is also rendered from %s", strings.Join(otherPaths, ", "))
+		}
+
+		issues = append(issues, newChartLintIssue(ChartLintSeverityError, ChartLintCheckDuplicateResources, res, "%s", msg))
+	}
+
+	return issues
+}
+
+func lintAnnotations(resources []*lintedResource) []*ChartLintIssue {
+	var issues []*ChartLintIssue
+	for _, res := range resources {
+		keys := lo.Keys(res.unstruct.GetAnnotations())
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			unknown, suggestion := resource.UnknownAnnotation(key)
+			if !unknown {
+				continue
+			}
+
+			if suggestion != "" {
+				issues = append(issues, newChartLintIssue(ChartLintSeverityWarning, ChartLintCheckAnnotations, res, "unknown annotation %q, did you mean %q?", key, suggestion))
+			} else {
+				issues = append(issues, newChartLintIssue(ChartLintSeverityWarning, ChartLintCheckAnnotations, res, "unknown annotation %q", key))
+			}
+		}
+
+		if err := res.validate(); err != nil {
+			check := ChartLintCheckAnnotations
+			if res.hook {
+				check = ChartLintCheckHooks
+			}
+
+			issues = append(issues, newChartLintIssue(ChartLintSeverityError, check, res, "%s", err))
+		}
+	}
+
+	return issues
+}
+
+// lintAPIVersions checks the apiVersions against the built-in table of deprecated ones for the
+// Kubernetes version. If the discovery is available, it also checks that the cluster serves the
+// kinds in their apiVersions and that the preferred apiVersions are used. Kinds of CRDs from the
+// chart itself are not checked against the cluster, since they might be not deployed yet.
+func lintAPIVersions(resources []*lintedResource, kubeVersion *semver.Version, apis *apiDiscovery) []*ChartLintIssue {
+	chartCRDKinds := crdKinds(lo.Map(resources, func(res *lintedResource, _ int) *unstructured.Unstructured {
+		return res.unstruct
+	}))
+
+	var issues []*ChartLintIssue
+	for _, res := range resources {
+		usages := findDeprecatedAPIs([]*id.ResourceID{res.ResourceID}, DeprecatedAPISourceChart, kubeVersion, apis, chartCRDKinds)
+		if len(usages) > 0 {
+			severity := ChartLintSeverityWarning
+			if usages[0].Removed {
+				severity = ChartLintSeverityError
+			}
+
+			issues = append(issues, newChartLintIssue(severity, ChartLintCheckAPIVersions, res, "%s", usages[0]))
+
+			continue
+		}
+
+		gvk := res.GroupVersionKind()
+		if apis == nil || chartCRDKinds[gvk.GroupKind()] {
+			continue
+		}
+
+		if _, err := apis.Served(gvk.GroupVersion(), gvk.Kind); err != nil {
+			issues = append(issues, newChartLintIssue(ChartLintSeverityWarning, ChartLintCheckAPIVersions, res, "unable to check whether apiVersion %q is served by the cluster: %s", gvk.GroupVersion(), err))
+			continue
+		}
+
+		preferredGV, found := apis.PreferredGroupVersion(gvk.Group)
+		if !found || preferredGV == gvk.GroupVersion() {
+			continue
+		}
+
+		if ok, err := apis.Served(preferredGV, gvk.Kind); err == nil && ok {
+			issues = append(issues, newChartLintIssue(ChartLintSeverityWarning, ChartLintCheckAPIVersions, res, "apiVersion %q is not the preferred version of %q in the cluster, use %q", gvk.GroupVersion(), gvk.Kind, preferredGV))
+		}
+	}
+
+	return issues
+}
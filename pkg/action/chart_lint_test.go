@@ -0,0 +1,332 @@
+package action_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("chart lint", func() {
+	// lintChart lints a chart with the templates, keyed by their paths relative to the templates
+	// directory.
+	lintChart := func(templates map[string]string, opts action.ChartLintOptions) (*action.ChartLintResultV1, error) {
+		chartDir := filepath.Join(GinkgoT().TempDir(), "chart")
+		writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+		for path, content := range templates {
+			writeFile(filepath.Join(chartDir, "templates", path), content)
+		}
+
+		opts.ChartDirPath = chartDir
+		opts.LogColorMode = action.LogColorModeOff
+		if opts.OutputFormat == "" {
+			opts.OutputFormat = action.JsonOutputFormat
+			opts.OutputNoPrint = true
+		}
+
+		return action.ChartLint(context.Background(), opts)
+	}
+
+	configMap := func(name string, annotations ...string) string {
+		manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+		if len(annotations) > 0 {
+			manifest += "  annotations:\n"
+			for _, annotation := range annotations {
+				manifest += "    " + annotation + "\n"
+			}
+		}
+
+		return manifest
+	}
+
+	It("finds no issues in a valid chart", func() {
+		result, err := lintChart(map[string]string{
+			"cm.yaml": configMap("app", `werf.io/weight: "10"`, "example.com/custom: value"),
+		}, action.ChartLintOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(BeZero())
+		Expect(result.Warnings).To(BeZero())
+		Expect(result.Issues).To(BeEmpty())
+	})
+
+	It("reports templates failing to render", func() {
+		result, err := lintChart(map[string]string{
+			"cm.yaml": `{{ fail "broken" }}`,
+		}, action.ChartLintOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(result.Issues).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Severity": Equal(action.ChartLintSeverityError),
+				"Check":    Equal(action.ChartLintCheckRender),
+				"Message":  ContainSubstring("broken"),
+			})),
+		))
+	})
+
+	It("reports the template rendering invalid YAML", func() {
+		result, err := lintChart(map[string]string{
+			"bad.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: [app\n",
+			"cm.yaml":  configMap("app"),
+		}, action.ChartLintOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(result.Issues).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Severity": Equal(action.ChartLintSeverityError),
+				"Check":    Equal(action.ChartLintCheckYAML),
+				"Path":     Equal("chart/templates/bad.yaml"),
+			})),
+		))
+	})
+
+	It("reports values not matching the schema and checks the rest of the chart anyway", func() {
+		chartDir := filepath.Join(GinkgoT().TempDir(), "chart")
+		writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+		writeFile(filepath.Join(chartDir, "values.yaml"), "replicas: many\n")
+		writeFile(filepath.Join(chartDir, "values.schema.json"), `{"type": "object", "properties": {"replicas": {"type": "integer"}}}`)
+		writeFile(filepath.Join(chartDir, "templates", "a.yaml"), configMap("app"))
+		writeFile(filepath.Join(chartDir, "templates", "b.yaml"), configMap("app"))
+
+		result, err := action.ChartLint(context.Background(), action.ChartLintOptions{
+			ChartDirPath:  chartDir,
+			LogColorMode:  action.LogColorModeOff,
+			OutputNoPrint: true,
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(result.Issues).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Severity": Equal(action.ChartLintSeverityError),
+			"Check":    Equal(action.ChartLintCheckValuesSchema),
+			"Message":  ContainSubstring("replicas"),
+		}))))
+		Expect(result.Issues).To(ContainElement(HaveField("Check", action.ChartLintCheckDuplicateResources)))
+	})
+
+	It("reports each duplicate resource with the other templates rendering it", func() {
+		result, err := lintChart(map[string]string{
+			"a.yaml": configMap("app"),
+			"b.yaml": configMap("app"),
+			"c.yaml": configMap("other"),
+		}, action.ChartLintOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(result.Errors).To(Equal(2))
+		Expect(result.Issues).To(Equal([]*action.ChartLintIssue{
+			{
+				Severity: action.ChartLintSeverityError,
+				Check:    action.ChartLintCheckDuplicateResources,
+				Path:     "chart/templates/a.yaml",
+				Resource: "ConfigMap/app",
+				Message:  "resource is also rendered from chart/templates/b.yaml",
+			},
+			{
+				Severity: action.ChartLintSeverityError,
+				Check:    action.ChartLintCheckDuplicateResources,
+				Path:     "chart/templates/b.yaml",
+				Resource: "ConfigMap/app",
+				Message:  "resource is also rendered from chart/templates/a.yaml",
+			},
+		}))
+	})
+
+	Context("with annotations", func() {
+		It("warns about unknown werf.io annotations, suggesting the closest known one", func() {
+			result, err := lintChart(map[string]string{
+				"cm.yaml": configMap("app", `werf.io/wieght: "10"`, `helm.sh/hook-wieght: "10"`, `werf.io/completely-unrelated-thing: "x"`, "example.com/wieght: x"),
+			}, action.ChartLintOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Warnings).To(Equal(3))
+			Expect(result.Issues).To(HaveEach(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Severity": Equal(action.ChartLintSeverityWarning),
+				"Check":    Equal(action.ChartLintCheckAnnotations),
+				"Path":     Equal("chart/templates/cm.yaml"),
+				"Resource": Equal("ConfigMap/app"),
+			}))))
+			Expect(result.Issues).To(HaveExactElements(
+				HaveField("Message", `unknown annotation "helm.sh/hook-wieght", did you mean "helm.sh/hook-weight"?`),
+				HaveField("Message", `unknown annotation "werf.io/completely-unrelated-thing"`),
+				HaveField("Message", `unknown annotation "werf.io/wieght", did you mean "werf.io/weight"?`),
+			))
+		})
+
+		It("reports invalid values of known annotations as errors", func() {
+			result, err := lintChart(map[string]string{
+				"cm.yaml": configMap("app", "werf.io/weight: abc"),
+			}, action.ChartLintOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(result.Issues).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Severity": Equal(action.ChartLintSeverityError),
+					"Check":    Equal(action.ChartLintCheckAnnotations),
+					"Message":  ContainSubstring(`invalid value "abc" for annotation "werf.io/weight"`),
+				})),
+			))
+		})
+	})
+
+	Context("with hooks", func() {
+		It("reports hooks of unknown types, which would be silently skipped", func() {
+			result, err := lintChart(map[string]string{
+				"cm.yaml": configMap("app", "helm.sh/hook: pre-instal"),
+			}, action.ChartLintOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(result.Issues).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Severity": Equal(action.ChartLintSeverityError),
+					"Check":    Equal(action.ChartLintCheckHooks),
+					"Message":  Equal(`unknown hook type "pre-instal", resources with this hook are not deployed`),
+				})),
+			))
+		})
+
+		It("reports invalid annotations of hooks under the hooks check", func() {
+			result, err := lintChart(map[string]string{
+				"cm.yaml": configMap("app", "helm.sh/hook: pre-install", "helm.sh/hook-weight: abc"),
+			}, action.ChartLintOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(result.Issues).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Severity": Equal(action.ChartLintSeverityError),
+					"Check":    Equal(action.ChartLintCheckHooks),
+					"Resource": Equal("ConfigMap/app"),
+					"Message":  ContainSubstring(`invalid value "abc" for annotation "helm.sh/hook-weight"`),
+				})),
+			))
+		})
+	})
+
+	DescribeTable("checks apiVersions against the local Kubernetes version",
+		func(kubeVersion string, expectedSeverity action.ChartLintSeverity) {
+			result, _ := lintChart(map[string]string{
+				"cronjob.yaml": "apiVersion: batch/v1beta1\nkind: CronJob\nmetadata:\n  name: cleanup\n",
+			}, action.ChartLintOptions{LocalKubeVersion: kubeVersion})
+
+			if expectedSeverity == "" {
+				Expect(result.Issues).To(BeEmpty())
+				return
+			}
+
+			Expect(result.Issues).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Severity": Equal(expectedSeverity),
+					"Check":    Equal(action.ChartLintCheckAPIVersions),
+					"Resource": Equal("CronJob/cleanup"),
+				})),
+			))
+		},
+		Entry("before the deprecation", "1.20.0", action.ChartLintSeverity("")),
+		Entry("deprecated", "1.21.0", action.ChartLintSeverityWarning),
+		Entry("removed", "1.25.0", action.ChartLintSeverityError),
+	)
+
+	Context("with --strict", func() {
+		templates := map[string]string{
+			"cm.yaml": configMap("app", `werf.io/wieght: "10"`),
+		}
+
+		It("doesn't fail on warnings without it", func() {
+			result, err := lintChart(templates, action.ChartLintOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Warnings).To(Equal(1))
+		})
+
+		It("fails on warnings with it", func() {
+			result, err := lintChart(templates, action.ChartLintOptions{Strict: true})
+			Expect(err).To(MatchError("chart lint found 0 error(s) and 1 warning(s)"))
+			Expect(result.Warnings).To(Equal(1))
+		})
+	})
+
+	It("prints the result as JSON", func() {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		Expect(err).NotTo(HaveOccurred())
+
+		prevStdout := os.Stdout
+		os.Stdout = stdoutWriter
+		_, lintErr := lintChart(map[string]string{
+			"a.yaml": configMap("app", `werf.io/wieght: "10"`),
+			"b.yaml": configMap("app"),
+		}, action.ChartLintOptions{OutputFormat: action.JsonOutputFormat})
+		os.Stdout = prevStdout
+		Expect(stdoutWriter.Close()).To(Succeed())
+		Expect(lintErr).To(HaveOccurred())
+
+		output, err := io.ReadAll(stdoutReader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(MatchJSON(`{
+			"apiVersion": "v1",
+			"errors": 2,
+			"warnings": 1,
+			"issues": [
+				{
+					"severity": "error",
+					"check": "duplicate-resources",
+					"path": "chart/templates/a.yaml",
+					"resource": "ConfigMap/app",
+					"message": "resource is also rendered from chart/templates/b.yaml"
+				},
+				{
+					"severity": "warning",
+					"check": "annotations",
+					"path": "chart/templates/a.yaml",
+					"resource": "ConfigMap/app",
+					"message": "unknown annotation \"werf.io/wieght\", did you mean \"werf.io/weight\"?"
+				},
+				{
+					"severity": "error",
+					"check": "duplicate-resources",
+					"path": "chart/templates/b.yaml",
+					"resource": "ConfigMap/app",
+					"message": "resource is also rendered from chart/templates/a.yaml"
+				}
+			]
+		}`))
+	})
+
+	Context("with remote values files", func() {
+		It("fetches them again in the next lint", func() {
+			chartDir := filepath.Join(GinkgoT().TempDir(), "chart")
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), `{{- if ne .Values.env "staging" }}{{ fail "env is not staging" }}{{ end }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+`)
+
+			var remoteValues atomic.Value
+			remoteValues.Store("env: staging\n")
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, remoteValues.Load())
+			}))
+			DeferCleanup(server.Close)
+
+			lint := func() (*action.ChartLintResultV1, error) {
+				return action.ChartLint(context.Background(), action.ChartLintOptions{
+					ChartDirPath:     chartDir,
+					LogColorMode:     action.LogColorModeOff,
+					OutputNoPrint:    true,
+					ValuesFilesPaths: []string{server.URL + "/values.yaml"},
+				})
+			}
+
+			result, err := lint()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Errors).To(BeZero())
+
+			remoteValues.Store("env: production\n")
+
+			result, err = lint()
+			Expect(err).To(HaveOccurred())
+			Expect(result.Issues).To(ContainElement(HaveField("Message", ContainSubstring("env is not staging"))))
+		})
+	})
+})
@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
-	"strings"
 
 	"github.com/gookit/color"
 	"github.com/samber/lo"
@@ -16,26 +15,21 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/yaml"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
-	"github.com/werf/3p-helm/pkg/chart/loader"
 	"github.com/werf/3p-helm/pkg/chartutil"
 	"github.com/werf/3p-helm/pkg/downloader"
 	"github.com/werf/3p-helm/pkg/getter"
-	"github.com/werf/3p-helm/pkg/registry"
 	"github.com/werf/3p-helm/pkg/storage"
 	"github.com/werf/3p-helm/pkg/storage/driver"
-	"github.com/werf/3p-helm/pkg/werf/chartextender"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
-	"github.com/werf/common-go/pkg/secrets_manager"
 	"github.com/werf/logboek"
 	"github.com/werf/nelm/internal/chart"
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -43,9 +37,14 @@ const (
 )
 
 type ChartRenderOptions struct {
+	CRDsPolicy                   string
 	ChartAppVersion              string
+	ChartDependencyUpdate        bool
 	ChartDirPath                 string
+	ChartProvenanceKeyringPath   string
+	ChartProvenanceVerify        bool
 	ChartRepositoryInsecure      bool
+	ChartRepositoryPlainHTTP     bool
 	ChartRepositorySkipTLSVerify bool
 	ChartRepositorySkipUpdate    bool
 	DefaultChartAPIVersion       string
@@ -62,10 +61,17 @@ type ChartRenderOptions struct {
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
+	KubeDiscoveryCacheDir        string
+	KubeImpersonateGroups        []string
+	KubeImpersonateUser          string
 	KubeQPSLimit                 int
+	KubeRefreshDiscovery         bool
 	KubeSkipTLSVerify            bool
 	KubeTLSServerName            string
 	KubeToken                    string
+	KubeTokenPath                string
+	PostRenderer                 string
+	PostRendererArgs             []string
 	Remote                       bool
 	LocalKubeVersion             string
 	LogColorMode                 string
@@ -76,23 +82,30 @@ type ChartRenderOptions struct {
 	ReleaseName                  string
 	ReleaseNamespace             string
 	ReleaseStorageDriver         string
+	RenderCacheDir               string
+	SecretAgeIdentityPath        string
 	SecretKey                    string
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	SecretWorkDir                string
 	ShowCRDs                     bool
 	ShowOnlyFiles                []string
-	TempDirPath                  string
-	ValuesFileSets               []string
-	ValuesFilesPaths             []string
-	ValuesSets                   []string
-	ValuesStringSets             []string
+	// Render the release values, merged from all values sources, instead of the manifests.
+	ShowValues           bool
+	SkipSchemaValidation bool
+	StrictTemplates      bool
+	TempDirPath          string
+	ValuesEnv            map[string]string
+	ValuesEnvPrefix      string
+	ValuesFileSets       []string
+	ValuesFilesPaths     []string
+	ValuesJSONSets       []string
+	ValuesLiteralSets    []string
+	ValuesSets           []string
+	ValuesStringSets     []string
 }
 
 func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -105,11 +118,7 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 
 	opts, err = applyChartRenderOptionsDefaults(opts, currentDir, currentUser)
 	if err != nil {
-		return fmt.Errorf("build chart render options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return &UsageError{Err: fmt.Errorf("build chart render options: %w", err)}
 	}
 
 	var clientFactory *kube.ClientFactory
@@ -129,6 +138,8 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 			BurstLimit:            opts.KubeBurstLimit,
 			CertificateAuthority:  opts.KubeCAPath,
 			CurrentContext:        opts.KubeContext,
+			Impersonate:           opts.KubeImpersonateUser,
+			ImpersonateGroups:     opts.KubeImpersonateGroups,
 			InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 			KubeConfigBase64:      opts.KubeConfigBase64,
 			Namespace:             opts.ReleaseNamespace,
@@ -136,12 +147,16 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 			Server:                opts.KubeAPIServerName,
 			TLSServerName:         opts.KubeTLSServerName,
 			Token:                 opts.KubeToken,
+			TokenFile:             opts.KubeTokenPath,
 		})
 		if err != nil {
 			return fmt.Errorf("construct kube config: %w", err)
 		}
 
-		clientFactory, err = kube.NewClientFactory(ctx, kubeConfig)
+		clientFactory, err = kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+			DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+			RefreshDiscovery:  opts.KubeRefreshDiscovery,
+		})
 		if err != nil {
 			return fmt.Errorf("construct kube client factory: %w", err)
 		}
@@ -149,25 +164,26 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 		restClientGetter = clientFactory.LegacyClientGetter()
 	}
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
+	helmSettings := newHelmSettings(ctx)
 
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-		registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-	}
-
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
+	helmRegistryClient, err := newHelmRegistryClient(ctx, opts.LogRegistryStreamOut, opts.RegistryCredentialsPath, opts.ChartRepositoryInsecure || opts.ChartRepositoryPlainHTTP)
+	if err != nil {
+		return fmt.Errorf("construct registry client: %w", err)
 	}
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	opts.ChartDirPath, err = chart.ResolveChart(ctx, opts.ChartDirPath, chart.ResolveChartOptions{
+		Getters:          getter.All(helmSettings),
+		Keyring:          opts.ChartProvenanceKeyringPath,
+		Out:              logboek.Context(ctx).OutStream(),
+		PlainHTTP:        opts.ChartRepositoryPlainHTTP,
+		RegistryClient:   helmRegistryClient,
+		RepositoryCache:  helmSettings.RepositoryCache,
+		RepositoryConfig: helmSettings.RepositoryConfig,
+		TempDirPath:      opts.TempDirPath,
+		Verify:           opts.ChartProvenanceVerify,
+	})
 	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		return fmt.Errorf("resolve chart: %w", err)
 	}
 
 	helmActionConfig := &action.Configuration{}
@@ -198,18 +214,6 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 
 	helmReleaseStorage := helmActionConfig.Releases
 
-	chartextender.DefaultChartAPIVersion = opts.DefaultChartAPIVersion
-	chartextender.DefaultChartName = opts.DefaultChartName
-	chartextender.DefaultChartVersion = opts.DefaultChartVersion
-	chartextender.ChartAppVersion = opts.ChartAppVersion
-	loader.WithoutDefaultSecretValues = opts.DefaultSecretValuesDisable
-	loader.WithoutDefaultValues = opts.DefaultValuesDisable
-	secrets.CoalesceTablesFunc = chartutil.CoalesceTables
-	secrets.SecretsWorkingDir = opts.SecretWorkDir
-	loader.SecretValuesFiles = opts.SecretValuesPaths
-	secrets.ChartDir = opts.ChartDirPath
-	secrets_manager.DisableSecretsDecryption = opts.SecretKeyIgnore
-
 	var historyOptions release.HistoryOptions
 	if opts.Remote {
 		historyOptions.Mapper = clientFactory.Mapper()
@@ -252,11 +256,39 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 		deployType = common.DeployTypeInitial
 	}
 
+	postRenderer, err := chart.NewPostRenderer(opts.PostRenderer, opts.PostRendererArgs)
+	if err != nil {
+		return fmt.Errorf("construct post-renderer: %w", err)
+	}
+
 	chartTreeOptions := chart.ChartTreeOptions{
-		StringSetValues: opts.ValuesStringSets,
-		SetValues:       opts.ValuesSets,
-		FileValues:      opts.ValuesFileSets,
-		ValuesFiles:     opts.ValuesFilesPaths,
+		AgeIdentityPath:            opts.SecretAgeIdentityPath,
+		StringSetValues:            opts.ValuesStringSets,
+		SetValues:                  opts.ValuesSets,
+		FileValues:                 opts.ValuesFileSets,
+		Env:                        opts.ValuesEnv,
+		EnvValuesPrefix:            opts.ValuesEnvPrefix,
+		JSONSetValues:              opts.ValuesJSONSets,
+		LiteralSetValues:           opts.ValuesLiteralSets,
+		ValuesFiles:                opts.ValuesFilesPaths,
+		PostRenderer:               postRenderer,
+		Getters:                    getter.All(helmSettings),
+		RegistryClient:             helmRegistryClient,
+		SecretKey:                  opts.SecretKey,
+		SecretValuesFiles:          opts.SecretValuesPaths,
+		DefaultSecretValuesDisable: opts.DefaultSecretValuesDisable,
+		ChartAppVersion:            opts.ChartAppVersion,
+		DefaultChartAPIVersion:     opts.DefaultChartAPIVersion,
+		DefaultChartName:           opts.DefaultChartName,
+		DefaultChartVersion:        opts.DefaultChartVersion,
+		DefaultValuesDisable:       opts.DefaultValuesDisable,
+		SecretKeyIgnore:            opts.SecretKeyIgnore,
+		SecretWorkDir:              opts.SecretWorkDir,
+		RenderCacheDir:             opts.RenderCacheDir,
+		StrictTemplates:            opts.StrictTemplates,
+		TempDirPath:                opts.TempDirPath,
+		ShowOnlyFiles:              opts.ShowOnlyFiles,
+		SkipSchemaValidation:       opts.SkipSchemaValidation,
 	}
 	if opts.Remote {
 		chartTreeOptions.Mapper = clientFactory.Mapper()
@@ -275,8 +307,13 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 		RepositoryCache:   helmSettings.RepositoryCache,
 		Debug:             helmSettings.Debug,
 	}
-	loader.SetChartPathFunc = downloader.SetChartPath
-	loader.DepsBuildFunc = downloader.Build
+	chartTreeOptions.DependencyManager = downloader
+
+	if opts.ChartDependencyUpdate {
+		if err := chart.UpdateDependencies(ctx, opts.ChartDirPath, chartTreeOptions); err != nil {
+			return fmt.Errorf("update chart dependencies: %w", err)
+		}
+	}
 
 	chartTree, err := chart.NewChartTree(
 		ctx,
@@ -289,7 +326,18 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 		chartTreeOptions,
 	)
 	if err != nil {
-		return fmt.Errorf("construct chart tree: %w", err)
+		return &TemplateError{Err: fmt.Errorf("construct chart tree: %w", err)}
+	}
+
+	var colorLevel color.Level
+	if opts.LogColorMode != LogColorModeOff {
+		colorLevel = color.DetectColorLevel()
+	}
+
+	if opts.ShowValues {
+		return writeChartRenderOutput(opts.OutputFilePath, func(renderOutStream io.Writer) error {
+			return renderValues(chartTree.ReleaseValues(), renderOutStream, colorLevel)
+		})
 	}
 
 	var prevRelGeneralResources []*resource.GeneralResource
@@ -298,7 +346,9 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 	}
 
 	resProcessorOptions := resourceinfo.DeployableResourcesProcessorOptions{
-		NetworkParallelism: opts.NetworkParallelism,
+		CRDPolicy:             resource.CRDPolicy(opts.CRDsPolicy),
+		StrictFieldValidation: opts.StrictTemplates,
+		NetworkParallelism:    opts.NetworkParallelism,
 		ReleasableHookResourcePatchers: []resource.ResourcePatcher{
 			resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 		},
@@ -343,84 +393,43 @@ func ChartRender(ctx context.Context, opts ChartRenderOptions) error {
 		return fmt.Errorf("process resources: %w", err)
 	}
 
-	var showFiles []string
-	for _, file := range opts.ShowOnlyFiles {
-		absFile, err := filepath.Abs(file)
-		if err != nil {
-			return fmt.Errorf("get absolute path for %q: %w", file, err)
-		}
-
-		if strings.HasPrefix(absFile, opts.ChartDirPath) {
-			f, err := filepath.Rel(opts.ChartDirPath, absFile)
-			if err != nil {
-				return fmt.Errorf("get relative path for %q: %w", absFile, err)
-			}
-
-			if !strings.HasPrefix(f, chartTree.Name()) {
-				f = filepath.Join(chartTree.Name(), f)
+	render := func(renderOutStream io.Writer) error {
+		if opts.ShowCRDs {
+			for _, resource := range resProcessor.DeployableStandaloneCRDs() {
+				if err := renderResource(resource.Unstructured(), resource.FilePath(), renderOutStream, colorLevel); err != nil {
+					return fmt.Errorf("render CRD %q: %w", resource.HumanID(), err)
+				}
 			}
-
-			showFiles = append(showFiles, f)
-		} else {
-			if !strings.HasPrefix(file, chartTree.Name()) {
-				file = filepath.Join(chartTree.Name(), file)
-			}
-
-			showFiles = append(showFiles, file)
-		}
-	}
-
-	var renderOutStream io.Writer
-	if opts.OutputFilePath != "" {
-		file, err := os.Create(opts.OutputFilePath)
-		if err != nil {
-			return fmt.Errorf("create chart render output file %q: %w", opts.OutputFilePath, err)
 		}
-		defer file.Close()
-
-		renderOutStream = file
-	} else {
-		renderOutStream = os.Stdout
-	}
-
-	var colorLevel color.Level
-	if opts.LogColorMode != LogColorModeOff {
-		colorLevel = color.DetectColorLevel()
-	}
-
-	if opts.ShowCRDs {
-		for _, resource := range resProcessor.DeployableStandaloneCRDs() {
-			if len(showFiles) > 0 && !lo.Contains(showFiles, resource.FilePath()) {
-				continue
-			}
 
+		for _, resource := range resProcessor.DeployableHookResources() {
 			if err := renderResource(resource.Unstructured(), resource.FilePath(), renderOutStream, colorLevel); err != nil {
-				return fmt.Errorf("render CRD %q: %w", resource.HumanID(), err)
+				return fmt.Errorf("render hook resource %q: %w", resource.HumanID(), err)
 			}
 		}
-	}
 
-	for _, resource := range resProcessor.DeployableHookResources() {
-		if len(showFiles) > 0 && !lo.Contains(showFiles, resource.FilePath()) {
-			continue
+		for _, resource := range resProcessor.DeployableGeneralResources() {
+			if err := renderResource(resource.Unstructured(), resource.FilePath(), renderOutStream, colorLevel); err != nil {
+				return fmt.Errorf("render general resource %q: %w", resource.HumanID(), err)
+			}
 		}
 
-		if err := renderResource(resource.Unstructured(), resource.FilePath(), renderOutStream, colorLevel); err != nil {
-			return fmt.Errorf("render hook resource %q: %w", resource.HumanID(), err)
-		}
+		return nil
 	}
 
-	for _, resource := range resProcessor.DeployableGeneralResources() {
-		if len(showFiles) > 0 && !lo.Contains(showFiles, resource.FilePath()) {
-			continue
-		}
+	return writeChartRenderOutput(opts.OutputFilePath, render)
+}
 
-		if err := renderResource(resource.Unstructured(), resource.FilePath(), renderOutStream, colorLevel); err != nil {
-			return fmt.Errorf("render general resource %q: %w", resource.HumanID(), err)
+func writeChartRenderOutput(outputFilePath string, render func(renderOutStream io.Writer) error) error {
+	if outputFilePath != "" {
+		if err := util.WriteFileAtomicFunc(outputFilePath, 0o644, render); err != nil {
+			return fmt.Errorf("write chart render output file %q: %w", outputFilePath, err)
 		}
+
+		return nil
 	}
 
-	return nil
+	return render(os.Stdout)
 }
 
 func applyChartRenderOptionsDefaults(opts ChartRenderOptions, currentDir string, currentUser *user.User) (ChartRenderOptions, error) {
@@ -454,6 +463,10 @@ func applyChartRenderOptionsDefaults(opts ChartRenderOptions, currentDir string,
 
 	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, opts.OutputFilePath != "")
 
+	if opts.CRDsPolicy, err = applyCRDsPolicyDefault(opts.CRDsPolicy); err != nil {
+		return ChartRenderOptions{}, fmt.Errorf("apply CRDs policy default: %w", err)
+	}
+
 	if opts.NetworkParallelism <= 0 {
 		opts.NetworkParallelism = DefaultNetworkParallelism
 	}
@@ -490,9 +503,26 @@ func applyChartRenderOptionsDefaults(opts ChartRenderOptions, currentDir string,
 		opts.RegistryCredentialsPath = DefaultRegistryCredentialsPath
 	}
 
+	if opts.ChartProvenanceKeyringPath == "" {
+		opts.ChartProvenanceKeyringPath = DefaultChartProvenanceKeyringPath
+	}
+
 	return opts, nil
 }
 
+func renderValues(values map[string]interface{}, outStream io.Writer, colorLevel color.Level) error {
+	valuesYamlBytes, err := yaml.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("marshal values to YAML: %w", err)
+	}
+
+	if err := writeWithSyntaxHighlight(outStream, string(valuesYamlBytes), "yaml", colorLevel); err != nil {
+		return fmt.Errorf("write values to output: %w", err)
+	}
+
+	return nil
+}
+
 func renderResource(unstruct *unstructured.Unstructured, path string, outStream io.Writer, colorLevel color.Level) error {
 	resourceJsonBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, unstruct)
 	if err != nil {
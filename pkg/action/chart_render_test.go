@@ -0,0 +1,715 @@
+package action_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("chart render", func() {
+	Context("with values from environment variables", func() {
+		var (
+			ctx      context.Context
+			tmpDir   string
+			chartDir string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "values.yaml"), "app:\n  fromChart: chart\n  fromEnv: chart\n")
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n")
+			writeFile(filepath.Join(tmpDir, "values.yaml"), "app:\n  fromFile: file\n  fromSet: file\n")
+		})
+
+		renderValues := func(opts action.ChartRenderOptions) map[string]interface{} {
+			opts.ChartDirPath = chartDir
+			opts.LogColorMode = action.LogColorModeOff
+			opts.OutputFilePath = filepath.Join(tmpDir, "values.out.yaml")
+			opts.ShowValues = true
+			opts.ValuesEnv = map[string]string{
+				"TEST_VALUES_app__fromEnv":        "env",
+				"TEST_VALUES_app__fromFile":       "env",
+				"TEST_VALUES_app__fromSet":        "env",
+				"TEST_VALUES_app__raw":            "a=b,c=d\nsecond line",
+				"TEST_VALUES_nested__deeply__key": "env",
+				"OTHER_VALUES_app__fromEnv":       "other",
+			}
+
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			values := map[string]interface{}{}
+			Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+
+			return values
+		}
+
+		It("merges them with lower priority than values files and --set values", func() {
+			values := renderValues(action.ChartRenderOptions{
+				ValuesEnvPrefix:  "TEST_VALUES_",
+				ValuesFilesPaths: []string{filepath.Join(tmpDir, "values.yaml")},
+				ValuesSets:       []string{"app.fromSet=set"},
+			})
+
+			Expect(values).To(Equal(map[string]interface{}{
+				"app": map[string]interface{}{
+					"fromEnv":  "env",
+					"fromFile": "file",
+					"fromSet":  "set",
+					"raw":      "a=b,c=d\nsecond line",
+				},
+				"nested": map[string]interface{}{
+					"deeply": map[string]interface{}{
+						"key": "env",
+					},
+				},
+			}))
+		})
+
+		It("ignores them without the prefix", func() {
+			values := renderValues(action.ChartRenderOptions{
+				ValuesSets: []string{"app.fromSet=set"},
+			})
+
+			Expect(values).To(Equal(map[string]interface{}{
+				"app": map[string]interface{}{
+					"fromSet": "set",
+				},
+			}))
+		})
+
+		It("takes them only from the passed environment", func() {
+			GinkgoT().Setenv("TEST_VALUES_app__fromProcess", "process")
+
+			values := renderValues(action.ChartRenderOptions{
+				ValuesEnvPrefix: "TEST_VALUES_",
+			})
+
+			Expect(values).To(HaveKey("app"))
+			Expect(values["app"]).NotTo(HaveKey("fromProcess"))
+		})
+	})
+
+	Context("with JSON and literal --set values", func() {
+		var (
+			ctx      context.Context
+			tmpDir   string
+			chartDir string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n")
+		})
+
+		DescribeTable("keeps the values as passed",
+			func(opts action.ChartRenderOptions, expected map[string]interface{}) {
+				opts.ChartDirPath = chartDir
+				opts.LogColorMode = action.LogColorModeOff
+				opts.OutputFilePath = filepath.Join(tmpDir, "values.out.yaml")
+				opts.ShowValues = true
+
+				Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+				data, err := os.ReadFile(opts.OutputFilePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				values := map[string]interface{}{}
+				Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+				Expect(values).To(Equal(expected))
+			},
+			Entry("with nested arrays in JSON",
+				action.ChartRenderOptions{ValuesJSONSets: []string{`config={"a":[1,[2,3]],"b":[{"c":true,"d":null}]}`}},
+				map[string]interface{}{
+					"config": map[string]interface{}{
+						"a": []interface{}{float64(1), []interface{}{float64(2), float64(3)}},
+						"b": []interface{}{map[string]interface{}{"c": true, "d": nil}},
+					},
+				},
+			),
+			Entry("with commas and backslashes in JSON strings",
+				action.ChartRenderOptions{ValuesJSONSets: []string{`app.path="C:\\data,backup"`, `app.list=["a,b","c\\d"]`}},
+				map[string]interface{}{
+					"app": map[string]interface{}{
+						"path": `C:\data,backup`,
+						"list": []interface{}{"a,b", `c\d`},
+					},
+				},
+			),
+			Entry("with JSON replacing --set values of the same key",
+				action.ChartRenderOptions{ValuesJSONSets: []string{`app={"replicas":3}`}, ValuesSets: []string{"app.name=web"}},
+				map[string]interface{}{
+					"app": map[string]interface{}{"replicas": float64(3), "name": "web"},
+				},
+			),
+			Entry("with commas and equal signs in literal values",
+				action.ChartRenderOptions{ValuesLiteralSets: []string{"password=a,b=c"}},
+				map[string]interface{}{"password": "a,b=c"},
+			),
+			Entry("with backslashes in literal values",
+				action.ChartRenderOptions{ValuesLiteralSets: []string{`app.path=C:\data\n\,x`}},
+				map[string]interface{}{
+					"app": map[string]interface{}{"path": `C:\data\n\,x`},
+				},
+			),
+			Entry("with braces and brackets in literal values",
+				action.ChartRenderOptions{ValuesLiteralSets: []string{"list={1,2}", "index=a[0]"}},
+				map[string]interface{}{"list": "{1,2}", "index": "a[0]"},
+			),
+			Entry("with literal values overriding --set values",
+				action.ChartRenderOptions{ValuesLiteralSets: []string{"app.password=x,y"}, ValuesSets: []string{"app.password=plain"}},
+				map[string]interface{}{
+					"app": map[string]interface{}{"password": "x,y"},
+				},
+			),
+		)
+	})
+
+	Context("with concurrent renders", func() {
+		It("renders each chart with its own options", func() {
+			ctx := context.Background()
+			tmpDir := GinkgoT().TempDir()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				chartDir := filepath.Join(tmpDir, fmt.Sprintf("chart-%d", i))
+				writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nversion: 0.1.0\n")
+				writeFile(filepath.Join(chartDir, "values.yaml"), fmt.Sprintf("index: %d\n", i))
+				writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  name: {{ .Chart.Name | quote }}
+  appVersion: {{ .Chart.AppVersion | quote }}
+  index: {{ .Values.index | quote }}
+  set: {{ .Values.set | quote }}
+`)
+
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					outputPath := filepath.Join(tmpDir, fmt.Sprintf("chart-%d.out.yaml", i))
+					Expect(action.ChartRender(ctx, action.ChartRenderOptions{
+						ChartAppVersion:  fmt.Sprintf("1.%d", i),
+						ChartDirPath:     chartDir,
+						DefaultChartName: fmt.Sprintf("chart-%d", i),
+						LogColorMode:     action.LogColorModeOff,
+						OutputFilePath:   outputPath,
+						ValuesSets:       []string{fmt.Sprintf("set=%d", i)},
+					})).To(Succeed())
+
+					data, err := os.ReadFile(outputPath)
+					Expect(err).NotTo(HaveOccurred())
+
+					var configMap struct {
+						Data map[string]string `json:"data"`
+					}
+					Expect(yaml.Unmarshal(data, &configMap)).To(Succeed())
+					Expect(configMap.Data).To(Equal(map[string]string{
+						"name":       fmt.Sprintf("chart-%d", i),
+						"appVersion": fmt.Sprintf("1.%d", i),
+						"index":      fmt.Sprint(i),
+						"set":        fmt.Sprint(i),
+					}))
+				}()
+			}
+			wg.Wait()
+		})
+	})
+
+	Context("with different kube contexts", func() {
+		var (
+			ctx               context.Context
+			tmpDir            string
+			chartDir          string
+			kubeConfigPath    string
+			discoveryCacheDir string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+			kubeConfigPath = filepath.Join(tmpDir, "kubeconfig")
+			discoveryCacheDir = filepath.Join(tmpDir, "cache")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  alpha: {{ .Capabilities.APIVersions.Has "alpha.example.com/v1" | quote }}
+  beta: {{ .Capabilities.APIVersions.Has "beta.example.com/v1" | quote }}
+`)
+
+			alphaServer := fake.NewAPIServer(append(fake.APIResources, &metav1.APIResourceList{
+				GroupVersion: "alpha.example.com/v1",
+				APIResources: []metav1.APIResource{{Name: "alphas", Kind: "Alpha", Namespaced: true}},
+			}))
+			DeferCleanup(alphaServer.Close)
+
+			betaServer := fake.NewAPIServer(append(fake.APIResources, &metav1.APIResourceList{
+				GroupVersion: "beta.example.com/v1",
+				APIResources: []metav1.APIResource{{Name: "betas", Kind: "Beta", Namespaced: true}},
+			}))
+			DeferCleanup(betaServer.Close)
+
+			writeFile(kubeConfigPath, fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: alpha
+  cluster: {server: %q}
+- name: beta
+  cluster: {server: %q}
+users:
+- name: test
+contexts:
+- name: alpha
+  context: {cluster: alpha, user: test}
+- name: beta
+  context: {cluster: beta, user: test}
+current-context: alpha
+`, alphaServer.URL, betaServer.URL))
+		})
+
+		render := func(kubeContext string) map[string]string {
+			outputPath := filepath.Join(tmpDir, kubeContext+".out.yaml")
+			Expect(action.ChartRender(ctx, action.ChartRenderOptions{
+				ChartDirPath:          chartDir,
+				KubeConfigPaths:       []string{kubeConfigPath},
+				KubeContext:           kubeContext,
+				KubeDiscoveryCacheDir: discoveryCacheDir,
+				LogColorMode:          action.LogColorModeOff,
+				OutputFilePath:        outputPath,
+				ReleaseName:           "app",
+				ReleaseNamespace:      "app-ns",
+				Remote:                true,
+			})).To(Succeed())
+
+			data, err := os.ReadFile(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			var configMap struct {
+				Data map[string]string `json:"data"`
+			}
+			Expect(yaml.Unmarshal(data, &configMap)).To(Succeed())
+
+			return configMap.Data
+		}
+
+		It("keeps the discovery of each context in its own cache on concurrent renders", func() {
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				results = map[string]map[string]string{}
+			)
+
+			for _, kubeContext := range []string{"alpha", "beta"} {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					data := render(kubeContext)
+
+					mu.Lock()
+					defer mu.Unlock()
+
+					results[kubeContext] = data
+				}()
+			}
+			wg.Wait()
+
+			Expect(results["alpha"]).To(Equal(map[string]string{"alpha": "true", "beta": "false"}))
+			Expect(results["beta"]).To(Equal(map[string]string{"alpha": "false", "beta": "true"}))
+
+			cachedGroupsPaths, err := filepath.Glob(filepath.Join(discoveryCacheDir, "discovery", "*", "servergroups.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cachedGroupsPaths).To(HaveLen(2))
+
+			var cachedGroups [][]string
+			for _, path := range cachedGroupsPaths {
+				data, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+
+				var groupList metav1.APIGroupList
+				Expect(yaml.Unmarshal(data, &groupList)).To(Succeed())
+
+				var names []string
+				for _, group := range groupList.Groups {
+					if strings.HasSuffix(group.Name, ".example.com") {
+						names = append(names, group.Name)
+					}
+				}
+
+				cachedGroups = append(cachedGroups, names)
+			}
+
+			Expect(cachedGroups).To(ConsistOf(
+				[]string{"alpha.example.com"},
+				[]string{"beta.example.com"},
+			))
+		})
+	})
+
+	Context("with render cache", func() {
+		var (
+			ctx      context.Context
+			tmpDir   string
+			chartDir string
+			cacheDir string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+			cacheDir = filepath.Join(tmpDir, "cache")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+		})
+
+		render := func(opts action.ChartRenderOptions) string {
+			opts.ChartDirPath = chartDir
+			opts.LogColorMode = action.LogColorModeOff
+			opts.OutputFilePath = filepath.Join(tmpDir, "manifests.yaml")
+			opts.RenderCacheDir = cacheDir
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			return string(data)
+		}
+
+		cacheEntries := func() []string {
+			entries, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			return entries
+		}
+
+		It("caches deterministic charts privately", func() {
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  # no lookup here\n")
+
+			manifests := render(action.ChartRenderOptions{})
+			Expect(render(action.ChartRenderOptions{})).To(Equal(manifests))
+
+			Expect(cacheEntries()).To(HaveLen(1))
+
+			dirInfo, err := os.Stat(cacheDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirInfo.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+
+			entryInfo, err := os.Stat(cacheEntries()[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entryInfo.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+		})
+
+		DescribeTable("doesn't cache charts using non-deterministic functions",
+			func(template string, values string) {
+				writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), template)
+				writeFile(filepath.Join(chartDir, "values.yaml"), values)
+
+				manifests := render(action.ChartRenderOptions{})
+				Expect(render(action.ChartRenderOptions{})).NotTo(Equal(manifests))
+
+				Expect(cacheEntries()).To(BeEmpty())
+			},
+			Entry("in templates",
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  id: {{ randAlphaNum 16 | quote }}\n",
+				"",
+			),
+			Entry("in templates defined in other templates",
+				"{{- define \"id\" }}{{ uuidv4 }}{{ end -}}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  id: {{ include \"id\" . | quote }}\n",
+				"",
+			),
+			Entry("in values passed to tpl",
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  id: {{ tpl .Values.id . | quote }}\n",
+				"id: '{{ randNumeric 16 }}'\n",
+			),
+		)
+
+		It("doesn't cache charts using secret values", func() {
+			GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+			secretKey, err := action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			plainPath := filepath.Join(tmpDir, "secret-values.yaml")
+			writeFile(plainPath, "password: secret\n")
+			Expect(action.SecretValuesFileEncrypt(ctx, plainPath, action.SecretValuesFileEncryptOptions{
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: filepath.Join(chartDir, "secret-values.yaml"),
+				SecretKey:      secretKey,
+				SecretWorkDir:  tmpDir,
+			})).To(Succeed())
+
+			writeFile(filepath.Join(chartDir, "templates", "secret.yaml"), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\nstringData:\n  password: {{ .Values.password }}\n")
+
+			Expect(render(action.ChartRenderOptions{SecretKey: secretKey, SecretWorkDir: tmpDir})).To(ContainSubstring("password: secret"))
+			Expect(cacheEntries()).To(BeEmpty())
+		})
+	})
+
+	Context("with partially encrypted secret values", func() {
+		var (
+			ctx       context.Context
+			tmpDir    string
+			chartDir  string
+			secretKey string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+			GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  user: {{ .Values.db.user }}\n  password: {{ .Values.db.password }}\n  port: {{ .Values.db.port | quote }}\n")
+
+			var err error
+			secretKey, err = action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			plainPath := filepath.Join(tmpDir, "secret-values.yaml")
+			writeFile(plainPath, "db:\n  user: app\n  password: secret\n  port: 5432\n")
+			Expect(action.SecretValuesFileEncrypt(ctx, plainPath, action.SecretValuesFileEncryptOptions{
+				Keys:           []string{".db.password"},
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: filepath.Join(chartDir, "secret-values.yaml"),
+				SecretKey:      secretKey,
+				SecretWorkDir:  tmpDir,
+			})).To(Succeed())
+		})
+
+		render := func(opts action.ChartRenderOptions) string {
+			opts.ChartDirPath = chartDir
+			opts.LogColorMode = action.LogColorModeOff
+			opts.OutputFilePath = filepath.Join(tmpDir, "manifests.yaml")
+			opts.SecretWorkDir = tmpDir
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			return string(data)
+		}
+
+		expectDecrypted := func(manifests string) {
+			Expect(manifests).To(ContainSubstring("user: app"))
+			Expect(manifests).To(ContainSubstring("password: secret"))
+			Expect(manifests).To(ContainSubstring(`port: "5432"`))
+		}
+
+		It("decrypts only the encrypted values with the passed secret key", func() {
+			expectDecrypted(render(action.ChartRenderOptions{SecretKey: secretKey}))
+		})
+
+		It("decrypts only the encrypted values with the secret key from the environment", func() {
+			GinkgoT().Setenv("WERF_SECRET_KEY", secretKey)
+
+			expectDecrypted(render(action.ChartRenderOptions{}))
+		})
+
+		It("decrypts only the encrypted values after the secret key rotation", func() {
+			newSecretKey, err := action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(action.SecretKeyRotate(ctx, action.SecretKeyRotateOptions{
+				ChartDirPath:  chartDir,
+				LogColorMode:  action.LogColorModeOff,
+				NewSecretKey:  newSecretKey,
+				OldSecretKey:  secretKey,
+				SecretWorkDir: tmpDir,
+			})).To(Succeed())
+
+			data, err := os.ReadFile(filepath.Join(chartDir, "secret-values.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("user: app"))
+			Expect(string(data)).To(ContainSubstring("password: !encrypted "))
+
+			expectDecrypted(render(action.ChartRenderOptions{SecretKey: newSecretKey}))
+		})
+	})
+
+	Context("with custom secret values files", func() {
+		var (
+			ctx       context.Context
+			tmpDir    string
+			chartDir  string
+			secretKey string
+		)
+
+		encryptValues := func(plain, outputPath, key string) {
+			plainPath := filepath.Join(tmpDir, "plain-values.yaml")
+			writeFile(plainPath, plain)
+			Expect(action.SecretValuesFileEncrypt(ctx, plainPath, action.SecretValuesFileEncryptOptions{
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: outputPath,
+				SecretKey:      key,
+				SecretWorkDir:  tmpDir,
+			})).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			chartDir = filepath.Join(tmpDir, "chart")
+			GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "secret.yaml"), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\nstringData:\n  user: {{ .Values.user }}\n  password: {{ .Values.password }}\n")
+
+			var err error
+			secretKey, err = action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			encryptValues("user: default\npassword: default\n", filepath.Join(chartDir, "secret-values.yaml"), secretKey)
+			encryptValues("password: first\n", filepath.Join(tmpDir, "first.yaml"), secretKey)
+			encryptValues("password: second\n", filepath.Join(tmpDir, "second.yaml"), secretKey)
+		})
+
+		render := func(opts action.ChartRenderOptions) string {
+			opts.ChartDirPath = chartDir
+			opts.LogColorMode = action.LogColorModeOff
+			opts.OutputFilePath = filepath.Join(tmpDir, "manifests.yaml")
+			opts.SecretValuesPaths = []string{filepath.Join(tmpDir, "first.yaml"), filepath.Join(tmpDir, "second.yaml")}
+			opts.SecretWorkDir = tmpDir
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			return string(data)
+		}
+
+		It("decrypts them with the passed secret key, later files overriding earlier ones and the default one", func() {
+			manifests := render(action.ChartRenderOptions{SecretKey: secretKey})
+			Expect(manifests).To(ContainSubstring("user: default"))
+			Expect(manifests).To(ContainSubstring("password: second"))
+		})
+
+		It("decrypts them with the secret key from the environment", func() {
+			GinkgoT().Setenv("WERF_SECRET_KEY", secretKey)
+
+			manifests := render(action.ChartRenderOptions{})
+			Expect(manifests).To(ContainSubstring("user: default"))
+			Expect(manifests).To(ContainSubstring("password: second"))
+		})
+
+		It("uses them without the default secret values file if it is disabled", func() {
+			manifests := render(action.ChartRenderOptions{SecretKey: secretKey, DefaultSecretValuesDisable: true})
+			Expect(manifests).NotTo(ContainSubstring("user: default"))
+			Expect(manifests).To(ContainSubstring("password: second"))
+		})
+
+		It("doesn't leak them into the next render", func() {
+			render(action.ChartRenderOptions{SecretKey: secretKey})
+
+			opts := action.ChartRenderOptions{
+				ChartDirPath:   chartDir,
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: filepath.Join(tmpDir, "manifests.yaml"),
+				SecretKey:      secretKey,
+				SecretWorkDir:  tmpDir,
+			}
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("password: default"))
+		})
+	})
+
+	Context("with only some template files shown", func() {
+		var (
+			ctx      context.Context
+			tmpDir   string
+			chartDir string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tmpDir = GinkgoT().TempDir()
+			// Named differently from the chart, so that paths relative to the current directory differ
+			// from the ones tracked by Helm.
+			chartDir = filepath.Join(tmpDir, "app-chart")
+
+			writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+			writeFile(filepath.Join(chartDir, "templates", "app.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n")
+			writeFile(filepath.Join(chartDir, "templates", "other.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n")
+
+			prevDir, err := os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chdir(tmpDir)).To(Succeed())
+			DeferCleanup(os.Chdir, prevDir)
+		})
+
+		render := func(showOnlyFiles ...string) string {
+			opts := action.ChartRenderOptions{
+				ChartDirPath:   chartDir,
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: filepath.Join(tmpDir, "manifests.yaml"),
+				ShowOnlyFiles:  showOnlyFiles,
+			}
+			Expect(action.ChartRender(ctx, opts)).To(Succeed())
+
+			data, err := os.ReadFile(opts.OutputFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			return string(data)
+		}
+
+		DescribeTable("renders manifests only from the matching files",
+			func(file string) {
+				manifests := render(file)
+
+				Expect(manifests).To(ContainSubstring("# Source: chart/templates/app.yaml"))
+				Expect(manifests).NotTo(ContainSubstring("name: other"))
+			},
+			Entry("relative to the chart directory", "templates/app.yaml"),
+			Entry("relative to the current directory", "./app-chart/templates/app.yaml"),
+			Entry("glob relative to the current directory", "app-chart/templates/a*.yaml"),
+		)
+
+		It("renders manifests from absolute paths", func() {
+			Expect(render(filepath.Join(chartDir, "templates", "app.yaml"))).To(ContainSubstring("# Source: chart/templates/app.yaml"))
+		})
+
+		It("renders nothing instead of failing if no files match", func() {
+			Expect(render("templates/missing.yaml")).NotTo(ContainSubstring("kind: ConfigMap"))
+		})
+	})
+})
+
+func writeFile(path, content string) {
+	Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+}
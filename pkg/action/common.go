@@ -2,14 +2,16 @@ package action
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/alecthomas/chroma/v2"
@@ -20,12 +22,26 @@ import (
 	"github.com/gookit/color"
 	"github.com/samber/lo"
 	"github.com/xo/terminfo"
+	"golang.org/x/crypto/ssh/terminal"
 	"k8s.io/klog"
 	klog_v2 "k8s.io/klog/v2"
 
+	helmcli "github.com/werf/3p-helm/pkg/cli"
+	"github.com/werf/3p-helm/pkg/registry"
 	"github.com/werf/kubedog/pkg/display"
 	"github.com/werf/logboek"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/plan/resourceinfo"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/matcher"
+	"github.com/werf/nelm/internal/track"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+	nelmrelease "github.com/werf/nelm/pkg/release"
 )
 
 const (
@@ -36,6 +52,37 @@ const (
 
 var LogColorModes = []string{LogColorModeAuto, LogColorModeOff, LogColorModeOn}
 
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+var LogFormats = []string{LogFormatText, LogFormatJSON}
+
+const (
+	ProgressModeAuto        = "auto"
+	ProgressModeInteractive = "interactive"
+	ProgressModePlain       = "plain"
+	ProgressModeNone        = "none"
+)
+
+var ProgressModes = []string{ProgressModeAuto, ProgressModeInteractive, ProgressModePlain, ProgressModeNone}
+
+const (
+	ShowLogsUntilReady = string(track.ShowLogsUntilReady)
+	ShowLogsUntilEnd   = string(track.ShowLogsUntilEnd)
+)
+
+var ShowLogsUntilValues = []string{ShowLogsUntilReady, ShowLogsUntilEnd}
+
+const (
+	CRDsPolicySkip   = string(resource.CRDPolicySkip)
+	CRDsPolicyCreate = string(resource.CRDPolicyCreate)
+	CRDsPolicyUpdate = string(resource.CRDPolicyUpdate)
+)
+
+var CRDsPolicies = []string{CRDsPolicySkip, CRDsPolicyCreate, CRDsPolicyUpdate}
+
 const (
 	ReleaseStorageDriverDefault    = ""
 	ReleaseStorageDriverSecrets    = "secrets"
@@ -47,8 +94,12 @@ const (
 )
 
 const (
-	YamlOutputFormat = "yaml"
-	JsonOutputFormat = "json"
+	YamlOutputFormat    = "yaml"
+	JsonOutputFormat    = "json"
+	DotOutputFormat     = "dot"
+	MermaidOutputFormat = "mermaid"
+	TableOutputFormat   = "table"
+	TextOutputFormat    = "text"
 )
 
 const (
@@ -64,23 +115,77 @@ var LogLevels []string = lo.Map(log.Levels, func(lvl log.Level, _ int) string {
 	return string(lvl)
 })
 
+const (
+	ChartLogComponent   = string(log.ChartComponent)
+	KubeLogComponent    = string(log.KubeComponent)
+	PlanLogComponent    = string(log.PlanComponent)
+	ReleaseLogComponent = string(log.ReleaseComponent)
+	TrackLogComponent   = string(log.TrackComponent)
+)
+
+var LogComponents []string = lo.Map(log.Components, func(component log.Component, _ int) string {
+	return string(component)
+})
+
 const (
 	DefaultQPSLimit              = 30
 	DefaultBurstLimit            = 100
 	DefaultNetworkParallelism    = 30
+	DefaultParallelism           = 0
+	DefaultTrackParallelism      = 0
 	DefaultLocalKubeVersion      = "1.20.0"
 	DefaultProgressPrintInterval = 5 * time.Second
+	DefaultProgressMode          = ProgressModeAuto
+	DefaultShowLogsUntil         = ShowLogsUntilReady
+	DefaultLogTail               = 100
 	DefaultReleaseHistoryLimit   = 10
+	DefaultResourceSizeLimit     = 1024 * 1024
 	DefaultLogColorMode          = LogColorModeAuto
+	DefaultLogFormat             = LogFormatText
+	DefaultCRDsPolicy            = CRDsPolicyUpdate
+	DefaultDiffContextLines      = 3
+	DefaultInterruptGracePeriod  = 30 * time.Second
+	DefaultOperationRetries      = 3
+	DefaultOperationRetryBackoff = time.Second
+
+	DefaultProtectedContextsFileName = "protected-contexts"
 
 	StubReleaseName      = "stub-release"
 	StubReleaseNamespace = "stub-namespace"
 )
 
-var DefaultRegistryCredentialsPath = filepath.Join(homedir.Get(), ".docker", config.ConfigFileName)
+var (
+	DefaultRegistryCredentialsPath    = filepath.Join(homedir.Get(), ".docker", config.ConfigFileName)
+	DefaultChartProvenanceKeyringPath = filepath.Join(homedir.Get(), ".gnupg", "pubring.gpg")
+)
+
+// newHelmSettings returns new Helm settings for the action, so that actions don't share the global
+// helm_v3.Settings.
+func newHelmSettings(ctx context.Context) *helmcli.EnvSettings {
+	helmSettings := helmcli.New()
+	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
+
+	return helmSettings
+}
+
+func newHelmRegistryClient(ctx context.Context, logStreamOut io.Writer, credentialsPath string, plainHTTP bool) (*registry.Client, error) {
+	clientOpts := []registry.ClientOption{
+		registry.ClientOptDebug(log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))),
+		registry.ClientOptWriter(logStreamOut),
+		registry.ClientOptCredentialsFile(credentialsPath),
+	}
+
+	if plainHTTP {
+		clientOpts = append(
+			clientOpts,
+			registry.ClientOptPlainHTTP(),
+			// Without an HTTP client the registry client panics in plain HTTP mode.
+			registry.ClientOptHTTPClient(&http.Client{}),
+		)
+	}
 
-// TODO: now actions are not thread-safe due to use of globals in actions, also we need to check used original Helm codebase for thread-safety
-var actionLock sync.Mutex
+	return registry.NewClient(clientOpts...)
+}
 
 func initKubedog(ctx context.Context) error {
 	flag.CommandLine.Parse([]string{})
@@ -160,6 +265,18 @@ func stdoutPiped() (bool, error) {
 	return piped, nil
 }
 
+func applyCRDsPolicyDefault(policy string) (string, error) {
+	if policy == "" {
+		return DefaultCRDsPolicy, nil
+	}
+
+	if !lo.Contains(CRDsPolicies, policy) {
+		return "", fmt.Errorf("unknown CRDs policy %q, expected one of: %s", policy, strings.Join(CRDsPolicies, ", "))
+	}
+
+	return policy, nil
+}
+
 func applyLogColorModeDefault(mode string, outputToFile bool) string {
 	if mode == "" || mode == LogColorModeAuto {
 		piped, err := stdoutPiped()
@@ -250,3 +367,256 @@ var syntaxHighlightTheme = fmt.Sprintf(`
   <entry type="GenericSubheading" style="#3f7541"/>
 </style>
 `, syntaxHighlightThemeName)
+
+func checkReferences(ctx context.Context, resProcessor *resourceinfo.DeployableResourcesProcessor, clientFactory *kube.ClientFactory, strict bool) error {
+	var resources []*plan.ReferencedResource
+	for _, res := range resProcessor.DeployableHookResources() {
+		resources = append(resources, &plan.ReferencedResource{ResourceID: res.ResourceID, Unstructured: res.Unstructured()})
+	}
+
+	for _, res := range resProcessor.DeployableGeneralResources() {
+		resources = append(resources, &plan.ReferencedResource{ResourceID: res.ResourceID, Unstructured: res.Unstructured()})
+	}
+
+	if err := plan.CheckReferences(ctx, resources, clientFactory.KubeClient(), clientFactory.Mapper(), plan.CheckReferencesOptions{
+		Strict: strict,
+	}); err != nil {
+		return fmt.Errorf("check references: %w", err)
+	}
+
+	return nil
+}
+
+func sanitizeReleaseName(ctx context.Context, releaseName string, autoSanitize bool) (string, error) {
+	sanitizedName := nelmrelease.SanitizeName(releaseName, nelmrelease.SanitizeNameOptions{})
+	if sanitizedName == releaseName {
+		return releaseName, nil
+	}
+
+	if !autoSanitize {
+		return "", fmt.Errorf("release name %q is not valid, the sanitized name would be %q", releaseName, sanitizedName)
+	}
+
+	log.Default.Info(ctx, "Sanitized release name %q to %q", releaseName, sanitizedName)
+
+	return sanitizedName, nil
+}
+
+// Aliased, so that the plan execution progress can be received by library users.
+type (
+	ProgressReporter  = plan.ProgressReporter
+	OperationProgress = plan.OperationProgress
+	StageProgress     = plan.StageProgress
+	ExecutionProgress = plan.ExecutionProgress
+
+	OperationStructuredID = operation.StructuredID
+)
+
+// NewLogProgressReporter returns a ProgressReporter which logs "12/87 operations done" lines.
+func NewLogProgressReporter() ProgressReporter {
+	return plan.NewLogProgressReporter()
+}
+
+// ParseOperationID parses an operation ID, as found in the plan, progress events and reports, into
+// its structured form.
+func ParseOperationID(opID string) (OperationStructuredID, error) {
+	return operation.ParseStructuredID(opID)
+}
+
+// ConfirmFunc asks the user to confirm a dangerous action, like deploying to a protected context or
+// applying the planned changes in interactive mode.
+type ConfirmFunc func(ctx context.Context, prompt string) (confirmed bool, err error)
+
+// TerminalConfirm asks for confirmation on stdin, accepting "y" or "yes". Declines by default.
+func TerminalConfirm(ctx context.Context, prompt string) (bool, error) {
+	return util.Confirm(os.Stdin, os.Stderr, prompt, false)
+}
+
+// askConfirmation uses TerminalConfirm if no confirm func specified. Fails instead of waiting for
+// an answer forever if stdin is not a terminal.
+func askConfirmation(ctx context.Context, confirmFunc ConfirmFunc, prompt string) (bool, error) {
+	if confirmFunc == nil {
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return false, fmt.Errorf("no terminal to ask for confirmation")
+		}
+
+		confirmFunc = TerminalConfirm
+	}
+
+	confirmed, err := confirmFunc(ctx, prompt)
+	if err != nil {
+		return false, fmt.Errorf("ask for confirmation: %w", err)
+	}
+
+	return confirmed, nil
+}
+
+type protectedContextCheckOptions struct {
+	ConfirmFunc      ConfirmFunc
+	Confirmed        bool
+	KubeContext      string
+	Patterns         []string
+	PatternsFilePath string
+}
+
+func checkProtectedContext(ctx context.Context, kubeConfig *kube.KubeConfig, opts protectedContextCheckOptions) error {
+	patterns := opts.Patterns
+
+	patternsFilePath := opts.PatternsFilePath
+	if patternsFilePath == "" {
+		if userConfigDir, err := os.UserConfigDir(); err == nil {
+			patternsFilePath = filepath.Join(userConfigDir, strings.ToLower(common.Brand), DefaultProtectedContextsFileName)
+		}
+	}
+
+	if patternsFilePath != "" {
+		if filePatterns, err := kube.LoadProtectedContextPatterns(patternsFilePath); err == nil {
+			patterns = append(patterns, filePatterns...)
+		} else if opts.PatternsFilePath != "" || !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("load protected contexts: %w", err)
+		}
+	}
+
+	contextName := opts.KubeContext
+	if contextName == "" && kubeConfig.RawConfig != nil {
+		contextName = kubeConfig.RawConfig.CurrentContext
+	}
+
+	pattern, matched := kube.MatchProtectedContext(contextName, kubeConfig.RestConfig.Host, patterns)
+	if !matched {
+		return nil
+	}
+
+	target := fmt.Sprintf("kube context %q (cluster %q) matches protected context pattern %q", contextName, kubeConfig.RestConfig.Host, pattern)
+
+	if opts.Confirmed {
+		log.Default.Warn(ctx, "Proceeding, since confirmed in advance that %s", target)
+		return nil
+	}
+
+	if confirmed, err := askConfirmation(ctx, opts.ConfirmFunc, util.Capitalize(target)+". Continue?"); err != nil {
+		return fmt.Errorf("%s, confirmation required: %w", target, err)
+	} else if !confirmed {
+		return fmt.Errorf("%s, not confirmed", target)
+	}
+
+	return nil
+}
+
+// splitPrevReleaseResources returns general resources of the previous release which pass the
+// resource filter, and hook and general resources of the previous release which don't. Resources
+// filtered out are left untouched in the cluster and are carried forward to the new release as they
+// are, so that the next full deploy doesn't consider them new or delete them.
+func splitPrevReleaseResources(prevRelease *release.Release, filter *matcher.ResourceFilter) (generalResources []*resource.GeneralResource, excludedHookResources []*resource.HookResource, excludedGeneralResources []*resource.GeneralResource) {
+	excludedHookResources = lo.Reject(prevRelease.HookResources(), func(res *resource.HookResource, _ int) bool {
+		return filter.Match(res.ResourceID, res.Unstructured().GetLabels())
+	})
+
+	generalResources, excludedGeneralResources = lo.FilterReject(prevRelease.GeneralResources(), func(res *resource.GeneralResource, _ int) bool {
+		return filter.Match(res.ResourceID, res.Unstructured().GetLabels())
+	})
+
+	return generalResources, excludedHookResources, excludedGeneralResources
+}
+
+// saveExecutedPlanGraph saves the plan graph again after the execution, so that it shows statuses of
+// operations. If the execution failed, the graph is saved to failurePath for debugging, unless the
+// path is specified explicitly.
+func saveExecutedPlanGraph(ctx context.Context, p *plan.Plan, path, failurePath string, executionFailed bool) error {
+	if path == "" {
+		if !executionFailed || failurePath == "" {
+			return nil
+		}
+
+		path = failurePath
+	}
+
+	if err := p.SaveDOT(path); err != nil {
+		return err
+	}
+
+	if executionFailed {
+		log.Default.Warn(ctx, "Graph of the failed plan saved to %q for debugging", path)
+	}
+
+	return nil
+}
+
+// A pending last release is left behind by a deploy that was killed before it could mark the release
+// failed. The release lock is held at this point, so no other deploy of the release is running, but
+// the lock might have been lost by a very slow deploy, so only releases pending for longer than the
+// TTL are repaired.
+func repairPendingRelease(ctx context.Context, history *release.History, rel *release.Release, ttl time.Duration) error {
+	if !rel.Pending() {
+		return nil
+	}
+
+	pendingFor := time.Since(rel.LastDeployed()).Round(time.Second)
+
+	if ttl <= 0 {
+		log.Default.Warn(ctx, "Warning: revision %d of release %q (namespace: %q) is stuck in status %q for %s, likely its deploy was interrupted. Pass --pending-release-ttl to mark such revisions failed automatically", rel.Revision(), rel.Name(), rel.Namespace(), rel.Status(), pendingFor)
+		return nil
+	} else if pendingFor < ttl {
+		log.Default.Warn(ctx, "Warning: revision %d of release %q (namespace: %q) is in status %q for %s, which is less than the pending release TTL %s, not marking it failed", rel.Revision(), rel.Name(), rel.Namespace(), rel.Status(), pendingFor, ttl)
+		return nil
+	}
+
+	prevStatus := rel.Status()
+
+	rel.Fail()
+
+	if err := history.UpdateRelease(ctx, rel); err != nil {
+		return fmt.Errorf("mark pending release revision %d as failed: %w", rel.Revision(), err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Yellow}.Render("Repaired pending release")+" %q (namespace: %q): revision %d in status %q for %s marked as failed", rel.Name(), rel.Namespace(), rel.Revision(), prevStatus, pendingFor)
+
+	return nil
+}
+
+// ActionTimeoutError is returned, wrapping the actual error, when the whole action didn't finish
+// within its Timeout option. Unlike track timeouts of separate resources, it means the action was
+// stopped the same way as if it was interrupted.
+type ActionTimeoutError struct {
+	Action  string
+	Timeout time.Duration
+}
+
+func (e *ActionTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Action, e.Timeout)
+}
+
+// withActionTimeout cancels the context with ActionTimeoutError as the cause once the timeout
+// expires. No limit if the timeout is 0.
+func withActionTimeout(ctx context.Context, action string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, &ActionTimeoutError{
+		Action:  action,
+		Timeout: timeout,
+	})
+}
+
+func actionTimedOut(ctx context.Context) (*ActionTimeoutError, bool) {
+	var timeoutErr *ActionTimeoutError
+	if !errors.As(context.Cause(ctx), &timeoutErr) {
+		return nil, false
+	}
+
+	return timeoutErr, true
+}
+
+// wrapActionTimeoutErr makes it clear that the action failed because it timed out as a whole.
+func wrapActionTimeoutErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if timeoutErr, timedOut := actionTimedOut(ctx); timedOut {
+		return fmt.Errorf("%w: %w", timeoutErr, err)
+	}
+
+	return err
+}
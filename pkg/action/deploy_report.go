@@ -0,0 +1,307 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	helmrelease "github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/util"
+)
+
+type DeployReportResourceOutcome string
+
+const (
+	DeployReportResourceOutcomeCreated   DeployReportResourceOutcome = "created"
+	DeployReportResourceOutcomeUpdated   DeployReportResourceOutcome = "updated"
+	DeployReportResourceOutcomeRecreated DeployReportResourceOutcome = "recreated"
+	DeployReportResourceOutcomeDeleted   DeployReportResourceOutcome = "deleted"
+	DeployReportResourceOutcomeUnchanged DeployReportResourceOutcome = "unchanged"
+	DeployReportResourceOutcomeFailed    DeployReportResourceOutcome = "failed"
+	// The deploy failed or was interrupted before the resource was changed.
+	DeployReportResourceOutcomeCanceled DeployReportResourceOutcome = "canceled"
+)
+
+type DeployReportHookResult string
+
+const (
+	DeployReportHookResultSucceeded DeployReportHookResult = "succeeded"
+	DeployReportHookResultFailed    DeployReportHookResult = "failed"
+	DeployReportHookResultCanceled  DeployReportHookResult = "canceled"
+)
+
+// DeployReport describes what the release install, rollback or uninstall did. It's produced even if
+// the action failed, with whatever was done by then. Saved to a file with --save-report.
+type DeployReport struct {
+	Version          int                     `json:"version,omitempty"`
+	Release          string                  `json:"release,omitempty"`
+	Namespace        string                  `json:"namespace,omitempty"`
+	Revision         int                     `json:"revision,omitempty"`
+	PreviousRevision int                     `json:"previousRevision,omitempty"`
+	Status           helmrelease.Status      `json:"status,omitempty"`
+	Description      string                  `json:"description,omitempty"`
+	StartedAt        time.Time               `json:"startedAt"`
+	Duration         string                  `json:"duration,omitempty"`
+	Resources        []*DeployReportResource `json:"resources,omitempty"`
+	Hooks            []*DeployReportHook     `json:"hooks,omitempty"`
+
+	CompletedOperations    []string                          `json:"completedOperations,omitempty"`
+	CanceledOperations     []string                          `json:"canceledOperations,omitempty"`
+	FailedOperations       []string                          `json:"failedOperations,omitempty"`
+	DegradedOperations     map[string]string                 `json:"degradedOperations,omitempty"`
+	ManifestHashes         map[string]string                 `json:"manifestHashes,omitempty"`
+	OperationsIDs          map[string]operation.StructuredID `json:"operationsIDs,omitempty"`
+	StabilizationDurations map[string]string                 `json:"stabilizationDurations,omitempty"`
+}
+
+type DeployReportResource struct {
+	Group     string                      `json:"group,omitempty"`
+	Version   string                      `json:"version,omitempty"`
+	Kind      string                      `json:"kind"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Name      string                      `json:"name"`
+	Outcome   DeployReportResourceOutcome `json:"outcome"`
+	// How long it took to change the resource and wait for it, if anything was done to it.
+	Duration string `json:"duration,omitempty"`
+}
+
+type DeployReportHook struct {
+	Group     string                 `json:"group,omitempty"`
+	Version   string                 `json:"version,omitempty"`
+	Kind      string                 `json:"kind"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Name      string                 `json:"name"`
+	Result    DeployReportHookResult `json:"result"`
+	Duration  string                 `json:"duration,omitempty"`
+}
+
+func (r *DeployReport) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling report: %w", err)
+	}
+
+	return data, nil
+}
+
+func (r *DeployReport) Save(path string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return fmt.Errorf("error constructing report JSON: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing report file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// newFailedDeployReport is for actions which failed before anything was deployed, so that the
+// report is produced anyway. The error becomes the description.
+func newFailedDeployReport(releaseName, releaseNamespace string, startedAt time.Time, err error) *DeployReport {
+	return &DeployReport{
+		Version:     2,
+		Release:     releaseName,
+		Namespace:   releaseNamespace,
+		Status:      helmrelease.StatusFailed,
+		Description: err.Error(),
+		StartedAt:   startedAt,
+		Duration:    time.Since(startedAt).Round(time.Millisecond).String(),
+	}
+}
+
+type resourceOperations struct {
+	resID     *id.ResourceID
+	completed []operation.Operation
+	failed    []operation.Operation
+	canceled  []operation.Operation
+}
+
+// Operations of the report grouped by the resource they work with.
+func (r *report) resourcesOperations() map[string]*resourceOperations {
+	result := map[string]*resourceOperations{}
+
+	add := func(ops []operation.Operation, appendTo func(resOps *resourceOperations, op operation.Operation)) {
+		for _, op := range ops {
+			resOp, ok := op.(interface{ ResourceID() *id.ResourceID })
+			if !ok {
+				continue
+			}
+
+			resID := resOp.ResourceID()
+			if _, found := result[resID.ID()]; !found {
+				result[resID.ID()] = &resourceOperations{resID: resID}
+			}
+
+			appendTo(result[resID.ID()], op)
+		}
+	}
+
+	add(r.completedOps, func(resOps *resourceOperations, op operation.Operation) {
+		resOps.completed = append(resOps.completed, op)
+	})
+	add(r.failedOps, func(resOps *resourceOperations, op operation.Operation) {
+		resOps.failed = append(resOps.failed, op)
+	})
+	add(r.canceledOps, func(resOps *resourceOperations, op operation.Operation) {
+		resOps.canceled = append(resOps.canceled, op)
+	})
+
+	return result
+}
+
+func (r *report) resources() []*DeployReportResource {
+	resourcesOps := r.resourcesOperations()
+
+	hookIDs := map[string]bool{}
+	for _, hook := range r.release.HookResources() {
+		hookIDs[hook.ID()] = true
+	}
+
+	var result []*DeployReportResource
+	addResource := func(resID *id.ResourceID, resOps *resourceOperations) {
+		gvk := resID.GroupVersionKind()
+
+		res := &DeployReportResource{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: resID.Namespace(),
+			Name:      resID.Name(),
+			Outcome:   DeployReportResourceOutcomeUnchanged,
+		}
+
+		if resOps != nil {
+			res.Outcome = resourceOutcome(resOps)
+			res.Duration = r.operationsDuration(resOps)
+		}
+
+		result = append(result, res)
+	}
+
+	for _, res := range r.release.GeneralResources() {
+		addResource(res.ResourceID, resourcesOps[res.ID()])
+		delete(resourcesOps, res.ID())
+	}
+
+	// Resources which are no longer in the release, but were deleted, failed to be deleted, etc.
+	for resID, resOps := range resourcesOps {
+		if !hookIDs[resID] {
+			addResource(resOps.resID, resOps)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return deployReportResourceKey(result[i].Kind, result[i].Namespace, result[i].Name) < deployReportResourceKey(result[j].Kind, result[j].Namespace, result[j].Name)
+	})
+
+	return result
+}
+
+func (r *report) hooks() []*DeployReportHook {
+	resourcesOps := r.resourcesOperations()
+
+	var result []*DeployReportHook
+	for _, hook := range r.release.HookResources() {
+		resOps, found := resourcesOps[hook.ID()]
+		if !found {
+			continue
+		}
+
+		gvk := hook.GroupVersionKind()
+
+		result = append(result, &DeployReportHook{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: hook.Namespace(),
+			Name:      hook.Name(),
+			Result:    hookResult(resOps),
+			Duration:  r.operationsDuration(resOps),
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return deployReportResourceKey(result[i].Kind, result[i].Namespace, result[i].Name) < deployReportResourceKey(result[j].Kind, result[j].Namespace, result[j].Name)
+	})
+
+	return result
+}
+
+// From the first start to the last finish of the operations.
+func (r *report) operationsDuration(resOps *resourceOperations) string {
+	if r.plan == nil {
+		return ""
+	}
+
+	var started, finished time.Time
+	for _, ops := range [][]operation.Operation{resOps.completed, resOps.failed} {
+		for _, op := range ops {
+			timing, found := r.plan.OperationTiming(op.ID())
+			if !found || timing.Finished.IsZero() {
+				continue
+			}
+
+			if started.IsZero() || timing.Started.Before(started) {
+				started = timing.Started
+			}
+
+			if timing.Finished.After(finished) {
+				finished = timing.Finished
+			}
+		}
+	}
+
+	if started.IsZero() {
+		return ""
+	}
+
+	return finished.Sub(started).Round(time.Millisecond).String()
+}
+
+// If the resource was changed several times, e.g. deleted and then created again, the most
+// significant change is reported.
+func resourceOutcome(resOps *resourceOperations) DeployReportResourceOutcome {
+	if len(resOps.failed) > 0 {
+		return DeployReportResourceOutcomeFailed
+	}
+
+	types := map[operation.Type]bool{}
+	for _, op := range resOps.completed {
+		types[op.Type()] = true
+	}
+
+	switch {
+	case types[operation.TypeRecreateResourceOperation]:
+		return DeployReportResourceOutcomeRecreated
+	case types[operation.TypeCreateResourceOperation]:
+		return DeployReportResourceOutcomeCreated
+	case types[operation.TypeUpdateResourceOperation], types[operation.TypeApplyResourceOperation]:
+		return DeployReportResourceOutcomeUpdated
+	case types[operation.TypeDeleteResourceOperation]:
+		return DeployReportResourceOutcomeDeleted
+	case len(resOps.canceled) > 0:
+		return DeployReportResourceOutcomeCanceled
+	}
+
+	return DeployReportResourceOutcomeUnchanged
+}
+
+func hookResult(resOps *resourceOperations) DeployReportHookResult {
+	switch {
+	case len(resOps.failed) > 0:
+		return DeployReportHookResultFailed
+	case len(resOps.canceled) > 0:
+		return DeployReportHookResultCanceled
+	}
+
+	return DeployReportHookResultSucceeded
+}
+
+func deployReportResourceKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
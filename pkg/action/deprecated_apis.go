@@ -0,0 +1,248 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	"github.com/werf/nelm/internal/chart"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	// The resource is from the last stored release revision.
+	DeprecatedAPISourceRelease = "release"
+	// The resource is from the newly rendered chart.
+	DeprecatedAPISourceChart = "chart"
+)
+
+// DeprecatedAPIUsage is a resource with an apiVersion which is deprecated or removed in the target
+// Kubernetes version.
+type DeprecatedAPIUsage struct {
+	Source                string `json:"source"`
+	Resource              string `json:"resource"`
+	APIVersion            string `json:"apiVersion"`
+	Kind                  string `json:"kind"`
+	ReplacementAPIVersion string `json:"replacementApiVersion,omitempty"`
+	DeprecatedIn          string `json:"deprecatedIn,omitempty"`
+	RemovedIn             string `json:"removedIn,omitempty"`
+	// The apiVersion is not served in the target version, so the resource can't be deployed or
+	// even read from the cluster anymore.
+	Removed bool `json:"removed"`
+}
+
+func (u *DeprecatedAPIUsage) String() string {
+	var msg string
+	switch {
+	case u.Removed && u.RemovedIn != "":
+		msg = fmt.Sprintf("apiVersion %q of %q is removed in Kubernetes %s", u.APIVersion, u.Kind, u.RemovedIn)
+	case u.Removed:
+		msg = fmt.Sprintf("apiVersion %q of %q is not served by the cluster", u.APIVersion, u.Kind)
+	default:
+		msg = fmt.Sprintf("apiVersion %q of %q is deprecated in Kubernetes %s and removed in %s", u.APIVersion, u.Kind, u.DeprecatedIn, u.RemovedIn)
+	}
+
+	if u.ReplacementAPIVersion != "" {
+		msg += fmt.Sprintf(", use %q instead", u.ReplacementAPIVersion)
+	}
+
+	return msg
+}
+
+// findDeprecatedAPIs checks the resources against the built-in table of deprecated apiVersions.
+// If the discovery is available, apiVersions not served by the cluster are reported as removed
+// too, unless their kinds are in skipKinds, e.g. because they are defined by CRDs which are not
+// deployed yet.
+func findDeprecatedAPIs(resources []*id.ResourceID, source string, targetKubeVersion *semver.Version, apis *apiDiscovery, skipKinds map[schema.GroupKind]bool) []*DeprecatedAPIUsage {
+	var usages []*DeprecatedAPIUsage
+	for _, res := range resources {
+		gvk := res.GroupVersionKind()
+
+		usage := &DeprecatedAPIUsage{
+			Source:     source,
+			Resource:   res.HumanID(),
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		}
+
+		if deprecatedAPI, found := resource.FindDeprecatedAPI(gvk, targetKubeVersion); found {
+			usage.DeprecatedIn = fmt.Sprintf("%d.%d", deprecatedAPI.DeprecatedIn.Major(), deprecatedAPI.DeprecatedIn.Minor())
+			usage.RemovedIn = fmt.Sprintf("%d.%d", deprecatedAPI.RemovedIn.Major(), deprecatedAPI.RemovedIn.Minor())
+			usage.Removed = deprecatedAPI.Removed(targetKubeVersion)
+
+			if !deprecatedAPI.Replacement.Empty() {
+				usage.ReplacementAPIVersion = deprecatedAPI.Replacement.String()
+			}
+
+			usages = append(usages, usage)
+
+			continue
+		}
+
+		if apis == nil || skipKinds[gvk.GroupKind()] {
+			continue
+		}
+
+		if served, err := apis.Served(gvk.GroupVersion(), gvk.Kind); err != nil || served {
+			continue
+		}
+
+		usage.Removed = true
+		if preferredGV, found := apis.PreferredGroupVersion(gvk.Group); found && preferredGV != gvk.GroupVersion() {
+			if served, err := apis.Served(preferredGV, gvk.Kind); err == nil && served {
+				usage.ReplacementAPIVersion = preferredGV.String()
+			}
+		}
+
+		usages = append(usages, usage)
+	}
+
+	return usages
+}
+
+func chartTreeDeprecatedAPIs(chartTree *chart.ChartTree, targetKubeVersion *semver.Version, apis *apiDiscovery) []*DeprecatedAPIUsage {
+	var (
+		resources []*id.ResourceID
+		unstructs []*unstructured.Unstructured
+	)
+	for _, res := range chartTree.StandaloneCRDs() {
+		resources = append(resources, res.ResourceID)
+		unstructs = append(unstructs, res.Unstructured())
+	}
+
+	for _, res := range chartTree.HookResources() {
+		resources = append(resources, res.ResourceID)
+		unstructs = append(unstructs, res.Unstructured())
+	}
+
+	for _, res := range chartTree.GeneralResources() {
+		resources = append(resources, res.ResourceID)
+		unstructs = append(unstructs, res.Unstructured())
+	}
+
+	return findDeprecatedAPIs(resources, DeprecatedAPISourceChart, targetKubeVersion, apis, crdKinds(unstructs))
+}
+
+// warnDeprecatedAPIs warns about resources of the chart with apiVersions deprecated in the version of
+// the cluster, or fails if failOnDeprecated. The deploy is not blocked if the check itself fails.
+func warnDeprecatedAPIs(ctx context.Context, chartTree *chart.ChartTree, discoveryClient discovery.DiscoveryInterface, failOnDeprecated bool) error {
+	kubeVersion, err := serverKubeVersion(discoveryClient)
+	if err != nil {
+		log.Default.Warn(ctx, "Unable to check for deprecated apiVersions: %s", err)
+		return nil
+	}
+
+	usages := chartTreeDeprecatedAPIs(chartTree, kubeVersion, newAPIDiscovery(discoveryClient))
+	if len(usages) == 0 {
+		return nil
+	}
+
+	for _, usage := range usages {
+		if failOnDeprecated {
+			log.Default.Error(ctx, "Resource %q: %s", usage.Resource, usage)
+		} else {
+			log.Default.Warn(ctx, "Resource %q: %s", usage.Resource, usage)
+		}
+	}
+
+	if failOnDeprecated {
+		return fmt.Errorf("%d resources use apiVersions deprecated or removed in Kubernetes %d.%d: %w", len(usages), kubeVersion.Major(), kubeVersion.Minor(), ErrDeprecatedAPIs)
+	}
+
+	return nil
+}
+
+// parseKubeVersion parses versions like "1.29", "v1.29.3" or "v1.29.3-gke.100".
+func parseKubeVersion(version string) (*semver.Version, error) {
+	v, err := semver.NewVersion(strings.TrimSpace(version))
+	if err != nil {
+		return nil, fmt.Errorf("parse kubernetes version %q: %w", version, err)
+	}
+
+	return v, nil
+}
+
+func serverKubeVersion(discoveryClient discovery.DiscoveryInterface) (*semver.Version, error) {
+	serverVersion, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("get kubernetes server version: %w", err)
+	}
+
+	return parseKubeVersion(serverVersion.GitVersion)
+}
+
+// crdKinds returns the kinds defined by the CRDs among the objects.
+func crdKinds(unstructs []*unstructured.Unstructured) map[schema.GroupKind]bool {
+	kinds := map[schema.GroupKind]bool{}
+	for _, unstruct := range unstructs {
+		if unstruct.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+
+		group, _, _ := unstructured.NestedString(unstruct.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(unstruct.Object, "spec", "names", "kind")
+		kinds[schema.GroupKind{Group: group, Kind: kind}] = true
+	}
+
+	return kinds
+}
+
+func newAPIDiscovery(discoveryClient discovery.DiscoveryInterface) *apiDiscovery {
+	return &apiDiscovery{
+		discoveryClient: discoveryClient,
+		servedKinds:     map[schema.GroupVersion]map[string]bool{},
+	}
+}
+
+// apiDiscovery caches which kinds are served by the cluster in which apiVersions.
+type apiDiscovery struct {
+	discoveryClient   discovery.DiscoveryInterface
+	servedKinds       map[schema.GroupVersion]map[string]bool
+	preferredVersions map[string]string
+}
+
+func (d *apiDiscovery) Served(gv schema.GroupVersion, kind string) (bool, error) {
+	if kinds, found := d.servedKinds[gv]; found {
+		return kinds[kind], nil
+	}
+
+	kinds := map[string]bool{}
+	if resourceList, err := d.discoveryClient.ServerResourcesForGroupVersion(gv.String()); err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	} else if err == nil {
+		for _, apiResource := range resourceList.APIResources {
+			kinds[apiResource.Kind] = true
+		}
+	}
+
+	d.servedKinds[gv] = kinds
+
+	return kinds[kind], nil
+}
+
+func (d *apiDiscovery) PreferredGroupVersion(group string) (schema.GroupVersion, bool) {
+	if d.preferredVersions == nil {
+		d.preferredVersions = map[string]string{}
+
+		if groups, err := d.discoveryClient.ServerGroups(); err == nil {
+			for _, g := range groups.Groups {
+				d.preferredVersions[g.Name] = g.PreferredVersion.Version
+			}
+		}
+	}
+
+	version, found := d.preferredVersions[group]
+	if !found || version == "" {
+		return schema.GroupVersion{}, false
+	}
+
+	return schema.GroupVersion{Group: group, Version: version}, true
+}
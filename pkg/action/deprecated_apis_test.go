@@ -0,0 +1,96 @@
+package action_test
+
+import (
+	"github.com/Masterminds/semver/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("deprecated apiVersions", func() {
+	resourceID := func(apiVersion, kind, name string) *id.ResourceID {
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		Expect(err).NotTo(HaveOccurred())
+
+		return id.NewResourceID(name, "app-ns", gv.WithKind(kind), id.ResourceIDOptions{})
+	}
+
+	discovery := func(resourceLists ...*metav1.APIResourceList) *discoveryfake.FakeDiscovery {
+		return &discoveryfake.FakeDiscovery{
+			Fake: &clienttesting.Fake{Resources: resourceLists},
+		}
+	}
+
+	It("reports deprecated and removed apiVersions from the built-in table", func() {
+		usages := action.FindDeprecatedAPIs([]*id.ResourceID{
+			resourceID("batch/v1beta1", "CronJob", "cleanup"),
+			resourceID("policy/v1beta1", "PodSecurityPolicy", "restricted"),
+			resourceID("autoscaling/v2beta2", "HorizontalPodAutoscaler", "api"),
+			resourceID("batch/v1", "Job", "migrate"),
+		}, action.DeprecatedAPISourceChart, semver.MustParse("1.25"), nil, nil)
+
+		Expect(usages).To(HaveLen(3))
+
+		Expect(*usages[0]).To(Equal(action.DeprecatedAPIUsage{
+			Source:                action.DeprecatedAPISourceChart,
+			Resource:              usages[0].Resource,
+			APIVersion:            "batch/v1beta1",
+			Kind:                  "CronJob",
+			ReplacementAPIVersion: "batch/v1",
+			DeprecatedIn:          "1.21",
+			RemovedIn:             "1.25",
+			Removed:               true,
+		}))
+		Expect(usages[0].String()).To(Equal(`apiVersion "batch/v1beta1" of "CronJob" is removed in Kubernetes 1.25, use "batch/v1" instead`))
+
+		Expect(usages[1].Removed).To(BeTrue())
+		Expect(usages[1].ReplacementAPIVersion).To(BeEmpty())
+		Expect(usages[1].String()).To(Equal(`apiVersion "policy/v1beta1" of "PodSecurityPolicy" is removed in Kubernetes 1.25`))
+
+		Expect(usages[2].Removed).To(BeFalse())
+		Expect(usages[2].String()).To(Equal(`apiVersion "autoscaling/v2beta2" of "HorizontalPodAutoscaler" is deprecated in Kubernetes 1.23 and removed in 1.26, use "autoscaling/v2" instead`))
+	})
+
+	It("reports apiVersions not served by the cluster, suggesting the preferred one", func() {
+		apis := action.NewAPIDiscovery(discovery(
+			&metav1.APIResourceList{GroupVersion: "example.com/v2", APIResources: []metav1.APIResource{{Kind: "Widget"}}},
+			&metav1.APIResourceList{GroupVersion: "example.com/v1beta1", APIResources: []metav1.APIResource{{Kind: "Gadget"}}},
+			&metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{{Kind: "ConfigMap"}}},
+		))
+
+		usages := action.FindDeprecatedAPIs([]*id.ResourceID{
+			resourceID("example.com/v1beta1", "Widget", "widget"),
+			resourceID("example.com/v1beta1", "Gadget", "gadget"),
+			resourceID("unknown.example.com/v1", "Thing", "thing"),
+			resourceID("v1", "ConfigMap", "config"),
+		}, action.DeprecatedAPISourceRelease, semver.MustParse("1.30"), apis, nil)
+
+		Expect(usages).To(HaveLen(2))
+
+		Expect(usages[0].Kind).To(Equal("Widget"))
+		Expect(usages[0].Source).To(Equal(action.DeprecatedAPISourceRelease))
+		Expect(usages[0].Removed).To(BeTrue())
+		Expect(usages[0].ReplacementAPIVersion).To(Equal("example.com/v2"))
+		Expect(usages[0].String()).To(Equal(`apiVersion "example.com/v1beta1" of "Widget" is not served by the cluster, use "example.com/v2" instead`))
+
+		Expect(usages[1].Kind).To(Equal("Thing"))
+		Expect(usages[1].Removed).To(BeTrue())
+		Expect(usages[1].ReplacementAPIVersion).To(BeEmpty())
+	})
+
+	It("doesn't report kinds defined by CRDs which are not deployed yet", func() {
+		usages := action.FindDeprecatedAPIs([]*id.ResourceID{
+			resourceID("example.com/v1", "Widget", "widget"),
+		}, action.DeprecatedAPISourceChart, semver.MustParse("1.30"), action.NewAPIDiscovery(discovery()), map[schema.GroupKind]bool{
+			{Group: "example.com", Kind: "Widget"}: true,
+		})
+
+		Expect(usages).To(BeEmpty())
+	})
+})
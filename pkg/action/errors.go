@@ -0,0 +1,124 @@
+package action
+
+import (
+	"errors"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+)
+
+// ErrDeployNotConfirmed is returned if the planned changes were not confirmed in interactive mode.
+var ErrDeployNotConfirmed = errors.New("deploy not confirmed")
+
+// ErrDeprecatedAPIs is returned if resources use deprecated or removed apiVersions and failing on
+// them is requested.
+var ErrDeprecatedAPIs = errors.New("deprecated apiVersions found")
+
+// Errors returned by actions are wrapped into one of these, so that the kind of failure can be
+// determined with errors.As, e.g. to choose the exit code. The messages are not changed.
+type (
+	// Invalid arguments, flags or options.
+	UsageError struct{ Err error }
+	// Failed to load the chart or values, or to render the templates.
+	TemplateError struct{ Err error }
+	// The cluster is not configured, can't be reached or rejects the credentials.
+	KubeConnectionError struct{ Err error }
+	// The cluster rejected changes to resources, e.g. by an admission webhook.
+	ApplyError struct{ Err error }
+	// Resources didn't become ready in time, or the whole action timed out.
+	ReadinessTimeoutError struct{ Err error }
+)
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+func (e *TemplateError) Error() string { return e.Err.Error() }
+func (e *TemplateError) Unwrap() error { return e.Err }
+
+func (e *KubeConnectionError) Error() string { return e.Err.Error() }
+func (e *KubeConnectionError) Unwrap() error { return e.Err }
+
+func (e *ApplyError) Error() string { return e.Err.Error() }
+func (e *ApplyError) Unwrap() error { return e.Err }
+
+func (e *ReadinessTimeoutError) Error() string { return e.Err.Error() }
+func (e *ReadinessTimeoutError) Unwrap() error { return e.Err }
+
+// classifyActionErr wraps errors of actions which deploy to the cluster by the kind of failure.
+// Timeouts take precedence, since other operations are usually canceled because of them.
+func classifyActionErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		actionTimeoutErr *ActionTimeoutError
+		trackTimeoutErr  *operation.TrackTimeoutError
+	)
+
+	switch {
+	case errors.As(err, &actionTimeoutErr), errors.As(err, &trackTimeoutErr):
+		return &ReadinessTimeoutError{Err: err}
+	case anyError(err, isApplyOperationError):
+		return &ApplyError{Err: err}
+	case kube.IsConnectionError(err):
+		return &KubeConnectionError{Err: err}
+	}
+
+	return err
+}
+
+func isApplyOperationError(err error) bool {
+	opErr, ok := err.(*plan.OperationError)
+	if !ok || kube.IsConnectionError(opErr.Unwrap()) {
+		return false
+	}
+
+	switch opErr.Operation.Type() {
+	case operation.TypeCreateResourceOperation,
+		operation.TypeRecreateResourceOperation,
+		operation.TypeUpdateResourceOperation,
+		operation.TypeApplyResourceOperation,
+		operation.TypeDeleteResourceOperation,
+		operation.TypeExtraPostCreateResourceOperation,
+		operation.TypeExtraPostRecreateResourceOperation,
+		operation.TypeExtraPostUpdateResourceOperation,
+		operation.TypeExtraPostApplyResourceOperation,
+		operation.TypeExtraPostDeleteResourceOperation:
+		return true
+	}
+
+	return false
+}
+
+// anyError checks every error in the tree instead of stopping at the first one of the type like
+// errors.As does, since several operations can fail at once.
+func anyError(err error, match func(err error) bool) bool {
+	if err == nil {
+		return false
+	}
+
+	if match(err) {
+		return true
+	}
+
+	switch e := err.(type) {
+	case interface{ WrappedErrors() []error }:
+		for _, err := range e.WrappedErrors() {
+			if anyError(err, match) {
+				return true
+			}
+		}
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if anyError(err, match) {
+				return true
+			}
+		}
+	case interface{ Unwrap() error }:
+		return anyError(e.Unwrap(), match)
+	}
+
+	return false
+}
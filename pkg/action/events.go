@@ -0,0 +1,54 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/release"
+)
+
+// newEventRecorder returns nil if events shouldn't be emitted. The involved object is specified as
+// "<Kind>[.<group>]/<name>" and must be in the release namespace, e.g. "Deployment.apps/myapp".
+func newEventRecorder(releaseName, releaseNamespace string, emitEvents bool, involvedObject string, clientFactory *kube.ClientFactory) (*release.EventRecorder, error) {
+	if !emitEvents {
+		return nil, nil
+	}
+
+	var opts release.EventRecorderOptions
+	if involvedObject != "" {
+		kind, name, found := strings.Cut(involvedObject, "/")
+		if !found || kind == "" || name == "" {
+			return nil, fmt.Errorf("invalid events involved object %q, expected <Kind>[.<group>]/<name>", involvedObject)
+		}
+
+		groupKind := schema.ParseGroupKind(kind)
+
+		mapping, err := clientFactory.Mapper().RESTMapping(groupKind)
+		if err != nil {
+			return nil, fmt.Errorf("get resource mapping for %q: %w", groupKind, err)
+		}
+
+		opts.InvolvedObject = &corev1.ObjectReference{
+			APIVersion: mapping.GroupVersionKind.GroupVersion().String(),
+			Kind:       mapping.GroupVersionKind.Kind,
+			Name:       name,
+			Namespace:  releaseNamespace,
+		}
+	}
+
+	return release.NewEventRecorder(releaseName, releaseNamespace, clientFactory.Static(), opts), nil
+}
+
+func operationsHumanIDs(ops []operation.Operation) []string {
+	var ids []string
+	for _, op := range ops {
+		ids = append(ids, op.HumanID())
+	}
+
+	return ids
+}
@@ -0,0 +1,50 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/release"
+)
+
+// SetReleaseDevelopInstall replaces the function the develop loop deploys with.
+func SetReleaseDevelopInstall(install func(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseInstallOptions) (*ReleaseInstallResultV1, error)) (restore func()) {
+	prev := releaseDevelopInstall
+	releaseDevelopInstall = install
+
+	return func() {
+		releaseDevelopInstall = prev
+	}
+}
+
+var BuildReleaseStatusResult = buildReleaseStatusResult
+
+// NewDeployReport builds the report of the executed plan from its worthy operations, like release
+// install does.
+func NewDeployReport(deployPlan *plan.Plan, rel *release.Release) (*DeployReport, error) {
+	completedOps, _, err := deployPlan.WorthyCompletedOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	canceledOps, _, err := deployPlan.WorthyCanceledOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	failedOps, _, err := deployPlan.WorthyFailedOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	return newReport(completedOps, canceledOps, failedOps, rel, reportOptions{StartedAt: time.Now(), Plan: deployPlan}).DeployReport(), nil
+}
+
+var SaveExecutedPlanGraph = saveExecutedPlanGraph
+
+var SplitPrevReleaseResources = splitPrevReleaseResources
+
+var FindDeprecatedAPIs = findDeprecatedAPIs
+
+var NewAPIDiscovery = newAPIDiscovery
@@ -0,0 +1,145 @@
+package action_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/storage"
+	"github.com/werf/3p-helm/pkg/storage/driver"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("operation IDs", func() {
+	It("are the same in the plan, progress events and the deploy report", func() {
+		ctx := action.SetupLogging(context.Background(), action.SilentLogLevel, action.SilentLogLevel)
+		cluster := fake.NewCluster(ctx)
+
+		history, err := release.NewHistory("app", "app-ns", storage.Init(driver.NewMemory()), release.HistoryOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rel, err := release.NewRelease("app", "app-ns", 1, nil, &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}}, nil, nil, "", release.ReleaseOptions{Mapper: cluster.Mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		deployPlan := plan.NewPlan()
+
+		pendingReleaseOp := operation.NewCreatePendingReleaseOperation(rel, common.DeployTypeInitial, history)
+		deployPlan.AddOperation(pendingReleaseOp)
+
+		var resourcesOps []operation.Operation
+		for _, manifest := range []string{
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, namespace: app-ns}}`,
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, namespace: app-ns}}`,
+		} {
+			unstruct := &unstructured.Unstructured{}
+			Expect(yaml.Unmarshal([]byte(manifest), &unstruct.Object)).To(Succeed())
+
+			resID := id.NewResourceIDFromUnstruct(unstruct, id.ResourceIDOptions{DefaultNamespace: "app-ns", Mapper: cluster.Mapper})
+			op := operation.NewCreateResourceOperation(resID, unstruct, cluster.KubeClient, operation.CreateResourceOperationOptions{})
+			deployPlan.AddStagedOperation(op, "main/"+plan.StageOpNameSuffixStart, "main/"+plan.StageOpNameSuffixEnd)
+			resourcesOps = append(resourcesOps, op)
+		}
+
+		succeedReleaseOp := operation.NewSucceedReleaseOperation(rel, history, operation.SucceedReleaseOperationOptions{})
+		deployPlan.AddOperation(succeedReleaseOp)
+
+		Expect(deployPlan.AddDependency(pendingReleaseOp.ID(), "main/"+plan.StageOpNameSuffixStart)).To(Succeed())
+		Expect(deployPlan.AddDependency("main/"+plan.StageOpNameSuffixEnd, succeedReleaseOp.ID())).To(Succeed())
+
+		reporter := &recordingProgressReporter{}
+		Expect(plan.NewPlanExecutor(deployPlan, plan.PlanExecutorOptions{ProgressReporter: reporter}).Execute(ctx)).To(Succeed())
+
+		planJSON, err := deployPlan.JSON()
+		Expect(err).NotTo(HaveOccurred())
+
+		var planDoc struct {
+			Operations []struct {
+				ID           string                       `json:"id"`
+				StructuredID action.OperationStructuredID `json:"structuredID"`
+			} `json:"operations"`
+		}
+		Expect(json.Unmarshal(planJSON, &planDoc)).To(Succeed())
+
+		planIDs := map[string]action.OperationStructuredID{}
+		for _, op := range planDoc.Operations {
+			planIDs[op.ID] = op.StructuredID
+		}
+
+		deployReport, err := action.NewDeployReport(deployPlan, rel)
+		Expect(err).NotTo(HaveOccurred())
+
+		reportJSON, err := json.Marshal(deployReport)
+		Expect(err).NotTo(HaveOccurred())
+
+		var reportDoc action.DeployReport
+		Expect(json.Unmarshal(reportJSON, &reportDoc)).To(Succeed())
+
+		expectedIDs := map[string]action.OperationStructuredID{
+			"create-pending-release/app-ns:app:1": {Type: "create-pending-release", Namespace: "app-ns", Name: "app", Qualifier: "1"},
+			"create/app-ns::ConfigMap:config":     {Type: "create", Kind: "ConfigMap", Namespace: "app-ns", Name: "config"},
+			"create/app-ns:apps:Deployment:api":   {Type: "create", Group: "apps", Kind: "Deployment", Namespace: "app-ns", Name: "api"},
+			"succeed-release/app-ns:app:1":        {Type: "succeed-release", Namespace: "app-ns", Name: "app", Qualifier: "1"},
+		}
+
+		Expect(reporter.completed).To(Equal(expectedIDs))
+		Expect(reporter.started).To(Equal(expectedIDs))
+
+		for opID, structuredID := range expectedIDs {
+			Expect(planIDs).To(HaveKeyWithValue(opID, structuredID))
+			Expect(structuredID.String()).To(Equal(opID))
+
+			parsedID, err := action.ParseOperationID(opID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsedID).To(Equal(structuredID))
+		}
+
+		for _, op := range resourcesOps {
+			Expect(reportDoc.OperationsIDs).To(HaveKeyWithValue(op.ID(), expectedIDs[op.ID()]))
+		}
+
+		for opID, structuredID := range reportDoc.OperationsIDs {
+			Expect(planIDs).To(HaveKeyWithValue(opID, structuredID))
+		}
+
+		Expect(lo.Keys(reportDoc.OperationsIDs)).To(ConsistOf(reportDoc.CompletedOperations))
+	})
+})
+
+type recordingProgressReporter struct {
+	started   map[string]action.OperationStructuredID
+	completed map[string]action.OperationStructuredID
+}
+
+func (r *recordingProgressReporter) OnOperationStart(ctx context.Context, progress action.OperationProgress) {
+	if r.started == nil {
+		r.started = map[string]action.OperationStructuredID{}
+	}
+
+	r.started[progress.OperationID] = progress.StructuredID
+}
+
+func (r *recordingProgressReporter) OnOperationComplete(ctx context.Context, progress action.OperationProgress) {
+	if r.completed == nil {
+		r.completed = map[string]action.OperationStructuredID{}
+	}
+
+	r.completed[progress.OperationID] = progress.StructuredID
+}
+
+func (r *recordingProgressReporter) OnOperationFailed(ctx context.Context, progress action.OperationProgress, err error) {
+}
+
+func (r *recordingProgressReporter) OnStageComplete(ctx context.Context, progress action.StageProgress) {
+}
@@ -0,0 +1,126 @@
+package action_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/internal/plan"
+	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("executed plan graph", func() {
+	var (
+		ctx         context.Context
+		dir         string
+		graphPath   string
+		failurePath string
+	)
+
+	BeforeEach(func() {
+		ctx = action.SetupLogging(context.Background(), action.SilentLogLevel, action.SilentLogLevel)
+		dir = GinkgoT().TempDir()
+		graphPath = filepath.Join(dir, "graph.dot")
+		failurePath = filepath.Join(dir, "failure-graph.dot")
+	})
+
+	executedPlan := func(fail bool) (*plan.Plan, error) {
+		p := plan.NewPlan()
+		p.AddOperation(&statusOperation{name: "succeeding"})
+		p.AddOperation(&statusOperation{name: "failing", fail: fail})
+
+		return p, plan.NewPlanExecutor(p, plan.PlanExecutorOptions{}).Execute(ctx)
+	}
+
+	readGraph := func(path string) string {
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		return string(data)
+	}
+
+	It("overwrites the graph saved before the execution with statuses of operations", func() {
+		p, execErr := executedPlan(false)
+		Expect(execErr).NotTo(HaveOccurred())
+		Expect(os.WriteFile(graphPath, []byte("stale"), 0o644)).To(Succeed())
+
+		Expect(action.SaveExecutedPlanGraph(ctx, p, graphPath, failurePath, false)).To(Succeed())
+
+		Expect(readGraph(graphPath)).To(ContainSubstring(`"test/succeeding" [shape="box", style="filled", fillcolor="palegreen"]`))
+		Expect(failurePath).NotTo(BeAnExistingFile())
+	})
+
+	It("saves the graph of a failed execution to the specified path", func() {
+		p, execErr := executedPlan(true)
+		Expect(execErr).To(HaveOccurred())
+
+		Expect(action.SaveExecutedPlanGraph(ctx, p, graphPath, failurePath, true)).To(Succeed())
+
+		Expect(readGraph(graphPath)).To(ContainSubstring(`"test/failing" [shape="box", style="filled", fillcolor="salmon"]`))
+		Expect(failurePath).NotTo(BeAnExistingFile())
+	})
+
+	It("saves the graph of a failed execution for debugging if the path is not specified", func() {
+		p, execErr := executedPlan(true)
+		Expect(execErr).To(HaveOccurred())
+
+		Expect(action.SaveExecutedPlanGraph(ctx, p, "", failurePath, true)).To(Succeed())
+
+		Expect(readGraph(failurePath)).To(ContainSubstring(`fillcolor="salmon"`))
+	})
+
+	It("doesn't save the graph of a successful execution if the path is not specified", func() {
+		p, execErr := executedPlan(false)
+		Expect(execErr).NotTo(HaveOccurred())
+
+		Expect(action.SaveExecutedPlanGraph(ctx, p, "", failurePath, false)).To(Succeed())
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+})
+
+var _ operation.Operation = (*statusOperation)(nil)
+
+type statusOperation struct {
+	name   string
+	fail   bool
+	status operation.Status
+}
+
+func (o *statusOperation) Execute(ctx context.Context) error {
+	if o.fail {
+		o.status = operation.StatusFailed
+		return errors.New("failed")
+	}
+
+	o.status = operation.StatusCompleted
+
+	return nil
+}
+
+func (o *statusOperation) ID() string {
+	return "test/" + o.name
+}
+
+func (o *statusOperation) HumanID() string {
+	return "test " + o.name
+}
+
+func (o *statusOperation) Status() operation.Status {
+	return o.status
+}
+
+func (o *statusOperation) Type() operation.Type {
+	return "test"
+}
+
+func (o *statusOperation) Empty() bool {
+	return false
+}
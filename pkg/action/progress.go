@@ -0,0 +1,307 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/types"
+	"github.com/werf/nelm/internal/track"
+	"github.com/werf/nelm/pkg/log"
+)
+
+// The interactive progress table is redrawn in place, so it can be refreshed much more often than
+// plain progress is printed.
+const interactiveProgressRefreshInterval = time.Second
+
+func applyProgressModeDefault(mode string, noProgressTablePrint bool) (string, error) {
+	if noProgressTablePrint {
+		return ProgressModeNone, nil
+	}
+
+	switch mode {
+	case "", ProgressModeAuto:
+		piped, err := stdoutPiped()
+		if err != nil || piped || os.Getenv("TERM") == "dumb" {
+			return ProgressModePlain, nil
+		}
+
+		return ProgressModeInteractive, nil
+	case ProgressModeInteractive, ProgressModePlain, ProgressModeNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown progress mode %q, expected one of %q", mode, ProgressModes)
+	}
+}
+
+func applyShowLogsUntilDefault(until string) (string, error) {
+	switch until {
+	case "":
+		return DefaultShowLogsUntil, nil
+	case ShowLogsUntilReady, ShowLogsUntilEnd:
+		return until, nil
+	default:
+		return "", fmt.Errorf("unknown value %q of show logs until, expected one of %q", until, ShowLogsUntilValues)
+	}
+}
+
+// startProgressPrinting prints progress of tracking and streams container logs in the background
+// until the returned function is called. Progress is printed one last time on stop. Log with the
+// returned context while tracking, so that messages don't get mixed with the interactive progress
+// table.
+func startProgressPrinting(ctx context.Context, mode string, interval time.Duration, tablesBuilder *track.TablesBuilder, logStreamer *track.LogStreamer) (context.Context, func()) {
+	var (
+		printFunc func()
+		finalize  = func() {}
+	)
+
+	switch mode {
+	case ProgressModeNone:
+		return ctx, func() {}
+	case ProgressModeInteractive:
+		printer := &interactiveProgressPrinter{}
+
+		logger, ok := log.FromContext(ctx)
+		if !ok {
+			logger = log.Global()
+		}
+
+		ctx = log.NewContext(ctx, &interactiveProgressLogger{Logger: logger, printer: printer})
+
+		// Log lines erase the table through the logger, so stream them before the table is redrawn.
+		printFunc = func() {
+			logStreamer.Stream(ctx)
+			printer.print(ctx, tablesBuilder)
+		}
+		finalize = printer.finalize
+		interval = interactiveProgressRefreshInterval
+	default:
+		printFunc = func() { printTables(ctx, tablesBuilder, logStreamer) }
+	}
+
+	stopCh := make(chan struct{})
+	finishedCh := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer func() {
+			ticker.Stop()
+			close(finishedCh)
+		}()
+
+		for {
+			select {
+			case <-ticker.C:
+				printFunc()
+			case <-stopCh:
+				printFunc()
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(stopCh)
+		<-finishedCh
+		finalize()
+	}
+}
+
+// interactiveProgressPrinter redraws the status table of all tracked resources in place.
+type interactiveProgressPrinter struct {
+	mu           sync.Mutex
+	finalized    bool
+	printedLines int
+	paused       int
+}
+
+func (p *interactiveProgressPrinter) print(ctx context.Context, tablesBuilder *track.TablesBuilder) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused > 0 || p.finalized {
+		return
+	}
+
+	tablesBuilder.SetMaxTableWidth(logboek.Context(ctx).Streams().ContentWidth() - 2)
+
+	table, nonEmpty := tablesBuilder.BuildStatusTable(time.Now())
+	if !nonEmpty {
+		return
+	}
+
+	table.SuppressTrailingSpaces()
+	rendered := table.Render() + "\n"
+
+	p.write(ctx, p.eraseSequence()+rendered)
+	p.printedLines = strings.Count(rendered, "\n")
+}
+
+// Keeps the last printed table as is. Nothing is printed or erased after this.
+func (p *interactiveProgressPrinter) finalize() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finalized = true
+	p.printedLines = 0
+}
+
+// Erases the table, so that the next message is printed in its place. The table is printed again
+// below the message on the next redraw. Must be called with the mutex locked.
+func (p *interactiveProgressPrinter) erase(ctx context.Context) {
+	if p.printedLines == 0 {
+		return
+	}
+
+	p.write(ctx, p.eraseSequence())
+	p.printedLines = 0
+}
+
+// Moves the cursor to the beginning of the table and clears everything below.
+func (p *interactiveProgressPrinter) eraseSequence() string {
+	if p.printedLines == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("\x1b[%dA\r\x1b[J", p.printedLines)
+}
+
+func (p *interactiveProgressPrinter) write(ctx context.Context, s string) {
+	logboek.Context(ctx).Streams().DoWithoutProxyStreamDataFormatting(func() {
+		fmt.Fprint(logboek.Context(ctx).OutStream(), s)
+	})
+}
+
+var _ log.Logger = (*interactiveProgressLogger)(nil)
+
+// interactiveProgressLogger erases the interactive progress table before every message, so that
+// the table is never drawn over messages.
+type interactiveProgressLogger struct {
+	log.Logger
+	printer *interactiveProgressPrinter
+}
+
+func (l *interactiveProgressLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.do(ctx, log.TraceLevel, func() { l.Logger.Trace(ctx, format, a...) })
+}
+
+func (l *interactiveProgressLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.do(ctx, log.TraceLevel, func() { l.Logger.TraceStruct(ctx, obj, format, a...) })
+}
+
+func (l *interactiveProgressLogger) TracePop(ctx context.Context, group string) {
+	l.do(ctx, log.TraceLevel, func() { l.Logger.TracePop(ctx, group) })
+}
+
+func (l *interactiveProgressLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.do(ctx, log.DebugLevel, func() { l.Logger.Debug(ctx, format, a...) })
+}
+
+func (l *interactiveProgressLogger) DebugPop(ctx context.Context, group string) {
+	l.do(ctx, log.DebugLevel, func() { l.Logger.DebugPop(ctx, group) })
+}
+
+func (l *interactiveProgressLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.do(ctx, log.InfoLevel, func() { l.Logger.Info(ctx, format, a...) })
+}
+
+func (l *interactiveProgressLogger) InfoPop(ctx context.Context, group string) {
+	l.do(ctx, log.InfoLevel, func() { l.Logger.InfoPop(ctx, group) })
+}
+
+func (l *interactiveProgressLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.do(ctx, log.WarningLevel, func() { l.Logger.Warn(ctx, format, a...) })
+}
+
+func (l *interactiveProgressLogger) WarnPop(ctx context.Context, group string) {
+	l.do(ctx, log.WarningLevel, func() { l.Logger.WarnPop(ctx, group) })
+}
+
+func (l *interactiveProgressLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.do(ctx, log.ErrorLevel, func() { l.Logger.Error(ctx, format, a...) })
+}
+
+func (l *interactiveProgressLogger) ErrorPop(ctx context.Context, group string) {
+	l.do(ctx, log.ErrorLevel, func() { l.Logger.ErrorPop(ctx, group) })
+}
+
+// The table is not redrawn until blocks and processes are done, since their headers and footers
+// are printed bypassing the logger.
+func (l *interactiveProgressLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	return &interactiveProgressLogBlock{LogBlockInterface: l.Logger.InfoBlock(ctx, format, a...), ctx: ctx, printer: l.printer}
+}
+
+func (l *interactiveProgressLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	return &interactiveProgressLogProcess{LogProcessInterface: l.Logger.InfoProcess(ctx, format, a...), ctx: ctx, printer: l.printer}
+}
+
+func (l *interactiveProgressLogger) do(ctx context.Context, lvl log.Level, f func()) {
+	if !l.AcceptLevel(ctx, lvl) {
+		return
+	}
+
+	l.printer.mu.Lock()
+	defer l.printer.mu.Unlock()
+
+	l.printer.erase(ctx)
+	f()
+}
+
+func (p *interactiveProgressPrinter) pause(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.erase(ctx)
+	p.paused++
+}
+
+func (p *interactiveProgressPrinter) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused--
+}
+
+type interactiveProgressLogBlock struct {
+	types.LogBlockInterface
+	ctx     context.Context
+	printer *interactiveProgressPrinter
+}
+
+func (b *interactiveProgressLogBlock) Do(f func()) {
+	b.printer.pause(b.ctx)
+	defer b.printer.resume()
+
+	b.LogBlockInterface.Do(f)
+}
+
+func (b *interactiveProgressLogBlock) DoError(f func() error) error {
+	b.printer.pause(b.ctx)
+	defer b.printer.resume()
+
+	return b.LogBlockInterface.DoError(f)
+}
+
+type interactiveProgressLogProcess struct {
+	types.LogProcessInterface
+	ctx     context.Context
+	printer *interactiveProgressPrinter
+}
+
+func (p *interactiveProgressLogProcess) Do(f func()) {
+	p.printer.pause(p.ctx)
+	defer p.printer.resume()
+
+	p.LogProcessInterface.Do(f)
+}
+
+func (p *interactiveProgressLogProcess) DoError(f func() error) error {
+	p.printer.pause(p.ctx)
+	defer p.printer.resume()
+
+	return p.LogProcessInterface.DoError(f)
+}
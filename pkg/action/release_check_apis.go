@@ -0,0 +1,416 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/samber/lo"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/3p-helm/pkg/downloader"
+	"github.com/werf/3p-helm/pkg/getter"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/internal/chart"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseCheckAPIsOutputFormat = TableOutputFormat
+	DefaultReleaseCheckAPIsLogLevel     = ErrorLogLevel
+)
+
+type ReleaseCheckAPIsOptions struct {
+	// If set, resources of the newly rendered chart are checked too.
+	ChartDirPath               string
+	ChartRepositorySkipUpdate  bool
+	DefaultSecretValuesDisable bool
+	DefaultValuesDisable       bool
+	FailOnDeprecatedAPIs       bool
+	KubeAPIServerName          string
+	KubeBurstLimit             int
+	KubeCAPath                 string
+	KubeConfigBase64           string
+	KubeConfigPaths            []string
+	KubeContext                string
+	KubeDiscoveryCacheDir      string
+	KubeImpersonateGroups      []string
+	KubeImpersonateUser        string
+	KubeQPSLimit               int
+	KubeRefreshDiscovery       bool
+	KubeSkipTLSVerify          bool
+	KubeTLSServerName          string
+	KubeToken                  string
+	KubeTokenPath              string
+	LogColorMode               string
+	LogRegistryStreamOut       io.Writer
+	OutputFormat               string
+	OutputNoPrint              bool
+	RegistryCredentialsPath    string
+	ReleaseStorageDriver       string
+	SecretKey                  string
+	SecretKeyIgnore            bool
+	SecretValuesPaths          []string
+	SecretWorkDir              string
+	// The Kubernetes version to check against. By default, the version of the cluster.
+	TargetKubeVersion string
+	TempDirPath       string
+	ValuesEnv         map[string]string
+	ValuesEnvPrefix   string
+	ValuesFileSets    []string
+	ValuesFilesPaths  []string
+	ValuesJSONSets    []string
+	ValuesLiteralSets []string
+	ValuesSets        []string
+	ValuesStringSets  []string
+}
+
+// ReleaseCheckAPIs finds resources of the last release revision, and of the chart if specified,
+// which use apiVersions deprecated or removed in the target Kubernetes version.
+func ReleaseCheckAPIs(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseCheckAPIsOptions) (*ReleaseCheckAPIsResultV1, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseCheckAPIsOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release check apis options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	var targetKubeVersion *semver.Version
+	if opts.TargetKubeVersion != "" {
+		targetKubeVersion, err = parseKubeVersion(opts.TargetKubeVersion)
+		if err != nil {
+			return nil, &UsageError{Err: err}
+		}
+	} else {
+		targetKubeVersion, err = serverKubeVersion(clientFactory.Discovery())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmActionConfig.Releases,
+		release.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	rel, found, err := history.LastRelease()
+	if err != nil {
+		return nil, fmt.Errorf("get last release: %w", err)
+	} else if !found && opts.ChartDirPath == "" {
+		return nil, fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+	}
+
+	apis := newAPIDiscovery(clientFactory.Discovery())
+
+	result := &ReleaseCheckAPIsResultV1{
+		ApiVersion:        ReleaseCheckAPIsResultApiVersionV1,
+		TargetKubeVersion: fmt.Sprintf("%d.%d", targetKubeVersion.Major(), targetKubeVersion.Minor()),
+		DeprecatedAPIs:    []*DeprecatedAPIUsage{},
+	}
+
+	if found {
+		result.Release = &ReleaseCheckAPIsResultRelease{
+			Name:      rel.Name(),
+			Namespace: rel.Namespace(),
+			Revision:  rel.Revision(),
+		}
+
+		var resources []*id.ResourceID
+		for _, res := range rel.HookResources() {
+			resources = append(resources, res.ResourceID)
+		}
+
+		for _, res := range rel.GeneralResources() {
+			resources = append(resources, res.ResourceID)
+		}
+
+		result.DeprecatedAPIs = append(result.DeprecatedAPIs, findDeprecatedAPIs(resources, DeprecatedAPISourceRelease, targetKubeVersion, apis, nil)...)
+	}
+
+	if opts.ChartDirPath != "" {
+		var newRevision int
+		var deployType common.DeployType
+		if found {
+			newRevision = rel.Revision() + 1
+			deployType = common.DeployTypeUpgrade
+		} else {
+			newRevision = 1
+			deployType = common.DeployTypeInitial
+		}
+
+		chartTree, err := newReleaseCheckAPIsChartTree(ctx, releaseName, releaseNamespace, newRevision, deployType, helmActionConfig, clientFactory, opts)
+		if err != nil {
+			return nil, &TemplateError{Err: fmt.Errorf("construct chart tree: %w", err)}
+		}
+
+		result.DeprecatedAPIs = append(result.DeprecatedAPIs, chartTreeDeprecatedAPIs(chartTree, targetKubeVersion, apis)...)
+	}
+
+	for _, usage := range result.DeprecatedAPIs {
+		if usage.Removed {
+			result.Summary.Removed++
+		} else {
+			result.Summary.Deprecated++
+		}
+	}
+
+	if !opts.OutputNoPrint {
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			var colorLevel color.Level
+			if opts.LogColorMode != LogColorModeOff {
+				colorLevel = color.DetectColorLevel()
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case TableOutputFormat:
+			if _, err := os.Stdout.Write([]byte(renderReleaseCheckAPIsTable(result))); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+	}
+
+	if opts.FailOnDeprecatedAPIs && len(result.DeprecatedAPIs) > 0 {
+		return result, ErrDeprecatedAPIs
+	}
+
+	return result, nil
+}
+
+func newReleaseCheckAPIsChartTree(ctx context.Context, releaseName, releaseNamespace string, revision int, deployType common.DeployType, helmActionConfig *action.Configuration, clientFactory *kube.ClientFactory, opts ReleaseCheckAPIsOptions) (*chart.ChartTree, error) {
+	helmSettings := newHelmSettings(ctx)
+
+	helmRegistryClient, err := newHelmRegistryClient(ctx, opts.LogRegistryStreamOut, opts.RegistryCredentialsPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("construct registry client: %w", err)
+	}
+
+	downloader := &downloader.Manager{
+		// FIXME(ilya-lesikov):
+		Out:               logboek.Context(ctx).OutStream(),
+		ChartPath:         opts.ChartDirPath,
+		SkipUpdate:        opts.ChartRepositorySkipUpdate,
+		AllowMissingRepos: true,
+		Getters:           getter.All(helmSettings),
+		RegistryClient:    helmRegistryClient,
+		RepositoryConfig:  helmSettings.RepositoryConfig,
+		RepositoryCache:   helmSettings.RepositoryCache,
+		Debug:             helmSettings.Debug,
+	}
+
+	return chart.NewChartTree(
+		ctx,
+		opts.ChartDirPath,
+		releaseName,
+		releaseNamespace,
+		revision,
+		deployType,
+		helmActionConfig,
+		chart.ChartTreeOptions{
+			StringSetValues:            opts.ValuesStringSets,
+			SetValues:                  opts.ValuesSets,
+			FileValues:                 opts.ValuesFileSets,
+			Env:                        opts.ValuesEnv,
+			EnvValuesPrefix:            opts.ValuesEnvPrefix,
+			JSONSetValues:              opts.ValuesJSONSets,
+			LiteralSetValues:           opts.ValuesLiteralSets,
+			ValuesFiles:                opts.ValuesFilesPaths,
+			Mapper:                     clientFactory.Mapper(),
+			DiscoveryClient:            clientFactory.Discovery(),
+			Getters:                    getter.All(helmSettings),
+			RegistryClient:             helmRegistryClient,
+			SecretKey:                  opts.SecretKey,
+			SecretValuesFiles:          opts.SecretValuesPaths,
+			DefaultSecretValuesDisable: opts.DefaultSecretValuesDisable,
+			DefaultValuesDisable:       opts.DefaultValuesDisable,
+			SecretKeyIgnore:            opts.SecretKeyIgnore,
+			SecretWorkDir:              opts.SecretWorkDir,
+			DependencyManager:          downloader,
+			TempDirPath:                opts.TempDirPath,
+		},
+	)
+}
+
+func renderReleaseCheckAPIsTable(result *ReleaseCheckAPIsResultV1) string {
+	if len(result.DeprecatedAPIs) == 0 {
+		return fmt.Sprintf("No deprecated apiVersions found for Kubernetes %s\n", result.TargetKubeVersion)
+	}
+
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.Style().Options = prtable.OptionsNoBordersAndSeparators
+	table.AppendHeader(prtable.Row{"RESOURCE", "SOURCE", "API VERSION", "REPLACEMENT", "STATUS"})
+
+	for _, usage := range result.DeprecatedAPIs {
+		var status string
+		switch {
+		case usage.Removed && usage.RemovedIn != "":
+			status = "removed in " + usage.RemovedIn
+		case usage.Removed:
+			status = "not served"
+		default:
+			status = fmt.Sprintf("deprecated in %s, removed in %s", usage.DeprecatedIn, usage.RemovedIn)
+		}
+
+		table.AppendRow(prtable.Row{usage.Resource, usage.Source, usage.APIVersion, lo.Ternary(usage.ReplacementAPIVersion != "", usage.ReplacementAPIVersion, "-"), status})
+	}
+
+	return fmt.Sprintf(
+		"%s\n\nFor Kubernetes %s: %d resources use removed apiVersions, %d use deprecated ones\n",
+		table.Render(),
+		result.TargetKubeVersion,
+		result.Summary.Removed,
+		result.Summary.Deprecated,
+	)
+}
+
+func applyReleaseCheckAPIsOptionsDefaults(opts ReleaseCheckAPIsOptions, currentUser *user.User) (ReleaseCheckAPIsOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseCheckAPIsOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.LogRegistryStreamOut == nil {
+		opts.LogRegistryStreamOut = os.Stdout
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.RegistryCredentialsPath == "" {
+		opts.RegistryCredentialsPath = DefaultRegistryCredentialsPath
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.SecretWorkDir == "" {
+		opts.SecretWorkDir, err = os.Getwd()
+		if err != nil {
+			return ReleaseCheckAPIsOptions{}, fmt.Errorf("get current working directory: %w", err)
+		}
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseCheckAPIsOutputFormat
+	}
+
+	return opts, nil
+}
+
+const ReleaseCheckAPIsResultApiVersionV1 = "v1"
+
+type ReleaseCheckAPIsResultV1 struct {
+	ApiVersion        string `json:"apiVersion"`
+	TargetKubeVersion string `json:"targetKubeVersion"`
+	// Not set if the release is not found and only the chart is checked.
+	Release        *ReleaseCheckAPIsResultRelease `json:"release,omitempty"`
+	DeprecatedAPIs []*DeprecatedAPIUsage          `json:"deprecatedApis"`
+	Summary        ReleaseCheckAPIsResultSummary  `json:"summary"`
+}
+
+type ReleaseCheckAPIsResultRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+}
+
+type ReleaseCheckAPIsResultSummary struct {
+	Deprecated int `json:"deprecated"`
+	Removed    int `json:"removed"`
+}
@@ -0,0 +1,333 @@
+package action
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gookit/color"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseDevelopLogLevel         = InfoLogLevel
+	DefaultReleaseDevelopDebounceInterval = 500 * time.Millisecond
+)
+
+// Replaced in tests.
+var releaseDevelopInstall = ReleaseInstall
+
+// All the install options, including the kube connection ones, apply to every deploy.
+type ReleaseDevelopOptions struct {
+	ReleaseInstallOptions
+
+	DebounceInterval time.Duration
+	ManualApply      bool
+	ManualApplyInput io.Reader
+}
+
+// ReleaseDevelop deploys the chart, then watches the chart directory and values files and
+// redeploys the release on every change until the context is canceled. Render and deploy errors
+// are reported, but don't stop the loop.
+func ReleaseDevelop(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseDevelopOptions) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	opts = applyReleaseDevelopOptionsDefaults(opts, currentDir)
+
+	// Ask once instead of on every redeploy.
+	if !opts.ProtectedContextConfirmed {
+		if err := confirmReleaseDevelopProtectedContext(ctx, releaseNamespace, opts.ReleaseInstallOptions); err != nil {
+			return fmt.Errorf("check protected context: %w", err)
+		}
+
+		opts.ProtectedContextConfirmed = true
+	}
+
+	watchedPaths := append([]string{opts.ChartDirPath}, opts.ValuesFilesPaths...)
+	watchedPaths = append(watchedPaths, opts.SecretValuesPaths...)
+
+	watcher, err := newPathsWatcher(ctx, watchedPaths)
+	if err != nil {
+		return fmt.Errorf("watch chart and values files: %w", err)
+	}
+	defer watcher.Close()
+
+	dev := &releaseDeveloper{
+		releaseName:      releaseName,
+		releaseNamespace: releaseNamespace,
+		opts:             opts,
+	}
+
+	if opts.ManualApply {
+		dev.manualApplyLines = readLines(opts.ManualApplyInput)
+	}
+
+	dev.deploy(ctx, false)
+
+	debounce := time.NewTimer(opts.DebounceInterval)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return dev.finalize(context.WithoutCancel(ctx))
+		case err := <-watcher.Errors:
+			log.Default.Warn(ctx, "Watching for changes failed: %s", err)
+
+			continue
+		case event := <-watcher.Events:
+			if !watcher.relevant(event) {
+				continue
+			}
+
+			dev.dirty = true
+			debounce.Reset(opts.DebounceInterval)
+
+			continue
+		case <-debounce.C:
+		}
+
+		log.Default.Info(ctx, color.Style{color.Bold, color.Cyan}.Render("Changes detected, redeploying"))
+
+		dev.deploy(ctx, opts.ManualApply)
+
+		if dev.manualApplyInputClosed {
+			return dev.finalize(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+func applyReleaseDevelopOptionsDefaults(opts ReleaseDevelopOptions, currentDir string) ReleaseDevelopOptions {
+	if opts.ChartDirPath == "" {
+		opts.ChartDirPath = currentDir
+	}
+
+	if opts.DebounceInterval <= 0 {
+		opts.DebounceInterval = DefaultReleaseDevelopDebounceInterval
+	}
+
+	if opts.ManualApplyInput == nil {
+		opts.ManualApplyInput = os.Stdin
+	}
+
+	return opts
+}
+
+func confirmReleaseDevelopProtectedContext(ctx context.Context, releaseNamespace string, opts ReleaseInstallOptions) error {
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		currentUser, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("get current user: %w", err)
+		}
+
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	kubeConfig, err := newReleaseInstallKubeConfig(ctx, releaseNamespace, opts)
+	if err != nil {
+		return fmt.Errorf("construct kube config: %w", err)
+	}
+
+	return checkProtectedContext(ctx, kubeConfig, protectedContextCheckOptions{
+		ConfirmFunc:      opts.ConfirmFunc,
+		KubeContext:      opts.KubeContext,
+		Patterns:         opts.ProtectedContexts,
+		PatternsFilePath: opts.ProtectedContextsFilePath,
+	})
+}
+
+type releaseDeveloper struct {
+	releaseName      string
+	releaseNamespace string
+	opts             ReleaseDevelopOptions
+
+	manualApplyLines       chan struct{}
+	manualApplyInputClosed bool
+	dirty                  bool
+}
+
+// deploy runs the release install with the options as passed. If confirm is set, the planned
+// changes are shown and applied after Enter is pressed.
+func (d *releaseDeveloper) deploy(ctx context.Context, confirm bool) {
+	opts := d.opts.ReleaseInstallOptions
+	if confirm {
+		opts.Interactive = true
+		opts.InteractiveConfirmed = false
+		opts.ConfirmFunc = d.waitForEnter
+	}
+
+	if _, err := releaseDevelopInstall(ctx, d.releaseName, d.releaseNamespace, opts); err != nil {
+		if errors.Is(err, ErrDeployNotConfirmed) {
+			log.Default.Info(ctx, "Changes not applied, waiting for further changes")
+		} else {
+			log.Default.Warn(ctx, "Deploy failed, waiting for further changes: %s", err)
+		}
+
+		d.dirty = true
+
+		return
+	}
+
+	d.dirty = false
+}
+
+func (d *releaseDeveloper) waitForEnter(ctx context.Context, prompt string) (bool, error) {
+	log.Default.Info(ctx, "%s Press Enter to apply", prompt)
+
+	select {
+	case <-ctx.Done():
+		return false, nil
+	case _, ok := <-d.manualApplyLines:
+		if !ok {
+			d.manualApplyInputClosed = true
+			return false, nil
+		}
+
+		return true, nil
+	}
+}
+
+// Leave the cluster with a revision built from the latest state of the chart, even if the last
+// deploy failed or the latest changes weren't applied yet.
+func (d *releaseDeveloper) finalize(ctx context.Context) error {
+	if !d.dirty {
+		return nil
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Cyan}.Render("Deploying the final revision"))
+
+	if _, err := releaseDevelopInstall(ctx, d.releaseName, d.releaseNamespace, d.opts.ReleaseInstallOptions); err != nil {
+		return fmt.Errorf("deploy final revision: %w", err)
+	}
+
+	return nil
+}
+
+// pathsWatcher watches directories recursively, and files through their parent directories, since
+// editors often replace a file instead of writing to it.
+type pathsWatcher struct {
+	*fsnotify.Watcher
+
+	dirs  []string
+	files map[string]bool
+}
+
+func newPathsWatcher(ctx context.Context, paths []string) (*pathsWatcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+
+	watcher := &pathsWatcher{
+		Watcher: fsWatcher,
+		files:   map[string]bool{},
+	}
+
+	for _, path := range paths {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("get absolute path: %w", err)
+		}
+
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Default.Warn(ctx, "Not watching %q for changes: it doesn't exist locally", path)
+			continue
+		} else if err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("stat %q: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			watcher.files[path] = true
+
+			if err := watcher.Add(filepath.Dir(path)); err != nil {
+				watcher.Close()
+				return nil, fmt.Errorf("watch %q: %w", filepath.Dir(path), err)
+			}
+
+			continue
+		}
+
+		watcher.dirs = append(watcher.dirs, path)
+
+		if err := watcher.addDirRecursively(path); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	return watcher, nil
+}
+
+func (w *pathsWatcher) addDirRecursively(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+
+		if err := w.Add(path); err != nil {
+			return fmt.Errorf("watch %q: %w", path, err)
+		}
+
+		return nil
+	})
+}
+
+// relevant reports whether the event is for a watched file or for anything in a watched directory.
+// Directories created in watched directories are watched too.
+func (w *pathsWatcher) relevant(event fsnotify.Event) bool {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return false
+	}
+
+	if w.files[event.Name] {
+		return true
+	}
+
+	for _, dir := range w.dirs {
+		if event.Name != dir && !strings.HasPrefix(event.Name, dir+string(filepath.Separator)) {
+			continue
+		}
+
+		if event.Has(fsnotify.Create) {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				w.addDirRecursively(event.Name)
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func readLines(reader io.Reader) chan struct{} {
+	lines := make(chan struct{})
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines <- struct{}{}
+		}
+	}()
+
+	return lines
+}
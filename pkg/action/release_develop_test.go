@@ -0,0 +1,303 @@
+package action_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("release develop", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		tmpDir     string
+		chartDir   string
+		valuesPath string
+
+		mu       sync.Mutex
+		installs []action.ReleaseInstallOptions
+		failNext bool
+
+		done chan error
+	)
+
+	installCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(installs)
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(action.SetupLogging(context.Background(), action.SilentLogLevel, action.SilentLogLevel))
+		DeferCleanup(cancel)
+
+		tmpDir = GinkgoT().TempDir()
+		chartDir = filepath.Join(tmpDir, "chart")
+		valuesPath = filepath.Join(tmpDir, "values.yaml")
+
+		writeFile(filepath.Join(chartDir, "Chart.yaml"), "apiVersion: v2\nname: chart\nversion: 0.1.0\n")
+		writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n")
+		writeFile(valuesPath, "replicas: 1\n")
+
+		installs = nil
+		failNext = false
+
+		DeferCleanup(action.SetReleaseDevelopInstall(func(ctx context.Context, releaseName, releaseNamespace string, opts action.ReleaseInstallOptions) (*action.ReleaseInstallResultV1, error) {
+			if opts.Interactive {
+				if confirmed, err := opts.ConfirmFunc(ctx, "Apply?"); err != nil {
+					return nil, err
+				} else if !confirmed {
+					return nil, action.ErrDeployNotConfirmed
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			installs = append(installs, opts)
+
+			if failNext {
+				failNext = false
+				return nil, errors.New("deploy failed")
+			}
+
+			return &action.ReleaseInstallResultV1{}, nil
+		}))
+	})
+
+	start := func(opts action.ReleaseDevelopOptions) {
+		opts.ChartDirPath = chartDir
+		opts.DebounceInterval = 100 * time.Millisecond
+		opts.ValuesFilesPaths = []string{valuesPath}
+
+		done = make(chan error, 1)
+		go func() {
+			done <- action.ReleaseDevelop(ctx, "app", "app-ns", opts)
+		}()
+
+		Eventually(installCount).Should(Equal(1))
+	}
+
+	stop := func() error {
+		cancel()
+
+		var err error
+		Eventually(done, 5*time.Second).Should(Receive(&err))
+
+		return err
+	}
+
+	It("deploys with the install options as passed", func() {
+		start(action.ReleaseDevelopOptions{
+			ReleaseInstallOptions: action.ReleaseInstallOptions{
+				AutoAdopt:             true,
+				DiffContextLines:      7,
+				ExcludeResources:      []string{"ConfigMap/skipped"},
+				ForceAdoption:         true,
+				KubeDiscoveryCacheDir: filepath.Join(tmpDir, "cache"),
+				KubeImpersonateGroups: []string{"developers"},
+				KubeImpersonateUser:   "developer",
+				KubeTokenPath:         filepath.Join(tmpDir, "token"),
+				StrictTemplates:       true,
+			},
+		})
+		Expect(stop()).To(Succeed())
+
+		Expect(installs).To(HaveLen(1))
+		Expect(installs[0].AutoAdopt).To(BeTrue())
+		Expect(installs[0].DiffContextLines).To(Equal(7))
+		Expect(installs[0].ExcludeResources).To(Equal([]string{"ConfigMap/skipped"}))
+		Expect(installs[0].ForceAdoption).To(BeTrue())
+		Expect(installs[0].KubeDiscoveryCacheDir).To(Equal(filepath.Join(tmpDir, "cache")))
+		Expect(installs[0].KubeImpersonateGroups).To(Equal([]string{"developers"}))
+		Expect(installs[0].KubeImpersonateUser).To(Equal("developer"))
+		Expect(installs[0].KubeTokenPath).To(Equal(filepath.Join(tmpDir, "token")))
+		Expect(installs[0].StrictTemplates).To(BeTrue())
+		Expect(installs[0].ValuesFilesPaths).To(Equal([]string{valuesPath}))
+	})
+
+	It("redeploys once per burst of changes in the chart and values files", func() {
+		start(action.ReleaseDevelopOptions{})
+
+		for i := 0; i < 5; i++ {
+			writeFile(filepath.Join(chartDir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n")
+		}
+		Eventually(installCount).Should(Equal(2))
+		Consistently(installCount, 500*time.Millisecond).Should(Equal(2))
+
+		writeFile(valuesPath, "replicas: 2\n")
+		Eventually(installCount).Should(Equal(3))
+
+		writeFile(filepath.Join(chartDir, "templates", "new", "secret.yaml"), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\n")
+		Eventually(installCount).Should(Equal(4))
+
+		writeFile(filepath.Join(chartDir, "templates", "new", "secret.yaml"), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app2\n")
+		Eventually(installCount).Should(Equal(5))
+
+		Expect(stop()).To(Succeed())
+		Expect(installCount()).To(Equal(5))
+	})
+
+	It("keeps watching after a failed deploy and deploys the final revision on exit", func() {
+		start(action.ReleaseDevelopOptions{})
+
+		mu.Lock()
+		failNext = true
+		mu.Unlock()
+
+		writeFile(valuesPath, "replicas: 2\n")
+		Eventually(installCount).Should(Equal(2))
+
+		Expect(stop()).To(Succeed())
+		Expect(installCount()).To(Equal(3))
+	})
+
+	It("waits for Enter before applying changes in manual mode", func() {
+		input, inputWriter := io.Pipe()
+		DeferCleanup(inputWriter.Close)
+
+		start(action.ReleaseDevelopOptions{
+			ManualApply:      true,
+			ManualApplyInput: input,
+		})
+
+		writeFile(valuesPath, "replicas: 2\n")
+		Consistently(installCount, 500*time.Millisecond).Should(Equal(1))
+
+		_, err := inputWriter.Write([]byte("\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(installCount).Should(Equal(2))
+
+		Expect(stop()).To(Succeed())
+		Expect(installCount()).To(Equal(2))
+	})
+
+	Describe("in a protected context", func() {
+		var (
+			prompts     []string
+			confirmFunc func(answer bool) action.ConfirmFunc
+			installOpts action.ReleaseInstallOptions
+		)
+
+		BeforeEach(func() {
+			prompts = nil
+			confirmFunc = func(answer bool) action.ConfirmFunc {
+				return func(ctx context.Context, prompt string) (bool, error) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					prompts = append(prompts, prompt)
+
+					return answer, nil
+				}
+			}
+
+			installOpts = action.ReleaseInstallOptions{
+				KubeConfigBase64:          base64.StdEncoding.EncodeToString([]byte(protectedKubeConfig)),
+				ProtectedContexts:         []string{"*prod*"},
+				ProtectedContextsFilePath: filepath.Join(tmpDir, "no-protected-contexts"),
+			}
+			writeFile(installOpts.ProtectedContextsFilePath, "")
+		})
+
+		It("asks for confirmation once and not on every redeploy", func() {
+			installOpts.ConfirmFunc = confirmFunc(true)
+			start(action.ReleaseDevelopOptions{ReleaseInstallOptions: installOpts})
+
+			writeFile(valuesPath, "replicas: 2\n")
+			Eventually(installCount).Should(Equal(2))
+
+			Expect(stop()).To(Succeed())
+			Expect(prompts).To(HaveLen(1))
+			Expect(prompts[0]).To(ContainSubstring(`Kube context "prod"`))
+
+			for _, opts := range installs {
+				Expect(opts.ProtectedContextConfirmed).To(BeTrue())
+			}
+		})
+
+		It("doesn't deploy if not confirmed", func() {
+			installOpts.ChartDirPath = chartDir
+			installOpts.ConfirmFunc = confirmFunc(false)
+			installOpts.ValuesFilesPaths = []string{valuesPath}
+
+			err := action.ReleaseDevelop(ctx, "app", "app-ns", action.ReleaseDevelopOptions{ReleaseInstallOptions: installOpts})
+			Expect(err).To(MatchError(ContainSubstring("not confirmed")))
+			Expect(prompts).To(HaveLen(1))
+			Expect(installCount()).To(BeZero())
+		})
+
+		It("asks for confirmation if the protected context is passed explicitly", func() {
+			installOpts.ConfirmFunc = confirmFunc(true)
+			installOpts.KubeConfigBase64 = base64.StdEncoding.EncodeToString([]byte(devAndProtectedKubeConfig))
+			installOpts.KubeContext = "prod"
+			start(action.ReleaseDevelopOptions{ReleaseInstallOptions: installOpts})
+
+			Expect(stop()).To(Succeed())
+			Expect(prompts).To(HaveLen(1))
+			Expect(prompts[0]).To(ContainSubstring(`Kube context "prod"`))
+		})
+
+		It("doesn't ask if confirmed in advance", func() {
+			installOpts.ConfirmFunc = confirmFunc(false)
+			installOpts.ProtectedContextConfirmed = true
+			start(action.ReleaseDevelopOptions{ReleaseInstallOptions: installOpts})
+
+			Expect(stop()).To(Succeed())
+			Expect(prompts).To(BeEmpty())
+		})
+	})
+})
+
+const protectedKubeConfig = `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: prod
+users:
+- name: prod
+  user:
+    token: secret
+`
+
+const devAndProtectedKubeConfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+- name: prod
+  context:
+    cluster: prod
+    user: dev
+users:
+- name: dev
+  user:
+    token: secret
+`
@@ -12,14 +12,11 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/gookit/color"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
-	"github.com/werf/3p-helm/pkg/chart/loader"
 	helmrelease "github.com/werf/3p-helm/pkg/release"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -28,29 +25,31 @@ const (
 )
 
 type ReleaseGetOptions struct {
-	KubeAPIServerName    string
-	KubeBurstLimit       int
-	KubeCAPath           string
-	KubeConfigBase64     string
-	KubeConfigPaths      []string
-	KubeContext          string
-	KubeQPSLimit         int
-	KubeSkipTLSVerify    bool
-	KubeTLSServerName    string
-	KubeToken            string
-	LogColorMode         string
-	NetworkParallelism   int
-	OutputFormat         string
-	OutputNoPrint        bool
-	ReleaseStorageDriver string
-	Revision             int
-	TempDirPath          string
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	Revision              int
+	TempDirPath           string
 }
 
 func ReleaseGet(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseGetOptions) (*ReleaseGetResultV1, error) {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentUser, err := user.Current()
 	if err != nil {
 		return nil, fmt.Errorf("get current user: %w", err)
@@ -58,7 +57,7 @@ func ReleaseGet(ctx context.Context, releaseName, releaseNamespace string, opts
 
 	opts, err = applyReleaseGetOptionsDefaults(opts, currentUser)
 	if err != nil {
-		return nil, fmt.Errorf("build release get options: %w", err)
+		return nil, &UsageError{Err: fmt.Errorf("build release get options: %w", err)}
 	}
 
 	if len(opts.KubeConfigPaths) > 0 {
@@ -75,6 +74,8 @@ func ReleaseGet(ctx context.Context, releaseName, releaseNamespace string, opts
 		BurstLimit:            opts.KubeBurstLimit,
 		CertificateAuthority:  opts.KubeCAPath,
 		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
 		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 		KubeConfigBase64:      opts.KubeConfigBase64,
 		Namespace:             releaseNamespace,
@@ -82,19 +83,20 @@ func ReleaseGet(ctx context.Context, releaseName, releaseNamespace string, opts
 		Server:                opts.KubeAPIServerName,
 		TLSServerName:         opts.KubeTLSServerName,
 		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("construct kube config: %w", err)
 	}
 
-	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig)
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("construct kube client factory: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
-
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
 		clientFactory.LegacyClientGetter(),
@@ -109,9 +111,6 @@ func ReleaseGet(ctx context.Context, releaseName, releaseNamespace string, opts
 
 	helmReleaseStorage := helmActionConfig.Releases
 
-	secrets.DisableSecrets = true
-	loader.NoChartLockWarning = ""
-
 	history, err := release.NewHistory(
 		releaseName,
 		releaseNamespace,
@@ -0,0 +1,288 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/samber/lo"
+
+	"github.com/werf/3p-helm/pkg/action"
+	helmrelease "github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseGetInfoOutputFormat = TableOutputFormat
+	DefaultReleaseGetInfoLogLevel     = ErrorLogLevel
+)
+
+type ReleaseGetInfoOptions struct {
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	Revision              int
+	TempDirPath           string
+}
+
+// ReleaseGetInfo returns metadata of the release revision, including its info annotations, without
+// its manifests and values.
+func ReleaseGetInfo(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseGetInfoOptions) (*ReleaseGetInfoResultV1, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseGetInfoOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release get info options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmReleaseStorage,
+		release.HistoryOptions{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	var (
+		release      *release.Release
+		releaseFound bool
+	)
+	if opts.Revision == 0 {
+		release, releaseFound, err = history.LastRelease()
+		if err != nil {
+			return nil, fmt.Errorf("get last release: %w", err)
+		}
+	} else {
+		release, releaseFound, err = history.Release(opts.Revision)
+		if err != nil {
+			return nil, fmt.Errorf("get release revision %d: %w", opts.Revision, err)
+		}
+	}
+
+	if !releaseFound {
+		if opts.Revision == 0 {
+			return nil, fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+		} else {
+			return nil, fmt.Errorf("revision %d of release %q (namespace %q) not found", opts.Revision, releaseName, releaseNamespace)
+		}
+	}
+
+	result := &ReleaseGetInfoResultV1{
+		ApiVersion:    ReleaseGetInfoResultApiVersionV1,
+		Name:          release.Name(),
+		Namespace:     release.Namespace(),
+		Revision:      release.Revision(),
+		Status:        release.Status(),
+		FirstDeployed: release.FirstDeployed(),
+		LastDeployed:  release.LastDeployed(),
+		Description:   release.Description(),
+		Annotations:   release.InfoAnnotations(),
+	}
+
+	if !opts.OutputNoPrint {
+		var colorLevel color.Level
+		if opts.LogColorMode != LogColorModeOff {
+			colorLevel = color.DetectColorLevel()
+		}
+
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case YamlOutputFormat:
+			b, err := yaml.MarshalContext(ctx, result)
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to yaml: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), YamlOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case TableOutputFormat:
+			if _, err := os.Stdout.Write([]byte(renderReleaseGetInfoTable(result))); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+	}
+
+	return result, nil
+}
+
+func renderReleaseGetInfoTable(result *ReleaseGetInfoResultV1) string {
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.Style().Options = prtable.OptionsNoBordersAndSeparators
+	table.SuppressTrailingSpaces()
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	}
+
+	table.AppendRow(prtable.Row{"Name:", result.Name})
+	table.AppendRow(prtable.Row{"Namespace:", result.Namespace})
+	table.AppendRow(prtable.Row{"Revision:", result.Revision})
+	table.AppendRow(prtable.Row{"Status:", result.Status})
+	table.AppendRow(prtable.Row{"First deployed:", formatTime(result.FirstDeployed)})
+	table.AppendRow(prtable.Row{"Last deployed:", formatTime(result.LastDeployed)})
+	table.AppendRow(prtable.Row{"Description:", result.Description})
+
+	if len(result.Annotations) > 0 {
+		table.AppendRow(prtable.Row{"Annotations:", ""})
+
+		keys := lo.Keys(result.Annotations)
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			table.AppendRow(prtable.Row{"  " + key + ":", result.Annotations[key]})
+		}
+	}
+
+	return table.Render() + "\n"
+}
+
+func applyReleaseGetInfoOptionsDefaults(opts ReleaseGetInfoOptions, currentUser *user.User) (ReleaseGetInfoOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseGetInfoOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = DefaultNetworkParallelism
+	}
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseGetInfoOutputFormat
+	}
+
+	return opts, nil
+}
+
+const ReleaseGetInfoResultApiVersionV1 = "v1"
+
+type ReleaseGetInfoResultV1 struct {
+	ApiVersion    string             `json:"apiVersion"`
+	Name          string             `json:"name"`
+	Namespace     string             `json:"namespace"`
+	Revision      int                `json:"revision"`
+	Status        helmrelease.Status `json:"status"`
+	FirstDeployed time.Time          `json:"firstDeployed"`
+	LastDeployed  time.Time          `json:"lastDeployed"`
+	Description   string             `json:"description,omitempty"`
+	Annotations   map[string]string  `json:"annotations"`
+}
@@ -0,0 +1,233 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/gookit/color"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseGetValuesOutputFormat = YamlOutputFormat
+	DefaultReleaseGetValuesLogLevel     = ErrorLogLevel
+)
+
+type ReleaseGetValuesOptions struct {
+	All                   bool
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	Revision              int
+	TempDirPath           string
+}
+
+func ReleaseGetValues(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseGetValuesOptions) (map[string]interface{}, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseGetValuesOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release get values options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmReleaseStorage,
+		release.HistoryOptions{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	var (
+		release      *release.Release
+		releaseFound bool
+	)
+	if opts.Revision == 0 {
+		release, releaseFound, err = history.LastRelease()
+		if err != nil {
+			return nil, fmt.Errorf("get last release: %w", err)
+		}
+	} else {
+		release, releaseFound, err = history.Release(opts.Revision)
+		if err != nil {
+			return nil, fmt.Errorf("get release revision %d: %w", opts.Revision, err)
+		}
+	}
+
+	if !releaseFound {
+		if opts.Revision == 0 {
+			return nil, fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+		} else {
+			return nil, fmt.Errorf("revision %d of release %q (namespace %q) not found", opts.Revision, releaseName, releaseNamespace)
+		}
+	}
+
+	var result map[string]interface{}
+	if opts.All {
+		result, err = chartutil.CoalesceValues(release.LegacyChart(), release.Values())
+		if err != nil {
+			return nil, fmt.Errorf("coalesce values of release %q (namespace %q, revision %d): %w", releaseName, releaseNamespace, release.Revision(), err)
+		}
+	} else {
+		result = release.Values()
+	}
+
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+
+	if !opts.OutputNoPrint {
+		var resultMessage string
+
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			resultMessage = string(b)
+		case YamlOutputFormat:
+			b, err := yaml.MarshalContext(ctx, result)
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to yaml: %w", err)
+			}
+
+			resultMessage = string(b)
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+
+		var colorLevel color.Level
+		if opts.LogColorMode != LogColorModeOff {
+			colorLevel = color.DetectColorLevel()
+		}
+
+		if err := writeWithSyntaxHighlight(os.Stdout, resultMessage, string(opts.OutputFormat), colorLevel); err != nil {
+			return nil, fmt.Errorf("write result to output: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+func applyReleaseGetValuesOptionsDefaults(opts ReleaseGetValuesOptions, currentUser *user.User) (ReleaseGetValuesOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseGetValuesOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = DefaultNetworkParallelism
+	}
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseGetValuesOutputFormat
+	}
+
+	return opts, nil
+}
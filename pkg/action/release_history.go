@@ -0,0 +1,254 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseHistoryOutputFormat = TableOutputFormat
+	DefaultReleaseHistoryLogLevel     = ErrorLogLevel
+)
+
+type ReleaseHistoryOptions struct {
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	TempDirPath           string
+}
+
+func ReleaseHistory(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseHistoryOptions) (*ReleaseHistoryResultV1, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseHistoryOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release history options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmReleaseStorage,
+		release.HistoryOptions{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	rels, err := history.Releases()
+	if err != nil {
+		return nil, fmt.Errorf("get releases: %w", err)
+	} else if len(rels) == 0 {
+		return nil, fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+	}
+
+	result := &ReleaseHistoryResultV1{
+		ApiVersion: ReleaseHistoryResultApiVersionV1,
+	}
+
+	for _, rel := range rels {
+		result.Revisions = append(result.Revisions, &ReleaseHistoryResultRevision{
+			Revision:     rel.Revision(),
+			Status:       string(rel.Status()),
+			Chart:        rel.ChartName(),
+			ChartVersion: rel.ChartVersion(),
+			AppVersion:   rel.AppVersion(),
+			Deployed:     rel.LastDeployed(),
+			Description:  rel.Description(),
+		})
+	}
+
+	if !opts.OutputNoPrint {
+		var colorLevel color.Level
+		if opts.LogColorMode != LogColorModeOff {
+			colorLevel = color.DetectColorLevel()
+		}
+
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case YamlOutputFormat:
+			b, err := yaml.MarshalContext(ctx, result)
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to yaml: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), YamlOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case TableOutputFormat:
+			if _, err := os.Stdout.Write([]byte(renderReleaseHistoryTable(result))); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+	}
+
+	return result, nil
+}
+
+func renderReleaseHistoryTable(result *ReleaseHistoryResultV1) string {
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.Style().Options = prtable.OptionsNoBordersAndSeparators
+	table.AppendHeader(prtable.Row{"REVISION", "STATUS", "CHART VERSION", "APP VERSION", "DEPLOYED", "DESCRIPTION"})
+
+	for _, rev := range result.Revisions {
+		var deployed string
+		if !rev.Deployed.IsZero() {
+			deployed = rev.Deployed.Format(time.RFC3339)
+		}
+
+		table.AppendRow(prtable.Row{rev.Revision, rev.Status, rev.ChartVersion, rev.AppVersion, deployed, rev.Description})
+	}
+
+	return table.Render() + "\n"
+}
+
+func applyReleaseHistoryOptionsDefaults(opts ReleaseHistoryOptions, currentUser *user.User) (ReleaseHistoryOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseHistoryOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = DefaultNetworkParallelism
+	}
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseHistoryOutputFormat
+	}
+
+	return opts, nil
+}
+
+const ReleaseHistoryResultApiVersionV1 = "v1"
+
+type ReleaseHistoryResultV1 struct {
+	ApiVersion string                          `json:"apiVersion"`
+	Revisions  []*ReleaseHistoryResultRevision `json:"revisions"`
+}
+
+type ReleaseHistoryResultRevision struct {
+	Revision     int       `json:"revision"`
+	Status       string    `json:"status"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	Deployed     time.Time `json:"deployed"`
+	Description  string    `json:"description,omitempty"`
+}
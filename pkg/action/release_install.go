@@ -8,24 +8,23 @@ import (
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gookit/color"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
-	"github.com/werf/3p-helm/pkg/chart/loader"
-	"github.com/werf/3p-helm/pkg/chartutil"
 	"github.com/werf/3p-helm/pkg/downloader"
 	"github.com/werf/3p-helm/pkg/getter"
-	"github.com/werf/3p-helm/pkg/registry"
-	"github.com/werf/3p-helm/pkg/werf/chartextender"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
-	"github.com/werf/common-go/pkg/secrets_manager"
+	helmrelease "github.com/werf/3p-helm/pkg/release"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
 	kubeutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
@@ -34,14 +33,17 @@ import (
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/lock"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan"
 	"github.com/werf/nelm/internal/plan/operation"
 	"github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/internal/resource/matcher"
+	"github.com/werf/nelm/internal/telemetry"
 	"github.com/werf/nelm/internal/track"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -49,130 +51,216 @@ const (
 )
 
 type ReleaseInstallOptions struct {
+	AdoptNamespace               bool
+	AutoAdopt                    bool
 	AutoRollback                 bool
+	AutoSanitizeReleaseName      bool
+	CRDsPolicy                   string
 	ChartAppVersion              string
+	ChartDependencyUpdate        bool
 	ChartDirPath                 string
+	ChartProvenanceKeyringPath   string
+	ChartProvenanceVerify        bool
 	ChartRepositoryInsecure      bool
+	ChartRepositoryPlainHTTP     bool
 	ChartRepositorySkipTLSVerify bool
 	ChartRepositorySkipUpdate    bool
+	CheckReferences              bool
+	CheckReferencesStrict        bool
+	CleanupOnFail                bool
+	ConfirmFunc                  ConfirmFunc
 	DefaultChartAPIVersion       string
 	DefaultChartName             string
 	DefaultChartVersion          string
 	DefaultSecretValuesDisable   bool
 	DefaultValuesDisable         bool
+	DeployID                     string
+	DiffContextLines             int
+	EmitEvents                   bool
+	EventsInvolvedObject         string
+	ExcludeResources             []string
 	ExtraAnnotations             map[string]string
 	ExtraLabels                  map[string]string
 	ExtraRuntimeAnnotations      map[string]string
+	FailOnDeprecatedAPIs         bool
+	ForceAdoption                bool
+	ForceReplace                 bool
+	IncludeResources             []string
 	InstallGraphPath             string
 	InstallReportPath            string
+	Interactive                  bool
+	InteractiveConfirmed         bool
 	KubeAPIServerName            string
 	KubeBurstLimit               int
 	KubeCAPath                   string
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
+	KubeDiscoveryCacheDir        string
+	KubeImpersonateGroups        []string
+	KubeImpersonateUser          string
 	KubeQPSLimit                 int
+	KubeRefreshDiscovery         bool
 	KubeSkipTLSVerify            bool
 	KubeTLSServerName            string
 	KubeToken                    string
+	KubeTokenPath                string
+	KubeWatchCache               bool
 	LogColorMode                 string
 	LogRegistryStreamOut         io.Writer
+	LogTail                      int
+	NamespaceAnnotations         map[string]string
+	NamespaceLabels              map[string]string
 	NetworkParallelism           int
+	NoManifestHashAnnotation     bool
 	NoProgressTablePrint         bool
+	NotesFunc                    NotesFunc
+	OperationRetries             int
+	OperationRetryBackoff        time.Duration
+	Parallelism                  int
+	PendingReleaseTTL            time.Duration
+	PostRenderer                 string
+	PostRendererArgs             []string
+	ProgressMode                 string
+	ProgressReporter             ProgressReporter
 	ProgressTablePrintInterval   time.Duration
+	ProtectedContextConfirmed    bool
+	ProtectedContexts            []string
+	ProtectedContextsFilePath    string
+	ReadyStableFor               time.Duration
 	RegistryCredentialsPath      string
+	ReleaseDescription           string
 	ReleaseHistoryLimit          int
 	ReleaseInfoAnnotations       map[string]string
 	ReleaseStorageDriver         string
+	RenderCacheDir               string
+	ResourceSizeLimit            int
+	Resume                       bool
 	RollbackGraphPath            string
+	SecretAgeIdentityPath        string
 	SecretKey                    string
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	SecretWorkDir                string
+	ShowLogsUntil                string
+	ShowSensitiveDiffs           bool
+	ShowTimings                  bool
+	SkipSchemaValidation         bool
+	StrictTemplates              bool
 	SubNotes                     bool
 	TempDirPath                  string
+	Timeout                      time.Duration
 	TrackCreationTimeout         time.Duration
 	TrackDeletionTimeout         time.Duration
+	TrackParallelism             int
 	TrackReadinessTimeout        time.Duration
+	ValuesEnv                    map[string]string
+	ValuesEnvPrefix              string
 	ValuesFileSets               []string
 	ValuesFilesPaths             []string
+	ValuesJSONSets               []string
+	ValuesLiteralSets            []string
 	ValuesSets                   []string
 	ValuesStringSets             []string
 }
 
-func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseInstallOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
+// ReleaseInstall returns the result even if the install failed, with whatever was done by then.
+func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseInstallOptions) (*ReleaseInstallResultV1, error) {
+	ctx, cancel := withActionTimeout(ctx, "release install", opts.Timeout)
+	defer cancel()
 
+	startedAt := time.Now()
+
+	result, err := releaseInstall(ctx, releaseName, releaseNamespace, startedAt, opts)
+	if err != nil && result == nil {
+		report := newFailedDeployReport(releaseName, releaseNamespace, startedAt, err)
+
+		if opts.InstallReportPath != "" {
+			if err := report.Save(opts.InstallReportPath); err != nil {
+				log.Default.Error(ctx, "Error: save release install report: %s", err)
+			}
+		}
+
+		result = newReleaseInstallResult(report, nil, false)
+	}
+
+	return result, classifyActionErr(wrapActionTimeoutErr(ctx, err))
+}
+
+func releaseInstall(ctx context.Context, releaseName, releaseNamespace string, startedAt time.Time, opts ReleaseInstallOptions) (*ReleaseInstallResultV1, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("get current working directory: %w", err)
+		return nil, fmt.Errorf("get current working directory: %w", err)
 	}
 
 	currentUser, err := user.Current()
 	if err != nil {
-		return fmt.Errorf("get current user: %w", err)
+		return nil, fmt.Errorf("get current user: %w", err)
 	}
 
 	opts, err = applyReleaseInstallOptionsDefaults(opts, currentDir, currentUser)
 	if err != nil {
-		return fmt.Errorf("build release install options: %w", err)
+		return nil, &UsageError{Err: fmt.Errorf("build release install options: %w", err)}
 	}
 
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+	releaseName, err = sanitizeReleaseName(ctx, releaseName, opts.AutoSanitizeReleaseName)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("validate release name: %w", err)}
 	}
 
-	if len(opts.KubeConfigPaths) > 0 {
-		var splitPaths []string
-		for _, path := range opts.KubeConfigPaths {
-			splitPaths = append(splitPaths, filepath.SplitList(path)...)
-		}
+	deployID := lo.Ternary(opts.DeployID != "", opts.DeployID, uuid.NewString())
+	log.Default.Debug(ctx, "Deploy ID: %s", deployID)
 
-		opts.KubeConfigPaths = splitPaths
+	kubeConfig, err := newReleaseInstallKubeConfig(ctx, releaseNamespace, opts)
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
 	}
 
-	// TODO(ilya-lesikov): some options are not propagated from cli/actions
-	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
-		BurstLimit:            opts.KubeBurstLimit,
-		CertificateAuthority:  opts.KubeCAPath,
-		CurrentContext:        opts.KubeContext,
-		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
-		KubeConfigBase64:      opts.KubeConfigBase64,
-		Namespace:             releaseNamespace,
-		QPSLimit:              opts.KubeQPSLimit,
-		Server:                opts.KubeAPIServerName,
-		TLSServerName:         opts.KubeTLSServerName,
-		Token:                 opts.KubeToken,
-	})
-	if err != nil {
-		return fmt.Errorf("construct kube config: %w", err)
+	if err := checkProtectedContext(ctx, kubeConfig, protectedContextCheckOptions{
+		ConfirmFunc:      opts.ConfirmFunc,
+		Confirmed:        opts.ProtectedContextConfirmed,
+		KubeContext:      opts.KubeContext,
+		Patterns:         opts.ProtectedContexts,
+		PatternsFilePath: opts.ProtectedContextsFilePath,
+	}); err != nil {
+		return nil, fmt.Errorf("check protected context: %w", err)
 	}
 
-	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig)
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+		WatchCache:        opts.KubeWatchCache,
+	})
 	if err != nil {
-		return fmt.Errorf("construct kube client factory: %w", err)
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
 	}
+	defer clientFactory.Close(context.WithoutCancel(ctx))
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
-
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-		registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+	eventRecorder, err := newEventRecorder(releaseName, releaseNamespace, opts.EmitEvents, opts.EventsInvolvedObject, clientFactory)
+	if err != nil {
+		return nil, fmt.Errorf("construct event recorder: %w", err)
 	}
 
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
-	}
+	helmSettings := newHelmSettings(ctx)
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	helmRegistryClient, err := newHelmRegistryClient(ctx, opts.LogRegistryStreamOut, opts.RegistryCredentialsPath, opts.ChartRepositoryInsecure || opts.ChartRepositoryPlainHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("construct registry client: %w", err)
+	}
+
+	opts.ChartDirPath, err = chart.ResolveChart(ctx, opts.ChartDirPath, chart.ResolveChartOptions{
+		Getters:          getter.All(helmSettings),
+		Keyring:          opts.ChartProvenanceKeyringPath,
+		Out:              logboek.Context(ctx).OutStream(),
+		PlainHTTP:        opts.ChartRepositoryPlainHTTP,
+		RegistryClient:   helmRegistryClient,
+		RepositoryCache:  helmSettings.RepositoryCache,
+		RepositoryConfig: helmSettings.RepositoryConfig,
+		TempDirPath:      opts.TempDirPath,
+		Verify:           opts.ChartProvenanceVerify,
+	})
 	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		return nil, fmt.Errorf("resolve chart: %w", err)
 	}
 
 	helmActionConfig := &action.Configuration{}
@@ -184,11 +272,10 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 			log.Default.Debug(ctx, format, a...)
 		},
 	); err != nil {
-		return fmt.Errorf("helm action config init: %w", err)
+		return nil, fmt.Errorf("helm action config init: %w", err)
 	}
 
 	helmReleaseStorage := helmActionConfig.Releases
-	helmReleaseStorage.MaxHistory = opts.ReleaseHistoryLimit
 
 	var lockManager *lock.LockManager
 	if m, err := lock.NewLockManager(
@@ -197,33 +284,36 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		clientFactory.Static(),
 		clientFactory.Dynamic(),
 	); err != nil {
-		return fmt.Errorf("construct lock manager: %w", err)
+		return nil, fmt.Errorf("construct lock manager: %w", err)
 	} else {
 		lockManager = m
 	}
 
-	chartextender.DefaultChartAPIVersion = opts.DefaultChartAPIVersion
-	chartextender.DefaultChartName = opts.DefaultChartName
-	chartextender.DefaultChartVersion = opts.DefaultChartVersion
-	chartextender.ChartAppVersion = opts.ChartAppVersion
-	loader.WithoutDefaultSecretValues = opts.DefaultSecretValuesDisable
-	loader.WithoutDefaultValues = opts.DefaultValuesDisable
-	secrets.CoalesceTablesFunc = chartutil.CoalesceTables
-	secrets.SecretsWorkingDir = opts.SecretWorkDir
-	loader.SecretValuesFiles = opts.SecretValuesPaths
-	secrets.ChartDir = opts.ChartDirPath
-	secrets_manager.DisableSecretsDecryption = opts.SecretKeyIgnore
-
-	if err := createReleaseNamespace(ctx, clientFactory, releaseNamespace); err != nil {
-		return fmt.Errorf("create release namespace: %w", err)
+	releaseNamespaceExists, releaseNamespaceReadable, err := getReleaseNamespaceState(ctx, clientFactory, releaseNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("get release namespace state: %w", err)
 	}
 
 	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Starting release")+" %q (namespace: %q)", releaseName, releaseNamespace)
 
-	if lock, err := lockManager.LockRelease(ctx, releaseName); err != nil {
-		return fmt.Errorf("lock release: %w", err)
+	// The release lock is stored in the release namespace. If the namespace doesn't exist yet, then
+	// there is no release to deploy concurrently with, and the release is locked by the plan right
+	// after the namespace is created. If someone creates the namespace or the release in the
+	// meantime, creating them fails.
+	var lockReleaseOp *operation.LockReleaseOperation
+	if releaseNamespaceExists {
+		if lock, err := lockManager.LockRelease(ctx, releaseName); err != nil {
+			return nil, fmt.Errorf("lock release: %w", err)
+		} else {
+			defer lockManager.Unlock(lock)
+		}
 	} else {
-		defer lockManager.Unlock(lock)
+		lockReleaseOp = operation.NewLockReleaseOperation(releaseName, lockManager)
+		defer func() {
+			if lock, locked := lockReleaseOp.Handle(); locked {
+				lockManager.Unlock(lock)
+			}
+		}()
 	}
 
 	log.Default.Debug(ctx, "Constructing release history")
@@ -237,17 +327,36 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("construct release history: %w", err)
+		return nil, fmt.Errorf("construct release history: %w", err)
 	}
 
 	prevRelease, prevReleaseFound, err := history.LastRelease()
 	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
+		return nil, fmt.Errorf("get last release: %w", err)
+	}
+
+	// An earlier attempt of the same deploy stored its revision, but failed before deploying it,
+	// e.g. because the release write timed out. Build this revision again instead of a new one.
+	var retriedRevision int
+	if prevReleaseFound && opts.DeployID != "" && prevRelease.Pending() && prevRelease.InfoAnnotations()[release.DeployIDInfoAnnotation] == opts.DeployID {
+		retriedRevision = prevRelease.Revision()
+		log.Default.Info(ctx, "Revision %d of release %q (namespace: %q) was stored by an earlier attempt of deploy %q, retrying it", retriedRevision, releaseName, releaseNamespace, opts.DeployID)
+
+		prevRelease, prevReleaseFound, err = history.Release(retriedRevision - 1)
+		if err != nil {
+			return nil, fmt.Errorf("get release revision %d: %w", retriedRevision-1, err)
+		}
+	}
+
+	if prevReleaseFound {
+		if err := repairPendingRelease(ctx, history, prevRelease, opts.PendingReleaseTTL); err != nil {
+			return nil, fmt.Errorf("repair pending release: %w", err)
+		}
 	}
 
 	prevDeployedRelease, prevDeployedReleaseFound, err := history.LastDeployedRelease()
 	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
+		return nil, fmt.Errorf("get last deployed release: %w", err)
 	}
 
 	var newRevision int
@@ -259,6 +368,10 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		newRevision = 1
 	}
 
+	if retriedRevision != 0 {
+		newRevision = retriedRevision
+	}
+
 	var deployType common.DeployType
 	if prevReleaseFound && prevDeployedReleaseFound {
 		deployType = common.DeployTypeUpgrade
@@ -280,8 +393,56 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		RepositoryCache:   helmSettings.RepositoryCache,
 		Debug:             helmSettings.Debug,
 	}
-	loader.SetChartPathFunc = downloader.SetChartPath
-	loader.DepsBuildFunc = downloader.Build
+
+	resourceFilter, err := matcher.NewResourceFilter(opts.IncludeResources, opts.ExcludeResources)
+	if err != nil {
+		return nil, fmt.Errorf("construct resource filter: %w", err)
+	}
+
+	postRenderer, err := chart.NewPostRenderer(opts.PostRenderer, opts.PostRendererArgs)
+	if err != nil {
+		return nil, fmt.Errorf("construct post-renderer: %w", err)
+	}
+
+	chartTreeOptions := chart.ChartTreeOptions{
+		AgeIdentityPath:            opts.SecretAgeIdentityPath,
+		StringSetValues:            opts.ValuesStringSets,
+		SetValues:                  opts.ValuesSets,
+		FileValues:                 opts.ValuesFileSets,
+		Env:                        opts.ValuesEnv,
+		EnvValuesPrefix:            opts.ValuesEnvPrefix,
+		JSONSetValues:              opts.ValuesJSONSets,
+		LiteralSetValues:           opts.ValuesLiteralSets,
+		ValuesFiles:                opts.ValuesFilesPaths,
+		SubNotes:                   opts.SubNotes,
+		Mapper:                     clientFactory.Mapper(),
+		DiscoveryClient:            clientFactory.Discovery(),
+		ResourceFilter:             resourceFilter,
+		PostRenderer:               postRenderer,
+		Getters:                    getter.All(helmSettings),
+		RegistryClient:             helmRegistryClient,
+		SecretKey:                  opts.SecretKey,
+		SecretValuesFiles:          opts.SecretValuesPaths,
+		DefaultSecretValuesDisable: opts.DefaultSecretValuesDisable,
+		ChartAppVersion:            opts.ChartAppVersion,
+		DefaultChartAPIVersion:     opts.DefaultChartAPIVersion,
+		DefaultChartName:           opts.DefaultChartName,
+		DefaultChartVersion:        opts.DefaultChartVersion,
+		DefaultValuesDisable:       opts.DefaultValuesDisable,
+		SecretKeyIgnore:            opts.SecretKeyIgnore,
+		SecretWorkDir:              opts.SecretWorkDir,
+		DependencyManager:          downloader,
+		TempDirPath:                opts.TempDirPath,
+		RenderCacheDir:             opts.RenderCacheDir,
+		StrictTemplates:            opts.StrictTemplates,
+		SkipSchemaValidation:       opts.SkipSchemaValidation,
+	}
+
+	if opts.ChartDependencyUpdate {
+		if err := chart.UpdateDependencies(ctx, opts.ChartDirPath, chartTreeOptions); err != nil {
+			return nil, fmt.Errorf("update chart dependencies: %w", err)
+		}
+	}
 
 	log.Default.Debug(ctx, "Constructing chart tree")
 	chartTree, err := chart.NewChartTree(
@@ -292,25 +453,48 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		newRevision,
 		deployType,
 		helmActionConfig,
-		chart.ChartTreeOptions{
-			StringSetValues: opts.ValuesStringSets,
-			SetValues:       opts.ValuesSets,
-			FileValues:      opts.ValuesFileSets,
-			ValuesFiles:     opts.ValuesFilesPaths,
-			SubNotes:        opts.SubNotes,
-			Mapper:          clientFactory.Mapper(),
-			DiscoveryClient: clientFactory.Discovery(),
-		},
+		chartTreeOptions,
 	)
 	if err != nil {
-		return fmt.Errorf("construct chart tree: %w", err)
+		return nil, &TemplateError{Err: fmt.Errorf("construct chart tree: %w", err)}
+	}
+
+	if chartTree.Partial() {
+		log.Default.Warn(ctx, "Only resources matching include/exclude filters will be deployed, the rest are kept in release %q as they were in the previous revision", releaseName)
+	}
+
+	log.Default.Debug(ctx, "Checking for deprecated apiVersions")
+	if err := warnDeprecatedAPIs(ctx, chartTree, clientFactory.Discovery(), opts.FailOnDeprecatedAPIs); err != nil {
+		return nil, fmt.Errorf("check for deprecated apiVersions: %w", err)
 	}
 
 	notes := chartTree.Notes()
+	notesByChart := chartTree.NotesByChart()
 
-	var prevRelGeneralResources []*resource.GeneralResource
+	var prevRelGeneralResources, excludedPrevRelGeneralResources []*resource.GeneralResource
+	var excludedPrevRelHookResources []*resource.HookResource
 	if prevReleaseFound {
-		prevRelGeneralResources = prevRelease.GeneralResources()
+		// Resources filtered out are left untouched, instead of being deleted as no longer present in the release.
+		prevRelGeneralResources, excludedPrevRelHookResources, excludedPrevRelGeneralResources = splitPrevReleaseResources(prevRelease, resourceFilter)
+	}
+
+	deployablePatchers := []resource.ResourcePatcher{
+		resource.NewExtraMetadataPatcher(
+			lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations),
+			opts.ExtraLabels,
+		),
+	}
+
+	if !opts.NoManifestHashAnnotation {
+		deployablePatchers = append(deployablePatchers, resource.NewManifestHashPatcher())
+	}
+
+	var releaseNamespaceRes *resource.ReleaseNamespace
+	if releaseNamespaceReadable {
+		releaseNamespaceRes, err = newReleaseNamespace(releaseNamespace, chartTree.FinalValues(), opts.NamespaceLabels, opts.NamespaceAnnotations, clientFactory.Mapper())
+		if err != nil {
+			return nil, fmt.Errorf("construct release namespace: %w", err)
+		}
 	}
 
 	log.Default.Debug(ctx, "Processing resources")
@@ -323,39 +507,66 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		chartTree.GeneralResources(),
 		prevRelGeneralResources,
 		resourceinfo.DeployableResourcesProcessorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
+			CRDPolicy:             resource.CRDPolicy(opts.CRDsPolicy),
+			StrictFieldValidation: opts.StrictTemplates,
+			AutoAdopt:             opts.AutoAdopt,
+			ForceAdoption:         opts.ForceAdoption,
+			ForceReplace:          opts.ForceReplace,
+			NetworkParallelism:    opts.NetworkParallelism,
+			ResourceSizeLimit:     opts.ResourceSizeLimit,
 			ReleasableHookResourcePatchers: []resource.ResourcePatcher{
 				resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 			},
 			ReleasableGeneralResourcePatchers: []resource.ResourcePatcher{
 				resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 			},
-			DeployableStandaloneCRDsPatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
-			},
-			DeployableHookResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
-			},
-			DeployableGeneralResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
-			},
-			KubeClient:         clientFactory.KubeClient(),
-			Mapper:             clientFactory.Mapper(),
-			DiscoveryClient:    clientFactory.Discovery(),
-			AllowClusterAccess: true,
+			DeployableStandaloneCRDsPatchers:  deployablePatchers,
+			DeployableHookResourcePatchers:    deployablePatchers,
+			DeployableGeneralResourcePatchers: deployablePatchers,
+			KubeClient:                        clientFactory.KubeClient(),
+			Mapper:                            clientFactory.Mapper(),
+			DiscoveryClient:                   clientFactory.Discovery(),
+			AllowClusterAccess:                true,
+			ReleaseNamespace:                  releaseNamespaceRes,
+			AdoptReleaseNamespace:             opts.AdoptNamespace,
 		},
 	)
 
 	if err := resProcessor.Process(ctx); err != nil {
-		return fmt.Errorf("process resources: %w", err)
+		return nil, fmt.Errorf("process resources: %w", err)
 	}
 
+	if nsInfo := resProcessor.DeployableReleaseNamespaceInfo(); nsInfo != nil {
+		if lockReleaseOp != nil && !nsInfo.ShouldCreate() {
+			return nil, fmt.Errorf("release namespace %q was created by someone else during the deploy, try again", releaseNamespace)
+		}
+
+		if !nsInfo.Managed() && (len(releaseNamespaceRes.Unstructured().GetLabels()) > 0 || len(releaseNamespaceRes.Unstructured().GetAnnotations()) > 0) {
+			log.Default.Warn(ctx, "Warning: release namespace %q is not owned by release %q, its labels and annotations are not changed without --adopt-namespace", releaseNamespace, releaseName)
+		}
+	}
+
+	telemetry.FromContext(ctx).SetResourceCount(len(resProcessor.DeployableStandaloneCRDsInfos()) + len(resProcessor.DeployableHookResourcesInfos()) + len(resProcessor.DeployableGeneralResourcesInfos()))
+
+	if opts.CheckReferences || opts.CheckReferencesStrict {
+		log.Default.Debug(ctx, "Checking references")
+		if err := checkReferences(ctx, resProcessor, clientFactory, opts.CheckReferencesStrict); err != nil {
+			return nil, err
+		}
+	}
+
+	description := opts.ReleaseDescription
+	if description == "" {
+		description = release.DefaultDescription(deployType, chartTree.LegacyChart().Metadata.Name, chartTree.LegacyChart().Metadata.Version)
+	}
+
+	// Info annotations are kept between revisions, unless overridden.
+	infoAnnotations := map[string]string{}
+	if prevReleaseFound {
+		infoAnnotations = lo.OmitByKeys(prevRelease.InfoAnnotations(), []string{release.DeployIDInfoAnnotation, release.DegradedResourcesInfoAnnotation})
+	}
+	infoAnnotations = lo.Assign(infoAnnotations, opts.ReleaseInfoAnnotations, map[string]string{release.DeployIDInfoAnnotation: deployID})
+
 	log.Default.Debug(ctx, "Constructing new release")
 	newRel, err := release.NewRelease(
 		releaseName,
@@ -363,17 +574,18 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		newRevision,
 		chartTree.ReleaseValues(),
 		chartTree.LegacyChart(),
-		resProcessor.ReleasableHookResources(),
-		resProcessor.ReleasableGeneralResources(),
+		slices.Concat(resProcessor.ReleasableHookResources(), excludedPrevRelHookResources),
+		slices.Concat(resProcessor.ReleasableGeneralResources(), excludedPrevRelGeneralResources),
 		notes,
 		release.ReleaseOptions{
-			InfoAnnotations: opts.ReleaseInfoAnnotations,
+			Description:     description,
+			InfoAnnotations: infoAnnotations,
 			FirstDeployed:   firstDeployed,
 			Mapper:          clientFactory.Mapper(),
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("construct new release: %w", err)
+		return nil, fmt.Errorf("construct new release: %w", err)
 	}
 
 	taskStore := statestore.NewTaskStore()
@@ -381,6 +593,12 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		logstore.NewLogStore(),
 	)
 
+	var logFollower *track.LogFollower
+	if opts.ShowLogsUntil == ShowLogsUntilEnd && opts.ProgressMode != ProgressModeNone {
+		logFollower = track.NewLogFollower(ctx, clientFactory.Static(), logStore)
+		defer logFollower.Stop()
+	}
+
 	log.Default.Debug(ctx, "Constructing new deploy plan")
 	deployPlanBuilder := plan.NewDeployPlanBuilder(
 		releaseNamespace,
@@ -399,11 +617,18 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		clientFactory.Discovery(),
 		clientFactory.Mapper(),
 		plan.DeployPlanBuilderOptions{
-			PrevRelease:         prevRelease,
-			PrevDeployedRelease: prevDeployedRelease,
-			CreationTimeout:     opts.TrackCreationTimeout,
-			ReadinessTimeout:    opts.TrackReadinessTimeout,
-			DeletionTimeout:     opts.TrackDeletionTimeout,
+			CRDPolicy:            resource.CRDPolicy(opts.CRDsPolicy),
+			PrevRelease:          prevRelease,
+			PrevDeployedRelease:  prevDeployedRelease,
+			CreationTimeout:      opts.TrackCreationTimeout,
+			ReadinessTimeout:     opts.TrackReadinessTimeout,
+			DeletionTimeout:      opts.TrackDeletionTimeout,
+			ReadyStableFor:       opts.ReadyStableFor,
+			IgnoreLogs:           opts.ProgressMode == ProgressModeNone,
+			LogFollower:          logFollower,
+			ForceReplace:         opts.ForceReplace,
+			ReleaseNamespaceInfo: resProcessor.DeployableReleaseNamespaceInfo(),
+			LockReleaseOperation: lockReleaseOp,
 		},
 	)
 
@@ -416,23 +641,39 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 			graphPath = filepath.Join(opts.TempDirPath, "release-install-graph.dot")
 		}
 
-		if _, err := os.Create(graphPath); err != nil {
-			log.Default.Error(ctx, "Error: create release install graph file: %s", err)
-			return fmt.Errorf("build deploy plan: %w", planBuildErr)
-		}
-
 		if err := deployPlan.SaveDOT(graphPath); err != nil {
 			log.Default.Error(ctx, "Error: save release install graph: %s", err)
+		} else {
+			log.Default.Warn(ctx, "Release install graph saved to %q for debugging", graphPath)
 		}
 
-		log.Default.Warn(ctx, "Release install graph saved to %q for debugging", graphPath)
-
-		return fmt.Errorf("build release install plan: %w", planBuildErr)
+		return nil, fmt.Errorf("build release install plan: %w", planBuildErr)
 	}
 
 	if opts.InstallGraphPath != "" {
 		if err := deployPlan.SaveDOT(opts.InstallGraphPath); err != nil {
-			return fmt.Errorf("save release install graph: %w", err)
+			return nil, fmt.Errorf("save release install graph: %w", err)
+		}
+	}
+
+	if err := deployPlan.Validate(); err != nil {
+		return nil, fmt.Errorf("validate release install plan: %w", err)
+	}
+
+	var planStateStore *plan.PlanStateStore
+	if opts.Resume {
+		chartContentHash, err := chartTree.ContentHash()
+		if err != nil {
+			return nil, fmt.Errorf("get chart content hash: %w", err)
+		}
+
+		planStateStore = plan.NewPlanStateStore(releaseName, releaseNamespace, chartContentHash, clientFactory.Static())
+
+		if state, found, err := planStateStore.Load(ctx); err != nil {
+			return nil, fmt.Errorf("load release install plan state: %w", err)
+		} else if found {
+			resumedOpsCount := deployPlan.MarkResumed(state)
+			log.Default.Info(ctx, "Resuming interrupted deploy, %d operations already completed", resumedOpsCount)
 		}
 	}
 
@@ -440,79 +681,129 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 	if prevReleaseFound {
 		releaseUpToDate, err = release.ReleaseUpToDate(prevRelease, newRel)
 		if err != nil {
-			return fmt.Errorf("check if release is up to date: %w", err)
+			return nil, fmt.Errorf("check if release is up to date: %w", err)
 		}
 	}
 
 	planUseless, err := deployPlan.Useless()
 	if err != nil {
-		return fmt.Errorf("check if release install plan will do anything useful: %w", err)
+		return nil, fmt.Errorf("check if release install plan will do anything useful: %w", err)
+	}
+
+	reportOpts := reportOptions{
+		StartedAt: startedAt,
+		Plan:      deployPlan,
+	}
+	if prevReleaseFound {
+		reportOpts.PreviousRevision = prevRelease.Revision()
 	}
 
 	if releaseUpToDate && planUseless {
-		if opts.InstallReportPath != "" {
-			newRel.Skip()
+		newRel.Skip()
 
-			report := newReport(nil, nil, nil, newRel)
+		report := newReport(nil, nil, nil, newRel, reportOpts)
 
+		if opts.InstallReportPath != "" {
 			if err := report.Save(opts.InstallReportPath); err != nil {
 				log.Default.Error(ctx, "Error: save release install report: %s", err)
 			}
 		}
 
-		printNotes(ctx, notes)
+		releaseNotes := newReleaseNotes(notesByChart, notes)
+		if opts.NotesFunc != nil {
+			opts.NotesFunc(ctx, releaseNotes)
+		}
 
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Skipped release %q (namespace: %q): cluster resources already as desired", releaseName, releaseNamespace)))
 
-		return nil
+		return newReleaseInstallResult(report.DeployReport(), releaseNotes, true), nil
+	}
+
+	if opts.Interactive {
+		var prevRelFailed bool
+		if prevReleaseFound {
+			prevRelFailed = prevRelease.Failed()
+		}
+
+		if err := confirmReleaseInstallPlan(ctx, releaseName, releaseNamespace, !releaseUpToDate, prevRelFailed, deployPlan, resProcessor, opts); err != nil {
+			return nil, err
+		}
 	}
 
 	tablesBuilder := track.NewTablesBuilder(
 		taskStore,
-		logStore,
 		track.TablesBuilderOptions{
 			DefaultNamespace: releaseNamespace,
 			Colorize:         opts.LogColorMode == LogColorModeOn,
 		},
 	)
 
+	logStreamer := track.NewLogStreamer(
+		taskStore,
+		logStore,
+		track.LogStreamerOptions{
+			DefaultNamespace: releaseNamespace,
+			Colorize:         opts.LogColorMode == LogColorModeOn,
+			Tail:             opts.LogTail,
+			Until:            track.ShowLogsUntil(opts.ShowLogsUntil),
+		},
+	)
+
 	log.Default.Debug(ctx, "Starting tracking")
-	stdoutTrackerStopCh := make(chan bool)
-	stdoutTrackerFinishedCh := make(chan bool)
-
-	if !opts.NoProgressTablePrint {
-		go func() {
-			ticker := time.NewTicker(opts.ProgressTablePrintInterval)
-			defer func() {
-				ticker.Stop()
-				stdoutTrackerFinishedCh <- true
-			}()
-
-			for {
-				select {
-				case <-ticker.C:
-					printTables(ctx, tablesBuilder)
-				case <-stdoutTrackerStopCh:
-					printTables(ctx, tablesBuilder)
-					return
-				}
-			}
-		}()
+	ctx, stopProgressPrinting := startProgressPrinting(ctx, opts.ProgressMode, opts.ProgressTablePrintInterval, tablesBuilder, logStreamer)
+
+	if eventRecorder != nil {
+		eventRecorder.Started(ctx, "install", newRel.Revision())
 	}
 
 	log.Default.Debug(ctx, "Executing release install plan")
+	var onOperationCompleted func(ctx context.Context, op operation.Operation)
+	if planStateStore != nil {
+		onOperationCompleted = planStateStore.Record
+	}
+
 	planExecutor := plan.NewPlanExecutor(
 		deployPlan,
 		plan.PlanExecutorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
+			InterruptGracePeriod: DefaultInterruptGracePeriod,
+			OnOperationCompleted: onOperationCompleted,
+			Parallelism:          opts.Parallelism,
+			ProgressReporter:     opts.ProgressReporter,
+			RetryPolicy: plan.OperationRetryPolicy{
+				Retries: opts.OperationRetries,
+				Backoff: opts.OperationRetryBackoff,
+			},
+			TrackParallelism: opts.TrackParallelism,
 		},
 	)
 
 	var criticalErrs, nonCriticalErrs []error
 
 	planExecutionErr := planExecutor.Execute(ctx)
+
+	// The release must be finalized even if interrupted, so don't let the cancellation propagate
+	// any further.
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		if timeoutErr, timedOut := actionTimedOut(ctx); timedOut {
+			log.Default.Warn(ctx, "Release install timed out after %s, finalizing release %q (namespace: %q)", timeoutErr.Timeout, releaseName, releaseNamespace)
+		} else {
+			log.Default.Warn(ctx, "Release install interrupted, finalizing release %q (namespace: %q)", releaseName, releaseNamespace)
+		}
+
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	if err := saveExecutedPlanGraph(ctx, deployPlan, opts.InstallGraphPath, filepath.Join(opts.TempDirPath, "release-install-graph.dot"), planExecutionErr != nil); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save release install graph: %w", err))
+	}
+
 	if planExecutionErr != nil {
 		criticalErrs = append(criticalErrs, fmt.Errorf("execute release install plan: %w", planExecutionErr))
+	} else if planStateStore != nil {
+		if err := planStateStore.Delete(ctx); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("delete release install plan state: %w", err))
+		}
 	}
 
 	var worthyCompletedOps []operation.Operation
@@ -557,7 +848,9 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 			prevRelease,
 			history,
 			clientFactory,
-			opts.NetworkParallelism,
+			opts.CleanupOnFail,
+			opts.Parallelism,
+			opts.TrackParallelism,
 		)
 
 		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
@@ -566,7 +859,8 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		criticalErrs = append(criticalErrs, criterrs...)
 		nonCriticalErrs = append(nonCriticalErrs, noncriterrs...)
 
-		if opts.AutoRollback && prevDeployedReleaseFound {
+		if opts.AutoRollback && prevDeployedReleaseFound && !interrupted {
+			notesByChart = nil
 			wcompops, wfailops, wcancops, notes, criterrs, noncriterrs = runRollbackPlan(
 				ctx,
 				taskStore,
@@ -587,6 +881,8 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 				opts.TrackDeletionTimeout,
 				opts.RollbackGraphPath,
 				opts.NetworkParallelism,
+				opts.Parallelism,
+				opts.TrackParallelism,
 			)
 
 			worthyCompletedOps = append(worthyCompletedOps, wcompops...)
@@ -597,39 +893,159 @@ func ReleaseInstall(ctx context.Context, releaseName, releaseNamespace string, o
 		}
 	}
 
-	if !opts.NoProgressTablePrint {
-		stdoutTrackerStopCh <- true
-		<-stdoutTrackerFinishedCh
+	if logFollower != nil {
+		logFollower.Stop()
 	}
 
+	stopProgressPrinting()
+
 	report := newReport(
 		worthyCompletedOps,
 		worthyCanceledOps,
 		worthyFailedOps,
 		newRel,
+		reportOpts,
 	)
 
 	report.Print(ctx)
 
+	if opts.ShowTimings {
+		if timingsReport, err := deployPlan.TimingsReport(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("build release install timings report: %w", err))
+		} else {
+			printTimingsReport(ctx, timingsReport)
+		}
+	}
+
 	if opts.InstallReportPath != "" {
 		if err := report.Save(opts.InstallReportPath); err != nil {
 			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save release install report: %w", err))
 		}
 	}
 
+	releaseNotes := newReleaseNotes(notesByChart, notes)
+
+	// Pruned on failures too, so that the history of a release which keeps failing doesn't grow.
+	if err := history.PruneReleases(ctx, opts.ReleaseHistoryLimit); err != nil {
+		log.Default.Warn(ctx, "Warning: prune release history: %s", err)
+	}
+
 	if len(criticalErrs) == 0 {
-		printNotes(ctx, notes)
+		logKeptResources(ctx, deployPlanBuilder.KeptResources())
+
+		if opts.NotesFunc != nil {
+			opts.NotesFunc(ctx, releaseNotes)
+		}
+	}
+
+	result := newReleaseInstallResult(report.DeployReport(), releaseNotes, false)
+
+	if eventRecorder != nil {
+		if len(criticalErrs) > 0 {
+			eventRecorder.Failed(ctx, "install", newRel.Revision(), operationsHumanIDs(worthyFailedOps), criticalErrs)
+		} else {
+			eventRecorder.Succeeded(ctx, "install", newRel.Revision())
+		}
 	}
 
 	if len(criticalErrs) > 0 {
-		return util.Multierrorf("failed release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), releaseName, releaseNamespace)
+		return result, util.Multierrorf("failed release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), releaseName, releaseNamespace)
 	} else if len(nonCriticalErrs) > 0 {
-		return util.Multierrorf("succeeded release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, releaseName, releaseNamespace)
+		return result, util.Multierrorf("succeeded release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, releaseName, releaseNamespace)
 	} else {
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Succeeded release %q (namespace: %q)", releaseName, releaseNamespace)))
 
+		return result, nil
+	}
+}
+
+// confirmReleaseInstallPlan shows the planned changes with diffs and asks whether to apply them.
+// Nothing is deployed yet at this point, even the release namespace is created by the plan.
+func confirmReleaseInstallPlan(
+	ctx context.Context,
+	releaseName string,
+	releaseNamespace string,
+	releaseChangesPlanned bool,
+	prevRelFailed bool,
+	deployPlan *plan.Plan,
+	resProcessor *resourceinfo.DeployableResourcesProcessor,
+	opts ReleaseInstallOptions,
+) error {
+	createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, _ := plan.CalculatePlannedChanges(
+		releaseName,
+		releaseNamespace,
+		resProcessor.DeployableReleaseNamespaceInfo(),
+		resProcessor.DeployableStandaloneCRDsInfos(),
+		resProcessor.DeployableHookResourcesInfos(),
+		resProcessor.DeployableGeneralResourcesInfos(),
+		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+		prevRelFailed,
+		plan.CalculatePlannedChangesOptions{
+			DiffContextLines:   opts.DiffContextLines,
+			ShowSensitiveDiffs: opts.ShowSensitiveDiffs,
+		},
+	)
+
+	plan.LogPlannedChanges(
+		ctx,
+		releaseName,
+		releaseNamespace,
+		releaseChangesPlanned,
+		createdChanges,
+		recreatedChanges,
+		updatedChanges,
+		appliedChanges,
+		deletedChanges,
+		plan.LogPlannedChangesOptions{
+			ShowDiff: true,
+		},
+	)
+
+	plan.LogStagesSummary(ctx, deployPlan.StagesSummary())
+
+	if opts.InteractiveConfirmed {
+		log.Default.Info(ctx, "Applying the planned changes, since confirmed in advance")
 		return nil
 	}
+
+	changesCount := len(createdChanges) + len(recreatedChanges) + len(updatedChanges) + len(appliedChanges) + len(deletedChanges)
+
+	prompt := fmt.Sprintf("Apply these %d changes to release %q (namespace: %q)?", changesCount, releaseName, releaseNamespace)
+	if changesCount == 0 {
+		prompt = fmt.Sprintf("Create new revision of release %q (namespace: %q)?", releaseName, releaseNamespace)
+	}
+
+	if confirmed, err := askConfirmation(ctx, opts.ConfirmFunc, prompt); err != nil {
+		return fmt.Errorf("confirm release install plan: %w, pass --yes to apply the changes without confirmation", err)
+	} else if !confirmed {
+		return ErrDeployNotConfirmed
+	}
+
+	return nil
+}
+
+func newReleaseInstallKubeConfig(ctx context.Context, releaseNamespace string, opts ReleaseInstallOptions) (*kube.KubeConfig, error) {
+	var kubeConfigPaths []string
+	for _, path := range opts.KubeConfigPaths {
+		kubeConfigPaths = append(kubeConfigPaths, filepath.SplitList(path)...)
+	}
+
+	// TODO(ilya-lesikov): some options are not propagated from cli/actions
+	return kube.NewKubeConfig(ctx, kubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
 }
 
 func applyReleaseInstallOptionsDefaults(
@@ -641,6 +1057,10 @@ func applyReleaseInstallOptionsDefaults(
 		opts.ChartDirPath = currentDir
 	}
 
+	if opts.DiffContextLines < 0 {
+		opts.DiffContextLines = DefaultDiffContextLines
+	}
+
 	var err error
 	if opts.TempDirPath == "" {
 		opts.TempDirPath, err = os.MkdirTemp("", "")
@@ -659,6 +1079,10 @@ func applyReleaseInstallOptionsDefaults(
 
 	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
 
+	if opts.CRDsPolicy, err = applyCRDsPolicyDefault(opts.CRDsPolicy); err != nil {
+		return ReleaseInstallOptions{}, fmt.Errorf("apply CRDs policy default: %w", err)
+	}
+
 	if opts.NetworkParallelism <= 0 {
 		opts.NetworkParallelism = DefaultNetworkParallelism
 	}
@@ -671,10 +1095,28 @@ func applyReleaseInstallOptionsDefaults(
 		opts.KubeBurstLimit = DefaultBurstLimit
 	}
 
+	if opts.OperationRetryBackoff <= 0 {
+		opts.OperationRetryBackoff = DefaultOperationRetryBackoff
+	}
+
+	opts.ProgressMode, err = applyProgressModeDefault(opts.ProgressMode, opts.NoProgressTablePrint)
+	if err != nil {
+		return ReleaseInstallOptions{}, fmt.Errorf("apply progress mode default: %w", err)
+	}
+
 	if opts.ProgressTablePrintInterval <= 0 {
 		opts.ProgressTablePrintInterval = DefaultProgressPrintInterval
 	}
 
+	opts.ShowLogsUntil, err = applyShowLogsUntilDefault(opts.ShowLogsUntil)
+	if err != nil {
+		return ReleaseInstallOptions{}, fmt.Errorf("apply show logs until default: %w", err)
+	}
+
+	if opts.LogTail <= 0 {
+		opts.LogTail = DefaultLogTail
+	}
+
 	if opts.ReleaseHistoryLimit <= 0 {
 		opts.ReleaseHistoryLimit = DefaultReleaseHistoryLimit
 	}
@@ -696,72 +1138,267 @@ func applyReleaseInstallOptionsDefaults(
 		opts.RegistryCredentialsPath = DefaultRegistryCredentialsPath
 	}
 
+	if opts.ChartProvenanceKeyringPath == "" {
+		opts.ChartProvenanceKeyringPath = DefaultChartProvenanceKeyringPath
+	}
+
 	return opts, nil
 }
 
-func createReleaseNamespace(
-	ctx context.Context,
-	clientFactory *kube.ClientFactory,
-	releaseNamespace string,
-) error {
-	releaseNamespaceResource := resource.NewReleaseNamespace(
-		&unstructured.Unstructured{
-			Object: map[string]interface{}{
-				"apiVersion": "v1",
-				"kind":       "Namespace",
-				"metadata": map[string]interface{}{
-					"name": releaseNamespace,
-				},
+// The release namespace can't be read without the permissions to get namespaces. It is assumed to
+// exist then, and is left untouched.
+func getReleaseNamespaceState(ctx context.Context, clientFactory *kube.ClientFactory, releaseNamespace string) (exists, readable bool, err error) {
+	namespaceID := id.NewResourceID(
+		releaseNamespace,
+		"",
+		schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		id.ResourceIDOptions{Mapper: clientFactory.Mapper()},
+	)
+
+	if _, err := clientFactory.KubeClient().Get(ctx, namespaceID, kube.KubeClientGetOptions{
+		TryCache: true,
+	}); err != nil {
+		if errors.IsNotFound(err) {
+			return false, true, nil
+		} else if errors.IsForbidden(err) {
+			log.Default.Debug(ctx, "Not allowed to get release namespace %q, assuming it exists: %s", releaseNamespace, err)
+			return true, false, nil
+		}
+
+		return false, false, fmt.Errorf("get release namespace: %w", err)
+	}
+
+	return true, true, nil
+}
+
+// Labels and annotations of the release namespace are taken from the "werf.releaseNamespace" chart
+// value, the ones passed explicitly take precedence.
+func newReleaseNamespace(releaseNamespace string, values map[string]interface{}, labels, annotations map[string]string, mapper meta.ResettableRESTMapper) (*resource.ReleaseNamespace, error) {
+	valuesLabels, err := releaseNamespaceMetadataFromValues(values, "labels")
+	if err != nil {
+		return nil, err
+	}
+
+	valuesAnnotations, err := releaseNamespaceMetadataFromValues(values, "annotations")
+	if err != nil {
+		return nil, err
+	}
+
+	unstruct := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]interface{}{
+				"name": releaseNamespace,
 			},
-		}, resource.ReleaseNamespaceOptions{
-			Mapper: clientFactory.Mapper(),
 		},
-	)
+	}
 
-	if _, err := clientFactory.KubeClient().Get(
-		ctx,
-		releaseNamespaceResource.ResourceID,
-		kube.KubeClientGetOptions{
-			TryCache: true,
+	if l := lo.Assign(valuesLabels, labels); len(l) > 0 {
+		unstruct.SetLabels(l)
+	}
+
+	if a := lo.Assign(valuesAnnotations, annotations); len(a) > 0 {
+		unstruct.SetAnnotations(a)
+	}
+
+	return resource.NewReleaseNamespace(unstruct, resource.ReleaseNamespaceOptions{
+		Mapper: mapper,
+	}), nil
+}
+
+func releaseNamespaceMetadataFromValues(values map[string]interface{}, field string) (map[string]string, error) {
+	path := []string{"werf", "releaseNamespace", field}
+
+	value, found, err := unstructured.NestedFieldNoCopy(values, path...)
+	if err != nil {
+		return nil, fmt.Errorf("get chart value %q: %w", strings.Join(path, "."), err)
+	} else if !found || value == nil {
+		return nil, nil
+	}
+
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("chart value %q must be a map, but got %T", strings.Join(path, "."), value)
+	}
+
+	result := make(map[string]string, len(valueMap))
+	for key, val := range valueMap {
+		valStr, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("chart value %q must be a string, but got %T", strings.Join(append(path, key), "."), val)
+		}
+
+		result[key] = valStr
+	}
+
+	return result, nil
+}
+
+const ReleaseInstallResultApiVersionV1 = "v1"
+
+type ReleaseInstallResultV1 struct {
+	ApiVersion string                       `json:"apiVersion"`
+	Release    *ReleaseInstallResultRelease `json:"release"`
+	// Zero if there was no previous release revision.
+	PreviousRevision int `json:"previousRevision,omitempty"`
+	// Cluster resources were already as desired, so nothing was deployed.
+	Useless          bool                            `json:"useless"`
+	CreatedResources []*ReleaseInstallResultResource `json:"createdResources"`
+	// Recreated resources are counted as updated.
+	UpdatedResources []*ReleaseInstallResultResource `json:"updatedResources"`
+	DeletedResources []*ReleaseInstallResultResource `json:"deletedResources"`
+	// The same report as saved with --save-report.
+	Report *DeployReport `json:"report"`
+}
+
+type ReleaseInstallResultRelease struct {
+	Name      string             `json:"name"`
+	Namespace string             `json:"namespace"`
+	Revision  int                `json:"revision"`
+	Status    helmrelease.Status `json:"status"`
+	// Notes of all charts of the release, as stored in the release.
+	Notes        string          `json:"notes"`
+	NotesByChart []*ReleaseNotes `json:"notesByChart"`
+}
+
+type ReleaseInstallResultResource struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func newReleaseInstallResult(report *DeployReport, notes []*ReleaseNotes, useless bool) *ReleaseInstallResultV1 {
+	result := &ReleaseInstallResultV1{
+		ApiVersion: ReleaseInstallResultApiVersionV1,
+		Release: &ReleaseInstallResultRelease{
+			Name:         report.Release,
+			Namespace:    report.Namespace,
+			Revision:     report.Revision,
+			Status:       report.Status,
+			Notes:        joinReleaseNotes(notes),
+			NotesByChart: notes,
 		},
-	); err != nil {
-		if errors.IsNotFound(err) {
-			log.Default.Debug(ctx, "Creating release namespace %q", releaseNamespace)
-
-			createOp := operation.NewCreateResourceOperation(
-				releaseNamespaceResource.ResourceID,
-				releaseNamespaceResource.Unstructured(),
-				clientFactory.KubeClient(),
-				operation.CreateResourceOperationOptions{
-					ManageableBy: resource.ManageableByAnyone,
-				},
-			)
+		PreviousRevision: report.PreviousRevision,
+		Useless:          useless,
+		CreatedResources: []*ReleaseInstallResultResource{},
+		UpdatedResources: []*ReleaseInstallResultResource{},
+		DeletedResources: []*ReleaseInstallResultResource{},
+		Report:           report,
+	}
+
+	for _, res := range report.Resources {
+		resultRes := &ReleaseInstallResultResource{
+			Group:     res.Group,
+			Version:   res.Version,
+			Kind:      res.Kind,
+			Namespace: res.Namespace,
+			Name:      res.Name,
+		}
 
-			if err := createOp.Execute(ctx); err != nil {
-				return fmt.Errorf("create release namespace: %w", err)
-			}
-		} else if errors.IsForbidden(err) {
-		} else {
-			return fmt.Errorf("get release namespace: %w", err)
+		switch res.Outcome {
+		case DeployReportResourceOutcomeCreated:
+			result.CreatedResources = append(result.CreatedResources, resultRes)
+		case DeployReportResourceOutcomeUpdated, DeployReportResourceOutcomeRecreated:
+			result.UpdatedResources = append(result.UpdatedResources, resultRes)
+		case DeployReportResourceOutcomeDeleted:
+			result.DeletedResources = append(result.DeletedResources, resultRes)
 		}
 	}
 
-	return nil
+	return result
+}
+
+// ReleaseNotes are the rendered NOTES.txt of a chart of the release.
+type ReleaseNotes struct {
+	// Path of the chart in the chart tree, e.g. "app" or "app/charts/redis". Empty if the notes
+	// couldn't be split by chart.
+	ChartPath string `json:"chartPath,omitempty"`
+	Notes     string `json:"notes"`
+}
+
+// NotesFunc receives the release notes after a successful deploy or rollback, e.g. PrintReleaseNotes.
+// Without it nothing is done with the notes, but they are returned in the result anyway.
+type NotesFunc func(ctx context.Context, notes []*ReleaseNotes)
+
+// PrintReleaseNotes prints the notes of each chart in a separate block, unless there are only notes
+// of the top-level chart.
+func PrintReleaseNotes(ctx context.Context, notes []*ReleaseNotes) {
+	if len(notes) <= 1 {
+		joinedNotes := joinReleaseNotes(notes)
+		if joinedNotes == "" {
+			return
+		}
+
+		log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Release notes")).Do(func() {
+			log.Default.Info(ctx, joinedNotes)
+		})
+
+		return
+	}
+
+	for _, chartNotes := range notes {
+		log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render(fmt.Sprintf("Release notes of chart %q", chartNotes.ChartPath))).Do(func() {
+			log.Default.Info(ctx, "%s", chartNotes.Notes)
+		})
+	}
+}
+
+// newReleaseNotes uses notesByChart if they are known, otherwise the notes of the whole release.
+func newReleaseNotes(notesByChart []*chart.ChartNotes, notes string) []*ReleaseNotes {
+	if notesByChart == nil {
+		if notes == "" {
+			return []*ReleaseNotes{}
+		}
+
+		return []*ReleaseNotes{{Notes: notes}}
+	}
+
+	result := []*ReleaseNotes{}
+	for _, chartNotes := range notesByChart {
+		result = append(result, &ReleaseNotes{ChartPath: chartNotes.ChartPath, Notes: chartNotes.Notes})
+	}
+
+	return result
 }
 
-func printNotes(ctx context.Context, notes string) {
-	if notes == "" {
+func joinReleaseNotes(notes []*ReleaseNotes) string {
+	var result []string
+	for _, n := range notes {
+		result = append(result, n.Notes)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+func printTimingsReport(ctx context.Context, report *plan.TimingsReport) {
+	if len(report.Slowest) == 0 {
 		return
 	}
 
-	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Release notes")).Do(func() {
-		log.Default.Info(ctx, notes)
+	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Timings")).Do(func() {
+		log.Default.Info(ctx, "Total: %s", report.Total.Round(time.Millisecond))
+
+		log.Default.Info(ctx, "")
+		log.Default.Info(ctx, "Slowest operations:")
+		for _, timing := range report.Slowest {
+			log.Default.Info(ctx, "  %s  %s", timing.Duration().Round(time.Millisecond), timing.HumanID)
+		}
+
+		log.Default.Info(ctx, "")
+		log.Default.Info(ctx, "Critical path:")
+		for _, timing := range report.CriticalPath {
+			log.Default.Info(ctx, "  %s  %s", timing.Duration().Round(time.Millisecond), timing.HumanID)
+		}
 	})
 }
 
 func printTables(
 	ctx context.Context,
 	tablesBuilder *track.TablesBuilder,
+	logStreamer *track.LogStreamer,
 ) {
 	maxTableWidth := logboek.Context(ctx).Streams().ContentWidth() - 2
 	tablesBuilder.SetMaxTableWidth(maxTableWidth)
@@ -778,17 +1415,7 @@ func printTables(
 		}
 	}
 
-	if tables, nonEmpty := tablesBuilder.BuildLogTables(); nonEmpty {
-		headers := lo.Keys(tables)
-		sort.Strings(headers)
-
-		for _, header := range headers {
-			logboek.Context(ctx).LogBlock(header).Do(func() {
-				tables[header].SuppressTrailingSpaces()
-				logboek.Context(ctx).LogLn(tables[header].Render())
-			})
-		}
-	}
+	logStreamer.Stream(ctx)
 
 	if table, nonEmpty := tablesBuilder.BuildProgressTable(); nonEmpty {
 		logboek.Context(ctx).LogBlock(color.Style{color.Bold, color.Blue}.Render("Progress status")).Do(func() {
@@ -808,7 +1435,9 @@ func runFailureDeployPlan(
 	newRel, prevRelease *release.Release,
 	history *release.History,
 	clientFactory *kube.ClientFactory,
-	networkParallelism int,
+	cleanupOnFail bool,
+	parallelism int,
+	trackParallelism int,
 ) (
 	worthyCompletedOps []operation.Operation,
 	worthyFailedOps []operation.Operation,
@@ -830,7 +1459,8 @@ func runFailureDeployPlan(
 		clientFactory.Dynamic(),
 		clientFactory.Mapper(),
 		plan.DeployFailurePlanBuilderOptions{
-			PrevRelease: prevRelease,
+			PrevRelease:   prevRelease,
+			CleanupOnFail: cleanupOnFail,
 		},
 	)
 
@@ -839,6 +1469,10 @@ func runFailureDeployPlan(
 		return nil, nil, nil, []error{fmt.Errorf("build failure plan: %w", err)}, nil
 	}
 
+	if cleanupOnFail {
+		defer logCleanupOnFailResources(ctx, failurePlan, failurePlanBuilder.CleanupOnFailResources(), failurePlanBuilder.KeptOnFailResources())
+	}
+
 	if useless, err := failurePlan.Useless(); err != nil {
 		return nil, nil, nil, []error{fmt.Errorf("check if failure plan do anything useful: %w", err)}, nil
 	} else if useless {
@@ -849,7 +1483,8 @@ func runFailureDeployPlan(
 	failurePlanExecutor := plan.NewPlanExecutor(
 		failurePlan,
 		plan.PlanExecutorOptions{
-			NetworkParallelism: networkParallelism,
+			Parallelism:      parallelism,
+			TrackParallelism: trackParallelism,
 		},
 	)
 
@@ -878,6 +1513,45 @@ func runFailureDeployPlan(
 	return worthyCompletedOps, worthyFailedOps, worthyCanceledOps, criticalErrs, nonCriticalErrs
 }
 
+func logCleanupOnFailResources(ctx context.Context, failurePlan *plan.Plan, cleanupResources []*id.ResourceID, keptResources []*plan.KeptOnFailResource) {
+	if len(cleanupResources) > 0 {
+		log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Cleaned up resources")).Do(func() {
+			for _, resID := range cleanupResources {
+				if op, found := failurePlan.Operation(operation.TypeTrackResourceAbsenceOperation + "/" + resID.ID()); found && op.Status() == operation.StatusCompleted {
+					log.Default.Info(ctx, "%s", resID.HumanID())
+				} else {
+					log.Default.Info(ctx, "%s (cleanup failed)", resID.HumanID())
+				}
+			}
+		})
+	}
+
+	if len(keptResources) > 0 {
+		log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Kept resources")).Do(func() {
+			for _, res := range keptResources {
+				log.Default.Info(ctx, "%s (%s)", res.HumanID(), res.Reason)
+			}
+		})
+	}
+}
+
+func logKeptResources(ctx context.Context, keptResources []*plan.KeptResource) {
+	if len(keptResources) == 0 {
+		return
+	}
+
+	title := fmt.Sprintf("Kept %d resources", len(keptResources))
+	if len(keptResources) == 1 {
+		title = "Kept 1 resource"
+	}
+
+	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render(title)).Do(func() {
+		for _, res := range keptResources {
+			log.Default.Info(ctx, "%s (%s)", res.HumanID(), res.Reason)
+		}
+	})
+}
+
 func runRollbackPlan(
 	ctx context.Context,
 	taskStore *statestore.TaskStore,
@@ -898,6 +1572,8 @@ func runRollbackPlan(
 	trackDeletionTimeout time.Duration,
 	rollbackGraphPath string,
 	networkParallelism int,
+	parallelism int,
+	trackParallelism int,
 ) (
 	worthyCompletedOps []operation.Operation,
 	worthyFailedOps []operation.Operation,
@@ -962,6 +1638,7 @@ func runRollbackPlan(
 		resProcessor.ReleasableGeneralResources(),
 		prevDeployedRelease.Notes(),
 		release.ReleaseOptions{
+			Description:   fmt.Sprintf("Rollback to %d", prevDeployedRelease.Revision()),
 			FirstDeployed: prevDeployedRelease.FirstDeployed(),
 			Mapper:        clientFactory.Mapper(),
 		},
@@ -1019,11 +1696,17 @@ func runRollbackPlan(
 	rollbackPlanExecutor := plan.NewPlanExecutor(
 		rollbackPlan,
 		plan.PlanExecutorOptions{
-			NetworkParallelism: networkParallelism,
+			Parallelism:      parallelism,
+			TrackParallelism: trackParallelism,
 		},
 	)
 
 	rollbackPlanExecutionErr := rollbackPlanExecutor.Execute(ctx)
+
+	if err := saveExecutedPlanGraph(ctx, rollbackPlan, rollbackGraphPath, "", rollbackPlanExecutionErr != nil); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save rollback graph: %w", err))
+	}
+
 	if rollbackPlanExecutionErr != nil {
 		criticalErrs = append(criticalErrs, fmt.Errorf("execute rollback plan: %w", rollbackPlanExecutionErr))
 	}
@@ -1067,7 +1750,9 @@ func runRollbackPlan(
 			failedRelease,
 			history,
 			clientFactory,
-			networkParallelism,
+			false,
+			parallelism,
+			trackParallelism,
 		)
 		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
 		worthyFailedOps = append(worthyFailedOps, wfailops...)
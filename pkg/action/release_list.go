@@ -0,0 +1,294 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseListOutputFormat = TableOutputFormat
+	DefaultReleaseListLogLevel     = ErrorLogLevel
+)
+
+type ReleaseListOptions struct {
+	AllNamespaces         bool
+	Deployed              bool
+	Failed                bool
+	Filter                string
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	Pending               bool
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	TempDirPath           string
+}
+
+// ReleaseList lists the latest revisions of releases in the namespace, or in all namespaces if
+// AllNamespaces is set.
+func ReleaseList(ctx context.Context, releaseNamespace string, opts ReleaseListOptions) (*ReleaseListResultV1, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseListOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release list options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	// Releases are looked up in all namespaces if the storage namespace is empty.
+	storageNamespace := kubeConfig.Namespace
+	if opts.AllNamespaces {
+		storageNamespace = ""
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		storageNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmListAction := action.NewList(helmActionConfig)
+	helmListAction.AllNamespaces = opts.AllNamespaces
+	helmListAction.Deployed = opts.Deployed
+	helmListAction.Failed = opts.Failed
+	helmListAction.Pending = opts.Pending
+	helmListAction.Filter = opts.Filter
+	// Show releases in any status unless filtered by status.
+	helmListAction.All = !opts.Deployed && !opts.Failed && !opts.Pending
+	helmListAction.SetStateMask()
+
+	legacyRels, err := helmListAction.Run()
+	if err != nil {
+		return nil, fmt.Errorf("list releases: %w", err)
+	}
+
+	sort.SliceStable(legacyRels, func(i, j int) bool {
+		if legacyRels[i].Namespace != legacyRels[j].Namespace {
+			return legacyRels[i].Namespace < legacyRels[j].Namespace
+		}
+
+		return legacyRels[i].Name < legacyRels[j].Name
+	})
+
+	result := &ReleaseListResultV1{
+		ApiVersion: ReleaseListResultApiVersionV1,
+		Releases:   []*ReleaseListResultRelease{},
+	}
+
+	for _, legacyRel := range legacyRels {
+		resultRel := &ReleaseListResultRelease{
+			Name:      legacyRel.Name,
+			Namespace: legacyRel.Namespace,
+			Revision:  legacyRel.Version,
+		}
+
+		if legacyRel.Info != nil {
+			resultRel.Status = string(legacyRel.Info.Status)
+			resultRel.LastDeployed = legacyRel.Info.LastDeployed.Time
+		}
+
+		if legacyRel.Chart != nil && legacyRel.Chart.Metadata != nil {
+			resultRel.Chart = legacyRel.Chart.Metadata.Name
+			resultRel.ChartVersion = legacyRel.Chart.Metadata.Version
+			resultRel.AppVersion = legacyRel.Chart.Metadata.AppVersion
+		}
+
+		result.Releases = append(result.Releases, resultRel)
+	}
+
+	if !opts.OutputNoPrint {
+		var colorLevel color.Level
+		if opts.LogColorMode != LogColorModeOff {
+			colorLevel = color.DetectColorLevel()
+		}
+
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case YamlOutputFormat:
+			b, err := yaml.MarshalContext(ctx, result)
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to yaml: %w", err)
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), YamlOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case TableOutputFormat:
+			if _, err := os.Stdout.Write([]byte(renderReleaseListTable(result))); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+	}
+
+	return result, nil
+}
+
+func renderReleaseListTable(result *ReleaseListResultV1) string {
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.Style().Options = prtable.OptionsNoBordersAndSeparators
+	table.AppendHeader(prtable.Row{"NAME", "NAMESPACE", "REVISION", "STATUS", "CHART", "APP VERSION", "LAST DEPLOYED"})
+
+	for _, rel := range result.Releases {
+		var lastDeployed string
+		if !rel.LastDeployed.IsZero() {
+			lastDeployed = rel.LastDeployed.Format(time.RFC3339)
+		}
+
+		chart := rel.Chart
+		if rel.ChartVersion != "" {
+			chart += "-" + rel.ChartVersion
+		}
+
+		table.AppendRow(prtable.Row{rel.Name, rel.Namespace, rel.Revision, rel.Status, chart, rel.AppVersion, lastDeployed})
+	}
+
+	return table.Render() + "\n"
+}
+
+func applyReleaseListOptionsDefaults(opts ReleaseListOptions, currentUser *user.User) (ReleaseListOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseListOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = DefaultNetworkParallelism
+	}
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseListOutputFormat
+	}
+
+	if opts.Filter != "" {
+		if _, err := regexp.Compile(opts.Filter); err != nil {
+			return ReleaseListOptions{}, fmt.Errorf("invalid release name filter %q: %w", opts.Filter, err)
+		}
+	}
+
+	return opts, nil
+}
+
+const ReleaseListResultApiVersionV1 = "v1"
+
+type ReleaseListResultV1 struct {
+	ApiVersion string                      `json:"apiVersion"`
+	Releases   []*ReleaseListResultRelease `json:"releases"`
+}
+
+type ReleaseListResultRelease struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Revision     int       `json:"revision"`
+	Status       string    `json:"status"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	LastDeployed time.Time `json:"lastDeployed"`
+}
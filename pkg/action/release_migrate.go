@@ -0,0 +1,354 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/nelm/internal/common"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/lock"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseMigrateLogLevel = InfoLogLevel
+)
+
+type ReleaseMigrateOptions struct {
+	ConfirmFunc               ConfirmFunc
+	DryRun                    bool
+	KubeAPIServerName         string
+	KubeBurstLimit            int
+	KubeCAPath                string
+	KubeConfigBase64          string
+	KubeConfigPaths           []string
+	KubeContext               string
+	KubeDiscoveryCacheDir     string
+	KubeImpersonateGroups     []string
+	KubeImpersonateUser       string
+	KubeQPSLimit              int
+	KubeRefreshDiscovery      bool
+	KubeSkipTLSVerify         bool
+	KubeTLSServerName         string
+	KubeToken                 string
+	KubeTokenPath             string
+	LogColorMode              string
+	ProtectedContextConfirmed bool
+	ProtectedContexts         []string
+	ProtectedContextsFilePath string
+	ReleaseStorageDriver      string
+	TempDirPath               string
+}
+
+// ReleaseMigrate makes the resources of the release deployed by Helm managed by Nelm: all resources
+// of the latest release revision are server-side applied as they are, which transfers ownership of
+// their fields to the Nelm field manager, then the release revision is marked as migrated. With
+// DryRun only the current field managers of the resources are reported.
+func ReleaseMigrate(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseMigrateOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseMigrateOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return &UsageError{Err: fmt.Errorf("build release migrate options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return fmt.Errorf("construct kube config: %w", err)
+	}
+
+	if !opts.DryRun {
+		if err := checkProtectedContext(ctx, kubeConfig, protectedContextCheckOptions{
+			ConfirmFunc:      opts.ConfirmFunc,
+			Confirmed:        opts.ProtectedContextConfirmed,
+			KubeContext:      opts.KubeContext,
+			Patterns:         opts.ProtectedContexts,
+			PatternsFilePath: opts.ProtectedContextsFilePath,
+		}); err != nil {
+			return fmt.Errorf("check protected context: %w", err)
+		}
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	if !opts.DryRun {
+		var lockManager *lock.LockManager
+		if m, err := lock.NewLockManager(
+			releaseNamespace,
+			false,
+			clientFactory.Static(),
+			clientFactory.Dynamic(),
+		); err != nil {
+			return fmt.Errorf("construct lock manager: %w", err)
+		} else {
+			lockManager = m
+		}
+
+		if lock, err := lockManager.LockRelease(ctx, releaseName); err != nil {
+			return fmt.Errorf("lock release: %w", err)
+		} else {
+			defer lockManager.Unlock(lock)
+		}
+	}
+
+	log.Default.Debug(ctx, "Constructing release history")
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmReleaseStorage,
+		release.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	rel, found, err := history.LastRelease()
+	if err != nil {
+		return fmt.Errorf("get last release: %w", err)
+	} else if !found {
+		return fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+	}
+
+	if migratedAt, migrated := rel.InfoAnnotations()[release.MigratedInfoAnnotation]; migrated && !opts.DryRun {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Release already migrated")+" %q (namespace: %q) at %s", releaseName, releaseNamespace, migratedAt)
+		return nil
+	}
+
+	kubeClient := clientFactory.KubeClient()
+
+	var migratableResources []*releaseMigrateResource
+	for _, res := range rel.GeneralResources() {
+		liveObj, found, err := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{})
+		if err != nil {
+			return fmt.Errorf("get resource %q: %w", res.HumanID(), err)
+		} else if !found {
+			log.Default.Warn(ctx, "Warning: resource %q of the release not found in the cluster, skipping", res.HumanID())
+			continue
+		}
+
+		migratableResources = append(migratableResources, &releaseMigrateResource{
+			res:     res,
+			liveObj: liveObj,
+		})
+	}
+
+	if opts.DryRun {
+		log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Field managers")).Do(func() {
+			for _, migratableRes := range migratableResources {
+				log.Default.Info(ctx, "- %s: %s", migratableRes.res.HumanID(), strings.Join(fieldManagers(migratableRes.liveObj), ", "))
+			}
+		})
+
+		return nil
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Migrating release")+" %q (namespace: %q)", releaseName, releaseNamespace)
+
+	releaseMetadataPatcher := resource.NewReleaseMetadataPatcher(releaseName, releaseNamespace)
+
+	var driftErrs []error
+	for _, migratableRes := range migratableResources {
+		remoteRes := resource.NewRemoteResource(migratableRes.liveObj, resource.RemoteResourceOptions{
+			FallbackNamespace: releaseNamespace,
+			Mapper:            clientFactory.Mapper(),
+		})
+
+		if changed, err := remoteRes.FixManagedFields(); err != nil {
+			return fmt.Errorf("fix managed fields of resource %q: %w", remoteRes.HumanID(), err)
+		} else if changed {
+			unstruct := unstructured.Unstructured{Object: map[string]interface{}{}}
+			unstruct.SetManagedFields(remoteRes.Unstructured().GetManagedFields())
+
+			patch, err := json.Marshal(unstruct.UnstructuredContent())
+			if err != nil {
+				return fmt.Errorf("marshal fixed managed fields of resource %q: %w", remoteRes.HumanID(), err)
+			}
+
+			log.Default.Debug(ctx, "Fixing managed fields for resource %q", remoteRes.HumanID())
+			if migratableRes.liveObj, err = kubeClient.MergePatch(ctx, remoteRes.ResourceID, patch); err != nil {
+				return fmt.Errorf("patch managed fields of resource %q: %w", remoteRes.HumanID(), err)
+			}
+		}
+
+		migratableRes.desiredObj, err = releaseMetadataPatcher.Patch(ctx, &resource.ResourcePatcherResourceInfo{
+			Obj:          migratableRes.res.Unstructured().DeepCopy(),
+			Type:         resource.TypeGeneralResource,
+			ManageableBy: migratableRes.res.ManageableBy(),
+		})
+		if err != nil {
+			return fmt.Errorf("patch resource %q by %q: %w", migratableRes.res.HumanID(), releaseMetadataPatcher.Type(), err)
+		}
+
+		dryAppliedObj, err := kubeClient.Apply(ctx, migratableRes.res.ResourceID, migratableRes.desiredObj, kube.KubeClientApplyOptions{
+			DryRun: true,
+		})
+		if err != nil {
+			return fmt.Errorf("dry-run apply resource %q: %w", migratableRes.res.HumanID(), err)
+		}
+
+		// Only the field managers are expected to change.
+		if differ, err := util.ResourcesReallyDiffer(withoutManagedFields(migratableRes.liveObj), withoutManagedFields(dryAppliedObj)); err != nil {
+			return fmt.Errorf("diff resource %q: %w", migratableRes.res.HumanID(), err)
+		} else if differ {
+			driftErrs = append(driftErrs, fmt.Errorf("resource %q in the cluster differs from the release manifest", migratableRes.res.HumanID()))
+		}
+	}
+
+	if err := util.Multierrorf("resources can't be migrated without changing them, deploy the release first", driftErrs); err != nil {
+		return err
+	}
+
+	for _, migratableRes := range migratableResources {
+		log.Default.Info(ctx, "Applying resource %q with field manager %q", migratableRes.res.HumanID(), common.DefaultFieldManager)
+
+		appliedObj, err := kubeClient.Apply(ctx, migratableRes.res.ResourceID, migratableRes.desiredObj, kube.KubeClientApplyOptions{})
+		if err != nil {
+			return fmt.Errorf("apply resource %q: %w", migratableRes.res.HumanID(), err)
+		}
+
+		dryAppliedObj, err := kubeClient.Apply(ctx, migratableRes.res.ResourceID, migratableRes.desiredObj, kube.KubeClientApplyOptions{
+			DryRun: true,
+		})
+		if err != nil {
+			return fmt.Errorf("dry-run apply resource %q: %w", migratableRes.res.HumanID(), err)
+		}
+
+		if differ, err := util.ResourcesReallyDiffer(appliedObj, dryAppliedObj); err != nil {
+			return fmt.Errorf("diff resource %q: %w", migratableRes.res.HumanID(), err)
+		} else if differ {
+			return fmt.Errorf("resource %q still has changes after migration", migratableRes.res.HumanID())
+		}
+	}
+
+	rel.SetInfoAnnotations(map[string]string{
+		release.MigratedInfoAnnotation: time.Now().UTC().Format(time.RFC3339),
+	})
+
+	if err := history.UpdateRelease(ctx, rel); err != nil {
+		return fmt.Errorf("update release: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Migrated release")+" %q (namespace: %q), %d resources now managed by Nelm", releaseName, releaseNamespace, len(migratableResources))
+
+	return nil
+}
+
+type releaseMigrateResource struct {
+	res        *resource.GeneralResource
+	liveObj    *unstructured.Unstructured
+	desiredObj *unstructured.Unstructured
+}
+
+func fieldManagers(obj *unstructured.Unstructured) []string {
+	var managers []string
+	for _, entry := range obj.GetManagedFields() {
+		managers = append(managers, fmt.Sprintf("%s (%s)", entry.Manager, entry.Operation))
+	}
+
+	managers = lo.Uniq(managers)
+	if len(managers) == 0 {
+		return []string{"<none>"}
+	}
+
+	return managers
+}
+
+func withoutManagedFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+
+	return obj
+}
+
+func applyReleaseMigrateOptionsDefaults(opts ReleaseMigrateOptions, currentUser *user.User) (ReleaseMigrateOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseMigrateOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	return opts, nil
+}
@@ -7,31 +7,30 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/gookit/color"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
-	"github.com/werf/3p-helm/pkg/chart/loader"
-	"github.com/werf/3p-helm/pkg/chartutil"
 	"github.com/werf/3p-helm/pkg/downloader"
 	"github.com/werf/3p-helm/pkg/getter"
-	"github.com/werf/3p-helm/pkg/registry"
-	"github.com/werf/3p-helm/pkg/werf/chartextender"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
-	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
 	"github.com/werf/logboek"
 	"github.com/werf/nelm/internal/chart"
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan"
 	"github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/matcher"
+	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -41,50 +40,89 @@ const (
 var ErrChangesPlanned = errors.New("changes planned")
 
 type ReleasePlanInstallOptions struct {
+	AutoAdopt                    bool
+	AutoSanitizeReleaseName      bool
+	CRDsPolicy                   string
 	ChartAppVersion              string
+	ChartDependencyUpdate        bool
 	ChartDirPath                 string
+	ChartProvenanceKeyringPath   string
+	ChartProvenanceVerify        bool
 	ChartRepositoryInsecure      bool
+	ChartRepositoryPlainHTTP     bool
 	ChartRepositorySkipTLSVerify bool
 	ChartRepositorySkipUpdate    bool
+	CheckReferences              bool
+	CheckReferencesStrict        bool
 	DefaultChartAPIVersion       string
 	DefaultChartName             string
 	DefaultChartVersion          string
 	DefaultSecretValuesDisable   bool
 	DefaultValuesDisable         bool
+	DiffContextLines             int
 	ErrorIfChangesPlanned        bool
+	ExcludeResources             []string
 	ExtraAnnotations             map[string]string
 	ExtraLabels                  map[string]string
 	ExtraRuntimeAnnotations      map[string]string
+	ForceAdoption                bool
+	ForceReplace                 bool
+	GraphFormat                  string
+	IncludeResources             []string
 	KubeAPIServerName            string
 	KubeBurstLimit               int
 	KubeCAPath                   string
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
+	KubeDiscoveryCacheDir        string
+	KubeImpersonateGroups        []string
+	KubeImpersonateUser          string
 	KubeQPSLimit                 int
+	KubeRefreshDiscovery         bool
 	KubeSkipTLSVerify            bool
 	KubeTLSServerName            string
 	KubeToken                    string
+	KubeTokenPath                string
 	LogColorMode                 string
 	LogRegistryStreamOut         io.Writer
 	NetworkParallelism           int
+	NoManifestHashAnnotation     bool
+	OutputFormat                 string
+	OutputPath                   string
+	PostRenderer                 string
+	PostRendererArgs             []string
 	RegistryCredentialsPath      string
 	ReleaseStorageDriver         string
+	RenderCacheDir               string
+	ResourceSizeLimit            int
+	SecretAgeIdentityPath        string
 	SecretKey                    string
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	SecretWorkDir                string
+	ShowDiff                     bool
+	ShowSensitiveDiffs           bool
+	SkipSchemaValidation         bool
+	StrictTemplates              bool
 	TempDirPath                  string
+	TrackCreationTimeout         time.Duration
+	TrackReadinessTimeout        time.Duration
+	ValuesEnv                    map[string]string
+	ValuesEnvPrefix              string
 	ValuesFileSets               []string
 	ValuesFilesPaths             []string
+	ValuesJSONSets               []string
+	ValuesLiteralSets            []string
 	ValuesSets                   []string
 	ValuesStringSets             []string
 }
 
 func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleasePlanInstallOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
+	return classifyActionErr(releasePlanInstall(ctx, releaseName, releaseNamespace, opts))
+}
 
+func releasePlanInstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleasePlanInstallOptions) error {
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -97,11 +135,12 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 
 	opts, err = applyReleasePlanInstallOptionsDefaults(opts, currentDir, currentUser)
 	if err != nil {
-		return fmt.Errorf("build release plan install options: %w", err)
+		return &UsageError{Err: fmt.Errorf("build release plan install options: %w", err)}
 	}
 
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+	releaseName, err = sanitizeReleaseName(ctx, releaseName, opts.AutoSanitizeReleaseName)
+	if err != nil {
+		return &UsageError{Err: fmt.Errorf("validate release name: %w", err)}
 	}
 
 	if len(opts.KubeConfigPaths) > 0 {
@@ -118,6 +157,8 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		BurstLimit:            opts.KubeBurstLimit,
 		CertificateAuthority:  opts.KubeCAPath,
 		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
 		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 		KubeConfigBase64:      opts.KubeConfigBase64,
 		Namespace:             releaseNamespace,
@@ -125,35 +166,40 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		Server:                opts.KubeAPIServerName,
 		TLSServerName:         opts.KubeTLSServerName,
 		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
 	})
 	if err != nil {
 		return fmt.Errorf("construct kube config: %w", err)
 	}
 
-	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig)
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
 	if err != nil {
 		return fmt.Errorf("construct kube client factory: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
-
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-		registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-	}
+	helmSettings := newHelmSettings(ctx)
 
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
+	helmRegistryClient, err := newHelmRegistryClient(ctx, opts.LogRegistryStreamOut, opts.RegistryCredentialsPath, opts.ChartRepositoryInsecure || opts.ChartRepositoryPlainHTTP)
+	if err != nil {
+		return fmt.Errorf("construct registry client: %w", err)
 	}
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	opts.ChartDirPath, err = chart.ResolveChart(ctx, opts.ChartDirPath, chart.ResolveChartOptions{
+		Getters:          getter.All(helmSettings),
+		Keyring:          opts.ChartProvenanceKeyringPath,
+		Out:              logboek.Context(ctx).OutStream(),
+		PlainHTTP:        opts.ChartRepositoryPlainHTTP,
+		RegistryClient:   helmRegistryClient,
+		RepositoryCache:  helmSettings.RepositoryCache,
+		RepositoryConfig: helmSettings.RepositoryConfig,
+		TempDirPath:      opts.TempDirPath,
+		Verify:           opts.ChartProvenanceVerify,
+	})
 	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		return fmt.Errorf("resolve chart: %w", err)
 	}
 
 	helmActionConfig := &action.Configuration{}
@@ -170,18 +216,6 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 
 	helmReleaseStorage := helmActionConfig.Releases
 
-	chartextender.DefaultChartAPIVersion = opts.DefaultChartAPIVersion
-	chartextender.DefaultChartName = opts.DefaultChartName
-	chartextender.DefaultChartVersion = opts.DefaultChartVersion
-	chartextender.ChartAppVersion = opts.ChartAppVersion
-	loader.WithoutDefaultSecretValues = opts.DefaultSecretValuesDisable
-	loader.WithoutDefaultValues = opts.DefaultValuesDisable
-	secrets.CoalesceTablesFunc = chartutil.CoalesceTables
-	secrets.SecretsWorkingDir = opts.SecretWorkDir
-	loader.SecretValuesFiles = opts.SecretValuesPaths
-	secrets.ChartDir = opts.ChartDirPath
-	secrets_manager.DisableSecretsDecryption = opts.SecretKeyIgnore
-
 	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Planning release install")+" %q (namespace: %q)", releaseName, releaseNamespace)
 
 	log.Default.Debug(ctx, "Constructing release history")
@@ -203,7 +237,7 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		return fmt.Errorf("get last release: %w", err)
 	}
 
-	_, prevDeployedReleaseFound, err := history.LastDeployedRelease()
+	prevDeployedRelease, prevDeployedReleaseFound, err := history.LastDeployedRelease()
 	if err != nil {
 		return fmt.Errorf("get last deployed release: %w", err)
 	}
@@ -238,8 +272,55 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		RepositoryCache:   helmSettings.RepositoryCache,
 		Debug:             helmSettings.Debug,
 	}
-	loader.SetChartPathFunc = downloader.SetChartPath
-	loader.DepsBuildFunc = downloader.Build
+
+	resourceFilter, err := matcher.NewResourceFilter(opts.IncludeResources, opts.ExcludeResources)
+	if err != nil {
+		return fmt.Errorf("construct resource filter: %w", err)
+	}
+
+	postRenderer, err := chart.NewPostRenderer(opts.PostRenderer, opts.PostRendererArgs)
+	if err != nil {
+		return fmt.Errorf("construct post-renderer: %w", err)
+	}
+
+	chartTreeOptions := chart.ChartTreeOptions{
+		AgeIdentityPath:            opts.SecretAgeIdentityPath,
+		StringSetValues:            opts.ValuesStringSets,
+		SetValues:                  opts.ValuesSets,
+		FileValues:                 opts.ValuesFileSets,
+		Env:                        opts.ValuesEnv,
+		EnvValuesPrefix:            opts.ValuesEnvPrefix,
+		JSONSetValues:              opts.ValuesJSONSets,
+		LiteralSetValues:           opts.ValuesLiteralSets,
+		ValuesFiles:                opts.ValuesFilesPaths,
+		Mapper:                     clientFactory.Mapper(),
+		DiscoveryClient:            clientFactory.Discovery(),
+		ResourceFilter:             resourceFilter,
+		PostRenderer:               postRenderer,
+		Getters:                    getter.All(helmSettings),
+		RegistryClient:             helmRegistryClient,
+		SecretKey:                  opts.SecretKey,
+		SecretValuesFiles:          opts.SecretValuesPaths,
+		DefaultSecretValuesDisable: opts.DefaultSecretValuesDisable,
+		ChartAppVersion:            opts.ChartAppVersion,
+		DefaultChartAPIVersion:     opts.DefaultChartAPIVersion,
+		DefaultChartName:           opts.DefaultChartName,
+		DefaultChartVersion:        opts.DefaultChartVersion,
+		DefaultValuesDisable:       opts.DefaultValuesDisable,
+		SecretKeyIgnore:            opts.SecretKeyIgnore,
+		SecretWorkDir:              opts.SecretWorkDir,
+		DependencyManager:          downloader,
+		TempDirPath:                opts.TempDirPath,
+		RenderCacheDir:             opts.RenderCacheDir,
+		StrictTemplates:            opts.StrictTemplates,
+		SkipSchemaValidation:       opts.SkipSchemaValidation,
+	}
+
+	if opts.ChartDependencyUpdate {
+		if err := chart.UpdateDependencies(ctx, opts.ChartDirPath, chartTreeOptions); err != nil {
+			return fmt.Errorf("update chart dependencies: %w", err)
+		}
+	}
 
 	log.Default.Debug(ctx, "Constructing chart tree")
 	chartTree, err := chart.NewChartTree(
@@ -250,28 +331,38 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		newRevision,
 		deployType,
 		helmActionConfig,
-		chart.ChartTreeOptions{
-			StringSetValues: opts.ValuesStringSets,
-			SetValues:       opts.ValuesSets,
-			FileValues:      opts.ValuesFileSets,
-			ValuesFiles:     opts.ValuesFilesPaths,
-			Mapper:          clientFactory.Mapper(),
-			DiscoveryClient: clientFactory.Discovery(),
-		},
+		chartTreeOptions,
 	)
 	if err != nil {
-		return fmt.Errorf("construct chart tree: %w", err)
+		return &TemplateError{Err: fmt.Errorf("construct chart tree: %w", err)}
+	}
+
+	if chartTree.Partial() {
+		log.Default.Warn(ctx, "Only resources matching include/exclude filters will be planned, the rest are kept in release %q as they were in the previous revision", releaseName)
 	}
 
 	notes := chartTree.Notes()
 
-	var prevRelGeneralResources []*resource.GeneralResource
+	var prevRelGeneralResources, excludedPrevRelGeneralResources []*resource.GeneralResource
+	var excludedPrevRelHookResources []*resource.HookResource
 	var prevRelFailed bool
 	if prevReleaseFound {
-		prevRelGeneralResources = prevRelease.GeneralResources()
+		// Resources filtered out are left untouched, instead of being deleted as no longer present in the release.
+		prevRelGeneralResources, excludedPrevRelHookResources, excludedPrevRelGeneralResources = splitPrevReleaseResources(prevRelease, resourceFilter)
 		prevRelFailed = prevRelease.Failed()
 	}
 
+	deployablePatchers := []resource.ResourcePatcher{
+		resource.NewExtraMetadataPatcher(
+			lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations),
+			opts.ExtraLabels,
+		),
+	}
+
+	if !opts.NoManifestHashAnnotation {
+		deployablePatchers = append(deployablePatchers, resource.NewManifestHashPatcher())
+	}
+
 	log.Default.Debug(ctx, "Processing resources")
 	resProcessor := resourceinfo.NewDeployableResourcesProcessor(
 		deployType,
@@ -282,35 +373,27 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		chartTree.GeneralResources(),
 		prevRelGeneralResources,
 		resourceinfo.DeployableResourcesProcessorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
+			CRDPolicy:             resource.CRDPolicy(opts.CRDsPolicy),
+			StrictFieldValidation: opts.StrictTemplates,
+			AutoAdopt:             opts.AutoAdopt,
+			ForceAdoption:         opts.ForceAdoption,
+			ForceReplace:          opts.ForceReplace,
+			NetworkParallelism:    opts.NetworkParallelism,
+			ResourceSizeLimit:     opts.ResourceSizeLimit,
 			ReleasableHookResourcePatchers: []resource.ResourcePatcher{
 				resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 			},
 			ReleasableGeneralResourcePatchers: []resource.ResourcePatcher{
 				resource.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
 			},
-			DeployableStandaloneCRDsPatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations),
-					opts.ExtraLabels,
-				),
-			},
-			DeployableHookResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations),
-					opts.ExtraLabels,
-				),
-			},
-			DeployableGeneralResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations),
-					opts.ExtraLabels,
-				),
-			},
-			KubeClient:         clientFactory.KubeClient(),
-			Mapper:             clientFactory.Mapper(),
-			DiscoveryClient:    clientFactory.Discovery(),
-			AllowClusterAccess: true,
+			DeployableStandaloneCRDsPatchers:  deployablePatchers,
+			DeployableHookResourcePatchers:    deployablePatchers,
+			DeployableGeneralResourcePatchers: deployablePatchers,
+			KubeClient:                        clientFactory.KubeClient(),
+			Mapper:                            clientFactory.Mapper(),
+			DiscoveryClient:                   clientFactory.Discovery(),
+			AllowClusterAccess:                true,
+			DryRunNewResources:                true,
 		},
 	)
 
@@ -318,6 +401,13 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		return fmt.Errorf("process resources: %w", err)
 	}
 
+	if opts.CheckReferences || opts.CheckReferencesStrict {
+		log.Default.Debug(ctx, "Checking references")
+		if err := checkReferences(ctx, resProcessor, clientFactory, opts.CheckReferencesStrict); err != nil {
+			return err
+		}
+	}
+
 	log.Default.Debug(ctx, "Constructing new release")
 	newRel, err := release.NewRelease(
 		releaseName,
@@ -325,8 +415,8 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		newRevision,
 		chartTree.ReleaseValues(),
 		chartTree.LegacyChart(),
-		resProcessor.ReleasableHookResources(),
-		resProcessor.ReleasableGeneralResources(),
+		slices.Concat(resProcessor.ReleasableHookResources(), excludedPrevRelHookResources),
+		slices.Concat(resProcessor.ReleasableGeneralResources(), excludedPrevRelGeneralResources),
 		notes,
 		release.ReleaseOptions{
 			FirstDeployed: firstDeployed,
@@ -341,11 +431,16 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 	createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, planChangesPlanned := plan.CalculatePlannedChanges(
 		releaseName,
 		releaseNamespace,
+		resProcessor.DeployableReleaseNamespaceInfo(),
 		resProcessor.DeployableStandaloneCRDsInfos(),
 		resProcessor.DeployableHookResourcesInfos(),
 		resProcessor.DeployableGeneralResourcesInfos(),
 		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
 		prevRelFailed,
+		plan.CalculatePlannedChangesOptions{
+			DiffContextLines:   opts.DiffContextLines,
+			ShowSensitiveDiffs: opts.ShowSensitiveDiffs,
+		},
 	)
 
 	var releaseUpToDate bool
@@ -366,8 +461,52 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 		updatedChanges,
 		appliedChanges,
 		deletedChanges,
+		plan.LogPlannedChangesOptions{
+			ShowDiff: opts.ShowDiff,
+		},
 	)
 
+	log.Default.Debug(ctx, "Constructing new deploy plan")
+	deployPlan, err := plan.NewDeployPlanBuilder(
+		releaseNamespace,
+		deployType,
+		statestore.NewTaskStore(),
+		kubeutil.NewConcurrent(logstore.NewLogStore()),
+		resProcessor.DeployableStandaloneCRDsInfos(),
+		resProcessor.DeployableHookResourcesInfos(),
+		resProcessor.DeployableGeneralResourcesInfos(),
+		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+		newRel,
+		history,
+		clientFactory.KubeClient(),
+		clientFactory.Static(),
+		clientFactory.Dynamic(),
+		clientFactory.Discovery(),
+		clientFactory.Mapper(),
+		plan.DeployPlanBuilderOptions{
+			CRDPolicy:           resource.CRDPolicy(opts.CRDsPolicy),
+			PrevRelease:         prevRelease,
+			PrevDeployedRelease: prevDeployedRelease,
+			CreationTimeout:     opts.TrackCreationTimeout,
+			ReadinessTimeout:    opts.TrackReadinessTimeout,
+		},
+	).Build(ctx)
+	if err != nil {
+		return fmt.Errorf("build release install plan: %w", err)
+	}
+
+	if err := deployPlan.Validate(); err != nil {
+		return fmt.Errorf("validate release install plan: %w", err)
+	}
+
+	plan.LogStagesSummary(ctx, deployPlan.StagesSummary())
+
+	if opts.OutputFormat != "" {
+		if err := outputPlan(deployPlan, opts.OutputFormat, opts.OutputPath); err != nil {
+			return fmt.Errorf("output release install plan: %w", err)
+		}
+	}
+
 	if opts.ErrorIfChangesPlanned && (planChangesPlanned || !releaseUpToDate) {
 		return ErrChangesPlanned
 	}
@@ -375,6 +514,40 @@ func ReleasePlanInstall(ctx context.Context, releaseName, releaseNamespace strin
 	return nil
 }
 
+func outputPlan(deployPlan *plan.Plan, format, path string) error {
+	var data []byte
+	var err error
+	switch format {
+	case JsonOutputFormat:
+		data, err = deployPlan.JSON()
+	case YamlOutputFormat:
+		data, err = deployPlan.YAML()
+	case DotOutputFormat:
+		data, err = deployPlan.DOT()
+	case MermaidOutputFormat:
+		data, err = deployPlan.Mermaid()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("serialize plan: %w", err)
+	}
+
+	if path == "" {
+		if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("write plan to stdout: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("write plan to %q: %w", path, err)
+	}
+
+	return nil
+}
+
 func applyReleasePlanInstallOptionsDefaults(opts ReleasePlanInstallOptions, currentDir string, currentUser *user.User) (ReleasePlanInstallOptions, error) {
 	if opts.ChartDirPath == "" {
 		opts.ChartDirPath = currentDir
@@ -398,6 +571,10 @@ func applyReleasePlanInstallOptionsDefaults(opts ReleasePlanInstallOptions, curr
 
 	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
 
+	if opts.CRDsPolicy, err = applyCRDsPolicyDefault(opts.CRDsPolicy); err != nil {
+		return ReleasePlanInstallOptions{}, fmt.Errorf("apply CRDs policy default: %w", err)
+	}
+
 	if opts.NetworkParallelism <= 0 {
 		opts.NetworkParallelism = DefaultNetworkParallelism
 	}
@@ -410,6 +587,10 @@ func applyReleasePlanInstallOptionsDefaults(opts ReleasePlanInstallOptions, curr
 		opts.KubeBurstLimit = DefaultBurstLimit
 	}
 
+	if opts.DiffContextLines < 0 {
+		opts.DiffContextLines = DefaultDiffContextLines
+	}
+
 	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
 		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
 	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
@@ -427,5 +608,21 @@ func applyReleasePlanInstallOptionsDefaults(opts ReleasePlanInstallOptions, curr
 		opts.RegistryCredentialsPath = DefaultRegistryCredentialsPath
 	}
 
+	if opts.ChartProvenanceKeyringPath == "" {
+		opts.ChartProvenanceKeyringPath = DefaultChartProvenanceKeyringPath
+	}
+
+	if opts.GraphFormat != "" {
+		if opts.GraphFormat != DotOutputFormat && opts.GraphFormat != MermaidOutputFormat {
+			return ReleasePlanInstallOptions{}, fmt.Errorf("unknown graph format %q, expected %q or %q", opts.GraphFormat, DotOutputFormat, MermaidOutputFormat)
+		}
+
+		if opts.OutputFormat != "" && opts.OutputFormat != opts.GraphFormat {
+			return ReleasePlanInstallOptions{}, fmt.Errorf("graph format %q conflicts with output format %q", opts.GraphFormat, opts.OutputFormat)
+		}
+
+		opts.OutputFormat = opts.GraphFormat
+	}
+
 	return opts, nil
 }
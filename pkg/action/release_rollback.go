@@ -9,24 +9,27 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gookit/color"
 
-	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/3p-helm/pkg/chartutil"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/logstore"
 	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
 	kubeutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm/internal/chart"
 	"github.com/werf/nelm/internal/common"
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/lock"
-	"github.com/werf/nelm/internal/log"
 	"github.com/werf/nelm/internal/plan"
 	"github.com/werf/nelm/internal/plan/operation"
 	"github.com/werf/nelm/internal/plan/resourceinfo"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/telemetry"
 	"github.com/werf/nelm/internal/track"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -34,46 +37,101 @@ const (
 )
 
 type ReleaseRollbackOptions struct {
+	ConfirmFunc                ConfirmFunc
+	DiffContextLines           int
+	EmitEvents                 bool
+	EventsInvolvedObject       string
 	ExtraRuntimeAnnotations    map[string]string
+	ForceReplace               bool
 	KubeAPIServerName          string
 	KubeBurstLimit             int
 	KubeCAPath                 string
 	KubeConfigBase64           string
 	KubeConfigPaths            []string
 	KubeContext                string
+	KubeDiscoveryCacheDir      string
+	KubeImpersonateGroups      []string
+	KubeImpersonateUser        string
 	KubeQPSLimit               int
+	KubeRefreshDiscovery       bool
 	KubeSkipTLSVerify          bool
 	KubeTLSServerName          string
 	KubeToken                  string
+	KubeTokenPath              string
+	KubeWatchCache             bool
 	LogColorMode               string
+	LogTail                    int
 	NetworkParallelism         int
+	NoManifestHashAnnotation   bool
+	NotesFunc                  NotesFunc
 	NoProgressTablePrint       bool
+	OperationRetries           int
+	OperationRetryBackoff      time.Duration
+	Parallelism                int
+	PendingReleaseTTL          time.Duration
+	PlanOnly                   bool
+	ProgressMode               string
+	ProgressReporter           ProgressReporter
 	ProgressTablePrintInterval time.Duration
+	ProtectedContextConfirmed  bool
+	ProtectedContexts          []string
+	ProtectedContextsFilePath  string
+	ReadyStableFor             time.Duration
+	ReleaseDescription         string
 	ReleaseHistoryLimit        int
 	ReleaseStorageDriver       string
+	ResourceSizeLimit          int
 	Revision                   int
 	RollbackGraphPath          string
 	RollbackReportPath         string
+	ShowDiff                   bool
+	ShowLogsUntil              string
+	ShowSensitiveDiffs         bool
+	SubNotes                   bool
 	TempDirPath                string
+	Timeout                    time.Duration
 	TrackCreationTimeout       time.Duration
 	TrackDeletionTimeout       time.Duration
+	TrackParallelism           int
 	TrackReadinessTimeout      time.Duration
 }
 
-func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseRollbackOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
+// ReleaseRollback returns the deploy report even if the rollback failed. No report is returned
+// with PlanOnly.
+func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseRollbackOptions) (*DeployReport, error) {
+	ctx, cancel := withActionTimeout(ctx, "release rollback", opts.Timeout)
+	defer cancel()
 
+	startedAt := time.Now()
+
+	report, err := releaseRollback(ctx, releaseName, releaseNamespace, startedAt, opts)
+	if err != nil && report == nil && !opts.PlanOnly {
+		report = newFailedDeployReport(releaseName, releaseNamespace, startedAt, err)
+
+		if opts.RollbackReportPath != "" {
+			if err := report.Save(opts.RollbackReportPath); err != nil {
+				log.Default.Error(ctx, "Error: save release rollback report: %s", err)
+			}
+		}
+	}
+
+	return report, classifyActionErr(wrapActionTimeoutErr(ctx, err))
+}
+
+func releaseRollback(ctx context.Context, releaseName, releaseNamespace string, startedAt time.Time, opts ReleaseRollbackOptions) (*DeployReport, error) {
 	currentUser, err := user.Current()
 	if err != nil {
-		return fmt.Errorf("get current user: %w", err)
+		return nil, fmt.Errorf("get current user: %w", err)
 	}
 
 	opts, err = applyReleaseRollbackOptionsDefaults(opts, currentUser)
 	if err != nil {
-		return fmt.Errorf("build release rollback options: %w", err)
+		return nil, &UsageError{Err: fmt.Errorf("build release rollback options: %w", err)}
 	}
 
+	deployID := uuid.NewString()
+	log.Default.Debug(ctx, "Deploy ID: %s", deployID)
+
 	if len(opts.KubeConfigPaths) > 0 {
 		var splitPaths []string
 		for _, path := range opts.KubeConfigPaths {
@@ -88,6 +146,8 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		BurstLimit:            opts.KubeBurstLimit,
 		CertificateAuthority:  opts.KubeCAPath,
 		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
 		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 		KubeConfigBase64:      opts.KubeConfigBase64,
 		Namespace:             releaseNamespace,
@@ -95,18 +155,36 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		Server:                opts.KubeAPIServerName,
 		TLSServerName:         opts.KubeTLSServerName,
 		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
 	})
 	if err != nil {
-		return fmt.Errorf("construct kube config: %w", err)
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	if err := checkProtectedContext(ctx, kubeConfig, protectedContextCheckOptions{
+		ConfirmFunc:      opts.ConfirmFunc,
+		Confirmed:        opts.ProtectedContextConfirmed,
+		KubeContext:      opts.KubeContext,
+		Patterns:         opts.ProtectedContexts,
+		PatternsFilePath: opts.ProtectedContextsFilePath,
+	}); err != nil {
+		return nil, fmt.Errorf("check protected context: %w", err)
 	}
 
-	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig)
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+		WatchCache:        opts.KubeWatchCache,
+	})
 	if err != nil {
-		return fmt.Errorf("construct kube client factory: %w", err)
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
 	}
+	defer clientFactory.Close(context.WithoutCancel(ctx))
 
-	helmSettings := helm_v3.Settings
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
+	eventRecorder, err := newEventRecorder(releaseName, releaseNamespace, opts.EmitEvents, opts.EventsInvolvedObject, clientFactory)
+	if err != nil {
+		return nil, fmt.Errorf("construct event recorder: %w", err)
+	}
 
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
@@ -117,7 +195,7 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 			log.Default.Debug(ctx, format, a...)
 		},
 	); err != nil {
-		return fmt.Errorf("helm action config init: %w", err)
+		return nil, fmt.Errorf("helm action config init: %w", err)
 	}
 
 	helmReleaseStorage := helmActionConfig.Releases
@@ -130,15 +208,19 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		clientFactory.Static(),
 		clientFactory.Dynamic(),
 	); err != nil {
-		return fmt.Errorf("construct lock manager: %w", err)
+		return nil, fmt.Errorf("construct lock manager: %w", err)
 	} else {
 		lockManager = m
 	}
 
-	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Starting rollback of release")+" %q (namespace: %q)", releaseName, releaseNamespace)
+	if opts.PlanOnly {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Planning rollback of release")+" %q (namespace: %q)", releaseName, releaseNamespace)
+	} else {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Starting rollback of release")+" %q (namespace: %q)", releaseName, releaseNamespace)
+	}
 
 	if lock, err := lockManager.LockRelease(ctx, releaseName); err != nil {
-		return fmt.Errorf("lock release: %w", err)
+		return nil, fmt.Errorf("lock release: %w", err)
 	} else {
 		defer lockManager.Unlock(lock)
 	}
@@ -154,30 +236,36 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("construct release history: %w", err)
+		return nil, fmt.Errorf("construct release history: %w", err)
 	}
 
 	prevRelease, prevReleaseFound, err := history.LastRelease()
 	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
+		return nil, fmt.Errorf("get last release: %w", err)
 	} else if !prevReleaseFound {
-		return fmt.Errorf("not found release %q (namespace: %q)", releaseName, releaseNamespace)
+		return nil, fmt.Errorf("not found release %q (namespace: %q)", releaseName, releaseNamespace)
+	}
+
+	if !opts.PlanOnly {
+		if err := repairPendingRelease(ctx, history, prevRelease, opts.PendingReleaseTTL); err != nil {
+			return nil, fmt.Errorf("repair pending release: %w", err)
+		}
 	}
 
 	prevDeployedRelease, _, err := history.LastDeployedRelease()
 	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
+		return nil, fmt.Errorf("get last deployed release: %w", err)
 	}
 
 	var releaseToRollback *release.Release
 	if opts.Revision == 0 {
 		prevDeployedReleaseExceptLastRelease, found, err := history.LastDeployedReleaseExceptLastRelease()
 		if err != nil {
-			return fmt.Errorf("get last deployed release except last release: %w", err)
+			return nil, fmt.Errorf("get last deployed release except last release: %w", err)
 		}
 
 		if !found {
-			return fmt.Errorf("not found successfully deployed (except last) release %q (namespace: %q)", releaseName, releaseNamespace)
+			return nil, fmt.Errorf("not found successfully deployed (except last) release %q (namespace: %q)", releaseName, releaseNamespace)
 		}
 
 		releaseToRollback = prevDeployedReleaseExceptLastRelease
@@ -185,9 +273,9 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		var found bool
 		releaseToRollback, found, err = history.Release(opts.Revision)
 		if err != nil {
-			return fmt.Errorf("get release revision %q: %w", opts.Revision, err)
+			return nil, fmt.Errorf("get release revision %q: %w", opts.Revision, err)
 		} else if !found {
-			return fmt.Errorf("not found revision %q for release %q (namespace: %q)", opts.Revision, releaseName, releaseNamespace)
+			return nil, fmt.Errorf("not found revision %q for release %q (namespace: %q)", opts.Revision, releaseName, releaseNamespace)
 		}
 	}
 
@@ -203,6 +291,32 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 	deployType := common.DeployTypeRollback
 	notes := releaseToRollback.Notes()
 
+	// Stored notes are already concatenated, so to split them by chart they have to be rendered again.
+	var notesByChart []*chart.ChartNotes
+	if opts.SubNotes {
+		if n, err := chart.RenderNotes(ctx, releaseToRollback.LegacyChart(), releaseToRollback.Values(), chartutil.ReleaseOptions{
+			Name:      releaseName,
+			Namespace: releaseNamespace,
+			Revision:  newRevision,
+			IsUpgrade: true,
+		}, helmActionConfig, true); err != nil {
+			log.Default.Warn(ctx, "Unable to render notes of subcharts, using notes of revision %d: %s", releaseToRollback.Revision(), err)
+		} else {
+			notesByChart = n
+			notes = chart.JoinNotes(n)
+		}
+	}
+
+	deployablePatchers := []resource.ResourcePatcher{
+		resource.NewExtraMetadataPatcher(
+			opts.ExtraRuntimeAnnotations, nil,
+		),
+	}
+
+	if !opts.NoManifestHashAnnotation {
+		deployablePatchers = append(deployablePatchers, resource.NewManifestHashPatcher())
+	}
+
 	log.Default.Debug(ctx, "Processing rollback resources")
 	resProcessor := resourceinfo.NewDeployableResourcesProcessor(
 		deployType,
@@ -213,26 +327,28 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		releaseToRollback.GeneralResources(),
 		prevRelease.GeneralResources(),
 		resourceinfo.DeployableResourcesProcessorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
-			DeployableHookResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					opts.ExtraRuntimeAnnotations, nil,
-				),
-			},
-			DeployableGeneralResourcePatchers: []resource.ResourcePatcher{
-				resource.NewExtraMetadataPatcher(
-					opts.ExtraRuntimeAnnotations, nil,
-				),
-			},
-			KubeClient:         clientFactory.KubeClient(),
-			Mapper:             clientFactory.Mapper(),
-			DiscoveryClient:    clientFactory.Discovery(),
-			AllowClusterAccess: true,
+			NetworkParallelism:                opts.NetworkParallelism,
+			ResourceSizeLimit:                 opts.ResourceSizeLimit,
+			DeployableHookResourcePatchers:    deployablePatchers,
+			DeployableGeneralResourcePatchers: deployablePatchers,
+			KubeClient:                        clientFactory.KubeClient(),
+			Mapper:                            clientFactory.Mapper(),
+			DiscoveryClient:                   clientFactory.Discovery(),
+			AllowClusterAccess:                true,
+			DryRunNewResources:                opts.PlanOnly,
+			ForceReplace:                      opts.ForceReplace,
 		},
 	)
 
 	if err := resProcessor.Process(ctx); err != nil {
-		return fmt.Errorf("process resources: %w", err)
+		return nil, fmt.Errorf("process resources: %w", err)
+	}
+
+	telemetry.FromContext(ctx).SetResourceCount(len(resProcessor.DeployableStandaloneCRDsInfos()) + len(resProcessor.DeployableHookResourcesInfos()) + len(resProcessor.DeployableGeneralResourcesInfos()))
+
+	description := opts.ReleaseDescription
+	if description == "" {
+		description = fmt.Sprintf("Rollback to %d", releaseToRollback.Revision())
 	}
 
 	log.Default.Debug(ctx, "Constructing new rollback release")
@@ -246,12 +362,54 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		resProcessor.ReleasableGeneralResources(),
 		notes,
 		release.ReleaseOptions{
-			FirstDeployed: firstDeployed,
-			Mapper:        clientFactory.Mapper(),
+			Description:     description,
+			FirstDeployed:   firstDeployed,
+			InfoAnnotations: map[string]string{release.DeployIDInfoAnnotation: deployID},
+			Mapper:          clientFactory.Mapper(),
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("construct new rollback release: %w", err)
+		return nil, fmt.Errorf("construct new rollback release: %w", err)
+	}
+
+	if opts.PlanOnly {
+		log.Default.Debug(ctx, "Calculating planned changes")
+		createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, _ := plan.CalculatePlannedChanges(
+			releaseName,
+			releaseNamespace,
+			resProcessor.DeployableReleaseNamespaceInfo(),
+			resProcessor.DeployableStandaloneCRDsInfos(),
+			resProcessor.DeployableHookResourcesInfos(),
+			resProcessor.DeployableGeneralResourcesInfos(),
+			resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+			prevRelease.Failed(),
+			plan.CalculatePlannedChangesOptions{
+				DiffContextLines:   opts.DiffContextLines,
+				ShowSensitiveDiffs: opts.ShowSensitiveDiffs,
+			},
+		)
+
+		releaseUpToDate, err := release.ReleaseUpToDate(prevRelease, newRel)
+		if err != nil {
+			return nil, fmt.Errorf("check if release is up to date: %w", err)
+		}
+
+		plan.LogPlannedChanges(
+			ctx,
+			releaseName,
+			releaseNamespace,
+			!releaseUpToDate,
+			createdChanges,
+			recreatedChanges,
+			updatedChanges,
+			appliedChanges,
+			deletedChanges,
+			plan.LogPlannedChangesOptions{
+				ShowDiff: opts.ShowDiff,
+			},
+		)
+
+		return nil, nil
 	}
 
 	taskStore := statestore.NewTaskStore()
@@ -259,6 +417,12 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		logstore.NewLogStore(),
 	)
 
+	var logFollower *track.LogFollower
+	if opts.ShowLogsUntil == ShowLogsUntilEnd && opts.ProgressMode != ProgressModeNone {
+		logFollower = track.NewLogFollower(ctx, clientFactory.Static(), logStore)
+		defer logFollower.Stop()
+	}
+
 	log.Default.Debug(ctx, "Constructing new rollback plan")
 	deployPlanBuilder := plan.NewDeployPlanBuilder(
 		releaseNamespace,
@@ -282,6 +446,10 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 			CreationTimeout:     opts.TrackCreationTimeout,
 			ReadinessTimeout:    opts.TrackReadinessTimeout,
 			DeletionTimeout:     opts.TrackDeletionTimeout,
+			ReadyStableFor:      opts.ReadyStableFor,
+			IgnoreLogs:          opts.ProgressMode == ProgressModeNone,
+			LogFollower:         logFollower,
+			ForceReplace:        opts.ForceReplace,
 		},
 	)
 
@@ -294,101 +462,125 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 			graphPath = filepath.Join(opts.TempDirPath, "release-rollback-graph.dot")
 		}
 
-		if _, err := os.Create(graphPath); err != nil {
-			log.Default.Error(ctx, "Error: create release rollback graph file: %s", err)
-			return fmt.Errorf("build release rollback plan: %w", planBuildErr)
-		}
-
 		if err := deployPlan.SaveDOT(graphPath); err != nil {
 			log.Default.Error(ctx, "Error: save release rollback graph: %s", err)
+		} else {
+			log.Default.Warn(ctx, "Release rollback graph saved to %q for debugging", graphPath)
 		}
 
-		log.Default.Warn(ctx, "Release rollback graph saved to %q for debugging", graphPath)
-
-		return fmt.Errorf("build release rollback plan: %w", planBuildErr)
+		return nil, fmt.Errorf("build release rollback plan: %w", planBuildErr)
 	}
 
 	if opts.RollbackGraphPath != "" {
 		if err := deployPlan.SaveDOT(opts.RollbackGraphPath); err != nil {
-			return fmt.Errorf("save release rollback graph: %w", err)
+			return nil, fmt.Errorf("save release rollback graph: %w", err)
 		}
 	}
 
+	if err := deployPlan.Validate(); err != nil {
+		return nil, fmt.Errorf("validate release rollback plan: %w", err)
+	}
+
 	var releaseUpToDate bool
 	if prevReleaseFound {
 		releaseUpToDate, err = release.ReleaseUpToDate(prevRelease, newRel)
 		if err != nil {
-			return fmt.Errorf("check if release is up to date: %w", err)
+			return nil, fmt.Errorf("check if release is up to date: %w", err)
 		}
 	}
 
 	planUseless, err := deployPlan.Useless()
 	if err != nil {
-		return fmt.Errorf("check if release rollback plan will do anything useful: %w", err)
+		return nil, fmt.Errorf("check if release rollback plan will do anything useful: %w", err)
+	}
+
+	reportOpts := reportOptions{
+		PreviousRevision: prevRelease.Revision(),
+		StartedAt:        startedAt,
+		Plan:             deployPlan,
 	}
 
 	if releaseUpToDate && planUseless {
-		if opts.RollbackReportPath != "" {
-			newRel.Skip()
+		newRel.Skip()
 
-			report := newReport(nil, nil, nil, newRel)
+		report := newReport(nil, nil, nil, newRel, reportOpts)
 
+		if opts.RollbackReportPath != "" {
 			if err := report.Save(opts.RollbackReportPath); err != nil {
 				log.Default.Error(ctx, "Error: save release rollback report: %s", err)
 			}
 		}
 
-		printNotes(ctx, notes)
+		if opts.NotesFunc != nil {
+			opts.NotesFunc(ctx, newReleaseNotes(notesByChart, notes))
+		}
 
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Skipped rollback of release %q (namespace: %q): cluster resources already as desired", releaseName, releaseNamespace)))
 
-		return nil
+		return report.DeployReport(), nil
 	}
 
 	tablesBuilder := track.NewTablesBuilder(
 		taskStore,
-		logStore,
 		track.TablesBuilderOptions{
 			DefaultNamespace: releaseNamespace,
 			Colorize:         opts.LogColorMode == LogColorModeOn,
 		},
 	)
 
+	logStreamer := track.NewLogStreamer(
+		taskStore,
+		logStore,
+		track.LogStreamerOptions{
+			DefaultNamespace: releaseNamespace,
+			Colorize:         opts.LogColorMode == LogColorModeOn,
+			Tail:             opts.LogTail,
+			Until:            track.ShowLogsUntil(opts.ShowLogsUntil),
+		},
+	)
+
 	log.Default.Debug(ctx, "Starting tracking")
-	stdoutTrackerStopCh := make(chan bool)
-	stdoutTrackerFinishedCh := make(chan bool)
-
-	if !opts.NoProgressTablePrint {
-		go func() {
-			ticker := time.NewTicker(opts.ProgressTablePrintInterval)
-			defer func() {
-				ticker.Stop()
-				stdoutTrackerFinishedCh <- true
-			}()
-
-			for {
-				select {
-				case <-ticker.C:
-					printTables(ctx, tablesBuilder)
-				case <-stdoutTrackerStopCh:
-					printTables(ctx, tablesBuilder)
-					return
-				}
-			}
-		}()
+	ctx, stopProgressPrinting := startProgressPrinting(ctx, opts.ProgressMode, opts.ProgressTablePrintInterval, tablesBuilder, logStreamer)
+
+	if eventRecorder != nil {
+		eventRecorder.Started(ctx, "rollback", newRel.Revision())
 	}
 
 	log.Default.Debug(ctx, "Executing release rollback plan")
 	planExecutor := plan.NewPlanExecutor(
 		deployPlan,
 		plan.PlanExecutorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
+			InterruptGracePeriod: DefaultInterruptGracePeriod,
+			Parallelism:          opts.Parallelism,
+			ProgressReporter:     opts.ProgressReporter,
+			RetryPolicy: plan.OperationRetryPolicy{
+				Retries: opts.OperationRetries,
+				Backoff: opts.OperationRetryBackoff,
+			},
+			TrackParallelism: opts.TrackParallelism,
 		},
 	)
 
 	var criticalErrs, nonCriticalErrs []error
 
 	planExecutionErr := planExecutor.Execute(ctx)
+
+	// The release must be finalized even if interrupted, so don't let the cancellation propagate
+	// any further.
+	if ctx.Err() != nil {
+		if timeoutErr, timedOut := actionTimedOut(ctx); timedOut {
+			log.Default.Warn(ctx, "Release rollback timed out after %s, finalizing release %q (namespace: %q)", timeoutErr.Timeout, releaseName, releaseNamespace)
+		} else {
+			log.Default.Warn(ctx, "Release rollback interrupted, finalizing release %q (namespace: %q)", releaseName, releaseNamespace)
+		}
+
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	if err := saveExecutedPlanGraph(ctx, deployPlan, opts.RollbackGraphPath, filepath.Join(opts.TempDirPath, "release-rollback-graph.dot"), planExecutionErr != nil); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save release rollback graph: %w", err))
+	}
+
 	if planExecutionErr != nil {
 		criticalErrs = append(criticalErrs, fmt.Errorf("execute release rollback plan: %w", planExecutionErr))
 	}
@@ -435,7 +627,9 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 			prevRelease,
 			history,
 			clientFactory,
-			opts.NetworkParallelism,
+			false,
+			opts.Parallelism,
+			opts.TrackParallelism,
 		)
 
 		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
@@ -445,16 +639,18 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 		nonCriticalErrs = append(nonCriticalErrs, noncriterrs...)
 	}
 
-	if !opts.NoProgressTablePrint {
-		stdoutTrackerStopCh <- true
-		<-stdoutTrackerFinishedCh
+	if logFollower != nil {
+		logFollower.Stop()
 	}
 
+	stopProgressPrinting()
+
 	report := newReport(
 		worthyCompletedOps,
 		worthyCanceledOps,
 		worthyFailedOps,
 		newRel,
+		reportOpts,
 	)
 
 	report.Print(ctx)
@@ -466,17 +662,29 @@ func ReleaseRollback(ctx context.Context, releaseName, releaseNamespace string,
 	}
 
 	if len(criticalErrs) == 0 {
-		printNotes(ctx, notes)
+		logKeptResources(ctx, deployPlanBuilder.KeptResources())
+
+		if opts.NotesFunc != nil {
+			opts.NotesFunc(ctx, newReleaseNotes(notesByChart, notes))
+		}
+	}
+
+	if eventRecorder != nil {
+		if len(criticalErrs) > 0 {
+			eventRecorder.Failed(ctx, "rollback", newRel.Revision(), operationsHumanIDs(worthyFailedOps), criticalErrs)
+		} else {
+			eventRecorder.Succeeded(ctx, "rollback", newRel.Revision())
+		}
 	}
 
 	if len(criticalErrs) > 0 {
-		return util.Multierrorf("failed rollback of release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), releaseName, releaseNamespace)
+		return report.DeployReport(), util.Multierrorf("failed rollback of release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), releaseName, releaseNamespace)
 	} else if len(nonCriticalErrs) > 0 {
-		return util.Multierrorf("succeeded rollback of release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, releaseName, releaseNamespace)
+		return report.DeployReport(), util.Multierrorf("succeeded rollback of release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, releaseName, releaseNamespace)
 	} else {
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Succeeded rollback of release %q (namespace: %q)", releaseName, releaseNamespace)))
 
-		return nil
+		return report.DeployReport(), nil
 	}
 }
 
@@ -510,10 +718,32 @@ func applyReleaseRollbackOptionsDefaults(
 		opts.KubeBurstLimit = DefaultBurstLimit
 	}
 
+	if opts.DiffContextLines < 0 {
+		opts.DiffContextLines = DefaultDiffContextLines
+	}
+
+	if opts.OperationRetryBackoff <= 0 {
+		opts.OperationRetryBackoff = DefaultOperationRetryBackoff
+	}
+
+	opts.ProgressMode, err = applyProgressModeDefault(opts.ProgressMode, opts.NoProgressTablePrint)
+	if err != nil {
+		return ReleaseRollbackOptions{}, fmt.Errorf("apply progress mode default: %w", err)
+	}
+
 	if opts.ProgressTablePrintInterval <= 0 {
 		opts.ProgressTablePrintInterval = DefaultProgressPrintInterval
 	}
 
+	opts.ShowLogsUntil, err = applyShowLogsUntilDefault(opts.ShowLogsUntil)
+	if err != nil {
+		return ReleaseRollbackOptions{}, fmt.Errorf("apply show logs until default: %w", err)
+	}
+
+	if opts.LogTail <= 0 {
+		opts.LogTail = DefaultLogTail
+	}
+
 	if opts.ReleaseHistoryLimit <= 0 {
 		opts.ReleaseHistoryLimit = DefaultReleaseHistoryLimit
 	}
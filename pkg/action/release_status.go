@@ -0,0 +1,342 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/gookit/color"
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/sourcegraph/conc/pool"
+
+	"github.com/werf/3p-helm/pkg/action"
+	"github.com/werf/nelm/internal/kube"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultReleaseStatusOutputFormat = TableOutputFormat
+	DefaultReleaseStatusLogLevel     = ErrorLogLevel
+)
+
+var ErrReleaseNotReady = errors.New("release resources not ready")
+
+type ReleaseStatusOptions struct {
+	FailOnUnready         bool
+	KubeAPIServerName     string
+	KubeBurstLimit        int
+	KubeCAPath            string
+	KubeConfigBase64      string
+	KubeConfigPaths       []string
+	KubeContext           string
+	KubeDiscoveryCacheDir string
+	KubeImpersonateGroups []string
+	KubeImpersonateUser   string
+	KubeQPSLimit          int
+	KubeRefreshDiscovery  bool
+	KubeSkipTLSVerify     bool
+	KubeTLSServerName     string
+	KubeToken             string
+	KubeTokenPath         string
+	LogColorMode          string
+	NetworkParallelism    int
+	OutputFormat          string
+	OutputNoPrint         bool
+	ReleaseStorageDriver  string
+	TempDirPath           string
+}
+
+func ReleaseStatus(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseStatusOptions) (*ReleaseStatusResultV1, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseStatusOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return nil, &UsageError{Err: fmt.Errorf("build release status options: %w", err)}
+	}
+
+	if len(opts.KubeConfigPaths) > 0 {
+		var splitPaths []string
+		for _, path := range opts.KubeConfigPaths {
+			splitPaths = append(splitPaths, filepath.SplitList(path)...)
+		}
+
+		opts.KubeConfigPaths = splitPaths
+	}
+
+	kubeConfig, err := kube.NewKubeConfig(ctx, opts.KubeConfigPaths, kube.KubeConfigOptions{
+		BurstLimit:            opts.KubeBurstLimit,
+		CertificateAuthority:  opts.KubeCAPath,
+		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
+		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
+		KubeConfigBase64:      opts.KubeConfigBase64,
+		Namespace:             releaseNamespace,
+		QPSLimit:              opts.KubeQPSLimit,
+		Server:                opts.KubeAPIServerName,
+		TLSServerName:         opts.KubeTLSServerName,
+		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube config: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		clientFactory.LegacyClientGetter(),
+		releaseNamespace,
+		string(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Debug(ctx, format, a...)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		helmReleaseStorage,
+		release.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	rel, found, err := history.LastRelease()
+	if err != nil {
+		return nil, fmt.Errorf("get last release: %w", err)
+	} else if !found {
+		return nil, fmt.Errorf("release %q (namespace %q) not found", releaseName, releaseNamespace)
+	}
+
+	result, err := buildReleaseStatusResult(ctx, rel, clientFactory.KubeClient(), opts.NetworkParallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.OutputNoPrint {
+		switch opts.OutputFormat {
+		case JsonOutputFormat:
+			b, err := json.MarshalIndent(result, "", strings.Repeat(" ", 2))
+			if err != nil {
+				return nil, fmt.Errorf("marshal result to json: %w", err)
+			}
+
+			var colorLevel color.Level
+			if opts.LogColorMode != LogColorModeOff {
+				colorLevel = color.DetectColorLevel()
+			}
+
+			if err := writeWithSyntaxHighlight(os.Stdout, string(b), JsonOutputFormat, colorLevel); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		case TableOutputFormat:
+			if _, err := os.Stdout.Write([]byte(renderReleaseStatusTable(result, releaseNamespace))); err != nil {
+				return nil, fmt.Errorf("write result to output: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+		}
+	}
+
+	if opts.FailOnUnready && (result.Summary.Missing > 0 || result.Summary.Unready > 0) {
+		return result, ErrReleaseNotReady
+	}
+
+	return result, nil
+}
+
+// buildReleaseStatusResult gets the live state of the resources of the release, without waiting for
+// anything.
+func buildReleaseStatusResult(ctx context.Context, rel *release.Release, kubeClient kube.KubeClienter, networkParallelism int) (*ReleaseStatusResultV1, error) {
+	resources := rel.GeneralResources()
+
+	resourcesResults := make([]*ReleaseStatusResultResource, len(resources))
+	statusPool := pool.New().WithContext(ctx).WithMaxGoroutines(networkParallelism).WithFirstError()
+	for i, res := range resources {
+		i, res := i, res
+		statusPool.Go(func(ctx context.Context) error {
+			liveObj, _, err := kubeClient.GetOrNil(ctx, res.ResourceID, kube.KubeClientGetOptions{})
+			if err != nil {
+				return fmt.Errorf("get resource %q: %w", res.HumanID(), err)
+			}
+
+			liveStatus, err := resource.EvaluateLiveStatus(liveObj)
+			if err != nil {
+				return fmt.Errorf("evaluate status of resource %q: %w", res.HumanID(), err)
+			}
+
+			resourcesResults[i] = &ReleaseStatusResultResource{
+				Name:      res.Name(),
+				Namespace: res.Namespace(),
+				Kind:      res.GroupVersionKind().Kind,
+				Group:     res.GroupVersionKind().Group,
+				Status:    string(liveStatus.Type),
+				Progress:  liveStatus.Progress,
+				Healthy:   liveStatus.Healthy(),
+			}
+
+			return nil
+		})
+	}
+
+	if err := statusPool.Wait(); err != nil {
+		return nil, fmt.Errorf("get live status of release resources: %w", err)
+	}
+
+	result := &ReleaseStatusResultV1{
+		ApiVersion: ReleaseStatusResultApiVersionV1,
+		Release: &ReleaseStatusResultRelease{
+			Name:      rel.Name(),
+			Namespace: rel.Namespace(),
+			Revision:  rel.Revision(),
+			Status:    string(rel.Status()),
+		},
+		Resources: resourcesResults,
+		Summary: &ReleaseStatusResultSummary{
+			Total: len(resourcesResults),
+		},
+	}
+
+	for _, res := range resourcesResults {
+		switch {
+		case res.Status == string(resource.LiveStatusMissing):
+			result.Summary.Missing++
+		case res.Healthy:
+			result.Summary.Ready++
+		default:
+			result.Summary.Unready++
+		}
+	}
+
+	return result, nil
+}
+
+func renderReleaseStatusTable(result *ReleaseStatusResultV1, releaseNamespace string) string {
+	table := prtable.NewWriter()
+	table.SetStyle(prtable.StyleLight)
+	table.Style().Options = prtable.OptionsNoBordersAndSeparators
+	table.AppendHeader(prtable.Row{"RESOURCE", "STATUS"})
+
+	for _, res := range result.Resources {
+		humanID := fmt.Sprintf("%s/%s", res.Kind, res.Name)
+		if res.Namespace != "" && res.Namespace != releaseNamespace {
+			humanID = res.Namespace + "/" + humanID
+		}
+
+		status := res.Status
+		if res.Progress != "" {
+			status += " " + res.Progress
+		}
+
+		table.AppendRow(prtable.Row{humanID, status})
+	}
+
+	return fmt.Sprintf(
+		"%s\n\nRelease %q revision %d is %s: %d/%d resources ready, %d unready, %d missing\n",
+		table.Render(),
+		result.Release.Name,
+		result.Release.Revision,
+		result.Release.Status,
+		result.Summary.Ready,
+		result.Summary.Total,
+		result.Summary.Unready,
+		result.Summary.Missing,
+	)
+}
+
+func applyReleaseStatusOptionsDefaults(opts ReleaseStatusOptions, currentUser *user.User) (ReleaseStatusOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return ReleaseStatusOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = DefaultNetworkParallelism
+	}
+
+	if opts.KubeQPSLimit <= 0 {
+		opts.KubeQPSLimit = DefaultQPSLimit
+	}
+
+	if opts.KubeBurstLimit <= 0 {
+		opts.KubeBurstLimit = DefaultBurstLimit
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	}
+
+	if opts.OutputFormat == "" {
+		opts.OutputFormat = DefaultReleaseStatusOutputFormat
+	}
+
+	return opts, nil
+}
+
+const ReleaseStatusResultApiVersionV1 = "v1"
+
+type ReleaseStatusResultV1 struct {
+	ApiVersion string                         `json:"apiVersion"`
+	Release    *ReleaseStatusResultRelease    `json:"release"`
+	Resources  []*ReleaseStatusResultResource `json:"resources"`
+	Summary    *ReleaseStatusResultSummary    `json:"summary"`
+}
+
+type ReleaseStatusResultRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	Status    string `json:"status"`
+}
+
+type ReleaseStatusResultResource struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Group     string `json:"group"`
+	Status    string `json:"status"`
+	Progress  string `json:"progress,omitempty"`
+	Healthy   bool   `json:"healthy"`
+}
+
+type ReleaseStatusResultSummary struct {
+	Total   int `json:"total"`
+	Ready   int `json:"ready"`
+	Unready int `json:"unready"`
+	Missing int `json:"missing"`
+}
@@ -0,0 +1,75 @@
+package action_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("release status", func() {
+	unstruct := func(manifest string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		Expect(yaml.Unmarshal([]byte(manifest), &obj.Object)).To(Succeed())
+
+		return obj
+	}
+
+	It("reports live status of release resources, including missing and unready ones", func() {
+		ctx := context.Background()
+
+		cluster := fake.NewCluster(ctx,
+			unstruct(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, namespace: app-ns}, spec: {replicas: 3}, status: {replicas: 3, updatedReplicas: 3, readyReplicas: 3, availableReplicas: 3}}`),
+			unstruct(`{apiVersion: apps/v1, kind: Deployment, metadata: {name: worker, namespace: app-ns}, spec: {replicas: 2}, status: {replicas: 2, updatedReplicas: 2, readyReplicas: 1, availableReplicas: 1}}`),
+			unstruct(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, namespace: app-ns}, status: {conditions: [{type: Complete, status: "True"}]}}`),
+			unstruct(`{apiVersion: v1, kind: ConfigMap, metadata: {name: config, namespace: app-ns}}`),
+		)
+
+		var resources []*resource.GeneralResource
+		for _, manifest := range []string{
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api}}`,
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: worker}}`,
+			`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate}}`,
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: config}}`,
+			`{apiVersion: v1, kind: Secret, metadata: {name: credentials}}`,
+		} {
+			resources = append(resources, resource.NewGeneralResource(unstruct(manifest), resource.GeneralResourceOptions{
+				DefaultNamespace: "app-ns",
+				Mapper:           cluster.Mapper,
+			}))
+		}
+
+		rel, err := release.NewRelease("app", "app-ns", 1, nil, &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}}, nil, resources, "", release.ReleaseOptions{Mapper: cluster.Mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := action.BuildReleaseStatusResult(ctx, rel, cluster.KubeClient, 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		statuses := map[string]string{}
+		for _, res := range result.Resources {
+			status := res.Status
+			if res.Progress != "" {
+				status += " " + res.Progress
+			}
+
+			statuses[res.Kind+"/"+res.Name] = status
+		}
+
+		Expect(statuses).To(Equal(map[string]string{
+			"Deployment/api":     "Ready 3/3",
+			"Deployment/worker":  "NotReady 1/2",
+			"Job/migrate":        "Completed",
+			"ConfigMap/config":   "Present",
+			"Secret/credentials": "MISSING",
+		}))
+		Expect(*result.Summary).To(Equal(action.ReleaseStatusResultSummary{Total: 5, Ready: 3, Unready: 1, Missing: 1}))
+	})
+})
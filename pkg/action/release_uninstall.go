@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -17,15 +18,20 @@ import (
 	helm_v3 "github.com/werf/3p-helm/cmd/helm"
 	"github.com/werf/3p-helm/pkg/action"
 	helm_kube "github.com/werf/3p-helm/pkg/kube"
+	helmrelease "github.com/werf/3p-helm/pkg/release"
 	"github.com/werf/3p-helm/pkg/storage/driver"
 	kdkube "github.com/werf/kubedog/pkg/kube"
-	"github.com/werf/logboek"
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog/pkg/trackers/dyntracker/util"
 	"github.com/werf/nelm/internal/kube"
 	"github.com/werf/nelm/internal/legacy/deploy"
 	"github.com/werf/nelm/internal/lock"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/plan"
 	"github.com/werf/nelm/internal/plan/operation"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
 	"github.com/werf/nelm/internal/resource/id"
+	"github.com/werf/nelm/pkg/log"
 )
 
 const (
@@ -33,8 +39,18 @@ const (
 )
 
 type ReleaseUninstallOptions struct {
+	ConfirmFunc                ConfirmFunc
+	KubeDiscoveryCacheDir      string
+	KubeImpersonateGroups      []string
+	KubeImpersonateUser        string
+	KubeRefreshDiscovery       bool
+	KubeTokenPath              string
 	NoDeleteHooks              bool
 	DeleteReleaseNamespace     bool
+	DryRun                     bool
+	EmitEvents                 bool
+	EventsInvolvedObject       string
+	KeepHistory                bool
 	KubeAPIServerName          string
 	KubeBurstLimit             int
 	KubeCAPath                 string
@@ -48,15 +64,100 @@ type ReleaseUninstallOptions struct {
 	LogColorMode               string
 	NetworkParallelism         int
 	ProgressTablePrintInterval time.Duration
+	ProtectedContextConfirmed  bool
+	ProtectedContexts          []string
+	ProtectedContextsFilePath  string
+	ReleaseDescription         string
 	ReleaseHistoryLimit        int
 	ReleaseStorageDriver       string
 	TempDirPath                string
+	Timeout                    time.Duration
+	TrackDeletionTimeout       time.Duration
+	UninstallReportPath        string
+	UninstallStepsPath         string
 }
 
-func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseUninstallOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
+// Kept resources are reported as unchanged. Only pre-delete and post-delete hooks are reported.
+func uninstallReportResources(rel *release.Release, keptSteps []*plan.UninstallStep) ([]*DeployReportResource, []*DeployReportHook) {
+	kept := map[string]bool{}
+	for _, step := range keptSteps {
+		kept[step.Resource] = true
+	}
+
+	var resources []*DeployReportResource
+	for _, res := range rel.GeneralResources() {
+		outcome := DeployReportResourceOutcomeDeleted
+		if kept[res.HumanID()] {
+			outcome = DeployReportResourceOutcomeUnchanged
+		}
+
+		gvk := res.GroupVersionKind()
+		resources = append(resources, &DeployReportResource{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: res.Namespace(),
+			Name:      res.Name(),
+			Outcome:   outcome,
+		})
+	}
+
+	var hooks []*DeployReportHook
+	for _, hook := range rel.HookResources() {
+		if !hook.OnPreDelete() && !hook.OnPostDelete() {
+			continue
+		}
+
+		gvk := hook.GroupVersionKind()
+		hooks = append(hooks, &DeployReportHook{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: hook.Namespace(),
+			Name:      hook.Name(),
+			Result:    DeployReportHookResultSucceeded,
+		})
+	}
+
+	return resources, hooks
+}
+
+// ReleaseUninstall returns the deploy report even if the uninstall failed. No report is returned
+// with DryRun.
+func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string, opts ReleaseUninstallOptions) (*DeployReport, error) {
+	ctx, cancel := withActionTimeout(ctx, "release uninstall", opts.Timeout)
+	defer cancel()
+
+	report := &DeployReport{
+		Version:   2,
+		Release:   releaseName,
+		Namespace: releaseNamespace,
+		StartedAt: time.Now(),
+	}
+
+	err := releaseUninstall(ctx, releaseName, releaseNamespace, report, opts)
+	if opts.DryRun {
+		return nil, classifyActionErr(wrapActionTimeoutErr(ctx, err))
+	}
 
+	if err != nil {
+		report.Status = helmrelease.StatusFailed
+		report.Description = err.Error()
+	}
+
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+
+	if opts.UninstallReportPath != "" {
+		if err := report.Save(opts.UninstallReportPath); err != nil {
+			log.Default.Error(ctx, "Error: save release uninstall report: %s", err)
+		}
+	}
+
+	return report, classifyActionErr(wrapActionTimeoutErr(ctx, err))
+}
+
+// The report is filled in as the uninstall goes.
+func releaseUninstall(ctx context.Context, releaseName, releaseNamespace string, report *DeployReport, opts ReleaseUninstallOptions) error {
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -69,7 +170,7 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 
 	opts, err = applyReleaseUninstallOptionsDefaults(opts, currentDir, currentUser)
 	if err != nil {
-		return fmt.Errorf("build release uninstall options: %w", err)
+		return &UsageError{Err: fmt.Errorf("build release uninstall options: %w", err)}
 	}
 
 	if len(opts.KubeConfigPaths) > 0 {
@@ -92,6 +193,8 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 		BurstLimit:            opts.KubeBurstLimit,
 		CertificateAuthority:  opts.KubeCAPath,
 		CurrentContext:        opts.KubeContext,
+		Impersonate:           opts.KubeImpersonateUser,
+		ImpersonateGroups:     opts.KubeImpersonateGroups,
 		InsecureSkipTLSVerify: opts.KubeSkipTLSVerify,
 		KubeConfigBase64:      opts.KubeConfigBase64,
 		Namespace:             releaseNamespace,
@@ -99,22 +202,40 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 		Server:                opts.KubeAPIServerName,
 		TLSServerName:         opts.KubeTLSServerName,
 		Token:                 opts.KubeToken,
+		TokenFile:             opts.KubeTokenPath,
 	})
 	if err != nil {
 		return fmt.Errorf("construct kube config: %w", err)
 	}
 
-	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig)
+	if err := checkProtectedContext(ctx, kubeConfig, protectedContextCheckOptions{
+		ConfirmFunc:      opts.ConfirmFunc,
+		Confirmed:        opts.ProtectedContextConfirmed,
+		KubeContext:      opts.KubeContext,
+		Patterns:         opts.ProtectedContexts,
+		PatternsFilePath: opts.ProtectedContextsFilePath,
+	}); err != nil {
+		return fmt.Errorf("check protected context: %w", err)
+	}
+
+	clientFactory, err := kube.NewClientFactory(ctx, kubeConfig, kube.ClientFactoryOptions{
+		DiscoveryCacheDir: opts.KubeDiscoveryCacheDir,
+		RefreshDiscovery:  opts.KubeRefreshDiscovery,
+	})
 	if err != nil {
 		return fmt.Errorf("construct kube client factory: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
+	eventRecorder, err := newEventRecorder(releaseName, releaseNamespace, opts.EmitEvents, opts.EventsInvolvedObject, clientFactory)
+	if err != nil {
+		return fmt.Errorf("construct event recorder: %w", err)
+	}
+
+	helmSettings := newHelmSettings(ctx)
 	*helmSettings.GetConfigP() = clientFactory.LegacyClientGetter()
 	*helmSettings.GetNamespaceP() = releaseNamespace
 	releaseNamespace = helmSettings.Namespace()
 	helmSettings.MaxHistory = opts.ReleaseHistoryLimit
-	helmSettings.Debug = log.Default.AcceptLevel(ctx, log.Level(DebugLogLevel))
 
 	if opts.KubeContext != "" {
 		helmSettings.KubeContext = opts.KubeContext
@@ -173,13 +294,14 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 		id.ResourceIDOptions{Mapper: clientFactory.Mapper()},
 	)
 
-	if _, err := clientFactory.KubeClient().Get(
+	namespaceObj, err := clientFactory.KubeClient().Get(
 		ctx,
 		namespaceID,
 		kube.KubeClientGetOptions{
 			TryCache: true,
 		},
-	); err != nil {
+	)
+	if err != nil {
 		if api_errors.IsNotFound(err) {
 			log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Skipped release %q removal: no release namespace %q found", releaseName, releaseNamespace)))
 
@@ -189,14 +311,25 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 		}
 	}
 
+	if opts.DryRun {
+		return planReleaseUninstall(ctx, releaseName, releaseNamespace, helmReleaseStorage, clientFactory, opts)
+	}
+
 	if err := func() error {
-		var releaseFound bool
-		if _, err := helmActionConfig.Releases.History(releaseName); err != nil {
+		var (
+			releaseFound bool
+			revision     int
+		)
+		if legacyRels, err := helmActionConfig.Releases.History(releaseName); err != nil {
 			if !errors.Is(err, driver.ErrReleaseNotFound) {
 				return fmt.Errorf("get release history: %w", err)
 			}
 		} else {
 			releaseFound = true
+
+			for _, legacyRel := range legacyRels {
+				revision = max(revision, legacyRel.Version)
+			}
 		}
 
 		if !releaseFound {
@@ -205,8 +338,14 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 			return nil
 		}
 
+		report.Revision = revision
+
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Deleting release")+" %q (namespace: %q)", releaseName, releaseNamespace)
 
+		if eventRecorder != nil {
+			eventRecorder.Started(ctx, "uninstall", revision)
+		}
+
 		var lockManager *lock.LockManager
 		if m, err := lock.NewLockManager(
 			releaseNamespace,
@@ -225,9 +364,15 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 			defer lockManager.Unlock(lock)
 		}
 
+		lastRelease, keptSteps, err := keptResourcesUninstallSteps(ctx, releaseName, releaseNamespace, helmReleaseStorage, clientFactory, opts)
+		if err != nil {
+			return fmt.Errorf("get resources to keep: %w", err)
+		}
+
+		// Kept resources and the result are reported below instead.
 		helmUninstallCmd := helm_v3.NewUninstallCmd(
 			helmActionConfig,
-			logboek.Context(ctx).OutStream(),
+			io.Discard,
 			helm_v3.UninstallCmdOptions{
 				StagesSplitter:      deploy.NewStagesSplitter(),
 				DeleteHooks:         lo.ToPtr(!opts.NoDeleteHooks),
@@ -235,8 +380,56 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 			},
 		)
 
+		if opts.ReleaseDescription != "" {
+			if err := helmUninstallCmd.Flags().Set("description", release.TruncateDescription(opts.ReleaseDescription)); err != nil {
+				return fmt.Errorf("set uninstall description: %w", err)
+			}
+		}
+
+		if opts.KeepHistory {
+			if err := helmUninstallCmd.Flags().Set("keep-history", "true"); err != nil {
+				return fmt.Errorf("set uninstall keep history: %w", err)
+			}
+		}
+
+		// The uninstall command doesn't accept the context, so at least don't let it wait for hooks
+		// longer than the time left.
+		if deadline, ok := ctx.Deadline(); ok {
+			if err := helmUninstallCmd.Flags().Set("timeout", time.Until(deadline).String()); err != nil {
+				return fmt.Errorf("set uninstall timeout: %w", err)
+			}
+		}
+
 		if err := helmUninstallCmd.RunE(helmUninstallCmd, []string{releaseName}); err != nil {
-			return fmt.Errorf("run uninstall command: %w", err)
+			err = fmt.Errorf("run uninstall command: %w", err)
+
+			if eventRecorder != nil {
+				eventRecorder.Failed(ctx, "uninstall", revision, nil, []error{err})
+			}
+
+			return err
+		}
+
+		if eventRecorder != nil {
+			eventRecorder.Succeeded(ctx, "uninstall", revision)
+		}
+
+		report.Status = helmrelease.StatusUninstalled
+		if lastRelease != nil {
+			report.Resources, report.Hooks = uninstallReportResources(lastRelease, keptSteps)
+		}
+
+		if len(keptSteps) > 0 {
+			title := fmt.Sprintf("Kept %d resources", len(keptSteps))
+			if len(keptSteps) == 1 {
+				title = "Kept 1 resource"
+			}
+
+			log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render(title)).Do(func() {
+				for _, step := range keptSteps {
+					log.Default.Info(ctx, "%s (%s)", step.Resource, step.Reason)
+				}
+			})
 		}
 
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Uninstalled release %q (namespace: %q)", releaseName, releaseNamespace)))
@@ -246,7 +439,11 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 		return err
 	}
 
-	if opts.DeleteReleaseNamespace {
+	if !opts.DeleteReleaseNamespace {
+		if owner, _ := resource.NewRemoteResource(namespaceObj, resource.RemoteResourceOptions{Mapper: clientFactory.Mapper()}).ReleaseNamespaceOwner(); owner == releaseName {
+			log.Default.Info(ctx, "Release namespace %q was created by release %q, but is kept, pass --delete-namespace to delete it", releaseNamespace, releaseName)
+		}
+	} else {
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Deleting release namespace %q", namespaceID.Name())))
 
 		deleteOp := operation.NewDeleteResourceOperation(
@@ -259,12 +456,121 @@ func ReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string,
 			return fmt.Errorf("delete release namespace: %w", err)
 		}
 
+		trackOp := operation.NewTrackResourceAbsenceOperation(
+			namespaceID,
+			kubeutil.NewConcurrent(
+				statestore.NewAbsenceTaskState(namespaceID.Name(), namespaceID.Namespace(), namespaceID.GroupVersionKind(), statestore.AbsenceTaskStateOptions{}),
+			),
+			clientFactory.Dynamic(),
+			clientFactory.Mapper(),
+			operation.TrackResourceAbsenceOperationOptions{
+				Timeout: opts.TrackDeletionTimeout,
+			},
+		)
+
+		if err := trackOp.Execute(ctx); err != nil {
+			return fmt.Errorf("track release namespace absence: %w", err)
+		}
+
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Deleted release namespace %q", namespaceID.Name())))
 	}
 
 	return nil
 }
 
+// Returns the last release and its resources that won't be deleted because of the resource policy
+// or because they are not owned by the release anymore.
+func keptResourcesUninstallSteps(ctx context.Context, releaseName, releaseNamespace string, releaseStorage release.LegacyStorage, clientFactory *kube.ClientFactory, opts ReleaseUninstallOptions) (*release.Release, []*plan.UninstallStep, error) {
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		releaseStorage,
+		release.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("construct release history: %w", err)
+	}
+
+	lastRelease, lastReleaseFound, err := history.LastRelease()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get last release: %w", err)
+	} else if !lastReleaseFound {
+		return nil, nil, nil
+	}
+
+	steps, err := plan.NewUninstallStepsBuilder(
+		releaseNamespace,
+		lastRelease,
+		clientFactory.KubeClient(),
+		clientFactory.Mapper(),
+		plan.UninstallStepsBuilderOptions{
+			DeleteHooks: !opts.NoDeleteHooks,
+		},
+	).Build(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build uninstall steps: %w", err)
+	}
+
+	return lastRelease, lo.Filter(steps, func(step *plan.UninstallStep, _ int) bool {
+		return step.Type == plan.UninstallStepTypeKeep
+	}), nil
+}
+
+func planReleaseUninstall(ctx context.Context, releaseName, releaseNamespace string, releaseStorage release.LegacyStorage, clientFactory *kube.ClientFactory, opts ReleaseUninstallOptions) error {
+	history, err := release.NewHistory(
+		releaseName,
+		releaseNamespace,
+		releaseStorage,
+		release.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	lastRelease, lastReleaseFound, err := history.LastRelease()
+	if err != nil {
+		return fmt.Errorf("get last release: %w", err)
+	}
+
+	if !lastReleaseFound {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Skipped release %q (namespace: %q) uninstall: no release found", releaseName, releaseNamespace)))
+
+		return nil
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Planning release uninstall")+" %q (namespace: %q)", releaseName, releaseNamespace)
+
+	steps, err := plan.NewUninstallStepsBuilder(
+		releaseNamespace,
+		lastRelease,
+		clientFactory.KubeClient(),
+		clientFactory.Mapper(),
+		plan.UninstallStepsBuilderOptions{
+			DeleteHooks:            !opts.NoDeleteHooks,
+			DeleteReleaseNamespace: opts.DeleteReleaseNamespace,
+		},
+	).Build(ctx)
+	if err != nil {
+		return fmt.Errorf("build uninstall steps: %w", err)
+	}
+
+	plan.LogUninstallSteps(ctx, releaseName, releaseNamespace, steps)
+
+	if opts.UninstallStepsPath != "" {
+		if err := plan.SaveUninstallSteps(opts.UninstallStepsPath, steps); err != nil {
+			return fmt.Errorf("save uninstall steps: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func applyReleaseUninstallOptionsDefaults(opts ReleaseUninstallOptions, currentDir string, currentUser *user.User) (ReleaseUninstallOptions, error) {
 	var err error
 	if opts.TempDirPath == "" {
@@ -296,6 +602,10 @@ func applyReleaseUninstallOptionsDefaults(opts ReleaseUninstallOptions, currentD
 		opts.ProgressTablePrintInterval = DefaultProgressPrintInterval
 	}
 
+	if opts.KeepHistory && opts.DeleteReleaseNamespace {
+		return ReleaseUninstallOptions{}, fmt.Errorf("release history can't be kept if the release namespace is deleted, since the history is stored in the release namespace")
+	}
+
 	if opts.ReleaseHistoryLimit <= 0 {
 		opts.ReleaseHistoryLimit = DefaultReleaseHistoryLimit
 	}
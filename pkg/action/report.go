@@ -2,22 +2,20 @@ package action
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"os"
 	"sort"
+	"time"
 
 	"github.com/gookit/color"
 	"github.com/samber/lo"
 
-	helmrelease "github.com/werf/3p-helm/pkg/release"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/internal/plan"
 	"github.com/werf/nelm/internal/plan/operation"
 	"github.com/werf/nelm/internal/release"
 	"github.com/werf/nelm/internal/util"
+	"github.com/werf/nelm/pkg/log"
 )
 
-func newReport(completedOps, canceledOps, failedOps []operation.Operation, release *release.Release) *report {
+func newReport(completedOps, canceledOps, failedOps []operation.Operation, release *release.Release, opts reportOptions) *report {
 	sort.Slice(completedOps, func(i, j int) bool {
 		return completedOps[i].HumanID() < completedOps[j].HumanID()
 	})
@@ -29,18 +27,33 @@ func newReport(completedOps, canceledOps, failedOps []operation.Operation, relea
 	})
 
 	return &report{
-		completedOps: completedOps,
-		failedOps:    failedOps,
-		canceledOps:  canceledOps,
-		release:      release,
+		completedOps:     completedOps,
+		failedOps:        failedOps,
+		canceledOps:      canceledOps,
+		release:          release,
+		previousRevision: opts.PreviousRevision,
+		startedAt:        opts.StartedAt,
+		finishedAt:       time.Now(),
+		plan:             opts.Plan,
 	}
 }
 
+type reportOptions struct {
+	PreviousRevision int
+	StartedAt        time.Time
+	// Durations are taken from the timings of the operations of this plan.
+	Plan *plan.Plan
+}
+
 type report struct {
-	completedOps []operation.Operation
-	failedOps    []operation.Operation
-	canceledOps  []operation.Operation
-	release      *release.Release
+	completedOps     []operation.Operation
+	failedOps        []operation.Operation
+	canceledOps      []operation.Operation
+	release          *release.Release
+	previousRevision int
+	startedAt        time.Time
+	finishedAt       time.Time
+	plan             *plan.Plan
 }
 
 func (r *report) Print(ctx context.Context) {
@@ -49,14 +62,38 @@ func (r *report) Print(ctx context.Context) {
 		return
 	}
 
-	if len(r.completedOps) > 0 {
+	if description := r.release.Description(); description != "" {
+		log.Default.Info(ctx, "Release description: %s", description)
+	}
+
+	degradedOps := degradedOperations(r.completedOps)
+	completedOps := lo.Filter(r.completedOps, func(op operation.Operation, _ int) bool {
+		return !lo.ContainsBy(degradedOps, func(degradedOp operation.DegradableOperation) bool {
+			return degradedOp.ID() == op.ID()
+		})
+	})
+
+	if len(completedOps) > 0 {
 		log.Default.InfoBlock(ctx, completedStyle("Completed operations")).Do(func() {
-			for _, op := range r.completedOps {
+			for _, op := range completedOps {
+				if stabilizer, ok := op.(readinessStabilizer); ok && stabilizer.StabilizationDuration() > 0 {
+					log.Default.Info(ctx, "%s (stable after %s)", util.Capitalize(op.HumanID()), stabilizer.StabilizationDuration().Round(time.Second))
+					continue
+				}
+
 				log.Default.Info(ctx, util.Capitalize(op.HumanID()))
 			}
 		})
 	}
 
+	if len(degradedOps) > 0 {
+		log.Default.InfoBlock(ctx, degradedStyle("Degraded resources")).Do(func() {
+			for _, op := range degradedOps {
+				log.Default.Info(ctx, "%s: %s", op.ResourceID().HumanID(), op.DegradationError())
+			}
+		})
+	}
+
 	if len(r.canceledOps) > 0 {
 		log.Default.InfoBlock(ctx, canceledStyle("Canceled operations")).Do(func() {
 			for _, op := range r.canceledOps {
@@ -74,13 +111,19 @@ func (r *report) Print(ctx context.Context) {
 	}
 }
 
-func (r *report) JSON() ([]byte, error) {
-	reportv2 := reportV2{
-		Version:   2,
-		Release:   r.release.Name(),
-		Namespace: r.release.Namespace(),
-		Revision:  r.release.Revision(),
-		Status:    r.release.Status(),
+func (r *report) DeployReport() *DeployReport {
+	deployReport := &DeployReport{
+		Version:          2,
+		Release:          r.release.Name(),
+		Namespace:        r.release.Namespace(),
+		Revision:         r.release.Revision(),
+		PreviousRevision: r.previousRevision,
+		Status:           r.release.Status(),
+		Description:      r.release.Description(),
+		StartedAt:        r.startedAt,
+		Duration:         r.finishedAt.Sub(r.startedAt).Round(time.Millisecond).String(),
+		Resources:        r.resources(),
+		Hooks:            r.hooks(),
 		CompletedOperations: lo.Map(r.completedOps, func(op operation.Operation, _ int) string {
 			return op.ID()
 		}),
@@ -90,33 +133,47 @@ func (r *report) JSON() ([]byte, error) {
 		FailedOperations: lo.Map(r.failedOps, func(op operation.Operation, _ int) string {
 			return op.ID()
 		}),
+		DegradedOperations:     map[string]string{},
+		ManifestHashes:         map[string]string{},
+		OperationsIDs:          map[string]operation.StructuredID{},
+		StabilizationDurations: map[string]string{},
 	}
 
-	data, err := json.MarshalIndent(reportv2, "", "\t")
-	if err != nil {
-		return nil, fmt.Errorf("error marshalling report: %w", err)
+	for _, op := range degradedOperations(r.completedOps) {
+		deployReport.DegradedOperations[op.ID()] = op.DegradationError().Error()
 	}
 
-	return data, nil
-}
-
-func (r *report) Save(path string) error {
-	data, err := r.JSON()
-	if err != nil {
-		return fmt.Errorf("error constructing report JSON: %w", err)
+	for _, ops := range [][]operation.Operation{r.completedOps, r.canceledOps, r.failedOps} {
+		for _, op := range ops {
+			deployReport.OperationsIDs[op.ID()] = operation.NewStructuredID(op)
+		}
 	}
 
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		return fmt.Errorf("error writing report file at %q: %w", path, err)
+	for _, op := range r.completedOps {
+		if hasher, ok := op.(manifestHasher); ok {
+			deployReport.ManifestHashes[op.ID()] = hasher.ManifestHash()
+		}
+
+		if stabilizer, ok := op.(readinessStabilizer); ok && stabilizer.StabilizationDuration() > 0 {
+			deployReport.StabilizationDurations[op.ID()] = stabilizer.StabilizationDuration().Round(time.Millisecond).String()
+		}
 	}
 
-	return nil
+	return deployReport
+}
+
+func (r *report) Save(path string) error {
+	return r.DeployReport().Save(path)
 }
 
 func completedStyle(text string) string {
 	return color.Style{color.Bold, color.Green}.Render(text)
 }
 
+func degradedStyle(text string) string {
+	return color.Style{color.Bold, color.Yellow}.Render(text)
+}
+
 func canceledStyle(text string) string {
 	return color.Style{color.Bold, color.Yellow}.Render(text)
 }
@@ -125,13 +182,21 @@ func failedStyle(text string) string {
 	return color.Style{color.Bold, color.Red}.Render(text)
 }
 
-type reportV2 struct {
-	Version             int                `json:"version,omitempty"`
-	Release             string             `json:"release,omitempty"`
-	Namespace           string             `json:"namespace,omitempty"`
-	Revision            int                `json:"revision,omitempty"`
-	Status              helmrelease.Status `json:"status,omitempty"`
-	CompletedOperations []string           `json:"operations,omitempty"`
-	CanceledOperations  []string           `json:"operations,omitempty"`
-	FailedOperations    []string           `json:"operations,omitempty"`
+type manifestHasher interface {
+	ManifestHash() string
+}
+
+type readinessStabilizer interface {
+	StabilizationDuration() time.Duration
+}
+
+func degradedOperations(ops []operation.Operation) []operation.DegradableOperation {
+	var result []operation.DegradableOperation
+	for _, op := range ops {
+		if degradableOp, ok := op.(operation.DegradableOperation); ok && degradableOp.DegradationError() != nil {
+			result = append(result, degradableOp)
+		}
+	}
+
+	return result
 }
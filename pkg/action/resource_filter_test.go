@@ -0,0 +1,86 @@
+package action_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/nelm/internal/kube/fake"
+	"github.com/werf/nelm/internal/release"
+	"github.com/werf/nelm/internal/resource"
+	"github.com/werf/nelm/internal/resource/matcher"
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("resources of the previous release with a resource filter", func() {
+	var prevRelease *release.Release
+
+	BeforeEach(func() {
+		cluster := fake.NewCluster(context.Background())
+
+		unstruct := func(manifest string) *unstructured.Unstructured {
+			obj := &unstructured.Unstructured{}
+			Expect(yaml.Unmarshal([]byte(manifest), &obj.Object)).To(Succeed())
+
+			return obj
+		}
+
+		hooks := []*resource.HookResource{
+			resource.NewHookResource(unstruct(`{apiVersion: batch/v1, kind: Job, metadata: {name: migrate, annotations: {helm.sh/hook: pre-install}}}`), resource.HookResourceOptions{DefaultNamespace: "app-ns", Mapper: cluster.Mapper}),
+		}
+
+		var generals []*resource.GeneralResource
+		for _, manifest := range []string{
+			`{apiVersion: v1, kind: ConfigMap, metadata: {name: config}}`,
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: api, labels: {tier: backend}}}`,
+			`{apiVersion: apps/v1, kind: Deployment, metadata: {name: worker, labels: {tier: backend}}}`,
+		} {
+			generals = append(generals, resource.NewGeneralResource(unstruct(manifest), resource.GeneralResourceOptions{DefaultNamespace: "app-ns", Mapper: cluster.Mapper}))
+		}
+
+		var err error
+		prevRelease, err = release.NewRelease("app", "app-ns", 1, nil, &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.1.0"}}, hooks, generals, "", release.ReleaseOptions{Mapper: cluster.Mapper})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	humanIDs := func(resources any) []string {
+		switch resources := resources.(type) {
+		case []*resource.HookResource:
+			return lo.Map(resources, func(res *resource.HookResource, _ int) string { return res.HumanID() })
+		case []*resource.GeneralResource:
+			return lo.Map(resources, func(res *resource.GeneralResource, _ int) string { return res.HumanID() })
+		default:
+			panic("unexpected resources type")
+		}
+	}
+
+	DescribeTable("are deployed if they pass the filter and carried forward as they are otherwise",
+		func(include, exclude, expectedDeployed, expectedExcludedHooks, expectedExcludedGenerals []string) {
+			filter, err := matcher.NewResourceFilter(include, exclude)
+			Expect(err).NotTo(HaveOccurred())
+
+			generals, excludedHooks, excludedGenerals := action.SplitPrevReleaseResources(prevRelease, filter)
+
+			Expect(humanIDs(generals)).To(ConsistOf(lo.ToAnySlice(expectedDeployed)...))
+			Expect(humanIDs(excludedHooks)).To(ConsistOf(lo.ToAnySlice(expectedExcludedHooks)...))
+			Expect(humanIDs(excludedGenerals)).To(ConsistOf(lo.ToAnySlice(expectedExcludedGenerals)...))
+		},
+		Entry("without filters",
+			nil, nil,
+			[]string{"ConfigMap/config", "Deployment/api", "Deployment/worker"}, nil, nil),
+		Entry("with an include filter",
+			[]string{"ConfigMap/*"}, nil,
+			[]string{"ConfigMap/config"}, []string{"Job/migrate"}, []string{"Deployment/api", "Deployment/worker"}),
+		Entry("with an exclude filter",
+			nil, []string{"Deployment/worker"},
+			[]string{"ConfigMap/config", "Deployment/api"}, nil, []string{"Deployment/worker"}),
+		Entry("with a label selector",
+			[]string{"tier=backend"}, nil,
+			[]string{"Deployment/api", "Deployment/worker"}, []string{"Job/migrate"}, []string{"ConfigMap/config"}),
+	)
+})
@@ -0,0 +1,70 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/werf/nelm/pkg/secret"
+)
+
+const (
+	DefaultSecretDirDecryptLogLevel = InfoLogLevel
+)
+
+type SecretDirDecryptOptions struct {
+	DryRun                bool
+	LogColorMode          string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	TempDirPath           string
+}
+
+func SecretDirDecrypt(ctx context.Context, dirPath string, opts SecretDirDecryptOptions) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	opts, err = applySecretDirDecryptOptionsDefaults(opts, currentDir)
+	if err != nil {
+		return &UsageError{Err: fmt.Errorf("build secret dir decrypt options: %w", err)}
+	}
+
+	result, err := secret.SecretDirDecrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, dirPath, secret.SecretDirOptions{
+		BackendOptions: secret.BackendOptions{
+			AgeIdentityPath: opts.SecretAgeIdentityPath,
+			Backend:         opts.SecretBackend,
+			SecretKey:       []byte(opts.SecretKey),
+		},
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("secret dir decrypt: %w", err)
+	}
+
+	logSecretDirResult(ctx, result, "decrypted", opts.DryRun)
+
+	return nil
+}
+
+func applySecretDirDecryptOptionsDefaults(opts SecretDirDecryptOptions, currentDir string) (SecretDirDecryptOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return SecretDirDecryptOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.SecretWorkDir == "" {
+		opts.SecretWorkDir = currentDir
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	return opts, nil
+}
@@ -0,0 +1,100 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/werf/nelm/pkg/log"
+	"github.com/werf/nelm/pkg/secret"
+)
+
+const (
+	DefaultSecretDirEncryptLogLevel = InfoLogLevel
+)
+
+type SecretDirEncryptOptions struct {
+	DryRun                bool
+	LogColorMode          string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	TempDirPath           string
+}
+
+func SecretDirEncrypt(ctx context.Context, dirPath string, opts SecretDirEncryptOptions) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	opts, err = applySecretDirEncryptOptionsDefaults(opts, currentDir)
+	if err != nil {
+		return &UsageError{Err: fmt.Errorf("build secret dir encrypt options: %w", err)}
+	}
+
+	result, err := secret.SecretDirEncrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, dirPath, secret.SecretDirOptions{
+		BackendOptions: secret.BackendOptions{
+			AgeIdentityPath: opts.SecretAgeIdentityPath,
+			Backend:         opts.SecretBackend,
+			SecretKey:       []byte(opts.SecretKey),
+		},
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("secret dir encrypt: %w", err)
+	}
+
+	logSecretDirResult(ctx, result, "encrypted", opts.DryRun)
+
+	return nil
+}
+
+func applySecretDirEncryptOptionsDefaults(opts SecretDirEncryptOptions, currentDir string) (SecretDirEncryptOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return SecretDirEncryptOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.SecretWorkDir == "" {
+		opts.SecretWorkDir = currentDir
+	}
+
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
+
+	return opts, nil
+}
+
+func logSecretDirResult(ctx context.Context, result *secret.SecretDirResult, verb string, dryRun bool) {
+	for _, filePath := range result.SkippedSymlinks {
+		log.Default.Warn(ctx, "Warning: skipped symlink %q, symlinks are not followed", filePath)
+	}
+
+	for _, filePath := range result.SkippedFiles {
+		log.Default.Info(ctx, "Skipped file %q, already %s", filePath, verb)
+	}
+
+	if len(result.ChangedFiles) == 0 {
+		log.Default.Info(ctx, "No files %s", verb)
+		return
+	}
+
+	header := fmt.Sprintf("%s %d file(s)", strings.ToUpper(verb[:1])+verb[1:], len(result.ChangedFiles))
+	if dryRun {
+		header = fmt.Sprintf("Would have %s %d file(s)", verb, len(result.ChangedFiles))
+	}
+
+	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Green}.Render(header)).Do(func() {
+		for _, filePath := range result.ChangedFiles {
+			log.Default.Info(ctx, "- %s", filePath)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package action_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("secret dir", func() {
+	const (
+		secretKey      = "0123456789abcdef0123456789abcdef"
+		otherSecretKey = "fedcba9876543210fedcba9876543210"
+	)
+
+	var (
+		ctx    context.Context
+		tmpDir string
+		dir    string
+		files  map[string]string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		tmpDir = GinkgoT().TempDir()
+		dir = filepath.Join(tmpDir, "secret")
+		GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+		files = map[string]string{
+			"plain":     "password\n",
+			"lookalike": "1000" + strings.Repeat("0123456789abcdef", 6) + "\n",
+		}
+		for name, content := range files {
+			writeFile(filepath.Join(dir, name), content)
+		}
+	})
+
+	readFiles := func() map[string]string {
+		result := map[string]string{}
+		for name := range files {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			Expect(err).NotTo(HaveOccurred())
+			result[name] = string(data)
+		}
+
+		return result
+	}
+
+	encrypt := func(key string) error {
+		return action.SecretDirEncrypt(ctx, dir, action.SecretDirEncryptOptions{LogColorMode: action.LogColorModeOff, SecretKey: key, SecretWorkDir: tmpDir})
+	}
+
+	decrypt := func(key string) error {
+		return action.SecretDirDecrypt(ctx, dir, action.SecretDirDecryptOptions{LogColorMode: action.LogColorModeOff, SecretKey: key, SecretWorkDir: tmpDir})
+	}
+
+	It("encrypts plaintext looking like a werf secret and skips actual secrets", func() {
+		Expect(encrypt(secretKey)).To(Succeed())
+
+		encrypted := readFiles()
+		for name, content := range files {
+			Expect(encrypted[name]).NotTo(Equal(content), name)
+		}
+
+		Expect(encrypt(secretKey)).To(Succeed())
+		Expect(readFiles()).To(Equal(encrypted))
+
+		Expect(decrypt(secretKey)).To(Succeed())
+		Expect(readFiles()).To(Equal(files))
+	})
+
+	It("fails to decrypt secrets with another key", func() {
+		Expect(encrypt(secretKey)).To(Succeed())
+		encrypted := readFiles()
+
+		Expect(decrypt(otherSecretKey)).To(MatchError(ContainSubstring("doesn't decrypt with the key")))
+		Expect(readFiles()).To(Equal(encrypted))
+	})
+})
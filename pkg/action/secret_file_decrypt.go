@@ -3,6 +3,7 @@ package action
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/werf/common-go/pkg/secrets_manager"
@@ -14,17 +15,18 @@ const (
 )
 
 type SecretFileDecryptOptions struct {
-	LogColorMode   string
-	OutputFilePath string
-	SecretKey      string
-	SecretWorkDir  string
-	TempDirPath    string
+	Input                 io.Reader
+	LogColorMode          string
+	Output                io.Writer
+	OutputFilePath        string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	TempDirPath           string
 }
 
 func SecretFileDecrypt(ctx context.Context, filePath string, opts SecretFileDecryptOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -32,14 +34,18 @@ func SecretFileDecrypt(ctx context.Context, filePath string, opts SecretFileDecr
 
 	opts, err = applySecretFileDecryptOptionsDefaults(opts, currentDir)
 	if err != nil {
-		return fmt.Errorf("build secret file decrypt options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return &UsageError{Err: fmt.Errorf("build secret file decrypt options: %w", err)}
 	}
 
-	if err := secret.SecretFileDecrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, filePath, opts.OutputFilePath); err != nil {
+	if err := secret.SecretFileDecrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, filePath, opts.OutputFilePath, secret.StreamOptions{
+		BackendOptions: secret.BackendOptions{
+			AgeIdentityPath: opts.SecretAgeIdentityPath,
+			Backend:         opts.SecretBackend,
+			SecretKey:       []byte(opts.SecretKey),
+		},
+		Input:  opts.Input,
+		Output: opts.Output,
+	}); err != nil {
 		return fmt.Errorf("secret file decrypt: %w", err)
 	}
 
@@ -63,7 +69,7 @@ func applySecretFileDecryptOptionsDefaults(opts SecretFileDecryptOptions, curren
 		}
 	}
 
-	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, opts.OutputFilePath != "")
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, opts.OutputFilePath != "" && opts.OutputFilePath != secret.StdioFilePath)
 
 	return opts, nil
 }
@@ -14,16 +14,17 @@ const (
 )
 
 type SecretFileEditOptions struct {
-	LogColorMode  string
-	SecretKey     string
-	SecretWorkDir string
-	TempDirPath   string
+	Editor                string
+	LogColorMode          string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	ShowDiff              bool
+	TempDirPath           string
 }
 
 func SecretFileEdit(ctx context.Context, filePath string, opts SecretFileEditOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -31,14 +32,18 @@ func SecretFileEdit(ctx context.Context, filePath string, opts SecretFileEditOpt
 
 	opts, err = applySecretFileEditOptionsDefaults(opts, currentDir)
 	if err != nil {
-		return fmt.Errorf("build secret file edit options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return &UsageError{Err: fmt.Errorf("build secret file edit options: %w", err)}
 	}
 
-	if err := secret.SecretEdit(ctx, secrets_manager.Manager, opts.SecretWorkDir, opts.TempDirPath, filePath, false); err != nil {
+	if err := secret.SecretEdit(ctx, secrets_manager.Manager, opts.SecretWorkDir, opts.TempDirPath, filePath, false, secret.SecretEditOptions{
+		BackendOptions: secret.BackendOptions{
+			AgeIdentityPath: opts.SecretAgeIdentityPath,
+			Backend:         opts.SecretBackend,
+			SecretKey:       []byte(opts.SecretKey),
+		},
+		Editor:   opts.Editor,
+		ShowDiff: opts.ShowDiff,
+	}); err != nil {
 		return fmt.Errorf("secret edit: %w", err)
 	}
 
@@ -2,39 +2,70 @@ package action
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
 
-	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm/internal/util"
 )
 
 const (
 	DefaultSecretKeyCreateLogLevel = ErrorLogLevel
+	DefaultSecretKeyCreateBits     = 128
 )
 
+// Key sizes of AES-128, AES-192 and AES-256, all of them supported by the werf secret encoder.
+var SecretKeyBits = []int{128, 192, 256}
+
 type SecretKeyCreateOptions struct {
-	LogColorMode  string
-	OutputNoPrint bool
-	TempDirPath   string
+	// Key strength in bits, one of SecretKeyBits. Defaults to DefaultSecretKeyCreateBits.
+	Bits int
+	// Overwrite OutputFilePath if it already exists.
+	Force        bool
+	LogColorMode string
+	// Save the key to this file instead of printing it.
+	OutputFilePath string
+	OutputNoPrint  bool
+	TempDirPath    string
 }
 
 func SecretKeyCreate(ctx context.Context, opts SecretKeyCreateOptions) (string, error) {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	opts, err := applySecretKeyCreateOptionsDefaults(opts)
 	if err != nil {
 		return "", fmt.Errorf("build secret key create options: %w", err)
 	}
 
-	var result string
-	if !opts.OutputNoPrint {
-		if keyByte, err := secrets_manager.GenerateSecretKey(); err != nil {
-			return "", fmt.Errorf("generate secret key: %w", err)
-		} else {
-			result = string(keyByte)
+	if opts.OutputFilePath != "" && !opts.Force {
+		if _, err := os.Stat(opts.OutputFilePath); err == nil {
+			return "", fmt.Errorf("secret key file %q already exists, use --force to overwrite it", opts.OutputFilePath)
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("stat %q: %w", opts.OutputFilePath, err)
+		}
+	}
+
+	result, err := generateSecretKey(opts.Bits)
+	if err != nil {
+		return "", fmt.Errorf("generate secret key: %w", err)
+	}
+
+	if opts.OutputFilePath != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.OutputFilePath), 0o755); err != nil {
+			return "", fmt.Errorf("create directory for secret key file %q: %w", opts.OutputFilePath, err)
 		}
 
+		if err := util.WriteFileAtomic(opts.OutputFilePath, []byte(result+"\n"), 0o600); err != nil {
+			return "", fmt.Errorf("write secret key file %q: %w", opts.OutputFilePath, err)
+		}
+
+		// WriteFileAtomic preserves permissions of the overwritten file.
+		if err := os.Chmod(opts.OutputFilePath, 0o600); err != nil {
+			return "", fmt.Errorf("chmod secret key file %q: %w", opts.OutputFilePath, err)
+		}
+	} else if !opts.OutputNoPrint {
 		fmt.Println(result)
 	}
 
@@ -50,7 +81,23 @@ func applySecretKeyCreateOptionsDefaults(opts SecretKeyCreateOptions) (SecretKey
 		}
 	}
 
+	if opts.Bits == 0 {
+		opts.Bits = DefaultSecretKeyCreateBits
+	} else if !lo.Contains(SecretKeyBits, opts.Bits) {
+		return SecretKeyCreateOptions{}, fmt.Errorf("invalid secret key bits %d, expected one of %v", opts.Bits, SecretKeyBits)
+	}
+
 	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, false)
 
 	return opts, nil
 }
+
+// Generates the key the same way as secrets_manager.GenerateSecretKey, but of the requested size.
+func generateSecretKey(bits int) (string, error) {
+	key := make([]byte, bits/8)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/gookit/color"
+
+	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/nelm/pkg/secret"
 )
 
@@ -19,13 +22,11 @@ type SecretKeyRotateOptions struct {
 	OldSecretKey      string
 	SecretValuesPaths []string
 	SecretWorkDir     string
+	SkipUndecryptable bool
 	TempDirPath       string
 }
 
 func SecretKeyRotate(ctx context.Context, opts SecretKeyRotateOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -33,21 +34,34 @@ func SecretKeyRotate(ctx context.Context, opts SecretKeyRotateOptions) error {
 
 	opts, err = applySecretKeyRotateOptionsDefaults(opts, currentDir)
 	if err != nil {
-		return fmt.Errorf("build secret key rotate options: %w", err)
+		return &UsageError{Err: fmt.Errorf("build secret key rotate options: %w", err)}
 	}
 
-	if opts.OldSecretKey != "" {
-		os.Setenv("WERF_OLD_SECRET_KEY", opts.OldSecretKey)
+	result, err := secret.RotateSecretKey(ctx, opts.ChartDirPath, opts.SecretWorkDir, secret.RotateSecretKeyOptions{
+		NewSecretKey:      []byte(opts.NewSecretKey),
+		OldSecretKey:      []byte(opts.OldSecretKey),
+		SecretValuesPaths: opts.SecretValuesPaths,
+		SkipUndecryptable: opts.SkipUndecryptable,
+	})
+	if err != nil {
+		return fmt.Errorf("rotate secret key: %w", err)
 	}
 
-	if opts.NewSecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.NewSecretKey)
+	for _, filePath := range result.UndecryptableFiles {
+		log.Default.Warn(ctx, "Warning: skipped file %q, unable to decrypt it with the old secret key", filePath)
 	}
 
-	if err := secret.RotateSecretKey(ctx, opts.ChartDirPath, opts.SecretWorkDir, opts.SecretValuesPaths...); err != nil {
-		return fmt.Errorf("rotate secret key: %w", err)
+	if len(result.RewrittenFiles) == 0 {
+		log.Default.Info(ctx, "No secret files reencrypted")
+		return nil
 	}
 
+	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Reencrypted %d file(s)", len(result.RewrittenFiles)))).Do(func() {
+		for _, filePath := range result.RewrittenFiles {
+			log.Default.Info(ctx, "- %s", filePath)
+		}
+	})
+
 	return nil
 }
 
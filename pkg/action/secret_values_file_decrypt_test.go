@@ -0,0 +1,110 @@
+package action_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/nelm/pkg/action"
+)
+
+var _ = Describe("secret values file decrypt", func() {
+	It("decrypts files with different keys concurrently", func() {
+		ctx := context.Background()
+		tmpDir := GinkgoT().TempDir()
+		GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+		const filesCount = 4
+
+		keys := make([]string, filesCount)
+		paths := make([]string, filesCount)
+		for i := range filesCount {
+			var err error
+			keys[i], err = action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			plainPath := filepath.Join(tmpDir, fmt.Sprintf("values-%d.yaml", i))
+			writeFile(plainPath, fmt.Sprintf("password: secret-%d\n", i))
+
+			paths[i] = filepath.Join(tmpDir, fmt.Sprintf("secret-values-%d.yaml", i))
+			Expect(action.SecretValuesFileEncrypt(ctx, plainPath, action.SecretValuesFileEncryptOptions{
+				LogColorMode:   action.LogColorModeOff,
+				OutputFilePath: paths[i],
+				SecretKey:      keys[i],
+				SecretWorkDir:  tmpDir,
+			})).To(Succeed())
+		}
+
+		var wg sync.WaitGroup
+		outputs := make([]*bytes.Buffer, filesCount)
+		errs := make([]error, filesCount)
+		for i := range filesCount {
+			outputs[i] = &bytes.Buffer{}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				errs[i] = action.SecretValuesFileDecrypt(ctx, paths[i], action.SecretValuesFileDecryptOptions{
+					LogColorMode:  action.LogColorModeOff,
+					Output:        outputs[i],
+					SecretKey:     keys[i],
+					SecretWorkDir: tmpDir,
+				})
+			}()
+		}
+		wg.Wait()
+
+		for i := range filesCount {
+			Expect(errs[i]).NotTo(HaveOccurred())
+
+			values := map[string]interface{}{}
+			Expect(yaml.Unmarshal(outputs[i].Bytes(), &values)).To(Succeed())
+			Expect(values).To(Equal(map[string]interface{}{"password": fmt.Sprintf("secret-%d", i)}))
+		}
+	})
+
+	It("decrypts only the encrypted values of partially encrypted files", func() {
+		ctx := context.Background()
+		tmpDir := GinkgoT().TempDir()
+		GinkgoT().Setenv("WERF_SECRET_KEY", "")
+
+		key, err := action.SecretKeyCreate(ctx, action.SecretKeyCreateOptions{OutputNoPrint: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		plainPath := filepath.Join(tmpDir, "values.yaml")
+		writeFile(plainPath, "db:\n  user: app\n  password: secret\n")
+
+		encryptedPath := filepath.Join(tmpDir, "secret-values.yaml")
+		Expect(action.SecretValuesFileEncrypt(ctx, plainPath, action.SecretValuesFileEncryptOptions{
+			Keys:           []string{".db.password"},
+			LogColorMode:   action.LogColorModeOff,
+			OutputFilePath: encryptedPath,
+			SecretKey:      key,
+			SecretWorkDir:  tmpDir,
+		})).To(Succeed())
+
+		for _, keys := range [][]string{nil, {".db"}} {
+			output := &bytes.Buffer{}
+			Expect(action.SecretValuesFileDecrypt(ctx, encryptedPath, action.SecretValuesFileDecryptOptions{
+				Keys:          keys,
+				LogColorMode:  action.LogColorModeOff,
+				Output:        output,
+				SecretKey:     key,
+				SecretWorkDir: tmpDir,
+			})).To(Succeed())
+
+			values := map[string]interface{}{}
+			Expect(yaml.Unmarshal(output.Bytes(), &values)).To(Succeed())
+			Expect(values).To(Equal(map[string]interface{}{
+				"db": map[string]interface{}{"user": "app", "password": "secret"},
+			}), "keys %v", keys)
+		}
+	})
+})
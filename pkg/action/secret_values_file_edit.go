@@ -14,16 +14,18 @@ const (
 )
 
 type SecretValuesFileEditOptions struct {
-	LogColorMode  string
-	SecretKey     string
-	SecretWorkDir string
-	TempDirPath   string
+	Editor                string
+	Keys                  []string
+	LogColorMode          string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	ShowDiff              bool
+	TempDirPath           string
 }
 
 func SecretValuesFileEdit(ctx context.Context, valuesFilePath string, opts SecretValuesFileEditOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -31,14 +33,19 @@ func SecretValuesFileEdit(ctx context.Context, valuesFilePath string, opts Secre
 
 	opts, err = applySecretValuesFileEditOptionsDefaults(opts, currentDir)
 	if err != nil {
-		return fmt.Errorf("build secret values file edit options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return &UsageError{Err: fmt.Errorf("build secret values file edit options: %w", err)}
 	}
 
-	if err := secret.SecretEdit(ctx, secrets_manager.Manager, opts.SecretWorkDir, opts.TempDirPath, valuesFilePath, true); err != nil {
+	if err := secret.SecretEdit(ctx, secrets_manager.Manager, opts.SecretWorkDir, opts.TempDirPath, valuesFilePath, true, secret.SecretEditOptions{
+		BackendOptions: secret.BackendOptions{
+			AgeIdentityPath: opts.SecretAgeIdentityPath,
+			Backend:         opts.SecretBackend,
+			SecretKey:       []byte(opts.SecretKey),
+		},
+		Editor:   opts.Editor,
+		Keys:     opts.Keys,
+		ShowDiff: opts.ShowDiff,
+	}); err != nil {
 		return fmt.Errorf("secret edit: %w", err)
 	}
 
@@ -3,6 +3,7 @@ package action
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/werf/common-go/pkg/secrets_manager"
@@ -14,17 +15,19 @@ const (
 )
 
 type SecretValuesFileEncryptOptions struct {
-	LogColorMode   string
-	OutputFilePath string
-	SecretKey      string
-	SecretWorkDir  string
-	TempDirPath    string
+	Input                 io.Reader
+	Keys                  []string
+	LogColorMode          string
+	Output                io.Writer
+	OutputFilePath        string
+	SecretAgeIdentityPath string
+	SecretBackend         string
+	SecretKey             string
+	SecretWorkDir         string
+	TempDirPath           string
 }
 
 func SecretValuesFileEncrypt(ctx context.Context, valuesFilePath string, opts SecretValuesFileEncryptOptions) error {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get current working directory: %w", err)
@@ -32,14 +35,21 @@ func SecretValuesFileEncrypt(ctx context.Context, valuesFilePath string, opts Se
 
 	opts, err = applySecretValuesFileEncryptOptionsDefaults(opts, currentDir)
 	if err != nil {
-		return fmt.Errorf("build secret values file encrypt options: %w", err)
-	}
-
-	if opts.SecretKey != "" {
-		os.Setenv("WERF_SECRET_KEY", opts.SecretKey)
+		return &UsageError{Err: fmt.Errorf("build secret values file encrypt options: %w", err)}
 	}
 
-	if err := secret.SecretValuesEncrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, valuesFilePath, opts.OutputFilePath); err != nil {
+	if err := secret.SecretValuesEncrypt(ctx, secrets_manager.Manager, opts.SecretWorkDir, valuesFilePath, opts.OutputFilePath, secret.SecretValuesOptions{
+		StreamOptions: secret.StreamOptions{
+			BackendOptions: secret.BackendOptions{
+				AgeIdentityPath: opts.SecretAgeIdentityPath,
+				Backend:         opts.SecretBackend,
+				SecretKey:       []byte(opts.SecretKey),
+			},
+			Input:  opts.Input,
+			Output: opts.Output,
+		},
+		Keys: opts.Keys,
+	}); err != nil {
 		return fmt.Errorf("secret values encrypt: %w", err)
 	}
 
@@ -63,7 +73,7 @@ func applySecretValuesFileEncryptOptionsDefaults(opts SecretValuesFileEncryptOpt
 		}
 	}
 
-	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, opts.OutputFilePath != "")
+	opts.LogColorMode = applyLogColorModeDefault(opts.LogColorMode, opts.OutputFilePath != "" && opts.OutputFilePath != secret.StdioFilePath)
 
 	return opts, nil
 }
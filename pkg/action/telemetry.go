@@ -0,0 +1,125 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/werf/nelm/internal/telemetry"
+	"github.com/werf/nelm/pkg/log"
+)
+
+const (
+	DefaultTelemetryLogLevel = InfoLogLevel
+)
+
+type TelemetryStatusOptions struct {
+	ConfigDir string
+}
+
+func TelemetryStatus(ctx context.Context, opts TelemetryStatusOptions) error {
+	configDir, err := telemetryConfigDir(opts.ConfigDir)
+	if err != nil {
+		return err
+	}
+
+	consent, err := telemetry.LoadConsent(configDir)
+	if err != nil {
+		return fmt.Errorf("load telemetry consent: %w", err)
+	}
+
+	if consent.Enabled {
+		log.Default.Info(ctx, "Telemetry: enabled")
+	} else {
+		log.Default.Info(ctx, "Telemetry: disabled")
+	}
+
+	if consent.Endpoint != "" {
+		log.Default.Info(ctx, "Endpoint: %s", consent.Endpoint)
+	} else {
+		log.Default.Info(ctx, "Endpoint: not configured, events are only kept locally")
+	}
+
+	log.Default.Info(ctx, "Config dir: %s", configDir)
+	log.Default.Info(ctx, "Events waiting to be sent: %d", telemetry.SpooledEventsCount(configDir))
+
+	return nil
+}
+
+type TelemetryEnableOptions struct {
+	ConfigDir string
+	Endpoint  string
+}
+
+func TelemetryEnable(ctx context.Context, opts TelemetryEnableOptions) error {
+	configDir, err := telemetryConfigDir(opts.ConfigDir)
+	if err != nil {
+		return err
+	}
+
+	consent, err := telemetry.LoadConsent(configDir)
+	if err != nil {
+		return fmt.Errorf("load telemetry consent: %w", err)
+	}
+
+	if opts.Endpoint != "" {
+		if u, err := url.Parse(opts.Endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid telemetry endpoint %q, expected http(s) URL", opts.Endpoint)
+		}
+
+		consent.Endpoint = opts.Endpoint
+	}
+
+	consent.Enabled = true
+
+	if err := telemetry.SaveConsent(configDir, consent); err != nil {
+		return fmt.Errorf("save telemetry consent: %w", err)
+	}
+
+	log.Default.Info(ctx, "Telemetry enabled. Thank you!")
+
+	return nil
+}
+
+type TelemetryDisableOptions struct {
+	ConfigDir string
+}
+
+func TelemetryDisable(ctx context.Context, opts TelemetryDisableOptions) error {
+	configDir, err := telemetryConfigDir(opts.ConfigDir)
+	if err != nil {
+		return err
+	}
+
+	consent, err := telemetry.LoadConsent(configDir)
+	if err != nil {
+		return fmt.Errorf("load telemetry consent: %w", err)
+	}
+
+	consent.Enabled = false
+
+	if err := telemetry.SaveConsent(configDir, consent); err != nil {
+		return fmt.Errorf("save telemetry consent: %w", err)
+	}
+
+	if err := telemetry.DeleteSpool(configDir); err != nil {
+		return fmt.Errorf("delete unsent telemetry events: %w", err)
+	}
+
+	log.Default.Info(ctx, "Telemetry disabled, unsent events deleted")
+
+	return nil
+}
+
+func telemetryConfigDir(configDir string) (string, error) {
+	if configDir != "" {
+		return configDir, nil
+	}
+
+	configDir, err := telemetry.DefaultConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("get config dir: %w", err)
+	}
+
+	return configDir, nil
+}
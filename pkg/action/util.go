@@ -15,10 +15,67 @@ import (
 	klogv2 "k8s.io/klog/v2"
 
 	"github.com/werf/logboek"
-	"github.com/werf/nelm/internal/log"
+	"github.com/werf/nelm/pkg/log"
 )
 
+// SetupLogFormat replaces the global logger used by actions with the logger of the specified format.
+// JSON logs are written to stderr, use log.NewJSONLogger to write them elsewhere.
+func SetupLogFormat(format string) error {
+	switch format {
+	case "", LogFormatText:
+		log.SetGlobal(log.DefaultLogboek)
+	case LogFormatJSON:
+		log.SetGlobal(log.NewJSONLogger(os.Stderr))
+	default:
+		return fmt.Errorf("unknown log format %q, expected one of %q", format, LogFormats)
+	}
+
+	return nil
+}
+
+// SetupLogFile makes the logger used by actions additionally write all logs down to the debug level
+// to the file, as plain text without colors. The returned function closes the file.
+func SetupLogFile(path string, appendToFile bool) (closeFunc func() error, err error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendToFile {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+
+	fileLogger := log.NewPlainLogger(file)
+	fileLogger.SetLevel(context.Background(), log.DebugLevel)
+
+	primary := log.Global()
+	log.SetGlobal(log.NewTeeLogger(primary, fileLogger))
+
+	return func() error {
+		log.SetGlobal(primary)
+		return file.Close()
+	}, nil
+}
+
+// ValidateLogLevel checks the log level accepted by SetupLogging.
+func ValidateLogLevel(logLevel string) error {
+	if _, _, err := log.ParseLevels(logLevel); err != nil {
+		return fmt.Errorf("validate log level: %w", err)
+	}
+
+	return nil
+}
+
+// The log level can be followed by the levels of components overriding it, e.g.
+// "info,kube=trace,plan=debug". Third-party loggers get the log level only.
 func SetupLogging(ctx context.Context, logLevel, defaultLogLevel string) context.Context {
+	lvl, componentLevels, err := log.ParseLevels(logLevel)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	logLevel = string(lvl)
 	if logLevel == "" {
 		logLevel = defaultLogLevel
 	}
@@ -28,6 +85,7 @@ func SetupLogging(ctx context.Context, logLevel, defaultLogLevel string) context
 	}
 
 	log.Default.SetLevel(ctx, log.Level(logLevel))
+	log.Default.SetComponentLevels(ctx, componentLevels)
 
 	klog.SetOutputBySeverity("FATAL", logboek.Context(ctx).ErrStream())
 	klog.SetOutputBySeverity("ERROR", logboek.Context(ctx).ErrStream())
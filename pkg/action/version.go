@@ -11,8 +11,6 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/gookit/color"
 
-	"github.com/werf/3p-helm/pkg/chart/loader"
-	"github.com/werf/3p-helm/pkg/werf/secrets"
 	"github.com/werf/nelm/internal/common"
 )
 
@@ -29,17 +27,11 @@ type VersionOptions struct {
 }
 
 func Version(ctx context.Context, opts VersionOptions) (*VersionResult, error) {
-	actionLock.Lock()
-	defer actionLock.Unlock()
-
 	opts, err := applyVersionOptionsDefaults(opts)
 	if err != nil {
-		return nil, fmt.Errorf("build version options: %w", err)
+		return nil, &UsageError{Err: fmt.Errorf("build version options: %w", err)}
 	}
 
-	secrets.DisableSecrets = true
-	loader.NoChartLockWarning = ""
-
 	result := &VersionResult{
 		FullVersion: common.Version,
 	}
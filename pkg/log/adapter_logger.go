@@ -0,0 +1,250 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/logboek/pkg/types"
+)
+
+// adapterLogger implements Logger on top of a third-party logger, which receives messages with
+// colors stripped and decides itself whether to drop them. Blocks and processes only run the
+// wrapped functions.
+type adapterLogger struct {
+	logFunc     func(ctx context.Context, lvl Level, msg string, data interface{})
+	enabledFunc func(ctx context.Context, lvl Level) bool
+
+	traceStash *util.Concurrent[map[string][]string]
+	debugStash *util.Concurrent[map[string][]string]
+	infoStash  *util.Concurrent[map[string][]string]
+	warnStash  *util.Concurrent[map[string][]string]
+	errorStash *util.Concurrent[map[string][]string]
+
+	levels *levels
+}
+
+func newAdapterLogger(
+	logFunc func(ctx context.Context, lvl Level, msg string, data interface{}),
+	enabledFunc func(ctx context.Context, lvl Level) bool,
+) *adapterLogger {
+	return &adapterLogger{
+		logFunc:     logFunc,
+		enabledFunc: enabledFunc,
+
+		traceStash: util.NewConcurrent(make(map[string][]string)),
+		debugStash: util.NewConcurrent(make(map[string][]string)),
+		infoStash:  util.NewConcurrent(make(map[string][]string)),
+		warnStash:  util.NewConcurrent(make(map[string][]string)),
+		errorStash: util.NewConcurrent(make(map[string][]string)),
+
+		levels: newLevels(TraceLevel),
+	}
+}
+
+func (l *adapterLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, TraceLevel, nil, format, a...)
+}
+
+func (l *adapterLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.log(ctx, TraceLevel, obj, format, a...)
+}
+
+func (l *adapterLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
+	l.traceStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *adapterLogger) TracePop(ctx context.Context, group string) {
+	l.traceStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Trace(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *adapterLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, DebugLevel, nil, format, a...)
+}
+
+func (l *adapterLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.debugStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *adapterLogger) DebugPop(ctx context.Context, group string) {
+	l.debugStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Debug(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *adapterLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, InfoLevel, nil, format, a...)
+}
+
+func (l *adapterLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.infoStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *adapterLogger) InfoPop(ctx context.Context, group string) {
+	l.infoStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Info(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *adapterLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, WarningLevel, nil, format, a...)
+}
+
+func (l *adapterLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.warnStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *adapterLogger) WarnPop(ctx context.Context, group string) {
+	l.warnStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Warn(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *adapterLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, ErrorLevel, nil, format, a...)
+}
+
+func (l *adapterLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.errorStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *adapterLogger) ErrorPop(ctx context.Context, group string) {
+	l.errorStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Error(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *adapterLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	l.Info(ctx, format, a...)
+	return &noopLogBlock{}
+}
+
+func (l *adapterLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	l.Info(ctx, format, a...)
+	return &noopLogProcess{}
+}
+
+func (l *adapterLogger) SetLevel(ctx context.Context, lvl Level) {
+	if !lo.Contains(Levels, lvl) {
+		panic(fmt.Sprintf("unsupported log level %q", lvl))
+	}
+
+	l.levels.Set(lvl)
+}
+
+func (l *adapterLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.levels.SetComponentLevels(componentLevels)
+}
+
+func (l *adapterLogger) Level(context.Context) Level {
+	return l.levels.Get()
+}
+
+// AcceptLevel also asks the underlying logger whether the level is enabled.
+func (l *adapterLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	if lvl == SilentLevel {
+		return false
+	}
+
+	return l.levels.Accept(ctx, lvl) && l.enabledFunc(ctx, lvl)
+}
+
+func (l *adapterLogger) log(ctx context.Context, lvl Level, data interface{}, format string, a ...interface{}) {
+	if !l.AcceptLevel(ctx, lvl) {
+		return
+	}
+
+	l.logFunc(ctx, lvl, color.ClearCode(fmt.Sprintf(format, a...)), Redact(data))
+}
+
+var (
+	_ types.LogBlockInterface   = (*noopLogBlock)(nil)
+	_ types.LogProcessInterface = (*noopLogProcess)(nil)
+)
+
+type noopLogBlock struct{}
+
+func (b *noopLogBlock) Options(func(options types.LogBlockOptionsInterface)) types.LogBlockInterface {
+	return b
+}
+
+func (b *noopLogBlock) Disable() types.LogBlockInterface {
+	return b
+}
+
+func (b *noopLogBlock) Enable() types.LogBlockInterface {
+	return b
+}
+
+func (b *noopLogBlock) Do(f func()) {
+	f()
+}
+
+func (b *noopLogBlock) DoError(f func() error) error {
+	return f()
+}
+
+type noopLogProcess struct{}
+
+func (p *noopLogProcess) Options(func(options types.LogProcessOptionsInterface)) types.LogProcessInterface {
+	return p
+}
+
+func (p *noopLogProcess) Disable() types.LogProcessInterface {
+	return p
+}
+
+func (p *noopLogProcess) Enable() types.LogProcessInterface {
+	return p
+}
+
+func (p *noopLogProcess) Do(f func()) {
+	f()
+}
+
+func (p *noopLogProcess) DoError(f func() error) error {
+	return f()
+}
+
+func (p *noopLogProcess) Start() {}
+
+func (p *noopLogProcess) StepEnd(format string, a ...interface{}) {}
+
+func (p *noopLogProcess) End() {}
+
+func (p *noopLogProcess) Fail() {}
@@ -0,0 +1,125 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+var _ = Describe("adapter loggers", func() {
+	var (
+		ctx     context.Context
+		slogBuf *bytes.Buffer
+		logrBuf *bytes.Buffer
+		loggers map[string]log.Logger
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		slogBuf = &bytes.Buffer{}
+		logrBuf = &bytes.Buffer{}
+
+		loggers = map[string]log.Logger{
+			"slog": log.NewSlogLogger(slog.New(slog.NewTextHandler(slogBuf, &slog.HandlerOptions{Level: log.SlogLevelTrace}))),
+			"logr": log.NewLogrLogger(funcr.New(func(prefix, args string) {
+				logrBuf.WriteString(args + "\n")
+			}, funcr.Options{Verbosity: 2})),
+		}
+	})
+
+	for _, name := range []string{"slog", "logr"} {
+		Context(name, func() {
+			It("runs the functions wrapped with blocks and processes", func() {
+				logger := loggers[name]
+
+				var calls int
+				logger.InfoBlock(ctx, "block").Do(func() { calls++ })
+				logger.InfoProcess(ctx, "process").Do(func() { calls++ })
+				Expect(calls).To(Equal(2))
+
+				err := errors.New("failed")
+				Expect(logger.InfoBlock(ctx, "block").DoError(func() error { return err })).To(MatchError(err))
+				Expect(logger.InfoProcess(ctx, "process").DoError(func() error { return err })).To(MatchError(err))
+				Expect(logger.InfoBlock(ctx, "block").DoError(func() error { return nil })).To(Succeed())
+			})
+
+			It("respects the level set with SetLevel", func() {
+				logger := loggers[name]
+
+				Expect(logger.AcceptLevel(ctx, log.TraceLevel)).To(BeTrue())
+
+				logger.SetLevel(ctx, log.WarningLevel)
+				Expect(logger.AcceptLevel(ctx, log.InfoLevel)).To(BeFalse())
+				Expect(logger.AcceptLevel(ctx, log.WarningLevel)).To(BeTrue())
+
+				logger.SetLevel(ctx, log.SilentLevel)
+				Expect(logger.AcceptLevel(ctx, log.ErrorLevel)).To(BeFalse())
+			})
+		})
+	}
+
+	It("maps levels to slog levels", func() {
+		logger := loggers["slog"]
+
+		logger.Trace(ctx, "trace message")
+		logger.TraceStruct(ctx, map[string]int{"a": 1}, "trace struct")
+		logger.Debug(ctx, "debug message")
+		logger.Warn(ctx, "warn message")
+		logger.Error(ctx, "error message")
+
+		Expect(slogBuf.String()).To(ContainSubstring(`level=DEBUG-4 msg="trace message"`))
+		Expect(slogBuf.String()).To(ContainSubstring(`msg="trace struct" data=map[a:1]`))
+		Expect(slogBuf.String()).To(ContainSubstring(`level=DEBUG msg="debug message"`))
+		Expect(slogBuf.String()).To(ContainSubstring(`level=WARN msg="warn message"`))
+		Expect(slogBuf.String()).To(ContainSubstring(`level=ERROR msg="error message"`))
+	})
+
+	It("maps levels to logr verbosity", func() {
+		logger := loggers["logr"]
+
+		logger.Trace(ctx, "trace message")
+		logger.Debug(ctx, "debug message")
+		logger.Warn(ctx, "warn message")
+
+		Expect(logrBuf.String()).To(ContainSubstring(`"level"=2 "msg"="trace message"`))
+		Expect(logrBuf.String()).To(ContainSubstring(`"level"=1 "msg"="debug message"`))
+		Expect(logrBuf.String()).To(ContainSubstring(`"level"=0 "msg"="warn message" "severity"="warning"`))
+	})
+
+	It("drops messages disabled in the underlying logger", func() {
+		logger := log.NewSlogLogger(slog.New(slog.NewTextHandler(slogBuf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+		logger.Debug(ctx, "debug message")
+		logger.Info(ctx, "info message")
+
+		Expect(logger.AcceptLevel(ctx, log.DebugLevel)).To(BeFalse())
+		Expect(slogBuf.String()).NotTo(ContainSubstring("debug message"))
+		Expect(slogBuf.String()).To(ContainSubstring("info message"))
+	})
+})
+
+var _ = Describe("Default", func() {
+	It("logs with the logger from the context", func() {
+		var ctxBuf, globalBuf bytes.Buffer
+		ctx := log.NewContext(context.Background(), log.NewSlogLogger(slog.New(slog.NewTextHandler(&ctxBuf, nil))))
+
+		global := log.Global()
+		defer log.SetGlobal(global)
+		log.SetGlobal(log.NewSlogLogger(slog.New(slog.NewTextHandler(&globalBuf, nil))))
+
+		log.Default.Info(ctx, "context message")
+		log.Default.Info(context.Background(), "global message")
+
+		Expect(ctxBuf.String()).To(ContainSubstring("context message"))
+		Expect(ctxBuf.String()).NotTo(ContainSubstring("global message"))
+		Expect(globalBuf.String()).To(ContainSubstring("global message"))
+		Expect(globalBuf.String()).NotTo(ContainSubstring("context message"))
+	})
+})
@@ -0,0 +1,271 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/logboek/pkg/types"
+)
+
+// Component is the subsystem a message is logged by. The level of a logger can be overridden for
+// every component.
+type Component string
+
+const (
+	ChartComponent   Component = "chart"
+	KubeComponent    Component = "kube"
+	PlanComponent    Component = "plan"
+	ReleaseComponent Component = "release"
+	TrackComponent   Component = "track"
+)
+
+var Components = []Component{ChartComponent, KubeComponent, PlanComponent, ReleaseComponent, TrackComponent}
+
+// Loggers of the components, the same as Default, but messages are tagged with the component.
+var (
+	Chart   = NewComponentLogger(Default, ChartComponent)
+	Kube    = NewComponentLogger(Default, KubeComponent)
+	Plan    = NewComponentLogger(Default, PlanComponent)
+	Release = NewComponentLogger(Default, ReleaseComponent)
+	Track   = NewComponentLogger(Default, TrackComponent)
+)
+
+type componentKey struct{}
+
+func WithComponent(ctx context.Context, component Component) context.Context {
+	return context.WithValue(ctx, componentKey{}, component)
+}
+
+func ComponentFromContext(ctx context.Context) (Component, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	component, ok := ctx.Value(componentKey{}).(Component)
+
+	return component, ok
+}
+
+// ParseLevels parses levels like "info,kube=trace,plan=debug": the level, which can be omitted,
+// and the levels of components overriding it.
+func ParseLevels(s string) (lvl Level, componentLevels map[Component]Level, err error) {
+	componentLevels = map[Component]Level{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, isComponentLevel := strings.Cut(part, "=")
+		if !isComponentLevel {
+			if lvl != "" {
+				return "", nil, fmt.Errorf("log level specified more than once in %q", s)
+			}
+
+			if lvl = Level(part); !lo.Contains(Levels, lvl) {
+				return "", nil, fmt.Errorf("unknown log level %q, expected one of %q", part, Levels)
+			}
+
+			continue
+		}
+
+		component := Component(name)
+		if !lo.Contains(Components, component) {
+			return "", nil, fmt.Errorf("unknown log component %q, expected one of %q", name, Components)
+		}
+
+		componentLvl := Level(value)
+		if !lo.Contains(Levels, componentLvl) {
+			return "", nil, fmt.Errorf("unknown log level %q of component %q, expected one of %q", value, name, Levels)
+		}
+
+		componentLevels[component] = componentLvl
+	}
+
+	return lvl, componentLevels, nil
+}
+
+var _ Logger = (*ComponentLogger)(nil)
+
+// NewComponentLogger returns the logger tagging all messages passed to the logger with the
+// component.
+func NewComponentLogger(logger Logger, component Component) *ComponentLogger {
+	return &ComponentLogger{
+		logger:    logger,
+		component: component,
+	}
+}
+
+type ComponentLogger struct {
+	logger    Logger
+	component Component
+}
+
+func (l *ComponentLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.logger.Trace(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.logger.TraceStruct(l.ctx(ctx), obj, format, a...)
+}
+
+func (l *ComponentLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger.TracePush(l.ctx(ctx), group, format, a...)
+}
+
+func (l *ComponentLogger) TracePop(ctx context.Context, group string) {
+	l.logger.TracePop(l.ctx(ctx), group)
+}
+
+func (l *ComponentLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.logger.Debug(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger.DebugPush(l.ctx(ctx), group, format, a...)
+}
+
+func (l *ComponentLogger) DebugPop(ctx context.Context, group string) {
+	l.logger.DebugPop(l.ctx(ctx), group)
+}
+
+func (l *ComponentLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.logger.Info(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger.InfoPush(l.ctx(ctx), group, format, a...)
+}
+
+func (l *ComponentLogger) InfoPop(ctx context.Context, group string) {
+	l.logger.InfoPop(l.ctx(ctx), group)
+}
+
+func (l *ComponentLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.logger.Warn(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger.WarnPush(l.ctx(ctx), group, format, a...)
+}
+
+func (l *ComponentLogger) WarnPop(ctx context.Context, group string) {
+	l.logger.WarnPop(l.ctx(ctx), group)
+}
+
+func (l *ComponentLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.logger.Error(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger.ErrorPush(l.ctx(ctx), group, format, a...)
+}
+
+func (l *ComponentLogger) ErrorPop(ctx context.Context, group string) {
+	l.logger.ErrorPop(l.ctx(ctx), group)
+}
+
+func (l *ComponentLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	return l.logger.InfoBlock(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	return l.logger.InfoProcess(l.ctx(ctx), format, a...)
+}
+
+func (l *ComponentLogger) SetLevel(ctx context.Context, lvl Level) {
+	l.logger.SetLevel(ctx, lvl)
+}
+
+func (l *ComponentLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.logger.SetComponentLevels(ctx, componentLevels)
+}
+
+func (l *ComponentLogger) Level(ctx context.Context) Level {
+	return l.logger.Level(ctx)
+}
+
+func (l *ComponentLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	return l.logger.AcceptLevel(l.ctx(ctx), lvl)
+}
+
+func (l *ComponentLogger) ctx(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return WithComponent(ctx, l.component)
+}
+
+// levels is the level of a logger and the levels of components overriding it.
+type levels struct {
+	level           *util.Concurrent[*Level]
+	componentLevels *util.Concurrent[map[Component]Level]
+}
+
+func newLevels(lvl Level) *levels {
+	return &levels{
+		level:           util.NewConcurrent(lo.ToPtr(lvl)),
+		componentLevels: util.NewConcurrent(map[Component]Level{}),
+	}
+}
+
+func (l *levels) Set(lvl Level) {
+	l.level.RWTransaction(func(lv *Level) {
+		*lv = lvl
+	})
+}
+
+func (l *levels) SetComponentLevels(componentLevels map[Component]Level) {
+	l.componentLevels.RWTransaction(func(levels map[Component]Level) {
+		clear(levels)
+		maps.Copy(levels, componentLevels)
+	})
+}
+
+func (l *levels) Get() Level {
+	var lv Level
+	l.level.RTransaction(func(l *Level) {
+		lv = *l
+	})
+
+	return lv
+}
+
+// Of returns the level of the component from the context, if it is overridden.
+func (l *levels) Of(ctx context.Context) Level {
+	lvl := l.Get()
+	if component, ok := ComponentFromContext(ctx); ok {
+		l.componentLevels.RTransaction(func(levels map[Component]Level) {
+			if componentLvl, ok := levels[component]; ok {
+				lvl = componentLvl
+			}
+		})
+	}
+
+	return lvl
+}
+
+// MostVerbose returns the most verbose of the level and the component levels.
+func (l *levels) MostVerbose() Level {
+	lvl := l.Get()
+	l.componentLevels.RTransaction(func(levels map[Component]Level) {
+		for _, componentLvl := range levels {
+			if slices.Index(Levels, componentLvl) > slices.Index(Levels, lvl) {
+				lvl = componentLvl
+			}
+		}
+	})
+
+	return lvl
+}
+
+func (l *levels) Accept(ctx context.Context, lvl Level) bool {
+	return slices.Index(Levels, l.Of(ctx)) >= slices.Index(Levels, lvl)
+}
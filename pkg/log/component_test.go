@@ -0,0 +1,91 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+var _ = Describe("component levels", func() {
+	DescribeTable("parsing",
+		func(s string, expectedLvl log.Level, expectedComponentLevels map[log.Component]log.Level) {
+			lvl, componentLevels, err := log.ParseLevels(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lvl).To(Equal(expectedLvl))
+			Expect(componentLevels).To(Equal(expectedComponentLevels))
+		},
+		Entry("empty", "", log.Level(""), map[log.Component]log.Level{}),
+		Entry("level only", "debug", log.DebugLevel, map[log.Component]log.Level{}),
+		Entry("level and components", "info,kube=trace,plan=debug", log.InfoLevel, map[log.Component]log.Level{
+			log.KubeComponent: log.TraceLevel,
+			log.PlanComponent: log.DebugLevel,
+		}),
+		Entry("components only", "track=silent", log.Level(""), map[log.Component]log.Level{
+			log.TrackComponent: log.SilentLevel,
+		}),
+	)
+
+	DescribeTable("parsing errors",
+		func(s, expectedErr string) {
+			_, _, err := log.ParseLevels(s)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("unknown level", "verbose", `unknown log level "verbose"`),
+		Entry("unknown component", "info,kubernetes=debug", `unknown log component "kubernetes", expected one of ["chart" "kube" "plan" "release" "track"]`),
+		Entry("unknown component level", "kube=verbose", `unknown log level "verbose" of component "kube"`),
+		Entry("level specified twice", "info,debug", "log level specified more than once"),
+	)
+
+	It("logs messages of components with their levels", func() {
+		ctx := context.Background()
+		buf := &bytes.Buffer{}
+
+		logger := log.NewPlainLogger(buf)
+		logger.SetLevel(ctx, log.InfoLevel)
+		logger.SetComponentLevels(ctx, map[log.Component]log.Level{
+			log.KubeComponent: log.DebugLevel,
+			log.PlanComponent: log.ErrorLevel,
+		})
+
+		log.NewComponentLogger(logger, log.KubeComponent).Debug(ctx, "kube debug")
+		log.NewComponentLogger(logger, log.PlanComponent).Warn(ctx, "plan warning")
+		log.NewComponentLogger(logger, log.ChartComponent).Info(ctx, "chart info")
+		log.NewComponentLogger(logger, log.ChartComponent).Debug(ctx, "chart debug")
+		logger.Debug(ctx, "untagged debug")
+
+		Expect(buf.String()).To(ContainSubstring("kube debug"))
+		Expect(buf.String()).To(ContainSubstring("chart info"))
+		Expect(buf.String()).NotTo(ContainSubstring("plan warning"))
+		Expect(buf.String()).NotTo(ContainSubstring("chart debug"))
+		Expect(buf.String()).NotTo(ContainSubstring("untagged debug"))
+	})
+
+	It("passes to secondary loggers of a tee only component levels more verbose than theirs", func() {
+		ctx := context.Background()
+		primaryBuf := &bytes.Buffer{}
+		secondaryBuf := &bytes.Buffer{}
+
+		primary := log.NewPlainLogger(primaryBuf)
+		secondary := log.NewPlainLogger(secondaryBuf)
+		secondary.SetLevel(ctx, log.DebugLevel)
+
+		logger := log.NewTeeLogger(primary, secondary)
+		logger.SetLevel(ctx, log.InfoLevel)
+		logger.SetComponentLevels(ctx, map[log.Component]log.Level{
+			log.KubeComponent: log.TraceLevel,
+			log.PlanComponent: log.ErrorLevel,
+		})
+
+		log.NewComponentLogger(logger, log.KubeComponent).Trace(ctx, "kube trace")
+		log.NewComponentLogger(logger, log.PlanComponent).Debug(ctx, "plan debug")
+
+		Expect(primaryBuf.String()).To(ContainSubstring("kube trace"))
+		Expect(primaryBuf.String()).NotTo(ContainSubstring("plan debug"))
+		Expect(secondaryBuf.String()).To(ContainSubstring("kube trace"))
+		Expect(secondaryBuf.String()).To(ContainSubstring("plan debug"))
+	})
+})
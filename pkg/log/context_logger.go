@@ -0,0 +1,157 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/werf/logboek/pkg/types"
+)
+
+var _ Logger = (*ContextLogger)(nil)
+
+type contextLoggerKey struct{}
+
+// NewContext returns the context, logging with which through Default goes to the logger.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextLoggerKey{}, logger)
+}
+
+// FromContext returns the logger added to the context with NewContext, if any.
+func FromContext(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	logger, ok := ctx.Value(contextLoggerKey{}).(Logger)
+
+	return logger, ok
+}
+
+// SetGlobal replaces the global logger of Default, used when there is no logger in the context.
+func SetGlobal(logger Logger) {
+	Default.setGlobal(logger)
+}
+
+// Global returns the global logger of Default.
+func Global() Logger {
+	return Default.logger(nil)
+}
+
+// NewContextLogger returns the logger passing every call to the logger from the context, or to the
+// global logger if there is none.
+func NewContextLogger(global Logger) *ContextLogger {
+	return &ContextLogger{
+		global: global,
+	}
+}
+
+type ContextLogger struct {
+	mu     sync.RWMutex
+	global Logger
+}
+
+func (l *ContextLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.logger(ctx).Trace(ctx, format, a...)
+}
+
+func (l *ContextLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.logger(ctx).TraceStruct(ctx, obj, format, a...)
+}
+
+func (l *ContextLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger(ctx).TracePush(ctx, group, format, a...)
+}
+
+func (l *ContextLogger) TracePop(ctx context.Context, group string) {
+	l.logger(ctx).TracePop(ctx, group)
+}
+
+func (l *ContextLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.logger(ctx).Debug(ctx, format, a...)
+}
+
+func (l *ContextLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger(ctx).DebugPush(ctx, group, format, a...)
+}
+
+func (l *ContextLogger) DebugPop(ctx context.Context, group string) {
+	l.logger(ctx).DebugPop(ctx, group)
+}
+
+func (l *ContextLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.logger(ctx).Info(ctx, format, a...)
+}
+
+func (l *ContextLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger(ctx).InfoPush(ctx, group, format, a...)
+}
+
+func (l *ContextLogger) InfoPop(ctx context.Context, group string) {
+	l.logger(ctx).InfoPop(ctx, group)
+}
+
+func (l *ContextLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.logger(ctx).Warn(ctx, format, a...)
+}
+
+func (l *ContextLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger(ctx).WarnPush(ctx, group, format, a...)
+}
+
+func (l *ContextLogger) WarnPop(ctx context.Context, group string) {
+	l.logger(ctx).WarnPop(ctx, group)
+}
+
+func (l *ContextLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.logger(ctx).Error(ctx, format, a...)
+}
+
+func (l *ContextLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.logger(ctx).ErrorPush(ctx, group, format, a...)
+}
+
+func (l *ContextLogger) ErrorPop(ctx context.Context, group string) {
+	l.logger(ctx).ErrorPop(ctx, group)
+}
+
+func (l *ContextLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	return l.logger(ctx).InfoBlock(ctx, format, a...)
+}
+
+func (l *ContextLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	return l.logger(ctx).InfoProcess(ctx, format, a...)
+}
+
+func (l *ContextLogger) SetLevel(ctx context.Context, lvl Level) {
+	l.logger(ctx).SetLevel(ctx, lvl)
+}
+
+func (l *ContextLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.logger(ctx).SetComponentLevels(ctx, componentLevels)
+}
+
+func (l *ContextLogger) Level(ctx context.Context) Level {
+	return l.logger(ctx).Level(ctx)
+}
+
+func (l *ContextLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	return l.logger(ctx).AcceptLevel(ctx, lvl)
+}
+
+func (l *ContextLogger) logger(ctx context.Context) Logger {
+	if logger, ok := FromContext(ctx); ok {
+		return logger
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.global
+}
+
+func (l *ContextLogger) setGlobal(logger Logger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.global = logger
+}
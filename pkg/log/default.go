@@ -0,0 +1,9 @@
+package log
+
+var (
+	// Default logs with the logger added to the context with NewContext, otherwise with the global
+	// logger set with SetGlobal, DefaultLogboek by default.
+	Default        = NewContextLogger(DefaultLogboek)
+	DefaultLogboek = NewLogboekLogger()
+	DefaultNull    = NewNullLogger()
+)
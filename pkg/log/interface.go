@@ -26,6 +26,7 @@ type Logger interface {
 	InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface
 	InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface
 	SetLevel(ctx context.Context, lvl Level)
+	SetComponentLevels(ctx context.Context, componentLevels map[Component]Level)
 	Level(ctx context.Context) Level
 	AcceptLevel(ctx context.Context, lvl Level) bool
 }
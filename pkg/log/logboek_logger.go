@@ -3,7 +3,7 @@ package log
 import (
 	"context"
 	"fmt"
-	"slices"
+	"sync/atomic"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/gookit/color"
@@ -27,7 +27,7 @@ func NewLogboekLogger() *LogboekLogger {
 		warnStash:  util.NewConcurrent(make(map[string][]string)),
 		errorStash: util.NewConcurrent(make(map[string][]string)),
 
-		level: util.NewConcurrent(lo.ToPtr(InfoLevel)),
+		levels: newLevels(InfoLevel),
 	}
 }
 
@@ -38,25 +38,26 @@ type LogboekLogger struct {
 	warnStash  *util.Concurrent[map[string][]string]
 	errorStash *util.Concurrent[map[string][]string]
 
-	level *util.Concurrent[*Level]
+	levels *levels
+	muted  atomic.Bool
 }
 
 func (l *LogboekLogger) Trace(ctx context.Context, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, TraceLevel) {
+	if !l.AcceptLevel(ctx, TraceLevel) {
 		return
 	}
 
-	logboek.Context(ctx).Debug().LogF(format+"\n", a...)
+	logboekLogF(ctx, level.Debug, nil, format+"\n", a...)
 }
 
 func (l *LogboekLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, TraceLevel) {
+	if !l.AcceptLevel(ctx, TraceLevel) {
 		return
 	}
 
-	dump := spew.Sdump(obj)
+	dump := spew.Sdump(Redact(obj))
 
-	logboek.Context(ctx).Debug().LogF(fmt.Sprintf(format+"\n", a...) + dump + "\n")
+	logboekLogF(ctx, level.Debug, nil, fmt.Sprintf(format+"\n", a...)+dump+"\n")
 }
 
 func (l *LogboekLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
@@ -76,11 +77,11 @@ func (l *LogboekLogger) TracePop(ctx context.Context, group string) {
 }
 
 func (l *LogboekLogger) Debug(ctx context.Context, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, DebugLevel) {
+	if !l.AcceptLevel(ctx, DebugLevel) {
 		return
 	}
 
-	logboek.Context(ctx).Debug().LogF(format+"\n", a...)
+	logboekLogF(ctx, level.Debug, nil, format+"\n", a...)
 }
 
 func (l *LogboekLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
@@ -100,11 +101,11 @@ func (l *LogboekLogger) DebugPop(ctx context.Context, group string) {
 }
 
 func (l *LogboekLogger) Info(ctx context.Context, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, InfoLevel) {
+	if !l.AcceptLevel(ctx, InfoLevel) {
 		return
 	}
 
-	logboek.Context(ctx).Default().LogF(format+"\n", a...)
+	logboekLogF(ctx, level.Default, nil, format+"\n", a...)
 }
 
 func (l *LogboekLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
@@ -124,11 +125,11 @@ func (l *LogboekLogger) InfoPop(ctx context.Context, group string) {
 }
 
 func (l *LogboekLogger) Warn(ctx context.Context, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, WarningLevel) {
+	if !l.AcceptLevel(ctx, WarningLevel) {
 		return
 	}
 
-	logboek.Context(ctx).Warn().LogFWithCustomStyle(color.Style{color.FgRed}, format+"\n", a...)
+	logboekLogF(ctx, level.Warn, color.Style{color.FgRed}, format+"\n", a...)
 }
 
 func (l *LogboekLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
@@ -148,11 +149,11 @@ func (l *LogboekLogger) WarnPop(ctx context.Context, group string) {
 }
 
 func (l *LogboekLogger) Error(ctx context.Context, format string, a ...interface{}) {
-	if !l.AcceptLevel(nil, ErrorLevel) {
+	if !l.AcceptLevel(ctx, ErrorLevel) {
 		return
 	}
 
-	logboek.Context(ctx).Error().LogFWithCustomStyle(color.Style{color.FgRed, color.Bold}, format+"\n", a...)
+	logboekLogF(ctx, level.Error, color.Style{color.FgRed, color.Bold}, format+"\n", a...)
 }
 
 func (l *LogboekLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
@@ -180,40 +181,83 @@ func (l *LogboekLogger) InfoProcess(ctx context.Context, format string, a ...int
 }
 
 func (l *LogboekLogger) SetLevel(ctx context.Context, lvl Level) {
-	switch lvl {
+	if !lo.Contains(Levels, lvl) {
+		panic(fmt.Sprintf("unsupported log level %q", lvl))
+	}
+
+	l.levels.Set(lvl)
+	l.setAcceptedLevel(ctx)
+}
+
+func (l *LogboekLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.levels.SetComponentLevels(componentLevels)
+	l.setAcceptedLevel(ctx)
+}
+
+func (l *LogboekLogger) Level(context.Context) Level {
+	return l.levels.Get()
+}
+
+func (l *LogboekLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	return l.levels.Accept(ctx, lvl)
+}
+
+// Logboek accepts the log level only, so that logs written to logboek directly by third-party code
+// are not affected by component levels. Messages of components more verbose than the log level are
+// let through by logboekLogF.
+func (l *LogboekLogger) setAcceptedLevel(ctx context.Context) {
+	if l.levels.MostVerbose() == SilentLevel {
+		logboek.Context(ctx).Streams().Mute()
+		l.muted.Store(true)
+
+		return
+	}
+
+	if l.muted.Swap(false) {
+		logboek.Context(ctx).Streams().Unmute()
+	}
+
+	switch l.levels.Get() {
 	case DebugLevel, TraceLevel:
 		logboek.Context(ctx).SetAcceptedLevel(level.Debug)
 	case InfoLevel:
 		logboek.Context(ctx).SetAcceptedLevel(level.Default)
 	case WarningLevel:
 		logboek.Context(ctx).SetAcceptedLevel(level.Warn)
-	case ErrorLevel:
+	case ErrorLevel, SilentLevel:
 		logboek.Context(ctx).SetAcceptedLevel(level.Error)
-	case SilentLevel:
-		logboek.Context(ctx).Streams().Mute()
-	default:
-		panic(fmt.Sprintf("unsupported log level %q", lvl))
 	}
-
-	l.level.RWTransaction(func(lv *Level) {
-		*lv = lvl
-	})
 }
 
-func (l *LogboekLogger) Level(context.Context) Level {
-	var lv Level
-	l.level.RTransaction(func(l *Level) {
-		lv = *l
-	})
+// logboekLogF logs with the manager of the logboek level, or of the most verbose level accepted by
+// logboek, if the level is not accepted, but with the style of the original level. Messages passed
+// to it must be already accepted by AcceptLevel.
+func logboekLogF(ctx context.Context, lvl level.Level, style color.Style, format string, a ...interface{}) {
+	logger := logboek.Context(ctx)
 
-	return lv
-}
+	manager := logboekManager(logger, lvl)
+	if style == nil {
+		style = manager.Style()
+	}
 
-func (l *LogboekLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
-	lvlI := slices.Index(Levels, lvl)
+	if !manager.IsAccepted() {
+		manager = logboekManager(logger, logger.AcceptedLevel())
+	}
 
-	currentLvl := l.Level(ctx)
-	currentLvlI := slices.Index(Levels, currentLvl)
+	manager.LogFWithCustomStyle(style, format, a...)
+}
 
-	return currentLvlI >= lvlI
+func logboekManager(logger types.LoggerInterface, lvl level.Level) types.ManagerInterface {
+	switch lvl {
+	case level.Debug:
+		return logger.Debug()
+	case level.Info:
+		return logger.Info()
+	case level.Warn:
+		return logger.Warn()
+	case level.Error:
+		return logger.Error()
+	default:
+		return logger.Default()
+	}
 }
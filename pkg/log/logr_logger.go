@@ -0,0 +1,57 @@
+package log
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+var _ Logger = (*LogrLogger)(nil)
+
+// NewLogrLogger returns the logger passing messages to the logr logger. Trace messages are logged
+// with V(2), debug messages with V(1), warnings as info messages with the "severity" key set to
+// "warning". Objects logged with TraceStruct are passed with the "data" key.
+func NewLogrLogger(logger logr.Logger) *LogrLogger {
+	return &LogrLogger{
+		adapterLogger: newAdapterLogger(
+			func(ctx context.Context, lvl Level, msg string, data interface{}) {
+				var keysAndValues []interface{}
+				if lvl == WarningLevel {
+					keysAndValues = append(keysAndValues, "severity", string(WarningLevel))
+				}
+
+				if data != nil {
+					keysAndValues = append(keysAndValues, "data", data)
+				}
+
+				if lvl == ErrorLevel {
+					logger.Error(nil, msg, keysAndValues...)
+				} else {
+					logger.V(logrVerbosity(lvl)).Info(msg, keysAndValues...)
+				}
+			},
+			func(ctx context.Context, lvl Level) bool {
+				if lvl == ErrorLevel {
+					return true
+				}
+
+				return logger.V(logrVerbosity(lvl)).Enabled()
+			},
+		),
+	}
+}
+
+type LogrLogger struct {
+	*adapterLogger
+}
+
+func logrVerbosity(lvl Level) int {
+	switch lvl {
+	case TraceLevel:
+		return 2
+	case DebugLevel:
+		return 1
+	default:
+		return 0
+	}
+}
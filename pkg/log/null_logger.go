@@ -57,6 +57,8 @@ func (l *NullLogger) InfoProcess(ctx context.Context, format string, a ...interf
 
 func (l *NullLogger) SetLevel(ctx context.Context, lvl Level) {}
 
+func (l *NullLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {}
+
 func (l *NullLogger) Level(context.Context) Level {
 	return InfoLevel
 }
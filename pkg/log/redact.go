@@ -0,0 +1,456 @@
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+const (
+	lastAppliedConfigAnnoName = "kubectl.kubernetes.io/last-applied-configuration"
+	sensitiveAnnoName         = "werf.io/sensitive"
+	redactedPrefix            = "*** (sha256:"
+)
+
+// Shorter values are not redacted in error messages, since they are likely to match unrelated
+// parts of the message.
+const minRedactedInErrorLen = 4
+
+// Values of fields with names containing any of these, case-insensitively, are redacted in objects
+// logged with TraceStruct.
+var DefaultSensitiveKeys = []string{"password", "token", "key"}
+
+type RedactOptions struct {
+	// Log objects as is.
+	Disable bool
+	// Defaults to DefaultSensitiveKeys.
+	SensitiveKeys []string
+}
+
+var redactOptions atomic.Pointer[RedactOptions]
+
+// SetRedactOptions configures redaction of objects logged with TraceStruct by all loggers.
+func SetRedactOptions(opts RedactOptions) {
+	if opts.SensitiveKeys == nil {
+		opts.SensitiveKeys = DefaultSensitiveKeys
+	}
+
+	redactOptions.Store(&opts)
+}
+
+// Redact returns a deep copy of the object with the values of sensitive fields of resources (see
+// RedactSensitiveResource) and the values of fields with sensitive names replaced with
+// "*** (sha256:<hash>…, <n> bytes)". Structs are converted to maps of their exported fields. The
+// object itself is never modified.
+func Redact(obj interface{}) interface{} {
+	opts := redactOptions.Load()
+	if opts == nil {
+		opts = &RedactOptions{SensitiveKeys: DefaultSensitiveKeys}
+	}
+
+	if opts.Disable || obj == nil {
+		return obj
+	}
+
+	r := &redactor{
+		sensitiveKeys: opts.SensitiveKeys,
+		visited:       map[uintptr]bool{},
+	}
+
+	return r.redact(reflect.ValueOf(obj))
+}
+
+type redactor struct {
+	sensitiveKeys []string
+	visited       map[uintptr]bool
+}
+
+func (r *redactor) redact(val reflect.Value) interface{} {
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer:
+		if val.IsNil() {
+			return nil
+		}
+
+		if r.visited[val.Pointer()] {
+			return fmt.Sprintf("<cycle %s>", val.Type())
+		}
+
+		r.visited[val.Pointer()] = true
+		defer delete(r.visited, val.Pointer())
+
+		result := r.redact(val.Elem())
+
+		// Keep pointers to values of the original type, e.g. to Unstructured, so that they are
+		// marshaled the same way.
+		if resultVal := reflect.ValueOf(result); resultVal.IsValid() && resultVal.Type() == val.Elem().Type() {
+			ptr := reflect.New(resultVal.Type())
+			ptr.Elem().Set(resultVal)
+
+			return ptr.Interface()
+		}
+
+		return result
+	case reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+
+		return r.redact(val.Elem())
+	case reflect.Struct:
+		switch val.Type() {
+		case reflect.TypeOf(corev1.Secret{}):
+			return r.redactTypedSecret(val.Interface().(corev1.Secret))
+		case reflect.TypeOf(unstructured.Unstructured{}):
+			object, _ := r.redact(val.FieldByName("Object")).(map[string]interface{})
+			return unstructured.Unstructured{Object: object}
+		}
+
+		// Values like resource.Quantity or metav1.Time are logged as is.
+		if val.Type().Implements(jsonMarshalerType) || reflect.PointerTo(val.Type()).Implements(jsonMarshalerType) {
+			return val.Interface()
+		}
+
+		return r.redactStruct(val)
+	case reflect.Map:
+		if val.IsNil() {
+			return nil
+		}
+
+		return r.redactMap(val)
+	case reflect.Slice:
+		if val.IsNil() {
+			return nil
+		}
+
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte{}, val.Bytes()...)
+		}
+
+		fallthrough
+	case reflect.Array:
+		result := make([]interface{}, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			result = append(result, r.redact(val.Index(i)))
+		}
+
+		return result
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if val.IsNil() {
+			return nil
+		}
+
+		return fmt.Sprintf("<%s>", val.Type())
+	default:
+		return val.Interface()
+	}
+}
+
+func (r *redactor) redactStruct(val reflect.Value) interface{} {
+	result := map[string]interface{}{}
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if r.isSensitive(field.Name) {
+			result[field.Name] = redactCopied(r.redact(val.Field(i)))
+		} else {
+			result[field.Name] = r.redact(val.Field(i))
+		}
+	}
+
+	// Unexported fields might have anything in them.
+	if len(result) == 0 && val.NumField() > 0 {
+		if stringer, ok := val.Interface().(fmt.Stringer); ok {
+			return stringer.String()
+		}
+
+		return fmt.Sprintf("<%s>", val.Type())
+	}
+
+	return result
+}
+
+func (r *redactor) redactMap(val reflect.Value) interface{} {
+	result := map[string]interface{}{}
+
+	iter := val.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		if r.isSensitive(key) {
+			result[key] = redactCopied(r.redact(iter.Value()))
+		} else {
+			result[key] = r.redact(iter.Value())
+		}
+	}
+
+	// Environment variables like {name: DB_PASSWORD, value: secret}.
+	if name, ok := result["name"].(string); ok && r.isSensitive(name) {
+		if value, ok := result["value"]; ok {
+			result["value"] = redactCopied(value)
+		}
+	}
+
+	redactSensitiveFields(result)
+
+	return result
+}
+
+func (r *redactor) redactTypedSecret(secret corev1.Secret) interface{} {
+	secret = *secret.DeepCopy()
+
+	for key, value := range secret.Data {
+		secret.Data[key] = []byte(redacted(value))
+	}
+
+	for key, value := range secret.StringData {
+		secret.StringData[key] = redacted([]byte(value))
+	}
+
+	if lastApplied, ok := secret.Annotations[lastAppliedConfigAnnoName]; ok {
+		secret.Annotations[lastAppliedConfigAnnoName] = redacted([]byte(lastApplied))
+	}
+
+	return secret
+}
+
+// Only non-empty strings and bytes are redacted, other values of sensitive fields, e.g. maps like
+// secretKeyRef, are already redacted recursively.
+func redactCopied(value interface{}) interface{} {
+	val := reflect.ValueOf(value)
+
+	switch {
+	case val.Kind() == reflect.String && val.Len() > 0:
+		return redacted([]byte(val.String()))
+	case val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 && val.Len() > 0:
+		return redacted(val.Bytes())
+	default:
+		return value
+	}
+}
+
+func (r *redactor) isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range r.sensitiveKeys {
+		if sensitiveKey != "" && strings.Contains(key, strings.ToLower(sensitiveKey)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactSensitiveResource returns a deep copy of the resource with the values of its sensitive
+// fields replaced with "*** (sha256:<hash>…, <n> bytes)", which is enough to see that they changed
+// without revealing them. Sensitive are data of Secrets and all fields except apiVersion, kind and
+// metadata of other resources annotated with "werf.io/sensitive: true". Unlike Redact, this
+// is not affected by the redact options.
+func RedactSensitiveResource(obj map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+
+	result := (&unstructured.Unstructured{Object: obj}).DeepCopy().Object
+	redactSensitiveFields(result)
+
+	return result
+}
+
+// RedactSensitiveError replaces the values of sensitive fields of the resource (see
+// RedactSensitiveResource) in the error message. The original error is still available with
+// errors.Unwrap.
+func RedactSensitiveError(err error, obj map[string]interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	for _, value := range sensitiveValues(obj) {
+		msg = strings.ReplaceAll(msg, value, redacted([]byte(value)))
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Modifies the object in place.
+func redactSensitiveFields(obj map[string]interface{}) {
+	fields := sensitiveFields(obj)
+	if len(fields) == 0 {
+		return
+	}
+
+	for _, field := range fields {
+		if value, ok := obj[field]; ok {
+			obj[field] = redactLeaves(value)
+		}
+	}
+
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if lastApplied, ok := annotations[lastAppliedConfigAnnoName].(string); ok {
+				annotations[lastAppliedConfigAnnoName] = redacted([]byte(lastApplied))
+			}
+		}
+	}
+}
+
+func sensitiveFields(obj map[string]interface{}) []string {
+	var fields []string
+	if isUnstructuredSecret(obj) {
+		fields = []string{"data", "stringData"}
+	} else if isAnnotatedSensitive(obj) {
+		for field := range obj {
+			if field != "apiVersion" && field != "kind" && field != "metadata" {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return fields
+}
+
+// Values are sorted from the longest, so that longer values are replaced before their substrings.
+func sensitiveValues(obj map[string]interface{}) []string {
+	values := map[string]bool{}
+
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for _, val := range v {
+				collect(val)
+			}
+		case []interface{}:
+			for _, val := range v {
+				collect(val)
+			}
+		case string:
+			if len(v) >= minRedactedInErrorLen {
+				values[v] = true
+			}
+		}
+	}
+
+	for _, field := range sensitiveFields(obj) {
+		collect(obj[field])
+	}
+
+	// Secrets data is base64 encoded, but errors might have decoded values.
+	if isUnstructuredSecret(obj) {
+		if data, ok := obj["data"].(map[string]interface{}); ok {
+			for _, value := range data {
+				if encoded, ok := value.(string); ok {
+					if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(decoded) >= minRedactedInErrorLen {
+						values[string(decoded)] = true
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for value := range values {
+		result = append(result, value)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) > len(result[j])
+		}
+
+		return result[i] < result[j]
+	})
+
+	return result
+}
+
+// Only non-empty strings are redacted, other values like numbers or booleans are kept as is.
+func redactLeaves(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = redactLeaves(val)
+		}
+
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactLeaves(val)
+		}
+
+		return v
+	case string:
+		// Already redacted because of the sensitive name of the field.
+		if strings.HasPrefix(v, redactedPrefix) {
+			return v
+		}
+
+		return redactCopied(v)
+	default:
+		return redactCopied(value)
+	}
+}
+
+func isUnstructuredSecret(obj map[string]interface{}) bool {
+	return obj["apiVersion"] == "v1" && obj["kind"] == "Secret"
+}
+
+func isAnnotatedSensitive(obj map[string]interface{}) bool {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	value, ok := annotations[sensitiveAnnoName].(string)
+	if !ok {
+		return false
+	}
+
+	sensitive, _ := strconv.ParseBool(value)
+
+	return sensitive
+}
+
+func redacted(value []byte) string {
+	hash := sha256.Sum256(value)
+
+	return fmt.Sprintf("%s%s…, %d bytes)", redactedPrefix, hex.EncodeToString(hash[:])[:8], len(value))
+}
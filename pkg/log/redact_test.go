@@ -0,0 +1,154 @@
+package log_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm/pkg/log"
+)
+
+var _ = Describe("redaction", func() {
+	AfterEach(func() {
+		log.SetRedactOptions(log.RedactOptions{})
+	})
+
+	newSecret := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name": "mysecret",
+				"annotations": map[string]interface{}{
+					"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"user":"YWRtaW4="}}`,
+				},
+			},
+			"type": "kubernetes.io/service-account-token",
+			"data": map[string]interface{}{
+				"user": "YWRtaW4=",
+			},
+		}}
+	}
+
+	It("redacts data of Secrets without modifying the original object", func() {
+		secret := newSecret()
+
+		redacted, ok := log.Redact(secret).(*unstructured.Unstructured)
+		Expect(ok).To(BeTrue())
+		Expect(redacted.Object["data"]).To(Equal(map[string]interface{}{"user": "*** (sha256:38d18098…, 8 bytes)"}))
+		Expect(redacted.GetAnnotations()).To(HaveKeyWithValue("kubectl.kubernetes.io/last-applied-configuration", "*** (sha256:fd749009…, 28 bytes)"))
+		Expect(redacted.GetName()).To(Equal("mysecret"))
+
+		Expect(secret).To(Equal(newSecret()))
+	})
+
+	It("redacts typed Secrets", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysecret"},
+			Data:       map[string][]byte{"user": []byte("admin")},
+		}
+
+		redacted, ok := log.Redact(secret).(*corev1.Secret)
+		Expect(ok).To(BeTrue())
+		Expect(redacted.Data).To(HaveKeyWithValue("user", []byte("*** (sha256:8c6976e5…, 5 bytes)")))
+		Expect(secret.Data).To(HaveKeyWithValue("user", []byte("admin")))
+	})
+
+	It("redacts fields with sensitive names in any object", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"spec": map[string]interface{}{
+				"apiToken": "abc",
+				"replicas": int64(2),
+				"env": []interface{}{
+					map[string]interface{}{"name": "DB_PASSWORD", "value": "qwerty"},
+					map[string]interface{}{"name": "DB_HOST", "value": "db"},
+				},
+			},
+		}}
+
+		redacted := log.Redact(obj).(*unstructured.Unstructured)
+		Expect(redacted.Object["spec"]).To(Equal(map[string]interface{}{
+			"apiToken": "*** (sha256:ba7816bf…, 3 bytes)",
+			"replicas": int64(2),
+			"env": []interface{}{
+				map[string]interface{}{"name": "DB_PASSWORD", "value": "*** (sha256:65e84be3…, 6 bytes)"},
+				map[string]interface{}{"name": "DB_HOST", "value": "db"},
+			},
+		}))
+	})
+
+	It("redacts exported fields of structs", func() {
+		type config struct {
+			Host        string
+			BearerToken string
+			password    string
+		}
+
+		Expect(log.Redact(&config{Host: "localhost", BearerToken: "abcd", password: "secret"})).To(Equal(map[string]interface{}{
+			"Host":        "localhost",
+			"BearerToken": "*** (sha256:88d4266f…, 4 bytes)",
+		}))
+	})
+
+	It("uses the configured sensitive keys", func() {
+		log.SetRedactOptions(log.RedactOptions{SensitiveKeys: []string{"host"}})
+
+		Expect(log.Redact(map[string]string{"host": "localhost", "token": "abc"})).To(Equal(map[string]interface{}{
+			"host":  "*** (sha256:49960de5…, 9 bytes)",
+			"token": "abc",
+		}))
+	})
+
+	It("redacts all fields except metadata of resources annotated as sensitive", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":        "myconfig",
+				"annotations": map[string]interface{}{"werf.io/sensitive": "true"},
+			},
+			"data": map[string]interface{}{
+				"user":     "admin",
+				"password": "qwerty",
+			},
+		}}
+
+		redacted := log.Redact(obj).(*unstructured.Unstructured)
+		Expect(redacted.Object["data"]).To(Equal(map[string]interface{}{
+			"user":     "*** (sha256:8c6976e5…, 5 bytes)",
+			"password": "*** (sha256:65e84be3…, 6 bytes)",
+		}))
+		Expect(redacted.GetName()).To(Equal("myconfig"))
+
+		Expect(log.RedactSensitiveResource(obj.Object)).To(Equal(redacted.Object))
+	})
+
+	It("redacts sensitive resources even if disabled", func() {
+		log.SetRedactOptions(log.RedactOptions{Disable: true})
+
+		secret := newSecret()
+		Expect(log.RedactSensitiveResource(secret.Object)["data"]).To(Equal(map[string]interface{}{"user": "*** (sha256:38d18098…, 8 bytes)"}))
+		Expect(secret).To(Equal(newSecret()))
+	})
+
+	It("redacts values of sensitive resources in errors", func() {
+		origErr := errors.New(`Secret "mysecret" is invalid: data[user]: Invalid value: "admin"`)
+
+		err := log.RedactSensitiveError(origErr, newSecret().Object)
+		Expect(err).To(MatchError(`Secret "mysecret" is invalid: data[user]: Invalid value: "*** (sha256:8c6976e5…, 5 bytes)"`))
+		Expect(errors.Is(err, origErr)).To(BeTrue())
+	})
+
+	It("does nothing if disabled", func() {
+		log.SetRedactOptions(log.RedactOptions{Disable: true})
+
+		secret := newSecret()
+		Expect(log.Redact(secret)).To(BeIdenticalTo(secret))
+	})
+})
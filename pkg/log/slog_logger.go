@@ -0,0 +1,57 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Slog level of the trace messages, slog has no trace level of its own.
+const SlogLevelTrace = slog.LevelDebug - 4
+
+var _ Logger = (*SlogLogger)(nil)
+
+// NewSlogLogger returns the logger passing messages to the slog logger. Objects logged with
+// TraceStruct are passed in the "data" attribute.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{
+		adapterLogger: newAdapterLogger(
+			func(ctx context.Context, lvl Level, msg string, data interface{}) {
+				if data != nil {
+					logger.Log(contextOrBackground(ctx), slogLevel(lvl), msg, slog.Any("data", data))
+				} else {
+					logger.Log(contextOrBackground(ctx), slogLevel(lvl), msg)
+				}
+			},
+			func(ctx context.Context, lvl Level) bool {
+				return logger.Enabled(contextOrBackground(ctx), slogLevel(lvl))
+			},
+		),
+	}
+}
+
+type SlogLogger struct {
+	*adapterLogger
+}
+
+func slogLevel(lvl Level) slog.Level {
+	switch lvl {
+	case TraceLevel:
+		return SlogLevelTrace
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarningLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+
+	return ctx
+}
@@ -0,0 +1,439 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+
+	"github.com/werf/kubedog/pkg/trackers/dyntracker/util"
+	"github.com/werf/logboek/pkg/types"
+)
+
+var _ Logger = (*StreamLogger)(nil)
+
+// NewJSONLogger returns the logger writing every message as a JSON object on a separate line.
+// Blocks and processes don't produce any output of their own besides their headers, but messages
+// logged inside of them have the "block" field set to the header.
+func NewJSONLogger(w io.Writer) *StreamLogger {
+	return newStreamLogger(w, encodeJSONRecord)
+}
+
+// NewPlainLogger returns the logger writing every message as a line of plain text prefixed with
+// the time and the level, without colors. Messages logged inside of blocks are indented.
+func NewPlainLogger(w io.Writer) *StreamLogger {
+	return newStreamLogger(w, encodePlainRecord)
+}
+
+func newStreamLogger(w io.Writer, encode func(record streamRecord) ([]byte, error)) *StreamLogger {
+	return &StreamLogger{
+		w:      w,
+		encode: encode,
+
+		traceStash: util.NewConcurrent(make(map[string][]string)),
+		debugStash: util.NewConcurrent(make(map[string][]string)),
+		infoStash:  util.NewConcurrent(make(map[string][]string)),
+		warnStash:  util.NewConcurrent(make(map[string][]string)),
+		errorStash: util.NewConcurrent(make(map[string][]string)),
+
+		levels: newLevels(InfoLevel),
+	}
+}
+
+// StreamLogger writes logs to an io.Writer in a machine-friendly format.
+type StreamLogger struct {
+	w      io.Writer
+	encode func(record streamRecord) ([]byte, error)
+	mu     sync.Mutex
+	blocks []string
+
+	traceStash *util.Concurrent[map[string][]string]
+	debugStash *util.Concurrent[map[string][]string]
+	infoStash  *util.Concurrent[map[string][]string]
+	warnStash  *util.Concurrent[map[string][]string]
+	errorStash *util.Concurrent[map[string][]string]
+
+	levels *levels
+}
+
+type streamRecord struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Blocks  []string
+	Data    interface{}
+}
+
+type jsonRecord struct {
+	Time    string      `json:"time"`
+	Level   Level       `json:"level"`
+	Message string      `json:"msg"`
+	Block   string      `json:"block,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (l *StreamLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, TraceLevel, nil, format, a...)
+}
+
+func (l *StreamLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.log(ctx, TraceLevel, obj, format, a...)
+}
+
+func (l *StreamLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
+	l.traceStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *StreamLogger) TracePop(ctx context.Context, group string) {
+	l.traceStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Trace(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *StreamLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, DebugLevel, nil, format, a...)
+}
+
+func (l *StreamLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.debugStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *StreamLogger) DebugPop(ctx context.Context, group string) {
+	l.debugStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Debug(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *StreamLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, InfoLevel, nil, format, a...)
+}
+
+func (l *StreamLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.infoStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *StreamLogger) InfoPop(ctx context.Context, group string) {
+	l.infoStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Info(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *StreamLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, WarningLevel, nil, format, a...)
+}
+
+func (l *StreamLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.warnStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *StreamLogger) WarnPop(ctx context.Context, group string) {
+	l.warnStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Warn(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *StreamLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.log(ctx, ErrorLevel, nil, format, a...)
+}
+
+func (l *StreamLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.errorStash.RWTransaction(func(stash map[string][]string) {
+		stash[group] = append(stash[group], fmt.Sprintf(format, a...))
+	})
+}
+
+func (l *StreamLogger) ErrorPop(ctx context.Context, group string) {
+	l.errorStash.RWTransaction(func(stash map[string][]string) {
+		for _, msg := range stash[group] {
+			l.Error(ctx, "%s", msg)
+		}
+
+		delete(stash, group)
+	})
+}
+
+func (l *StreamLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	return &jsonLogBlock{ctx: ctx, logger: l, header: fmt.Sprintf(format, a...)}
+}
+
+func (l *StreamLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	return &jsonLogProcess{jsonLogBlock: jsonLogBlock{ctx: ctx, logger: l, header: fmt.Sprintf(format, a...)}}
+}
+
+func (l *StreamLogger) SetLevel(ctx context.Context, lvl Level) {
+	if !lo.Contains(Levels, lvl) {
+		panic(fmt.Sprintf("unsupported log level %q", lvl))
+	}
+
+	l.levels.Set(lvl)
+}
+
+func (l *StreamLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.levels.SetComponentLevels(componentLevels)
+}
+
+func (l *StreamLogger) Level(context.Context) Level {
+	return l.levels.Get()
+}
+
+func (l *StreamLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	return l.levels.Accept(ctx, lvl)
+}
+
+func (l *StreamLogger) log(ctx context.Context, lvl Level, data interface{}, format string, a ...interface{}) {
+	if !l.AcceptLevel(ctx, lvl) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line, err := l.encode(streamRecord{
+		Time:    time.Now().UTC(),
+		Level:   lvl,
+		Message: color.ClearCode(strings.TrimSuffix(fmt.Sprintf(format, a...), "\n")),
+		Blocks:  l.blocks,
+		Data:    Redact(data),
+	})
+	if err != nil {
+		return
+	}
+
+	l.w.Write(line)
+}
+
+func (l *StreamLogger) pushBlock(header string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.blocks = append(l.blocks, color.ClearCode(header))
+}
+
+func (l *StreamLogger) popBlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.blocks) > 0 {
+		l.blocks = l.blocks[:len(l.blocks)-1]
+	}
+}
+
+func encodeJSONRecord(record streamRecord) ([]byte, error) {
+	jsonRec := jsonRecord{
+		Time:    record.Time.Format(time.RFC3339Nano),
+		Level:   record.Level,
+		Message: record.Message,
+		Data:    record.Data,
+	}
+
+	if len(record.Blocks) > 0 {
+		jsonRec.Block = record.Blocks[len(record.Blocks)-1]
+	}
+
+	line, err := json.Marshal(jsonRec)
+	if err != nil {
+		jsonRec.Data = fmt.Sprintf("%+v", record.Data)
+
+		if line, err = json.Marshal(jsonRec); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(line, '\n'), nil
+}
+
+func encodePlainRecord(record streamRecord) ([]byte, error) {
+	prefix := fmt.Sprintf("%s %-7s ", record.Time.Format("2006-01-02T15:04:05.000Z07:00"), strings.ToUpper(string(record.Level)))
+	indent := strings.Repeat("  ", len(record.Blocks))
+
+	text := record.Message
+	if record.Data != nil {
+		text += "\n" + strings.TrimSuffix(spew.Sdump(record.Data), "\n")
+	}
+
+	var result strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		result.WriteString(prefix + indent + line + "\n")
+	}
+
+	return []byte(result.String()), nil
+}
+
+var (
+	_ types.LogBlockInterface   = (*jsonLogBlock)(nil)
+	_ types.LogProcessInterface = (*jsonLogProcess)(nil)
+)
+
+type jsonLogBlock struct {
+	ctx      context.Context
+	logger   *StreamLogger
+	header   string
+	disabled bool
+}
+
+func (b *jsonLogBlock) Options(func(options types.LogBlockOptionsInterface)) types.LogBlockInterface {
+	return b
+}
+
+func (b *jsonLogBlock) Disable() types.LogBlockInterface {
+	b.disabled = true
+	return b
+}
+
+func (b *jsonLogBlock) Enable() types.LogBlockInterface {
+	b.disabled = false
+	return b
+}
+
+func (b *jsonLogBlock) Do(f func()) {
+	b.DoError(func() error {
+		f()
+		return nil
+	})
+}
+
+func (b *jsonLogBlock) DoError(f func() error) error {
+	if b.disabled {
+		return f()
+	}
+
+	b.start()
+	defer b.logger.popBlock()
+
+	return f()
+}
+
+func (b *jsonLogBlock) start() {
+	b.logger.Info(b.ctx, "%s", b.header)
+	b.logger.pushBlock(b.header)
+}
+
+type jsonLogProcess struct {
+	jsonLogBlock
+
+	options jsonLogProcessOptions
+}
+
+func (p *jsonLogProcess) Options(f func(options types.LogProcessOptionsInterface)) types.LogProcessInterface {
+	f(&p.options)
+	return p
+}
+
+func (p *jsonLogProcess) Disable() types.LogProcessInterface {
+	p.disabled = true
+	return p
+}
+
+func (p *jsonLogProcess) Enable() types.LogProcessInterface {
+	p.disabled = false
+	return p
+}
+
+func (p *jsonLogProcess) Do(f func()) {
+	p.DoError(func() error {
+		f()
+		return nil
+	})
+}
+
+func (p *jsonLogProcess) DoError(f func() error) error {
+	if p.disabled {
+		return f()
+	}
+
+	p.Start()
+
+	err := f()
+	if err != nil {
+		p.Fail()
+	} else {
+		p.End()
+	}
+
+	if p.options.infoSectionFunc != nil {
+		p.options.infoSectionFunc(err)
+	}
+
+	if p.options.successInfoSectionFunc != nil && err == nil {
+		p.options.successInfoSectionFunc()
+	}
+
+	return err
+}
+
+func (p *jsonLogProcess) Start() {
+	p.start()
+}
+
+func (p *jsonLogProcess) StepEnd(format string, a ...interface{}) {
+	p.logger.Info(p.ctx, format, a...)
+	p.logger.popBlock()
+}
+
+func (p *jsonLogProcess) End() {
+	p.logger.Info(p.ctx, "%s", "Done")
+	p.logger.popBlock()
+}
+
+func (p *jsonLogProcess) Fail() {
+	p.logger.Error(p.ctx, "%s", "Failed")
+	p.logger.popBlock()
+}
+
+var _ types.LogProcessOptionsInterface = (*jsonLogProcessOptions)(nil)
+
+type jsonLogProcessOptions struct {
+	infoSectionFunc        func(err error)
+	successInfoSectionFunc func()
+}
+
+func (o *jsonLogProcessOptions) DisableIfLevelNotAccepted() {}
+
+func (o *jsonLogProcessOptions) Mute() {}
+
+func (o *jsonLogProcessOptions) WithIndent() {}
+
+func (o *jsonLogProcessOptions) WithoutLogOptionalLn() {}
+
+func (o *jsonLogProcessOptions) WithoutElapsedTime() {}
+
+func (o *jsonLogProcessOptions) InfoSectionFunc(f func(err error)) {
+	o.infoSectionFunc = f
+}
+
+func (o *jsonLogProcessOptions) SuccessInfoSectionFunc(f func()) {
+	o.successInfoSectionFunc = f
+}
+
+func (o *jsonLogProcessOptions) Style(color.Style) {}
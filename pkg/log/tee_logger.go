@@ -0,0 +1,276 @@
+package log
+
+import (
+	"context"
+	"slices"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/logboek/pkg/types"
+)
+
+var _ Logger = (*TeeLogger)(nil)
+
+// NewTeeLogger returns the logger duplicating all logs of the primary logger to the secondary
+// ones. Level getters and AcceptLevel reflect the primary logger only. Secondary loggers keep their
+// own levels, unless the level set with SetLevel or a component level is more verbose.
+func NewTeeLogger(primary Logger, secondaries ...Logger) *TeeLogger {
+	return &TeeLogger{
+		primary:     primary,
+		secondaries: secondaries,
+	}
+}
+
+type TeeLogger struct {
+	primary     Logger
+	secondaries []Logger
+}
+
+func (l *TeeLogger) Trace(ctx context.Context, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.Trace(ctx, format, a...) })
+}
+
+func (l *TeeLogger) TraceStruct(ctx context.Context, obj interface{}, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.TraceStruct(ctx, obj, format, a...) })
+}
+
+func (l *TeeLogger) TracePush(ctx context.Context, group, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.TracePush(ctx, group, format, a...) })
+}
+
+func (l *TeeLogger) TracePop(ctx context.Context, group string) {
+	l.each(func(logger Logger) { logger.TracePop(ctx, group) })
+}
+
+func (l *TeeLogger) Debug(ctx context.Context, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.Debug(ctx, format, a...) })
+}
+
+func (l *TeeLogger) DebugPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.DebugPush(ctx, group, format, a...) })
+}
+
+func (l *TeeLogger) DebugPop(ctx context.Context, group string) {
+	l.each(func(logger Logger) { logger.DebugPop(ctx, group) })
+}
+
+func (l *TeeLogger) Info(ctx context.Context, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.Info(ctx, format, a...) })
+}
+
+func (l *TeeLogger) InfoPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.InfoPush(ctx, group, format, a...) })
+}
+
+func (l *TeeLogger) InfoPop(ctx context.Context, group string) {
+	l.each(func(logger Logger) { logger.InfoPop(ctx, group) })
+}
+
+func (l *TeeLogger) Warn(ctx context.Context, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.Warn(ctx, format, a...) })
+}
+
+func (l *TeeLogger) WarnPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.WarnPush(ctx, group, format, a...) })
+}
+
+func (l *TeeLogger) WarnPop(ctx context.Context, group string) {
+	l.each(func(logger Logger) { logger.WarnPop(ctx, group) })
+}
+
+func (l *TeeLogger) Error(ctx context.Context, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.Error(ctx, format, a...) })
+}
+
+func (l *TeeLogger) ErrorPush(ctx context.Context, group, format string, a ...interface{}) {
+	l.each(func(logger Logger) { logger.ErrorPush(ctx, group, format, a...) })
+}
+
+func (l *TeeLogger) ErrorPop(ctx context.Context, group string) {
+	l.each(func(logger Logger) { logger.ErrorPop(ctx, group) })
+}
+
+func (l *TeeLogger) InfoBlock(ctx context.Context, format string, a ...interface{}) types.LogBlockInterface {
+	block := &teeLogBlock{}
+	l.each(func(logger Logger) {
+		if b := logger.InfoBlock(ctx, format, a...); b != nil {
+			block.blocks = append(block.blocks, b)
+		}
+	})
+
+	return block
+}
+
+func (l *TeeLogger) InfoProcess(ctx context.Context, format string, a ...interface{}) types.LogProcessInterface {
+	process := &teeLogProcess{}
+	l.each(func(logger Logger) {
+		if p := logger.InfoProcess(ctx, format, a...); p != nil {
+			process.processes = append(process.processes, p)
+		}
+	})
+
+	return process
+}
+
+func (l *TeeLogger) SetLevel(ctx context.Context, lvl Level) {
+	l.primary.SetLevel(ctx, lvl)
+
+	for _, logger := range l.secondaries {
+		if slices.Index(Levels, lvl) > slices.Index(Levels, logger.Level(ctx)) {
+			logger.SetLevel(ctx, lvl)
+		}
+	}
+}
+
+// Secondary loggers get only the component levels more verbose than their own levels.
+func (l *TeeLogger) SetComponentLevels(ctx context.Context, componentLevels map[Component]Level) {
+	l.primary.SetComponentLevels(ctx, componentLevels)
+
+	for _, logger := range l.secondaries {
+		logger.SetComponentLevels(ctx, lo.PickBy(componentLevels, func(_ Component, lvl Level) bool {
+			return slices.Index(Levels, lvl) > slices.Index(Levels, logger.Level(ctx))
+		}))
+	}
+}
+
+func (l *TeeLogger) Level(ctx context.Context) Level {
+	return l.primary.Level(ctx)
+}
+
+func (l *TeeLogger) AcceptLevel(ctx context.Context, lvl Level) bool {
+	return l.primary.AcceptLevel(ctx, lvl)
+}
+
+func (l *TeeLogger) each(f func(logger Logger)) {
+	f(l.primary)
+
+	for _, logger := range l.secondaries {
+		f(logger)
+	}
+}
+
+var (
+	_ types.LogBlockInterface   = (*teeLogBlock)(nil)
+	_ types.LogProcessInterface = (*teeLogProcess)(nil)
+)
+
+// The wrapped function is run once, inside of the blocks of all loggers.
+type teeLogBlock struct {
+	blocks []types.LogBlockInterface
+}
+
+func (b *teeLogBlock) Options(f func(options types.LogBlockOptionsInterface)) types.LogBlockInterface {
+	for _, block := range b.blocks {
+		block.Options(f)
+	}
+
+	return b
+}
+
+func (b *teeLogBlock) Disable() types.LogBlockInterface {
+	for _, block := range b.blocks {
+		block.Disable()
+	}
+
+	return b
+}
+
+func (b *teeLogBlock) Enable() types.LogBlockInterface {
+	for _, block := range b.blocks {
+		block.Enable()
+	}
+
+	return b
+}
+
+func (b *teeLogBlock) Do(f func()) {
+	b.DoError(func() error {
+		f()
+		return nil
+	})
+}
+
+func (b *teeLogBlock) DoError(f func() error) error {
+	for _, block := range b.blocks {
+		f = func(block types.LogBlockInterface, f func() error) func() error {
+			return func() error {
+				return block.DoError(f)
+			}
+		}(block, f)
+	}
+
+	return f()
+}
+
+type teeLogProcess struct {
+	processes []types.LogProcessInterface
+}
+
+// Options are applied to the process of the primary logger only, so that info section functions
+// are called once.
+func (p *teeLogProcess) Options(f func(options types.LogProcessOptionsInterface)) types.LogProcessInterface {
+	if len(p.processes) > 0 {
+		p.processes[0].Options(f)
+	}
+
+	return p
+}
+
+func (p *teeLogProcess) Disable() types.LogProcessInterface {
+	for _, process := range p.processes {
+		process.Disable()
+	}
+
+	return p
+}
+
+func (p *teeLogProcess) Enable() types.LogProcessInterface {
+	for _, process := range p.processes {
+		process.Enable()
+	}
+
+	return p
+}
+
+func (p *teeLogProcess) Do(f func()) {
+	p.DoError(func() error {
+		f()
+		return nil
+	})
+}
+
+func (p *teeLogProcess) DoError(f func() error) error {
+	for _, process := range p.processes {
+		f = func(process types.LogProcessInterface, f func() error) func() error {
+			return func() error {
+				return process.DoError(f)
+			}
+		}(process, f)
+	}
+
+	return f()
+}
+
+func (p *teeLogProcess) Start() {
+	for _, process := range p.processes {
+		process.Start()
+	}
+}
+
+func (p *teeLogProcess) StepEnd(format string, a ...interface{}) {
+	for _, process := range p.processes {
+		process.StepEnd(format, a...)
+	}
+}
+
+func (p *teeLogProcess) End() {
+	for _, process := range p.processes {
+		process.End()
+	}
+}
+
+func (p *teeLogProcess) Fail() {
+	for _, process := range p.processes {
+		process.Fail()
+	}
+}
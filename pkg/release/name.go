@@ -0,0 +1,73 @@
+// Package release provides helpers for release names, which don't need a cluster or a chart.
+package release
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/werf/3p-helm/pkg/chartutil"
+)
+
+const (
+	MaxNameLength = 53
+
+	sanitizedNameHashLength = 8
+)
+
+var invalidNameCharsRegex = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type SanitizeNameOptions struct {
+	// Defaults to MaxNameLength.
+	MaxLength int
+}
+
+// SanitizeName turns an arbitrary string, e.g. a Git branch name, into a valid release name.
+// Names which are already valid, as well as an empty name, are returned as is, which makes
+// sanitizing idempotent. If the name has to be truncated, a hash of the original name is appended
+// to keep it unique.
+func SanitizeName(raw string, opts SanitizeNameOptions) string {
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = MaxNameLength
+	}
+
+	if raw == "" || (len(raw) <= maxLength && chartutil.ValidateReleaseName(raw) == nil) {
+		return raw
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.ToLower(raw), ".") {
+		segment = strings.Trim(invalidNameCharsRegex.ReplaceAllString(segment, "-"), "-")
+		segment = collapseDashes(segment)
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	name := strings.Join(segments, ".")
+
+	if name != "" && len(name) <= maxLength {
+		return name
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(raw)))[:sanitizedNameHashLength]
+	if maxLength <= sanitizedNameHashLength {
+		return hash[:maxLength]
+	}
+
+	name = strings.TrimRight(name[:min(len(name), maxLength-sanitizedNameHashLength-1)], "-.")
+	if name == "" {
+		return hash
+	}
+
+	return name + "-" + hash
+}
+
+func collapseDashes(s string) string {
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "-")
+	}
+
+	return s
+}
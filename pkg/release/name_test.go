@@ -0,0 +1,68 @@
+package release_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/nelm/pkg/release"
+)
+
+var _ = Describe("release name sanitizing", func() {
+	DescribeTable("sanitizes names",
+		func(raw, expected string) {
+			Expect(release.SanitizeName(raw, release.SanitizeNameOptions{})).To(Equal(expected))
+		},
+		Entry("keeps a valid name", "my-app", "my-app"),
+		Entry("keeps an empty name", "", ""),
+		Entry("lowercases", "Feature-ABC", "feature-abc"),
+		Entry("replaces invalid characters", "feature/JIRA-123_fix", "feature-jira-123-fix"),
+		Entry("collapses dashes", "feature//--fix", "feature-fix"),
+		Entry("trims leading and trailing dashes", "-/feature/-", "feature"),
+		Entry("keeps leading digits", "123-fix", "123-fix"),
+		Entry("replaces unicode", "фича-über", "ber"),
+		Entry("drops empty dot-separated segments", "app..v1.", "app.v1"),
+	)
+
+	DescribeTable("produces valid names",
+		func(raw string) {
+			name := release.SanitizeName(raw, release.SanitizeNameOptions{})
+
+			Expect(chartutil.ValidateReleaseName(name)).To(Succeed())
+			Expect(len(name)).To(BeNumerically("<=", release.MaxNameLength))
+			Expect(release.SanitizeName(name, release.SanitizeNameOptions{})).To(Equal(name), "sanitizing must be idempotent")
+		},
+		Entry("long name", "feature/"+strings.Repeat("very-long-branch-name-", 10)),
+		Entry("long name with a dash at the truncation point", strings.Repeat("a", 43)+"-"+strings.Repeat("b", 20)),
+		Entry("only invalid characters", "///___"),
+		Entry("only unicode", "фича"),
+		Entry("uppercase with invalid characters", "Release/Candidate#1"),
+	)
+
+	It("truncates long names to the max length with a hash suffix", func() {
+		name := release.SanitizeName(strings.Repeat("a", 60), release.SanitizeNameOptions{})
+
+		Expect(name).To(HaveLen(release.MaxNameLength))
+		Expect(name).To(MatchRegexp(`^a{44}-[0-9a-f]{8}$`))
+	})
+
+	It("keeps truncated names of different inputs unique", func() {
+		first := release.SanitizeName(strings.Repeat("a", 60)+"-first", release.SanitizeNameOptions{})
+		second := release.SanitizeName(strings.Repeat("a", 60)+"-second", release.SanitizeNameOptions{})
+
+		Expect(first).NotTo(Equal(second))
+	})
+
+	It("honors a custom max length", func() {
+		name := release.SanitizeName("feature-branch-name", release.SanitizeNameOptions{MaxLength: 12})
+
+		Expect(len(name)).To(BeNumerically("<=", 12))
+		Expect(chartutil.ValidateReleaseName(name)).To(Succeed())
+	})
+
+	It("uses a hash only if the max length is too short for anything else", func() {
+		Expect(release.SanitizeName("feature-branch-name", release.SanitizeNameOptions{MaxLength: 5})).To(MatchRegexp(`^[0-9a-f]{5}$`))
+	})
+})
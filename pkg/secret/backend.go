@@ -0,0 +1,234 @@
+package secret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/werf/common-go/pkg/secret"
+	"github.com/werf/common-go/pkg/secrets_manager"
+	"github.com/werf/nelm/internal/age"
+	"github.com/werf/nelm/internal/sops"
+)
+
+const (
+	// Files are encrypted with the werf secret key.
+	WerfBackendName = "werf"
+	// Files are encrypted for age recipients. Values files are encrypted in the SOPS format, other
+	// files are age encrypted files in the ASCII armor.
+	AgeBackendName = "age"
+)
+
+var BackendNames = []string{WerfBackendName, AgeBackendName}
+
+// Backend encrypts and decrypts secret values files and arbitrary secret files.
+type Backend interface {
+	Name() string
+	Encrypt(data []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+	EncryptValues(data []byte) ([]byte, error)
+	DecryptValues(data []byte) ([]byte, error)
+}
+
+type BackendOptions struct {
+	// Path to the file with age identities, required for the age backend. Files are encrypted for
+	// the recipients of these identities.
+	AgeIdentityPath string
+	// The werf backend is used by default.
+	Backend string
+	// The key for the werf backend. If not set, it is taken from $WERF_SECRET_KEY, the
+	// .werf_secret_key file in the working directory or ~/.werf/global_secret_key.
+	SecretKey []byte
+}
+
+func NewBackend(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, opts BackendOptions) (Backend, error) {
+	switch opts.Backend {
+	case "", WerfBackendName:
+		if len(opts.SecretKey) > 0 {
+			encoder, err := NewWerfEncoder(opts.SecretKey)
+			if err != nil {
+				return nil, fmt.Errorf("check encryption key: %w", err)
+			}
+
+			return NewWerfBackend(encoder), nil
+		}
+
+		encoder, err := m.GetYamlEncoder(ctx, workingDir)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewWerfBackend(encoder), nil
+	case AgeBackendName:
+		if opts.AgeIdentityPath == "" {
+			return nil, fmt.Errorf("age identity file must be specified for the %q secret backend", AgeBackendName)
+		}
+
+		return NewAgeBackend(opts.AgeIdentityPath)
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q, expected one of %q", opts.Backend, BackendNames)
+	}
+}
+
+// DetectBackendName returns the name of the backend the file is encrypted with. Files not
+// recognized as age or SOPS encrypted are expected to be encrypted with the werf backend.
+func DetectBackendName(data []byte, values bool) string {
+	if values && sops.IsEncrypted(data) || !values && age.IsEncrypted(data) {
+		return AgeBackendName
+	}
+
+	return WerfBackendName
+}
+
+func checkBackendMatches(backend Backend, data []byte, values bool) error {
+	if detected := DetectBackendName(data, values); detected != backend.Name() {
+		return fmt.Errorf("file is encrypted with the %q secret backend, but the %q secret backend is selected", detected, backend.Name())
+	}
+
+	return nil
+}
+
+// NewWerfEncoder returns the encoder for the werf secret key, without looking for the key in the
+// environment or key files.
+func NewWerfEncoder(key []byte) (*secret.YamlEncoder, error) {
+	encoder, err := secret.NewAesEncoder(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.NewYamlEncoder(encoder), nil
+}
+
+type WerfBackend struct {
+	encoder *secret.YamlEncoder
+}
+
+func NewWerfBackend(encoder *secret.YamlEncoder) *WerfBackend {
+	return &WerfBackend{encoder: encoder}
+}
+
+func (b *WerfBackend) Name() string {
+	return WerfBackendName
+}
+
+func (b *WerfBackend) Encrypt(data []byte) ([]byte, error) {
+	return b.encoder.Encrypt(data)
+}
+
+func (b *WerfBackend) Decrypt(data []byte) ([]byte, error) {
+	return b.encoder.Decrypt(data)
+}
+
+// Returns true if the werf secret decrypts with valid padding. The werf encoder doesn't verify the
+// padding and decrypts data encrypted with another key, or not encrypted at all, into garbage.
+func (b *WerfBackend) decryptsWithKey(data []byte) bool {
+	aesEncoder, ok := b.encoder.Encoder.(*secret.AesEncoder)
+	if !ok {
+		return true
+	}
+
+	raw, err := hex.DecodeString(string(data))
+	if err != nil || len(raw) < 2+2*aes.BlockSize || (len(raw)-2)%aes.BlockSize != 0 {
+		return false
+	}
+
+	iv := raw[2 : 2+aes.BlockSize]
+	plaintext := raw[2+aes.BlockSize:]
+	cipher.NewCBCDecrypter(aesEncoder.CipherBlock, iv).CryptBlocks(plaintext, plaintext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return false
+	}
+
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *WerfBackend) EncryptValues(data []byte) ([]byte, error) {
+	return b.encoder.EncryptYamlData(data)
+}
+
+func (b *WerfBackend) DecryptValues(data []byte) ([]byte, error) {
+	if IsPartiallyEncryptedValues(data) {
+		return DecryptPartiallyEncryptedValues(data, b.encoder.Decrypt)
+	}
+
+	return b.encoder.DecryptYamlData(data)
+}
+
+type AgeBackend struct {
+	identities []*age.Identity
+}
+
+func NewAgeBackend(identityFilePath string) (*AgeBackend, error) {
+	data, err := os.ReadFile(identityFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read age identity file %q: %w", identityFilePath, err)
+	}
+
+	identities, err := age.ParseIdentities(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse age identity file %q: %w", identityFilePath, err)
+	}
+
+	return &AgeBackend{identities: identities}, nil
+}
+
+func (b *AgeBackend) Name() string {
+	return AgeBackendName
+}
+
+// Errors are prefixed the same way as by the werf backend, secret edit relies on it.
+func (b *AgeBackend) Encrypt(data []byte) ([]byte, error) {
+	encrypted, err := age.Encrypt(data, b.recipients()...)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+
+	return age.Armor(encrypted), nil
+}
+
+func (b *AgeBackend) Decrypt(data []byte) ([]byte, error) {
+	decrypted, err := age.Decrypt(data, b.identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+
+	return decrypted, nil
+}
+
+func (b *AgeBackend) EncryptValues(data []byte) ([]byte, error) {
+	encrypted, err := sops.EncryptValues(data, b.recipients())
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+
+	return encrypted, nil
+}
+
+func (b *AgeBackend) DecryptValues(data []byte) ([]byte, error) {
+	decrypted, err := sops.DecryptValues(data, b.identities)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+
+	return decrypted, nil
+}
+
+func (b *AgeBackend) recipients() []*age.Recipient {
+	var recipients []*age.Recipient
+	for _, identity := range b.identities {
+		recipients = append(recipients, identity.Recipient())
+	}
+
+	return recipients
+}
@@ -3,6 +3,7 @@ package secret
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -13,14 +14,40 @@ import (
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
 	"github.com/werf/logboek/pkg/style"
+	nelmutil "github.com/werf/nelm/internal/util"
 )
 
+// Passed instead of the input or the output file path, means stdin or stdout.
+const StdioFilePath = "-"
+
 type GenerateOptions struct {
+	BackendOptions
+
 	FilePath       string
+	Input          io.Reader
+	Keys           []string
+	Output         io.Writer
 	OutputFilePath string
 	Values         bool
 }
 
+type StreamOptions struct {
+	BackendOptions
+
+	// If set, the input is read from here instead of the file.
+	Input io.Reader
+	// If set, the output is written here instead of stdout, unless the output file is specified.
+	Output io.Writer
+}
+
+type SecretValuesOptions struct {
+	StreamOptions
+
+	// If set, only values under these keys, e.g. ".db.password", are encrypted or decrypted, the
+	// rest of the values are left as is.
+	Keys []string
+}
+
 func ExpectedFilePathOrPipeError() error {
 	return errors.New("expected FILE_PATH or pipe")
 }
@@ -93,13 +120,59 @@ func SaveGeneratedData(filePath string, data []byte) error {
 		return err
 	}
 
-	if err := ioutil.WriteFile(filePath, data, 0o644); err != nil {
+	if err := nelmutil.WriteFileAtomic(filePath, data, 0o644); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Returns no data and no error if the input is neither specified nor piped.
+func readGeneratorInput(options *GenerateOptions) (data []byte, explicit bool, err error) {
+	switch {
+	case options.Input != nil:
+		data, err = io.ReadAll(options.Input)
+		return data, true, err
+	case options.FilePath == StdioFilePath:
+		data, err = InputFromStdin()
+		return data, true, err
+	case options.FilePath != "":
+		data, err = readFileData(options.FilePath)
+		return data, true, err
+	case !terminal.IsTerminal(int(os.Stdin.Fd())):
+		data, err = InputFromStdin()
+		return data, false, err
+	default:
+		return nil, false, ExpectedFilePathOrPipeError()
+	}
+}
+
+func writeGeneratorOutput(options *GenerateOptions, data []byte) error {
+	if options.OutputFilePath != "" && options.OutputFilePath != StdioFilePath {
+		return SaveGeneratedData(options.OutputFilePath, data)
+	}
+
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	if _, err := output.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Only the terminal gets the trailing newline, piped output is written as is.
+func outputToTerminal(options *GenerateOptions) bool {
+	if options.OutputFilePath != "" && options.OutputFilePath != StdioFilePath {
+		return false
+	}
+
+	return options.Output == nil && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
 func readFileData(filePath string) ([]byte, error) {
 	if exist, err := util.FileExists(filePath); err != nil {
 		return nil, err
@@ -4,11 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os"
 
-	"golang.org/x/crypto/ssh/terminal"
-
-	"github.com/werf/common-go/pkg/secret"
 	"github.com/werf/common-go/pkg/secrets_manager"
 )
 
@@ -16,9 +12,13 @@ func SecretFileDecrypt(
 	ctx context.Context,
 	m *secrets_manager.SecretsManager,
 	workingDir, filePath, outputFilePath string,
+	opts StreamOptions,
 ) error {
 	options := &GenerateOptions{
+		BackendOptions: opts.BackendOptions,
 		FilePath:       filePath,
+		Input:          opts.Input,
+		Output:         opts.Output,
 		OutputFilePath: outputFilePath,
 		Values:         false,
 	}
@@ -30,9 +30,14 @@ func SecretValuesDecrypt(
 	ctx context.Context,
 	m *secrets_manager.SecretsManager,
 	workingDir, filePath, outputFilePath string,
+	opts SecretValuesOptions,
 ) error {
 	options := &GenerateOptions{
+		BackendOptions: opts.BackendOptions,
 		FilePath:       filePath,
+		Input:          opts.Input,
+		Keys:           opts.Keys,
+		Output:         opts.Output,
 		OutputFilePath: outputFilePath,
 		Values:         true,
 	}
@@ -46,64 +51,51 @@ func secretDecrypt(
 	workingDir string,
 	options *GenerateOptions,
 ) error {
-	var encodedData []byte
 	var data []byte
 	var err error
 
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
+	backend, err := NewBackend(ctx, m, workingDir, options.BackendOptions)
+	if err != nil {
 		return err
-	} else {
-		encoder = enc
 	}
 
-	if options.FilePath != "" {
-		encodedData, err = readFileData(options.FilePath)
-		if err != nil {
-			return err
-		}
-	} else {
-		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
-			encodedData, err = InputFromStdin()
-			if err != nil {
-				return err
-			}
-		} else {
-			return ExpectedFilePathOrPipeError()
-		}
-
-		if len(encodedData) == 0 {
-			return nil
-		}
+	encodedData, explicitInput, err := readGeneratorInput(options)
+	if err != nil {
+		return err
+	} else if len(encodedData) == 0 && !explicitInput {
+		return nil
 	}
 
 	encodedData = bytes.TrimSpace(encodedData)
 
-	if options.Values {
-		data, err = encoder.DecryptYamlData(encodedData)
-		if err != nil {
-			return err
+	if err := checkBackendMatches(backend, encodedData, options.Values); err != nil {
+		return err
+	}
+
+	if options.Values && len(options.Keys) > 0 {
+		if backend.Name() != WerfBackendName {
+			return fmt.Errorf("decrypting only selected keys is not supported by the %q secret backend", backend.Name())
 		}
-	} else {
-		data, err = encoder.Decrypt(encodedData)
+
+		data, err = processValuesKeys(encodedData, options.Keys, false, backend.Decrypt)
 		if err != nil {
 			return err
 		}
-	}
-
-	if options.OutputFilePath != "" {
-		if err := SaveGeneratedData(options.OutputFilePath, data); err != nil {
+	} else if options.Values {
+		data, err = backend.DecryptValues(encodedData)
+		if err != nil {
 			return err
 		}
 	} else {
-		if terminal.IsTerminal(int(os.Stdout.Fd())) {
-			if !bytes.HasSuffix(data, []byte("\n")) {
-				data = append(data, []byte("\n")...)
-			}
+		data, err = backend.Decrypt(encodedData)
+		if err != nil {
+			return err
 		}
+	}
 
-		fmt.Printf("%s", string(data))
+	if outputToTerminal(options) && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, []byte("\n")...)
 	}
 
-	return nil
+	return writeGeneratorOutput(options, data)
 }
@@ -0,0 +1,180 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/common-go/pkg/secrets_manager"
+	nelmutil "github.com/werf/nelm/internal/util"
+)
+
+type SecretDirOptions struct {
+	BackendOptions
+
+	// Only report the files which would be changed, don't write them.
+	DryRun bool
+}
+
+type SecretDirResult struct {
+	// Files encrypted or decrypted, or which would be with DryRun.
+	ChangedFiles []string
+	// Files already encrypted when encrypting or not encrypted when decrypting.
+	SkippedFiles []string
+	// Symlinks are never followed.
+	SkippedSymlinks []string
+}
+
+// SecretDirEncrypt encrypts in place every regular file in the directory tree, except already
+// encrypted ones. Nothing is written unless all files are encrypted successfully.
+func SecretDirEncrypt(
+	ctx context.Context,
+	m *secrets_manager.SecretsManager,
+	workingDir, dirPath string,
+	opts SecretDirOptions,
+) (*SecretDirResult, error) {
+	backend, err := NewBackend(ctx, m, workingDir, opts.BackendOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return processSecretDir(dirPath, opts, func(data []byte) ([]byte, error) {
+		if isEncryptedFor(backend, data) {
+			return nil, nil
+		}
+
+		encodedData, err := backend.Encrypt(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(bytes.TrimSpace(encodedData), []byte("\n")...), nil
+	})
+}
+
+// SecretDirDecrypt decrypts in place every regular file in the directory tree, except not
+// encrypted ones. Nothing is written unless all files are decrypted successfully.
+func SecretDirDecrypt(
+	ctx context.Context,
+	m *secrets_manager.SecretsManager,
+	workingDir, dirPath string,
+	opts SecretDirOptions,
+) (*SecretDirResult, error) {
+	backend, err := NewBackend(ctx, m, workingDir, opts.BackendOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return processSecretDir(dirPath, opts, func(data []byte) ([]byte, error) {
+		if !IsEncrypted(data) {
+			return nil, nil
+		}
+
+		encodedData := bytes.TrimSpace(data)
+		if err := checkBackendMatches(backend, encodedData, false); err != nil {
+			return nil, err
+		}
+
+		if !isEncryptedFor(backend, encodedData) {
+			return nil, fmt.Errorf("decryption failed: check encryption key and data: data looks encrypted, but doesn't decrypt with the key")
+		}
+
+		return backend.Decrypt(encodedData)
+	})
+}
+
+// IsEncrypted reports whether the arbitrary secret file data looks encrypted with any of the
+// secret backends.
+func IsEncrypted(data []byte) bool {
+	return DetectBackendName(data, false) == AgeBackendName || isWerfEncrypted(data)
+}
+
+// Unlike IsEncrypted, the werf secret must also decrypt with the werf backend key, so that plaintext
+// in the werf secret format isn't mistaken for a secret.
+func isEncryptedFor(backend Backend, data []byte) bool {
+	if !IsEncrypted(data) {
+		return false
+	}
+
+	if werfBackend, ok := backend.(*WerfBackend); ok && isWerfEncrypted(data) {
+		return werfBackend.decryptsWithKey(bytes.TrimSpace(data))
+	}
+
+	return true
+}
+
+// The werf secret is the hex encoded 16 byte IV size, the IV and at least one AES block.
+func isWerfEncrypted(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) < 4+32+32 || (len(data)-4)%32 != 0 || !bytes.HasPrefix(data, []byte("1000")) {
+		return false
+	}
+
+	for _, b := range data {
+		if (b < '0' || b > '9') && (b < 'a' || b > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// processFunc returns nil data if the file must be left as is.
+func processSecretDir(dirPath string, opts SecretDirOptions, processFunc func(data []byte) ([]byte, error)) (*SecretDirResult, error) {
+	result := &SecretDirResult{}
+	processedFilesData := map[string][]byte{}
+
+	var errs []error
+	if err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			result.SkippedSymlinks = append(result.SkippedSymlinks, path)
+			return nil
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file %q: %w", path, err)
+		}
+
+		processedData, err := processFunc(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file %q: %w", path, err))
+		} else if processedData == nil {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+		} else {
+			processedFilesData[path] = processedData
+			result.ChangedFiles = append(result.ChangedFiles, path)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, nelmutil.Multierrorf("unable to process %d file(s), no files changed", errs, len(errs))
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, filePath := range result.ChangedFiles {
+		// File mode of the existing file is preserved.
+		if err := nelmutil.WriteFileAtomic(filePath, processedFilesData[filePath], 0o644); err != nil {
+			return nil, fmt.Errorf("error writing file %q: %w", filePath, err)
+		}
+	}
+
+	return result, nil
+}
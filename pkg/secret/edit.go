@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -13,41 +12,74 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/werf/common-go/pkg/secret"
 	"github.com/werf/common-go/pkg/secrets_manager"
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
 	"github.com/werf/logboek/pkg/style"
+	nelmutil "github.com/werf/nelm/internal/util"
 )
 
+type SecretEditOptions struct {
+	BackendOptions
+
+	// Editor command with arguments. Overrides $VISUAL and $EDITOR.
+	Editor string
+	// If set, only values under these keys of the values file are decrypted for editing and
+	// encrypted back.
+	Keys []string
+	// Show the diff of decrypted data and ask for confirmation before saving.
+	ShowDiff bool
+}
+
 func SecretEdit(
 	ctx context.Context,
 	m *secrets_manager.SecretsManager,
 	workingDir, tempDir, filePath string,
 	values bool,
+	opts SecretEditOptions,
 ) error {
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
+	backend, err := NewBackend(ctx, m, workingDir, opts.BackendOptions)
+	if err != nil {
 		return err
-	} else {
-		encoder = enc
 	}
 
-	data, encodedData, err := readEditedFile(filePath, values, encoder)
+	if len(opts.Keys) > 0 && backend.Name() != WerfBackendName {
+		return fmt.Errorf("editing only selected keys is not supported by the %q secret backend", backend.Name())
+	}
+
+	data, encodedData, err := readEditedFile(filePath, values, opts.Keys, backend)
+	if err != nil {
+		return err
+	}
+
+	// Values encrypted by keys are encrypted by the same keys again, so that the file stays
+	// partially encrypted.
+	keys := opts.Keys
+	if values && len(keys) == 0 && IsPartiallyEncryptedValues(encodedData) {
+		keys, err = encryptedValuesKeys(encodedData)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The file is edited in a private dir, which is removed afterwards along with swap and backup
+	// files the editor keeps next to the edited file.
+	editDir, err := createEditDir(tempDir)
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(editDir)
 
-	tmpFilePath := filepath.Join(tempDir, fmt.Sprintf("werf-edit-secret-%s.yaml", uuid.NewString()))
-	defer os.RemoveAll(tmpFilePath)
+	tmpFilePath := filepath.Join(editDir, fmt.Sprintf("werf-edit-secret-%s.yaml", uuid.NewString()))
+	defer shredFile(tmpFilePath)
 
 	if err := createTmpEditedFile(tmpFilePath, data); err != nil {
 		return err
 	}
 
-	bin, binArgs, err := editor()
+	bin, binArgs, err := editor(opts.Editor)
 	if err != nil {
 		return err
 	}
@@ -69,32 +101,60 @@ func SecretEdit(
 			return err
 		}
 
+		if bytes.Equal(bytes.TrimSpace(data), bytes.TrimSpace(newData)) {
+			fmt.Println(logboek.Colorize(style.Details(), fmt.Sprintf("No changes made, file %q is left as is", filePath)))
+			return nil
+		}
+
+		if opts.ShowDiff {
+			if diff, changed := nelmutil.ColoredUnifiedDiff(string(data), string(newData), 3); changed {
+				fmt.Println(diff)
+			}
+
+			ok, err := nelmutil.Confirm(os.Stdin, os.Stdout, logboek.Colorize(style.Highlight(), "Save changes?"), true)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				fmt.Println(logboek.Colorize(style.Details(), fmt.Sprintf("Changes discarded, file %q is left as is", filePath)))
+				return nil
+			}
+		}
+
 		var newEncodedData []byte
-		if values {
-			newEncodedData, err = encoder.EncryptYamlData(newData)
+		if values && len(keys) > 0 {
+			newEncodedData, err = processValuesKeys(newData, keys, true, backend.Encrypt)
+			if err != nil {
+				return fmt.Errorf("encryption failed: %w", err)
+			}
+		} else if values {
+			newEncodedData, err = backend.EncryptValues(newData)
 			if err != nil {
 				return err
 			}
 		} else {
-			newEncodedData, err = encoder.Encrypt(newData)
+			newEncodedData, err = backend.Encrypt(newData)
 			if err != nil {
 				return err
 			}
 
-			newEncodedData = append(newEncodedData, []byte("\n")...)
+			if !bytes.HasSuffix(newEncodedData, []byte("\n")) {
+				newEncodedData = append(newEncodedData, []byte("\n")...)
+			}
 		}
 
-		if !bytes.Equal(data, newData) {
-			if values {
-				newEncodedData, err = secret.MergeEncodedYaml(data, newData, encodedData, newEncodedData)
-				if err != nil {
-					return fmt.Errorf("unable to merge changed values of encoded yaml: %w", err)
-				}
+		// Unchanged values are kept as is to keep the diff of the encrypted file minimal. Not possible
+		// with SOPS, since the MAC covers all values.
+		if values && backend.Name() == WerfBackendName {
+			newEncodedData, err = secret.MergeEncodedYaml(data, newData, encodedData, newEncodedData)
+			if err != nil {
+				return fmt.Errorf("unable to merge changed values of encoded yaml: %w", err)
 			}
+		}
 
-			if err := SaveGeneratedData(filePath, newEncodedData); err != nil {
-				return err
-			}
+		if err := SaveGeneratedData(filePath, newEncodedData); err != nil {
+			return err
 		}
 
 		return nil
@@ -105,7 +165,7 @@ func SecretEdit(
 		if err != nil {
 			if strings.HasPrefix(err.Error(), "encryption failed") {
 				logboek.Warn().LogF("Error: %s\n", err)
-				ok, err := askForConfirmation()
+				ok, err := nelmutil.Confirm(os.Stdin, os.Stdout, logboek.Colorize(style.Highlight(), "Do you want to continue editing the file?"), true)
 				if err != nil {
 					return err
 				}
@@ -124,7 +184,7 @@ func SecretEdit(
 	return nil
 }
 
-func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) (
+func readEditedFile(filePath string, values bool, keys []string, backend Backend) (
 	[]byte,
 	[]byte,
 	error,
@@ -144,13 +204,22 @@ func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) (
 
 		encodedData = bytes.TrimSpace(encodedData)
 
-		if values {
-			data, err = encoder.DecryptYamlData(encodedData)
+		if err := checkBackendMatches(backend, encodedData, values); err != nil {
+			return nil, nil, err
+		}
+
+		if values && len(keys) > 0 {
+			data, err = processValuesKeys(encodedData, keys, false, backend.Decrypt)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else if values {
+			data, err = backend.DecryptValues(encodedData)
 			if err != nil {
 				return nil, nil, err
 			}
 		} else {
-			data, err = encoder.Decrypt(encodedData)
+			data, err = backend.Decrypt(encodedData)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -160,52 +229,52 @@ func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) (
 	return data, encodedData, nil
 }
 
-func askForConfirmation() (bool, error) {
-	r := os.Stdin
-
-	fmt.Println(logboek.Colorize(style.Highlight(), "Do you want to continue editing the file (Y/n)?"))
-
-	isTerminal := terminal.IsTerminal(int(r.Fd()))
-	if isTerminal {
-		if oldState, err := terminal.MakeRaw(int(r.Fd())); err != nil {
-			return false, err
-		} else {
-			defer terminal.Restore(int(r.Fd()), oldState)
-		}
-	}
-
-	var buf [1]byte
-	n, err := r.Read(buf[:])
-	if n > 0 {
-		switch buf[0] {
-		case 'y', 'Y', 13:
-			return true, nil
-		default:
-			return false, nil
-		}
+// Only the current user can access the created dir.
+func createEditDir(tempDir string) (string, error) {
+	if err := os.MkdirAll(tempDir, 0o700); err != nil {
+		return "", fmt.Errorf("unable to create dir %q: %w", tempDir, err)
 	}
 
-	if err != nil && err != io.EOF {
-		return false, err
+	dir, err := os.MkdirTemp(tempDir, "werf-edit-secret-")
+	if err != nil {
+		return "", fmt.Errorf("unable to create dir in %q: %w", tempDir, err)
 	}
 
-	return false, nil
+	return dir, nil
 }
 
 func createTmpEditedFile(filePath string, data []byte) error {
-	if err := SaveGeneratedData(filePath, data); err != nil {
-		return err
+	// Only the current user can read the decrypted data.
+	if err := nelmutil.WriteFileAtomic(filePath, data, 0o600); err != nil {
+		return fmt.Errorf("unable to write temporary file %q: %w", filePath, err)
 	}
+
 	return nil
 }
 
-func editor() (string, []string, error) {
+// Overwrites the last saved version of the file before removal, even if the editor failed. Data
+// the editor left elsewhere, e.g. in blocks freed when it saved by rename or in swap files outside
+// of the edit dir, is not overwritten.
+func shredFile(filePath string) {
+	if f, err := os.OpenFile(filePath, os.O_WRONLY, 0); err == nil {
+		if stat, err := f.Stat(); err == nil {
+			f.Write(make([]byte, stat.Size()))
+			f.Sync()
+		}
+
+		f.Close()
+	}
+
+	os.RemoveAll(filePath)
+}
+
+func editor(editorOverride string) (string, []string, error) {
 	var editorArgs []string
 
-	editorValue := os.Getenv("EDITOR")
-	if editorValue != "" {
-		editorFields := strings.Fields(editorValue)
-		return editorFields[0], editorFields[1:], nil
+	for _, editorValue := range []string{editorOverride, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if editorFields := strings.Fields(editorValue); len(editorFields) > 0 {
+			return editorFields[0], editorFields[1:], nil
+		}
 	}
 
 	var defaultEditors []string
@@ -4,11 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os"
 
-	"golang.org/x/crypto/ssh/terminal"
-
-	"github.com/werf/common-go/pkg/secret"
 	"github.com/werf/common-go/pkg/secrets_manager"
 )
 
@@ -16,9 +12,13 @@ func SecretFileEncrypt(
 	ctx context.Context,
 	m *secrets_manager.SecretsManager,
 	workingDir, filePath, outputFilePath string,
+	opts StreamOptions,
 ) error {
 	options := &GenerateOptions{
+		BackendOptions: opts.BackendOptions,
 		FilePath:       filePath,
+		Input:          opts.Input,
+		Output:         opts.Output,
 		OutputFilePath: outputFilePath,
 		Values:         false,
 	}
@@ -30,9 +30,14 @@ func SecretValuesEncrypt(
 	ctx context.Context,
 	m *secrets_manager.SecretsManager,
 	workingDir, filePath, outputFilePath string,
+	opts SecretValuesOptions,
 ) error {
 	options := &GenerateOptions{
+		BackendOptions: opts.BackendOptions,
 		FilePath:       filePath,
+		Input:          opts.Input,
+		Keys:           opts.Keys,
+		Output:         opts.Output,
 		OutputFilePath: outputFilePath,
 		Values:         true,
 	}
@@ -46,43 +51,37 @@ func secretEncrypt(
 	workingDir string,
 	options *GenerateOptions,
 ) error {
-	var data []byte
 	var encodedData []byte
 	var err error
 
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
+	backend, err := NewBackend(ctx, m, workingDir, options.BackendOptions)
+	if err != nil {
 		return err
-	} else {
-		encoder = enc
 	}
 
-	switch {
-	case options.FilePath != "":
-		data, err = readFileData(options.FilePath)
-		if err != nil {
-			return err
+	data, explicitInput, err := readGeneratorInput(options)
+	if err != nil {
+		return err
+	} else if len(data) == 0 && !explicitInput {
+		return nil
+	}
+
+	if options.Values && len(options.Keys) > 0 {
+		if backend.Name() != WerfBackendName {
+			return fmt.Errorf("encrypting only selected keys is not supported by the %q secret backend", backend.Name())
 		}
-	case !terminal.IsTerminal(int(os.Stdin.Fd())):
-		data, err = InputFromStdin()
+
+		encodedData, err = processValuesKeys(data, options.Keys, true, backend.Encrypt)
 		if err != nil {
 			return err
 		}
-
-		if len(data) == 0 {
-			return nil
-		}
-	default:
-		return ExpectedFilePathOrPipeError()
-	}
-
-	if options.Values {
-		encodedData, err = encoder.EncryptYamlData(data)
+	} else if options.Values {
+		encodedData, err = backend.EncryptValues(data)
 		if err != nil {
 			return err
 		}
 	} else {
-		encodedData, err = encoder.Encrypt(data)
+		encodedData, err = backend.Encrypt(data)
 		if err != nil {
 			return err
 		}
@@ -92,13 +91,5 @@ func secretEncrypt(
 		encodedData = append(encodedData, []byte("\n")...)
 	}
 
-	if options.OutputFilePath != "" {
-		if err := SaveGeneratedData(options.OutputFilePath, encodedData); err != nil {
-			return err
-		}
-	} else {
-		fmt.Printf("%s", string(encodedData))
-	}
-
-	return nil
+	return writeGeneratorOutput(options, encodedData)
 }
@@ -7,39 +7,75 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+
+	"github.com/samber/lo"
 
 	"github.com/werf/common-go/pkg/secret"
 	"github.com/werf/common-go/pkg/secrets_manager"
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
+	nelmutil "github.com/werf/nelm/internal/util"
 )
 
+type RotateSecretKeyOptions struct {
+	// If not set, it is taken from $WERF_SECRET_KEY, the .werf_secret_key file in the working
+	// directory or ~/.werf/global_secret_key.
+	NewSecretKey []byte
+	// If not set, it is taken from $WERF_OLD_SECRET_KEY.
+	OldSecretKey      []byte
+	SecretValuesPaths []string
+	// Leave files which can't be decrypted with the old key as is instead of failing.
+	SkipUndecryptable bool
+}
+
+type RotateSecretKeyResult struct {
+	RewrittenFiles     []string
+	UndecryptableFiles []string
+}
+
+// RotateSecretKey reencrypts secret values files and files in the "secret" directory of the chart
+// with the new key. Nothing is written unless all files are decrypted and reencrypted successfully.
 func RotateSecretKey(
 	ctx context.Context,
 	helmChartDir string,
 	secretWorkingDir string,
-	secretValuesPaths ...string,
-) error {
+	opts RotateSecretKeyOptions,
+) (*RotateSecretKeyResult, error) {
 	secretsManager := secrets_manager.Manager
 
-	newEncoder, err := secretsManager.GetYamlEncoder(ctx, secretWorkingDir)
-	if err != nil {
-		return err
+	var (
+		newEncoder, oldEncoder *secret.YamlEncoder
+		err                    error
+	)
+	if len(opts.NewSecretKey) > 0 {
+		newEncoder, err = NewWerfEncoder(opts.NewSecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("check encryption key: %w", err)
+		}
+	} else if newEncoder, err = secretsManager.GetYamlEncoder(ctx, secretWorkingDir); err != nil {
+		return nil, err
 	}
 
-	oldEncoder, err := secretsManager.GetYamlEncoderForOldKey(ctx)
-	if err != nil {
-		return err
+	if len(opts.OldSecretKey) > 0 {
+		oldEncoder, err = NewWerfEncoder(opts.OldSecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("check old encryption key: %w", err)
+		}
+	} else if oldEncoder, err = secretsManager.GetYamlEncoderForOldKey(ctx); err != nil {
+		return nil, err
 	}
 
-	return secretsRegenerate(newEncoder, oldEncoder, helmChartDir, secretValuesPaths...)
+	return secretsRegenerate(newEncoder, oldEncoder, helmChartDir, opts)
 }
 
 func secretsRegenerate(
 	newEncoder, oldEncoder *secret.YamlEncoder,
 	helmChartDir string,
-	secretValuesPaths ...string,
-) error {
+	opts RotateSecretKeyOptions,
+) (*RotateSecretKeyResult, error) {
+	secretValuesPaths := append([]string{}, opts.SecretValuesPaths...)
+
 	var secretFilesPaths []string
 	var secretFilesData map[string][]byte
 	var secretValuesFilesData map[string][]byte
@@ -47,14 +83,14 @@ func secretsRegenerate(
 
 	isHelmChartDirExist, err := util.FileExists(helmChartDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if isHelmChartDirExist {
 		defaultSecretValuesPath := filepath.Join(helmChartDir, "secret-values.yaml")
 		isDefaultSecretValuesExist, err := util.FileExists(defaultSecretValuesPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if isDefaultSecretValuesExist {
@@ -64,7 +100,7 @@ func secretsRegenerate(
 		secretDirectory := filepath.Join(helmChartDir, "secret")
 		isSecretDirectoryExist, err := util.FileExists(secretDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if isSecretDirectoryExist {
@@ -86,59 +122,102 @@ func secretsRegenerate(
 					return nil
 				})
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
 	pwd, err := os.Getwd()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	secretFilesData, err = readFilesToDecode(secretFilesPaths, pwd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	secretValuesFilesData, err = readFilesToDecode(secretValuesPaths, pwd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := regenerateSecrets(secretFilesData, regeneratedFilesData, oldEncoder.Decrypt, newEncoder.Encrypt); err != nil {
-		return err
+	undecryptableFilesErrs := map[string]error{}
+
+	if err := regenerateSecrets(secretFilesData, regeneratedFilesData, undecryptableFilesErrs, oldEncoder.Decrypt, newEncoder.Encrypt); err != nil {
+		return nil, err
 	}
 
-	if err := regenerateSecrets(secretValuesFilesData, regeneratedFilesData, oldEncoder.DecryptYamlData, newEncoder.EncryptYamlData); err != nil {
-		return err
+	partiallyEncryptedValuesFilesData := map[string][]byte{}
+	for filePath, data := range secretValuesFilesData {
+		if IsPartiallyEncryptedValues(data) {
+			partiallyEncryptedValuesFilesData[filePath] = data
+			delete(secretValuesFilesData, filePath)
+		}
 	}
 
-	for filePath, fileData := range regeneratedFilesData {
-		err := logboek.LogProcess(fmt.Sprintf("Saving file %q", filePath)).DoError(func() error {
-			fileData = append(bytes.TrimSpace(fileData), []byte("\n")...)
-			return ioutil.WriteFile(filePath, fileData, 0o644)
-		})
-		if err != nil {
-			return err
+	if err := regenerateSecrets(secretValuesFilesData, regeneratedFilesData, undecryptableFilesErrs, oldEncoder.DecryptYamlData, newEncoder.EncryptYamlData); err != nil {
+		return nil, err
+	}
+
+	// Only the values marked as encrypted are reencrypted, the rest of the file is left as is.
+	reencryptValues := func(data []byte) ([]byte, error) {
+		return reencryptPartiallyEncryptedValues(data, oldEncoder.Decrypt, newEncoder.Encrypt)
+	}
+	noop := func(data []byte) ([]byte, error) { return data, nil }
+	if err := regenerateSecrets(partiallyEncryptedValuesFilesData, regeneratedFilesData, undecryptableFilesErrs, reencryptValues, noop); err != nil {
+		return nil, err
+	}
+
+	result := &RotateSecretKeyResult{
+		UndecryptableFiles: sortedFilePaths(undecryptableFilesErrs),
+	}
+
+	if len(result.UndecryptableFiles) > 0 && !opts.SkipUndecryptable {
+		var errs []error
+		for _, filePath := range result.UndecryptableFiles {
+			errs = append(errs, fmt.Errorf("file %q: %w", filePath, undecryptableFilesErrs[filePath]))
 		}
+
+		return nil, nelmutil.Multierrorf("unable to decrypt %d file(s) with the old secret key, no files changed", errs, len(errs))
 	}
 
-	return nil
+	if len(regeneratedFilesData) == 0 {
+		return result, nil
+	}
+
+	filesToSave := map[string][]byte{}
+	for filePath, fileData := range regeneratedFilesData {
+		filesToSave[filePath] = append(bytes.TrimSpace(fileData), []byte("\n")...)
+	}
+
+	// Written all at once, so that a failure partway doesn't leave files encrypted with different
+	// keys.
+	if err := logboek.LogProcess(fmt.Sprintf("Saving %d file(s)", len(filesToSave))).DoError(func() error {
+		return nelmutil.WriteFilesAtomic(filesToSave, 0o644)
+	}); err != nil {
+		return nil, fmt.Errorf("save reencrypted files: %w", err)
+	}
+
+	result.RewrittenFiles = sortedFilePaths(regeneratedFilesData)
+
+	return result, nil
 }
 
 func regenerateSecrets(
 	filesData, regeneratedFilesData map[string][]byte,
+	undecryptableFilesErrs map[string]error,
 	decodeFunc, encodeFunc func([]byte) ([]byte, error),
 ) error {
-	for filePath, fileData := range filesData {
-		err := logboek.LogProcess(fmt.Sprintf("Regenerating file %q", filePath)).
-			DoError(func() error {
-				data, err := decodeFunc(fileData)
-				if err != nil {
-					return fmt.Errorf("check old encryption key and file data: %w", err)
-				}
+	for _, filePath := range sortedFilePaths(filesData) {
+		data, err := decodeFunc(filesData[filePath])
+		if err != nil {
+			undecryptableFilesErrs[filePath] = fmt.Errorf("check old encryption key and file data: %w", err)
+			continue
+		}
 
+		err = logboek.LogProcess(fmt.Sprintf("Regenerating file %q", filePath)).
+			DoError(func() error {
 				resultData, err := encodeFunc(data)
 				if err != nil {
 					return err
@@ -156,6 +235,13 @@ func regenerateSecrets(
 	return nil
 }
 
+func sortedFilePaths[T any](filesData map[string]T) []string {
+	filePaths := lo.Keys(filesData)
+	sort.Strings(filePaths)
+
+	return filePaths
+}
+
 func readFilesToDecode(filePaths []string, pwd string) (map[string][]byte, error) {
 	filesData := map[string][]byte{}
 	for _, filePath := range filePaths {
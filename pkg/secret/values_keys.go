@@ -0,0 +1,304 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml_v3 "gopkg.in/yaml.v3"
+)
+
+// Values encrypted by key selectors are marked with this tag, so that the rest of the partially
+// encrypted values file is left as is on decryption.
+const EncryptedValueTag = "!encrypted"
+
+var valuesKeyIndexRegexp = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+
+type valuesKeyPathElement struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Parses selectors like ".db.password" or ".servers[0].token" into path elements.
+func parseValuesKey(selector string) ([]valuesKeyPathElement, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(selector), ".")
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty key selector %q", selector)
+	}
+
+	var path []valuesKeyPathElement
+	for _, part := range strings.Split(trimmed, ".") {
+		match := valuesKeyIndexRegexp.FindStringSubmatch(part)
+		if match == nil || (match[1] == "" && match[2] == "") {
+			return nil, fmt.Errorf("invalid key selector %q", selector)
+		}
+
+		if match[1] != "" {
+			path = append(path, valuesKeyPathElement{key: match[1]})
+		}
+
+		for _, index := range strings.Split(strings.Trim(match[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+
+			i, err := strconv.Atoi(index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in key selector %q: %w", index, selector, err)
+			}
+
+			path = append(path, valuesKeyPathElement{index: i, isIndex: true})
+		}
+	}
+
+	return path, nil
+}
+
+// IsPartiallyEncryptedValues returns true if the values file has values marked as encrypted.
+func IsPartiallyEncryptedValues(data []byte) bool {
+	if !bytes.Contains(data, []byte(EncryptedValueTag)) {
+		return false
+	}
+
+	var doc yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+
+	return len(encryptedValuesNodes(&doc, "", true)) > 0
+}
+
+// DecryptPartiallyEncryptedValues decrypts only the values marked as encrypted and drops the marks.
+func DecryptPartiallyEncryptedValues(data []byte, decrypt func([]byte) ([]byte, error)) ([]byte, error) {
+	return processEncryptedValues(data, func(node *yaml_v3.Node) error {
+		return processValuesNode(node, false, true, decrypt)
+	})
+}
+
+// Decrypts the values marked as encrypted and encrypts them again, keeping the marks.
+func reencryptPartiallyEncryptedValues(data []byte, decrypt, encrypt func([]byte) ([]byte, error)) ([]byte, error) {
+	return processEncryptedValues(data, func(node *yaml_v3.Node) error {
+		if err := processValuesNode(node, false, true, decrypt); err != nil {
+			return err
+		}
+
+		return processValuesNode(node, true, false, encrypt)
+	})
+}
+
+// Returns the key selectors of the values marked as encrypted.
+func encryptedValuesKeys(data []byte) ([]string, error) {
+	var doc yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal values: %w", err)
+	}
+
+	nodes := encryptedValuesNodes(&doc, "", true)
+
+	selectors := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.selectable {
+			return nil, fmt.Errorf("unable to select encrypted value %q by a key selector, specify keys explicitly", node.selector)
+		}
+
+		selectors = append(selectors, node.selector)
+	}
+
+	return selectors, nil
+}
+
+func processEncryptedValues(data []byte, doFunc func(node *yaml_v3.Node) error) ([]byte, error) {
+	var doc yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal values: %w", err)
+	}
+
+	for _, node := range encryptedValuesNodes(&doc, "", true) {
+		if err := doFunc(node.node); err != nil {
+			return nil, fmt.Errorf("unable to process key %q: %w", node.selector, err)
+		}
+	}
+
+	return encodeValuesDocument(&doc)
+}
+
+type encryptedValuesNode struct {
+	node     *yaml_v3.Node
+	selector string
+	// False if the value can't be selected by a key selector, e.g. if its key contains dots.
+	selectable bool
+}
+
+func encryptedValuesNodes(node *yaml_v3.Node, selector string, selectable bool) []encryptedValuesNode {
+	var result []encryptedValuesNode
+	switch node.Kind {
+	case yaml_v3.DocumentNode:
+		for _, child := range node.Content {
+			result = append(result, encryptedValuesNodes(child, selector, selectable)...)
+		}
+	case yaml_v3.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			key := node.Content[i-1].Value
+			keySelectable := key != "" && !strings.ContainsAny(key, ".[]")
+			result = append(result, encryptedValuesNodes(node.Content[i], selector+"."+key, selectable && keySelectable)...)
+		}
+	case yaml_v3.SequenceNode:
+		for i, child := range node.Content {
+			result = append(result, encryptedValuesNodes(child, fmt.Sprintf("%s[%d]", selector, i), selectable)...)
+		}
+	case yaml_v3.ScalarNode:
+		if node.Tag == EncryptedValueTag {
+			result = append(result, encryptedValuesNode{node: node, selector: selector, selectable: selectable})
+		}
+	}
+
+	return result
+}
+
+// Encrypts or decrypts only the values selected by the key selectors, all scalar values under
+// selected mappings and sequences included. Other values are left as is. Encrypted values are
+// marked with EncryptedValueTag. If the values file has marked values, only they are decrypted.
+func processValuesKeys(data []byte, selectors []string, encrypt bool, doFunc func([]byte) ([]byte, error)) ([]byte, error) {
+	var doc yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal values: %w", err)
+	}
+
+	onlyMarked := !encrypt && len(encryptedValuesNodes(&doc, "", true)) > 0
+
+	for _, selector := range selectors {
+		path, err := parseValuesKey(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		var root *yaml_v3.Node
+		if len(doc.Content) > 0 {
+			root = doc.Content[0]
+		}
+
+		node, found := findValuesKeyNode(root, path)
+		if !found {
+			return nil, fmt.Errorf("key %q not found", selector)
+		}
+
+		if err := processValuesNode(node, encrypt, onlyMarked, doFunc); err != nil {
+			return nil, fmt.Errorf("unable to process key %q: %w", selector, err)
+		}
+	}
+
+	return encodeValuesDocument(&doc)
+}
+
+func encodeValuesDocument(doc *yaml_v3.Node) ([]byte, error) {
+	var result bytes.Buffer
+	encoder := yaml_v3.NewEncoder(&result)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("unable to marshal values: %w", err)
+	}
+
+	return result.Bytes(), nil
+}
+
+func findValuesKeyNode(node *yaml_v3.Node, path []valuesKeyPathElement) (*yaml_v3.Node, bool) {
+	for _, elem := range path {
+		if node == nil {
+			return nil, false
+		}
+
+		if node.Kind == yaml_v3.AliasNode {
+			node = node.Alias
+		}
+
+		switch {
+		case elem.isIndex && node.Kind == yaml_v3.SequenceNode:
+			if elem.index >= len(node.Content) {
+				return nil, false
+			}
+
+			node = node.Content[elem.index]
+		case !elem.isIndex && node.Kind == yaml_v3.MappingNode:
+			var found bool
+			for i := 0; i < len(node.Content); i += 2 {
+				if node.Content[i].Value == elem.key {
+					node = node.Content[i+1]
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+
+	return node, node != nil
+}
+
+// If onlyMarked, values not marked as encrypted are skipped. Already marked values are never
+// encrypted again.
+func processValuesNode(node *yaml_v3.Node, encrypt, onlyMarked bool, doFunc func([]byte) ([]byte, error)) error {
+	switch node.Kind {
+	case yaml_v3.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := processValuesNode(node.Content[i], encrypt, onlyMarked, doFunc); err != nil {
+				return err
+			}
+		}
+	case yaml_v3.SequenceNode:
+		for _, child := range node.Content {
+			if err := processValuesNode(child, encrypt, onlyMarked, doFunc); err != nil {
+				return err
+			}
+		}
+	case yaml_v3.AliasNode:
+		return fmt.Errorf("aliases are not supported")
+	case yaml_v3.ScalarNode:
+		marked := node.Tag == EncryptedValueTag
+		if node.ShortTag() == "!!null" || encrypt && marked || onlyMarked && !marked {
+			return nil
+		}
+
+		var value string
+		if encrypt {
+			// Same as werf does for secret values files: values of any type are encrypted as strings.
+			var v interface{}
+			if err := node.Decode(&v); err != nil {
+				return fmt.Errorf("unable to decode value %q: %w", node.Value, err)
+			}
+
+			value = fmt.Sprintf("%v", v)
+		} else {
+			if !marked && node.ShortTag() != "!!str" {
+				return fmt.Errorf("unable to decrypt non string value %q: expected encrypted value as hex string", node.Value)
+			}
+
+			if err := node.Decode(&value); err != nil {
+				return fmt.Errorf("unable to decode value %q: %w", node.Value, err)
+			}
+		}
+
+		newValue, err := doFunc([]byte(value))
+		if err != nil {
+			return err
+		}
+
+		if err := node.Encode(string(newValue)); err != nil {
+			return fmt.Errorf("unable to encode value: %w", err)
+		}
+
+		if encrypt {
+			node.Tag = EncryptedValueTag
+			node.Style = 0
+		}
+	}
+
+	return nil
+}